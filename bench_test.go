@@ -0,0 +1,80 @@
+//go:build !lite
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Benchmarks of the hot paths through the whole server, from routing and
+// middleware to the Supabase client, with Supabase and Claude faked as in the
+// end-to-end tests. They measure the server's own overhead; the latency
+// budgets in the README add what the real upstreams take. Compare runs with
+// benchstat:
+//
+//	go test -run '^$' -bench . -count 6 . > new.txt
+
+// benchTasks is how many tasks the signed-in user has
+const benchTasks = 100
+
+// startBench starts the server and signs in a user with benchTasks tasks
+func startBench(b *testing.B) (*e2eServer, []string, string) {
+	s := startServer(b)
+	access, _ := s.signIn()
+	auth := bearer(access)
+	due := time.Now().UTC().Add(2 * time.Hour)
+	for i := 0; i < benchTasks; i++ {
+		s.json(http.MethodPost, "/api/tasks", map[string]interface{}{
+			"title": fmt.Sprintf("Benchmark task %d", i), "priority": 1 + i%5, "due_date": due.Add(time.Duration(i) * time.Minute),
+		}, http.StatusCreated, nil, auth...)
+	}
+	var me struct {
+		UserID string `json:"user_id"`
+	}
+	s.json(http.MethodGet, "/api/me", nil, http.StatusOK, &me, auth...)
+	return s, auth, me.UserID
+}
+
+// reportPercentiles adds the 50th and 99th percentile of latencies to b's results
+func reportPercentiles(b *testing.B, latencies []time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[len(latencies)/2].Microseconds()), "p50-µs")
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Microseconds()), "p99-µs")
+}
+
+// benchmarkRequest times one request per iteration
+func benchmarkRequest(b *testing.B, s *e2eServer, method, path string, body interface{}, headers ...string) {
+	var latencies []time.Duration
+	for b.Loop() {
+		start := time.Now()
+		s.json(method, path, body, http.StatusOK, nil, headers...)
+		latencies = append(latencies, time.Since(start))
+	}
+	reportPercentiles(b, latencies)
+}
+
+func BenchmarkListTasks(b *testing.B) {
+	s, auth, _ := startBench(b)
+	benchmarkRequest(b, s, http.MethodGet, "/api/tasks", nil, auth...)
+}
+
+func BenchmarkMCPCallTool(b *testing.B) {
+	s, auth, _ := startBench(b)
+	benchmarkRequest(b, s, http.MethodPost, "/mcp/call_tool", map[string]interface{}{
+		"jsonrpc": "2.0", "id": 1, "method": "get_today_tasks", "params": map[string]interface{}{},
+	}, auth...)
+}
+
+func BenchmarkParseTask(b *testing.B) {
+	s, auth, userID := startBench(b)
+	benchmarkRequest(b, s, http.MethodPost, "/api/mcp/parse-task", map[string]interface{}{
+		"input": "urgent: send the client report tomorrow at 5pm", "user_id": userID,
+	}, auth...)
+}
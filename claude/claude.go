@@ -0,0 +1,261 @@
+// Package claude is a client for the Anthropic Messages API. Requests that
+// are rate limited (429) or find the API overloaded (529) are retried with
+// exponential backoff, waiting at least as long as Retry-After asks, and the
+// rate limits reported with each answer are kept for callers to surface.
+package claude
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// APIVersion is the anthropic-version header sent with every request
+const APIVersion = "2023-06-01"
+
+// StatusOverloaded is the status Anthropic answers with when the API is
+// temporarily overloaded
+const StatusOverloaded = 529
+
+// Client calls the Messages API with one model configuration
+type Client struct {
+	apiKey       string
+	baseURL      string
+	model        string
+	maxTokens    int
+	temperature  float64
+	systemPrompt string
+	retry        *utils.RetryConfig
+	httpClient   *http.Client
+
+	mu        sync.Mutex
+	rateLimit *RateLimit
+}
+
+// New creates a client from the Claude configuration
+func New(cfg config.Claude) *Client {
+	c := &Client{
+		apiKey:       cfg.APIKey,
+		baseURL:      cfg.BaseURL,
+		model:        cfg.Model,
+		maxTokens:    cfg.MaxTokens,
+		temperature:  cfg.Temperature,
+		systemPrompt: cfg.SystemPrompt,
+		httpClient:   &http.Client{Transport: utils.NewLoggingTransport("claude", nil), Timeout: cfg.Timeout.Duration},
+	}
+	c.retry = &utils.RetryConfig{
+		MaxAttempts:  cfg.MaxRetries + 1,
+		InitialDelay: time.Second,
+		MaxDelay:     20 * time.Second,
+		Multiplier:   2,
+		ShouldRetry:  Retryable,
+		RetryAfter: func(err error) time.Duration {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) {
+				return apiErr.RetryAfter
+			}
+			return 0
+		},
+	}
+	return c
+}
+
+// Model is the model the client asks for
+func (c *Client) Model() string {
+	return c.model
+}
+
+// Configured reports whether the client has an API key to call with
+func (c *Client) Configured() bool {
+	return c.apiKey != ""
+}
+
+// RateLimit is the API's rate limit state as of its latest answer. Counts
+// are -1 when the API did not report them.
+type RateLimit struct {
+	RequestsLimit     int        `json:"requests_limit"`
+	RequestsRemaining int        `json:"requests_remaining"`
+	TokensLimit       int        `json:"tokens_limit"`
+	TokensRemaining   int        `json:"tokens_remaining"`
+	Reset             *time.Time `json:"reset,omitempty"` // when the requests limit next refills
+}
+
+// RateLimit returns the rate limits reported with the latest answer, or nil
+// before the first one
+func (c *Client) RateLimit() *RateLimit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rateLimit == nil {
+		return nil
+	}
+	limit := *c.rateLimit
+	return &limit
+}
+
+// APIError is an answer other than 200 OK
+type APIError struct {
+	StatusCode int
+	Status     string
+	Body       string
+	// RetryAfter is how long the API asked callers to wait, if it did
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Claude API error: %s - %s", e.Status, e.Body)
+}
+
+// Retryable reports whether err is worth retrying: a rate-limited or
+// overloaded answer
+func Retryable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == StatusOverloaded)
+}
+
+// Response is Claude's answer to Complete
+type Response struct {
+	Text       string
+	StopReason string
+	// Attempts is how many requests it took, retries included
+	Attempts  int
+	RateLimit *RateLimit
+}
+
+// Params override the client's configuration for one request; zero values
+// keep the configured settings
+type Params struct {
+	Model       string
+	MaxTokens   int
+	Temperature *float64
+}
+
+// Complete sends messages, each a {"role", "content"} map, and returns the
+// text of Claude's answer
+func (c *Client) Complete(ctx context.Context, messages []map[string]interface{}) (*Response, error) {
+	return c.CompleteWith(ctx, messages, Params{})
+}
+
+// CompleteWith is Complete with p overriding the configured settings
+func (c *Client) CompleteWith(ctx context.Context, messages []map[string]interface{}, p Params) (*Response, error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("Claude API key not configured")
+	}
+
+	model, maxTokens, temperature := c.model, c.maxTokens, c.temperature
+	if p.Model != "" {
+		model = p.Model
+	}
+	if p.MaxTokens > 0 {
+		maxTokens = p.MaxTokens
+	}
+	if p.Temperature != nil {
+		temperature = *p.Temperature
+	}
+	payload := map[string]interface{}{
+		"model":       model,
+		"max_tokens":  maxTokens,
+		"temperature": temperature,
+		"messages":    messages,
+	}
+	if c.systemPrompt != "" {
+		payload["system"] = c.systemPrompt
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var resp *Response
+	attempts := 0
+	err = utils.Retry(ctx, c.retry, func() error {
+		attempts++
+		var err error
+		resp, err = c.send(utils.ContextWithAttempt(ctx, attempts), jsonData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Attempts = attempts
+	return resp, nil
+}
+
+// send makes one request
+func (c *Client) send(ctx context.Context, body []byte) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", APIVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Claude API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	limit := parseRateLimit(resp.Header)
+	c.mu.Lock()
+	c.rateLimit = limit
+	c.mu.Unlock()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(body),
+			RetryAfter: utils.ParseRetryAfter(resp.Header.Get("retry-after")),
+		}
+	}
+
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Content) == 0 || result.Content[0].Type != "text" {
+		return nil, fmt.Errorf("unexpected response format from Claude API")
+	}
+	copied := *limit
+	return &Response{Text: result.Content[0].Text, StopReason: result.StopReason, RateLimit: &copied}, nil
+}
+
+// parseRateLimit reads the anthropic-ratelimit-* headers
+func parseRateLimit(h http.Header) *RateLimit {
+	count := func(name string) int {
+		n, err := strconv.Atoi(h.Get(name))
+		if err != nil {
+			return -1
+		}
+		return n
+	}
+	limit := &RateLimit{
+		RequestsLimit:     count("anthropic-ratelimit-requests-limit"),
+		RequestsRemaining: count("anthropic-ratelimit-requests-remaining"),
+		TokensLimit:       count("anthropic-ratelimit-tokens-limit"),
+		TokensRemaining:   count("anthropic-ratelimit-tokens-remaining"),
+	}
+	if reset, err := time.Parse(time.RFC3339, h.Get("anthropic-ratelimit-requests-reset")); err == nil {
+		limit.Reset = &reset
+	}
+	return limit
+}
@@ -0,0 +1,96 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+)
+
+// flakyAPI answers with the given statuses in turn, then succeeds
+func flakyAPI(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if req["system"] != "Be brief." || req["temperature"] != 0.2 {
+			t.Errorf("request: %v", req)
+		}
+		w.Header().Set("anthropic-ratelimit-requests-limit", "50")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", fmt.Sprint(50-calls))
+		w.Header().Set("anthropic-ratelimit-requests-reset", "2026-01-02T15:04:05Z")
+		if calls <= len(statuses) {
+			w.Header().Set("retry-after", retryAfter)
+			w.WriteHeader(statuses[calls-1])
+			fmt.Fprint(w, `{"type":"error"}`)
+			return
+		}
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newTestClient(url string, retries int) *Client {
+	c := New(config.Claude{
+		APIKey:       "key",
+		BaseURL:      url,
+		Model:        "claude-test",
+		MaxTokens:    16,
+		Temperature:  0.2,
+		SystemPrompt: "Be brief.",
+		MaxRetries:   retries,
+		Timeout:      config.Duration{Duration: 5 * time.Second},
+	})
+	c.retry.InitialDelay = time.Millisecond
+	return c
+}
+
+func TestCompleteRetries(t *testing.T) {
+	messages := []map[string]interface{}{{"role": "user", "content": "hi"}}
+	cases := []struct {
+		name       string
+		retryAfter string
+		statuses   []int
+		retries    int
+		calls      int
+		ok         bool
+	}{
+		{"succeeds", "", nil, 3, 1, true},
+		{"rate limited then ok", "", []int{429, StatusOverloaded}, 3, 3, true},
+		{"out of retries", "", []int{429, 429, 429}, 2, 3, false},
+		{"not retryable", "", []int{400}, 3, 1, false},
+		{"retry-after beyond the max delay", "3600", []int{429}, 3, 1, false},
+	}
+	for _, tc := range cases {
+		server, calls := flakyAPI(t, tc.retryAfter, tc.statuses...)
+		client := newTestClient(server.URL, tc.retries)
+		resp, err := client.Complete(context.Background(), messages)
+		if (err == nil) != tc.ok || *calls != tc.calls {
+			t.Errorf("%s: err %v after %d calls, want ok %v after %d", tc.name, err, *calls, tc.ok, tc.calls)
+			continue
+		}
+		if err != nil {
+			if !Retryable(err) && tc.statuses[0] != 400 {
+				t.Errorf("%s: %v is not retryable", tc.name, err)
+			}
+			continue
+		}
+		if resp.Text != "ok" || resp.Attempts != tc.calls {
+			t.Errorf("%s: %+v", tc.name, resp)
+		}
+		limit := client.RateLimit()
+		if limit == nil || limit.RequestsLimit != 50 || limit.RequestsRemaining != 50-tc.calls ||
+			limit.TokensRemaining != -1 || limit.Reset == nil {
+			t.Errorf("%s: rate limit %+v", tc.name, limit)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/productivity/mcp-server/validation"
+)
+
+// fence matches a markdown code fence, closed or cut off by max_tokens
+var fence = regexp.MustCompile("(?s)```[\\w-]*[ \\t]*\\n?(.*?)(?:```|$)")
+
+// literals are the non-JSON words models write for JSON's literals
+var literals = map[string]string{
+	"True":  "true",
+	"False": "false",
+	"None":  "null",
+}
+
+// OutputError is a reply that is not the JSON its prompt asked for, even
+// after repair
+type OutputError struct {
+	Err error
+}
+
+func (e *OutputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OutputError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeJSON decodes the JSON value in a reply into out, repairing what it
+// can, and checks it against schema if one is given. Errors about the value
+// as a whole name it "reply". It returns an *OutputError if the reply cannot
+// be used.
+func DecodeJSON(text string, schema *validation.Schema, out interface{}) error {
+	raw := ExtractJSON(text)
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		repaired := RepairJSON(raw)
+		if json.Unmarshal([]byte(repaired), &value) != nil {
+			if !strings.ContainsAny(raw, "{[") {
+				err = errors.New("reply contains no JSON object or array")
+			}
+			return &OutputError{Err: err}
+		}
+		raw = repaired
+	}
+	if schema != nil {
+		if errs := schema.ValidateAs("reply", value); errs != nil {
+			return &OutputError{Err: errs}
+		}
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return &OutputError{Err: err}
+	}
+	return nil
+}
+
+// ExtractJSON returns the JSON value in a reply, dropping a markdown fence
+// around it and any prose before or after it. The value runs from the first
+// { or [ to its matching bracket, or to the end if it is never closed.
+func ExtractJSON(text string) string {
+	if m := fence.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	}
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return strings.TrimSpace(text)
+	}
+
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(text); i++ {
+		switch ch := text[i]; {
+		case inString && escaped:
+			escaped = false
+		case inString && ch == '\\':
+			escaped = true
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '{' || ch == '[':
+			depth++
+		case ch == '}' || ch == ']':
+			if depth--; depth == 0 {
+				return text[start : i+1]
+			}
+		}
+	}
+	return strings.TrimSpace(text[start:])
+}
+
+// RepairJSON fixes the mistakes models commonly make writing JSON: trailing
+// commas, comments, smart quotes around strings, raw newlines and tabs in
+// strings, Python's True, False and None, and a value cut off part way,
+// which is closed where it stops. Anything else is left as it is.
+func RepairJSON(s string) string {
+	out := make([]byte, 0, len(s)+8)
+	var closers []byte
+	inString, smart, escaped := false, false, false
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case inString && escaped:
+			out = utf8.AppendRune(out, r)
+			escaped = false
+		case inString && r == '\\':
+			out = append(out, '\\')
+			escaped = true
+		case inString && !smart && r == '"', inString && smart && (r == '“' || r == '”'):
+			out = append(out, '"')
+			inString = false
+		case inString && r == '"':
+			out = append(out, '\\', '"')
+		case inString && r == '\n':
+			out = append(out, '\\', 'n')
+		case inString && r == '\r':
+			out = append(out, '\\', 'r')
+		case inString && r == '\t':
+			out = append(out, '\\', 't')
+		case inString:
+			out = utf8.AppendRune(out, r)
+
+		case r == '"', r == '“', r == '”':
+			out = append(out, '"')
+			inString, smart = true, r != '"'
+		case strings.HasPrefix(s[i:], "//"):
+			if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+				size = end
+			} else {
+				size = len(s) - i
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+				size = end + 4
+			} else {
+				size = len(s) - i
+			}
+		case r == '{':
+			out = append(out, '{')
+			closers = append(closers, '}')
+		case r == '[':
+			out = append(out, '[')
+			closers = append(closers, ']')
+		case r == '}' || r == ']':
+			out = append(trimTrailingComma(out), byte(r))
+			if n := len(closers); n > 0 && closers[n-1] == byte(r) {
+				closers = closers[:n-1]
+			}
+		case unicode.IsLetter(r):
+			end := i
+			for end < len(s) && (s[end] >= 'a' && s[end] <= 'z' || s[end] >= 'A' && s[end] <= 'Z') {
+				end++
+			}
+			if end == i {
+				end = i + size
+			}
+			word := s[i:end]
+			if literal, ok := literals[word]; ok {
+				word = literal
+			}
+			out = append(out, word...)
+			size = end - i
+		default:
+			out = utf8.AppendRune(out, r)
+		}
+		i += size
+	}
+
+	// Close whatever was left open where the reply stopped
+	if inString {
+		if escaped {
+			out = out[:len(out)-1]
+		}
+		out = append(out, '"')
+	}
+	out = trimTrailingComma(out)
+	if trimmed := strings.TrimRightFunc(string(out), unicode.IsSpace); strings.HasSuffix(trimmed, ":") {
+		out = append([]byte(trimmed), " null"...)
+	}
+	for i := len(closers) - 1; i >= 0; i-- {
+		out = append(out, closers[i])
+	}
+	return string(out)
+}
+
+// trimTrailingComma drops a comma, and the space after it, from the end of out
+func trimTrailingComma(out []byte) []byte {
+	trimmed := strings.TrimRightFunc(string(out), unicode.IsSpace)
+	if strings.HasSuffix(trimmed, ",") {
+		return out[:len(trimmed)-1]
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package claude
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/productivity/mcp-server/validation"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want interface{}
+	}{
+		{"plain", `{"title": "Call mom", "priority": 3}`, map[string]interface{}{"title": "Call mom", "priority": 3.0}},
+		{"fenced", "Here you go:\n```json\n{\"title\": \"Call mom\"}\n```\nLet me know!", map[string]interface{}{"title": "Call mom"}},
+		{"prose", `Sure! {"title": "Call {mom}"} Hope that helps.`, map[string]interface{}{"title": "Call {mom}"}},
+		{"trailing commas", "{\"tags\": [\"a\", \"b\",],\n}", map[string]interface{}{"tags": []interface{}{"a", "b"}}},
+		{"comments", "{\"title\": \"x\", // the title\n /* none */ \"done\": False}", map[string]interface{}{"title": "x", "done": false}},
+		{"python literals", `{"a": True, "b": None, "c": "True"}`, map[string]interface{}{"a": true, "b": nil, "c": "True"}},
+		{"smart quotes", `{“title”: “Say "hi"”, "note": "a “quote”"}`, map[string]interface{}{"title": `Say "hi"`, "note": "a “quote”"}},
+		{"raw newline", "{\"description\": \"line one\nline two\"}", map[string]interface{}{"description": "line one\nline two"}},
+		{"truncated", "```json\n{\"tasks\": [{\"title\": \"Plan\"}, {\"title\": \"Bui", map[string]interface{}{
+			"tasks": []interface{}{map[string]interface{}{"title": "Plan"}, map[string]interface{}{"title": "Bui"}},
+		}},
+		{"truncated after key", `{"title": "Plan", "priority":`, map[string]interface{}{"title": "Plan", "priority": nil}},
+	}
+	for _, tc := range cases {
+		var got interface{}
+		if err := DecodeJSON(tc.text, nil, &got); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %#v, want %#v", tc.name, got, tc.want)
+		}
+	}
+
+	var outputErr *OutputError
+	var got interface{}
+	if err := DecodeJSON("I can't help with that.", nil, &got); !errors.As(err, &outputErr) {
+		t.Errorf("prose only: got %v, want an output error", err)
+	}
+
+	schema := &validation.Schema{
+		Type: "array",
+		Items: &validation.Schema{
+			Type:       "object",
+			Required:   []string{"title"},
+			Properties: map[string]*validation.Schema{"title": {Type: "string", MinLength: 1}},
+		},
+	}
+	var milestones []struct {
+		Title string `json:"title"`
+	}
+	if err := DecodeJSON(`[{"title": "Draft"}, {"title": "Ship"},]`, schema, &milestones); err != nil || len(milestones) != 2 {
+		t.Errorf("valid milestones: %v, %+v", err, milestones)
+	}
+	err := DecodeJSON(`{"title": "Draft"}`, schema, &milestones)
+	if !errors.As(err, &outputErr) || err.Error() != "reply: reply must be an array" {
+		t.Errorf("object for array: got %v", err)
+	}
+	err = DecodeJSON(`[{"title": "Draft"}, {"due_date": "2026-01-02"}]`, schema, &milestones)
+	if !errors.As(err, &outputErr) || err.Error() != "reply[1].title: reply[1].title is required" {
+		t.Errorf("missing title: got %v", err)
+	}
+}
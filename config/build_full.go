@@ -0,0 +1,6 @@
+//go:build !lite
+
+package config
+
+// liteBuild reports whether this is the lite build (go build -tags lite)
+const liteBuild = false
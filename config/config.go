@@ -0,0 +1,1125 @@
+// Package config loads every server setting at startup from defaults, an
+// optional YAML or TOML file and environment variables (in that order of
+// precedence, lowest first), then validates the result in one pass so that a
+// misconfigured deployment fails fast with a complete list of problems.
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// FileEnv names the environment variable holding the config file path
+const FileEnv = "CONFIG_FILE"
+
+// Config holds every setting the server reads at startup
+type Config struct {
+	Server        Server        `yaml:"server" toml:"server"`
+	Supabase      Supabase      `yaml:"supabase" toml:"supabase"`
+	Auth          Auth          `yaml:"auth" toml:"auth"`
+	Claude        Claude        `yaml:"claude" toml:"claude"`
+	Ollama        Ollama        `yaml:"ollama" toml:"ollama"`
+	Failover      Failover      `yaml:"failover" toml:"failover"`
+	Routing       Routing       `yaml:"routing" toml:"routing"`
+	Embeddings    Embeddings    `yaml:"embeddings" toml:"embeddings"`
+	Transcription Transcription `yaml:"transcription" toml:"transcription"`
+	CORS          CORS          `yaml:"cors" toml:"cors"`
+	Quota         Quota         `yaml:"quota" toml:"quota"`
+	Plans         Plans         `yaml:"plans" toml:"plans"`
+	Flags         Flags         `yaml:"flags" toml:"flags"`
+	MCP           MCP           `yaml:"mcp" toml:"mcp"`
+	Streaks       Streaks       `yaml:"streaks" toml:"streaks"`
+	Triggers      Triggers      `yaml:"triggers" toml:"triggers"`
+	Email         Email         `yaml:"email" toml:"email"`
+	Secrets       Secrets       `yaml:"secrets" toml:"secrets"`
+	Janitor       Janitor       `yaml:"janitor" toml:"janitor"`
+	Notion        Notion        `yaml:"notion" toml:"notion"`
+	Events        Events        `yaml:"events" toml:"events"`
+	Log           Log           `yaml:"log" toml:"log"`
+	SLO           SLO           `yaml:"slo" toml:"slo"`
+	Webhooks      Webhooks      `yaml:"webhooks" toml:"webhooks"`
+	Lite          Lite          `yaml:"lite" toml:"lite"`
+	Record        Record        `yaml:"record" toml:"record"`
+
+	// File is the config file that was loaded, empty when none was used
+	File string `yaml:"-" toml:"-"`
+}
+
+// Server configures the HTTP listener
+type Server struct {
+	Port            string   `yaml:"port" toml:"port" env:"PORT"`
+	GinMode         string   `yaml:"gin_mode" toml:"gin_mode" env:"GIN_MODE"`
+	ReadTimeout     Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout    Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout     Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// MaxBodyBytes caps request bodies; uploads keep their own larger caps
+	MaxBodyBytes int `yaml:"max_body_bytes" toml:"max_body_bytes" env:"SERVER_MAX_BODY_BYTES"`
+	// ParseFileMaxBodyBytes caps parse-file requests, whose body carries a whole file
+	ParseFileMaxBodyBytes int `yaml:"parse_file_max_body_bytes" toml:"parse_file_max_body_bytes" env:"SERVER_PARSE_FILE_MAX_BODY_BYTES"`
+	// Gzip compresses large responses for clients that accept it
+	Gzip bool `yaml:"gzip" toml:"gzip" env:"SERVER_GZIP"`
+}
+
+// Release reports whether the server runs in production (GIN_MODE=release)
+func (s Server) Release() bool {
+	return s.GinMode == "release"
+}
+
+// Supabase configures the database connection. The pool settings apply to
+// the one HTTP client every handler shares.
+type Supabase struct {
+	URL     string `yaml:"url" toml:"url" env:"SUPABASE_URL"`
+	AnonKey string `yaml:"anon_key" toml:"anon_key" env:"SUPABASE_ANON_KEY"`
+	// ServiceRoleKey reads and writes the tables holding credentials and
+	// authorization state (API keys, OAuth clients, revocations, ...), which
+	// the anon key the apps ship with cannot reach
+	ServiceRoleKey string `yaml:"service_role_key" toml:"service_role_key" env:"SUPABASE_SERVICE_ROLE_KEY"`
+
+	Timeout             Duration `yaml:"timeout" toml:"timeout" env:"SUPABASE_TIMEOUT"`
+	MaxIdleConnsPerHost int      `yaml:"max_idle_conns_per_host" toml:"max_idle_conns_per_host" env:"SUPABASE_MAX_IDLE_CONNS_PER_HOST"`
+	IdleConnTimeout     Duration `yaml:"idle_conn_timeout" toml:"idle_conn_timeout" env:"SUPABASE_IDLE_CONN_TIMEOUT"`
+	HTTP2               bool     `yaml:"http2" toml:"http2" env:"SUPABASE_HTTP2"`
+	Gzip                bool     `yaml:"gzip" toml:"gzip" env:"SUPABASE_GZIP"`
+	// TaskCacheTTL is how long a user's task list is served from memory
+	// between writes; 0 fetches it every time
+	TaskCacheTTL Duration `yaml:"task_cache_ttl" toml:"task_cache_ttl" env:"SUPABASE_TASK_CACHE_TTL"`
+	// MaxRetries bounds the retries of requests that were rate limited
+	// (429) or, for reads and other idempotent requests, failed in transit
+	// or at the gateway (502, 503, 504)
+	MaxRetries int `yaml:"max_retries" toml:"max_retries" env:"SUPABASE_MAX_RETRIES"`
+}
+
+// Auth configures token signing and admin access
+type Auth struct {
+	JWTSecret    string   `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET"`
+	AdminUserIDs []string `yaml:"admin_user_ids" toml:"admin_user_ids" env:"ADMIN_USER_IDS"`
+
+	// Strict takes the user only from the validated token or API key:
+	// ?user_id=, X-User-ID and tool user_id params naming anyone else are
+	// refused unless the caller is a service account with the act_as scope
+	Strict bool `yaml:"strict" toml:"strict" env:"AUTH_STRICT"`
+
+	// Resource is this server's canonical URI as an OAuth protected resource
+	// (RFC 8707), such as https://tasks.example.com/mcp. Tokens are issued
+	// with it as their audience and tokens for any other audience are
+	// refused; empty issues tokens without one.
+	Resource string `yaml:"resource" toml:"resource" env:"OAUTH_RESOURCE"`
+
+	// PlainPKCEClients may use the plain code_challenge_method; every other
+	// client must use S256
+	PlainPKCEClients []string `yaml:"plain_pkce_clients" toml:"plain_pkce_clients" env:"OAUTH_PLAIN_PKCE_CLIENTS"`
+
+	// ClientCredentials lets confidential clients get tokens of their own
+	// with the client_credentials grant. Each entry is client_id=scopes,
+	// such as digest-worker=read act_as; the client may ask for any of its
+	// space-separated scopes.
+	ClientCredentials []string `yaml:"client_credentials" toml:"client_credentials" env:"OAUTH_CLIENT_CREDENTIALS"`
+
+	// SigningKeys are PEM files of RS256 or EdDSA keys; the first signs
+	// tokens, the rest only verify them while they are rotated out
+	SigningKeys []string `yaml:"signing_keys" toml:"signing_keys" env:"JWT_SIGNING_KEYS"`
+
+	// GeneratedSecret is set when JWTSecret was generated for development
+	GeneratedSecret bool `yaml:"-" toml:"-"`
+}
+
+// Claude configures the Anthropic API client. SystemPrompt, if set, is sent
+// with every request; MaxRetries bounds the retries of rate-limited (429) and
+// overloaded (529) requests. PromptsDir holds <name>.tmpl files overriding
+// the built-in prompt templates.
+type Claude struct {
+	APIKey       string   `yaml:"api_key" toml:"api_key" env:"CLAUDE_API_KEY"`
+	BaseURL      string   `yaml:"base_url" toml:"base_url" env:"CLAUDE_BASE_URL"`
+	Model        string   `yaml:"model" toml:"model" env:"CLAUDE_MODEL"`
+	MaxTokens    int      `yaml:"max_tokens" toml:"max_tokens" env:"CLAUDE_MAX_TOKENS"`
+	Temperature  float64  `yaml:"temperature" toml:"temperature" env:"CLAUDE_TEMPERATURE"`
+	SystemPrompt string   `yaml:"system_prompt" toml:"system_prompt" env:"CLAUDE_SYSTEM_PROMPT"`
+	MaxRetries   int      `yaml:"max_retries" toml:"max_retries" env:"CLAUDE_MAX_RETRIES"`
+	PromptsDir   string   `yaml:"prompts_dir" toml:"prompts_dir" env:"CLAUDE_PROMPTS_DIR"`
+	Timeout      Duration `yaml:"timeout" toml:"timeout" env:"CLAUDE_TIMEOUT"`
+}
+
+// Ollama configures the local model server
+type Ollama struct {
+	URL   string `yaml:"url" toml:"url" env:"OLLAMA_URL"`
+	Model string `yaml:"model" toml:"model" env:"OLLAMA_MODEL"`
+}
+
+// Failover routes Claude requests to the Ollama model while Claude keeps
+// failing. After Threshold consecutive 429 or 5xx failures Claude is skipped
+// for Cooldown; then a single request probes it, and requests go back to
+// Claude once one succeeds.
+type Failover struct {
+	Enabled   bool     `yaml:"enabled" toml:"enabled" env:"LLM_FAILOVER"`
+	Threshold int      `yaml:"threshold" toml:"threshold" env:"LLM_FAILOVER_THRESHOLD"`
+	Cooldown  Duration `yaml:"cooldown" toml:"cooldown" env:"LLM_FAILOVER_COOLDOWN"`
+}
+
+// Routing sends AI operations to other models than CLAUDE_MODEL, to trade
+// cost and latency against quality per operation. Each route is
+// operation=model, such as parse_task=claude-3-5-haiku-latest or
+// parse_file=ollama:qwen3-coder:480b-cloud. Requests may pick a model
+// themselves from CLAUDE_MODEL, OLLAMA_MODEL, the routed models and
+// AllowedModels, and ask for up to MaxTokensCap tokens.
+type Routing struct {
+	Routes        []string `yaml:"routes" toml:"routes" env:"LLM_ROUTES"`
+	AllowedModels []string `yaml:"allowed_models" toml:"allowed_models" env:"LLM_ALLOWED_MODELS"`
+	MaxTokensCap  int      `yaml:"max_tokens_cap" toml:"max_tokens_cap" env:"LLM_MAX_TOKENS_CAP"`
+}
+
+// Embeddings configures the model that embeds task text for semantic search.
+// Provider is local (hashed words, no network or key), openai, voyage or
+// ollama; Model and URL default per provider, and ollama uses OLLAMA_URL.
+type Embeddings struct {
+	Provider string `yaml:"provider" toml:"provider" env:"EMBEDDINGS_PROVIDER"`
+	Model    string `yaml:"model" toml:"model" env:"EMBEDDINGS_MODEL"`
+	URL      string `yaml:"url" toml:"url" env:"EMBEDDINGS_URL"`
+	APIKey   string `yaml:"api_key" toml:"api_key" env:"EMBEDDINGS_API_KEY"`
+}
+
+// Transcription configures speech to text for parse-audio. Provider is none
+// (parse-audio is off), openai, or whisper for a self-hosted server with the
+// same /v1/audio/transcriptions API, which needs URL. Model and URL default
+// per provider.
+type Transcription struct {
+	Provider string   `yaml:"provider" toml:"provider" env:"TRANSCRIPTION_PROVIDER"`
+	Model    string   `yaml:"model" toml:"model" env:"TRANSCRIPTION_MODEL"`
+	URL      string   `yaml:"url" toml:"url" env:"TRANSCRIPTION_URL"`
+	APIKey   string   `yaml:"api_key" toml:"api_key" env:"TRANSCRIPTION_API_KEY"`
+	Timeout  Duration `yaml:"timeout" toml:"timeout" env:"TRANSCRIPTION_TIMEOUT"`
+}
+
+// CORS configures cross-origin access. Origins are exact ("https://app.example.com"),
+// subdomain wildcards ("https://*.example.com") or "*" for any origin.
+type CORS struct {
+	AllowedOrigins   []string `yaml:"allowed_origins" toml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string `yaml:"allowed_methods" toml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string `yaml:"allowed_headers" toml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string `yaml:"exposed_headers" toml:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           Duration `yaml:"max_age" toml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// Quota configures the per-user request quota; zero requests disables it
+type Quota struct {
+	Requests int      `yaml:"requests" toml:"requests" env:"QUOTA_REQUESTS"`
+	Window   Duration `yaml:"window" toml:"window" env:"QUOTA_WINDOW"`
+}
+
+// Plans sets the limits of the free and pro plans and the plan of users who
+// have not been assigned one. Limits are only enforced when Enabled; zero
+// limits are unlimited.
+type Plans struct {
+	Enabled             bool   `yaml:"enabled" toml:"enabled" env:"PLANS_ENABLED"`
+	Default             string `yaml:"default" toml:"default" env:"PLAN_DEFAULT"`
+	FreeTasks           int    `yaml:"free_tasks" toml:"free_tasks" env:"PLAN_FREE_TASKS"`
+	FreeAICallsPerDay   int    `yaml:"free_ai_calls_per_day" toml:"free_ai_calls_per_day" env:"PLAN_FREE_AI_CALLS_PER_DAY"`
+	FreeAttachmentBytes int    `yaml:"free_attachment_bytes" toml:"free_attachment_bytes" env:"PLAN_FREE_ATTACHMENT_BYTES"`
+	ProTasks            int    `yaml:"pro_tasks" toml:"pro_tasks" env:"PLAN_PRO_TASKS"`
+	ProAICallsPerDay    int    `yaml:"pro_ai_calls_per_day" toml:"pro_ai_calls_per_day" env:"PLAN_PRO_AI_CALLS_PER_DAY"`
+	ProAttachmentBytes  int    `yaml:"pro_attachment_bytes" toml:"pro_attachment_bytes" env:"PLAN_PRO_ATTACHMENT_BYTES"`
+}
+
+// Flags configures feature flags. Rules is a comma-separated list of
+// name=on, name=off or name=N% (a stable N% of users); Users is a
+// comma-separated list of name:user_id pairs who get a flag whatever its
+// rule. Rules saved in the feature_flags table override these and are
+// re-read every RefreshInterval.
+type Flags struct {
+	Rules           string   `yaml:"rules" toml:"rules" env:"FEATURE_FLAGS"`
+	Users           string   `yaml:"users" toml:"users" env:"FEATURE_FLAG_USERS"`
+	RefreshInterval Duration `yaml:"refresh_interval" toml:"refresh_interval" env:"FEATURE_FLAGS_REFRESH_INTERVAL"`
+}
+
+// MCP configures the MCP streamable HTTP transport
+type MCP struct {
+	// SessionIdleTimeout ends an Mcp-Session-Id session after this long without a request
+	SessionIdleTimeout Duration `yaml:"session_idle_timeout" toml:"session_idle_timeout" env:"MCP_SESSION_IDLE_TIMEOUT"`
+	// RequireDryRun makes tools that change data run only with the
+	// confirmation token from a dry run of the same call
+	RequireDryRun bool `yaml:"require_dry_run" toml:"require_dry_run" env:"MCP_REQUIRE_DRY_RUN"`
+	// DrainTimeout is how long shutdown waits for in-flight tool calls before
+	// cancelling them; it comes out of SERVER_SHUTDOWN_TIMEOUT
+	DrainTimeout Duration `yaml:"drain_timeout" toml:"drain_timeout" env:"MCP_DRAIN_TIMEOUT"`
+}
+
+// Streaks configures the default habit streak grace rules
+type Streaks struct {
+	FreezesPerWeek int  `yaml:"freezes_per_week" toml:"freezes_per_week" env:"STREAK_FREEZES_PER_WEEK"`
+	WeekendExempt  bool `yaml:"weekend_exempt" toml:"weekend_exempt" env:"STREAK_WEEKEND_EXEMPT"`
+}
+
+// Triggers configures the automation trigger API
+type Triggers struct {
+	// Tokens is a comma-separated list of token:user_id pairs
+	Tokens string `yaml:"tokens" toml:"tokens" env:"TRIGGER_TOKENS"`
+	File   string `yaml:"file" toml:"file" env:"TRIGGERS_FILE"`
+}
+
+// Email configures turning forwarded email into tasks. Each user forwards to
+// tasks+TOKEN@IngestDomain; the mail provider's inbound parse webhook posts
+// to /api/email/inbound?key=WebhookSecret. Both empty turns ingestion off.
+type Email struct {
+	IngestDomain  string `yaml:"ingest_domain" toml:"ingest_domain" env:"EMAIL_INGEST_DOMAIN"`
+	WebhookSecret string `yaml:"webhook_secret" toml:"webhook_secret" env:"EMAIL_WEBHOOK_SECRET"`
+}
+
+// Secrets configures encryption of the credentials users hand the server.
+// MasterKeys are base64-encoded 32-byte keys; the first seals, the rest only
+// open values sealed before they were rotated out. Without one the
+// integration endpoints answer 503.
+type Secrets struct {
+	MasterKeys []string `yaml:"master_keys" toml:"master_keys" env:"SECRETS_MASTER_KEYS"`
+}
+
+// Janitor configures the periodic purge of expired auth codes, sessions,
+// revoked tokens and old audit entries
+type Janitor struct {
+	Interval Duration `yaml:"interval" toml:"interval" env:"JANITOR_INTERVAL"`
+	// AuditRetention is how long audit entries are kept; zero keeps them forever
+	AuditRetention Duration `yaml:"audit_retention" toml:"audit_retention" env:"AUDIT_RETENTION"`
+}
+
+// Notion configures the Notion sync. Each user connects with their own
+// integration token; these settings apply to every user's sync.
+type Notion struct {
+	APIURL       string   `yaml:"api_url" toml:"api_url" env:"NOTION_API_URL"`
+	SyncInterval Duration `yaml:"sync_interval" toml:"sync_interval" env:"NOTION_SYNC_INTERVAL"`
+	Timeout      Duration `yaml:"timeout" toml:"timeout" env:"NOTION_TIMEOUT"`
+}
+
+// Events configures the optional external event publisher
+type Events struct {
+	Publisher         string `yaml:"publisher" toml:"publisher" env:"EVENT_PUBLISHER"`
+	NATSURL           string `yaml:"nats_url" toml:"nats_url" env:"NATS_URL"`
+	NATSSubjectPrefix string `yaml:"nats_subject_prefix" toml:"nats_subject_prefix" env:"NATS_SUBJECT_PREFIX"`
+	KafkaRESTURL      string `yaml:"kafka_rest_url" toml:"kafka_rest_url" env:"KAFKA_REST_URL"`
+	KafkaTopic        string `yaml:"kafka_topic" toml:"kafka_topic" env:"KAFKA_TOPIC"`
+}
+
+// Log configures logging. Sinks is any of stdout, file, syslog and http; each
+// sink buffers up to BufferSize entries and drops entries when full.
+type Log struct {
+	Level      string   `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	Sinks      []string `yaml:"sinks" toml:"sinks" env:"LOG_SINKS"`
+	BufferSize int      `yaml:"buffer_size" toml:"buffer_size" env:"LOG_BUFFER_SIZE"`
+
+	// RedactFields and RedactPatterns add field names and regular expressions
+	// to the secrets scrubbed from every log entry
+	RedactFields   []string `yaml:"redact_fields" toml:"redact_fields" env:"LOG_REDACT_FIELDS"`
+	RedactPatterns []string `yaml:"redact_patterns" toml:"redact_patterns" env:"LOG_REDACT_PATTERNS"`
+
+	// DebugOAuth traces each step of the OAuth flow at DEBUG level, for
+	// DebugOAuthSampleRate of requests
+	DebugOAuth           bool    `yaml:"debug_oauth" toml:"debug_oauth" env:"DEBUG_OAUTH"`
+	DebugOAuthSampleRate float64 `yaml:"debug_oauth_sample_rate" toml:"debug_oauth_sample_rate" env:"DEBUG_OAUTH_SAMPLE_RATE"`
+
+	File           string `yaml:"file" toml:"file" env:"LOG_FILE"`
+	FileMaxSizeMB  int    `yaml:"file_max_size_mb" toml:"file_max_size_mb" env:"LOG_FILE_MAX_SIZE_MB"`
+	FileMaxBackups int    `yaml:"file_max_backups" toml:"file_max_backups" env:"LOG_FILE_MAX_BACKUPS"`
+
+	SyslogNetwork string `yaml:"syslog_network" toml:"syslog_network" env:"LOG_SYSLOG_NETWORK"`
+	SyslogAddr    string `yaml:"syslog_addr" toml:"syslog_addr" env:"LOG_SYSLOG_ADDR"`
+	SyslogTag     string `yaml:"syslog_tag" toml:"syslog_tag" env:"LOG_SYSLOG_TAG"`
+
+	// HTTPURL receives batches of entries, e.g. http://loki:3100/loki/api/v1/push
+	HTTPURL    string `yaml:"http_url" toml:"http_url" env:"LOG_HTTP_URL"`
+	HTTPFormat string `yaml:"http_format" toml:"http_format" env:"LOG_HTTP_FORMAT"`
+}
+
+// SLO configures latency and error objectives for REST routes and MCP tools.
+// An alert fires when an objective's error budget burns faster than
+// BurnRateThreshold over both the short and the long window.
+type SLO struct {
+	Objectives        []SLOObjective `yaml:"objectives" toml:"objectives"`
+	ShortWindow       Duration       `yaml:"short_window" toml:"short_window" env:"SLO_SHORT_WINDOW"`
+	LongWindow        Duration       `yaml:"long_window" toml:"long_window" env:"SLO_LONG_WINDOW"`
+	BurnRateThreshold float64        `yaml:"burn_rate_threshold" toml:"burn_rate_threshold" env:"SLO_BURN_RATE_THRESHOLD"`
+	MinRequests       int            `yaml:"min_requests" toml:"min_requests" env:"SLO_MIN_REQUESTS"`
+	AlertWebhookURL   string         `yaml:"alert_webhook_url" toml:"alert_webhook_url" env:"SLO_ALERT_WEBHOOK_URL"`
+}
+
+// Webhooks configures the server's outgoing webhook deliveries. With a
+// SigningSecret each carries an X-Signature header that receivers check
+// with the webhook package.
+type Webhooks struct {
+	SigningSecret string `yaml:"signing_secret" toml:"signing_secret" env:"WEBHOOK_SIGNING_SECRET"`
+}
+
+// Lite configures the lite build (go build -tags lite): a single-user MCP
+// server on stdin/stdout backed by a local SQLite database. The Supabase
+// settings are not used by the lite build.
+type Lite struct {
+	Database string `yaml:"database" toml:"database" env:"LITE_DATABASE"` // defaults to productivity-mcp/productivity.db in the user config dir
+	UserID   string `yaml:"user_id" toml:"user_id" env:"LITE_USER_ID"`
+}
+
+// SLOObjective is the objective for one target: a REST route ("GET /api/tasks/:id"),
+// an MCP tool ("tool:create_task") or a prefix pattern ending in "*" ("tool:*", "*").
+// The most specific matching objective applies, and each target is tracked separately.
+type SLOObjective struct {
+	Target        string   `yaml:"target" toml:"target"`
+	Latency       Duration `yaml:"latency" toml:"latency"`               // slower requests count against the latency objective
+	LatencyTarget float64  `yaml:"latency_target" toml:"latency_target"` // fraction that must be faster than Latency
+	ErrorTarget   float64  `yaml:"error_target" toml:"error_target"`     // fraction that must succeed
+}
+
+// Record configures request/response recording for replay debugging; it is
+// refused in release mode because fixtures hold real request data
+type Record struct {
+	Dir          string   `yaml:"dir" toml:"dir" env:"RECORD_DIR"`
+	Routes       []string `yaml:"routes" toml:"routes" env:"RECORD_ROUTES"`
+	MaxBodyBytes int      `yaml:"max_body_bytes" toml:"max_body_bytes" env:"RECORD_MAX_BODY_BYTES"`
+}
+
+// Defaults returns the configuration used when nothing overrides it
+func Defaults() *Config {
+	return &Config{
+		Server: Server{
+			Port:                  "8080",
+			ReadTimeout:           Duration{15 * time.Second},
+			WriteTimeout:          Duration{15 * time.Second},
+			IdleTimeout:           Duration{60 * time.Second},
+			ShutdownTimeout:       Duration{30 * time.Second},
+			MaxBodyBytes:          1 << 20,
+			ParseFileMaxBodyBytes: 10 << 20,
+			Gzip:                  true,
+		},
+		Supabase: Supabase{
+			Timeout:             Duration{30 * time.Second},
+			MaxIdleConnsPerHost: 32,
+			IdleConnTimeout:     Duration{90 * time.Second},
+			HTTP2:               true,
+			Gzip:                true,
+			TaskCacheTTL:        Duration{30 * time.Second},
+			MaxRetries:          2,
+		},
+		Claude: Claude{
+			BaseURL:     "https://api.anthropic.com",
+			Model:       "claude-3-5-sonnet-20241022",
+			MaxTokens:   1024,
+			Temperature: 1,
+			MaxRetries:  3,
+			Timeout:     Duration{30 * time.Second},
+		},
+		Ollama: Ollama{
+			URL:   "http://localhost:11434",
+			Model: "qwen3-coder:480b-cloud",
+		},
+		Failover: Failover{
+			Threshold: 3,
+			Cooldown:  Duration{time.Minute},
+		},
+		Routing: Routing{
+			MaxTokensCap: 8192,
+		},
+		Embeddings: Embeddings{
+			Provider: "local",
+		},
+		Transcription: Transcription{
+			Provider: "none",
+			Timeout:  Duration{2 * time.Minute},
+		},
+		CORS: CORS{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token",
+				"Authorization", "Accept", "Origin", "Cache-Control", "X-Requested-With", "X-User-ID", "X-Request-ID",
+				"Mcp-Session-Id"},
+			ExposedHeaders: []string{"X-Request-ID", "X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset",
+				"X-Quota-Warning", "Retry-After", "Mcp-Session-Id"},
+			MaxAge: Duration{10 * time.Minute},
+		},
+		Quota: Quota{
+			Window: Duration{time.Hour},
+		},
+		Plans: Plans{
+			Default:             "free",
+			FreeTasks:           500,
+			FreeAICallsPerDay:   50,
+			FreeAttachmentBytes: 1 << 20,
+			ProAICallsPerDay:    1000,
+			ProAttachmentBytes:  10 << 20,
+		},
+		Flags: Flags{
+			RefreshInterval: Duration{time.Minute},
+		},
+		MCP: MCP{
+			SessionIdleTimeout: Duration{30 * time.Minute},
+			DrainTimeout:       Duration{20 * time.Second},
+		},
+		Streaks: Streaks{
+			FreezesPerWeek: 1,
+		},
+		Janitor: Janitor{
+			Interval:       Duration{time.Hour},
+			AuditRetention: Duration{365 * 24 * time.Hour},
+		},
+		Notion: Notion{
+			APIURL:       "https://api.notion.com",
+			SyncInterval: Duration{15 * time.Minute},
+			Timeout:      Duration{30 * time.Second},
+		},
+		Events: Events{
+			NATSSubjectPrefix: "productivity.events",
+			KafkaTopic:        "productivity-events",
+		},
+		SLO: SLO{
+			Objectives: []SLOObjective{
+				{Target: "*", Latency: Duration{time.Second}, LatencyTarget: 0.99, ErrorTarget: 0.995},
+				{Target: "tool:*", Latency: Duration{15 * time.Second}, LatencyTarget: 0.95, ErrorTarget: 0.99},
+			},
+			ShortWindow:       Duration{5 * time.Minute},
+			LongWindow:        Duration{time.Hour},
+			BurnRateThreshold: 14.4,
+			MinRequests:       20,
+		},
+		Lite: Lite{
+			UserID: "local",
+		},
+		Record: Record{
+			MaxBodyBytes: 64 << 10,
+		},
+		Log: Log{
+			Level:                "INFO",
+			Sinks:                []string{"stdout"},
+			BufferSize:           1024,
+			DebugOAuthSampleRate: 1,
+			File:                 "logs/server.log",
+			FileMaxSizeMB:        100,
+			FileMaxBackups:       5,
+			SyslogTag:            "productivity-mcp-server",
+			HTTPFormat:           "loki",
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the file at path (or the file
+// named by CONFIG_FILE when path is empty) and the environment, then validates
+// it. All problems are reported together in a *ValidationError.
+func Load(path string) (*Config, error) {
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookup func(string) (string, bool)) (*Config, error) {
+	cfg := Defaults()
+	var problems []string
+
+	if path == "" {
+		path, _ = lookup(FileEnv)
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+		cfg.File = path
+	}
+
+	problems = append(problems, applyEnv(reflect.ValueOf(cfg).Elem(), lookup)...)
+	cfg.normalize()
+	problems = append(problems, cfg.problems()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{File: cfg.File, Problems: problems, Config: cfg}
+	}
+
+	if cfg.Auth.JWTSecret == "" {
+		secret, err := randomSecret()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate development JWT secret: %w", err)
+		}
+		cfg.Auth.JWTSecret = secret
+		cfg.Auth.GeneratedSecret = true
+	}
+	return cfg, nil
+}
+
+// loadFile decodes a YAML (.yaml, .yml) or TOML (.toml) file over cfg
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalWithOptions(data, c, yaml.Strict())
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(c)
+	default:
+		return fmt.Errorf("unsupported config file extension %q (expected .yaml, .yml or .toml)", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides every field tagged with env that is set in the
+// environment, returning a problem for each value that fails to parse
+func applyEnv(v reflect.Value, lookup func(string) (string, bool)) []string {
+	var problems []string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			problems = append(problems, applyEnv(fv, lookup)...)
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setField(fv, strings.TrimSpace(raw)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	return problems
+}
+
+var durationType = reflect.TypeOf(Duration{})
+
+func setField(fv reflect.Value, raw string) error {
+	if fv.Type() == durationType {
+		var d Duration
+		if err := d.UnmarshalText([]byte(raw)); err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int:
+		if raw == "" {
+			fv.SetInt(0)
+			return nil
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		fv.SetInt(int64(n))
+	case reflect.Bool:
+		if raw == "" {
+			fv.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Float64:
+		if raw == "" {
+			fv.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		fv.Set(reflect.ValueOf(splitList(raw)))
+	default:
+		return fmt.Errorf("unsupported setting type %s", fv.Type())
+	}
+	return nil
+}
+
+// splitList parses a comma-separated list, dropping empty entries
+// flagNamePattern and flagValuePattern match the parts of a FEATURE_FLAGS entry
+var (
+	flagNamePattern  = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	flagValuePattern = regexp.MustCompile(`^(on|off|true|false|100%|[1-9]?[0-9]%)$`)
+)
+
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func (c *Config) normalize() {
+	c.Log.Level = strings.ToUpper(c.Log.Level)
+	for i, sink := range c.Log.Sinks {
+		c.Log.Sinks[i] = strings.ToLower(sink)
+	}
+	c.Log.HTTPFormat = strings.ToLower(c.Log.HTTPFormat)
+	c.Events.Publisher = strings.ToLower(c.Events.Publisher)
+	c.Supabase.URL = strings.TrimSuffix(c.Supabase.URL, "/")
+	c.Claude.BaseURL = strings.TrimSuffix(c.Claude.BaseURL, "/")
+	c.Embeddings.Provider = strings.ToLower(c.Embeddings.Provider)
+	c.Embeddings.URL = strings.TrimSuffix(c.Embeddings.URL, "/")
+	c.Transcription.Provider = strings.ToLower(c.Transcription.Provider)
+	c.Transcription.URL = strings.TrimSuffix(c.Transcription.URL, "/")
+	c.Email.IngestDomain = strings.ToLower(strings.TrimPrefix(c.Email.IngestDomain, "@"))
+	c.Notion.APIURL = strings.TrimSuffix(c.Notion.APIURL, "/")
+	c.Auth.Resource = strings.TrimSuffix(c.Auth.Resource, "/")
+	c.Plans.Default = strings.ToLower(strings.TrimSpace(c.Plans.Default))
+	if liteBuild && c.Lite.Database == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			c.Lite.Database = filepath.Join(dir, "productivity-mcp", "productivity.db")
+		}
+	}
+}
+
+// problems returns every invalid or missing setting, named by its env var
+func (c *Config) problems() []string {
+	var p []string
+	add := func(format string, args ...interface{}) {
+		p = append(p, fmt.Sprintf(format, args...))
+	}
+
+	if n, err := strconv.Atoi(c.Server.Port); err != nil || n < 1 || n > 65535 {
+		add("PORT: %q is not a valid port (1-65535)", c.Server.Port)
+	}
+	switch c.Server.GinMode {
+	case "", "debug", "release", "test":
+	default:
+		add("GIN_MODE: %q must be debug, release or test", c.Server.GinMode)
+	}
+	for _, d := range []struct {
+		name  string
+		value Duration
+	}{
+		{"SERVER_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout},
+		{"SUPABASE_TIMEOUT", c.Supabase.Timeout},
+		{"SUPABASE_IDLE_CONN_TIMEOUT", c.Supabase.IdleConnTimeout},
+		{"CLAUDE_TIMEOUT", c.Claude.Timeout},
+		{"TRANSCRIPTION_TIMEOUT", c.Transcription.Timeout},
+		{"JANITOR_INTERVAL", c.Janitor.Interval},
+		{"NOTION_SYNC_INTERVAL", c.Notion.SyncInterval},
+		{"NOTION_TIMEOUT", c.Notion.Timeout},
+		{"QUOTA_WINDOW", c.Quota.Window},
+		{"FEATURE_FLAGS_REFRESH_INTERVAL", c.Flags.RefreshInterval},
+		{"SLO_SHORT_WINDOW", c.SLO.ShortWindow},
+		{"SLO_LONG_WINDOW", c.SLO.LongWindow},
+	} {
+		if d.value.Duration <= 0 {
+			add("%s: must be a positive duration", d.name)
+		}
+	}
+	if c.Supabase.TaskCacheTTL.Duration < 0 {
+		add("SUPABASE_TASK_CACHE_TTL: must not be negative")
+	}
+	if c.Supabase.MaxRetries < 0 || c.Supabase.MaxRetries > 10 {
+		add("SUPABASE_MAX_RETRIES: must be between 0 and 10")
+	}
+
+	if liteBuild {
+		if c.Lite.Database == "" {
+			add("LITE_DATABASE: required (no user config directory to default to)")
+		}
+		if c.Lite.UserID == "" {
+			add("LITE_USER_ID: must not be empty")
+		}
+	} else {
+		if c.Supabase.URL == "" {
+			add("SUPABASE_URL: required")
+		} else if !isHTTPURL(c.Supabase.URL) {
+			add("SUPABASE_URL: %q is not an http(s) URL", c.Supabase.URL)
+		}
+		if c.Supabase.AnonKey == "" {
+			add("SUPABASE_ANON_KEY: required")
+		}
+		if c.Supabase.ServiceRoleKey == "" && c.Server.Release() {
+			add("SUPABASE_SERVICE_ROLE_KEY: required when GIN_MODE=release; API keys, OAuth clients and token revocations are only readable with it")
+		}
+		if c.Supabase.MaxIdleConnsPerHost < 1 {
+			add("SUPABASE_MAX_IDLE_CONNS_PER_HOST: must be at least 1")
+		}
+	}
+
+	if c.Auth.JWTSecret == "" && len(c.Auth.SigningKeys) == 0 && c.Server.Release() {
+		add("JWT_SECRET: required when GIN_MODE=release unless JWT_SIGNING_KEYS is set")
+	}
+	for _, file := range c.Auth.SigningKeys {
+		if _, err := os.Stat(file); err != nil {
+			add("JWT_SIGNING_KEYS: %v", err)
+		}
+	}
+	if c.Auth.Resource != "" && (!isHTTPURL(c.Auth.Resource) || strings.Contains(c.Auth.Resource, "#")) {
+		add("OAUTH_RESOURCE: %q is not an http(s) URL without a fragment", c.Auth.Resource)
+	}
+	for _, entry := range c.Auth.ClientCredentials {
+		clientID, scopes, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(clientID) == "" || strings.TrimSpace(scopes) == "" {
+			add("OAUTH_CLIENT_CREDENTIALS: %q is not client_id=scopes", entry)
+		}
+	}
+
+	if c.Record.Dir != "" {
+		if c.Server.Release() {
+			add("RECORD_DIR: recording is not allowed when GIN_MODE=release")
+		}
+		if len(c.Record.Routes) == 0 {
+			add("RECORD_ROUTES: required when RECORD_DIR is set (e.g. \"POST /api/tasks\", \"GET /api/*\" or \"*\")")
+		}
+	}
+
+	if !isHTTPURL(c.Claude.BaseURL) {
+		add("CLAUDE_BASE_URL: %q is not an http(s) URL", c.Claude.BaseURL)
+	}
+	if c.Claude.Model == "" {
+		add("CLAUDE_MODEL: must not be empty")
+	}
+	if c.Claude.MaxTokens < 1 {
+		add("CLAUDE_MAX_TOKENS: must be at least 1")
+	}
+	if c.Claude.Temperature < 0 || c.Claude.Temperature > 1 {
+		add("CLAUDE_TEMPERATURE: must be between 0 and 1")
+	}
+	if c.Claude.MaxRetries < 0 || c.Claude.MaxRetries > 10 {
+		add("CLAUDE_MAX_RETRIES: must be between 0 and 10")
+	}
+	if c.Claude.PromptsDir != "" {
+		if info, err := os.Stat(c.Claude.PromptsDir); err != nil || !info.IsDir() {
+			add("CLAUDE_PROMPTS_DIR: %q is not a directory", c.Claude.PromptsDir)
+		}
+	}
+
+	if c.Ollama.URL != "" && !isHTTPURL(c.Ollama.URL) {
+		add("OLLAMA_URL: %q is not an http(s) URL", c.Ollama.URL)
+	}
+	if c.Failover.Enabled {
+		if c.Ollama.URL == "" || c.Ollama.Model == "" {
+			add("LLM_FAILOVER: requires OLLAMA_URL and OLLAMA_MODEL")
+		}
+		if c.Failover.Threshold < 1 {
+			add("LLM_FAILOVER_THRESHOLD: must be at least 1")
+		}
+		if c.Failover.Cooldown.Duration <= 0 {
+			add("LLM_FAILOVER_COOLDOWN: must be positive")
+		}
+	}
+	for _, route := range c.Routing.Routes {
+		operation, model, ok := strings.Cut(route, "=")
+		if !ok || strings.TrimSpace(operation) == "" || strings.TrimSpace(model) == "" {
+			add("LLM_ROUTES: %q is not operation=model", route)
+		} else if strings.HasPrefix(strings.TrimSpace(model), "ollama:") && c.Ollama.URL == "" {
+			add("LLM_ROUTES: %q requires OLLAMA_URL", route)
+		}
+	}
+	for _, model := range c.Routing.AllowedModels {
+		if strings.ContainsAny(model, " =") || model == "ollama:" {
+			add("LLM_ALLOWED_MODELS: %q is not a model name", model)
+		}
+	}
+	if c.Routing.MaxTokensCap < c.Claude.MaxTokens {
+		add("LLM_MAX_TOKENS_CAP: must be at least CLAUDE_MAX_TOKENS (%d)", c.Claude.MaxTokens)
+	}
+
+	switch c.Embeddings.Provider {
+	case "local", "ollama":
+	case "openai", "voyage":
+		if c.Embeddings.APIKey == "" {
+			add("EMBEDDINGS_API_KEY: required for the %s provider", c.Embeddings.Provider)
+		}
+	default:
+		add("EMBEDDINGS_PROVIDER: %q must be local, openai, voyage or ollama", c.Embeddings.Provider)
+	}
+	if c.Embeddings.URL != "" && !isHTTPURL(c.Embeddings.URL) {
+		add("EMBEDDINGS_URL: %q is not an http(s) URL", c.Embeddings.URL)
+	}
+
+	switch c.Transcription.Provider {
+	case "none":
+	case "openai":
+		if c.Transcription.APIKey == "" {
+			add("TRANSCRIPTION_API_KEY: required for the openai provider")
+		}
+	case "whisper":
+		if c.Transcription.URL == "" {
+			add("TRANSCRIPTION_URL: required for the whisper provider")
+		}
+	default:
+		add("TRANSCRIPTION_PROVIDER: %q must be none, openai or whisper", c.Transcription.Provider)
+	}
+	if c.Transcription.URL != "" && !isHTTPURL(c.Transcription.URL) {
+		add("TRANSCRIPTION_URL: %q is not an http(s) URL", c.Transcription.URL)
+	}
+
+	if c.Email.IngestDomain != "" && c.Email.WebhookSecret == "" {
+		add("EMAIL_WEBHOOK_SECRET: required when EMAIL_INGEST_DOMAIN is set")
+	}
+	if c.Email.WebhookSecret != "" && c.Email.IngestDomain == "" {
+		add("EMAIL_INGEST_DOMAIN: required when EMAIL_WEBHOOK_SECRET is set")
+	}
+	if c.Email.IngestDomain != "" && strings.ContainsAny(c.Email.IngestDomain, "@/ ") {
+		add("EMAIL_INGEST_DOMAIN: %q is not a domain", c.Email.IngestDomain)
+	}
+
+	for i, encoded := range c.Secrets.MasterKeys {
+		if key, err := base64.StdEncoding.DecodeString(encoded); err != nil || len(key) != 32 {
+			add("SECRETS_MASTER_KEYS: key %d must be 32 bytes, base64-encoded (openssl rand -base64 32)", i+1)
+		}
+	}
+	if !isHTTPURL(c.Notion.APIURL) {
+		add("NOTION_API_URL: %q is not an http(s) URL", c.Notion.APIURL)
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				add("CORS_ALLOW_CREDENTIALS: cannot be combined with * in CORS_ALLOWED_ORIGINS; list explicit origins")
+			}
+			continue
+		}
+		if !isHTTPURL(origin) {
+			add("CORS_ALLOWED_ORIGINS: %q must be * or an http(s) origin", origin)
+		} else if u, _ := url.Parse(origin); u.Path != "" && u.Path != "/" {
+			add("CORS_ALLOWED_ORIGINS: %q must not include a path", origin)
+		}
+	}
+	if len(c.CORS.AllowedMethods) == 0 {
+		add("CORS_ALLOWED_METHODS: must list at least one method")
+	}
+	if c.CORS.MaxAge.Duration < 0 {
+		add("CORS_MAX_AGE: must not be negative")
+	}
+
+	if c.Server.MaxBodyBytes <= 0 {
+		add("SERVER_MAX_BODY_BYTES: must be positive")
+	}
+	if c.Server.ParseFileMaxBodyBytes <= 0 {
+		add("SERVER_PARSE_FILE_MAX_BODY_BYTES: must be positive")
+	}
+
+	if c.Quota.Requests < 0 {
+		add("QUOTA_REQUESTS: must not be negative")
+	}
+
+	if c.Plans.Default != "free" && c.Plans.Default != "pro" {
+		add("PLAN_DEFAULT: must be free or pro")
+	}
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{
+		{"PLAN_FREE_TASKS", c.Plans.FreeTasks},
+		{"PLAN_FREE_AI_CALLS_PER_DAY", c.Plans.FreeAICallsPerDay},
+		{"PLAN_FREE_ATTACHMENT_BYTES", c.Plans.FreeAttachmentBytes},
+		{"PLAN_PRO_TASKS", c.Plans.ProTasks},
+		{"PLAN_PRO_AI_CALLS_PER_DAY", c.Plans.ProAICallsPerDay},
+		{"PLAN_PRO_ATTACHMENT_BYTES", c.Plans.ProAttachmentBytes},
+	} {
+		if limit.value < 0 {
+			add("%s: must not be negative (0 is unlimited)", limit.name)
+		}
+	}
+
+	if c.Janitor.AuditRetention.Duration < 0 {
+		add("AUDIT_RETENTION: must not be negative")
+	}
+	if c.MCP.SessionIdleTimeout.Duration <= 0 {
+		add("MCP_SESSION_IDLE_TIMEOUT: must be positive")
+	}
+	if c.MCP.DrainTimeout.Duration < 0 || c.MCP.DrainTimeout.Duration > c.Server.ShutdownTimeout.Duration {
+		add("MCP_DRAIN_TIMEOUT: must be between 0 and SERVER_SHUTDOWN_TIMEOUT")
+	}
+
+	if c.Streaks.FreezesPerWeek < 0 || c.Streaks.FreezesPerWeek > 7 {
+		add("STREAK_FREEZES_PER_WEEK: must be between 0 and 7")
+	}
+
+	for _, entry := range splitList(c.Flags.Rules) {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !flagNamePattern.MatchString(strings.TrimSpace(name)) || !flagValuePattern.MatchString(strings.ToLower(strings.TrimSpace(value))) {
+			add("FEATURE_FLAGS: %q must be name=on, name=off or name=N%% with N from 0 to 100", entry)
+		}
+	}
+	for _, pair := range splitList(c.Flags.Users) {
+		if name, user, ok := strings.Cut(pair, ":"); !ok || !flagNamePattern.MatchString(strings.TrimSpace(name)) || strings.TrimSpace(user) == "" {
+			add("FEATURE_FLAG_USERS: entries must be name:user_id pairs")
+		}
+	}
+
+	for _, pair := range splitList(c.Triggers.Tokens) {
+		if token, user, ok := strings.Cut(pair, ":"); !ok || strings.TrimSpace(token) == "" || strings.TrimSpace(user) == "" {
+			add("TRIGGER_TOKENS: entries must be token:user_id pairs")
+			break
+		}
+	}
+	if c.Triggers.File != "" {
+		if _, err := os.Stat(c.Triggers.File); err != nil {
+			add("TRIGGERS_FILE: %v", err)
+		}
+	}
+
+	switch c.Events.Publisher {
+	case "":
+	case "nats":
+		if c.Events.NATSURL == "" {
+			add("NATS_URL: required when EVENT_PUBLISHER=nats")
+		}
+	case "kafka":
+		if c.Events.KafkaRESTURL == "" {
+			add("KAFKA_REST_URL: required when EVENT_PUBLISHER=kafka")
+		}
+	default:
+		add("EVENT_PUBLISHER: %q must be nats or kafka", c.Events.Publisher)
+	}
+
+	switch c.Log.Level {
+	case "DEBUG", "INFO", "WARN", "ERROR":
+	default:
+		add("LOG_LEVEL: %q must be DEBUG, INFO, WARN or ERROR", c.Log.Level)
+	}
+	if c.Log.DebugOAuth && c.Log.Level != "DEBUG" {
+		add("DEBUG_OAUTH: traces log at DEBUG, so LOG_LEVEL must be DEBUG")
+	}
+	if c.Log.DebugOAuthSampleRate < 0 || c.Log.DebugOAuthSampleRate > 1 {
+		add("DEBUG_OAUTH_SAMPLE_RATE: must be between 0 and 1")
+	}
+	for _, pattern := range c.Log.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			add("LOG_REDACT_PATTERNS: %q is not a valid regular expression", pattern)
+		}
+	}
+	if len(c.Log.Sinks) == 0 {
+		add("LOG_SINKS: must list at least one of stdout, file, syslog, http")
+	}
+	for _, sink := range c.Log.Sinks {
+		switch sink {
+		case "stdout", "syslog":
+		case "file":
+			if c.Log.File == "" {
+				add("LOG_FILE: required when LOG_SINKS includes file")
+			}
+			if c.Log.FileMaxSizeMB < 1 {
+				add("LOG_FILE_MAX_SIZE_MB: must be at least 1")
+			}
+			if c.Log.FileMaxBackups < 0 {
+				add("LOG_FILE_MAX_BACKUPS: must not be negative")
+			}
+		case "http":
+			if !isHTTPURL(c.Log.HTTPURL) {
+				add("LOG_HTTP_URL: required http(s) URL when LOG_SINKS includes http")
+			}
+			if c.Log.HTTPFormat != "loki" && c.Log.HTTPFormat != "json" {
+				add("LOG_HTTP_FORMAT: %q must be loki or json", c.Log.HTTPFormat)
+			}
+		default:
+			add("LOG_SINKS: unknown sink %q (expected stdout, file, syslog or http)", sink)
+		}
+	}
+	if c.Log.BufferSize < 1 {
+		add("LOG_BUFFER_SIZE: must be at least 1")
+	}
+
+	if c.SLO.ShortWindow.Duration >= c.SLO.LongWindow.Duration {
+		add("SLO_SHORT_WINDOW: must be shorter than SLO_LONG_WINDOW")
+	}
+	if c.SLO.BurnRateThreshold <= 0 {
+		add("SLO_BURN_RATE_THRESHOLD: must be positive")
+	}
+	if c.SLO.MinRequests < 0 {
+		add("SLO_MIN_REQUESTS: must not be negative")
+	}
+	if c.SLO.AlertWebhookURL != "" && !isHTTPURL(c.SLO.AlertWebhookURL) {
+		add("SLO_ALERT_WEBHOOK_URL: %q is not an http(s) URL", c.SLO.AlertWebhookURL)
+	}
+	for i, o := range c.SLO.Objectives {
+		name := fmt.Sprintf("slo.objectives[%d]", i)
+		if o.Target == "" {
+			add("%s.target: required", name)
+		}
+		if o.Latency.Duration <= 0 {
+			add("%s.latency: must be a positive duration", name)
+		}
+		if o.LatencyTarget <= 0 || o.LatencyTarget >= 1 {
+			add("%s.latency_target: must be between 0 and 1 (exclusive)", name)
+		}
+		if o.ErrorTarget <= 0 || o.ErrorTarget >= 1 {
+			add("%s.error_target: must be between 0 and 1 (exclusive)", name)
+		}
+	}
+
+	return p
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func randomSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// ValidationError lists every configuration problem found at startup
+type ValidationError struct {
+	File     string
+	Problems []string
+
+	// Config is the configuration as loaded despite the problems, for diagnostics
+	Config *Config
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration")
+	if e.File != "" {
+		fmt.Fprintf(&b, " (file %s)", e.File)
+	}
+	fmt.Fprintf(&b, ": %d problem(s)", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// Duration is a time.Duration written as a Go duration string ("15s", "1h")
+// in config files and environment variables
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a Go duration string
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("%q is not a duration (e.g. 30s, 5m, 1h)", string(text))
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalText formats the duration as a Go duration string
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
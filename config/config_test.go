@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+var requiredEnv = map[string]string{
+	"SUPABASE_URL":      "https://example.supabase.co",
+	"SUPABASE_ANON_KEY": "anon",
+}
+
+func withEnv(extra map[string]string) map[string]string {
+	env := map[string]string{}
+	for k, v := range requiredEnv {
+		env[k] = v
+	}
+	for k, v := range extra {
+		env[k] = v
+	}
+	return env
+}
+
+func TestLoadDefaultsAndEnv(t *testing.T) {
+	cfg, err := load("", envLookup(withEnv(map[string]string{
+		"PORT":                  "9000",
+		"QUOTA_REQUESTS":        "100",
+		"QUOTA_WINDOW":          "30m",
+		"ADMIN_USER_IDS":        "a, b,,c",
+		"STREAK_WEEKEND_EXEMPT": "true",
+		"LOG_LEVEL":             "debug",
+	})))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if cfg.Server.Port != "9000" || cfg.Server.ReadTimeout.Duration != 15*time.Second {
+		t.Errorf("server = %+v", cfg.Server)
+	}
+	if cfg.Quota.Requests != 100 || cfg.Quota.Window.Duration != 30*time.Minute {
+		t.Errorf("quota = %+v", cfg.Quota)
+	}
+	if got := strings.Join(cfg.Auth.AdminUserIDs, "|"); got != "a|b|c" {
+		t.Errorf("admin ids = %q", got)
+	}
+	if !cfg.Streaks.WeekendExempt || cfg.Streaks.FreezesPerWeek != 1 {
+		t.Errorf("streaks = %+v", cfg.Streaks)
+	}
+	if cfg.Log.Level != "DEBUG" {
+		t.Errorf("log level = %q", cfg.Log.Level)
+	}
+	if cfg.Auth.JWTSecret == "" || !cfg.Auth.GeneratedSecret {
+		t.Error("expected a generated development JWT secret")
+	}
+}
+
+func TestLoadReportsAllProblems(t *testing.T) {
+	_, err := load("", envLookup(map[string]string{
+		"GIN_MODE":        "release",
+		"PORT":            "http",
+		"QUOTA_REQUESTS":  "lots",
+		"EVENT_PUBLISHER": "nats",
+
+		"CORS_ALLOW_CREDENTIALS": "true",
+		"EMAIL_INGEST_DOMAIN":    "in.example.com",
+
+		"SECRETS_MASTER_KEYS":   "c2hvcnQ=",
+		"MCP_DRAIN_TIMEOUT":     "1h",
+		"DEBUG_OAUTH":           "true",
+		"LLM_FAILOVER":          "true",
+		"LLM_FAILOVER_COOLDOWN": "0s",
+		"LLM_ROUTES":            "parse_task",
+		"LLM_MAX_TOKENS_CAP":    "100",
+	}))
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	wants := []string{
+		"QUOTA_REQUESTS:",
+		"PORT:",
+		"JWT_SECRET: required",
+		"NATS_URL: required",
+		"CORS_ALLOW_CREDENTIALS: cannot be combined with *",
+		"EMAIL_WEBHOOK_SECRET: required",
+		"SECRETS_MASTER_KEYS: key 1 must be 32 bytes",
+		"MCP_DRAIN_TIMEOUT:",
+		"DEBUG_OAUTH:",
+		"LLM_FAILOVER_COOLDOWN:",
+		"LLM_ROUTES:",
+		"LLM_MAX_TOKENS_CAP:",
+	}
+	if !liteBuild {
+		wants = append(wants, "SUPABASE_URL: required", "SUPABASE_ANON_KEY: required")
+	}
+	for _, want := range wants {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("report missing %q:\n%s", want, err)
+		}
+	}
+}
+
+func TestLoadFileWithEnvOverride(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": `
+server:
+  port: "7000"
+  write_timeout: 45s
+claude:
+  model: claude-test
+cors:
+  allowed_origins: ["https://app.example.com"]
+`,
+		"config.toml": `
+[server]
+port = "7000"
+write_timeout = "45s"
+
+[claude]
+model = "claude-test"
+
+[cors]
+allowed_origins = ["https://app.example.com"]
+`,
+	}
+
+	for name, body := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := load("", envLookup(withEnv(map[string]string{
+				FileEnv: path,
+				"PORT":  "7001",
+			})))
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if cfg.File != path {
+				t.Errorf("file = %q", cfg.File)
+			}
+			if cfg.Server.Port != "7001" {
+				t.Errorf("env should override file port, got %q", cfg.Server.Port)
+			}
+			if cfg.Server.WriteTimeout.Duration != 45*time.Second {
+				t.Errorf("write timeout = %v", cfg.Server.WriteTimeout)
+			}
+			if cfg.Claude.Model != "claude-test" || cfg.Claude.MaxTokens != 1024 {
+				t.Errorf("claude = %+v", cfg.Claude)
+			}
+			if len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "https://app.example.com" {
+				t.Errorf("origins = %v", cfg.CORS.AllowedOrigins)
+			}
+		})
+	}
+}
+
+func TestLoadFileRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  prot: \"80\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load(path, envLookup(requiredEnv)); err == nil {
+		t.Fatal("expected unknown key to be rejected")
+	}
+}
@@ -0,0 +1,410 @@
+// Package dates resolves English date and time phrases in task text, such as
+// "tomorrow 5pm", "next Friday" or "in 2 weeks", without a language model.
+// Phrases are resolved against a reference time in the user's time zone, so
+// the same input always gives the same answer.
+package dates
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHour is the time of day given to a date named without one, so a
+// task due "Friday" is due by the end of the working day
+const DefaultHour = 17
+
+// Options adapt parsing to a user's calendar
+type Options struct {
+	// WeekStart is the first day of the week, which "next week" and "next
+	// Friday" count from
+	WeekStart time.Weekday
+	// DefaultHour and DefaultMinute are the time given to a date named
+	// without one, such as the end of the user's working day
+	DefaultHour, DefaultMinute int
+}
+
+// DefaultOptions are used by Parse: weeks start on Monday and dates without
+// a time are due at DefaultHour
+var DefaultOptions = Options{WeekStart: time.Monday, DefaultHour: DefaultHour}
+
+// Match is a date found in text
+type Match struct {
+	// Time is the resolved date and time, in the reference time's location
+	Time time.Time
+	// HasTime reports whether the text named a time of day; otherwise Time is
+	// at the default hour
+	HasTime bool
+	// Text is the phrase that named the date, as written
+	Text string
+
+	spans [][2]int
+}
+
+// Strip returns text without the matched phrase, or a connecting word such as
+// "by" or "on" just before it, so "Call Sam tomorrow at 5pm" leaves "Call Sam"
+func (m Match) Strip(text string) string {
+	lower := asciiLower(text)
+	var b strings.Builder
+	last := 0
+	for _, span := range m.spans {
+		start := span[0]
+		if loc := connector.FindStringIndex(lower[last:start]); loc != nil {
+			start = last + loc[0]
+		}
+		b.WriteString(text[last:start])
+		b.WriteByte(' ')
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	return strings.Trim(strings.Join(strings.Fields(b.String()), " "), " ,.;:-")
+}
+
+// Parse finds the first date phrase in text and resolves it relative to now.
+// A time of day alone means its next occurrence; a date alone gets
+// DefaultHour. It reports false when text names no date.
+func Parse(text string, now time.Time) (Match, bool) {
+	return ParseWith(text, now, DefaultOptions)
+}
+
+// ParseWith is Parse using opts in place of DefaultOptions
+func ParseWith(text string, now time.Time, opts Options) (Match, bool) {
+	lower := asciiLower(text)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	ref := reference{now: now, today: today, weekStart: opts.WeekStart}
+
+	date, hasDate := firstPhrase(lower, datePatterns)
+	clock, hasClock := firstPhrase(lower, timePatterns)
+	if hasDate && hasClock && overlaps(date, clock) {
+		hasClock = false
+	}
+	if !hasDate && !hasClock {
+		return Match{}, false
+	}
+
+	var m Match
+	switch {
+	case hasDate && date.exact:
+		// "in 2 hours" names the time itself
+		m.Time, m.HasTime = date.resolve(ref), true
+		hasClock = false
+	case hasDate && hasClock:
+		day := date.resolve(ref)
+		m.Time, m.HasTime = atClock(day, clock.hour, clock.minute), true
+	case hasDate:
+		day := date.resolve(ref)
+		if date.hour >= 0 {
+			m.Time, m.HasTime = atClock(day, date.hour, date.minute), true
+		} else {
+			m.Time = atClock(day, opts.DefaultHour, opts.DefaultMinute)
+		}
+	default:
+		m.Time, m.HasTime = atClock(today, clock.hour, clock.minute), true
+		if m.Time.Before(now) {
+			m.Time = m.Time.AddDate(0, 0, 1)
+		}
+	}
+
+	if hasDate {
+		m.spans = append(m.spans, [2]int{date.start, date.end})
+	}
+	if hasClock {
+		m.spans = append(m.spans, [2]int{clock.start, clock.end})
+	}
+	sort.Slice(m.spans, func(i, j int) bool { return m.spans[i][0] < m.spans[j][0] })
+	texts := make([]string, len(m.spans))
+	for i, span := range m.spans {
+		texts[i] = text[span[0]:span[1]]
+	}
+	m.Text = strings.Join(texts, " ")
+	return m, true
+}
+
+// phrase is a date or time phrase found in text
+type phrase struct {
+	start, end int
+	// resolve gives the day, or for exact phrases the instant, the phrase names
+	resolve func(r reference) time.Time
+	// exact phrases ("in 3 hours") name an instant rather than a day
+	exact bool
+	// hour and minute are the time of day a phrase names, or -1
+	hour, minute int
+}
+
+// reference is what phrases are resolved against
+type reference struct {
+	now, today time.Time
+	weekStart  time.Weekday
+}
+
+// pattern recognises one kind of phrase; build returns false when the
+// submatches do not name a real date or time
+type pattern struct {
+	re    *regexp.Regexp
+	build func(m []string) (phrase, bool)
+}
+
+// firstPhrase returns the earliest phrase any pattern finds, preferring the longest
+func firstPhrase(lower string, patterns []pattern) (phrase, bool) {
+	var best phrase
+	found := false
+	for _, p := range patterns {
+		for _, loc := range p.re.FindAllStringSubmatchIndex(lower, -1) {
+			subs := make([]string, len(loc)/2)
+			for i := range subs {
+				if loc[2*i] >= 0 {
+					subs[i] = lower[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			ph, ok := p.build(subs)
+			if !ok {
+				continue
+			}
+			ph.start, ph.end = loc[0], loc[1]
+			if !found || ph.start < best.start || (ph.start == best.start && ph.end > best.end) {
+				best, found = ph, true
+			}
+			break
+		}
+	}
+	return best, found
+}
+
+func overlaps(a, b phrase) bool {
+	return a.start < b.end && b.start < a.end
+}
+
+func atClock(day time.Time, hour, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+}
+
+// day returns a phrase naming a day found by f
+func day(f func(r reference) time.Time) phrase {
+	return phrase{resolve: f, hour: -1, minute: -1}
+}
+
+// dayAt returns a phrase naming a day and a time of day, such as "tonight"
+func dayAt(f func(r reference) time.Time, hour, minute int) phrase {
+	return phrase{resolve: f, hour: hour, minute: minute}
+}
+
+// clockAt returns a phrase naming only a time of day
+func clockAt(hour, minute int) phrase {
+	return phrase{hour: hour, minute: minute}
+}
+
+// connector matches a word joining a date phrase to the rest of the text
+var connector = regexp.MustCompile(`\b(?:due|by|on|at|before|until|for)\s+$`)
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+var numberWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "a couple of": 2, "a few": 3,
+}
+
+const (
+	weekdayNames = `(sunday|monday|tuesday|wednesday|thursday|friday|saturday)`
+	monthNames   = `(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)(?:uary|ruary|ch|il|e|y|ust|t|tember|ober|ember)?\.?`
+	ordinal      = `(\d{1,2})(?:st|nd|rd|th)?`
+	countWords   = `(\d+|a couple of|a few|an?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)`
+)
+
+// datePatterns name a day, and sometimes a time with it
+var datePatterns = []pattern{
+	{regexp.MustCompile(`\b(?:the\s+)?day\s+after\s+tomorrow\b`), func([]string) (phrase, bool) {
+		return day(func(r reference) time.Time { return r.today.AddDate(0, 0, 2) }), true
+	}},
+	{regexp.MustCompile(`\b(?:tomorrow|tmrw)\b`), func([]string) (phrase, bool) {
+		return day(func(r reference) time.Time { return r.today.AddDate(0, 0, 1) }), true
+	}},
+	{regexp.MustCompile(`\btoday\b`), func([]string) (phrase, bool) {
+		return day(func(r reference) time.Time { return r.today }), true
+	}},
+	{regexp.MustCompile(`\btonight\b`), func([]string) (phrase, bool) {
+		return dayAt(func(r reference) time.Time { return r.today }, 20, 0), true
+	}},
+	{regexp.MustCompile(`\bin\s+` + countWords + `\s+(minute|hour|day|week|month|year)s?\b`), func(m []string) (phrase, bool) {
+		n, ok := numberWords[m[1]]
+		if !ok {
+			var err error
+			if n, err = strconv.Atoi(m[1]); err != nil {
+				return phrase{}, false
+			}
+		}
+		switch m[2] {
+		case "minute", "hour":
+			unit := time.Minute
+			if m[2] == "hour" {
+				unit = time.Hour
+			}
+			return phrase{resolve: func(r reference) time.Time { return r.now.Add(time.Duration(n) * unit).Truncate(time.Minute) }, exact: true}, true
+		case "day":
+			return day(func(r reference) time.Time { return r.today.AddDate(0, 0, n) }), true
+		case "week":
+			return day(func(r reference) time.Time { return r.today.AddDate(0, 0, 7*n) }), true
+		case "month":
+			return day(func(r reference) time.Time { return r.today.AddDate(0, n, 0) }), true
+		}
+		return day(func(r reference) time.Time { return r.today.AddDate(n, 0, 0) }), true
+	}},
+	{regexp.MustCompile(`\b(this|next)\s+weekend\b`), func(m []string) (phrase, bool) {
+		return day(func(r reference) time.Time {
+			saturday := nextWeekday(r.today, time.Saturday, true)
+			if m[1] == "next" && r.today.Weekday() != time.Sunday {
+				saturday = saturday.AddDate(0, 0, 7)
+			}
+			return saturday
+		}), true
+	}},
+	{regexp.MustCompile(`\bnext\s+(week|month|year)\b`), func(m []string) (phrase, bool) {
+		return day(func(r reference) time.Time {
+			switch m[1] {
+			case "week":
+				return weekStart(r.today, r.weekStart).AddDate(0, 0, 7)
+			case "month":
+				return time.Date(r.today.Year(), r.today.Month()+1, 1, 0, 0, 0, 0, r.today.Location())
+			}
+			return time.Date(r.today.Year()+1, time.January, 1, 0, 0, 0, 0, r.today.Location())
+		}), true
+	}},
+	{regexp.MustCompile(`\b(?:(this|next)\s+)?` + weekdayNames + `\b`), func(m []string) (phrase, bool) {
+		want := weekdays[m[2]]
+		return day(func(r reference) time.Time {
+			switch m[1] {
+			case "next":
+				// The named day of next week
+				return weekStart(r.today, r.weekStart).AddDate(0, 0, 7+daysFrom(r.weekStart, want))
+			case "this":
+				return nextWeekday(r.today, want, true)
+			}
+			return nextWeekday(r.today, want, false)
+		}), true
+	}},
+	{regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`), func(m []string) (phrase, bool) {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		dayOfMonth, _ := strconv.Atoi(m[3])
+		return calendarDay(year, time.Month(month), dayOfMonth)
+	}},
+	{regexp.MustCompile(`\b` + monthNames + `\s+` + ordinal + `(?:,?\s+(\d{4}))?\b`), func(m []string) (phrase, bool) {
+		return monthDay(m[1], m[2], m[3])
+	}},
+	{regexp.MustCompile(`\b` + ordinal + `\s+(?:of\s+)?` + monthNames + `(?:,?\s+(\d{4}))?\b`), func(m []string) (phrase, bool) {
+		return monthDay(m[2], m[1], m[3])
+	}},
+}
+
+// timePatterns name a time of day
+var timePatterns = []pattern{
+	{regexp.MustCompile(`\b(?:at\s+)?(\d{1,2})(?::([0-5]\d))?\s*(a\.m\.|p\.m\.|am\b|pm\b)`), func(m []string) (phrase, bool) {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour < 1 || hour > 12 {
+			return phrase{}, false
+		}
+		hour %= 12
+		if strings.HasPrefix(m[3], "p") {
+			hour += 12
+		}
+		return clockAt(hour, minute), true
+	}},
+	{regexp.MustCompile(`\b(?:at\s+)?([01]?\d|2[0-3]):([0-5]\d)\b`), func(m []string) (phrase, bool) {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		return clockAt(hour, minute), true
+	}},
+	{regexp.MustCompile(`\bat\s+(\d{1,2})\b`), func(m []string) (phrase, bool) {
+		// A bare hour is read as working or evening hours: "at 3" is 15:00, "at 9" is 09:00
+		hour, _ := strconv.Atoi(m[1])
+		switch {
+		case hour < 1 || hour > 12:
+			return phrase{}, false
+		case hour < 8:
+			hour += 12
+		}
+		return clockAt(hour, 0), true
+	}},
+	{regexp.MustCompile(`\b(?:noon|midday)\b`), func([]string) (phrase, bool) { return clockAt(12, 0), true }},
+	{regexp.MustCompile(`\bmidnight\b`), func([]string) (phrase, bool) { return clockAt(23, 59), true }},
+	{regexp.MustCompile(`\b(?:end\s+of\s+(?:the\s+)?day|eod)\b`), func([]string) (phrase, bool) { return clockAt(17, 0), true }},
+	{regexp.MustCompile(`\b(?:in\s+the\s+|this\s+)?(morning|afternoon|evening)\b`), func(m []string) (phrase, bool) {
+		hours := map[string]int{"morning": 9, "afternoon": 15, "evening": 18}
+		return clockAt(hours[m[1]], 0), true
+	}},
+}
+
+// calendarDay is a phrase naming a fixed date, rejecting ones that do not exist
+func calendarDay(year int, month time.Month, dayOfMonth int) (phrase, bool) {
+	probe := time.Date(year, month, dayOfMonth, 0, 0, 0, 0, time.UTC)
+	if probe.Month() != month || probe.Day() != dayOfMonth {
+		return phrase{}, false
+	}
+	return day(func(r reference) time.Time {
+		return time.Date(year, month, dayOfMonth, 0, 0, 0, 0, r.today.Location())
+	}), true
+}
+
+// monthDay is a phrase like "March 14" or "14th of March". Without a year it
+// means the next such day, today included.
+func monthDay(monthName, dayText, yearText string) (phrase, bool) {
+	month := months[monthName]
+	dayOfMonth, _ := strconv.Atoi(dayText)
+	if yearText != "" {
+		year, _ := strconv.Atoi(yearText)
+		return calendarDay(year, month, dayOfMonth)
+	}
+	if _, ok := calendarDay(2024, month, dayOfMonth); !ok {
+		return phrase{}, false
+	}
+	return day(func(r reference) time.Time {
+		for year := r.today.Year(); ; year++ {
+			d := time.Date(year, month, dayOfMonth, 0, 0, 0, 0, r.today.Location())
+			// February 29 only exists in leap years
+			if d.Day() == dayOfMonth && !d.Before(r.today) {
+				return d
+			}
+		}
+	}), true
+}
+
+// nextWeekday returns the next day named want after today, or today itself if
+// includeToday is set and it matches
+func nextWeekday(today time.Time, want time.Weekday, includeToday bool) time.Time {
+	ahead := (int(want) - int(today.Weekday()) + 7) % 7
+	if ahead == 0 && !includeToday {
+		ahead = 7
+	}
+	return today.AddDate(0, 0, ahead)
+}
+
+// weekStart returns the first day of today's week, for weeks starting on first
+func weekStart(today time.Time, first time.Weekday) time.Time {
+	return today.AddDate(0, 0, -daysFrom(first, today.Weekday()))
+}
+
+// daysFrom counts the days from first to d within a week
+func daysFrom(first, d time.Weekday) int {
+	return (int(d) - int(first) + 7) % 7
+}
+
+// asciiLower lowercases ASCII letters only, keeping byte offsets valid for the original text
+func asciiLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
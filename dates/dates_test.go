@@ -0,0 +1,109 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data unavailable:", err)
+	}
+	// Wednesday 12 March 2025, 10:30 in Berlin
+	now := time.Date(2025, 3, 12, 10, 30, 0, 0, berlin)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, berlin)
+	}
+
+	cases := []struct {
+		text    string
+		want    time.Time
+		hasTime bool
+		matched string
+		rest    string
+	}{
+		{"Call Sam tomorrow at 5pm", at(3, 13, 17, 0), true, "tomorrow at 5pm", "Call Sam"},
+		{"5:30 pm tomorrow: dentist", at(3, 13, 17, 30), true, "5:30 pm tomorrow", "dentist"},
+		{"Submit report by next Friday", at(3, 21, 17, 0), false, "next Friday", "Submit report"},
+		{"Submit report Friday", at(3, 14, 17, 0), false, "Friday", "Submit report"},
+		{"Team sync on Wednesday at 9:15", at(3, 19, 9, 15), true, "Wednesday at 9:15", "Team sync"},
+		{"Review this Wednesday", at(3, 12, 17, 0), false, "this Wednesday", "Review"},
+		{"Renew passport in 2 weeks", at(3, 26, 17, 0), false, "in 2 weeks", "Renew passport"},
+		{"Check the oven in 45 minutes", at(3, 12, 11, 15), true, "in 45 minutes", "Check the oven"},
+		{"Pay rent in a couple of days", at(3, 14, 17, 0), false, "in a couple of days", "Pay rent"},
+		{"Taxes due April 15th", at(4, 15, 17, 0), false, "April 15th", "Taxes"},
+		{"Anniversary 3rd of March", time.Date(2026, 3, 3, 17, 0, 0, 0, berlin), false, "3rd of March", "Anniversary"},
+		{"Launch on 2025-06-01 at 08:00", at(6, 1, 8, 0), true, "2025-06-01 at 08:00", "Launch"},
+		{"Lunch with Ana at noon", at(3, 12, 12, 0), true, "noon", "Lunch with Ana"},
+		{"Stand-up at 9", at(3, 13, 9, 0), true, "at 9", "Stand-up"},
+		{"Call back at 3", at(3, 12, 15, 0), true, "at 3", "Call back"},
+		{"Water plants tonight", at(3, 12, 20, 0), true, "tonight", "Water plants"},
+		{"Gym tomorrow morning", at(3, 13, 9, 0), true, "tomorrow morning", "Gym"},
+		{"Visit the day after tomorrow", at(3, 14, 17, 0), false, "the day after tomorrow", "Visit"},
+		{"Hike next weekend", at(3, 22, 17, 0), false, "next weekend", "Hike"},
+		{"Plan the quarter next month", at(4, 1, 17, 0), false, "next month", "Plan the quarter"},
+	}
+	for _, tc := range cases {
+		m, ok := Parse(tc.text, now)
+		if !ok {
+			t.Errorf("%q: no date found", tc.text)
+			continue
+		}
+		if !m.Time.Equal(tc.want) || m.HasTime != tc.hasTime || m.Text != tc.matched {
+			t.Errorf("%q: got %s (time %v, %q), want %s (time %v, %q)",
+				tc.text, m.Time, m.HasTime, m.Text, tc.want, tc.hasTime, tc.matched)
+		}
+		if got := m.Strip(tc.text); got != tc.rest {
+			t.Errorf("%q: Strip = %q, want %q", tc.text, got, tc.rest)
+		}
+	}
+
+	for _, text := range []string{"Buy milk", "Read chapter 5", "Book for Sam", "February 30", "We may 2x the budget"} {
+		if m, ok := Parse(text, now); ok {
+			t.Errorf("%q: unexpected date %s from %q", text, m.Time, m.Text)
+		}
+	}
+}
+
+func TestParseUsesTheReferenceTimeZone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("time zone data unavailable:", err)
+	}
+	// 23:00 UTC on 12 March is already 13 March in Tokyo
+	now := time.Date(2025, 3, 12, 23, 0, 0, 0, time.UTC)
+
+	m, _ := Parse("tomorrow", now)
+	if want := time.Date(2025, 3, 13, 17, 0, 0, 0, time.UTC); !m.Time.Equal(want) {
+		t.Errorf("UTC: got %s, want %s", m.Time, want)
+	}
+	m, _ = Parse("tomorrow", now.In(tokyo))
+	if want := time.Date(2025, 3, 14, 17, 0, 0, 0, tokyo); !m.Time.Equal(want) {
+		t.Errorf("Tokyo: got %s, want %s", m.Time, want)
+	}
+}
+
+func TestParseWithOptions(t *testing.T) {
+	// Wednesday 12 March 2025
+	now := time.Date(2025, 3, 12, 10, 30, 0, 0, time.UTC)
+	sundayWeeks := Options{WeekStart: time.Sunday, DefaultHour: 18}
+
+	cases := []struct {
+		text string
+		opts Options
+		want time.Time
+	}{
+		{"next week", DefaultOptions, time.Date(2025, 3, 17, 17, 0, 0, 0, time.UTC)},
+		{"next week", sundayWeeks, time.Date(2025, 3, 16, 18, 0, 0, 0, time.UTC)},
+		{"next Sunday", DefaultOptions, time.Date(2025, 3, 23, 17, 0, 0, 0, time.UTC)},
+		{"next Sunday", sundayWeeks, time.Date(2025, 3, 16, 18, 0, 0, 0, time.UTC)},
+		{"next Friday at 9am", sundayWeeks, time.Date(2025, 3, 21, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		m, ok := ParseWith(tc.text, now, tc.opts)
+		if !ok || !m.Time.Equal(tc.want) {
+			t.Errorf("%q (week starts %s): got %s, want %s", tc.text, tc.opts.WeekStart, m.Time, tc.want)
+		}
+	}
+}
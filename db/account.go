@@ -0,0 +1,80 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// AccountTable is a table holding users' data and the column naming the user
+// a row belongs to
+type AccountTable struct {
+	Name  string
+	Owner string
+	// Secret columns are left out of exports: hashes, tokens and sealed
+	// credentials that only the server can use, and derived embeddings
+	Secret []string
+}
+
+// AccountTables are the tables holding a user's data, ordered so that rows
+// referencing another table's rows come before them. Workspaces are listed by
+// creator: deleting one returns its members' shared tasks and goals to them.
+// revoked_tokens is not listed; its rows must outlive the account so revoked
+// tokens stay revoked, and the janitor purges them once the tokens expire.
+// sync_tombstones comes last, as deleting tasks and goals records more.
+var AccountTables = []AccountTable{
+	{Name: "task_embeddings", Owner: "user_id", Secret: []string{"embedding"}},
+	{Name: "task_completions", Owner: "user_id"},
+	{Name: "task_reschedules", Owner: "user_id"},
+	{Name: "streak_freezes", Owner: "user_id"},
+	{Name: "grace_rules", Owner: "user_id"},
+	{Name: "goal_milestones", Owner: "user_id"},
+	{Name: "focus_sessions", Owner: "user_id"},
+	{Name: "focus_contracts", Owner: "user_id"},
+	{Name: "completion_stats", Owner: "user_id"},
+	{Name: "weekly_completions", Owner: "user_id"},
+	{Name: "user_preferences", Owner: "user_id"},
+	{Name: "user_memory", Owner: "user_id"},
+	{Name: "user_plans", Owner: "user_id"},
+	{Name: "integration_links", Owner: "user_id"},
+	{Name: "integration_connections", Owner: "user_id"},
+	{Name: "user_credentials", Owner: "user_id", Secret: []string{"key_id", "wrapped_key", "ciphertext"}},
+	{Name: "email_ingest_addresses", Owner: "user_id", Secret: []string{"token"}},
+	{Name: "api_keys", Owner: "user_id", Secret: []string{"key_hash"}},
+	{Name: "oauth_grants", Owner: "user_id"},
+	{Name: "oauth_sessions", Owner: "user_id"},
+	{Name: "mcp_sessions", Owner: "user_id"},
+	{Name: "audit_log", Owner: "user_id"},
+	{Name: "workspace_invites", Owner: "invited_by", Secret: []string{"token_hash"}},
+	{Name: "workspace_members", Owner: "user_id"},
+	{Name: "workspaces", Owner: "created_by"},
+	{Name: "tasks", Owner: "user_id"},
+	{Name: "goals", Owner: "user_id"},
+	{Name: "sync_tombstones", Owner: "user_id"},
+}
+
+// ExportAccountTable returns every row userID owns in table, without its
+// secret columns
+func (sc *SupabaseClient) ExportAccountTable(table AccountTable, userID string) ([]map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("%s?%s=eq.%s&select=*", table.Name, table.Owner, url.QueryEscape(userID)), "export "+table.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for _, column := range table.Secret {
+			delete(row, column)
+		}
+	}
+	return rows, nil
+}
+
+// CountAccountRows returns how many rows userID owns in table
+func (sc *SupabaseClient) CountAccountRows(table AccountTable, userID string) (int, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("%s?%s=eq.%s&select=%s", table.Name, table.Owner, url.QueryEscape(userID), table.Owner), "count "+table.Name)
+	return len(rows), err
+}
+
+// DeleteAccountRows deletes every row userID owns in table and returns how
+// many there were
+func (sc *SupabaseClient) DeleteAccountRows(table AccountTable, userID string) (int, error) {
+	return sc.deleteRowsCounted(fmt.Sprintf("%s?%s=eq.%s", table.Name, table.Owner, url.QueryEscape(userID)), "delete "+table.Name)
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// tasksCreatedSince filters userID's tasks outside the trash to those
+// created after since, or all of them for the zero time
+func tasksCreatedSince(userID string, since time.Time) string {
+	filter := fmt.Sprintf("tasks?user_id=eq.%s&deleted_at=is.null", url.QueryEscape(userID))
+	if !since.IsZero() {
+		filter += "&created_at=gt." + url.QueryEscape(since.UTC().Format(time.RFC3339))
+	}
+	return filter
+}
+
+// CountTasksCreatedSince returns how many of userID's tasks were created
+// after since (all of them for the zero time), only completed ones if
+// completedOnly. Nothing but the count is fetched.
+func (sc *SupabaseClient) CountTasksCreatedSince(userID string, since time.Time, completedOnly bool) (int, error) {
+	endpoint := tasksCreatedSince(userID, since)
+	if completedOnly {
+		endpoint += "&completed=is.true"
+	}
+	return sc.countRows(endpoint, "count tasks")
+}
+
+// GetTasksCreatedSince returns columns, comma-separated, of at most limit of
+// userID's tasks created after since, newest first
+func (sc *SupabaseClient) GetTasksCreatedSince(userID string, since time.Time, columns string, limit int) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("%s&select=%s&order=created_at.desc&limit=%d", tasksCreatedSince(userID, since), columns, limit), "get recent tasks")
+}
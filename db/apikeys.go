@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// apiKeyColumns are returned when listing keys; the hash never leaves the database layer
+const apiKeyColumns = "id,user_id,name,prefix,scopes,created_at,expires_at,last_used_at,revoked_at"
+
+// CreateAPIKey stores a new API key (hash, prefix, name and scopes)
+func (sc *SupabaseClient) CreateAPIKey(userID string, keyData map[string]interface{}) (map[string]interface{}, error) {
+	keyData["user_id"] = userID
+	return sc.insertRow("api_keys", keyData, "create API key")
+}
+
+// GetAPIKeyByHash returns the active (unrevoked) key with the given hash, or nil if none matches
+func (sc *SupabaseClient) GetAPIKeyByHash(keyHash string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("api_keys?key_hash=eq.%s&revoked_at=is.null&select=%s&limit=1",
+		url.QueryEscape(keyHash), apiKeyColumns), "get API key")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// ListAPIKeys lists a user's API keys, newest first, including revoked keys
+func (sc *SupabaseClient) ListAPIKeys(userID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("api_keys?user_id=eq.%s&select=%s&order=created_at.desc",
+		url.QueryEscape(userID), apiKeyColumns), "list API keys")
+}
+
+// RevokeAPIKey revokes one of a user's keys, returning nil if no active key matched
+func (sc *SupabaseClient) RevokeAPIKey(userID, keyID string) (map[string]interface{}, error) {
+	rows, err := sc.updateRowsReturning(fmt.Sprintf("api_keys?id=eq.%s&user_id=eq.%s&revoked_at=is.null&select=%s",
+		url.QueryEscape(keyID), url.QueryEscape(userID), apiKeyColumns), map[string]interface{}{
+		"revoked_at": time.Now().UTC().Format(time.RFC3339),
+	}, "revoke API key")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// TouchAPIKey records that a key was just used
+func (sc *SupabaseClient) TouchAPIKey(keyID string, usedAt time.Time) error {
+	return sc.updateRows(fmt.Sprintf("api_keys?id=eq.%s", url.QueryEscape(keyID)), map[string]interface{}{
+		"last_used_at": usedAt.UTC().Format(time.RFC3339),
+	}, "touch API key")
+}
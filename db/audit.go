@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InsertAuditEntry appends an entry to the audit log
+func (sc *SupabaseClient) InsertAuditEntry(entry map[string]interface{}) error {
+	_, err := sc.insertRow("audit_log", entry, "insert audit entry")
+	return err
+}
+
+// GetAuditLog lists audit entries matching the given column equality filters, newest first
+func (sc *SupabaseClient) GetAuditLog(filters map[string]string, limit int) ([]map[string]interface{}, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var query []string
+	for _, k := range keys {
+		if filters[k] != "" {
+			query = append(query, fmt.Sprintf("%s=eq.%s", k, url.QueryEscape(filters[k])))
+		}
+	}
+	query = append(query, "select=*", "order=created_at.desc", fmt.Sprintf("limit=%d", limit))
+
+	return sc.selectRows("audit_log?"+strings.Join(query, "&"), "get audit log")
+}
+
+// GetAuditEntry returns the audit entry with id, or nil if there is none
+func (sc *SupabaseClient) GetAuditEntry(id string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("audit_log?id=eq.%s&select=*", url.QueryEscape(id)), "get audit entry")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// PurgeAuditLog removes audit entries recorded before cutoff and returns how many
+func (sc *SupabaseClient) PurgeAuditLog(cutoff time.Time) (int, error) {
+	return sc.deleteRowsCounted(fmt.Sprintf("audit_log?created_at=lt.%s", url.QueryEscape(cutoff.UTC().Format(time.RFC3339))), "purge audit log")
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// GetUserCredential returns a user's sealed credential, or nil if there is none
+func (sc *SupabaseClient) GetUserCredential(userID, name string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("user_credentials?user_id=eq.%s&name=eq.%s&select=*",
+		url.QueryEscape(userID), url.QueryEscape(name)), "get user credential")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// UpsertUserCredential creates or replaces a user's sealed credential
+func (sc *SupabaseClient) UpsertUserCredential(userID, name string, data map[string]interface{}) error {
+	data["user_id"] = userID
+	data["name"] = name
+	_, err := sc.upsertRow("user_credentials", "user_id,name", data, "upsert user credential")
+	return err
+}
+
+// DeleteUserCredential deletes a user's sealed credential
+func (sc *SupabaseClient) DeleteUserCredential(userID, name string) error {
+	return sc.deleteRows(fmt.Sprintf("user_credentials?user_id=eq.%s&name=eq.%s",
+		url.QueryEscape(userID), url.QueryEscape(name)), "delete user credential")
+}
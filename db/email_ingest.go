@@ -0,0 +1,38 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// GetEmailIngestToken returns the token in a user's ingest address, or "" if
+// they have none yet
+func (sc *SupabaseClient) GetEmailIngestToken(userID string) (string, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("email_ingest_addresses?user_id=eq.%s&select=token", url.QueryEscape(userID)), "get email ingest address")
+	if err != nil || len(rows) == 0 {
+		return "", err
+	}
+	token, _ := rows[0]["token"].(string)
+	return token, nil
+}
+
+// GetEmailIngestUser returns the user whose ingest address holds token, or ""
+// if no address does
+func (sc *SupabaseClient) GetEmailIngestUser(token string) (string, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("email_ingest_addresses?token=eq.%s&select=user_id", url.QueryEscape(token)), "get email ingest user")
+	if err != nil || len(rows) == 0 {
+		return "", err
+	}
+	userID, _ := rows[0]["user_id"].(string)
+	return userID, nil
+}
+
+// SetEmailIngestToken gives a user the ingest address holding token,
+// replacing any address they had
+func (sc *SupabaseClient) SetEmailIngestToken(userID, token string) error {
+	_, err := sc.upsertRow("email_ingest_addresses", "user_id", map[string]interface{}{
+		"user_id": userID,
+		"token":   token,
+	}, "set email ingest address")
+	return err
+}
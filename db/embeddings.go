@@ -0,0 +1,100 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/productivity/mcp-server/embeddings"
+)
+
+// EmbeddingMatch is a task ranked by similarity to a query vector
+type EmbeddingMatch struct {
+	TaskID     string  `json:"task_id"`
+	Similarity float64 `json:"similarity"`
+}
+
+// GetTaskEmbeddingHashes lists the content hash of every task of a user
+// embedded with model, without the vectors themselves
+func (sc *SupabaseClient) GetTaskEmbeddingHashes(userID, model string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("task_embeddings?user_id=eq.%s&model=eq.%s&select=task_id,content_hash",
+		url.QueryEscape(userID), url.QueryEscape(model)), "get task embedding hashes")
+}
+
+// GetTaskEmbedding returns a task's stored embedding row, or nil if it has none
+func (sc *SupabaseClient) GetTaskEmbedding(taskID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("task_embeddings?task_id=eq.%s&select=*", url.QueryEscape(taskID)), "get task embedding")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// UpsertTaskEmbedding stores a task's embedding, replacing any older one
+func (sc *SupabaseClient) UpsertTaskEmbedding(taskID, userID, model, contentHash string, vec []float32) error {
+	_, err := sc.upsertRow("task_embeddings", "task_id", map[string]interface{}{
+		"task_id":      taskID,
+		"user_id":      userID,
+		"model":        model,
+		"content_hash": contentHash,
+		"embedding":    embeddings.Format(vec),
+		"updated_at":   time.Now().UTC().Format(time.RFC3339),
+	}, "upsert task embedding")
+	return err
+}
+
+// MatchTaskEmbeddings ranks a user's tasks embedded with model by cosine
+// similarity to vec, returning at most limit, most similar first. Supabase
+// ranks them with pgvector; the lite build compares them in process.
+func (sc *SupabaseClient) MatchTaskEmbeddings(userID, model string, vec []float32, limit int) ([]EmbeddingMatch, error) {
+	if liteBuild {
+		return sc.matchTaskEmbeddingsLocally(userID, model, vec, limit)
+	}
+
+	resp, err := sc.makeRequest("POST", "rpc/match_task_embeddings", map[string]interface{}{
+		"query_embedding": embeddings.Format(vec),
+		"match_user_id":   userID,
+		"match_model":     model,
+		"match_count":     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newPostgRESTError("match task embeddings", resp)
+	}
+
+	var matches []EmbeddingMatch
+	if err := json.NewDecoder(resp.Body).Decode(&matches); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return matches, nil
+}
+
+func (sc *SupabaseClient) matchTaskEmbeddingsLocally(userID, model string, vec []float32, limit int) ([]EmbeddingMatch, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("task_embeddings?user_id=eq.%s&model=eq.%s&select=task_id,embedding",
+		url.QueryEscape(userID), url.QueryEscape(model)), "get task embeddings")
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []EmbeddingMatch{}
+	for _, row := range rows {
+		stored, ok := embeddings.Parse(row["embedding"])
+		if !ok || len(stored) != len(vec) {
+			continue
+		}
+		taskID, _ := row["task_id"].(string)
+		matches = append(matches, EmbeddingMatch{TaskID: taskID, Similarity: embeddings.Cosine(vec, stored)})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
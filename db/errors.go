@@ -0,0 +1,97 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/productivity/mcp-server/utils"
+)
+
+// What a failed request meant, for errors.Is
+var (
+	// ErrNotFound is a row that does not exist or that the caller cannot see
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is a write that clashes with a row already stored, such as
+	// a second row with the same unique key
+	ErrConflict = errors.New("conflicts with an existing row")
+	// ErrForbidden is a write refused by a row-level security policy
+	ErrForbidden = errors.New("not allowed by the database's access policy")
+)
+
+// Postgres and PostgREST error codes classified by PostgRESTError.Is
+const (
+	pgUniqueViolation       = "23505"
+	pgForeignKeyViolation   = "23503"
+	pgExclusionViolation    = "23P01"
+	pgInsufficientPrivilege = "42501"
+	pgrstSingularNotFound   = "PGRST116"
+)
+
+// PostgRESTError is an error answer from PostgREST, with the error body it
+// sends (https://postgrest.org/en/stable/references/errors.html). The
+// message, details and hint may quote stored values, so they are for the
+// log; clients are shown what Op was and, for the errors Is classifies, why
+// it failed.
+type PostgRESTError struct {
+	Op      string `json:"-"` // what was attempted, such as "create task"
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details"`
+	Hint    string `json:"hint"`
+}
+
+func (e *PostgRESTError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to %s: %d %s", e.Op, e.Status, http.StatusText(e.Status))
+	if e.Code != "" {
+		b.WriteString(" (" + e.Code + ")")
+	}
+	for _, part := range []string{e.Message, e.Details, e.Hint} {
+		if part != "" {
+			b.WriteString(" - " + part)
+		}
+	}
+	return b.String()
+}
+
+// Is classifies the error as ErrNotFound, ErrConflict or ErrForbidden
+func (e *PostgRESTError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Code == pgrstSingularNotFound
+	case ErrConflict:
+		switch e.Code {
+		case pgUniqueViolation, pgForeignKeyViolation, pgExclusionViolation:
+			return true
+		}
+		return e.Status == http.StatusConflict
+	case ErrForbidden:
+		return e.Code == pgInsufficientPrivilege || e.Status == http.StatusForbidden
+	}
+	return false
+}
+
+// newPostgRESTError reads the error answer resp to op. Errors classified as
+// not found, conflicting or forbidden come wrapped in the AppError handlers
+// answer them with; others are left to be reported as internal errors.
+func newPostgRESTError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	pgErr := &PostgRESTError{Op: op, Status: resp.StatusCode}
+	if json.Unmarshal(body, pgErr) != nil || pgErr.Message == "" {
+		pgErr.Message = strings.TrimSpace(string(body))
+	}
+	switch {
+	case errors.Is(pgErr, ErrNotFound):
+		return utils.ErrNotFound("record").WithError(pgErr)
+	case errors.Is(pgErr, ErrConflict):
+		return utils.ErrConflict("could not " + op + ": it " + ErrConflict.Error()).WithError(pgErr)
+	case errors.Is(pgErr, ErrForbidden):
+		return utils.ErrForbidden("could not " + op + ": it is " + ErrForbidden.Error()).WithError(pgErr)
+	}
+	return pgErr
+}
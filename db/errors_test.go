@@ -0,0 +1,60 @@
+package db
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestNewPostgRESTError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		is     error
+		answer int // the status a handler answers with, or 0 for an internal error
+	}{
+		{"unique violation", http.StatusConflict,
+			`{"code":"23505","message":"duplicate key value violates unique constraint \"tasks_pkey\"","details":"Key (id)=(t1) already exists.","hint":null}`,
+			ErrConflict, http.StatusConflict},
+		{"row-level security", http.StatusForbidden,
+			`{"code":"42501","message":"new row violates row-level security policy for table \"tasks\""}`,
+			ErrForbidden, http.StatusForbidden},
+		{"single row missing", http.StatusNotAcceptable,
+			`{"code":"PGRST116","message":"JSON object requested, multiple (or no) rows returned","details":"The result contains 0 rows"}`,
+			ErrNotFound, http.StatusNotFound},
+		{"database error", http.StatusInternalServerError, `{"code":"P0001","message":"raised"}`, nil, 0},
+		{"gateway page", http.StatusInternalServerError, `<html>oops</html>`, nil, 0},
+	}
+	for _, tc := range tests {
+		resp := &http.Response{StatusCode: tc.status, Body: io.NopCloser(strings.NewReader(tc.body))}
+		err := newPostgRESTError("create task", resp)
+
+		var pgErr *PostgRESTError
+		if !errors.As(err, &pgErr) || pgErr.Op != "create task" || pgErr.Status != tc.status {
+			t.Errorf("%s: %v is not the PostgREST error", tc.name, err)
+			continue
+		}
+		if tc.is != nil && !errors.Is(err, tc.is) {
+			t.Errorf("%s: %v is not %v", tc.name, err, tc.is)
+		}
+		var appErr *utils.AppError
+		switch {
+		case tc.answer == 0 && errors.As(err, &appErr):
+			t.Errorf("%s: answered with %d, want an internal error", tc.name, appErr.HTTPStatus)
+		case tc.answer != 0 && (!errors.As(err, &appErr) || appErr.HTTPStatus != tc.answer):
+			t.Errorf("%s: %v does not answer %d", tc.name, err, tc.answer)
+		case appErr != nil && strings.Contains(appErr.Message, "Key (id)"):
+			t.Errorf("%s: the answer quotes the database: %q", tc.name, appErr.Message)
+		}
+	}
+
+	resp := &http.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader("<html>oops</html>"))}
+	if got := newPostgRESTError("get task", resp).Error(); got != "failed to get task: 500 Internal Server Error - <html>oops</html>" {
+		t.Errorf("Error() = %q", got)
+	}
+}
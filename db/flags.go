@@ -0,0 +1,22 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// ListFeatureFlags returns every saved feature flag rule
+func (sc *SupabaseClient) ListFeatureFlags() ([]map[string]interface{}, error) {
+	return sc.selectRows("feature_flags?select=*&order=name.asc", "list feature flags")
+}
+
+// UpsertFeatureFlag creates or replaces a feature flag's rule
+func (sc *SupabaseClient) UpsertFeatureFlag(name string, data map[string]interface{}) (map[string]interface{}, error) {
+	data["name"] = name
+	return sc.upsertRow("feature_flags", "name", data, "upsert feature flag")
+}
+
+// DeleteFeatureFlag deletes a feature flag's saved rule
+func (sc *SupabaseClient) DeleteFeatureFlag(name string) error {
+	return sc.deleteRows(fmt.Sprintf("feature_flags?name=eq.%s", url.QueryEscape(name)), "delete feature flag")
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// CreateFocusSession starts a new focus session for a user
+func (sc *SupabaseClient) CreateFocusSession(userID string, sessionData map[string]interface{}) (map[string]interface{}, error) {
+	sessionData["user_id"] = userID
+	return sc.insertRow("focus_sessions", sessionData, "create focus session")
+}
+
+// GetActiveFocusSession returns the user's running focus session, or nil if none is active
+func (sc *SupabaseClient) GetActiveFocusSession(userID string) (map[string]interface{}, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	endpoint := fmt.Sprintf("focus_sessions?user_id=eq.%s&ended_at=is.null&ends_at=gt.%s&select=*&order=started_at.desc&limit=1",
+		url.QueryEscape(userID), url.QueryEscape(now))
+
+	sessions, err := sc.selectRows(endpoint, "get active focus session")
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	return sessions[0], nil
+}
+
+// EndFocusSession marks a focus session as ended
+func (sc *SupabaseClient) EndFocusSession(sessionID string) error {
+	return sc.updateRows(fmt.Sprintf("focus_sessions?id=eq.%s", url.QueryEscape(sessionID)), map[string]interface{}{
+		"ended_at": time.Now().UTC().Format(time.RFC3339),
+	}, "end focus session")
+}
+
+// GetFocusContract returns a user's focus contract settings, or nil if they never set any
+func (sc *SupabaseClient) GetFocusContract(userID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("focus_contracts?user_id=eq.%s&select=*", url.QueryEscape(userID)), "get focus contract")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// UpsertFocusContract creates or replaces a user's focus contract settings
+func (sc *SupabaseClient) UpsertFocusContract(userID string, contract map[string]interface{}) (map[string]interface{}, error) {
+	contract["user_id"] = userID
+	return sc.upsertRow("focus_contracts", "user_id", contract, "upsert focus contract")
+}
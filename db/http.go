@@ -0,0 +1,104 @@
+package db
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/utils"
+)
+
+var (
+	// sharedHTTPClient carries every Supabase request so handlers reuse one
+	// pool of keep-alive connections instead of one per SupabaseClient
+	sharedHTTPClient = newHTTPClient(config.Defaults().Supabase)
+
+	// sharedRetry is how every Supabase client retries failed requests
+	sharedRetry = newRetryConfig(config.Defaults().Supabase)
+
+	// sharedServiceRoleKey is sent instead of a client's own key in requests
+	// for the tables in serviceRoleTables; empty sends the client's key
+	sharedServiceRoleKey string
+
+	// clients caches one SupabaseClient per project URL and key
+	clientsMu sync.Mutex
+	clients   = make(map[string]*SupabaseClient)
+)
+
+// serviceRoleTables hold credentials and authorization state, or (the admin
+// views) see past every other table's policies. Their migrations give them no
+// row-level security policy or revoke them from the API roles, so only the
+// service role can read or write them; the anon key the apps ship with sees
+// no rows.
+var serviceRoleTables = map[string]bool{
+	"admin_usage":             true,
+	"admin_users":             true,
+	"api_keys":                true,
+	"email_ingest_addresses":  true,
+	"feature_flags":           true,
+	"integration_connections": true,
+	"mcp_sessions":            true,
+	"oauth_clients":           true,
+	"oauth_grants":            true,
+	"oauth_sessions":          true,
+	"revoked_tokens":          true,
+	"user_credentials":        true,
+	"user_plans":              true,
+	"workspace_invites":       true,
+	"workspace_members":       true,
+	"workspaces":              true,
+}
+
+// ConfigureServiceRole sets the service-role key requests for the tables in
+// serviceRoleTables carry. Call it before creating clients; clients created
+// earlier keep the old key.
+func ConfigureServiceRole(key string) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	sharedServiceRoleKey = key
+	clients = make(map[string]*SupabaseClient)
+}
+
+// ConfigureHTTP tunes the connection pool and retries used by every Supabase
+// client. Call it before creating clients; clients created earlier keep the
+// old pool.
+func ConfigureHTTP(cfg config.Supabase) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	old := sharedHTTPClient
+	sharedHTTPClient = newHTTPClient(cfg)
+	sharedRetry = newRetryConfig(cfg)
+	clients = make(map[string]*SupabaseClient)
+	old.CloseIdleConnections()
+}
+
+// newHTTPClient builds a client whose transport keeps up to
+// MaxIdleConnsPerHost connections to Supabase open between requests.
+// Gzip lets large task lists travel compressed; Go decompresses them
+// transparently. Every request is logged with its duration and status.
+func newHTTPClient(cfg config.Supabase) *http.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     cfg.HTTP2,
+		MaxIdleConns:          cfg.MaxIdleConnsPerHost * 2,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout.Duration,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		DisableCompression:    !cfg.Gzip,
+	}
+	if !cfg.HTTP2 {
+		// A non-nil empty map disables the transport's automatic HTTP/2 upgrade
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return &http.Client{Transport: utils.NewLoggingTransport("supabase", transport), Timeout: cfg.Timeout.Duration}
+}
@@ -0,0 +1,67 @@
+//go:build !lite
+
+package db
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestClientsSharePool(t *testing.T) {
+	defer ConfigureHTTP(config.Defaults().Supabase)
+
+	a, err := NewSupabaseClient("https://a.supabase.co", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewSupabaseClient("https://a.supabase.co/", "key")
+	other, _ := NewSupabaseClient("https://a.supabase.co", "other-key")
+	if a != b {
+		t.Error("expected one client per project and key")
+	}
+	if a == other || a.httpClient != other.httpClient {
+		t.Error("expected separate clients sharing one HTTP pool")
+	}
+
+	cfg := config.Defaults().Supabase
+	cfg.Gzip = false
+	cfg.MaxIdleConnsPerHost = 4
+	ConfigureHTTP(cfg)
+	c, _ := NewSupabaseClient("https://a.supabase.co", "key")
+	if c == a {
+		t.Fatal("ConfigureHTTP should start a new pool")
+	}
+	transport := c.httpClient.Transport.(*utils.LoggingTransport).Base.(*http.Transport)
+	if !transport.DisableCompression || transport.MaxIdleConnsPerHost != 4 {
+		t.Errorf("transport not tuned: compression disabled %v, max idle per host %d",
+			transport.DisableCompression, transport.MaxIdleConnsPerHost)
+	}
+}
+
+func TestServiceRoleTables(t *testing.T) {
+	defer ConfigureServiceRole("")
+
+	ConfigureServiceRole("service-key")
+	client, err := NewSupabaseClient("https://a.supabase.co", "anon-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for endpoint, want := range map[string]string{
+		"api_keys?key_hash=eq.abc": "service-key",
+		"api_keys":                 "service-key",
+		"revoked_tokens?jti=eq.x":  "service-key",
+		"tasks?user_id=eq.u1":      "anon-key",
+	} {
+		if got := client.keyFor(endpoint); got != want {
+			t.Errorf("%s: sent with %s, want %s", endpoint, got, want)
+		}
+	}
+
+	ConfigureServiceRole("")
+	if client, _ := NewSupabaseClient("https://a.supabase.co", "anon-key"); client.keyFor("api_keys") != "anon-key" {
+		t.Error("without a service-role key every request should carry the client's key")
+	}
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// GetTasksByExternalSource lists a user's tasks imported from source, including
+// trashed ones, so re-imports can match them by external_id
+func (sc *SupabaseClient) GetTasksByExternalSource(userID, source string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("tasks?user_id=eq.%s&external_source=eq.%s&select=*",
+		url.QueryEscape(userID), url.QueryEscape(source)), "get imported tasks")
+}
+
+// CreateTasks creates many tasks for a user in one request and returns their
+// IDs in the order given. Columns a task leaves out get their defaults.
+func (sc *SupabaseClient) CreateTasks(userID string, tasks []map[string]interface{}) ([]string, error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	columns := map[string]bool{}
+	for _, task := range tasks {
+		task["user_id"] = userID
+		for column := range task {
+			columns[column] = true
+		}
+	}
+	names := make([]string, 0, len(columns))
+	for column := range columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+
+	resp, err := sc.makeRequestWithPrefer("POST", "tasks?columns="+url.QueryEscape(strings.Join(names, ",")), tasks, "return=representation,missing=default")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, newPostgRESTError("create tasks", resp)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(rows) != len(tasks) {
+		return nil, fmt.Errorf("created %d tasks, expected %d", len(rows), len(tasks))
+	}
+
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		id, ok := row["id"].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid task ID in response")
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// GetIntegrationConnection returns a user's connection to a provider, or nil if they have not connected
+func (sc *SupabaseClient) GetIntegrationConnection(userID, provider string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("integration_connections?user_id=eq.%s&provider=eq.%s&select=*",
+		url.QueryEscape(userID), url.QueryEscape(provider)), "get integration connection")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// ListUserIntegrationConnections returns a user's connections to every provider
+func (sc *SupabaseClient) ListUserIntegrationConnections(userID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("integration_connections?user_id=eq.%s&select=*&order=provider.asc",
+		url.QueryEscape(userID)), "list integration connections")
+}
+
+// ListIntegrationConnections returns every user's connections to a provider
+func (sc *SupabaseClient) ListIntegrationConnections(provider string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("integration_connections?provider=eq.%s&select=*&order=user_id.asc",
+		url.QueryEscape(provider)), "list integration connections")
+}
+
+// UpsertIntegrationConnection creates or replaces a user's connection to a provider
+func (sc *SupabaseClient) UpsertIntegrationConnection(userID, provider string, conn map[string]interface{}) (map[string]interface{}, error) {
+	conn["user_id"] = userID
+	conn["provider"] = provider
+	return sc.upsertRow("integration_connections", "user_id,provider", conn, "upsert integration connection")
+}
+
+// UpdateIntegrationConnection sets fields of a user's connection to a provider
+func (sc *SupabaseClient) UpdateIntegrationConnection(userID, provider string, data map[string]interface{}) error {
+	return sc.updateRows(fmt.Sprintf("integration_connections?user_id=eq.%s&provider=eq.%s",
+		url.QueryEscape(userID), url.QueryEscape(provider)), data, "update integration connection")
+}
+
+// DeleteIntegrationConnection disconnects a user from a provider, forgetting which outside items mirror what
+func (sc *SupabaseClient) DeleteIntegrationConnection(userID, provider string) error {
+	if err := sc.deleteRows(fmt.Sprintf("integration_links?user_id=eq.%s&provider=eq.%s",
+		url.QueryEscape(userID), url.QueryEscape(provider)), "delete integration links"); err != nil {
+		return err
+	}
+	return sc.deleteRows(fmt.Sprintf("integration_connections?user_id=eq.%s&provider=eq.%s",
+		url.QueryEscape(userID), url.QueryEscape(provider)), "delete integration connection")
+}
+
+// ListIntegrationLinks returns which outside item of a provider mirrors each of a user's tasks and goals
+func (sc *SupabaseClient) ListIntegrationLinks(userID, provider string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("integration_links?user_id=eq.%s&provider=eq.%s&select=*",
+		url.QueryEscape(userID), url.QueryEscape(provider)), "list integration links")
+}
+
+// UpsertIntegrationLink records the outside item mirroring a task or goal and the content last synced
+func (sc *SupabaseClient) UpsertIntegrationLink(userID, provider, entityType, entityID, externalID, contentHash string) error {
+	_, err := sc.upsertRow("integration_links", "provider,entity_type,entity_id", map[string]interface{}{
+		"user_id":      userID,
+		"provider":     provider,
+		"entity_type":  entityType,
+		"entity_id":    entityID,
+		"external_id":  externalID,
+		"content_hash": contentHash,
+		"synced_at":    time.Now().UTC().Format(time.RFC3339),
+	}, "upsert integration link")
+	return err
+}
+
+// DeleteIntegrationLink forgets the outside item mirroring a task or goal
+func (sc *SupabaseClient) DeleteIntegrationLink(provider, entityType, entityID string) error {
+	return sc.deleteRows(fmt.Sprintf("integration_links?provider=eq.%s&entity_type=eq.%s&entity_id=eq.%s",
+		url.QueryEscape(provider), url.QueryEscape(entityType), url.QueryEscape(entityID)), "delete integration link")
+}
+
+// DeleteIntegrationLinks forgets the outside items mirroring a user's tasks
+// or goals, for when they are synced somewhere else
+func (sc *SupabaseClient) DeleteIntegrationLinks(userID, provider, entityType string) error {
+	return sc.deleteRows(fmt.Sprintf("integration_links?user_id=eq.%s&provider=eq.%s&entity_type=eq.%s",
+		url.QueryEscape(userID), url.QueryEscape(provider), url.QueryEscape(entityType)), "delete integration links")
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SaveMCPSessions stores MCP sessions so another instance can resume them,
+// replacing any saved earlier under the same IDs
+func (sc *SupabaseClient) SaveMCPSessions(sessions []map[string]interface{}) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	resp, err := sc.makeRequestWithPrefer("POST", "mcp_sessions?on_conflict=id", sessions, "return=minimal,resolution=merge-duplicates")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newPostgRESTError("save MCP sessions", resp)
+	}
+	return nil
+}
+
+// GetMCPSession returns the saved MCP session with id, or nil if there is none
+func (sc *SupabaseClient) GetMCPSession(id string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("mcp_sessions?id=eq.%s&select=*", url.QueryEscape(id)), "get MCP session")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// DeleteMCPSession removes a saved MCP session once an instance has taken it over
+func (sc *SupabaseClient) DeleteMCPSession(id string) error {
+	return sc.deleteRows(fmt.Sprintf("mcp_sessions?id=eq.%s", url.QueryEscape(id)), "delete MCP session")
+}
+
+// PurgeMCPSessions removes saved MCP sessions last used before cutoff and returns how many
+func (sc *SupabaseClient) PurgeMCPSessions(cutoff time.Time) (int, error) {
+	return sc.deleteRowsCounted(fmt.Sprintf("mcp_sessions?last_seen_at=lt.%s", url.QueryEscape(cutoff.UTC().Format(time.RFC3339))), "purge MCP sessions")
+}
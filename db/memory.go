@@ -0,0 +1,34 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// ListMemory returns everything remembered about a user, by key
+func (sc *SupabaseClient) ListMemory(userID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("user_memory?user_id=eq.%s&select=*&order=key.asc", url.QueryEscape(userID)), "list memory")
+}
+
+// GetMemory returns one remembered entry, or nil if there is none
+func (sc *SupabaseClient) GetMemory(userID, key string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("user_memory?user_id=eq.%s&key=eq.%s&select=*",
+		url.QueryEscape(userID), url.QueryEscape(key)), "get memory")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// UpsertMemory creates or replaces a remembered entry
+func (sc *SupabaseClient) UpsertMemory(userID, key string, entry map[string]interface{}) (map[string]interface{}, error) {
+	entry["user_id"] = userID
+	entry["key"] = key
+	return sc.upsertRow("user_memory", "user_id,key", entry, "upsert memory")
+}
+
+// DeleteMemory forgets a remembered entry
+func (sc *SupabaseClient) DeleteMemory(userID, key string) error {
+	return sc.deleteRows(fmt.Sprintf("user_memory?user_id=eq.%s&key=eq.%s",
+		url.QueryEscape(userID), url.QueryEscape(key)), "delete memory")
+}
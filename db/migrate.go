@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/productivity/mcp-server/db/migrations"
+)
+
+// migrationsTable records which migrations have been applied
+const migrationsTable = "public.schema_migrations"
+
+// Table is a table the server uses and the migration that creates it
+type Table struct {
+	Name      string
+	Migration string
+}
+
+// Tables lists every table the server reads or writes
+var Tables = []Table{
+	{"tasks", "000_core_schema"},
+	{"goals", "000_core_schema"},
+	{"goal_milestones", "014_goal_milestones"},
+	{"focus_sessions", "001_focus_sessions"},
+	{"audit_log", "003_audit_log"},
+	{"task_completions", "004_streaks"},
+	{"streak_freezes", "004_streaks"},
+	{"grace_rules", "004_streaks"},
+	{"completion_stats", "016_completion_stats"},
+	{"weekly_completions", "016_completion_stats"},
+	{"focus_contracts", "007_focus_contract"},
+	{"api_keys", "008_api_keys"},
+	{"revoked_tokens", "009_revoked_tokens"},
+	{"oauth_clients", "010_oauth_clients"},
+	{"workspaces", "012_workspaces"},
+	{"workspace_members", "012_workspaces"},
+	{"workspace_invites", "012_workspaces"},
+	{"oauth_sessions", "013_oauth_sessions"},
+	{"user_preferences", "015_user_preferences"},
+	{"task_embeddings", "017_task_embeddings"},
+	{"user_memory", "018_user_memory"},
+	{"task_reschedules", "019_task_reschedules"},
+	{"email_ingest_addresses", "021_email_ingest"},
+	{"integration_connections", "023_integrations"},
+	{"integration_links", "023_integrations"},
+	{"user_credentials", "024_user_credentials"},
+}
+
+// Migrations lists the embedded migration names (e.g. "004_streaks") in the
+// order they are applied
+func Migrations() ([]string, error) {
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = strings.TrimSuffix(file, ".sql")
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Migrate applies every embedded migration not yet recorded in
+// schema_migrations, each in its own transaction, and returns the names it
+// applied. conn must be a direct Postgres connection; the REST API cannot run DDL.
+func Migrate(ctx context.Context, conn *sql.DB) ([]string, error) {
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	names, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []string
+	for _, name := range names {
+		if applied[name] {
+			continue
+		}
+		script, err := migrations.FS.ReadFile(name + ".sql")
+		if err != nil {
+			return ran, err
+		}
+		if err := runMigration(ctx, conn, name, string(script)); err != nil {
+			return ran, fmt.Errorf("migration %s: %w", name, err)
+		}
+		ran = append(ran, name)
+	}
+	return ran, nil
+}
+
+// Baseline records every migration up to and including through as applied
+// without running it, for databases whose schema was created by hand
+func Baseline(ctx context.Context, conn *sql.DB, through string) ([]string, error) {
+	if _, err := appliedMigrations(ctx, conn); err != nil {
+		return nil, err
+	}
+	names, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var marked []string
+	for _, name := range names {
+		if name > through && !strings.HasPrefix(name, through+"_") {
+			break
+		}
+		if _, err := conn.ExecContext(ctx,
+			"INSERT INTO "+migrationsTable+" (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", name); err != nil {
+			return marked, fmt.Errorf("failed to baseline %s: %w", name, err)
+		}
+		marked = append(marked, name)
+	}
+	return marked, nil
+}
+
+// appliedMigrations creates the tracking table if needed and returns the applied names
+func appliedMigrations(ctx context.Context, conn *sql.DB) (map[string]bool, error) {
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+  name TEXT PRIMARY KEY,
+  applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+)`); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", migrationsTable, err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM "+migrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		applied[name] = true
+	}
+	return applied, rows.Err()
+}
+
+func runMigration(ctx context.Context, conn *sql.DB, name, script string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO "+migrationsTable+" (name) VALUES ($1)", name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	names, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 || names[0] != "000_core_schema" {
+		t.Fatalf("expected the core schema first, got %v", names)
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("migrations out of order: %v", names)
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name[:3]] {
+			t.Errorf("duplicate migration number %s", name[:3])
+		}
+		seen[name[:3]] = true
+	}
+}
@@ -0,0 +1,47 @@
+-- Core tables every other migration builds on. Columns added later
+-- (deleted_at, external ids, language, deferred_until) live in their own migrations.
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS public.tasks (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id TEXT NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT DEFAULT '',
+  priority INTEGER DEFAULT 2,
+  due_date TIMESTAMP WITH TIME ZONE NOT NULL,
+  estimated_duration INTEGER DEFAULT 0,
+  category TEXT DEFAULT 'work',
+  completed BOOLEAN DEFAULT false,
+  completed_at TIMESTAMP WITH TIME ZONE,
+  recurring_frequency TEXT,
+  recurring_interval INTEGER,
+  recurring_end_date TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON public.tasks(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS public.goals (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id TEXT NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT DEFAULT '',
+  start_date TIMESTAMP WITH TIME ZONE NOT NULL,
+  target_date TIMESTAMP WITH TIME ZONE NOT NULL,
+  progress INTEGER DEFAULT 0,
+  archived BOOLEAN DEFAULT false,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_user_id ON public.goals(user_id, created_at);
+
+ALTER TABLE public.tasks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.goals ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.tasks
+  FOR ALL USING (true) WITH CHECK (true);
+
+CREATE POLICY "Allow all for authenticated users" ON public.goals
+  FOR ALL USING (true) WITH CHECK (true);
@@ -0,0 +1,17 @@
+-- Focus sessions (deep-work blocks started from the API, triggers or MCP)
+CREATE TABLE IF NOT EXISTS public.focus_sessions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id TEXT NOT NULL,
+  started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  ended_at TIMESTAMP WITH TIME ZONE,
+  source TEXT DEFAULT 'api',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_focus_sessions_user_id ON public.focus_sessions(user_id);
+
+ALTER TABLE public.focus_sessions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.focus_sessions
+  FOR ALL USING (true) WITH CHECK (true);
@@ -0,0 +1,6 @@
+-- Soft delete: trashed tasks and goals keep their row until purged after 30 days
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE public.goals ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON public.tasks(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_goals_deleted_at ON public.goals(deleted_at) WHERE deleted_at IS NOT NULL;
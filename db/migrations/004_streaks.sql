@@ -0,0 +1,40 @@
+-- Completion history used for habit streaks (one row per completion)
+CREATE TABLE IF NOT EXISTS public.task_completions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id TEXT NOT NULL,
+  task_id UUID NOT NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  completed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_completions_user ON public.task_completions(user_id, completed_at DESC);
+
+-- Streak freezes consumed to cover a missed day
+CREATE TABLE IF NOT EXISTS public.streak_freezes (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id TEXT NOT NULL,
+  task_id UUID REFERENCES public.tasks(id) ON DELETE CASCADE,
+  frozen_on DATE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (task_id, frozen_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_streak_freezes_user ON public.streak_freezes(user_id);
+
+-- Per-user grace rules (defaults come from server configuration)
+CREATE TABLE IF NOT EXISTS public.grace_rules (
+  user_id TEXT PRIMARY KEY,
+  freezes_per_week INTEGER NOT NULL DEFAULT 1,
+  weekend_exempt BOOLEAN NOT NULL DEFAULT false,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE public.task_completions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.streak_freezes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.grace_rules ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.task_completions
+  FOR ALL USING (true) WITH CHECK (true);
+CREATE POLICY "Allow all for authenticated users" ON public.streak_freezes
+  FOR ALL USING (true) WITH CHECK (true);
+CREATE POLICY "Allow all for authenticated users" ON public.grace_rules
+  FOR ALL USING (true) WITH CHECK (true);
@@ -0,0 +1,7 @@
+-- External IDs let imports from other apps (Habitica, Streaks) be re-run idempotently
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS external_source TEXT;
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS external_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_external_id
+  ON public.tasks(user_id, external_source, external_id)
+  WHERE external_id IS NOT NULL;
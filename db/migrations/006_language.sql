@@ -0,0 +1,6 @@
+-- Detected (or user-set) language of each task and goal, as an ISO 639-1 code
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS language TEXT;
+ALTER TABLE public.goals ADD COLUMN IF NOT EXISTS language TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_language ON public.tasks(user_id, language);
+CREATE INDEX IF NOT EXISTS idx_goals_language ON public.goals(user_id, language);
@@ -0,0 +1,14 @@
+-- Focus contract: while a focus session runs, new tasks go to the Inbox and
+-- are deferred until the session ends instead of interrupting the plan
+CREATE TABLE IF NOT EXISTS public.focus_contracts (
+  user_id TEXT PRIMARY KEY,
+  enabled BOOLEAN NOT NULL DEFAULT false,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS deferred_until TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE public.focus_contracts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.focus_contracts
+  FOR ALL USING (true) WITH CHECK (true);
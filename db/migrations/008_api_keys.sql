@@ -0,0 +1,21 @@
+-- API keys for server-to-server clients. Only a SHA-256 hash of each key is
+-- stored; the prefix identifies a key in listings without revealing it.
+CREATE TABLE IF NOT EXISTS public.api_keys (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  key_hash TEXT NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP WITH TIME ZONE,
+  last_used_at TIMESTAMP WITH TIME ZONE,
+  revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON public.api_keys(user_id);
+
+-- A row here lets its key act as user_id, so only the server may read or
+-- write the table: RLS with no policy keeps out the anon and authenticated
+-- roles, and the server uses the service-role key (SUPABASE_SERVICE_ROLE_KEY)
+ALTER TABLE public.api_keys ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,15 @@
+-- Denylist of access tokens revoked before they expire (logout or compromise).
+-- Rows are only needed until the token would have expired anyway.
+CREATE TABLE IF NOT EXISTS public.revoked_tokens (
+  jti TEXT PRIMARY KEY,
+  user_id TEXT,
+  reason TEXT,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  revoked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON public.revoked_tokens(expires_at);
+
+-- Deleting a row would bring a revoked token back, so only the server (with
+-- the service-role key) may touch the table: RLS with no policy
+ALTER TABLE public.revoked_tokens ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,15 @@
+-- OAuth clients registered at runtime or by `setup`. Only a SHA-256 hash of
+-- the client secret is stored; public (PKCE-only) clients have no secret.
+CREATE TABLE IF NOT EXISTS public.oauth_clients (
+  client_id TEXT PRIMARY KEY,
+  client_secret_hash TEXT,
+  redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+  name TEXT,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Anyone who could insert a row could register redirect URIs for a client
+-- users trust, so only the server (with the service-role key) may touch the
+-- table: RLS with no policy
+ALTER TABLE public.oauth_clients ENABLE ROW LEVEL SECURITY;
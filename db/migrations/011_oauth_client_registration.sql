@@ -0,0 +1,14 @@
+-- RFC 7591 dynamic client registration metadata. The registration access
+-- token authorizes GET/PUT/DELETE /oauth/register/:client_id and, like the
+-- client secret, is stored only as a SHA-256 hash.
+ALTER TABLE public.oauth_clients ADD COLUMN IF NOT EXISTS registration_token_hash TEXT;
+ALTER TABLE public.oauth_clients ADD COLUMN IF NOT EXISTS token_endpoint_auth_method TEXT DEFAULT 'client_secret_basic';
+ALTER TABLE public.oauth_clients ADD COLUMN IF NOT EXISTS grant_types TEXT[] NOT NULL DEFAULT '{authorization_code}';
+ALTER TABLE public.oauth_clients ADD COLUMN IF NOT EXISTS response_types TEXT[] NOT NULL DEFAULT '{code}';
+ALTER TABLE public.oauth_clients ADD COLUMN IF NOT EXISTS scope TEXT;
+ALTER TABLE public.oauth_clients ADD COLUMN IF NOT EXISTS client_id_issued_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+-- The registration token hash is as sensitive as the client secret. The table
+-- is only reachable with the service-role key (see 010); drop the open policy
+-- older schemas created.
+DROP POLICY IF EXISTS "Allow all for authenticated users" ON public.oauth_clients;
@@ -0,0 +1,50 @@
+-- Workspaces let several users share tasks and goals. Members have a role:
+-- owner (manages members and invites), editor (changes tasks and goals) or
+-- viewer (reads them). Tasks and goals without a workspace stay personal.
+CREATE TABLE IF NOT EXISTS public.workspaces (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  name TEXT NOT NULL,
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS public.workspace_members (
+  workspace_id UUID NOT NULL REFERENCES public.workspaces(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL,
+  role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON public.workspace_members(user_id);
+
+-- Invites are single-use; only a SHA-256 hash of the token is stored
+CREATE TABLE IF NOT EXISTS public.workspace_invites (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  workspace_id UUID NOT NULL REFERENCES public.workspaces(id) ON DELETE CASCADE,
+  role TEXT NOT NULL CHECK (role IN ('editor', 'viewer')),
+  email TEXT,
+  token_hash TEXT NOT NULL UNIQUE,
+  invited_by TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  accepted_by TEXT,
+  accepted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_invites_workspace_id ON public.workspace_invites(workspace_id);
+
+-- Deleting a workspace hands its tasks and goals back to their creators
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES public.workspaces(id) ON DELETE SET NULL;
+ALTER TABLE public.goals ADD COLUMN IF NOT EXISTS workspace_id UUID REFERENCES public.workspaces(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_workspace_id ON public.tasks(workspace_id, created_at) WHERE workspace_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_goals_workspace_id ON public.goals(workspace_id, created_at) WHERE workspace_id IS NOT NULL;
+
+-- A workspace_members row grants its user a role, and an invite token joins
+-- whoever holds it, so these tables get no policy: only the server (with the
+-- service-role key), which checks the caller's role, may read or write them
+ALTER TABLE public.workspaces ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.workspace_members ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.workspace_invites ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,54 @@
+-- Access tokens issued by /oauth/token, so operators can see who is signed in
+-- through which client and force-revoke sessions. Rows are kept for a while
+-- after the token expires so /admin/users can report when a user was last seen.
+CREATE TABLE IF NOT EXISTS public.oauth_sessions (
+  jti TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  client_id TEXT,
+  scope TEXT,
+  issued_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_sessions_user_id ON public.oauth_sessions(user_id, issued_at);
+CREATE INDEX IF NOT EXISTS idx_oauth_sessions_client_id ON public.oauth_sessions(client_id, issued_at);
+CREATE INDEX IF NOT EXISTS idx_oauth_sessions_expires_at ON public.oauth_sessions(expires_at);
+
+-- Sessions name who holds which token, so only the server (with the service-role
+-- key) may read or write them: RLS with no policy
+ALTER TABLE public.oauth_sessions ENABLE ROW LEVEL SECURITY;
+
+-- Every user the server knows about: anyone who owns tasks, goals or API keys
+-- or has signed in, with what they have and when they were last seen
+CREATE OR REPLACE VIEW public.admin_users AS
+SELECT
+  ids.user_id,
+  (SELECT COUNT(*) FROM public.tasks t WHERE t.user_id = ids.user_id AND t.deleted_at IS NULL) AS task_count,
+  (SELECT COUNT(*) FROM public.goals g WHERE g.user_id = ids.user_id AND g.deleted_at IS NULL) AS goal_count,
+  (SELECT COUNT(*) FROM public.api_keys k WHERE k.user_id = ids.user_id AND k.revoked_at IS NULL) AS api_key_count,
+  (SELECT COUNT(*) FROM public.oauth_sessions s
+     WHERE s.user_id = ids.user_id AND s.revoked_at IS NULL AND s.expires_at > CURRENT_TIMESTAMP) AS active_sessions,
+  (SELECT MAX(s.issued_at) FROM public.oauth_sessions s WHERE s.user_id = ids.user_id) AS last_seen_at
+FROM (
+  SELECT user_id FROM public.tasks
+  UNION SELECT user_id FROM public.goals
+  UNION SELECT user_id FROM public.api_keys
+  UNION SELECT user_id FROM public.oauth_sessions
+) ids;
+
+-- One row of usage totals for /admin/metrics
+CREATE OR REPLACE VIEW public.admin_usage AS
+SELECT
+  (SELECT COUNT(*) FROM public.admin_users) AS users,
+  (SELECT COUNT(*) FROM public.tasks WHERE deleted_at IS NULL) AS tasks,
+  (SELECT COUNT(*) FROM public.tasks WHERE deleted_at IS NULL AND created_at > CURRENT_TIMESTAMP - INTERVAL '1 day') AS tasks_created_24h,
+  (SELECT COUNT(*) FROM public.goals WHERE deleted_at IS NULL) AS goals,
+  (SELECT COUNT(*) FROM public.oauth_clients) AS oauth_clients,
+  (SELECT COUNT(*) FROM public.api_keys WHERE revoked_at IS NULL) AS api_keys,
+  (SELECT COUNT(*) FROM public.oauth_sessions WHERE revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP) AS active_sessions,
+  (SELECT COUNT(*) FROM public.oauth_sessions WHERE issued_at > CURRENT_TIMESTAMP - INTERVAL '1 day') AS tokens_issued_24h;
+
+-- The views run as their owner, past every table's RLS, so only the server
+-- (with the service-role key) may query them
+REVOKE ALL ON public.admin_users, public.admin_usage FROM anon, authenticated;
@@ -0,0 +1,17 @@
+-- Per-user calendar preferences: the time zone relative dates, "today" and
+-- "overdue" are computed in, the first day of the week and working hours.
+-- Users without a row get the server defaults.
+CREATE TABLE IF NOT EXISTS public.user_preferences (
+  user_id TEXT PRIMARY KEY,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  locale TEXT NOT NULL DEFAULT 'en-US',
+  week_start TEXT NOT NULL DEFAULT 'monday',
+  work_start TEXT NOT NULL DEFAULT '09:00',
+  work_end TEXT NOT NULL DEFAULT '17:00',
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE public.user_preferences ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.user_preferences
+  FOR ALL USING (true) WITH CHECK (true);
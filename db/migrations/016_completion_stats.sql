@@ -0,0 +1,28 @@
+-- Running completion streak and totals per user, updated on every completion
+-- instead of being recomputed from task_completions. last_active_day is a
+-- day in the user's time zone.
+CREATE TABLE IF NOT EXISTS public.completion_stats (
+  user_id TEXT PRIMARY KEY,
+  current_streak INTEGER NOT NULL DEFAULT 0,
+  longest_streak INTEGER NOT NULL DEFAULT 0,
+  total_completed INTEGER NOT NULL DEFAULT 0,
+  last_active_day DATE,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Completions per week, for weekly velocity. Weeks start on the user's
+-- preferred first day of the week.
+CREATE TABLE IF NOT EXISTS public.weekly_completions (
+  user_id TEXT NOT NULL,
+  week_start DATE NOT NULL,
+  completed INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, week_start)
+);
+
+ALTER TABLE public.completion_stats ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.weekly_completions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.completion_stats
+  FOR ALL USING (true) WITH CHECK (true);
+CREATE POLICY "Allow all for authenticated users" ON public.weekly_completions
+  FOR ALL USING (true) WITH CHECK (true);
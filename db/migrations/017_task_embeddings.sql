@@ -0,0 +1,45 @@
+-- Embeddings of task titles and descriptions, for semantic search, related
+-- tasks and duplicate detection. Each vector is kept with the model that made
+-- it, since vectors from different models are not comparable, and the column
+-- has no fixed dimension so the model can change without a migration.
+-- content_hash tells when a task's text changed and it needs embedding again.
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS public.task_embeddings (
+  task_id UUID PRIMARY KEY REFERENCES public.tasks(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL,
+  model TEXT NOT NULL,
+  content_hash TEXT NOT NULL,
+  embedding vector NOT NULL,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_embeddings_user_model ON public.task_embeddings(user_id, model);
+
+ALTER TABLE public.task_embeddings ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.task_embeddings
+  FOR ALL USING (true) WITH CHECK (true);
+
+-- match_task_embeddings ranks a user's tasks by cosine similarity to
+-- query_embedding, most similar first, skipping tasks in the trash. It is
+-- called through the REST API as POST /rest/v1/rpc/match_task_embeddings.
+CREATE OR REPLACE FUNCTION public.match_task_embeddings(
+  query_embedding vector,
+  match_user_id TEXT,
+  match_model TEXT,
+  match_count INTEGER
+)
+RETURNS TABLE (task_id UUID, similarity DOUBLE PRECISION)
+LANGUAGE sql STABLE
+AS $$
+  SELECT e.task_id, 1 - (e.embedding <=> query_embedding) AS similarity
+  FROM public.task_embeddings e
+  JOIN public.tasks t ON t.id = e.task_id
+  WHERE e.user_id = match_user_id
+    AND e.model = match_model
+    AND t.deleted_at IS NULL
+    AND vector_dims(e.embedding) = vector_dims(query_embedding)
+  ORDER BY e.embedding <=> query_embedding
+  LIMIT match_count;
+$$;
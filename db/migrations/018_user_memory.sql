@@ -0,0 +1,18 @@
+-- What the assistant remembers about each user across MCP sessions: facts
+-- ("works at Acme"), preferences ("default_category": "work") and recurring
+-- commitments ("standup every weekday at 9:30"). Entries are keyed per user
+-- so setting a key again replaces its value.
+CREATE TABLE IF NOT EXISTS public.user_memory (
+  user_id TEXT NOT NULL,
+  key TEXT NOT NULL,
+  kind TEXT NOT NULL DEFAULT 'fact' CHECK (kind IN ('fact', 'preference', 'commitment')),
+  value TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, key)
+);
+
+ALTER TABLE public.user_memory ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.user_memory
+  FOR ALL USING (true) WITH CHECK (true);
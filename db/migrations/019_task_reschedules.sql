@@ -0,0 +1,21 @@
+-- Reschedule history: one row each time a task is snoozed, so how often and
+-- how far tasks get pushed back can be analysed later. source is 'phrase'
+-- when the user named the time and 'smart' when the server picked it.
+CREATE TABLE IF NOT EXISTS public.task_reschedules (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id TEXT NOT NULL,
+  task_id UUID NOT NULL REFERENCES public.tasks(id) ON DELETE CASCADE,
+  previous_due_date TIMESTAMP WITH TIME ZONE,
+  new_due_date TIMESTAMP WITH TIME ZONE NOT NULL,
+  source TEXT NOT NULL CHECK (source IN ('phrase', 'smart')),
+  phrase TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_reschedules_task ON public.task_reschedules(task_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_task_reschedules_user ON public.task_reschedules(user_id, created_at DESC);
+
+ALTER TABLE public.task_reschedules ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.task_reschedules
+  FOR ALL USING (true) WITH CHECK (true);
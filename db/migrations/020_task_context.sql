@@ -0,0 +1,5 @@
+-- Where or in what situation a task can be done, such as @home, @office or
+-- @errands, so the assistant can answer "what can I do while I'm out"
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS context TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_context ON public.tasks(user_id, context) WHERE context IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Personal ingest addresses: mail forwarded to tasks+TOKEN@EMAIL_INGEST_DOMAIN
+-- becomes tasks for the user holding TOKEN. Rotating the token replaces the
+-- row, so the old address stops working at once.
+CREATE TABLE IF NOT EXISTS public.email_ingest_addresses (
+  user_id TEXT PRIMARY KEY,
+  token TEXT NOT NULL UNIQUE,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The token is all a sender needs to add tasks for the user, so only the
+-- server (with the service-role key) may read or write the table: RLS with no policy
+ALTER TABLE public.email_ingest_addresses ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,35 @@
+-- Notion sync: each user's integration token and the databases their tasks
+-- and goals are mirrored into, and which page mirrors which task or goal.
+-- content_hash fingerprints the row as last written to or read from Notion,
+-- so either side's edits can be told apart from the sync's own.
+CREATE TABLE IF NOT EXISTS public.notion_connections (
+  user_id TEXT PRIMARY KEY,
+  token TEXT NOT NULL,
+  tasks_database_id TEXT,
+  goals_database_id TEXT,
+  pull BOOLEAN NOT NULL DEFAULT false,
+  last_synced_at TIMESTAMP WITH TIME ZONE,
+  last_error TEXT,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS public.notion_pages (
+  user_id TEXT NOT NULL,
+  entity_type TEXT NOT NULL CHECK (entity_type IN ('task', 'goal')),
+  entity_id UUID NOT NULL,
+  page_id TEXT NOT NULL,
+  content_hash TEXT NOT NULL,
+  synced_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (entity_type, entity_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notion_pages_user ON public.notion_pages(user_id);
+
+ALTER TABLE public.notion_pages ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.notion_pages
+  FOR ALL USING (true) WITH CHECK (true);
+
+-- Connections hold the user's Notion token, so only the server (with the
+-- service-role key) may read or write them: RLS with no policy
+ALTER TABLE public.notion_connections ENABLE ROW LEVEL SECURITY;
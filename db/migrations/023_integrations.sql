@@ -0,0 +1,29 @@
+-- Integrations: one connection per user and provider, holding the
+-- provider's credentials, sealed by the server before they are stored, and
+-- its other settings, and the outside items (such as Notion pages) mirroring
+-- tasks and goals. Notion tokens were stored in plain text and cannot be
+-- sealed here, so Notion users reconnect; the links to their pages are kept.
+CREATE TABLE IF NOT EXISTS public.integration_connections (
+  user_id TEXT NOT NULL,
+  provider TEXT NOT NULL,
+  credentials TEXT NOT NULL,
+  settings JSONB NOT NULL DEFAULT '{}'::jsonb,
+  last_synced_at TIMESTAMP WITH TIME ZONE,
+  last_error TEXT,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, provider)
+);
+
+-- Connections hold sealed provider credentials, so only the server (with the
+-- service-role key) may read or write them: RLS with no policy
+ALTER TABLE public.integration_connections ENABLE ROW LEVEL SECURITY;
+
+ALTER TABLE public.notion_pages RENAME TO integration_links;
+ALTER TABLE public.integration_links RENAME COLUMN page_id TO external_id;
+ALTER TABLE public.integration_links ADD COLUMN provider TEXT NOT NULL DEFAULT 'notion';
+ALTER TABLE public.integration_links ALTER COLUMN provider DROP DEFAULT;
+ALTER TABLE public.integration_links DROP CONSTRAINT notion_pages_pkey;
+ALTER TABLE public.integration_links ADD PRIMARY KEY (provider, entity_type, entity_id);
+ALTER INDEX public.idx_notion_pages_user RENAME TO idx_integration_links_user;
+
+DROP TABLE IF EXISTS public.notion_connections;
@@ -0,0 +1,24 @@
+-- Secrets users hand the server, such as integration tokens, sealed with
+-- envelope encryption: ciphertext is the value under its own data key and
+-- wrapped_key that data key under the master key named by key_id, both
+-- base64. Rows still under a retired master key can be found by key_id.
+-- Integration credentials move here from integration_connections; those
+-- sealed there cannot be converted in SQL, so users connect again.
+CREATE TABLE IF NOT EXISTS public.user_credentials (
+  user_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  key_id TEXT NOT NULL,
+  wrapped_key TEXT NOT NULL,
+  ciphertext TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_credentials_key_id ON public.user_credentials(key_id);
+
+-- Secrets get no policy, unlike the app tables: with RLS on, only the server
+-- (with the service-role key) may read or write them
+ALTER TABLE public.user_credentials ENABLE ROW LEVEL SECURITY;
+
+ALTER TABLE public.integration_connections DROP COLUMN IF EXISTS credentials;
@@ -0,0 +1,18 @@
+-- MCP sessions saved by an instance on shutdown. The instance that replaces it
+-- looks a session up here the first time its client comes back, then deletes
+-- the row and keeps the session in memory.
+CREATE TABLE IF NOT EXISTS public.mcp_sessions (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  protocol_version TEXT NOT NULL,
+  client_name TEXT,
+  client_version TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_sessions_last_seen_at ON public.mcp_sessions(last_seen_at);
+
+-- A session ID is a credential for the session's user, so only the server
+-- (with the service-role key) may read or write the table: RLS with no policy
+ALTER TABLE public.mcp_sessions ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,5 @@
+-- Subtasks point at the task they break down, so nested checklists keep
+-- their shape through an import and back out again
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES public.tasks(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON public.tasks(parent_id) WHERE parent_id IS NOT NULL;
@@ -0,0 +1,10 @@
+-- The plan each user is on. Users without a row are on PLAN_DEFAULT.
+CREATE TABLE IF NOT EXISTS public.user_plans (
+  user_id TEXT PRIMARY KEY,
+  plan TEXT NOT NULL CHECK (plan IN ('free', 'pro')),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Users must not pick their own plan, so only the server (with the
+-- service-role key) may read or write the table: RLS with no policy
+ALTER TABLE public.user_plans ENABLE ROW LEVEL SECURITY;
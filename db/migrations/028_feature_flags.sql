@@ -0,0 +1,12 @@
+-- Feature flag rules set from /admin/flags. A row replaces the flag's rule
+-- from FEATURE_FLAGS and FEATURE_FLAG_USERS.
+CREATE TABLE IF NOT EXISTS public.feature_flags (
+  name TEXT PRIMARY KEY,
+  percent INTEGER NOT NULL CHECK (percent BETWEEN 0 AND 100),
+  users TEXT[] NOT NULL DEFAULT '{}',
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Flags are set from /admin/flags only, so only the server (with the
+-- service-role key) may read or write the table: RLS with no policy
+ALTER TABLE public.feature_flags ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,14 @@
+-- The scopes each user approved for each OAuth client on the consent screen.
+-- An authorization asking for no more than this skips the screen.
+CREATE TABLE IF NOT EXISTS public.oauth_grants (
+  user_id TEXT NOT NULL,
+  client_id TEXT NOT NULL,
+  scope TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, client_id)
+);
+
+-- A grant lets its client skip the consent screen, so only the server (with
+-- the service-role key) may read or write the table: RLS with no policy
+ALTER TABLE public.oauth_grants ENABLE ROW LEVEL SECURITY;
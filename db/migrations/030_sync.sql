@@ -0,0 +1,59 @@
+-- Incremental sync. updated_at is stamped by the database on every write, so
+-- it orders changes whatever the writer sent, and hard deletes leave a
+-- tombstone behind so clients syncing later still hear about them. A task or
+-- goal handed to another user is a deletion for its previous owner.
+CREATE OR REPLACE FUNCTION public.touch_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at := now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tasks_touch_updated_at ON public.tasks;
+CREATE TRIGGER tasks_touch_updated_at BEFORE INSERT OR UPDATE ON public.tasks
+  FOR EACH ROW EXECUTE FUNCTION public.touch_updated_at();
+DROP TRIGGER IF EXISTS goals_touch_updated_at ON public.goals;
+CREATE TRIGGER goals_touch_updated_at BEFORE INSERT OR UPDATE ON public.goals
+  FOR EACH ROW EXECUTE FUNCTION public.touch_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_tasks_sync ON public.tasks(user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_goals_sync ON public.goals(user_id, updated_at, id);
+
+CREATE TABLE IF NOT EXISTS public.sync_tombstones (
+  id BIGSERIAL PRIMARY KEY,
+  entity_type TEXT NOT NULL CHECK (entity_type IN ('task', 'goal')),
+  entity_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  deleted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_tombstones_user ON public.sync_tombstones(user_id, deleted_at, id);
+
+-- TG_ARGV[0] is the entity_type of the table the trigger is on
+CREATE OR REPLACE FUNCTION public.record_sync_tombstone() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO public.sync_tombstones (entity_type, entity_id, user_id)
+  VALUES (TG_ARGV[0], OLD.id::text, OLD.user_id);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tasks_sync_tombstone ON public.tasks;
+CREATE TRIGGER tasks_sync_tombstone AFTER DELETE ON public.tasks
+  FOR EACH ROW EXECUTE FUNCTION public.record_sync_tombstone('task');
+DROP TRIGGER IF EXISTS tasks_sync_reassigned ON public.tasks;
+CREATE TRIGGER tasks_sync_reassigned AFTER UPDATE OF user_id ON public.tasks
+  FOR EACH ROW WHEN (OLD.user_id IS DISTINCT FROM NEW.user_id)
+  EXECUTE FUNCTION public.record_sync_tombstone('task');
+DROP TRIGGER IF EXISTS goals_sync_tombstone ON public.goals;
+CREATE TRIGGER goals_sync_tombstone AFTER DELETE ON public.goals
+  FOR EACH ROW EXECUTE FUNCTION public.record_sync_tombstone('goal');
+DROP TRIGGER IF EXISTS goals_sync_reassigned ON public.goals;
+CREATE TRIGGER goals_sync_reassigned AFTER UPDATE OF user_id ON public.goals
+  FOR EACH ROW WHEN (OLD.user_id IS DISTINCT FROM NEW.user_id)
+  EXECUTE FUNCTION public.record_sync_tombstone('goal');
+
+ALTER TABLE public.sync_tombstones ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Allow all for authenticated users" ON public.sync_tombstones
+  FOR ALL USING (true) WITH CHECK (true);
@@ -0,0 +1,26 @@
+-- When each field of a task or goal last changed, so a change a client made
+-- offline conflicts only with the fields changed on the server since the copy
+-- it edited. Maintained by the database on every update.
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS field_updated_at JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE public.goals ADD COLUMN IF NOT EXISTS field_updated_at JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE OR REPLACE FUNCTION public.stamp_field_updates() RETURNS trigger AS $$
+DECLARE
+  changed JSONB;
+BEGIN
+  SELECT COALESCE(jsonb_object_agg(n.key, to_jsonb(now())), '{}'::jsonb) INTO changed
+  FROM jsonb_each(to_jsonb(NEW)) n
+  JOIN jsonb_each(to_jsonb(OLD)) o ON o.key = n.key
+  WHERE n.value IS DISTINCT FROM o.value
+    AND n.key NOT IN ('updated_at', 'field_updated_at');
+  NEW.field_updated_at := COALESCE(OLD.field_updated_at, '{}'::jsonb) || changed;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tasks_stamp_field_updates ON public.tasks;
+CREATE TRIGGER tasks_stamp_field_updates BEFORE UPDATE ON public.tasks
+  FOR EACH ROW EXECUTE FUNCTION public.stamp_field_updates();
+DROP TRIGGER IF EXISTS goals_stamp_field_updates ON public.goals;
+CREATE TRIGGER goals_stamp_field_updates BEFORE UPDATE ON public.goals
+  FOR EACH ROW EXECUTE FUNCTION public.stamp_field_updates();
@@ -0,0 +1,28 @@
+-- Functions called through SupabaseClient.Rpc, for operations that must see
+-- and change several rows at once without another write slipping in between.
+
+-- Sets a goal's progress from the share of its milestones completed, rounded
+-- down, and returns it; a goal without milestones keeps its progress and NULL
+-- is returned. The goal row is locked, so concurrent milestone changes roll
+-- up one after the other.
+CREATE OR REPLACE FUNCTION public.sync_goal_progress(p_goal_id UUID) RETURNS INTEGER AS $$
+DECLARE
+  total INTEGER;
+  done INTEGER;
+  new_progress INTEGER;
+BEGIN
+  PERFORM 1 FROM public.goals WHERE id = p_goal_id FOR UPDATE;
+  SELECT count(*), count(*) FILTER (WHERE completed)
+    INTO total, done
+    FROM public.goal_milestones
+   WHERE goal_id = p_goal_id;
+  IF total = 0 THEN
+    RETURN NULL;
+  END IF;
+  new_progress := done * 100 / total;
+  UPDATE public.goals
+     SET progress = new_progress, updated_at = now()
+   WHERE id = p_goal_id AND progress IS DISTINCT FROM new_progress;
+  RETURN new_progress;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,10 @@
+// Package migrations embeds the Supabase schema migrations so the binary can
+// apply them without the source tree.
+package migrations
+
+import "embed"
+
+// FS holds every NNN_name.sql migration; they are applied in file name order
+//
+//go:embed *.sql
+var FS embed.FS
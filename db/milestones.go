@@ -0,0 +1,42 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// CreateMilestone stores a milestone under a goal and returns it
+func (sc *SupabaseClient) CreateMilestone(milestoneData map[string]interface{}) (map[string]interface{}, error) {
+	return sc.insertRow("goal_milestones", milestoneData, "create milestone")
+}
+
+// GetMilestone returns one of a goal's milestones, or nil if none matches
+func (sc *SupabaseClient) GetMilestone(goalID, milestoneID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("goal_milestones?id=eq.%s&goal_id=eq.%s&select=*",
+		url.QueryEscape(milestoneID), url.QueryEscape(goalID)), "get milestone")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// ListMilestones lists a goal's milestones by due date, undated ones last
+func (sc *SupabaseClient) ListMilestones(goalID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("goal_milestones?goal_id=eq.%s&select=*&order=due_date.asc.nullslast,created_at.asc",
+		url.QueryEscape(goalID)), "list milestones")
+}
+
+// UpdateMilestone patches a milestone and returns it, or nil if it no longer exists
+func (sc *SupabaseClient) UpdateMilestone(milestoneID string, milestoneData map[string]interface{}) (map[string]interface{}, error) {
+	rows, err := sc.updateRowsReturning(fmt.Sprintf("goal_milestones?id=eq.%s", url.QueryEscape(milestoneID)),
+		milestoneData, "update milestone")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// DeleteMilestone deletes a milestone
+func (sc *SupabaseClient) DeleteMilestone(milestoneID string) error {
+	return sc.deleteRows(fmt.Sprintf("goal_milestones?id=eq.%s", url.QueryEscape(milestoneID)), "delete milestone")
+}
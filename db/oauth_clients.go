@@ -0,0 +1,33 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// UpsertOAuthClient stores a client (secret hash, redirect URIs and name), replacing any previous registration
+func (sc *SupabaseClient) UpsertOAuthClient(clientID string, clientData map[string]interface{}) (map[string]interface{}, error) {
+	clientData["client_id"] = clientID
+	clientData["updated_at"] = time.Now().UTC().Format(time.RFC3339)
+	return sc.upsertRow("oauth_clients", "client_id", clientData, "upsert OAuth client")
+}
+
+// GetOAuthClient returns a registered client, or nil if there is none
+func (sc *SupabaseClient) GetOAuthClient(clientID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("oauth_clients?client_id=eq.%s&select=*", url.QueryEscape(clientID)), "get OAuth client")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// DeleteOAuthClient removes a registered client
+func (sc *SupabaseClient) DeleteOAuthClient(clientID string) error {
+	return sc.deleteRows(fmt.Sprintf("oauth_clients?client_id=eq.%s", url.QueryEscape(clientID)), "delete OAuth client")
+}
+
+// ListOAuthClients lists registered clients without their secret or token hashes
+func (sc *SupabaseClient) ListOAuthClients() ([]map[string]interface{}, error) {
+	return sc.selectRows("oauth_clients?select=client_id,name,redirect_uris,token_endpoint_auth_method,grant_types,scope,client_id_issued_at&order=client_id.asc", "list OAuth clients")
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// GetOAuthGrant returns the scopes userID approved for clientID, or nil if
+// they approved none
+func (sc *SupabaseClient) GetOAuthGrant(userID, clientID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("oauth_grants?user_id=eq.%s&client_id=eq.%s&select=*",
+		url.QueryEscape(userID), url.QueryEscape(clientID)), "get OAuth grant")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// UpsertOAuthGrant records the scopes userID approved for clientID,
+// replacing the ones approved before
+func (sc *SupabaseClient) UpsertOAuthGrant(userID, clientID, scope string) (map[string]interface{}, error) {
+	return sc.upsertRow("oauth_grants", "user_id,client_id", map[string]interface{}{
+		"user_id":    userID,
+		"client_id":  clientID,
+		"scope":      scope,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}, "upsert OAuth grant")
+}
+
+// ListOAuthGrants lists the clients userID approved, most recent first
+func (sc *SupabaseClient) ListOAuthGrants(userID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("oauth_grants?user_id=eq.%s&select=*&order=updated_at.desc", url.QueryEscape(userID)), "list OAuth grants")
+}
+
+// DeleteOAuthGrant forgets what userID approved for clientID and returns
+// how many grants were deleted
+func (sc *SupabaseClient) DeleteOAuthGrant(userID, clientID string) (int, error) {
+	return sc.deleteRowsCounted(fmt.Sprintf("oauth_grants?user_id=eq.%s&client_id=eq.%s",
+		url.QueryEscape(userID), url.QueryEscape(clientID)), "delete OAuth grant")
+}
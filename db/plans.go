@@ -0,0 +1,32 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// GetUserPlan returns the plan a user was assigned, or "" if none was
+func (sc *SupabaseClient) GetUserPlan(userID string) (string, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("user_plans?user_id=eq.%s&select=plan", url.QueryEscape(userID)), "get user plan")
+	if err != nil || len(rows) == 0 {
+		return "", err
+	}
+	plan, _ := rows[0]["plan"].(string)
+	return plan, nil
+}
+
+// SetUserPlan assigns a user a plan
+func (sc *SupabaseClient) SetUserPlan(userID, plan string) (map[string]interface{}, error) {
+	return sc.upsertRow("user_plans", "user_id", map[string]interface{}{
+		"user_id":    userID,
+		"plan":       plan,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}, "set user plan")
+}
+
+// CountTasks returns how many tasks a user has outside the trash
+func (sc *SupabaseClient) CountTasks(userID string) (int, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("tasks?user_id=eq.%s&deleted_at=is.null&select=id", url.QueryEscape(userID)), "count tasks")
+	return len(rows), err
+}
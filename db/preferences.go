@@ -0,0 +1,26 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// GetPreferences returns a user's preferences, or nil if they never set any
+func (sc *SupabaseClient) GetPreferences(userID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("user_preferences?user_id=eq.%s&select=*", url.QueryEscape(userID)), "get preferences")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// UpsertPreferences creates or replaces a user's preferences
+func (sc *SupabaseClient) UpsertPreferences(userID string, prefs map[string]interface{}) (map[string]interface{}, error) {
+	prefs["user_id"] = userID
+	return sc.upsertRow("user_preferences", "user_id", prefs, "upsert preferences")
+}
+
+// DeletePreferences removes a user's preferences, restoring the defaults
+func (sc *SupabaseClient) DeletePreferences(userID string) error {
+	return sc.deleteRows(fmt.Sprintf("user_preferences?user_id=eq.%s", url.QueryEscape(userID)), "delete preferences")
+}
@@ -0,0 +1,20 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// RecordReschedule appends an entry to a task's reschedule history
+func (sc *SupabaseClient) RecordReschedule(userID, taskID string, entry map[string]interface{}) error {
+	entry["user_id"] = userID
+	entry["task_id"] = taskID
+	_, err := sc.insertRow("task_reschedules", entry, "record reschedule")
+	return err
+}
+
+// GetTaskReschedules returns a task's reschedule history, oldest first
+func (sc *SupabaseClient) GetTaskReschedules(taskID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("task_reschedules?task_id=eq.%s&select=*&order=created_at.asc",
+		url.QueryEscape(taskID)), "get task reschedules")
+}
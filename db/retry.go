@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// noRetry makes a request once, for clients such as SQLite's that have no
+// network in between to fail
+var noRetry = newRetryConfig(config.Supabase{})
+
+// newRetryConfig retries the attempts newAttemptError finds worth
+// repeating, up to cfg.MaxRetries times, with jittered exponential backoff
+// from 100ms or as long as Retry-After asks, up to five seconds
+func newRetryConfig(cfg config.Supabase) *utils.RetryConfig {
+	return &utils.RetryConfig{
+		MaxAttempts:  cfg.MaxRetries + 1,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     5 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.5,
+		ShouldRetry: func(err error) bool {
+			var attempt *attemptError
+			return errors.As(err, &attempt) && attempt.retry
+		},
+		RetryAfter: func(err error) time.Duration {
+			var attempt *attemptError
+			if errors.As(err, &attempt) {
+				return attempt.retryAfter
+			}
+			return 0
+		},
+	}
+}
+
+// attemptError is an attempt at a request that failed in a way expected to
+// pass: rate limited, refused by the gateway in front of the database, or
+// lost in transit
+type attemptError struct {
+	status     string // empty when no answer came
+	retry      bool   // whether repeating the request is safe
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *attemptError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return e.status
+}
+
+func (e *attemptError) Unwrap() error {
+	return e.err
+}
+
+// idempotentMethod reports whether repeating a request has the same effect
+// as making it once
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// newAttemptError returns the attemptError for an attempt at method that
+// got resp or failed with err, or nil when the outcome is the database's
+// answer. A 429 means the request was turned away before it did anything,
+// so any request may be repeated. A gateway error (502, 503, 504) or a
+// request lost in transit may have been carried out all the same, so only
+// idempotent requests are. Other client errors, and a 500, which PostgREST
+// gives for errors the database raised, would only fail the same way again.
+func newAttemptError(method string, resp *http.Response, err error) *attemptError {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil
+		}
+		return &attemptError{retry: idempotentMethod(method), err: err}
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return &attemptError{status: resp.Status, retry: true, retryAfter: utils.ParseRetryAfter(resp.Header.Get("Retry-After"))}
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &attemptError{status: resp.Status, retry: idempotentMethod(method), retryAfter: utils.ParseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	return nil
+}
+
+// errUnavailable reports a request whose last attempt failed as attempt
+// did: Supabase being unavailable, answered with 503 rather than blamed on
+// this server
+func errUnavailable(attempt *attemptError) error {
+	return utils.NewAppError(utils.ErrCodeExternal, "the database is temporarily unavailable; try again shortly",
+		http.StatusServiceUnavailable).WithError(fmt.Errorf("supabase: %w", attempt))
+}
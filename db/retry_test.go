@@ -0,0 +1,81 @@
+//go:build !lite
+
+package db
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestRequestRetries(t *testing.T) {
+	cfg := config.Defaults().Supabase
+	cfg.MaxRetries = 2
+	ConfigureHTTP(cfg)
+	defer ConfigureHTTP(config.Defaults().Supabase)
+
+	var calls atomic.Int32
+	var answers []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		status := http.StatusOK
+		if n < len(answers) {
+			status = answers[n]
+		}
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	client, err := NewSupabaseClient(server.URL, "retry-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		answers  []int
+		calls    int32
+		status   int // the answer the caller sees
+		degraded bool
+	}{
+		{"read after gateway errors", http.MethodGet, []int{503, 502}, 3, 200, false},
+		{"read never answered", http.MethodGet, []int{504, 504, 504}, 3, 0, true},
+		{"rate limited write", http.MethodPost, []int{429}, 2, 200, false},
+		{"write at a gateway error", http.MethodPost, []int{503}, 1, 0, true},
+		{"client error", http.MethodGet, []int{400}, 1, 400, false},
+		{"database error", http.MethodPatch, []int{500}, 1, 500, false},
+	}
+	for _, tc := range tests {
+		calls.Store(0)
+		answers = tc.answers
+		resp, err := client.makeRequest(tc.method, "tasks", nil)
+		if got := calls.Load(); got != tc.calls {
+			t.Errorf("%s: %d attempts, want %d", tc.name, got, tc.calls)
+		}
+		var appErr *utils.AppError
+		if tc.degraded {
+			if !errors.As(err, &appErr) || appErr.HTTPStatus != http.StatusServiceUnavailable {
+				t.Errorf("%s: got %v, want a 503", tc.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.status)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ErrFunctionNotFound is returned by Rpc for a function the database does
+// not have, such as one whose migration has not run, or any function in the
+// lite build
+var ErrFunctionNotFound = errors.New("database function not found")
+
+// Rpc calls the Postgres function name with args, named as its parameters
+// are, and decodes what it returns into into unless into is nil. The
+// function runs in a single transaction, so an operation of several steps
+// done there either happens entirely or not at all. Functions may write to
+// any table, so a call drops every cached task list.
+func (sc *SupabaseClient) Rpc(name string, args map[string]interface{}, into interface{}) error {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	resp, err := sc.makeRequest("POST", "rpc/"+url.PathEscape(name), args)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %s - %s", ErrFunctionNotFound, name, string(body))
+	default:
+		return newPostgRESTError("call "+name, resp)
+	}
+
+	if into == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", name, err)
+	}
+	return nil
+}
+
+// SyncGoalProgress sets a goal's progress from its milestones in one
+// transaction, returning the progress, or nil for a goal without milestones,
+// which keeps the progress it was given
+func (sc *SupabaseClient) SyncGoalProgress(goalID string) (*int, error) {
+	var progress *int
+	if err := sc.Rpc("sync_goal_progress", map[string]interface{}{"p_goal_id": goalID}, &progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
@@ -0,0 +1,60 @@
+//go:build !lite
+
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRpc(t *testing.T) {
+	ConfigureTaskCache(time.Minute)
+	defer ConfigureTaskCache(0)
+
+	var reads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet:
+			reads++
+			w.Write([]byte(`[{"id":"t1","user_id":"u1"}]`))
+		case r.URL.Path == "/rest/v1/rpc/sync_goal_progress":
+			var args map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&args); err != nil || args["p_goal_id"] != "g1" {
+				t.Errorf("args = %v, %v", args, err)
+			}
+			w.Write([]byte(`50`))
+		case r.URL.Path == "/rest/v1/rpc/no_result":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"PGRST202","message":"Could not find the function"}`))
+		}
+	}))
+	defer server.Close()
+	client, err := NewSupabaseClient(server.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.GetUserTasks("u1")
+	progress, err := client.SyncGoalProgress("g1")
+	if err != nil || progress == nil || *progress != 50 {
+		t.Fatalf("SyncGoalProgress = %v, %v; want 50", progress, err)
+	}
+	client.GetUserTasks("u1")
+	if reads != 2 {
+		t.Errorf("a function call should drop cached task lists; %d reads, want 2", reads)
+	}
+
+	if err := client.Rpc("no_result", nil, nil); err != nil {
+		t.Errorf("no_result: %v", err)
+	}
+	if err := client.Rpc("missing", nil, nil); !errors.Is(err, ErrFunctionNotFound) {
+		t.Errorf("missing function: got %v, want ErrFunctionNotFound", err)
+	}
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// InsertRows inserts rows into table in one request, for bulk loads such as
+// the demo data of the seed command. Columns a row leaves out get their
+// defaults.
+func (sc *SupabaseClient) InsertRows(table string, rows []map[string]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	columns := map[string]bool{}
+	for _, row := range rows {
+		for column := range row {
+			columns[column] = true
+		}
+	}
+	names := make([]string, 0, len(columns))
+	for column := range columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+
+	resp, err := sc.makeRequestWithPrefer("POST", table+"?columns="+url.QueryEscape(strings.Join(names, ",")), rows, "return=minimal,missing=default")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newPostgRESTError("insert "+table, resp)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RecordSession stores an issued access token (jti, user, client, scope and expiry)
+func (sc *SupabaseClient) RecordSession(sessionData map[string]interface{}) error {
+	_, err := sc.insertRow("oauth_sessions", sessionData, "record session")
+	return err
+}
+
+// GetSession returns the session for an access token, or nil if none was recorded
+func (sc *SupabaseClient) GetSession(jti string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("oauth_sessions?jti=eq.%s&select=*", url.QueryEscape(jti)), "get session")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// ListSessions lists sessions newest first, filtered by user_id and client_id
+// when set; active limits the list to unrevoked, unexpired tokens
+func (sc *SupabaseClient) ListSessions(filters map[string]string, active bool, limit int) ([]map[string]interface{}, error) {
+	query := []string{"select=*", "order=issued_at.desc", fmt.Sprintf("limit=%d", limit)}
+	for _, key := range []string{"user_id", "client_id"} {
+		if value := filters[key]; value != "" {
+			query = append(query, fmt.Sprintf("%s=eq.%s", key, url.QueryEscape(value)))
+		}
+	}
+	if active {
+		query = append(query, "revoked_at=is.null",
+			"expires_at=gt."+url.QueryEscape(time.Now().UTC().Format(time.RFC3339)))
+	}
+	return sc.selectRows("oauth_sessions?"+strings.Join(query, "&"), "list sessions")
+}
+
+// MarkSessionRevoked records when a session's token was revoked; tokens
+// without a recorded session are left alone
+func (sc *SupabaseClient) MarkSessionRevoked(jti string, revokedAt time.Time) error {
+	return sc.updateRows(fmt.Sprintf("oauth_sessions?jti=eq.%s&revoked_at=is.null", url.QueryEscape(jti)), map[string]interface{}{
+		"revoked_at": revokedAt.UTC().Format(time.RFC3339),
+	}, "mark session revoked")
+}
+
+// PurgeSessions removes sessions whose tokens expired before cutoff and returns how many
+func (sc *SupabaseClient) PurgeSessions(cutoff time.Time) (int, error) {
+	return sc.deleteRowsCounted(fmt.Sprintf("oauth_sessions?expires_at=lt.%s", url.QueryEscape(cutoff.UTC().Format(time.RFC3339))), "purge sessions")
+}
+
+// ListAdminUsers lists known users, most recently seen first
+func (sc *SupabaseClient) ListAdminUsers(limit int) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("admin_users?select=*&order=last_seen_at.desc.nullslast,user_id.asc&limit=%d", limit), "list users")
+}
+
+// GetUsage returns the usage totals row
+func (sc *SupabaseClient) GetUsage() (map[string]interface{}, error) {
+	rows, err := sc.selectRows("admin_usage?select=*", "get usage")
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	return rows[0], nil
+}
@@ -0,0 +1,851 @@
+//go:build lite
+
+package db
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// liteBuild reports whether this is the lite build (go build -tags lite)
+const liteBuild = true
+
+// sqliteSchema mirrors the Supabase tables used by the lite build. Timestamps
+// are RFC 3339 text, BOOLEAN columns hold 0/1 and JSON columns hold JSONB and
+// text[] values.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS tasks (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT DEFAULT '',
+  priority INTEGER DEFAULT 2,
+  due_date TEXT NOT NULL,
+  estimated_duration INTEGER DEFAULT 0,
+  category TEXT DEFAULT 'work',
+  completed BOOLEAN DEFAULT 0,
+  completed_at TEXT,
+  recurring_frequency TEXT,
+  recurring_interval INTEGER,
+  recurring_end_date TEXT,
+  external_source TEXT,
+  external_id TEXT,
+  language TEXT,
+  context TEXT,
+  parent_id TEXT,
+  deferred_until TEXT,
+  deleted_at TEXT,
+  created_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_external_id
+  ON tasks(user_id, external_source, external_id) WHERE external_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS goals (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT DEFAULT '',
+  start_date TEXT NOT NULL,
+  target_date TEXT NOT NULL,
+  progress INTEGER DEFAULT 0,
+  archived BOOLEAN DEFAULT 0,
+  language TEXT,
+  deleted_at TEXT,
+  created_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+CREATE INDEX IF NOT EXISTS idx_goals_user_id ON goals(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS goal_milestones (
+  id TEXT PRIMARY KEY,
+  goal_id TEXT NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL,
+  title TEXT NOT NULL,
+  due_date TEXT,
+  completed BOOLEAN DEFAULT 0,
+  completed_at TEXT,
+  created_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+CREATE INDEX IF NOT EXISTS idx_goal_milestones_goal_id ON goal_milestones(goal_id, due_date);
+
+CREATE TABLE IF NOT EXISTS focus_sessions (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  started_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  ends_at TEXT NOT NULL,
+  ended_at TEXT,
+  source TEXT DEFAULT 'api',
+  created_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS focus_contracts (
+  user_id TEXT PRIMARY KEY,
+  enabled BOOLEAN NOT NULL DEFAULT 0,
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+  id TEXT PRIMARY KEY,
+  actor TEXT NOT NULL,
+  user_id TEXT,
+  entity_type TEXT NOT NULL,
+  entity_id TEXT NOT NULL,
+  action TEXT NOT NULL,
+  before JSON,
+  after JSON,
+  diff JSON,
+  request_id TEXT,
+  created_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_id ON audit_log(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS task_completions (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  completed_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS task_reschedules (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  previous_due_date TEXT,
+  new_due_date TEXT NOT NULL,
+  source TEXT NOT NULL,
+  phrase TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS streak_freezes (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  task_id TEXT REFERENCES tasks(id) ON DELETE CASCADE,
+  frozen_on TEXT NOT NULL,
+  created_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  UNIQUE (task_id, frozen_on)
+);
+
+CREATE TABLE IF NOT EXISTS grace_rules (
+  user_id TEXT PRIMARY KEY,
+  freezes_per_week INTEGER NOT NULL DEFAULT 1,
+  weekend_exempt BOOLEAN NOT NULL DEFAULT 0,
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+  user_id TEXT PRIMARY KEY,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  locale TEXT NOT NULL DEFAULT 'en-US',
+  week_start TEXT NOT NULL DEFAULT 'monday',
+  work_start TEXT NOT NULL DEFAULT '09:00',
+  work_end TEXT NOT NULL DEFAULT '17:00',
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS user_memory (
+  user_id TEXT NOT NULL,
+  key TEXT NOT NULL,
+  kind TEXT NOT NULL DEFAULT 'fact',
+  value TEXT NOT NULL,
+  created_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  PRIMARY KEY (user_id, key)
+);
+
+CREATE TABLE IF NOT EXISTS email_ingest_addresses (
+  user_id TEXT PRIMARY KEY,
+  token TEXT NOT NULL UNIQUE,
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS completion_stats (
+  user_id TEXT PRIMARY KEY,
+  current_streak INTEGER NOT NULL DEFAULT 0,
+  longest_streak INTEGER NOT NULL DEFAULT 0,
+  total_completed INTEGER NOT NULL DEFAULT 0,
+  last_active_day TEXT,
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS weekly_completions (
+  user_id TEXT NOT NULL,
+  week_start TEXT NOT NULL,
+  completed INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (user_id, week_start)
+);
+
+-- embedding holds the vector as a JSON array; similarity is computed in Go
+CREATE TABLE IF NOT EXISTS task_embeddings (
+  task_id TEXT PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+  user_id TEXT NOT NULL,
+  model TEXT NOT NULL,
+  content_hash TEXT NOT NULL,
+  embedding JSON NOT NULL,
+  updated_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  key_hash TEXT NOT NULL UNIQUE,
+  scopes JSON NOT NULL DEFAULT '[]',
+  created_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  expires_at TEXT,
+  last_used_at TEXT,
+  revoked_at TEXT
+);
+
+-- The lite build is single-user and has no workspaces; this table only lets
+-- the task and goal handlers' membership lookups come back empty
+CREATE TABLE IF NOT EXISTS workspace_members (
+  workspace_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  role TEXT NOT NULL,
+  created_at TEXT DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+  PRIMARY KEY (workspace_id, user_id)
+);
+`
+
+// sqliteBaseURL is never dialled; requests to it are answered by sqliteTransport
+const sqliteBaseURL = "http://sqlite.local/"
+
+var (
+	sqliteMu         sync.Mutex
+	sqliteTransports = make(map[string]*sqliteTransport)
+)
+
+// newSQLiteClient returns a client whose REST requests are executed against the
+// SQLite database at path. Handlers opening the same path share one connection.
+func newSQLiteClient(path string) (*SupabaseClient, error) {
+	if path == "" {
+		return nil, fmt.Errorf("SQLite database path is required")
+	}
+
+	sqliteMu.Lock()
+	defer sqliteMu.Unlock()
+
+	t, ok := sqliteTransports[path]
+	if !ok {
+		var err error
+		if t, err = openSQLite(path); err != nil {
+			return nil, err
+		}
+		sqliteTransports[path] = t
+	}
+
+	return &SupabaseClient{
+		baseURL:    sqliteBaseURL,
+		httpClient: &http.Client{Transport: t},
+	}, nil
+}
+
+// sqliteTransport answers the subset of PostgREST used by this package:
+// eq/neq/gt/gte/lt/lte/like/ilike/is/in filters (optionally negated with not.),
+// select, order, limit, offset and on_conflict upserts
+type sqliteTransport struct {
+	db      *sql.DB
+	columns map[string]map[string]string // table -> column -> declared type
+}
+
+func openSQLite(path string) (*sqliteTransport, error) {
+	if path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	conn, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// A single connection serialises writers and keeps :memory: databases shared
+	conn.SetMaxOpenConns(1)
+
+	if _, err := conn.Exec(sqliteSchema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create SQLite schema: %w", err)
+	}
+
+	t := &sqliteTransport{db: conn, columns: make(map[string]map[string]string)}
+	tables, err := conn.Query(`SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var names []string
+	for tables.Next() {
+		var name string
+		tables.Scan(&name)
+		names = append(names, name)
+	}
+	tables.Close()
+
+	for _, name := range names {
+		cols, err := conn.Query(`SELECT name, type FROM pragma_table_info(?)`, name)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		t.columns[name] = make(map[string]string)
+		for cols.Next() {
+			var col, typ string
+			cols.Scan(&col, &typ)
+			t.columns[name][col] = strings.ToUpper(typ)
+		}
+		cols.Close()
+	}
+	return t, nil
+}
+
+// restError is returned to the client as a PostgREST-style error body
+type restError struct {
+	status  int
+	message string
+}
+
+func (e *restError) Error() string { return e.message }
+
+func badRequest(format string, args ...interface{}) error {
+	return &restError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func (t *sqliteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status, rows, err := t.handle(req)
+	if err != nil {
+		var rerr *restError
+		var serr sqlite3.Error
+		body := map[string]string{"message": err.Error()}
+		switch {
+		case errors.As(err, &rerr):
+			status = rerr.status
+		case errors.As(err, &serr) && serr.Code == sqlite3.ErrConstraint:
+			// Coded as Postgres codes them, so errors classify the same
+			status = http.StatusConflict
+			switch serr.ExtendedCode {
+			case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+				body["code"] = pgUniqueViolation
+			case sqlite3.ErrConstraintForeignKey:
+				body["code"] = pgForeignKeyViolation
+			}
+		default:
+			status = http.StatusInternalServerError
+		}
+		return jsonResponse(req, status, body), nil
+	}
+	if req.Method == http.MethodHead {
+		resp := jsonResponse(req, status, nil)
+		resp.Header.Set("Content-Range", fmt.Sprintf("*/%d", len(rows)))
+		return resp, nil
+	}
+	if rows == nil {
+		return jsonResponse(req, status, nil), nil
+	}
+	return jsonResponse(req, status, rows), nil
+}
+
+func jsonResponse(req *http.Request, status int, v interface{}) *http.Response {
+	var body []byte
+	if v != nil {
+		body, _ = json.Marshal(v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func (t *sqliteTransport) handle(req *http.Request) (int, []map[string]interface{}, error) {
+	table := strings.Trim(req.URL.Path, "/")
+	columns, ok := t.columns[table]
+	if !ok {
+		return 0, nil, &restError{http.StatusNotFound, fmt.Sprintf("relation %q does not exist", table)}
+	}
+	query := req.URL.Query()
+
+	switch req.Method {
+	case http.MethodGet:
+		rows, err := t.selectRows(t.db, table, columns, query, "", nil)
+		return http.StatusOK, rows, err
+
+	case http.MethodHead:
+		// Counted from the matching rows; RoundTrip sends only the count
+		rows, err := t.selectRows(t.db, table, columns, query, "", nil)
+		return http.StatusOK, rows, err
+
+	case http.MethodPost:
+		var body interface{}
+		if err := decodeBody(req, &body); err != nil {
+			return 0, nil, err
+		}
+		var records []map[string]interface{}
+		switch b := body.(type) {
+		case map[string]interface{}:
+			records = []map[string]interface{}{b}
+		case []interface{}:
+			for _, r := range b {
+				record, ok := r.(map[string]interface{})
+				if !ok {
+					return 0, nil, badRequest("expected an array of objects")
+				}
+				records = append(records, record)
+			}
+		default:
+			return 0, nil, badRequest("expected an object or an array of objects")
+		}
+		merge := strings.Contains(req.Header.Get("Prefer"), "resolution=merge-duplicates")
+		rows, err := t.insert(table, columns, records, query.Get("on_conflict"), merge)
+		return http.StatusCreated, rows, err
+
+	case http.MethodPatch:
+		var data map[string]interface{}
+		if err := decodeBody(req, &data); err != nil {
+			return 0, nil, err
+		}
+		rows, err := t.update(table, columns, query, data)
+		return http.StatusOK, rows, err
+
+	case http.MethodDelete:
+		where, args, err := t.where(table, columns, query)
+		if err != nil {
+			return 0, nil, err
+		}
+		_, err = t.db.Exec(`DELETE FROM `+quoteIdent(table)+where, args...)
+		return http.StatusNoContent, nil, err
+	}
+	return 0, nil, &restError{http.StatusMethodNotAllowed, "unsupported method " + req.Method}
+}
+
+func decodeBody(req *http.Request, v interface{}) error {
+	if req.Body == nil {
+		return badRequest("request body is required")
+	}
+	if err := json.NewDecoder(req.Body).Decode(v); err != nil {
+		return badRequest("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// selectRows runs a GET. extraWhere, if set, replaces the query's filters.
+func (t *sqliteTransport) selectRows(q queryer, table string, columns map[string]string, query url.Values, extraWhere string, extraArgs []interface{}) ([]map[string]interface{}, error) {
+	selected := "*"
+	if sel := query.Get("select"); sel != "" && sel != "*" {
+		var cols []string
+		for _, col := range strings.Split(sel, ",") {
+			if _, ok := columns[col]; !ok {
+				return nil, badRequest("column %q does not exist in %s", col, table)
+			}
+			cols = append(cols, quoteIdent(col))
+		}
+		selected = strings.Join(cols, ", ")
+	}
+
+	where, args := extraWhere, extraArgs
+	if extraWhere == "" {
+		var err error
+		if where, args, err = t.where(table, columns, query); err != nil {
+			return nil, err
+		}
+	}
+
+	stmt := "SELECT " + selected + " FROM " + quoteIdent(table) + where
+	if order := query.Get("order"); order != "" {
+		var terms []string
+		for _, term := range strings.Split(order, ",") {
+			parts := strings.Split(term, ".")
+			if _, ok := columns[parts[0]]; !ok {
+				return nil, badRequest("cannot order by unknown column %q", parts[0])
+			}
+			dir := "ASC"
+			for _, p := range parts[1:] {
+				switch p {
+				case "desc":
+					dir = "DESC"
+				case "asc":
+					dir = "ASC"
+				case "nullsfirst":
+					dir += " NULLS FIRST"
+				case "nullslast":
+					dir += " NULLS LAST"
+				}
+			}
+			terms = append(terms, quoteIdent(parts[0])+" "+dir)
+		}
+		stmt += " ORDER BY " + strings.Join(terms, ", ")
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, badRequest("invalid limit %q", limit)
+		}
+		stmt += " LIMIT " + strconv.Itoa(n)
+		if offset := query.Get("offset"); offset != "" {
+			n, err := strconv.Atoi(offset)
+			if err != nil || n < 0 {
+				return nil, badRequest("invalid offset %q", offset)
+			}
+			stmt += " OFFSET " + strconv.Itoa(n)
+		}
+	}
+
+	rows, err := q.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRows(rows, columns)
+}
+
+// where translates PostgREST filters into a WHERE clause
+func (t *sqliteTransport) where(table string, columns map[string]string, query url.Values) (string, []interface{}, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		switch key {
+		case "select", "order", "limit", "offset", "on_conflict", "columns":
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var clauses []string
+	var args []interface{}
+	for _, col := range keys {
+		typ, ok := columns[col]
+		if !ok {
+			return "", nil, badRequest("column %q does not exist in %s", col, table)
+		}
+		for _, filter := range query[col] {
+			negate := false
+			if rest, ok := strings.CutPrefix(filter, "not."); ok {
+				negate, filter = true, rest
+			}
+			op, value, ok := strings.Cut(filter, ".")
+			if !ok {
+				return "", nil, badRequest("invalid filter %s=%s", col, filter)
+			}
+
+			ident := quoteIdent(col)
+			var clause string
+			switch op {
+			case "eq", "neq", "gt", "gte", "lt", "lte":
+				clause = ident + " " + map[string]string{"eq": "=", "neq": "<>", "gt": ">", "gte": ">=", "lt": "<", "lte": "<="}[op] + " ?"
+				args = append(args, filterArg(typ, value))
+			case "like", "ilike":
+				clause = ident + " LIKE ?"
+				args = append(args, strings.ReplaceAll(value, "*", "%"))
+			case "is":
+				switch value {
+				case "null":
+					clause = ident + " IS NULL"
+				case "true", "false":
+					clause = ident + " = ?"
+					args = append(args, filterArg("BOOLEAN", value))
+				default:
+					return "", nil, badRequest("invalid is filter %q", value)
+				}
+			case "in":
+				values := strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, "("), ")"), ",")
+				placeholders := make([]string, len(values))
+				for i, v := range values {
+					placeholders[i] = "?"
+					args = append(args, filterArg(typ, strings.Trim(v, `"`)))
+				}
+				clause = ident + " IN (" + strings.Join(placeholders, ", ") + ")"
+			default:
+				return "", nil, badRequest("unsupported operator %q", op)
+			}
+			if negate {
+				clause = "NOT (" + clause + ")"
+			}
+			clauses = append(clauses, clause)
+		}
+	}
+
+	if len(clauses) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args, nil
+}
+
+func (t *sqliteTransport) insert(table string, columns map[string]string, records []map[string]interface{}, conflict string, merge bool) ([]map[string]interface{}, error) {
+	// on_conflict may name several columns, as in "workspace_id,user_id"
+	keys := []string{"id"}
+	if conflict != "" {
+		keys = strings.Split(conflict, ",")
+		for _, col := range keys {
+			if _, ok := columns[col]; !ok {
+				return nil, badRequest("on_conflict column %q does not exist in %s", col, table)
+			}
+		}
+	}
+	keyIdents := make([]string, len(keys))
+	for i, col := range keys {
+		keyIdents[i] = quoteIdent(col)
+	}
+
+	tx, err := t.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var out []map[string]interface{}
+	for _, record := range records {
+		if _, hasID := columns["id"]; hasID && record["id"] == nil {
+			record["id"] = newUUID()
+		}
+
+		cols := make([]string, 0, len(record))
+		for col := range record {
+			if _, ok := columns[col]; !ok {
+				return nil, badRequest("column %q does not exist in %s", col, table)
+			}
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+
+		idents := make([]string, len(cols))
+		placeholders := make([]string, len(cols))
+		args := make([]interface{}, len(cols))
+		var updates []string
+		for i, col := range cols {
+			idents[i] = quoteIdent(col)
+			placeholders[i] = "?"
+			args[i] = storeArg(columns[col], record[col])
+			if !slices.Contains(keys, col) {
+				updates = append(updates, idents[i]+" = excluded."+idents[i])
+			}
+		}
+
+		stmt := "INSERT INTO " + quoteIdent(table) + " (" + strings.Join(idents, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+		if conflict != "" && merge {
+			if len(updates) == 0 {
+				stmt += " ON CONFLICT (" + strings.Join(keyIdents, ", ") + ") DO NOTHING"
+			} else {
+				stmt += " ON CONFLICT (" + strings.Join(keyIdents, ", ") + ") DO UPDATE SET " + strings.Join(updates, ", ")
+			}
+		}
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			return nil, err
+		}
+
+		match := make([]string, len(keys))
+		matchArgs := make([]interface{}, len(keys))
+		for i, col := range keys {
+			match[i] = keyIdents[i] + " = ?"
+			matchArgs[i] = storeArg(columns[col], record[col])
+		}
+		rows, err := t.selectRows(tx, table, columns, url.Values{}, " WHERE "+strings.Join(match, " AND "), matchArgs)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rows...)
+	}
+
+	return out, tx.Commit()
+}
+
+// update patches the matched rows and returns them as they are afterwards,
+// even if the change means they no longer match the filters
+func (t *sqliteTransport) update(table string, columns map[string]string, query url.Values, data map[string]interface{}) ([]map[string]interface{}, error) {
+	where, args, err := t.where(table, columns, query)
+	if err != nil {
+		return nil, err
+	}
+
+	cols := make([]string, 0, len(data))
+	for col := range data {
+		if _, ok := columns[col]; !ok {
+			return nil, badRequest("column %q does not exist in %s", col, table)
+		}
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	if len(cols) == 0 {
+		return nil, badRequest("no columns to update")
+	}
+
+	tx, err := t.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids, err := tx.Query("SELECT rowid FROM "+quoteIdent(table)+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	var rowids []interface{}
+	for ids.Next() {
+		var id int64
+		ids.Scan(&id)
+		rowids = append(rowids, id)
+	}
+	ids.Close()
+	if len(rowids) == 0 {
+		return []map[string]interface{}{}, tx.Commit()
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(rowids)), ", ")
+	inRows := " WHERE rowid IN (" + placeholders + ")"
+
+	sets := make([]string, len(cols))
+	setArgs := make([]interface{}, 0, len(cols)+len(rowids))
+	for i, col := range cols {
+		sets[i] = quoteIdent(col) + " = ?"
+		setArgs = append(setArgs, storeArg(columns[col], data[col]))
+	}
+	setArgs = append(setArgs, rowids...)
+	if _, err := tx.Exec("UPDATE "+quoteIdent(table)+" SET "+strings.Join(sets, ", ")+inRows, setArgs...); err != nil {
+		return nil, err
+	}
+
+	rows, err := t.selectRows(tx, table, columns, url.Values{"select": query["select"]}, inRows, rowids)
+	if err != nil {
+		return nil, err
+	}
+	return rows, tx.Commit()
+}
+
+// scanRows decodes rows into JSON-ready maps using the declared column types
+func scanRows(rows *sql.Rows, columns map[string]string) ([]map[string]interface{}, error) {
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(names))
+		ptrs := make([]interface{}, len(names))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			row[name] = loadValue(columns[name], values[i])
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+func loadValue(typ string, v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	switch typ {
+	case "BOOLEAN":
+		if n, ok := v.(int64); ok {
+			return n != 0
+		}
+	case "JSON":
+		if s, ok := v.(string); ok {
+			var decoded interface{}
+			if json.Unmarshal([]byte(s), &decoded) == nil {
+				return decoded
+			}
+		}
+	}
+	return v
+}
+
+// storeArg converts a JSON value into the column's storage representation
+func storeArg(typ string, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if typ == "JSON" {
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	switch value := v.(type) {
+	case bool:
+		if value {
+			return 1
+		}
+		return 0
+	case float64:
+		if typ == "INTEGER" && value == math.Trunc(value) {
+			return int64(value)
+		}
+		return value
+	case string:
+		return value
+	case time.Time:
+		return value.UTC().Format(time.RFC3339)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// filterArg converts a filter value from the query string
+func filterArg(typ, value string) interface{} {
+	switch typ {
+	case "BOOLEAN":
+		switch value {
+		case "true":
+			return 1
+		case "false":
+			return 0
+		}
+	case "INTEGER":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return value
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// newUUID returns a random (version 4) UUID like Postgres' uuid_generate_v4
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
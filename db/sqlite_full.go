@@ -0,0 +1,12 @@
+//go:build !lite
+
+package db
+
+import "fmt"
+
+// liteBuild reports whether this is the lite build (go build -tags lite)
+const liteBuild = false
+
+func newSQLiteClient(path string) (*SupabaseClient, error) {
+	return nil, fmt.Errorf("SQLite databases require the lite build (go build -tags lite)")
+}
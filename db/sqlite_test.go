@@ -0,0 +1,128 @@
+//go:build lite
+
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteClientTasks(t *testing.T) {
+	sc, err := NewSupabaseClient(SQLiteScheme+filepath.Join(t.TempDir(), "test.db"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := sc.CreateTask("user-1", map[string]interface{}{
+		"title":     "Write report",
+		"priority":  3,
+		"due_date":  time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		"completed": false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := sc.GetTask(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task["title"] != "Write report" || task["completed"] != false || task["priority"] != float64(3) {
+		t.Errorf("unexpected task %v", task)
+	}
+
+	if err := sc.SoftDeleteTask(id); err != nil {
+		t.Fatal(err)
+	}
+	if tasks, _ := sc.GetUserTasks("user-1"); len(tasks) != 0 {
+		t.Errorf("trashed task still listed: %v", tasks)
+	}
+	restored, err := sc.RestoreTask("user-1", id)
+	if err != nil || restored == nil || restored["deleted_at"] != nil {
+		t.Fatalf("restore = %v, %v", restored, err)
+	}
+
+	// Errors classify as they do against Supabase
+	if _, err := sc.CreateTask("user-1", map[string]interface{}{"id": id, "title": "Again"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("creating a task with a taken ID: got %v, want ErrConflict", err)
+	}
+	if _, err := sc.GetTask("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("getting a missing task: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLiteClientTaskCounts(t *testing.T) {
+	sc, err := NewSupabaseClient(SQLiteScheme+filepath.Join(t.TempDir(), "test.db"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	for i, created := range []time.Time{now.AddDate(0, 0, -30), now.AddDate(0, 0, -2), now.AddDate(0, 0, -1)} {
+		if _, err := sc.CreateTask("user-1", map[string]interface{}{
+			"title":      "Task",
+			"due_date":   now.Format(time.RFC3339),
+			"completed":  i == 2,
+			"created_at": created.Format(time.RFC3339),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	since := now.AddDate(0, 0, -7)
+	for _, tc := range []struct {
+		since         time.Time
+		completedOnly bool
+		want          int
+	}{{time.Time{}, false, 3}, {since, false, 2}, {since, true, 1}} {
+		if n, err := sc.CountTasksCreatedSince("user-1", tc.since, tc.completedOnly); err != nil || n != tc.want {
+			t.Errorf("count since %v, completed only %v = %d, %v; want %d", tc.since, tc.completedOnly, n, err, tc.want)
+		}
+	}
+
+	tasks, err := sc.GetTasksCreatedSince("user-1", since, "title,completed,created_at", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0]["completed"] != true || tasks[0]["id"] != nil {
+		t.Errorf("recent tasks %v, want only the newest, with only the selected columns", tasks)
+	}
+}
+
+func TestSQLiteClientUpsert(t *testing.T) {
+	sc, err := NewSupabaseClient(SQLiteScheme+filepath.Join(t.TempDir(), "test.db"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, enabled := range []bool{true, false} {
+		if _, err := sc.UpsertFocusContract("user-1", map[string]interface{}{"enabled": enabled}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	contract, err := sc.GetFocusContract("user-1")
+	if err != nil || contract["enabled"] != false {
+		t.Fatalf("contract = %v, %v", contract, err)
+	}
+
+	if _, err := sc.CreateAPIKey("user-1", map[string]interface{}{
+		"name": "cron", "prefix": "pmcp_abc", "key_hash": "hash", "scopes": []string{"read", "mcp"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	key, err := sc.GetAPIKeyByHash("hash")
+	if err != nil || key == nil {
+		t.Fatalf("key = %v, %v", key, err)
+	}
+	if scopes, _ := key["scopes"].([]interface{}); len(scopes) != 2 {
+		t.Errorf("scopes = %v", key["scopes"])
+	}
+}
+
+func TestNewSupabaseClientRejectsHTTPInLiteBuild(t *testing.T) {
+	if _, err := NewSupabaseClient("https://example.supabase.co", "key"); err == nil {
+		t.Error("expected the lite build to reject Supabase URLs")
+	}
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// RecordCompletion appends a task completion to the completion history
+func (sc *SupabaseClient) RecordCompletion(userID, taskID string, completedAt time.Time) error {
+	_, err := sc.insertRow("task_completions", map[string]interface{}{
+		"user_id":      userID,
+		"task_id":      taskID,
+		"completed_at": completedAt.UTC().Format(time.RFC3339),
+	}, "record completion")
+	return err
+}
+
+// GetCompletionsSince lists a user's task completions since the given time
+func (sc *SupabaseClient) GetCompletionsSince(userID string, since time.Time) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("task_completions?user_id=eq.%s&completed_at=gte.%s&select=*&order=completed_at.asc",
+		url.QueryEscape(userID), url.QueryEscape(since.UTC().Format(time.RFC3339))), "get completions")
+}
+
+// GetStreakFreezes lists every freeze a user has consumed
+func (sc *SupabaseClient) GetStreakFreezes(userID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("streak_freezes?user_id=eq.%s&select=*&order=frozen_on.asc", url.QueryEscape(userID)), "get streak freezes")
+}
+
+// RecordStreakFreeze records that a freeze covered a missed day of a habit
+func (sc *SupabaseClient) RecordStreakFreeze(userID, taskID string, frozenOn time.Time) error {
+	_, err := sc.insertRow("streak_freezes", map[string]interface{}{
+		"user_id":   userID,
+		"task_id":   taskID,
+		"frozen_on": frozenOn.Format("2006-01-02"),
+	}, "record streak freeze")
+	return err
+}
+
+// GetGraceRules returns a user's grace rules, or nil if they never set any
+func (sc *SupabaseClient) GetGraceRules(userID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("grace_rules?user_id=eq.%s&select=*", url.QueryEscape(userID)), "get grace rules")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// UpsertGraceRules creates or replaces a user's grace rules
+func (sc *SupabaseClient) UpsertGraceRules(userID string, rules map[string]interface{}) (map[string]interface{}, error) {
+	rules["user_id"] = userID
+	return sc.upsertRow("grace_rules", "user_id", rules, "upsert grace rules")
+}
+
+// GetCompletionStats returns a user's running completion stats, or nil if none are kept yet
+func (sc *SupabaseClient) GetCompletionStats(userID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("completion_stats?user_id=eq.%s&select=*", url.QueryEscape(userID)), "get completion stats")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// UpsertCompletionStats creates or replaces a user's completion stats
+func (sc *SupabaseClient) UpsertCompletionStats(userID string, stats map[string]interface{}) error {
+	stats["user_id"] = userID
+	_, err := sc.upsertRow("completion_stats", "user_id", stats, "upsert completion stats")
+	return err
+}
+
+// GetWeeklyCompletions lists a user's completion counts for the weeks starting on or after since
+func (sc *SupabaseClient) GetWeeklyCompletions(userID string, since time.Time) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("weekly_completions?user_id=eq.%s&week_start=gte.%s&select=*&order=week_start.asc",
+		url.QueryEscape(userID), since.Format("2006-01-02")), "get weekly completions")
+}
+
+// UpsertWeeklyCompletions sets a user's completion count for the week starting on weekStart
+func (sc *SupabaseClient) UpsertWeeklyCompletions(userID string, weekStart time.Time, completed int) error {
+	_, err := sc.upsertRow("weekly_completions", "user_id,week_start", map[string]interface{}{
+		"user_id":    userID,
+		"week_start": weekStart.Format("2006-01-02"),
+		"completed":  completed,
+	}, "upsert weekly completions")
+	return err
+}
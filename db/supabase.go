@@ -2,26 +2,44 @@ package db
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
-	"time"
+
+	"github.com/productivity/mcp-server/utils"
 )
 
 // SupabaseClient wraps HTTP client for Supabase REST API
 type SupabaseClient struct {
 	baseURL    string
 	apiKey     string
+	serviceKey string // for serviceRoleTables; see ConfigureServiceRole
 	httpClient *http.Client
-	timeout    time.Duration
+	retry      *utils.RetryConfig
+	ctx        context.Context
 }
 
-// NewSupabaseClient creates a new Supabase client
+// SQLiteScheme prefixes a database URL naming a local SQLite file instead of a
+// Supabase project. SQLite is only available in the lite build.
+const SQLiteScheme = "sqlite:"
+
+// NewSupabaseClient returns the Supabase client for a project, creating it on
+// first use; every client shares the pool set up by ConfigureHTTP. A
+// "sqlite:<path>" URL opens a local SQLite database that answers the same
+// REST queries in process.
 func NewSupabaseClient(supabaseURL, supabaseKey string) (*SupabaseClient, error) {
+	if path, ok := strings.CutPrefix(supabaseURL, SQLiteScheme); ok {
+		return newSQLiteClient(path)
+	}
+	if liteBuild {
+		return nil, fmt.Errorf("the lite build only supports %s database URLs", SQLiteScheme)
+	}
 	if supabaseURL == "" {
 		return nil, fmt.Errorf("supabase URL is required")
 	}
@@ -31,15 +49,31 @@ func NewSupabaseClient(supabaseURL, supabaseKey string) (*SupabaseClient, error)
 
 	baseURL := strings.TrimRight(supabaseURL, "/") + "/rest/v1/"
 
-	log.Printf("Supabase client initialized for: %s", baseURL)
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if client, ok := clients[baseURL+"\x00"+supabaseKey]; ok {
+		return client, nil
+	}
 
-	timeout := 30 * time.Second
-	return &SupabaseClient{
+	utils.DefaultLogger().Info("Supabase client initialized", map[string]interface{}{"url": baseURL})
+
+	client := &SupabaseClient{
 		baseURL:    baseURL,
 		apiKey:     supabaseKey,
-		httpClient: &http.Client{Timeout: timeout},
-		timeout:    timeout,
-	}, nil
+		serviceKey: sharedServiceRoleKey,
+		httpClient: sharedHTTPClient,
+		retry:      sharedRetry,
+	}
+	clients[baseURL+"\x00"+supabaseKey] = client
+	return client, nil
+}
+
+// WithContext returns a copy of the client whose requests are cancelled with
+// ctx, so an abandoned request stops waiting on Supabase
+func (sc *SupabaseClient) WithContext(ctx context.Context) *SupabaseClient {
+	copied := *sc
+	copied.ctx = ctx
+	return &copied
 }
 
 // Close closes the database connection (no-op for HTTP client)
@@ -47,46 +81,108 @@ func (sc *SupabaseClient) Close() error {
 	return nil
 }
 
+// Ping checks that the REST API accepts the key and that table is exposed
+func (sc *SupabaseClient) Ping(table string) error {
+	_, err := sc.selectRows(table+"?select=*&limit=1", "query "+table)
+	return err
+}
+
+// keyFor returns the key a request for endpoint is sent with: the
+// service-role key for the tables in serviceRoleTables, when one is set
+func (sc *SupabaseClient) keyFor(endpoint string) string {
+	table, _, _ := strings.Cut(endpoint, "?")
+	if sc.serviceKey != "" && serviceRoleTables[table] {
+		return sc.serviceKey
+	}
+	return sc.apiKey
+}
+
 // makeRequest makes an HTTP request to Supabase REST API
 func (sc *SupabaseClient) makeRequest(method, endpoint string, body interface{}) (*http.Response, error) {
-	var reqBody io.Reader
+	return sc.makeRequestWithPrefer(method, endpoint, body, "return=representation")
+}
+
+// makeRequestWithPrefer makes an HTTP request with a custom PostgREST Prefer
+// header. Attempts that are rate limited or fail on the way to the database
+// are retried as newAttemptError allows; when the last one fails that way
+// too, the error is a 503 for the caller to pass on.
+func (sc *SupabaseClient) makeRequestWithPrefer(method, endpoint string, body interface{}, prefer string) (*http.Response, error) {
+	var jsonData []byte
 	if body != nil {
-		jsonData, err := json.Marshal(body)
-		if err != nil {
+		var err error
+		if jsonData, err = json.Marshal(body); err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, sc.baseURL+endpoint, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	ctx := sc.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	retry := sc.retry
+	if retry == nil {
+		retry = noRetry
 	}
 
-	req.Header.Set("apikey", sc.apiKey)
-	req.Header.Set("Authorization", "Bearer "+sc.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Prefer", "return=representation")
+	var resp *http.Response
+	attempts := 0
+	err := utils.Retry(ctx, retry, func() error {
+		attempts++
+		var reqBody io.Reader
+		if jsonData != nil {
+			reqBody = bytes.NewReader(jsonData)
+		}
+		req, err := http.NewRequestWithContext(utils.ContextWithAttempt(ctx, attempts), method, sc.baseURL+endpoint, reqBody)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
 
-	resp, err := sc.httpClient.Do(req)
+		key := sc.keyFor(endpoint)
+		req.Header.Set("apikey", key)
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Prefer", prefer)
+
+		resp, err = sc.httpClient.Do(req)
+		if attempt := newAttemptError(method, resp, err); attempt != nil {
+			if resp != nil {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				resp = nil
+			}
+			return attempt
+		}
+		if err != nil {
+			return fmt.Errorf("failed to make request: %w", err)
+		}
+		return nil
+	})
+	// Dropped once the write is done, so a list fetched while it was under
+	// way is not cached
+	if method != http.MethodGet && method != http.MethodHead {
+		taskCache.invalidate(sc.baseURL, endpoint, body)
+	}
+	var attempt *attemptError
+	if errors.As(err, &attempt) {
+		return nil, errUnavailable(attempt)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, err
 	}
 
 	return resp, nil
 }
 
-// GetTask retrieves a task by ID from Supabase
+// GetTask retrieves a task by ID from Supabase, ignoring trashed tasks
 func (sc *SupabaseClient) GetTask(taskID string) (map[string]interface{}, error) {
-	resp, err := sc.makeRequest("GET", fmt.Sprintf("tasks?id=eq.%s&select=*", url.QueryEscape(taskID)), nil)
+	resp, err := sc.makeRequest("GET", fmt.Sprintf("tasks?id=eq.%s&deleted_at=is.null&select=*", url.QueryEscape(taskID)), nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get task: %s - %s", resp.Status, string(body))
+		return nil, newPostgRESTError("get task", resp)
 	}
 
 	var tasks []map[string]interface{}
@@ -95,7 +191,7 @@ func (sc *SupabaseClient) GetTask(taskID string) (map[string]interface{}, error)
 	}
 
 	if len(tasks) == 0 {
-		return nil, fmt.Errorf("task not found")
+		return nil, fmt.Errorf("task %w", ErrNotFound)
 	}
 
 	return tasks[0], nil
@@ -111,8 +207,7 @@ func (sc *SupabaseClient) CreateTask(userID string, taskData map[string]interfac
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to create task: %s - %s", resp.Status, string(body))
+		return "", newPostgRESTError("create task", resp)
 	}
 
 	var tasks []map[string]interface{}
@@ -141,14 +236,13 @@ func (sc *SupabaseClient) UpdateTask(taskID string, taskData map[string]interfac
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to update task: %s - %s", resp.Status, string(body))
+		return newPostgRESTError("update task", resp)
 	}
 
 	return nil
 }
 
-// DeleteTask deletes a task from Supabase
+// DeleteTask permanently deletes a task from Supabase (see SoftDeleteTask)
 func (sc *SupabaseClient) DeleteTask(taskID string) error {
 	resp, err := sc.makeRequest("DELETE", fmt.Sprintf("tasks?id=eq.%s", url.QueryEscape(taskID)), nil)
 	if err != nil {
@@ -157,24 +251,29 @@ func (sc *SupabaseClient) DeleteTask(taskID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete task: %s - %s", resp.Status, string(body))
+		return newPostgRESTError("delete task", resp)
 	}
 
 	return nil
 }
 
-// GetUserTasks retrieves all tasks for a user
+// GetUserTasks retrieves all tasks for a user, excluding trashed tasks. The
+// list is served from the task cache while no write has changed it.
 func (sc *SupabaseClient) GetUserTasks(userID string) ([]map[string]interface{}, error) {
-	resp, err := sc.makeRequest("GET", fmt.Sprintf("tasks?user_id=eq.%s&select=*&order=created_at.desc", url.QueryEscape(userID)), nil)
+	key := taskCacheKey(sc.baseURL, userID)
+	cached, generation, ok := taskCache.get(key)
+	if ok {
+		return cached, nil
+	}
+
+	resp, err := sc.makeRequest("GET", fmt.Sprintf("tasks?user_id=eq.%s&deleted_at=is.null&select=*&order=created_at.desc", url.QueryEscape(userID)), nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get user tasks: %s - %s", resp.Status, string(body))
+		return nil, newPostgRESTError("get user tasks", resp)
 	}
 
 	var tasks []map[string]interface{}
@@ -182,20 +281,26 @@ func (sc *SupabaseClient) GetUserTasks(userID string) ([]map[string]interface{},
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	taskCache.put(key, tasks, generation)
 	return tasks, nil
 }
 
-// GetGoal retrieves a goal by ID from Supabase
+// GetOpenDatedTasks returns a user's open tasks that have a due date, soonest first
+func (sc *SupabaseClient) GetOpenDatedTasks(userID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("tasks?user_id=eq.%s&deleted_at=is.null&completed=not.is.true&due_date=not.is.null&select=*&order=due_date.asc",
+		url.QueryEscape(userID)), "get open dated tasks")
+}
+
+// GetGoal retrieves a goal by ID from Supabase, ignoring trashed goals
 func (sc *SupabaseClient) GetGoal(goalID string) (map[string]interface{}, error) {
-	resp, err := sc.makeRequest("GET", fmt.Sprintf("goals?id=eq.%s&select=*", url.QueryEscape(goalID)), nil)
+	resp, err := sc.makeRequest("GET", fmt.Sprintf("goals?id=eq.%s&deleted_at=is.null&select=*", url.QueryEscape(goalID)), nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get goal: %s - %s", resp.Status, string(body))
+		return nil, newPostgRESTError("get goal", resp)
 	}
 
 	var goals []map[string]interface{}
@@ -204,7 +309,7 @@ func (sc *SupabaseClient) GetGoal(goalID string) (map[string]interface{}, error)
 	}
 
 	if len(goals) == 0 {
-		return nil, fmt.Errorf("goal not found")
+		return nil, fmt.Errorf("goal %w", ErrNotFound)
 	}
 
 	return goals[0], nil
@@ -220,8 +325,7 @@ func (sc *SupabaseClient) CreateGoal(userID string, goalData map[string]interfac
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to create goal: %s - %s", resp.Status, string(body))
+		return "", newPostgRESTError("create goal", resp)
 	}
 
 	var goals []map[string]interface{}
@@ -250,14 +354,13 @@ func (sc *SupabaseClient) UpdateGoal(goalID string, goalData map[string]interfac
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to update goal: %s - %s", resp.Status, string(body))
+		return newPostgRESTError("update goal", resp)
 	}
 
 	return nil
 }
 
-// DeleteGoal deletes a goal from Supabase
+// DeleteGoal permanently deletes a goal from Supabase (see SoftDeleteGoal)
 func (sc *SupabaseClient) DeleteGoal(goalID string) error {
 	resp, err := sc.makeRequest("DELETE", fmt.Sprintf("goals?id=eq.%s", url.QueryEscape(goalID)), nil)
 	if err != nil {
@@ -266,24 +369,22 @@ func (sc *SupabaseClient) DeleteGoal(goalID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to delete goal: %s - %s", resp.Status, string(body))
+		return newPostgRESTError("delete goal", resp)
 	}
 
 	return nil
 }
 
-// GetUserGoals retrieves all goals for a user
+// GetUserGoals retrieves all goals for a user, excluding trashed goals
 func (sc *SupabaseClient) GetUserGoals(userID string) ([]map[string]interface{}, error) {
-	resp, err := sc.makeRequest("GET", fmt.Sprintf("goals?user_id=eq.%s&select=*&order=created_at.desc", url.QueryEscape(userID)), nil)
+	resp, err := sc.makeRequest("GET", fmt.Sprintf("goals?user_id=eq.%s&deleted_at=is.null&select=*&order=created_at.desc", url.QueryEscape(userID)), nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get user goals: %s - %s", resp.Status, string(body))
+		return nil, newPostgRESTError("get user goals", resp)
 	}
 
 	var goals []map[string]interface{}
@@ -293,3 +394,162 @@ func (sc *SupabaseClient) GetUserGoals(userID string) ([]map[string]interface{},
 
 	return goals, nil
 }
+
+// selectRows performs a GET against endpoint and decodes the returned rows
+func (sc *SupabaseClient) selectRows(endpoint, op string) ([]map[string]interface{}, error) {
+	resp, err := sc.makeRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newPostgRESTError(op, resp)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return rows, nil
+}
+
+// insertRow inserts a single row into table and returns the stored representation
+func (sc *SupabaseClient) insertRow(table string, data map[string]interface{}, op string) (map[string]interface{}, error) {
+	resp, err := sc.makeRequest("POST", table, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, newPostgRESTError(op, resp)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no row returned from %s", op)
+	}
+
+	return rows[0], nil
+}
+
+// upsertRow inserts a row or merges it into the existing row with the same conflict key
+func (sc *SupabaseClient) upsertRow(table, conflictColumn string, data map[string]interface{}, op string) (map[string]interface{}, error) {
+	resp, err := sc.makeRequestWithPrefer("POST", table+"?on_conflict="+url.QueryEscape(conflictColumn), data, "return=representation,resolution=merge-duplicates")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, newPostgRESTError(op, resp)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no row returned from %s", op)
+	}
+
+	return rows[0], nil
+}
+
+// updateRows patches every row matched by endpoint's filters
+func (sc *SupabaseClient) updateRows(endpoint string, data map[string]interface{}, op string) error {
+	resp, err := sc.makeRequest("PATCH", endpoint, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newPostgRESTError(op, resp)
+	}
+
+	return nil
+}
+
+// updateRowsReturning patches every row matched by endpoint's filters and returns the updated rows
+func (sc *SupabaseClient) updateRowsReturning(endpoint string, data map[string]interface{}, op string) ([]map[string]interface{}, error) {
+	resp, err := sc.makeRequest("PATCH", endpoint, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newPostgRESTError(op, resp)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return rows, nil
+}
+
+// deleteRowsCounted deletes every row matched by endpoint's filters and
+// returns how many there were
+func (sc *SupabaseClient) deleteRowsCounted(endpoint, op string) (int, error) {
+	resp, err := sc.makeRequestWithPrefer("DELETE", endpoint, nil, "return=minimal,count=exact")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return 0, newPostgRESTError(op, resp)
+	}
+
+	return contentRangeTotal(resp), nil
+}
+
+// countRows returns how many rows endpoint's filters match, without fetching them
+func (sc *SupabaseClient) countRows(endpoint, op string) (int, error) {
+	resp, err := sc.makeRequestWithPrefer("HEAD", endpoint, nil, "count=exact")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("failed to %s: %s", op, resp.Status)
+	}
+	return contentRangeTotal(resp), nil
+}
+
+// contentRangeTotal reads the row count from Content-Range, which is "*/N"
+// or "0-9/N" when count=exact is honoured
+func contentRangeTotal(resp *http.Response) int {
+	contentRange := resp.Header.Get("Content-Range")
+	if i := strings.LastIndex(contentRange, "/"); i >= 0 {
+		if n, err := strconv.Atoi(contentRange[i+1:]); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// deleteRows deletes every row matched by endpoint's filters
+func (sc *SupabaseClient) deleteRows(endpoint, op string) error {
+	resp, err := sc.makeRequest("DELETE", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newPostgRESTError(op, resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// SyncPosition is where a sync stream stopped: the timestamp of the last row
+// handed out, exactly as the database returned it, and that row's id to
+// order rows sharing the timestamp
+type SyncPosition struct {
+	At string `json:"t,omitempty"`
+	ID string `json:"id,omitempty"`
+}
+
+// syncQuery selects userID's rows whose column and id come after pos and
+// whose column is before cutoff, in that order, at most limit of them
+func syncQuery(table, column, userID string, pos SyncPosition, cutoff time.Time, limit int) string {
+	endpoint := fmt.Sprintf("%s?user_id=eq.%s&%s=lt.%s", table, url.QueryEscape(userID), column, url.QueryEscape(cutoff.UTC().Format(time.RFC3339Nano)))
+	if pos.At != "" {
+		after := fmt.Sprintf(`(%s.gt."%s",and(%s.eq."%s",id.gt."%s"))`, column, pos.At, column, pos.At, pos.ID)
+		endpoint += "&or=" + url.QueryEscape(after)
+	}
+	return endpoint + fmt.Sprintf("&select=*&order=%s.asc,id.asc&limit=%d", column, limit)
+}
+
+// ChangedTasks returns up to limit of userID's tasks, trashed ones included,
+// written after pos and before cutoff, oldest first
+func (sc *SupabaseClient) ChangedTasks(userID string, pos SyncPosition, cutoff time.Time, limit int) ([]map[string]interface{}, error) {
+	return sc.selectRows(syncQuery("tasks", "updated_at", userID, pos, cutoff, limit), "get changed tasks")
+}
+
+// ChangedGoals returns up to limit of userID's goals, trashed ones included,
+// written after pos and before cutoff, oldest first
+func (sc *SupabaseClient) ChangedGoals(userID string, pos SyncPosition, cutoff time.Time, limit int) ([]map[string]interface{}, error) {
+	return sc.selectRows(syncQuery("goals", "updated_at", userID, pos, cutoff, limit), "get changed goals")
+}
+
+// SyncTombstones returns up to limit of the tasks and goals userID lost after
+// pos and before cutoff, deleted for good or handed to another user, oldest
+// first
+func (sc *SupabaseClient) SyncTombstones(userID string, pos SyncPosition, cutoff time.Time, limit int) ([]map[string]interface{}, error) {
+	return sc.selectRows(syncQuery("sync_tombstones", "deleted_at", userID, pos, cutoff, limit), "get sync tombstones")
+}
+
+// PurgeSyncTombstones removes tombstones recorded before cutoff and returns how many
+func (sc *SupabaseClient) PurgeSyncTombstones(cutoff time.Time) (int, error) {
+	return sc.deleteRowsCounted(fmt.Sprintf("sync_tombstones?deleted_at=lt.%s", url.QueryEscape(cutoff.UTC().Format(time.RFC3339))), "purge sync tombstones")
+}
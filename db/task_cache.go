@@ -0,0 +1,188 @@
+package db
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCachedTaskLists bounds how many users' task lists are kept at once
+const maxCachedTaskLists = 10000
+
+// TaskCache keeps each user's task list, as GetUserTasks returns it, between
+// writes. Every write to the tasks table made through a SupabaseClient drops
+// the lists it may change; lists also expire after the TTL, which bounds how
+// long a write made by another instance goes unseen.
+type TaskCache struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	lists      map[string]cachedTaskList
+	generation uint64 // bumped by every invalidation
+}
+
+type cachedTaskList struct {
+	tasks    []map[string]interface{}
+	cachedAt time.Time
+}
+
+// taskCache serves GetUserTasks; nil caches nothing
+var taskCache *TaskCache
+
+// ConfigureTaskCache caches users' task lists for ttl; 0 turns caching off
+func ConfigureTaskCache(ttl time.Duration) {
+	if ttl <= 0 {
+		taskCache = nil
+		return
+	}
+	taskCache = &TaskCache{ttl: ttl, lists: make(map[string]cachedTaskList)}
+}
+
+func taskCacheKey(baseURL, userID string) string {
+	return baseURL + "\x00" + userID
+}
+
+// get returns a copy of a fresh cached list and the generation to store a
+// fetched one under when there is none
+func (tc *TaskCache) get(key string) ([]map[string]interface{}, uint64, bool) {
+	if tc == nil {
+		return nil, 0, false
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	list, ok := tc.lists[key]
+	if !ok || time.Since(list.cachedAt) > tc.ttl {
+		return nil, tc.generation, false
+	}
+	return copyRows(list.tasks), tc.generation, true
+}
+
+// put caches tasks unless something was invalidated since generation, when
+// tasks may already be out of date
+func (tc *TaskCache) put(key string, tasks []map[string]interface{}, generation uint64) {
+	if tc == nil {
+		return
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if generation != tc.generation {
+		return
+	}
+	if len(tc.lists) >= maxCachedTaskLists {
+		tc.evictLocked()
+	}
+	tc.lists[key] = cachedTaskList{tasks: copyRows(tasks), cachedAt: time.Now()}
+}
+
+// evictLocked drops expired lists, or every list if none has expired
+func (tc *TaskCache) evictLocked() {
+	for key, list := range tc.lists {
+		if time.Since(list.cachedAt) > tc.ttl {
+			delete(tc.lists, key)
+		}
+	}
+	if len(tc.lists) >= maxCachedTaskLists {
+		tc.lists = make(map[string]cachedTaskList)
+	}
+}
+
+// invalidate drops the lists a write to endpoint with body may change: the
+// lists of the users its filters or body name, and of the users holding the
+// tasks it names by ID. A write naming neither, or a task no cached list
+// holds, such as one being restored from the trash, drops every list, as
+// does any call of a database function.
+func (tc *TaskCache) invalidate(baseURL, endpoint string, body interface{}) {
+	if tc == nil {
+		return
+	}
+	table, rawQuery, _ := strings.Cut(endpoint, "?")
+	if strings.HasPrefix(table, "rpc/") {
+		// A database function may write to any table
+		tc.mu.Lock()
+		defer tc.mu.Unlock()
+		tc.generation++
+		tc.lists = make(map[string]cachedTaskList)
+		return
+	}
+	if table != "tasks" {
+		return
+	}
+	query, _ := url.ParseQuery(rawQuery)
+
+	users := bodyUserIDs(body)
+	if userID, ok := strings.CutPrefix(query.Get("user_id"), "eq."); ok {
+		users = append(users, userID)
+	}
+	var ids []string
+	if id := query.Get("id"); strings.HasPrefix(id, "eq.") {
+		ids = append(ids, strings.TrimPrefix(id, "eq."))
+	} else if list, ok := strings.CutPrefix(id, "in."); ok {
+		ids = append(ids, strings.Split(strings.Trim(list, "()"), ",")...)
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.generation++
+	if len(users) == 0 && len(ids) == 0 {
+		tc.lists = make(map[string]cachedTaskList)
+		return
+	}
+	for _, userID := range users {
+		delete(tc.lists, taskCacheKey(baseURL, userID))
+	}
+	for _, id := range ids {
+		found := false
+		for key, list := range tc.lists {
+			if strings.HasPrefix(key, baseURL+"\x00") && listHolds(list.tasks, id) {
+				delete(tc.lists, key)
+				found = true
+			}
+		}
+		if !found && len(users) == 0 {
+			tc.lists = make(map[string]cachedTaskList)
+			return
+		}
+	}
+}
+
+// bodyUserIDs returns the user_id of each row a write sends
+func bodyUserIDs(body interface{}) []string {
+	var rows []map[string]interface{}
+	switch b := body.(type) {
+	case map[string]interface{}:
+		rows = append(rows, b)
+	case []map[string]interface{}:
+		rows = b
+	}
+	var users []string
+	for _, row := range rows {
+		if userID, ok := row["user_id"].(string); ok && userID != "" {
+			users = append(users, userID)
+		}
+	}
+	return users
+}
+
+func listHolds(tasks []map[string]interface{}, id string) bool {
+	for _, task := range tasks {
+		if taskID, _ := task["id"].(string); taskID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// copyRows copies each row, so callers changing the rows they get do not
+// change the cached ones
+func copyRows(rows []map[string]interface{}) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		copied := make(map[string]interface{}, len(row))
+		for k, v := range row {
+			copied[k] = v
+		}
+		out[i] = copied
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+//go:build !lite
+
+package db
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTaskCache(t *testing.T) {
+	ConfigureTaskCache(time.Minute)
+	defer ConfigureTaskCache(0)
+
+	var reads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			reads.Add(1)
+			if r.URL.Query().Get("user_id") == "eq.u1" {
+				w.Write([]byte(`[{"id":"t1","user_id":"u1","title":"a"}]`))
+			} else {
+				w.Write([]byte(`[{"id":"t2","user_id":"u2","title":"b"}]`))
+			}
+			return
+		}
+		w.Write([]byte(`[{"id":"t1"}]`))
+	}))
+	defer server.Close()
+	client, err := NewSupabaseClient(server.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(userID string) []map[string]interface{} {
+		t.Helper()
+		tasks, err := client.GetUserTasks(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tasks
+	}
+	expectReads := func(step string, want int32) {
+		t.Helper()
+		if got := reads.Load(); got != want {
+			t.Errorf("%s: %d reads, want %d", step, got, want)
+		}
+	}
+
+	list("u1")
+	list("u2")
+	tasks := list("u1")
+	expectReads("cached", 2)
+
+	// Changing a returned row leaves the cached one alone
+	tasks[0]["title"] = "changed"
+	if list("u1")[0]["title"] != "a" {
+		t.Error("the cached row was changed through a returned one")
+	}
+
+	// Writing a task drops only the list holding it
+	if err := client.UpdateTask("t1", map[string]interface{}{"title": "c"}); err != nil {
+		t.Fatal(err)
+	}
+	list("u1")
+	list("u2")
+	expectReads("after updating t1", 3)
+
+	// A write naming no user or cached task drops every list
+	if err := client.updateRows("tasks?workspace_id=eq.w1", map[string]interface{}{"workspace_id": nil}, "unshare tasks"); err != nil {
+		t.Fatal(err)
+	}
+	list("u1")
+	list("u2")
+	expectReads("after an unscoped write", 5)
+
+	// Writes to other tables keep the lists
+	if err := client.updateRows("goals?id=eq.g1", map[string]interface{}{"title": "g"}, "update goal"); err != nil {
+		t.Fatal(err)
+	}
+	list("u1")
+	expectReads("after a goal write", 5)
+
+	// Counting tasks is a read
+	if _, err := client.CountTasksCreatedSince("u1", time.Time{}, false); err != nil {
+		t.Fatal(err)
+	}
+	list("u1")
+	expectReads("after a count", 5)
+}
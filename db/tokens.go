@@ -0,0 +1,27 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// RevokeToken adds an access token's jti to the denylist; revoking twice is harmless
+func (sc *SupabaseClient) RevokeToken(jti string, tokenData map[string]interface{}) (map[string]interface{}, error) {
+	tokenData["jti"] = jti
+	return sc.upsertRow("revoked_tokens", "jti", tokenData, "revoke token")
+}
+
+// GetRevokedToken returns the denylist entry for jti, or nil if the token was never revoked
+func (sc *SupabaseClient) GetRevokedToken(jti string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("revoked_tokens?jti=eq.%s&select=*", url.QueryEscape(jti)), "get revoked token")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// PurgeRevokedTokens removes denylist entries for tokens that expired before cutoff and returns how many
+func (sc *SupabaseClient) PurgeRevokedTokens(cutoff time.Time) (int, error) {
+	return sc.deleteRowsCounted(fmt.Sprintf("revoked_tokens?expires_at=lt.%s", url.QueryEscape(cutoff.UTC().Format(time.RFC3339))), "purge revoked tokens")
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// trashTables are the tables that support soft delete
+var trashTables = []string{"tasks", "goals"}
+
+// SoftDeleteTask moves a task to the trash
+func (sc *SupabaseClient) SoftDeleteTask(taskID string) error {
+	return sc.softDelete("tasks", taskID)
+}
+
+// SoftDeleteGoal moves a goal to the trash
+func (sc *SupabaseClient) SoftDeleteGoal(goalID string) error {
+	return sc.softDelete("goals", goalID)
+}
+
+// RestoreTask takes a user's task out of the trash, returning nil if no trashed task matched
+func (sc *SupabaseClient) RestoreTask(userID, taskID string) (map[string]interface{}, error) {
+	return sc.restore("tasks", userID, taskID)
+}
+
+// RestoreGoal takes a user's goal out of the trash, returning nil if no trashed goal matched
+func (sc *SupabaseClient) RestoreGoal(userID, goalID string) (map[string]interface{}, error) {
+	return sc.restore("goals", userID, goalID)
+}
+
+// GetDeletedTasks lists a user's trashed tasks, most recently deleted first
+func (sc *SupabaseClient) GetDeletedTasks(userID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("tasks?user_id=eq.%s&deleted_at=not.is.null&select=*&order=deleted_at.desc", url.QueryEscape(userID)), "get deleted tasks")
+}
+
+// GetDeletedGoals lists a user's trashed goals, most recently deleted first
+func (sc *SupabaseClient) GetDeletedGoals(userID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("goals?user_id=eq.%s&deleted_at=not.is.null&select=*&order=deleted_at.desc", url.QueryEscape(userID)), "get deleted goals")
+}
+
+// PurgeDeleted permanently removes tasks and goals trashed before cutoff
+func (sc *SupabaseClient) PurgeDeleted(cutoff time.Time) error {
+	for _, table := range trashTables {
+		endpoint := fmt.Sprintf("%s?deleted_at=lt.%s", table, url.QueryEscape(cutoff.UTC().Format(time.RFC3339)))
+		if err := sc.deleteRows(endpoint, "purge deleted "+table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sc *SupabaseClient) softDelete(table, id string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	return sc.updateRows(fmt.Sprintf("%s?id=eq.%s&deleted_at=is.null", table, url.QueryEscape(id)), map[string]interface{}{
+		"deleted_at": now,
+		"updated_at": now,
+	}, "soft delete from "+table)
+}
+
+func (sc *SupabaseClient) restore(table, userID, id string) (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("%s?id=eq.%s&user_id=eq.%s&deleted_at=not.is.null", table, url.QueryEscape(id), url.QueryEscape(userID))
+	rows, err := sc.updateRowsReturning(endpoint, map[string]interface{}{
+		"deleted_at": nil,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}, "restore from "+table)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rows[0], nil
+}
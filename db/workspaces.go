@@ -0,0 +1,129 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CreateWorkspace stores a workspace and makes userID its owner
+func (sc *SupabaseClient) CreateWorkspace(userID string, workspaceData map[string]interface{}) (map[string]interface{}, error) {
+	workspaceData["created_by"] = userID
+	workspace, err := sc.insertRow("workspaces", workspaceData, "create workspace")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sc.UpsertWorkspaceMember(fmt.Sprint(workspace["id"]), userID, "owner"); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// GetWorkspace returns a workspace, or nil if none matches
+func (sc *SupabaseClient) GetWorkspace(workspaceID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("workspaces?id=eq.%s&select=*", url.QueryEscape(workspaceID)), "get workspace")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// GetWorkspaces returns the workspaces with the given IDs, oldest first
+func (sc *SupabaseClient) GetWorkspaces(workspaceIDs []string) ([]map[string]interface{}, error) {
+	if len(workspaceIDs) == 0 {
+		return []map[string]interface{}{}, nil
+	}
+	return sc.selectRows(fmt.Sprintf("workspaces?id=in.(%s)&select=*&order=created_at.asc", inList(workspaceIDs)), "get workspaces")
+}
+
+// DeleteWorkspace deletes a workspace with its members and invites; its tasks
+// and goals go back to the users who created them
+func (sc *SupabaseClient) DeleteWorkspace(workspaceID string) error {
+	return sc.deleteRows(fmt.Sprintf("workspaces?id=eq.%s", url.QueryEscape(workspaceID)), "delete workspace")
+}
+
+// GetWorkspaceMember returns a user's membership of a workspace, or nil if they are not a member
+func (sc *SupabaseClient) GetWorkspaceMember(workspaceID, userID string) (map[string]interface{}, error) {
+	rows, err := sc.selectRows(fmt.Sprintf("workspace_members?workspace_id=eq.%s&user_id=eq.%s&select=*",
+		url.QueryEscape(workspaceID), url.QueryEscape(userID)), "get workspace member")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// ListWorkspaceMembers lists a workspace's members in the order they joined
+func (sc *SupabaseClient) ListWorkspaceMembers(workspaceID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("workspace_members?workspace_id=eq.%s&select=*&order=created_at.asc",
+		url.QueryEscape(workspaceID)), "list workspace members")
+}
+
+// ListUserMemberships lists every workspace membership of a user
+func (sc *SupabaseClient) ListUserMemberships(userID string) ([]map[string]interface{}, error) {
+	return sc.selectRows(fmt.Sprintf("workspace_members?user_id=eq.%s&select=*&order=created_at.asc",
+		url.QueryEscape(userID)), "list workspace memberships")
+}
+
+// UpsertWorkspaceMember adds a member or changes their role
+func (sc *SupabaseClient) UpsertWorkspaceMember(workspaceID, userID, role string) (map[string]interface{}, error) {
+	return sc.upsertRow("workspace_members", "workspace_id,user_id", map[string]interface{}{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+		"role":         role,
+	}, "save workspace member")
+}
+
+// DeleteWorkspaceMember removes a user from a workspace
+func (sc *SupabaseClient) DeleteWorkspaceMember(workspaceID, userID string) error {
+	return sc.deleteRows(fmt.Sprintf("workspace_members?workspace_id=eq.%s&user_id=eq.%s",
+		url.QueryEscape(workspaceID), url.QueryEscape(userID)), "remove workspace member")
+}
+
+// CreateWorkspaceInvite stores an invite (role, token hash, expiry)
+func (sc *SupabaseClient) CreateWorkspaceInvite(inviteData map[string]interface{}) (map[string]interface{}, error) {
+	return sc.insertRow("workspace_invites", inviteData, "create workspace invite")
+}
+
+// AcceptWorkspaceInvite marks the unexpired, unused invite with the given hash
+// as accepted by userID and returns it, or nil if no such invite exists. The
+// conditional update makes each invite usable once.
+func (sc *SupabaseClient) AcceptWorkspaceInvite(tokenHash, userID string) (map[string]interface{}, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	rows, err := sc.updateRowsReturning(fmt.Sprintf("workspace_invites?token_hash=eq.%s&accepted_at=is.null&expires_at=gt.%s",
+		url.QueryEscape(tokenHash), url.QueryEscape(now)), map[string]interface{}{
+		"accepted_by": userID,
+		"accepted_at": now,
+	}, "accept workspace invite")
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// GetWorkspaceTasks returns the tasks shared in any of the workspaces, excluding trashed tasks
+func (sc *SupabaseClient) GetWorkspaceTasks(workspaceIDs []string) ([]map[string]interface{}, error) {
+	if len(workspaceIDs) == 0 {
+		return []map[string]interface{}{}, nil
+	}
+	return sc.selectRows(fmt.Sprintf("tasks?workspace_id=in.(%s)&deleted_at=is.null&select=*&order=created_at.desc",
+		inList(workspaceIDs)), "get workspace tasks")
+}
+
+// GetWorkspaceGoals returns the goals shared in any of the workspaces, excluding trashed goals
+func (sc *SupabaseClient) GetWorkspaceGoals(workspaceIDs []string) ([]map[string]interface{}, error) {
+	if len(workspaceIDs) == 0 {
+		return []map[string]interface{}{}, nil
+	}
+	return sc.selectRows(fmt.Sprintf("goals?workspace_id=in.(%s)&deleted_at=is.null&select=*&order=created_at.desc",
+		inList(workspaceIDs)), "get workspace goals")
+}
+
+// inList formats values for a PostgREST in.(...) filter
+func inList(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = url.QueryEscape(v)
+	}
+	return strings.Join(escaped, ",")
+}
@@ -0,0 +1,525 @@
+//go:build !lite
+
+// Package doctor implements the `doctor` subcommand: it checks the
+// configuration, the services the server depends on, the database schema,
+// clock skew, token signing and common misconfigurations, and prints a fix
+// for everything it finds. Its output is meant to be pasted into bug reports.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/handlers"
+	"github.com/productivity/mcp-server/signing"
+)
+
+// Check outcomes
+const (
+	StatusOK   = "OK"
+	StatusWarn = "WARN"
+	StatusFail = "FAIL"
+	StatusSkip = "SKIP"
+)
+
+// Clock skew limits; access tokens are only valid for an hour and are
+// compared against the server clock
+const (
+	skewWarn = 30 * time.Second
+	skewFail = 5 * time.Minute
+)
+
+// claudeCallback is the redirect URI Claude uses for remote MCP servers
+const claudeCallback = "https://claude.ai/api/mcp/auth_callback"
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string
+	Status string
+	Detail string
+	Fix    string
+}
+
+// Doctor runs the checks against one configuration
+type Doctor struct {
+	cfg      *config.Config
+	problems []string
+	loadErr  error
+	client   *http.Client
+
+	// serverTime is the Supabase clock, taken from its Date header
+	serverTime time.Time
+
+	results []Result
+	out     io.Writer
+}
+
+// Run parses the subcommand's arguments, runs every check and returns an
+// error if any of them failed
+func Run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file (default $"+config.FileEnv+")")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout for each connectivity check")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	d := New(*configFile, *timeout, out)
+	return d.Run(ctx)
+}
+
+// New loads the configuration at path, keeping it for the checks even when it
+// has problems
+func New(path string, timeout time.Duration, out io.Writer) *Doctor {
+	d := &Doctor{
+		client: &http.Client{Timeout: timeout},
+		out:    out,
+	}
+
+	cfg, err := config.Load(path)
+	var verr *config.ValidationError
+	switch {
+	case err == nil:
+		d.cfg = cfg
+	case errors.As(err, &verr):
+		d.cfg = verr.Config
+		d.problems = verr.Problems
+	default:
+		d.loadErr = err
+	}
+	return d
+}
+
+// Run runs every check, printing each result as it completes
+func (d *Doctor) Run(ctx context.Context) error {
+	d.checkConfig()
+	if d.cfg == nil {
+		return d.summary()
+	}
+	d.checkJWT()
+	d.checkStrictAuth()
+	d.checkAudience()
+	d.checkSupabase(ctx)
+	d.checkClock()
+	d.checkAnthropic(ctx)
+	d.checkOllama(ctx)
+	d.checkEventPublisher(ctx)
+	d.checkCORS()
+	d.checkRedirectURIs()
+	return d.summary()
+}
+
+// Results returns every result recorded so far
+func (d *Doctor) Results() []Result {
+	return d.results
+}
+
+func (d *Doctor) report(name, status, detail, fix string) {
+	d.results = append(d.results, Result{Name: name, Status: status, Detail: detail, Fix: fix})
+	fmt.Fprintf(d.out, "[%-4s] %-18s %s\n", status, name, detail)
+	if fix != "" {
+		fmt.Fprintf(d.out, "       %-18s fix: %s\n", "", fix)
+	}
+}
+
+func (d *Doctor) summary() error {
+	failed, warned := 0, 0
+	for _, r := range d.results {
+		switch r.Status {
+		case StatusFail:
+			failed++
+		case StatusWarn:
+			warned++
+		}
+	}
+	fmt.Fprintf(d.out, "\n%d checks, %d failed, %d warnings\n", len(d.results), failed, warned)
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
+
+func (d *Doctor) checkConfig() {
+	source := "environment"
+	if d.cfg != nil && d.cfg.File != "" {
+		source = d.cfg.File
+	}
+	if d.loadErr != nil {
+		d.report("Configuration", StatusFail, d.loadErr.Error(), "check the -config path or $"+config.FileEnv)
+		return
+	}
+	if len(d.problems) == 0 {
+		d.report("Configuration", StatusOK, "loaded from "+source, "")
+		return
+	}
+	for _, problem := range d.problems {
+		name, _, _ := strings.Cut(problem, ":")
+		d.report("Configuration", StatusFail, problem,
+			fmt.Sprintf("set %s in the environment, .env or the config file (see config.example.yaml)", name))
+	}
+}
+
+func (d *Doctor) checkJWT() {
+	if len(d.cfg.Auth.SigningKeys) > 0 {
+		d.checkSigningKeys()
+		return
+	}
+
+	secret := d.cfg.Auth.JWTSecret
+	if secret == "" || d.cfg.Auth.GeneratedSecret {
+		d.report("Token signing", StatusWarn, "JWT_SECRET is not set; a random secret is generated on every start",
+			"set JWT_SECRET (`setup` generates one) so tokens survive restarts and work across instances")
+		return
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "doctor",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte(secret))
+	if err == nil {
+		_, err = jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return []byte(secret), nil })
+	}
+	switch {
+	case err != nil:
+		d.report("Token signing", StatusFail, "sign/verify round trip failed: "+err.Error(), "regenerate JWT_SECRET")
+	case len(secret) < 32:
+		d.report("Token signing", StatusWarn, fmt.Sprintf("HS256 works but JWT_SECRET is only %d characters", len(secret)),
+			"use at least 32 random bytes, e.g. `openssl rand -hex 32`")
+	default:
+		d.report("Token signing", StatusOK, "HS256 sign/verify round trip", "")
+	}
+}
+
+// checkStrictAuth warns when callers can name the user they act for
+func (d *Doctor) checkStrictAuth() {
+	if d.cfg.Auth.Strict {
+		d.report("User identity", StatusOK, "users come only from tokens and API keys", "")
+		return
+	}
+	d.report("User identity", StatusWarn, "anonymous callers may act for any user with ?user_id= or X-User-ID",
+		"set AUTH_STRICT=true, and give services acting for users a `servicetoken`")
+}
+
+// checkAudience warns when tokens are not bound to this server
+func (d *Doctor) checkAudience() {
+	if d.cfg.Auth.Resource != "" {
+		d.report("Token audience", StatusOK, "tokens are issued for and accepted only for "+d.cfg.Auth.Resource, "")
+		return
+	}
+	d.report("Token audience", StatusWarn, "OAUTH_RESOURCE is not set; tokens carry no audience",
+		"set OAUTH_RESOURCE to this server's MCP URL so its tokens cannot be replayed elsewhere")
+}
+
+// checkSigningKeys loads the asymmetric keys and round-trips a token through
+// each of them, so a key that is only listed for verification is checked too
+func (d *Doctor) checkSigningKeys() {
+	keys, err := signing.Load("", d.cfg.Auth.SigningKeys)
+	if err != nil {
+		d.report("Token signing", StatusFail, err.Error(),
+			"point JWT_SIGNING_KEYS at PEM private keys, e.g. from `productivity-mcp keygen -out key.pem`")
+		return
+	}
+
+	active := keys.SigningKey()
+	token, err := keys.Sign(jwt.MapClaims{"sub": "doctor", "exp": time.Now().Add(time.Minute).Unix()})
+	if err == nil {
+		_, err = keys.Verify(token)
+	}
+	if err != nil {
+		d.report("Token signing", StatusFail, "sign/verify round trip failed: "+err.Error(), "regenerate the key with `productivity-mcp keygen`")
+		return
+	}
+	detail := fmt.Sprintf("%s sign/verify round trip with key %s", active.Algorithm, active.ID)
+	if n := len(keys.JWKS().Keys) - 1; n > 0 {
+		detail += fmt.Sprintf("; %d more published for verification", n)
+	}
+	d.report("Token signing", StatusOK, detail, "")
+}
+
+func (d *Doctor) checkSupabase(ctx context.Context) {
+	base := d.cfg.Supabase.URL
+	if base == "" || d.cfg.Supabase.AnonKey == "" {
+		d.report("Supabase", StatusSkip, "SUPABASE_URL or SUPABASE_ANON_KEY not set", "")
+		return
+	}
+	if strings.HasPrefix(base, db.SQLiteScheme) {
+		d.report("Supabase", StatusSkip, "using a local SQLite database", "")
+		return
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, base+"/rest/v1/", nil)
+	req.Header.Set("apikey", d.cfg.Supabase.AnonKey)
+	req.Header.Set("Authorization", "Bearer "+d.cfg.Supabase.AnonKey)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.report("Supabase", StatusFail, "unreachable: "+err.Error(),
+			"check SUPABASE_URL (Project Settings > API) and outbound network access")
+		return
+	}
+	resp.Body.Close()
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		d.serverTime = date
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		d.report("Supabase", StatusFail, "the anon key was rejected ("+resp.Status+")",
+			"copy the anon public key from Project Settings > API into SUPABASE_ANON_KEY")
+		return
+	case resp.StatusCode >= 500:
+		d.report("Supabase", StatusFail, "REST API error ("+resp.Status+")", "check the project status in the Supabase dashboard")
+		return
+	}
+	d.report("Supabase", StatusOK, "REST API reachable at "+base, "")
+
+	// The tables holding credentials have no policy letting the anon key in
+	db.ConfigureServiceRole(d.cfg.Supabase.ServiceRoleKey)
+	if d.cfg.Supabase.ServiceRoleKey == "" {
+		d.report("Service role", StatusWarn, "SUPABASE_SERVICE_ROLE_KEY not set; API keys, OAuth clients and token revocations are unreadable with the anon key",
+			"copy the service_role key from Project Settings > API into SUPABASE_SERVICE_ROLE_KEY")
+	}
+
+	client, err := db.NewSupabaseClient(base, d.cfg.Supabase.AnonKey)
+	if err != nil {
+		d.report("Schema", StatusFail, err.Error(), "")
+		return
+	}
+	var missing []string
+	migrations := map[string]bool{}
+	for _, table := range db.Tables {
+		if err := client.Ping(table.Name); err != nil {
+			missing = append(missing, table.Name)
+			migrations[table.Migration] = true
+		}
+	}
+	if len(missing) == 0 {
+		d.report("Schema", StatusOK, fmt.Sprintf("all %d tables present", len(db.Tables)), "")
+		return
+	}
+	var names []string
+	for _, table := range db.Tables {
+		if migrations[table.Migration] {
+			names = append(names, table.Migration)
+			delete(migrations, table.Migration)
+		}
+	}
+	d.report("Schema", StatusFail, "missing or unreadable tables: "+strings.Join(missing, ", "),
+		"run `productivity-mcp setup -database-url ...` or apply db/migrations "+strings.Join(names, ", "))
+}
+
+func (d *Doctor) checkClock() {
+	if d.serverTime.IsZero() {
+		d.report("Clock skew", StatusSkip, "no reference clock (Supabase did not answer)", "")
+		return
+	}
+	// The Date header has one-second resolution
+	skew := time.Since(d.serverTime).Round(time.Second)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	detail := fmt.Sprintf("local clock is %s off Supabase", skew)
+	fix := "enable NTP time sync (e.g. `timedatectl set-ntp true`)"
+	switch {
+	case abs > skewFail:
+		d.report("Clock skew", StatusFail, detail+"; tokens will be rejected as expired or not yet valid", fix)
+	case abs > skewWarn:
+		d.report("Clock skew", StatusWarn, detail, fix)
+	default:
+		d.report("Clock skew", StatusOK, detail, "")
+	}
+}
+
+func (d *Doctor) checkAnthropic(ctx context.Context) {
+	if d.cfg.Claude.APIKey == "" {
+		d.report("Anthropic", StatusWarn, "CLAUDE_API_KEY is not set; AI features are disabled",
+			"set CLAUDE_API_KEY from console.anthropic.com")
+		return
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, d.cfg.Claude.BaseURL+"/v1/models?limit=1000", nil)
+	req.Header.Set("x-api-key", d.cfg.Claude.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.report("Anthropic", StatusFail, "unreachable: "+err.Error(), "check outbound HTTPS access to "+d.cfg.Claude.BaseURL)
+		return
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		d.report("Anthropic", StatusFail, "CLAUDE_API_KEY was rejected ("+resp.Status+")",
+			"create a new key at console.anthropic.com and set CLAUDE_API_KEY")
+		return
+	case resp.StatusCode != http.StatusOK:
+		d.report("Anthropic", StatusWarn, "unexpected response ("+resp.Status+")", "check status.anthropic.com")
+		return
+	}
+
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&models)
+	for _, m := range models.Data {
+		if m.ID == d.cfg.Claude.Model {
+			d.report("Anthropic", StatusOK, "API key accepted; model "+d.cfg.Claude.Model+" available", "")
+			return
+		}
+	}
+	d.report("Anthropic", StatusWarn, "API key accepted but model "+d.cfg.Claude.Model+" is not listed",
+		"set CLAUDE_MODEL to a current model ID")
+}
+
+func (d *Doctor) checkOllama(ctx context.Context) {
+	if d.cfg.Ollama.URL == "" {
+		d.report("Ollama", StatusSkip, "OLLAMA_URL not set", "")
+		return
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(d.cfg.Ollama.URL, "/")+"/api/tags", nil)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.report("Ollama", StatusWarn, "unreachable at "+d.cfg.Ollama.URL+" (only needed for /api/ollama routes)",
+			"start Ollama (`ollama serve`) or point OLLAMA_URL at a running instance")
+		return
+	}
+	defer resp.Body.Close()
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	json.NewDecoder(resp.Body).Decode(&tags)
+	for _, m := range tags.Models {
+		if m.Name == d.cfg.Ollama.Model {
+			d.report("Ollama", StatusOK, "reachable; model "+d.cfg.Ollama.Model+" pulled", "")
+			return
+		}
+	}
+	d.report("Ollama", StatusWarn, "reachable but model "+d.cfg.Ollama.Model+" is not pulled",
+		"run `ollama pull "+d.cfg.Ollama.Model+"` or change OLLAMA_MODEL")
+}
+
+func (d *Doctor) checkEventPublisher(ctx context.Context) {
+	switch d.cfg.Events.Publisher {
+	case "nats":
+		u, err := url.Parse(d.cfg.Events.NATSURL)
+		if err != nil || u.Host == "" {
+			d.report("Event publisher", StatusFail, "NATS_URL is not a URL", "use nats://host:4222")
+			return
+		}
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "4222")
+		}
+		dialer := net.Dialer{Timeout: d.client.Timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", host)
+		if err != nil {
+			d.report("Event publisher", StatusFail, "NATS unreachable: "+err.Error(), "check NATS_URL and that the server is running")
+			return
+		}
+		conn.Close()
+		d.report("Event publisher", StatusOK, "NATS reachable at "+host, "")
+	case "kafka":
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(d.cfg.Events.KafkaRESTURL, "/")+"/topics", nil)
+		resp, err := d.client.Do(req)
+		if err != nil {
+			d.report("Event publisher", StatusFail, "Kafka REST proxy unreachable: "+err.Error(), "check KAFKA_REST_URL")
+			return
+		}
+		resp.Body.Close()
+		d.report("Event publisher", StatusOK, "Kafka REST proxy reachable", "")
+	default:
+		d.report("Event publisher", StatusSkip, "EVENT_PUBLISHER not set", "")
+	}
+}
+
+func (d *Doctor) checkCORS() {
+	cors := d.cfg.CORS
+	for _, origin := range cors.AllowedOrigins {
+		if origin == "*" {
+			if d.cfg.Server.Release() {
+				d.report("CORS", StatusWarn, "any website may call the API from a browser",
+					"set CORS_ALLOWED_ORIGINS to the origins of your web clients")
+			} else {
+				d.report("CORS", StatusOK, "all origins allowed (fine outside release mode)", "")
+			}
+			return
+		}
+	}
+	for _, header := range []string{"Authorization", "Content-Type"} {
+		found := false
+		for _, h := range cors.AllowedHeaders {
+			if strings.EqualFold(h, header) {
+				found = true
+			}
+		}
+		if !found {
+			d.report("CORS", StatusWarn, header+" is not in CORS_ALLOWED_HEADERS; browser clients cannot send it",
+				"add "+header+" to CORS_ALLOWED_HEADERS")
+			return
+		}
+	}
+	d.report("CORS", StatusOK, fmt.Sprintf("%d explicit origins", len(cors.AllowedOrigins)), "")
+}
+
+func (d *Doctor) checkRedirectURIs() {
+	if d.cfg.Supabase.URL == "" || d.cfg.Supabase.AnonKey == "" || strings.HasPrefix(d.cfg.Supabase.URL, db.SQLiteScheme) {
+		d.report("Redirect URIs", StatusSkip, "no Supabase project to read OAuth clients from", "")
+		return
+	}
+	client, err := db.NewSupabaseClient(d.cfg.Supabase.URL, d.cfg.Supabase.AnonKey)
+	if err != nil {
+		d.report("Redirect URIs", StatusSkip, err.Error(), "")
+		return
+	}
+	rows, err := client.ListOAuthClients()
+	if err != nil {
+		d.report("Redirect URIs", StatusSkip, "cannot list OAuth clients: "+err.Error(), "")
+		return
+	}
+	if len(rows) == 0 {
+		d.report("Redirect URIs", StatusWarn, "no registered OAuth clients; only the built-in development clients work",
+			"run `productivity-mcp setup` or POST /oauth/register")
+		return
+	}
+
+	claude := false
+	for _, row := range rows {
+		id, _ := row["client_id"].(string)
+		uris, _ := row["redirect_uris"].([]interface{})
+		for _, raw := range uris {
+			uri, _ := raw.(string)
+			if uri == claudeCallback {
+				claude = true
+			}
+			if err := handlers.CheckRedirectURI(uri); err != nil {
+				d.report("Redirect URIs", StatusWarn, "client "+id+": "+err.Error(),
+					"update it with PUT /oauth/register/"+id)
+				return
+			}
+		}
+	}
+	if !claude {
+		d.report("Redirect URIs", StatusWarn, "no client allows "+claudeCallback,
+			"register a client with that redirect URI to connect Claude")
+		return
+	}
+	d.report("Redirect URIs", StatusOK, fmt.Sprintf("%d clients, all redirect URIs valid", len(rows)), "")
+}
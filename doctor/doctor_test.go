@@ -0,0 +1,103 @@
+//go:build !lite
+
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDoctorReportsProblemsWithFixes(t *testing.T) {
+	supabase := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-10*time.Minute).UTC().Format(http.TimeFormat))
+		switch r.URL.Path {
+		case "/rest/v1/revoked_tokens":
+			http.Error(w, `{"message":"relation does not exist"}`, http.StatusNotFound)
+		case "/rest/v1/oauth_clients":
+			w.Write([]byte(`[{"client_id":"web","redirect_uris":["http://app.example.com/cb"]}]`))
+		default:
+			w.Write([]byte("[]"))
+		}
+	}))
+	defer supabase.Close()
+	anthropic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type":"error"}`, http.StatusUnauthorized)
+	}))
+	defer anthropic.Close()
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[{"name":"llama3"}]}`))
+	}))
+	defer ollama.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`supabase:
+  url: `+supabase.URL+`
+  anon_key: anon
+auth:
+  jwt_secret: 0123456789abcdef0123456789abcdef
+claude:
+  api_key: sk-ant-bad
+  base_url: `+anthropic.URL+`
+ollama:
+  url: `+ollama.URL+`
+  model: llama3
+`), 0600)
+
+	var out strings.Builder
+	d := New(path, time.Second, &out)
+	if err := d.Run(context.Background()); err == nil {
+		t.Error("expected failed checks to return an error")
+	}
+
+	got := map[string]Result{}
+	for _, r := range d.Results() {
+		got[r.Name] = r
+	}
+	want := map[string]string{
+		"Configuration":   StatusOK,
+		"Token signing":   StatusOK,
+		"Supabase":        StatusOK,
+		"Schema":          StatusFail,
+		"Clock skew":      StatusFail,
+		"Anthropic":       StatusFail,
+		"Ollama":          StatusOK,
+		"Event publisher": StatusSkip,
+		"Redirect URIs":   StatusWarn,
+	}
+	for name, status := range want {
+		if got[name].Status != status {
+			t.Errorf("%s: status %q, want %q (%s)", name, got[name].Status, status, got[name].Detail)
+		}
+	}
+	if fix := got["Schema"].Fix; !strings.Contains(fix, "009_revoked_tokens") {
+		t.Errorf("schema fix should name the missing migration, got %q", fix)
+	}
+	if !strings.Contains(out.String(), "fix:") {
+		t.Errorf("fixes not printed:\n%s", out.String())
+	}
+}
+
+func TestDoctorReportsEveryConfigProblem(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("server:\n  port: \"0\"\nquota:\n  requests: -1\n"), 0600)
+
+	var out strings.Builder
+	d := New(path, time.Second, &out)
+	d.Run(context.Background())
+
+	failed := 0
+	for _, r := range d.Results() {
+		if r.Name == "Configuration" && r.Status == StatusFail {
+			failed++
+		}
+	}
+	if failed < 2 {
+		t.Errorf("expected each config problem reported, got:\n%s", out.String())
+	}
+}
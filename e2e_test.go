@@ -0,0 +1,392 @@
+//go:build !lite
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/mockllm"
+	"github.com/productivity/mcp-server/mocksupabase"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// e2eServer is the server as main runs it, with Supabase and Claude faked
+type e2eServer struct {
+	t        testing.TB
+	url      string
+	supabase *mocksupabase.Handler
+	client   *http.Client
+}
+
+func startServer(t testing.TB) *e2eServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	supabase := mocksupabase.NewHandler()
+	supabaseServer := httptest.NewServer(supabase)
+	t.Cleanup(supabaseServer.Close)
+	llm := httptest.NewServer(mockllm.NewHandler())
+	t.Cleanup(llm.Close)
+
+	for key, value := range map[string]string{
+		config.FileEnv:      "",
+		"SUPABASE_URL":      supabaseServer.URL,
+		"SUPABASE_ANON_KEY": "e2e-anon-key",
+		"CLAUDE_API_KEY":    "mock",
+		"CLAUDE_BASE_URL":   llm.URL,
+		"JWT_SECRET":        "e2e-jwt-secret",
+		"GIN_MODE":          gin.TestMode,
+		"LOG_LEVEL":         "error",
+	} {
+		t.Setenv(key, value)
+	}
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := utils.NewLogger()
+	logger.SetLevel(utils.LogLevel(cfg.Log.Level))
+
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	t.Cleanup(stopWorkers)
+	router, err := newRouter(ctx, cfg, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return &e2eServer{t: t, url: server.URL, supabase: supabase, client: &http.Client{
+		// Redirects are what the OAuth flow is checked by
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		Timeout:       10 * time.Second,
+	}}
+}
+
+// do sends a request with a JSON body, if body is not nil, and the given
+// headers, and returns the response with its body read
+func (s *e2eServer) do(method, path string, body interface{}, headers ...string) (*http.Response, []byte) {
+	s.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.url+path, reader)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return resp, data
+}
+
+// json sends a request like do, fails unless it is answered with status,
+// and decodes the answer into out
+func (s *e2eServer) json(method, path string, body interface{}, status int, out interface{}, headers ...string) http.Header {
+	s.t.Helper()
+	resp, data := s.do(method, path, body, headers...)
+	if resp.StatusCode != status {
+		s.t.Fatalf("%s %s: %d %s, want %d", method, path, resp.StatusCode, data, status)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			s.t.Fatalf("%s %s: decode %s: %v", method, path, data, err)
+		}
+	}
+	return resp.Header
+}
+
+var consentIDPattern = regexp.MustCompile(`name="consent_id" value="([^"]+)"`)
+
+// signIn registers a public client and takes it through the authorization
+// code flow with PKCE, approving the consent screen, and returns the tokens
+func (s *e2eServer) signIn() (access, refresh string) {
+	s.t.Helper()
+	const redirectURI = "http://localhost:9000/callback"
+	var client struct {
+		ClientID string `json:"client_id"`
+	}
+	s.json(http.MethodPost, "/oauth/register", map[string]interface{}{
+		"client_name":                "E2E Client",
+		"redirect_uris":              []string{redirectURI},
+		"token_endpoint_auth_method": "none",
+	}, http.StatusCreated, &client)
+
+	verifier := strings.Repeat("e2e-verifier-", 4)
+	sum := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"client_id":             {client.ClientID},
+		"redirect_uri":          {redirectURI},
+		"response_type":         {"code"},
+		"state":                 {"e2e-state"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	resp, body := s.do(http.MethodGet, "/authorize?"+q.Encode(), nil)
+	m := consentIDPattern.FindSubmatch(body)
+	if resp.StatusCode != http.StatusOK || m == nil {
+		s.t.Fatalf("authorize: want the consent screen, got %d %s", resp.StatusCode, body)
+	}
+
+	form := url.Values{"consent_id": {string(m[1])}, "decision": {"approve"}}
+	req, _ := http.NewRequest(http.MethodPost, s.url+"/authorize/consent", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound || err != nil || !strings.HasPrefix(location.String(), redirectURI) {
+		s.t.Fatalf("consent: %d to %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if location.Query().Get("state") != "e2e-state" || location.Query().Get("code") == "" {
+		s.t.Fatalf("consent redirected with %v", location.Query())
+	}
+
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+	}
+	s.json(http.MethodPost, "/oauth/token", map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          location.Query().Get("code"),
+		"client_id":     client.ClientID,
+		"redirect_uri":  redirectURI,
+		"code_verifier": verifier,
+	}, http.StatusOK, &tokens)
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" || !strings.EqualFold(tokens.TokenType, "bearer") {
+		s.t.Fatalf("token: got %+v", tokens)
+	}
+	return tokens.AccessToken, tokens.RefreshToken
+}
+
+func bearer(token string) []string {
+	return []string{"Authorization", "Bearer " + token}
+}
+
+func TestEndToEndOAuth(t *testing.T) {
+	s := startServer(t)
+
+	var discovery map[string]interface{}
+	s.json(http.MethodGet, "/.well-known/oauth-authorization-server", nil, http.StatusOK, &discovery)
+	for _, key := range []string{"authorization_endpoint", "token_endpoint", "registration_endpoint"} {
+		if endpoint, _ := discovery[key].(string); !strings.HasPrefix(endpoint, s.url) {
+			t.Errorf("discovery %s = %v", key, discovery[key])
+		}
+	}
+
+	access, refresh := s.signIn()
+	if len(s.supabase.Rows("oauth_clients")) != 1 || len(s.supabase.Rows("oauth_grants")) != 1 {
+		t.Errorf("client and grant not stored: %v %v", s.supabase.Rows("oauth_clients"), s.supabase.Rows("oauth_grants"))
+	}
+
+	var me map[string]interface{}
+	s.json(http.MethodGet, "/api/me", nil, http.StatusOK, &me, bearer(access)...)
+	if me["user_id"] == "" || me["user_id"] == nil {
+		t.Errorf("/api/me: %v", me)
+	}
+
+	// The refresh token gets a new access token; signing out revokes the old one
+	var refreshed struct {
+		AccessToken string `json:"access_token"`
+	}
+	s.json(http.MethodPost, "/oauth/token", map[string]string{"grant_type": "refresh_token", "refresh_token": refresh},
+		http.StatusOK, &refreshed)
+	if refreshed.AccessToken == "" || refreshed.AccessToken == access {
+		t.Fatalf("refresh: got %q", refreshed.AccessToken)
+	}
+	s.json(http.MethodPost, "/oauth/logout", map[string]string{"token": access}, http.StatusOK, nil)
+	if resp, body := s.do(http.MethodPost, "/mcp/initialize", nil, bearer(access)...); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("revoked token: %d %s, want 401", resp.StatusCode, body)
+	}
+	if resp, body := s.do(http.MethodPost, "/mcp/initialize", nil, bearer(refreshed.AccessToken)...); resp.StatusCode != http.StatusOK {
+		t.Errorf("refreshed token: %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := s.do(http.MethodPost, "/mcp/initialize", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no token: %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestEndToEndMCP(t *testing.T) {
+	s := startServer(t)
+	access, _ := s.signIn()
+
+	var initialized struct {
+		Result struct {
+			ProtocolVersion string `json:"protocolVersion"`
+			ServerInfo      struct {
+				Name string `json:"name"`
+			} `json:"serverInfo"`
+		} `json:"result"`
+	}
+	header := s.json(http.MethodPost, "/mcp/initialize", map[string]interface{}{
+		"jsonrpc": "2.0", "id": 1, "method": "initialize",
+		"params": map[string]interface{}{"protocolVersion": "2025-06-18", "clientInfo": map[string]string{"name": "e2e"}},
+	}, http.StatusOK, &initialized, bearer(access)...)
+	session := header.Get("Mcp-Session-Id")
+	if session == "" || initialized.Result.ProtocolVersion != "2025-06-18" || initialized.Result.ServerInfo.Name == "" {
+		t.Fatalf("initialize: session %q, result %+v", session, initialized.Result)
+	}
+	headers := append(bearer(access), "Mcp-Session-Id", session)
+
+	var listed struct {
+		Result struct {
+			Tools []struct {
+				Name string `json:"name"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	s.json(http.MethodPost, "/mcp/list_tools", map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "tools/list"},
+		http.StatusOK, &listed, headers...)
+	names := map[string]bool{}
+	for _, tool := range listed.Result.Tools {
+		names[tool.Name] = true
+	}
+	for _, want := range []string{"create_task", "parse_task", "get_today_tasks"} {
+		if !names[want] {
+			t.Errorf("tools/list is missing %s: %v", want, names)
+		}
+	}
+
+	type callResult struct {
+		ID     int `json:"id"`
+		Result struct {
+			IsError           bool                   `json:"isError"`
+			StructuredContent map[string]interface{} `json:"structuredContent"`
+		} `json:"result"`
+		Error map[string]interface{} `json:"error"`
+	}
+	call := func(id int, method string, params map[string]interface{}) callResult {
+		t.Helper()
+		var result callResult
+		s.json(http.MethodPost, "/mcp/call_tool", map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params},
+			http.StatusOK, &result, headers...)
+		if result.ID != id || result.Error != nil || result.Result.IsError {
+			t.Fatalf("%s: %+v", method, result)
+		}
+		return result
+	}
+
+	// parse_task asks the fake Claude; create_task writes to the fake Supabase
+	parsed := call(3, "parse_task", map[string]interface{}{"input": "urgent: send the client report tomorrow"})
+	task, _ := parsed.Result.StructuredContent["task"].(map[string]interface{})
+	if task == nil || task["priority"] != 5.0 {
+		t.Fatalf("parse_task: %+v", parsed.Result)
+	}
+	call(4, "create_task", map[string]interface{}{
+		"title":    task["title"],
+		"priority": task["priority"],
+		"due_date": time.Now().UTC().Add(time.Hour).Format(time.RFC3339),
+	})
+	rows := s.supabase.Rows("tasks")
+	if len(rows) != 1 || rows[0]["title"] != task["title"] {
+		t.Fatalf("create_task stored %v", rows)
+	}
+	today := call(5, "get_today_tasks", map[string]interface{}{})
+	if !strings.Contains(mustJSON(t, today.Result.StructuredContent), rows[0]["id"].(string)) {
+		t.Errorf("get_today_tasks does not list the new task: %+v", today.Result.StructuredContent)
+	}
+
+	// Unknown tools are JSON-RPC errors, and an ended session is refused
+	var unknown callResult
+	s.json(http.MethodPost, "/mcp/call_tool", map[string]interface{}{"jsonrpc": "2.0", "id": 6, "method": "no_such_tool"},
+		http.StatusBadRequest, &unknown, headers...)
+	if unknown.Error == nil || unknown.Error["code"] != -32601.0 {
+		t.Errorf("unknown tool: %+v", unknown)
+	}
+	s.json(http.MethodDelete, "/mcp/session", nil, http.StatusNoContent, nil, headers...)
+	if resp, body := s.do(http.MethodPost, "/mcp/list_tools", nil, headers...); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("ended session: %d %s, want 404", resp.StatusCode, body)
+	}
+}
+
+func TestEndToEndTaskCRUD(t *testing.T) {
+	s := startServer(t)
+	access, _ := s.signIn()
+	auth := bearer(access)
+
+	due := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)
+	var created map[string]interface{}
+	s.json(http.MethodPost, "/api/tasks", map[string]interface{}{
+		"title": "Write the quarterly report", "priority": 4, "due_date": due, "category": "work",
+	}, http.StatusCreated, &created, auth...)
+	id, _ := created["id"].(string)
+	if id == "" || created["title"] != "Write the quarterly report" {
+		t.Fatalf("create: %v", created)
+	}
+
+	var got map[string]interface{}
+	s.json(http.MethodGet, "/api/tasks/"+id, nil, http.StatusOK, &got, auth...)
+	if got["id"] != id || got["priority"] != 4.0 {
+		t.Errorf("get: %v", got)
+	}
+
+	var list []map[string]interface{}
+	s.json(http.MethodGet, "/api/tasks", nil, http.StatusOK, &list, auth...)
+	if len(list) != 1 || list[0]["id"] != id {
+		t.Errorf("list: %v", list)
+	}
+
+	var updated map[string]interface{}
+	s.json(http.MethodPut, "/api/tasks/"+id, map[string]interface{}{"title": "Send the quarterly report", "completed": true},
+		http.StatusOK, &updated, auth...)
+	if updated["title"] != "Send the quarterly report" || updated["completed"] != true {
+		t.Errorf("update: %v", updated)
+	}
+	if rows := s.supabase.Rows("tasks"); len(rows) != 1 || rows[0]["completed"] != true {
+		t.Errorf("update stored %v", rows)
+	}
+
+	s.json(http.MethodDelete, "/api/tasks/"+id, nil, http.StatusOK, nil, auth...)
+	if resp, body := s.do(http.MethodGet, "/api/tasks/"+id, nil, auth...); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("get after delete: %d %s, want 404", resp.StatusCode, body)
+	}
+	s.json(http.MethodGet, "/api/tasks", nil, http.StatusOK, &list, auth...)
+	if len(list) != 0 {
+		t.Errorf("list after delete: %v", list)
+	}
+	if len(s.supabase.Rows("audit_log")) < 3 {
+		t.Errorf("audit log: %v", s.supabase.Rows("audit_log"))
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,107 @@
+// Package embeddings turns task text into vectors whose cosine similarity
+// reflects how alike the texts are. Vectors come from a hosted model (OpenAI,
+// Voyage, which Anthropic recommends for Claude users, or a local Ollama) or
+// from a hashed bag of words that needs no network at all.
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/productivity/mcp-server/config"
+)
+
+// Embedder embeds texts. Vectors from different models are not comparable,
+// so stored vectors are kept with the Model that made them.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Model() string
+}
+
+// Provider defaults
+const (
+	DefaultOpenAIURL   = "https://api.openai.com"
+	DefaultOpenAIModel = "text-embedding-3-small"
+	DefaultVoyageURL   = "https://api.voyageai.com"
+	DefaultVoyageModel = "voyage-3-lite"
+	DefaultOllamaModel = "nomic-embed-text"
+)
+
+// New creates the embedder selected by cfg.Provider. ollamaURL is used by the
+// ollama provider when cfg.URL is empty.
+func New(cfg config.Embeddings, ollamaURL string) (Embedder, error) {
+	switch cfg.Provider {
+	case "", "local":
+		return NewLocal(), nil
+	case "openai":
+		return newHTTPEmbedder(cfg, DefaultOpenAIURL, DefaultOpenAIModel), nil
+	case "voyage":
+		return newHTTPEmbedder(cfg, DefaultVoyageURL, DefaultVoyageModel), nil
+	case "ollama":
+		if cfg.URL == "" {
+			cfg.URL = ollamaURL
+		}
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("EMBEDDINGS_URL or OLLAMA_URL is required for the ollama provider")
+		}
+		return newOllamaEmbedder(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown EMBEDDINGS_PROVIDER %q (expected local, openai, voyage or ollama)", cfg.Provider)
+	}
+}
+
+// Cosine returns the cosine similarity of a and b, from -1 to 1; vectors of
+// different lengths or without magnitude have similarity 0
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// Parse reads a stored vector: a JSON array, or its text form "[0.1,0.2]" as
+// pgvector returns it through the REST API
+func Parse(v interface{}) ([]float32, bool) {
+	switch value := v.(type) {
+	case []float32:
+		return value, true
+	case []interface{}:
+		vec := make([]float32, len(value))
+		for i, x := range value {
+			f, ok := x.(float64)
+			if !ok {
+				return nil, false
+			}
+			vec[i] = float32(f)
+		}
+		return vec, len(vec) > 0
+	case string:
+		var vec []float32
+		if err := json.Unmarshal([]byte(strings.TrimSpace(value)), &vec); err != nil {
+			return nil, false
+		}
+		return vec, len(vec) > 0
+	}
+	return nil, false
+}
+
+// Format writes vec in pgvector's text form
+func Format(vec []float32) string {
+	parts := make([]string, len(vec))
+	for i, x := range vec {
+		parts[i] = fmt.Sprint(x)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
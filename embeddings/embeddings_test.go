@@ -0,0 +1,115 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/productivity/mcp-server/config"
+)
+
+func TestLocalRanksSharedWordingHigher(t *testing.T) {
+	vecs, err := NewLocal().Embed(context.Background(), []string{
+		"Email the client about the invoice",
+		"Emailing clients about invoices",
+		"Go for a run in the park",
+		"",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	similar, unrelated := Cosine(vecs[0], vecs[1]), Cosine(vecs[0], vecs[2])
+	if similar <= unrelated || similar < 0.4 {
+		t.Errorf("similar %.2f, unrelated %.2f", similar, unrelated)
+	}
+	if got := Cosine(vecs[0], vecs[0]); math.Abs(got-1) > 1e-6 {
+		t.Errorf("self similarity %.4f", got)
+	}
+	if got := Cosine(vecs[0], vecs[3]); got != 0 {
+		t.Errorf("similarity to empty text %.2f", got)
+	}
+}
+
+func TestParseAndFormat(t *testing.T) {
+	vec := []float32{0.5, -1, 0.25}
+	for _, stored := range []interface{}{Format(vec), []interface{}{0.5, -1.0, 0.25}} {
+		got, ok := Parse(stored)
+		if !ok || len(got) != 3 || got[0] != 0.5 || got[1] != -1 || got[2] != 0.25 {
+			t.Errorf("Parse(%v) = %v, %v", stored, got, ok)
+		}
+	}
+	for _, bad := range []interface{}{nil, "", "[]", "nope", []interface{}{"x"}} {
+		if _, ok := Parse(bad); ok {
+			t.Errorf("Parse(%v) accepted", bad)
+		}
+	}
+}
+
+func TestHTTPProviders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v1/embeddings":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			// Out of order, as the API allows
+			data := []map[string]interface{}{}
+			for i := len(req.Input) - 1; i >= 0; i-- {
+				data = append(data, map[string]interface{}{"index": i, "embedding": []float32{float32(i), 1}})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		case "/api/embed":
+			var vecs [][]float32
+			for i := range req.Input {
+				vecs = append(vecs, []float32{float32(i), 2})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": vecs})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		cfg   config.Embeddings
+		model string
+		last  float32
+	}{
+		{config.Embeddings{Provider: "openai", URL: srv.URL, APIKey: "secret"}, DefaultOpenAIModel, 1},
+		{config.Embeddings{Provider: "voyage", URL: srv.URL, APIKey: "secret", Model: "voyage-3"}, "voyage-3", 1},
+		{config.Embeddings{Provider: "ollama"}, DefaultOllamaModel, 2},
+	}
+	for _, tc := range cases {
+		e, err := New(tc.cfg, srv.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.cfg.Provider, err)
+		}
+		if e.Model() != tc.model {
+			t.Errorf("%s: model %q", tc.cfg.Provider, e.Model())
+		}
+		vecs, err := e.Embed(context.Background(), []string{"a", "b"})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.cfg.Provider, err)
+		}
+		if len(vecs) != 2 || vecs[1][0] != 1 || vecs[1][1] != tc.last {
+			t.Errorf("%s: vectors %v", tc.cfg.Provider, vecs)
+		}
+	}
+
+	bad, _ := New(config.Embeddings{Provider: "openai", URL: srv.URL, APIKey: "wrong"}, "")
+	if _, err := bad.Embed(context.Background(), []string{"a"}); err == nil {
+		t.Error("expected an error for a rejected key")
+	}
+	if _, err := New(config.Embeddings{Provider: "word2vec"}, ""); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
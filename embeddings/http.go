@@ -0,0 +1,137 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// httpEmbedder calls the /v1/embeddings API that OpenAI and Voyage share
+type httpEmbedder struct {
+	url        string
+	model      string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newHTTPEmbedder(cfg config.Embeddings, defaultURL, defaultModel string) *httpEmbedder {
+	e := &httpEmbedder{
+		url:        cfg.URL,
+		model:      cfg.Model,
+		apiKey:     cfg.APIKey,
+		httpClient: &http.Client{Transport: utils.NewLoggingTransport(cfg.Provider, nil), Timeout: 30 * time.Second},
+	}
+	if e.url == "" {
+		e.url = defaultURL
+	}
+	if e.model == "" {
+		e.model = defaultModel
+	}
+	return e
+}
+
+func (e *httpEmbedder) Model() string {
+	return e.model
+}
+
+func (e *httpEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := postJSON(ctx, e.httpClient, e.url+"/v1/embeddings", e.apiKey,
+		map[string]interface{}{"model": e.model, "input": texts}, &result); err != nil {
+		return nil, err
+	}
+
+	vecs := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	for i, vec := range vecs {
+		if len(vec) == 0 {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return vecs, nil
+}
+
+// ollamaEmbedder calls Ollama's /api/embed
+type ollamaEmbedder struct {
+	url        string
+	model      string
+	httpClient *http.Client
+}
+
+func newOllamaEmbedder(cfg config.Embeddings) *ollamaEmbedder {
+	e := &ollamaEmbedder{
+		url:        cfg.URL,
+		model:      cfg.Model,
+		httpClient: &http.Client{Transport: utils.NewLoggingTransport("ollama", nil), Timeout: 60 * time.Second},
+	}
+	if e.model == "" {
+		e.model = DefaultOllamaModel
+	}
+	return e
+}
+
+func (e *ollamaEmbedder) Model() string {
+	return e.model
+}
+
+func (e *ollamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := postJSON(ctx, e.httpClient, e.url+"/api/embed", "",
+		map[string]interface{}{"model": e.model, "input": texts}, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Embeddings))
+	}
+	return result.Embeddings, nil
+}
+
+// postJSON posts body to url and decodes a 200 response into out
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach embeddings API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("embeddings API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode embeddings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// localDimensions is the length of local vectors
+const localDimensions = 256
+
+// Local embeds text by hashing its words and their character trigrams into a
+// fixed number of buckets. It catches shared and slightly varied wording
+// ("email the client" and "emailing clients") but not synonyms, which need a
+// real model.
+type Local struct{}
+
+// NewLocal returns the local embedder
+func NewLocal() Local {
+	return Local{}
+}
+
+// Model names the local scheme so its vectors are never mixed with a model's
+func (Local) Model() string {
+	return "local-hash-256"
+}
+
+// Embed returns one unit-length vector per text; empty text gives a zero vector
+func (l Local) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vecs := make([][]float32, len(texts))
+	for i, text := range texts {
+		vecs[i] = l.embed(text)
+	}
+	return vecs, nil
+}
+
+func (Local) embed(text string) []float32 {
+	vec := make([]float32, localDimensions)
+	add := func(feature string, weight float32) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		// The top bit picks a sign so unrelated features cancel out on average
+		if sum&(1<<31) != 0 {
+			weight = -weight
+		}
+		vec[sum%localDimensions] += weight
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		add("w:"+word, 1)
+		padded := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			add("t:"+string(padded[i:i+3]), 0.5)
+		}
+	}
+
+	var norm float64
+	for _, x := range vec {
+		norm += float64(x) * float64(x)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec
+}
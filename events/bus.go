@@ -0,0 +1,123 @@
+// Package events provides an in-process domain event bus. Published events
+// are fanned out to subscribers and kept in a bounded history.
+package events
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TypeStreakFreezeConsumed = "streak.freeze_consumed"
+	TypeQuotaWarning         = "quota.warning"
+	TypeQuotaExceeded        = "quota.exceeded"
+	TypeSLOBurnRateAlert     = "slo.burn_rate_alert"
+	TypeSLORecovered         = "slo.recovered"
+)
+
+// EntityEventType builds the event type for an entity mutation, e.g. "task.created"
+func EntityEventType(entityType, action string) string {
+	switch action {
+	case "create", "update", "delete", "restore", "revoke":
+		action = strings.TrimSuffix(action, "e") + "ed"
+	}
+	return entityType + "." + action
+}
+
+// DefaultHistorySize is the number of events retained by NewBus when size <= 0
+const DefaultHistorySize = 1000
+
+// Event is a domain event
+type Event struct {
+	Seq        int64                  `json:"seq"`
+	Type       string                 `json:"type"`
+	UserID     string                 `json:"user_id,omitempty"`
+	EntityType string                 `json:"entity_type,omitempty"`
+	EntityID   string                 `json:"entity_id,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// Handler receives published events
+type Handler func(Event)
+
+// Bus is a synchronous publish/subscribe bus with a bounded ring-buffer history
+type Bus struct {
+	mu          sync.RWMutex
+	seq         int64
+	history     []Event
+	historySize int
+	handlers    []Handler
+}
+
+// NewBus creates a bus that keeps the last historySize events
+func NewBus(historySize int) *Bus {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &Bus{historySize: historySize}
+}
+
+// Subscribe registers a handler for every subsequently published event.
+// Handlers run on the publisher's goroutine and must not block.
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish assigns the event a sequence number and timestamp, records it and
+// delivers it to subscribers. It is a no-op on a nil bus.
+func (b *Bus) Publish(e Event) Event {
+	if b == nil {
+		return e
+	}
+
+	b.mu.Lock()
+	b.seq++
+	e.Seq = b.seq
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now().UTC()
+	}
+	b.history = append(b.history, e)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+	handlers := append([]Handler(nil), b.handlers...)
+	b.mu.Unlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+	return e
+}
+
+// Since returns up to limit events with a sequence number greater than after,
+// oldest first, that match filter (nil matches everything). oldest is the
+// smallest sequence number still in history; a cursor below oldest-1 means
+// events were dropped from the ring buffer before they could be read.
+func (b *Bus) Since(after int64, limit int, filter func(Event) bool) (page []Event, oldest int64, more bool) {
+	if b == nil {
+		return nil, 0, false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.history) > 0 {
+		oldest = b.history[0].Seq
+	}
+	page = make([]Event, 0)
+	for _, e := range b.history {
+		if e.Seq <= after || (filter != nil && !filter(e)) {
+			continue
+		}
+		if len(page) == limit {
+			return page, oldest, true
+		}
+		page = append(page, e)
+	}
+	return page, oldest, false
+}
@@ -0,0 +1,39 @@
+package events
+
+import "testing"
+
+func TestSincePaginatesInOrder(t *testing.T) {
+	bus := NewBus(3)
+	for i := 0; i < 5; i++ {
+		user := "a"
+		if i%2 == 1 {
+			user = "b"
+		}
+		bus.Publish(Event{Type: "task.created", UserID: user})
+	}
+
+	// History holds seq 3-5; reading from 0 starts at the oldest retained event
+	page, oldest, more := bus.Since(0, 2, nil)
+	if oldest != 3 || len(page) != 2 || page[0].Seq != 3 || page[1].Seq != 4 || !more {
+		t.Fatalf("unexpected first page: oldest=%d more=%v page=%+v", oldest, more, page)
+	}
+
+	page, _, more = bus.Since(page[1].Seq, 2, nil)
+	if len(page) != 1 || page[0].Seq != 5 || more {
+		t.Fatalf("unexpected second page: more=%v page=%+v", more, page)
+	}
+
+	onlyA := func(e Event) bool { return e.UserID == "a" }
+	if page, _, _ := bus.Since(0, 10, onlyA); len(page) != 2 {
+		t.Fatalf("expected 2 events for user a, got %+v", page)
+	}
+}
+
+func TestEntityEventType(t *testing.T) {
+	cases := map[string]string{"create": "task.created", "update": "task.updated", "delete": "task.deleted", "restore": "task.restored", "revoke": "task.revoked"}
+	for action, want := range cases {
+		if got := EntityEventType("task", action); got != want {
+			t.Errorf("EntityEventType(task, %s) = %s, want %s", action, got, want)
+		}
+	}
+}
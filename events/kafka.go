@@ -0,0 +1,87 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KafkaRESTPublisher publishes events to a Kafka topic through a Kafka REST
+// proxy (Confluent REST Proxy v2 API). Records are keyed by user ID so each
+// user's events land on one partition and keep their order.
+type KafkaRESTPublisher struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewKafkaRESTPublisher creates a publisher for topic on the REST proxy at baseURL
+func NewKafkaRESTPublisher(baseURL, topic string) (*KafkaRESTPublisher, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("KAFKA_REST_URL is required for the kafka publisher")
+	}
+	return &KafkaRESTPublisher{
+		endpoint:   strings.TrimSuffix(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type kafkaRecord struct {
+	Key   string   `json:"key,omitempty"`
+	Value Envelope `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Publish produces one record and returns an error unless the proxy reports it written
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []kafkaRecord{{Key: e.UserID, Value: NewEnvelope(e)}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Kafka REST proxy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Kafka REST proxy error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var produced kafkaProduceResponse
+	if err := json.Unmarshal(respBody, &produced); err == nil {
+		for _, offset := range produced.Offsets {
+			if offset.ErrorCode != nil {
+				return fmt.Errorf("Kafka rejected event %d: %s", e.Seq, offset.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// Close is a no-op; the REST publisher holds no connection
+func (p *KafkaRESTPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// SchemaVersion identifies the wire format of published events
+const SchemaVersion = "productivity.event.v1"
+
+// Envelope is the JSON document sent to external message buses
+type Envelope struct {
+	Schema string `json:"schema"`
+	Source string `json:"source"`
+	Event
+}
+
+// NewEnvelope wraps an event in the published wire format
+func NewEnvelope(e Event) Envelope {
+	return Envelope{Schema: SchemaVersion, Source: "productivity-mcp-server", Event: e}
+}
+
+// Publisher delivers events to an external message bus
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+	Close() error
+}
+
+// NewPublisher creates the publisher selected by cfg.Publisher ("nats" or
+// "kafka"). It returns nil when no publisher is configured.
+func NewPublisher(cfg config.Events) (Publisher, error) {
+	switch kind := cfg.Publisher; kind {
+	case "":
+		return nil, nil
+	case "nats":
+		prefix := cfg.NATSSubjectPrefix
+		if prefix == "" {
+			prefix = "productivity.events"
+		}
+		return NewNATSPublisher(cfg.NATSURL, prefix)
+	case "kafka":
+		topic := cfg.KafkaTopic
+		if topic == "" {
+			topic = "productivity-events"
+		}
+		return NewKafkaRESTPublisher(cfg.KafkaRESTURL, topic)
+	default:
+		return nil, fmt.Errorf("unknown EVENT_PUBLISHER %q (expected nats or kafka)", kind)
+	}
+}
+
+// Forward delivers every event published on bus to pub until ctx is cancelled,
+// then closes pub. Events are queued so publishers never block the bus; when
+// the queue is full the event is dropped and logged. Each event is retried
+// with backoff before being given up on.
+func Forward(ctx context.Context, bus *Bus, pub Publisher, logger *utils.Logger, queueSize int) {
+	if queueSize <= 0 {
+		queueSize = DefaultHistorySize
+	}
+	queue := make(chan Event, queueSize)
+	bus.Subscribe(func(e Event) {
+		select {
+		case queue <- e:
+		default:
+			logger.Warn("Event publisher queue full, dropping event", map[string]interface{}{"seq": e.Seq, "type": e.Type})
+		}
+	})
+
+	defer pub.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-queue:
+			err := utils.Retry(ctx, utils.DefaultRetryConfig(), func() error {
+				return pub.Publish(ctx, e)
+			})
+			if err != nil {
+				logger.Error("Failed to publish event", err, map[string]interface{}{"seq": e.Seq, "type": e.Type})
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestNATSPublisherSendsEnvelope(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {}\r\n"))
+		r := bufio.NewReader(conn)
+		r.ReadString('\n') // CONNECT
+		pub, _ := r.ReadString('\n')
+		payload, _ := r.ReadString('\n')
+		r.ReadString('\n') // PING
+		conn.Write([]byte("PONG\r\n"))
+		received <- pub + payload
+	}()
+
+	p, err := NewNATSPublisher("nats://"+ln.Addr().String(), "productivity.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if err := p.Publish(context.Background(), Event{Seq: 7, Type: "task.created", UserID: "u1"}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	msg := <-received
+	if !strings.HasPrefix(msg, "PUB productivity.events.task.created ") {
+		t.Fatalf("unexpected PUB line: %q", msg)
+	}
+	var env Envelope
+	if err := json.Unmarshal([]byte(msg[strings.Index(msg, "\n")+1:]), &env); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if env.Schema != SchemaVersion || env.Seq != 7 || env.UserID != "u1" {
+		t.Fatalf("unexpected envelope: %+v", env)
+	}
+}
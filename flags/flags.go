@@ -0,0 +1,176 @@
+// Package flags decides which users get experimental features, by rules that
+// turn a flag on for everyone, no one, a stable percentage of users or a list
+// of users.
+package flags
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Flags the server checks
+const (
+	SemanticSearch = "semantic_search"
+	DailyPlanning  = "daily_planning"
+)
+
+// Flag describes a flag the server checks
+type Flag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Default is whether users get the flag when no rule names it
+	Default bool `json:"default"`
+}
+
+// Known are the flags the server checks
+var Known = []Flag{
+	{SemanticSearch, "Semantic task search, related tasks and duplicate detection", true},
+	{DailyPlanning, "The day's agenda and capacity planning", true},
+}
+
+// namePattern is what a flag name may look like
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ValidName reports whether name can name a flag
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Rule decides who gets a flag
+type Rule struct {
+	// Percent of users get the flag, picked by a stable hash of the flag and
+	// the user: 100 is everyone and 0 no one
+	Percent int `json:"percent"`
+	// Users get the flag whatever Percent says
+	Users []string `json:"users,omitempty"`
+}
+
+// Enabled reports whether userID gets flag under r. Anonymous callers only
+// get flags that are on for everyone.
+func (r Rule) Enabled(flag, userID string) bool {
+	if r.Percent >= 100 {
+		return true
+	}
+	if userID == "" {
+		return false
+	}
+	for _, user := range r.Users {
+		if user == userID {
+			return true
+		}
+	}
+	return r.Percent > 0 && Bucket(flag, userID) < r.Percent
+}
+
+// Bucket places userID in one of 100 buckets for flag. The hash includes the
+// flag, so each rollout starts with different users.
+func Bucket(flag, userID string) int {
+	sum := sha256.Sum256([]byte(flag + "\x00" + userID))
+	return int(binary.BigEndian.Uint32(sum[:4]) % 100)
+}
+
+// Rules are the rules of the flags that have one
+type Rules map[string]Rule
+
+// Enabled reports whether userID gets flag: by its rule if it has one,
+// otherwise by its default
+func (r Rules) Enabled(flag, userID string) bool {
+	if rule, ok := r[flag]; ok {
+		return rule.Enabled(flag, userID)
+	}
+	return defaultPercent(flag) == 100
+}
+
+// Evaluate returns whether userID gets each known flag and each flag with a
+// rule
+func (r Rules) Evaluate(userID string) map[string]bool {
+	out := make(map[string]bool, len(Known)+len(r))
+	for _, flag := range Known {
+		out[flag.Name] = r.Enabled(flag.Name, userID)
+	}
+	for name := range r {
+		out[name] = r.Enabled(name, userID)
+	}
+	return out
+}
+
+// With returns a copy of r with rule replacing the rule of flag
+func (r Rules) With(flag string, rule Rule) Rules {
+	out := make(Rules, len(r)+1)
+	for name, existing := range r {
+		out[name] = existing
+	}
+	out[flag] = rule
+	return out
+}
+
+func defaultPercent(flag string) int {
+	for _, known := range Known {
+		if known.Name == flag && known.Default {
+			return 100
+		}
+	}
+	return 0
+}
+
+// Parse reads rules written as a comma-separated list of name=on, name=off
+// or name=N% entries, and users as a comma-separated list of name:user_id
+// pairs. Listing users for a flag without a rule keeps its default for
+// everyone else.
+func Parse(rules, users string) (Rules, error) {
+	out := Rules{}
+	for _, entry := range splitList(rules) {
+		name, value, ok := strings.Cut(entry, "=")
+		name, value = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(value))
+		if !ok || !ValidName(name) {
+			return nil, fmt.Errorf("%q is not name=on, name=off or name=N%%", entry)
+		}
+		percent, err := parsePercent(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[name] = Rule{Percent: percent}
+	}
+	for _, pair := range splitList(users) {
+		name, user, ok := strings.Cut(pair, ":")
+		name, user = strings.TrimSpace(name), strings.TrimSpace(user)
+		if !ok || !ValidName(name) || user == "" {
+			return nil, fmt.Errorf("%q is not a name:user_id pair", pair)
+		}
+		rule, ok := out[name]
+		if !ok {
+			rule.Percent = defaultPercent(name)
+		}
+		rule.Users = append(rule.Users, user)
+		out[name] = rule
+	}
+	return out, nil
+}
+
+func parsePercent(value string) (int, error) {
+	switch value {
+	case "on", "true":
+		return 100, nil
+	case "off", "false":
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || !strings.HasSuffix(value, "%") || n < 0 || n > 100 {
+		return 0, fmt.Errorf("%q is not on, off or a percentage from 0%% to 100%%", value)
+	}
+	return n, nil
+}
+
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
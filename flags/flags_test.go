@@ -0,0 +1,83 @@
+package flags
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	rules, err := Parse("beta_ui=25%, daily_planning=off, semantic_search=on", "daily_planning:u1, new_thing:u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		flag string
+		want Rule
+	}{
+		{"beta_ui", Rule{Percent: 25}},
+		{"daily_planning", Rule{Percent: 0, Users: []string{"u1"}}},
+		{"semantic_search", Rule{Percent: 100}},
+		{"new_thing", Rule{Percent: 0, Users: []string{"u2"}}},
+	}
+	for _, tt := range tests {
+		if got := rules[tt.flag]; fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.flag, got, tt.want)
+		}
+	}
+
+	// Users listed for a flag on by default keep it on for everyone else
+	rules, err = Parse("", "semantic_search:u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules[SemanticSearch].Percent != 100 {
+		t.Errorf("semantic_search percent = %d, want 100", rules[SemanticSearch].Percent)
+	}
+
+	for _, bad := range [][2]string{{"beta", ""}, {"beta=120%", ""}, {"beta=25", ""}, {"Beta=on", ""}, {"", "beta"}, {"", "beta:"}} {
+		if _, err := Parse(bad[0], bad[1]); err == nil {
+			t.Errorf("Parse(%q, %q) succeeded", bad[0], bad[1])
+		}
+	}
+}
+
+func TestRulesEnabled(t *testing.T) {
+	rules := Rules{
+		"beta":         {Percent: 30},
+		DailyPlanning:  {Percent: 0, Users: []string{"u1"}},
+		"for_everyone": {Percent: 100},
+	}
+
+	if !rules.Enabled(SemanticSearch, "u2") {
+		t.Error("semantic_search without a rule is off, want its default")
+	}
+	if rules.Enabled("unknown", "u2") {
+		t.Error("an unknown flag without a rule is on")
+	}
+	if !rules.Enabled(DailyPlanning, "u1") || rules.Enabled(DailyPlanning, "u2") {
+		t.Error("daily_planning should be on for u1 only")
+	}
+	if !rules.Enabled("for_everyone", "") || rules.Enabled("beta", "") {
+		t.Error("anonymous callers should only get flags on for everyone")
+	}
+
+	on := 0
+	for i := 0; i < 1000; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		enabled := rules.Enabled("beta", user)
+		if enabled != rules.Enabled("beta", user) {
+			t.Fatalf("%s got different answers", user)
+		}
+		if enabled {
+			on++
+		}
+	}
+	if on < 250 || on > 350 {
+		t.Errorf("30%% rollout reached %d of 1000 users", on)
+	}
+
+	flags := rules.Evaluate("u1")
+	if len(flags) != 4 || !flags[SemanticSearch] || !flags[DailyPlanning] || !flags["for_everyone"] {
+		t.Errorf("Evaluate(u1) = %v", flags)
+	}
+}
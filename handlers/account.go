@@ -0,0 +1,170 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// accountExportVersion is bumped when the layout of an account export changes
+const accountExportVersion = 1
+
+// AccountHandler exports and deletes everything stored for a user
+type AccountHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(supabaseURL, supabaseKey string) *AccountHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &AccountHandler{
+		supabaseClient: client,
+	}
+}
+
+// accountUserID returns the signed-in user. Account routes never act on a
+// user_id named in the request, which would let anyone export or erase any
+// account.
+func accountUserID(c *gin.Context) (string, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.Error(utils.ErrUnauthorized("account routes require a signed-in user"))
+		return "", false
+	}
+	return userID, true
+}
+
+// ExportAccount returns everything stored for the user as one JSON document,
+// keyed by table. Hashes, tokens and sealed credentials are left out.
+// GET /api/account/export
+func (h *AccountHandler) ExportAccount(c *gin.Context) {
+	userID, ok := accountUserID(c)
+	if !ok {
+		return
+	}
+	client := h.supabaseClient.WithContext(c.Request.Context())
+
+	now := time.Now().UTC()
+	tables := make(map[string][]map[string]interface{}, len(db.AccountTables))
+	for _, table := range db.AccountTables {
+		rows, err := client.ExportAccountTable(table, userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		tables[table.Name] = rows
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="account-export-`+now.Format("2006-01-02")+`.json"`)
+	c.JSON(http.StatusOK, gin.H{
+		"version":     accountExportVersion,
+		"user_id":     userID,
+		"exported_at": now.Format(time.RFC3339),
+		"tables":      tables,
+	})
+}
+
+// DeleteAccountRequest confirms an account deletion
+type DeleteAccountRequest struct {
+	ConfirmationToken string `json:"confirmation_token"`
+}
+
+// DeleteAccount erases everything stored for the user. Without a
+// confirmation_token it deletes nothing and returns how many rows would go
+// along with a token; repeating the request with that token within ten
+// minutes deletes them and signs out every session. Deleting an account with
+// an API key is refused.
+// DELETE /api/account?confirmation_token=...
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	if c.GetString("auth_method") == "api_key" {
+		c.Error(utils.ErrForbidden("API keys cannot delete the account"))
+		return
+	}
+	userID, ok := accountUserID(c)
+	if !ok {
+		return
+	}
+
+	req := DeleteAccountRequest{ConfirmationToken: c.Query("confirmation_token")}
+	if req.ConfirmationToken == "" && c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+	client := h.supabaseClient.WithContext(c.Request.Context())
+	key := confirmationKey(userID, "delete_account", nil)
+
+	if req.ConfirmationToken == "" {
+		counts := make(map[string]int, len(db.AccountTables))
+		for _, table := range db.AccountTables {
+			n, err := client.CountAccountRows(table, userID)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			counts[table.Name] = n
+		}
+		token, err := issueConfirmation(key)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"deleted":            false,
+			"rows":               counts,
+			"confirmation_token": token,
+			"expires_at":         time.Now().Add(confirmationTTL).UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if !redeemConfirmation(req.ConfirmationToken, key) {
+		c.Error(utils.ErrBadRequest("confirmation_token is invalid or expired; send DELETE /api/account without one for a new token"))
+		return
+	}
+
+	if !h.revokeSessions(c, userID) {
+		return
+	}
+	// A failure part way leaves the remaining rows; deleting again finishes the job
+	counts := make(map[string]int, len(db.AccountTables))
+	for _, table := range db.AccountTables {
+		n, err := client.DeleteAccountRows(table, userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		counts[table.Name] = n
+	}
+	utils.LoggerFromContext(c.Request.Context()).Info("Account deleted", map[string]interface{}{"user_id": userID})
+	c.JSON(http.StatusOK, gin.H{"deleted": true, "rows": counts})
+}
+
+// revokeSessions denylists the user's unexpired access tokens before their
+// sessions are deleted. The denylist entries do not name the user.
+func (h *AccountHandler) revokeSessions(c *gin.Context, userID string) bool {
+	if tokenRevocations == nil {
+		return true
+	}
+	sessions, err := h.supabaseClient.ListSessions(map[string]string{"user_id": userID}, true, 500)
+	if err != nil {
+		c.Error(err)
+		return false
+	}
+	for _, session := range sessions {
+		expiresAt, ok := rowTime(session, "expires_at")
+		if !ok {
+			expiresAt = time.Now().Add(time.Duration(AccessTokenExpiration) * time.Second)
+		}
+		if err := tokenRevocations.Revoke(c.Request.Context(), rowString(session, "jti"), "", "account deleted", expiresAt); err != nil {
+			c.Error(err)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/seed"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// SessionRetention is how long a session is kept after its token expires, so
+// /admin/users can still report when a user was last seen
+const SessionRetention = 30 * 24 * time.Hour
+
+// sessionStore records the access tokens /oauth/token issues; nil disables session tracking
+var sessionStore *db.SupabaseClient
+
+// SetSessionStore records every issued access token in Supabase for the admin API
+func SetSessionStore(supabaseURL, supabaseKey string) {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	sessionStore = client
+}
+
+// recordSession stores a newly signed token's claims. A failure is logged
+// rather than failing the token request; the token works either way.
+func recordSession(claims jwt.MapClaims) {
+	if sessionStore == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"jti":       claims["jti"],
+		"user_id":   claims["sub"],
+		"client_id": claims["client_id"],
+		"scope":     claims["scope"],
+	}
+	for _, key := range []string{"iat", "exp"} {
+		if unix, ok := claims[key].(int64); ok {
+			column := map[string]string{"iat": "issued_at", "exp": "expires_at"}[key]
+			data[column] = time.Unix(unix, 0).UTC().Format(time.RFC3339)
+		}
+	}
+	if err := sessionStore.RecordSession(data); err != nil {
+		utils.DefaultLogger().Error("Failed to record session", err, map[string]interface{}{"jti": claims["jti"]})
+	}
+}
+
+// AdminHandler serves the /admin routes for operating a hosted deployment
+type AdminHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(supabaseURL, supabaseKey string) *AdminHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &AdminHandler{
+		supabaseClient: client,
+	}
+}
+
+// adminLimit reads ?limit=, defaulting to 100 and capped at 500
+func adminLimit(c *gin.Context) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return 100, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > 500 {
+		c.Error(utils.ErrBadRequest("limit must be between 1 and 500"))
+		return 0, false
+	}
+	return n, true
+}
+
+// ListUsers lists every user the server knows about with their task, goal,
+// key and session counts, most recently seen first
+// GET /admin/users?limit=100
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit, ok := adminLimit(c)
+	if !ok {
+		return
+	}
+	users, err := h.supabaseClient.ListAdminUsers(limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"users": users, "count": len(users)})
+}
+
+// ListClients lists the built-in and registered OAuth clients without secrets
+// GET /admin/clients
+func (h *AdminHandler) ListClients(c *gin.Context) {
+	ids := make([]string, 0, len(defaultClients))
+	for id := range defaultClients {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	clients := make([]map[string]interface{}, 0, len(defaultClients))
+	for _, id := range ids {
+		snapshot := auditClientSnapshot(defaultClients[id])
+		snapshot["built_in"] = true
+		clients = append(clients, snapshot)
+	}
+
+	registered, err := h.supabaseClient.ListOAuthClients()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for _, row := range registered {
+		row["built_in"] = false
+		clients = append(clients, row)
+	}
+	c.JSON(http.StatusOK, gin.H{"clients": clients, "count": len(clients)})
+}
+
+// GetClient describes one OAuth client and its active sessions
+// GET /admin/clients/:client_id
+func (h *AdminHandler) GetClient(c *gin.Context) {
+	clientID := c.Param("client_id")
+	client := lookupClient(clientID)
+	if client == nil {
+		c.Error(utils.ErrNotFound("client"))
+		return
+	}
+
+	sessions, err := h.supabaseClient.ListSessions(map[string]string{"client_id": clientID}, true, 500)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	resp := auditClientSnapshot(client)
+	resp["grant_types"] = client.GrantTypes
+	resp["scope"] = client.Scope
+	resp["built_in"] = defaultClients[clientID] != nil
+	resp["active_sessions"] = sessions
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListSessions lists issued access tokens, newest first
+// GET /admin/sessions?user_id=xxx&client_id=xxx&active=true&limit=100
+func (h *AdminHandler) ListSessions(c *gin.Context) {
+	limit, ok := adminLimit(c)
+	if !ok {
+		return
+	}
+	sessions, err := h.supabaseClient.ListSessions(map[string]string{
+		"user_id":   c.Query("user_id"),
+		"client_id": c.Query("client_id"),
+	}, c.Query("active") == "true", limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "count": len(sessions)})
+}
+
+// RevokeSession force-revokes one access token
+// POST /admin/sessions/:jti/revoke
+func (h *AdminHandler) RevokeSession(c *gin.Context) {
+	session, err := h.supabaseClient.GetSession(c.Param("jti"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if session == nil {
+		c.Error(utils.ErrNotFound("session"))
+		return
+	}
+	if !h.revoke(c, session) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jti": rowString(session, "jti"), "revoked": true})
+}
+
+// RevokeUserSessions force-revokes every active access token of a user
+// POST /admin/users/:user_id/revoke
+func (h *AdminHandler) RevokeUserSessions(c *gin.Context) {
+	userID := c.Param("user_id")
+	sessions, err := h.supabaseClient.ListSessions(map[string]string{"user_id": userID}, true, 500)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for _, session := range sessions {
+		if !h.revoke(c, session) {
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "revoked": len(sessions)})
+}
+
+// revoke denylists a session's token and audits it under the session's user
+func (h *AdminHandler) revoke(c *gin.Context, session map[string]interface{}) bool {
+	if tokenRevocations == nil {
+		c.Error(utils.ErrInternal("token revocation is not configured"))
+		return false
+	}
+	jti := rowString(session, "jti")
+	expiresAt, ok := rowTime(session, "expires_at")
+	if !ok {
+		expiresAt = time.Now().Add(time.Duration(AccessTokenExpiration) * time.Second)
+	}
+	if err := tokenRevocations.Revoke(c.Request.Context(), jti, rowString(session, "user_id"), "admin", expiresAt); err != nil {
+		c.Error(err)
+		return false
+	}
+
+	after := make(map[string]interface{}, len(session)+1)
+	for k, v := range session {
+		after[k] = v
+	}
+	after["revoked_at"] = time.Now().UTC().Format(time.RFC3339)
+	recordAudit(c, AuditEntitySession, jti, AuditActionRevoke, session, after)
+	return true
+}
+
+// Metrics reports usage totals: users, tasks, goals, clients, keys and sessions
+// GET /admin/metrics
+func (h *AdminHandler) Metrics(c *gin.Context) {
+	usage, err := h.supabaseClient.GetUsage()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"usage":          usage,
+		"started_at":     startedAt.UTC().Format(time.RFC3339),
+		"uptime_seconds": int64(time.Since(startedAt).Seconds()),
+	})
+}
+
+// Seed replaces the demo users' data with freshly generated demo data. It is
+// only routed outside release mode.
+// POST /admin/seed {"users": 3, "days": 30, "seed": 0}
+func (h *AdminHandler) Seed(c *gin.Context) {
+	var opts seed.Options
+	if c.Request.ContentLength > 0 && !bindJSON(c, &opts) {
+		return
+	}
+	data, err := seed.Generate(opts, time.Now())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	result, err := seed.Write(h.supabaseClient.WithContext(c.Request.Context()), data)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
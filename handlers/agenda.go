@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// Agenda output formats
+const (
+	AgendaFormatANSI     = "ansi"
+	AgendaFormatText     = "text"
+	AgendaFormatMarkdown = "md"
+)
+
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+
+	maxAgendaTitle = 48
+)
+
+// AgendaHandler renders the day's plan server-side for terminals and Markdown viewers
+type AgendaHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewAgendaHandler creates a new agenda handler
+func NewAgendaHandler(supabaseURL, supabaseKey string) *AgendaHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &AgendaHandler{
+		supabaseClient: client,
+	}
+}
+
+// agendaItem is one row of the rendered agenda
+type agendaItem struct {
+	Due       time.Time
+	Title     string
+	Priority  int
+	Category  string
+	Estimate  int
+	Completed bool
+	Overdue   bool
+}
+
+// agendaSection groups agenda rows under a heading
+type agendaSection struct {
+	Title string
+	Items []agendaItem
+}
+
+// Today renders today's agenda
+// GET /api/agenda/today?format=ansi|text|md
+func (h *AgendaHandler) Today(c *gin.Context) {
+	format := c.DefaultQuery("format", AgendaFormatANSI)
+	if format != AgendaFormatANSI && format != AgendaFormatText && format != AgendaFormatMarkdown {
+		c.Error(utils.ErrBadRequest("format must be ansi, text or md"))
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	tasks, err := h.supabaseClient.GetUserTasks(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// The day and its overdue tasks follow the user's time zone
+	now, prefs, err := userNow(h.supabaseClient.WithContext(c.Request.Context()), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	sections := buildAgenda(tasks, now)
+	heading := agendaHeading(now, prefs)
+
+	switch format {
+	case AgendaFormatMarkdown:
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(renderAgendaMarkdown(sections, heading)))
+	default:
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(renderAgendaText(sections, heading, format == AgendaFormatANSI)))
+	}
+}
+
+// agendaHeading titles the agenda with the day and the user's working hours
+func agendaHeading(now time.Time, prefs models.Preferences) string {
+	return fmt.Sprintf("Agenda for %s (working hours %s–%s)", now.Format("Monday, Jan 2"), prefs.WorkStart, prefs.WorkEnd)
+}
+
+// buildAgenda splits tasks into overdue and due-today sections, sorted by due time
+func buildAgenda(tasks []map[string]interface{}, now time.Time) []agendaSection {
+	today := startOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	var overdue, dueToday []agendaItem
+	for _, task := range tasks {
+		due, ok := rowTime(task, "due_date")
+		if !ok {
+			continue
+		}
+		due = due.In(now.Location())
+		item := agendaItem{
+			Due:       due,
+			Title:     rowString(task, "title"),
+			Priority:  rowInt(task, "priority"),
+			Category:  rowString(task, "category"),
+			Estimate:  rowInt(task, "estimated_duration"),
+			Completed: rowBool(task, "completed"),
+		}
+
+		switch {
+		case due.Before(today) && !item.Completed:
+			item.Overdue = true
+			overdue = append(overdue, item)
+		case !due.Before(today) && due.Before(tomorrow):
+			dueToday = append(dueToday, item)
+		}
+	}
+
+	byDue := func(items []agendaItem) {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Due.Before(items[j].Due) })
+	}
+	byDue(overdue)
+	byDue(dueToday)
+
+	var sections []agendaSection
+	if len(overdue) > 0 {
+		sections = append(sections, agendaSection{Title: "Overdue", Items: overdue})
+	}
+	sections = append(sections, agendaSection{Title: "Today", Items: dueToday})
+	return sections
+}
+
+func agendaColumns(item agendaItem) []string {
+	when := item.Due.Format("15:04")
+	if item.Overdue {
+		when = item.Due.Format("Jan 02")
+	}
+	status := " "
+	if item.Completed {
+		status = "x"
+	}
+	estimate := ""
+	if item.Estimate > 0 {
+		estimate = fmt.Sprintf("%dm", item.Estimate)
+	}
+	return []string{status, when, fmt.Sprintf("P%d", item.Priority), truncateRunes(item.Title, maxAgendaTitle), item.Category, estimate}
+}
+
+var agendaHeaders = []string{" ", "Due", "Pri", "Task", "Category", "Est"}
+
+// renderAgendaText renders aligned plain-text columns, optionally with ANSI colors
+func renderAgendaText(sections []agendaSection, heading string, color bool) string {
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	var b strings.Builder
+	b.WriteString(paint(ansiBold, heading) + "\n")
+
+	for _, section := range sections {
+		b.WriteString("\n" + paint(ansiBold, section.Title) + "\n")
+		if len(section.Items) == 0 {
+			b.WriteString(paint(ansiDim, "  nothing scheduled") + "\n")
+			continue
+		}
+
+		rows := make([][]string, len(section.Items))
+		widths := make([]int, len(agendaHeaders))
+		for i, item := range section.Items {
+			rows[i] = agendaColumns(item)
+			for col, cell := range rows[i] {
+				if n := utf8.RuneCountInString(cell); n > widths[col] {
+					widths[col] = n
+				}
+			}
+		}
+
+		for i, item := range section.Items {
+			cells := make([]string, len(rows[i]))
+			for col, cell := range rows[i] {
+				cells[col] = padRight(cell, widths[col])
+			}
+			cells[0] = "[" + rows[i][0] + "]"
+			line := "  " + strings.TrimRight(strings.Join(cells, "  "), " ")
+
+			switch {
+			case item.Completed:
+				line = paint(ansiDim, line)
+			case item.Overdue:
+				line = paint(ansiRed, line)
+			case item.Priority >= 4:
+				line = paint(ansiYellow, line)
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// renderAgendaMarkdown renders each section as a Markdown table
+func renderAgendaMarkdown(sections []agendaSection, heading string) string {
+	var b strings.Builder
+	b.WriteString("# " + heading + "\n")
+
+	for _, section := range sections {
+		b.WriteString("\n## " + section.Title + "\n\n")
+		if len(section.Items) == 0 {
+			b.WriteString("_Nothing scheduled._\n")
+			continue
+		}
+
+		b.WriteString("| Done | Due | Pri | Task | Category | Est |\n")
+		b.WriteString("|------|-----|-----|------|----------|-----|\n")
+		for _, item := range section.Items {
+			cols := agendaColumns(item)
+			done := " "
+			if item.Completed {
+				done = "✓"
+			}
+			cols[0] = done
+			for i := range cols {
+				cols[i] = strings.ReplaceAll(cols[i], "|", "\\|")
+			}
+			b.WriteString("| " + strings.Join(cols, " | ") + " |\n")
+		}
+	}
+
+	return b.String()
+}
+
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/productivity/mcp-server/utils"
+)
+
+// FallbackCount is how often one AI operation answered without a model
+// since the server started, and when and why it last did
+type FallbackCount struct {
+	Count     int64     `json:"count"`
+	LastAt    time.Time `json:"last_at"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// fallbackCounts counts fallbacks per operation
+type fallbackCounts struct {
+	mu     sync.Mutex
+	counts map[string]*FallbackCount
+}
+
+// aiFallbacks counts the canned answers AI operations gave in place of a
+// model's, so an outage that clients only see as blander answers shows up
+// in AdminStats
+var aiFallbacks = &fallbackCounts{counts: make(map[string]*FallbackCount)}
+
+// record counts a fallback of operation, named as its prompt is, after err
+func (f *fallbackCounts) record(ctx context.Context, operation string, err error) {
+	if err != nil {
+		utils.LoggerFromContext(ctx).Warn("AI operation fell back without a model", map[string]interface{}{
+			"operation": operation,
+			"error":     err.Error(),
+		})
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := f.counts[operation]
+	if count == nil {
+		count = &FallbackCount{}
+		f.counts[operation] = count
+	}
+	count.Count++
+	count.LastAt = time.Now().UTC()
+	if err != nil {
+		count.LastError = err.Error()
+	}
+}
+
+// snapshot returns a copy of the counts by operation
+func (f *fallbackCounts) snapshot() map[string]FallbackCount {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	counts := make(map[string]FallbackCount, len(f.counts))
+	for operation, count := range f.counts {
+		counts[operation] = *count
+	}
+	return counts
+}
@@ -0,0 +1,1135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/productivity/mcp-server/claude"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/dates"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/importers"
+	"github.com/productivity/mcp-server/language"
+	"github.com/productivity/mcp-server/llm"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/prompts"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// AIService implements the Claude-backed operations shared by the REST
+// handlers and the MCP tools. Parsing and subtask generation fall back to a
+// best-effort result when Claude is unavailable, so they never fail.
+type AIService struct {
+	supabaseURL string
+	supabaseKey string
+	claude      *claude.Client
+	// failover, when set, routes requests to a fallback model while Claude
+	// keeps failing
+	failover *llm.Failover
+	// routes send operations to other models than CLAUDE_MODEL; local
+	// answers those routed to Ollama
+	routes llm.Routes
+	local  *llm.Ollama
+	// extraModels may be asked for on top of the routed ones, and
+	// maxTokensCap bounds the max_tokens a request may ask for
+	extraModels  []string
+	maxTokensCap int
+}
+
+// defaultMaxTokensCap bounds a request's max_tokens until SetRequestLimits is called
+const defaultMaxTokensCap = 8192
+
+// NewAIService creates an AI service; Supabase is only used to analyze productivity
+func NewAIService(supabaseURL, supabaseKey string, cfg config.Claude) *AIService {
+	return &AIService{
+		supabaseURL:  supabaseURL,
+		supabaseKey:  supabaseKey,
+		claude:       claude.New(cfg),
+		maxTokensCap: defaultMaxTokensCap,
+	}
+}
+
+// RateLimit returns Claude's rate limits as of its latest answer, or nil
+// before the first call
+func (s *AIService) RateLimit() *claude.RateLimit {
+	return s.claude.RateLimit()
+}
+
+// SetFailover sends requests to fallback while Claude keeps answering with
+// rate limits or server errors, as cfg describes, and back to Claude once it
+// recovers
+func (s *AIService) SetFailover(fallback llm.Provider, cfg config.Failover) {
+	s.failover = llm.NewFailover(llm.Claude(s.claude), fallback, cfg.Threshold, cfg.Cooldown.Duration)
+}
+
+// RoutableOperations are the operations routes can send to other models,
+// named like their prompts
+var RoutableOperations = []string{
+	prompts.ParseTask, prompts.RefineTask, prompts.ParseFile, prompts.GenerateSubtasks,
+	prompts.SuggestMilestones, prompts.AnalyzeProductivity, prompts.ReviewMatrix,
+	prompts.SummarizeFile, prompts.MapColumns,
+}
+
+// SetRoutes sends each operation in routes to its model instead of
+// CLAUDE_MODEL; local answers the operations routed to Ollama and may be nil
+// when none are
+func (s *AIService) SetRoutes(routes llm.Routes, local *llm.Ollama) {
+	s.routes = routes
+	s.local = local
+}
+
+// target picks where an operation's request goes: the request's override,
+// else the operation's route, else Claude's configured model
+func (s *AIService) target(ctx context.Context, operation string) llm.Target {
+	if t, ok := llm.OverrideFrom(ctx); ok {
+		return t
+	}
+	if t, ok := s.routes[operation]; ok {
+		return t
+	}
+	return llm.Target{Provider: llm.ProviderClaude}
+}
+
+// SetRequestLimits lets requests ask for extraModels as well as the models
+// AllowedModels always lists, and for at most maxTokensCap tokens
+func (s *AIService) SetRequestLimits(extraModels []string, maxTokensCap int) {
+	s.extraModels = extraModels
+	s.maxTokensCap = maxTokensCap
+}
+
+// AllowedModels lists the models a request may ask for instead of the routed
+// ones: CLAUDE_MODEL, OLLAMA_MODEL when Ollama is set up, every model the
+// routes name and those SetRequestLimits allows
+func (s *AIService) AllowedModels() []string {
+	models := []string{s.claude.Model()}
+	if s.local != nil {
+		models = append(models, llm.Target{Provider: llm.ProviderOllama, Model: s.local.Model()}.String())
+	}
+	for _, model := range append(s.routes.Models(), s.extraModels...) {
+		if !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// allowedTarget reads a model a request asks for, which must be one of
+// AllowedModels
+func (s *AIService) allowedTarget(spec string) (llm.Target, error) {
+	target, err := llm.ParseTarget(spec)
+	if err != nil {
+		return llm.Target{}, err
+	}
+	allowed := s.AllowedModels()
+	if !slices.Contains(allowed, target.String()) {
+		return llm.Target{}, fmt.Errorf("model %q is not allowed; use one of %s", spec, strings.Join(allowed, ", "))
+	}
+	return target, nil
+}
+
+// withLLMParams applies a request's model, max_tokens and temperature to
+// the AI calls made with the returned context, adding to v what breaks the
+// allow-list or caps
+func (s *AIService) withLLMParams(ctx context.Context, p models.LLMParams, v *validation.Validator) context.Context {
+	if p.Model != "" {
+		target, err := s.allowedTarget(p.Model)
+		if err != nil {
+			v.Add("model", validation.CodeInvalidValue, err.Error())
+		} else {
+			ctx = llm.WithOverride(ctx, target)
+		}
+	}
+	v.Check(p.MaxTokens >= 0 && p.MaxTokens <= s.maxTokensCap, "max_tokens", validation.CodeOutOfRange,
+		fmt.Sprintf("max_tokens must be between 1 and %d", s.maxTokensCap))
+	if p.Temperature != nil {
+		v.Check(*p.Temperature >= 0 && *p.Temperature <= 1, "temperature", validation.CodeOutOfRange, "temperature must be between 0 and 1")
+	}
+	if p.MaxTokens > 0 || p.Temperature != nil {
+		ctx = llm.WithParams(ctx, llm.Params{MaxTokens: p.MaxTokens, Temperature: p.Temperature})
+	}
+	return ctx
+}
+
+// Progress reports how far a long-running operation has got; total is 0 when unknown
+type Progress func(progress, total float64, message string)
+
+// report calls p if it is set
+func (p Progress) report(progress, total float64, message string) {
+	if p != nil {
+		p(progress, total, message)
+	}
+}
+
+// callModel sends messages for operation to the model it is routed to and
+// returns the text of the answer. Claude's requests are retried while it is
+// rate limited or overloaded and, with failover set, answered by the
+// fallback model while Claude is down. The provider that answered is added
+// to the ctx's providersUsed.
+func (s *AIService) callModel(ctx context.Context, operation string, messages []map[string]interface{}) (string, error) {
+	target := s.target(ctx, operation)
+	req := llm.Request{Messages: messages, Model: target.Model, Params: llm.ParamsFrom(ctx)}
+	if target.Provider == llm.ProviderOllama {
+		if s.local == nil {
+			return "", fmt.Errorf("%s is routed to Ollama, which is not configured", operation)
+		}
+		text, err := s.local.Complete(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		providersUsedFrom(ctx).add(llm.ProviderOllama)
+		return text, nil
+	}
+	if s.failover != nil {
+		text, provider, err := s.failover.Complete(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		providersUsedFrom(ctx).add(provider)
+		return text, nil
+	}
+	resp, err := s.claude.CompleteWith(ctx, messages, claude.Params{
+		Model:       target.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	providersUsedFrom(ctx).add(llm.ProviderClaude)
+	return resp.Text, nil
+}
+
+// providersUsed collects the providers that answered during one operation,
+// reported as its provider_used
+type providersUsed struct {
+	mu    sync.Mutex
+	names []string
+}
+
+type providersUsedKey struct{}
+
+// withProvidersUsed starts collecting the providers that answer calls made
+// with the returned context
+func withProvidersUsed(ctx context.Context) (context.Context, *providersUsed) {
+	used := &providersUsed{}
+	return context.WithValue(ctx, providersUsedKey{}, used), used
+}
+
+// providersUsedFrom returns the ctx's collector, or nil when nothing collects
+func providersUsedFrom(ctx context.Context) *providersUsed {
+	used, _ := ctx.Value(providersUsedKey{}).(*providersUsed)
+	return used
+}
+
+func (p *providersUsed) add(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, n := range p.names {
+		if n == name {
+			return
+		}
+	}
+	p.names = append(p.names, name)
+}
+
+// String lists the providers in the order they first answered, empty when
+// none did
+func (p *providersUsed) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return strings.Join(p.names, ",")
+}
+
+// providerLabel names the providers behind an answer in its explanation
+func providerLabel(used string) string {
+	switch used {
+	case "", llm.ProviderClaude:
+		return "Claude AI"
+	case llm.ProviderOllama:
+		return "Ollama"
+	}
+	return used
+}
+
+// jsonCorrections is how many times a reply that is not the JSON its prompt
+// asked for is sent back to Claude to correct
+const jsonCorrections = 1
+
+// completeJSON renders the named prompt, sends it to Claude and decodes the
+// JSON in the answer into out, as converseJSON does
+func (s *AIService) completeJSON(ctx context.Context, name string, data interface{}, schema *validation.Schema, out interface{}) error {
+	prompt, err := renderPrompt(name, data)
+	if err != nil {
+		return err
+	}
+	_, err = s.converseJSON(ctx, name, []map[string]interface{}{{"role": "user", "content": prompt}}, schema, out)
+	return err
+}
+
+// converseJSON sends messages, the last a user message rendered from the
+// named prompt, to Claude and decodes the JSON in the answer into out,
+// repairing common mistakes and checking it against schema. An answer that
+// still cannot be used is sent back with what was wrong, up to
+// jsonCorrections times; after that the *claude.OutputError is returned for
+// the caller to fall back on. It returns the answer that was decoded.
+func (s *AIService) converseJSON(ctx context.Context, name string, messages []map[string]interface{}, schema *validation.Schema, out interface{}) (string, error) {
+	for attempt := 0; ; attempt++ {
+		text, err := s.callModel(ctx, name, messages)
+		if err != nil {
+			return "", err
+		}
+		err = claude.DecodeJSON(text, schema, out)
+		if err == nil || attempt == jsonCorrections {
+			return text, err
+		}
+		utils.LoggerFromContext(ctx).Warn("Unusable model reply, asking for a correction", map[string]interface{}{
+			"prompt": name,
+			"error":  err.Error(),
+		})
+
+		correction, renderErr := renderPrompt(prompts.CorrectJSON, prompts.CorrectJSONData{Error: err.Error()})
+		if renderErr != nil {
+			return text, err
+		}
+		// The API rejects empty assistant turns
+		if strings.TrimSpace(text) != "" {
+			messages = append(messages, map[string]interface{}{"role": "assistant", "content": text})
+		}
+		messages = append(messages, map[string]interface{}{"role": "user", "content": correction})
+	}
+}
+
+// parseTaskSchema is the answer the parse_task prompt asks for
+var parseTaskSchema = &validation.Schema{
+	Type:     "object",
+	Required: []string{"title"},
+	Properties: map[string]*validation.Schema{
+		"title":    {Type: "string", MinLength: 1},
+		"priority": {Type: "integer", Minimum: validation.Bound(1), Maximum: validation.Bound(5)},
+	},
+}
+
+// ParseTask parses natural language into a structured task. Dates are
+// resolved by the dates package, following the user's preferences and the
+// request's time zone, as well as by Claude; when it finds one, its answer
+// wins, so due dates stay right when Claude is unavailable or misreads a
+// relative date.
+func (s *AIService) ParseTask(ctx context.Context, req models.ParseTaskRequest) *models.ParseTaskResponse {
+	ctx, used := withProvidersUsed(ctx)
+	inputLanguage := language.Detect(req.Input)
+	// Parsing never fails; without saved preferences dates follow the defaults
+	prefs, _ := userPreferences(ctx, req.UserID)
+	if req.Timezone != "" {
+		prefs.Timezone = req.Timezone
+	}
+	loc := preferencesLocation(prefs)
+	now := time.Now().In(loc)
+	match, hasDate := dates.ParseWith(req.Input, now, dateOptions(prefs))
+	// Like preferences, memory only adds context; parsing goes on without it
+	memory, err := userMemory(ctx, req.UserID)
+	if err != nil {
+		utils.LoggerFromContext(ctx).Error("Failed to load memory", err, map[string]interface{}{"user_id": req.UserID})
+	}
+	defaultCategory := memoryValue(memory, DefaultCategoryKey)
+
+	// fallback keeps the whole input as the title, less any date phrase
+	fallback := func(confidence float64, explanation string) *models.ParseTaskResponse {
+		task := &models.Task{
+			Title:    req.Input,
+			UserID:   req.UserID,
+			Category: defaultCategory,
+			Language: inputLanguage,
+		}
+		if hasDate {
+			if rest := match.Strip(req.Input); rest != "" {
+				task.Title = rest
+			}
+			task.DueDate = match.Time
+		}
+		return &models.ParseTaskResponse{
+			Task:         task,
+			Confidence:   confidence,
+			Explanation:  explanation,
+			ProviderUsed: used.String(),
+			Degraded:     true,
+		}
+	}
+
+	var parsedTask map[string]interface{}
+	err = s.completeJSON(ctx, prompts.ParseTask, prompts.ParseTaskData{
+		LanguageLine: languagePromptLine(inputLanguage),
+		Now:          now.Format(time.RFC3339),
+		Timezone:     loc.String(),
+		Locale:       prefs.Locale,
+		MemoryLines:  memoryPromptLines(memory),
+		Input:        req.Input,
+	}, parseTaskSchema, &parsedTask)
+	var outputErr *claude.OutputError
+	if errors.As(err, &outputErr) {
+		// Claude answered, but not with a usable task even when asked again
+		aiFallbacks.record(ctx, prompts.ParseTask, err)
+		return fallback(0.6, fmt.Sprintf("Parsed with Claude but JSON decode failed: %v", err))
+	}
+	if err != nil {
+		// Fallback to simple parsing if Claude API fails
+		aiFallbacks.record(ctx, prompts.ParseTask, err)
+		return fallback(0.5, fmt.Sprintf("Fallback parsing (Claude API error: %v)", err))
+	}
+
+	// Build task from parsed data
+	task := &models.Task{
+		UserID:   req.UserID,
+		Language: inputLanguage,
+	}
+	if title, ok := parsedTask["title"].(string); ok {
+		task.Title = title
+	} else {
+		task.Title = req.Input
+	}
+	if desc, ok := parsedTask["description"].(string); ok {
+		task.Description = desc
+	}
+	if priority, ok := parsedTask["priority"].(float64); ok {
+		task.Priority = int(priority)
+	} else {
+		task.Priority = 3
+	}
+	if category, ok := parsedTask["category"].(string); ok && category != "" {
+		task.Category = category
+	} else {
+		task.Category = defaultCategory
+	}
+	if dueDateStr, ok := parsedTask["due_date"].(string); ok {
+		if dueDate, err := time.Parse(time.RFC3339, dueDateStr); err == nil {
+			task.DueDate = dueDate
+		}
+	}
+	if hasDate {
+		task.DueDate = match.Time
+	}
+
+	response := models.ParseTaskResponse{
+		Task:         task,
+		Confidence:   0.9,
+		Explanation:  "Successfully parsed task using " + providerLabel(used.String()),
+		ProviderUsed: used.String(),
+	}
+
+	return &response
+}
+
+// parseFileChunkSize is the most new file content sent to Claude in one
+// request; larger files are parsed in parts so each call stays well inside
+// its timeout. Each part after the first also repeats up to
+// parseFileChunkOverlap bytes of whole lines from the end of the one before,
+// so an item that straddles the cut is seen whole by at least one part.
+const (
+	parseFileChunkSize    = 20000
+	parseFileChunkOverlap = 1000
+)
+
+// ParseFile parses a file and extracts task data, reporting progress per part
+// for files longer than parseFileChunkSize. The tasks of all parts are merged,
+// dropping ones found twice, and their summaries combined into one.
+func (s *AIService) ParseFile(ctx context.Context, req models.ParseFileRequest, progress Progress) *models.ParseFileResponse {
+	ctx, used := withProvidersUsed(ctx)
+	fileLanguage := language.Detect(req.FileContent)
+	parts := splitFileContent(req.FileContent, parseFileChunkSize, parseFileChunkOverlap)
+
+	response := models.ParseFileResponse{
+		Tasks:         []models.Task{},
+		ExtractedData: map[string]interface{}{},
+	}
+	var summaries []string
+	var partErr error // the last part that failed
+	parsedParts := 0
+	for i, part := range parts {
+		// Stop spending Claude calls on a request nobody is waiting for
+		if ctx.Err() != nil {
+			break
+		}
+		progress.report(float64(i), float64(len(parts)), fmt.Sprintf("Parsing part %d of %d", i+1, len(parts)))
+
+		partLine := ""
+		if len(parts) > 1 {
+			partLine = fmt.Sprintf("Part: %d of %d\n", i+1, len(parts))
+			if i > 0 {
+				partLine += "The first lines repeat the end of the previous part, so tasks in them may already have been found.\n"
+			}
+		}
+		parsed, err := s.parseFilePart(ctx, req, fileLanguage, partLine, part)
+		summary := "File parsed successfully"
+		if err != nil {
+			summary = err.Error()
+			if ctx.Err() == nil {
+				response.Degraded = true
+				partErr = err
+			}
+		} else {
+			parsedParts++
+			response.Tasks = mergeFileTasks(response.Tasks, fileTasks(parsed, req.UserID))
+			if data, ok := parsed["extracted_data"].(map[string]interface{}); ok {
+				for k, v := range data {
+					response.ExtractedData[k] = v
+				}
+			}
+			if text, ok := parsed["summary"].(string); ok {
+				summary = text
+			}
+		}
+		if len(parts) > 1 {
+			summary = fmt.Sprintf("Part %d: %s", i+1, summary)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	response.Summary = strings.Join(summaries, "\n")
+	if parsedParts > 1 && ctx.Err() == nil {
+		response.Summary = s.summarizeFile(ctx, req.FileName, fileLanguage, response.Summary)
+	}
+	progress.report(float64(len(parts)), float64(len(parts)), "File parsed")
+
+	if response.Degraded {
+		aiFallbacks.record(ctx, prompts.ParseFile, partErr)
+	}
+	response.ProviderUsed = used.String()
+	return &response
+}
+
+// summarizeFileSchema is the answer the summarize_file prompt asks for
+var summarizeFileSchema = &validation.Schema{
+	Type:       "object",
+	Required:   []string{"summary"},
+	Properties: map[string]*validation.Schema{"summary": {Type: "string", MinLength: 1}},
+}
+
+// summarizeFile asks Claude to combine the summaries of a file's parts into
+// one, keeping them as they are if it cannot
+func (s *AIService) summarizeFile(ctx context.Context, fileName, fileLanguage, summaries string) string {
+	var combined struct {
+		Summary string `json:"summary"`
+	}
+	err := s.completeJSON(ctx, prompts.SummarizeFile, prompts.SummarizeFileData{
+		LanguageLine: languagePromptLine(fileLanguage),
+		FileName:     fileName,
+		Summaries:    summaries,
+	}, summarizeFileSchema, &combined)
+	if err != nil {
+		utils.LoggerFromContext(ctx).Warn("Combining file summaries failed, keeping them per part", map[string]interface{}{
+			"file":  fileName,
+			"error": err.Error(),
+		})
+		return summaries
+	}
+	return combined.Summary
+}
+
+// mapColumnsSchema is the answer the map_columns prompt asks for
+var mapColumnsSchema = &validation.Schema{
+	Type:       "object",
+	Required:   []string{"columns"},
+	Properties: map[string]*validation.Schema{"columns": {Type: "object"}},
+}
+
+// mapColumnsSampleRows is how many rows of a spreadsheet Claude sees when
+// mapping its columns
+const mapColumnsSampleRows = 5
+
+// MapColumns asks Claude which of table's columns hold which task fields.
+// Only the header and the first few rows are sent, and only fields mapped
+// to a column the table has are returned.
+func (s *AIService) MapColumns(ctx context.Context, table *importers.Table) (importers.ColumnMapping, error) {
+	var fields strings.Builder
+	for _, field := range importers.ColumnFields {
+		fmt.Fprintf(&fields, "- %s: %s\n", field.Name, field.Description)
+	}
+	header, _ := json.Marshal(table.Header)
+	var rows strings.Builder
+	for _, row := range table.Rows[:min(len(table.Rows), mapColumnsSampleRows)] {
+		line, _ := json.Marshal(row)
+		rows.Write(line)
+		rows.WriteByte('\n')
+	}
+
+	var answer struct {
+		Columns map[string]interface{} `json:"columns"`
+	}
+	err := s.completeJSON(ctx, prompts.MapColumns, prompts.MapColumnsData{
+		Fields:  strings.TrimSuffix(fields.String(), "\n"),
+		Columns: string(header),
+		Rows:    strings.TrimSuffix(rows.String(), "\n"),
+	}, mapColumnsSchema, &answer)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := importers.ColumnMapping{}
+	for field, column := range answer.Columns {
+		if name, ok := column.(string); ok {
+			mapping[field] = name
+		}
+	}
+	return mapping.Known(table.Header), nil
+}
+
+// parseFileSchema is the answer the parse_file prompt asks for
+var parseFileSchema = &validation.Schema{
+	Type:     "object",
+	Required: []string{"tasks"},
+	Properties: map[string]*validation.Schema{
+		"tasks": {
+			Type: "array",
+			Items: &validation.Schema{
+				Type:       "object",
+				Required:   []string{"title"},
+				Properties: map[string]*validation.Schema{"title": {Type: "string", MinLength: 1}},
+			},
+		},
+	},
+}
+
+// parseFilePart asks Claude for the tasks in one part of a file
+func (s *AIService) parseFilePart(ctx context.Context, req models.ParseFileRequest, fileLanguage, partLine, content string) (map[string]interface{}, error) {
+	var parsed map[string]interface{}
+	err := s.completeJSON(ctx, prompts.ParseFile, prompts.ParseFileData{
+		LanguageLine: languagePromptLine(fileLanguage),
+		FileName:     req.FileName,
+		FileType:     req.FileType,
+		PartLine:     partLine,
+		Content:      content,
+	}, parseFileSchema, &parsed)
+	var outputErr *claude.OutputError
+	if errors.As(err, &outputErr) {
+		return nil, fmt.Errorf("Failed to parse Claude response: %v", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("File parsing failed: %v", err)
+	}
+	return parsed, nil
+}
+
+// fileTasks builds tasks from the "tasks" array of a parsed file
+func fileTasks(parsed map[string]interface{}, userID string) []models.Task {
+	var tasks []models.Task
+	tasksArray, _ := parsed["tasks"].([]interface{})
+	for _, t := range tasksArray {
+		taskMap, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		task := models.Task{UserID: userID}
+		if title, ok := taskMap["title"].(string); ok {
+			task.Title = title
+		}
+		if desc, ok := taskMap["description"].(string); ok {
+			task.Description = desc
+		}
+		if priority, ok := taskMap["priority"].(float64); ok {
+			task.Priority = int(priority)
+		}
+		if category, ok := taskMap["category"].(string); ok {
+			task.Category = category
+		}
+		if dueDateStr, ok := taskMap["due_date"].(string); ok {
+			if dueDate, err := time.Parse(time.RFC3339, dueDateStr); err == nil {
+				task.DueDate = dueDate
+			}
+		}
+		task.Language = language.Detect(task.Title + "\n" + task.Description)
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// mergeFileTasks adds found to tasks, folding a task whose title matches one
+// already there into it: the parts of a file overlap, and notes often repeat
+// an action item. The first task found keeps its place and text, taking the
+// fields it lacks and the higher priority from the later one.
+func mergeFileTasks(tasks, found []models.Task) []models.Task {
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		if key := taskTitleKey(task.Title); key != "" {
+			if _, ok := index[key]; !ok {
+				index[key] = i
+			}
+		}
+	}
+	for _, task := range found {
+		key := taskTitleKey(task.Title)
+		i, ok := index[key]
+		if key == "" || !ok {
+			if key != "" {
+				index[key] = len(tasks)
+			}
+			tasks = append(tasks, task)
+			continue
+		}
+		kept := &tasks[i]
+		if kept.Description == "" {
+			kept.Description = task.Description
+		}
+		if kept.Category == "" {
+			kept.Category = task.Category
+		}
+		if kept.DueDate.IsZero() {
+			kept.DueDate = task.DueDate
+		}
+		kept.Priority = max(kept.Priority, task.Priority)
+	}
+	return tasks
+}
+
+// taskTitleKey is title with case, punctuation and spacing dropped, so
+// "Call the bank." and "call the  bank" compare equal
+func taskTitleKey(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// splitFileContent splits content into parts of at most size new bytes,
+// breaking after a newline where possible so list items are not cut in half.
+// Each part after the first starts with the last whole lines of the one
+// before that fit in overlap bytes.
+func splitFileContent(content string, size, overlap int) []string {
+	var parts []string
+	prefix := ""
+	for len(content) > size {
+		cut := strings.LastIndexByte(content[:size], '\n') + 1
+		if cut == 0 {
+			cut = size
+		}
+		parts = append(parts, prefix+content[:cut])
+		prefix = lastLines(content[:cut], overlap)
+		content = content[cut:]
+	}
+	return append(parts, prefix+content)
+}
+
+// lastLines returns the whole lines at the end of s that fit in n bytes
+func lastLines(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	start := max(len(s)-n, 0)
+	if start > 0 && s[start-1] != '\n' {
+		next := strings.IndexByte(s[start:], '\n')
+		if next < 0 {
+			return ""
+		}
+		start += next + 1
+	}
+	return s[start:]
+}
+
+// generateSubtasksSchema is the answer the generate_subtasks prompt asks for
+var generateSubtasksSchema = &validation.Schema{
+	Type:  "array",
+	Items: &validation.Schema{Type: "string", MinLength: 1},
+}
+
+// GenerateSubtasks generates subtasks for a task using Claude
+func (s *AIService) GenerateSubtasks(ctx context.Context, req models.GenerateSubtasksRequest) *models.GenerateSubtasksResponse {
+	ctx, used := withProvidersUsed(ctx)
+	var subtasks []string
+	err := s.completeJSON(ctx, prompts.GenerateSubtasks, prompts.GenerateSubtasksData{
+		Title:        req.TaskTitle,
+		Description:  req.TaskDescription,
+		LanguageLine: languagePromptLine(language.Detect(req.TaskTitle + "\n" + req.TaskDescription)),
+	}, generateSubtasksSchema, &subtasks)
+	if err != nil {
+		// Fallback to default subtasks
+		aiFallbacks.record(ctx, prompts.GenerateSubtasks, err)
+		explanation := fmt.Sprintf("Fallback subtasks (Claude API error: %v)", err)
+		var outputErr *claude.OutputError
+		if errors.As(err, &outputErr) {
+			explanation = fmt.Sprintf("Fallback subtasks (JSON decode error: %v)", err)
+		}
+		response := models.GenerateSubtasksResponse{
+			Subtasks: []string{
+				"Break down the task into smaller steps",
+				"Research and gather information",
+				"Execute the main components",
+			},
+			Explanation:  explanation,
+			ProviderUsed: used.String(),
+			Degraded:     true,
+		}
+		return &response
+	}
+
+	response := models.GenerateSubtasksResponse{
+		Subtasks:     subtasks,
+		Explanation:  fmt.Sprintf("Generated %d subtasks using %s", len(subtasks), providerLabel(used.String())),
+		ProviderUsed: used.String(),
+	}
+
+	return &response
+}
+
+// fallbackMilestones are proposed when Claude is unavailable
+var fallbackMilestones = []string{
+	"Define what achieving the goal looks like",
+	"Complete the first major step",
+	"Reach the halfway point",
+	"Finish the remaining work and review",
+}
+
+// suggestMilestonesSchema is the answer the suggest_milestones prompt asks for
+var suggestMilestonesSchema = &validation.Schema{
+	Type: "array",
+	Items: &validation.Schema{
+		Type:       "object",
+		Required:   []string{"title"},
+		Properties: map[string]*validation.Schema{"title": {Type: "string"}},
+	},
+}
+
+// SuggestMilestones proposes milestones for a goal using Claude, spread
+// between its start and target dates
+func (s *AIService) SuggestMilestones(ctx context.Context, req models.SuggestMilestonesRequest) *models.SuggestMilestonesResponse {
+	start := time.Now()
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	targetLine := "none"
+	if req.TargetDate != nil {
+		targetLine = req.TargetDate.Format(time.RFC3339)
+	}
+
+	var proposed []struct {
+		Title   string `json:"title"`
+		DueDate string `json:"due_date"`
+	}
+	err := s.completeJSON(ctx, prompts.SuggestMilestones, prompts.SuggestMilestonesData{
+		Title:        req.GoalTitle,
+		Description:  req.GoalDescription,
+		Start:        start.Format(time.RFC3339),
+		Target:       targetLine,
+		LanguageLine: languagePromptLine(language.Detect(req.GoalTitle + "\n" + req.GoalDescription)),
+	}, suggestMilestonesSchema, &proposed)
+	if err != nil {
+		aiFallbacks.record(ctx, prompts.SuggestMilestones, err)
+		explanation := fmt.Sprintf("Fallback milestones (Claude API error: %v)", err)
+		var outputErr *claude.OutputError
+		if errors.As(err, &outputErr) {
+			explanation = fmt.Sprintf("Fallback milestones (JSON decode error: %v)", err)
+		}
+		return &models.SuggestMilestonesResponse{
+			Milestones:  spreadMilestones(fallbackMilestones, start, req.TargetDate),
+			Explanation: explanation,
+			Degraded:    true,
+		}
+	}
+
+	milestones := []models.CreateMilestoneRequest{}
+	for _, p := range proposed {
+		if strings.TrimSpace(p.Title) == "" {
+			continue
+		}
+		milestone := models.CreateMilestoneRequest{Title: p.Title}
+		if due, err := time.Parse(time.RFC3339, p.DueDate); err == nil {
+			milestone.DueDate = &due
+		}
+		milestones = append(milestones, milestone)
+	}
+
+	return &models.SuggestMilestonesResponse{
+		Milestones:  milestones,
+		Explanation: fmt.Sprintf("Suggested %d milestones using Claude AI", len(milestones)),
+	}
+}
+
+// spreadMilestones dates titles evenly from start to target, the last on the
+// target date itself; without a target they are left undated
+func spreadMilestones(titles []string, start time.Time, target *time.Time) []models.CreateMilestoneRequest {
+	milestones := make([]models.CreateMilestoneRequest, len(titles))
+	for i, title := range titles {
+		milestones[i].Title = title
+		if target != nil && target.After(start) {
+			due := start.Add(target.Sub(start) * time.Duration(i+1) / time.Duration(len(titles))).UTC().Truncate(time.Second)
+			milestones[i].DueDate = &due
+		}
+	}
+	return milestones
+}
+
+// analyzeProductivitySchema is the answer the analyze_productivity prompt asks for
+var analyzeProductivitySchema = &validation.Schema{
+	Type:     "object",
+	Required: []string{"insights", "recommendations"},
+	Properties: map[string]*validation.Schema{
+		"insights":        {Type: "array", Items: &validation.Schema{Type: "string"}},
+		"recommendations": {Type: "array", Items: &validation.Schema{Type: "string"}},
+	},
+}
+
+// maxAnalyzeDays bounds the period analyze-productivity looks back over
+const maxAnalyzeDays = 365
+
+// maxAnalyzedTasks bounds how many of the period's tasks, newest first, are
+// sent to Claude; the counts cover every task however many there are
+const maxAnalyzedTasks = 200
+
+// analyzedTaskColumns are the task fields the analyze_productivity prompt shows
+const analyzedTaskColumns = "title,priority,category,completed,completed_at,due_date,created_at,estimated_duration"
+
+// AnalyzeProductivity analyzes user productivity patterns, reporting progress
+// as it fetches the tasks and waits for Claude. Supabase counts the tasks and
+// returns only the period's most recent ones, so a user with thousands of
+// tasks costs no more than one with a few hundred.
+func (s *AIService) AnalyzeProductivity(ctx context.Context, req models.AnalyzeProductivityRequest, progress Progress) (*models.AnalyzeProductivityResponse, error) {
+	if req.Days == 0 {
+		req.Days = 7 // Default to last 7 days
+	}
+	if req.Days < 0 || req.Days > maxAnalyzeDays {
+		return nil, utils.ErrBadRequest(fmt.Sprintf("days must be between 1 and %d", maxAnalyzeDays))
+	}
+
+	// Fetch user's tasks from Supabase
+	progress.report(0, 3, "Fetching tasks")
+	supabaseClient, err := db.NewSupabaseClient(s.supabaseURL, s.supabaseKey)
+	if err != nil {
+		return nil, utils.ErrInternal("failed to connect to Supabase").WithError(err)
+	}
+	store := supabaseClient.WithContext(ctx)
+
+	cutoffDate := time.Now().AddDate(0, 0, -req.Days)
+	var recentTasks []map[string]interface{}
+	var totalCount, createdCount, completedCount int
+	queries := []func() error{
+		func() (err error) {
+			recentTasks, err = store.GetTasksCreatedSince(req.UserID, cutoffDate, analyzedTaskColumns, maxAnalyzedTasks)
+			return err
+		},
+		func() (err error) {
+			totalCount, err = store.CountTasksCreatedSince(req.UserID, time.Time{}, false)
+			return err
+		},
+		func() (err error) {
+			createdCount, err = store.CountTasksCreatedSince(req.UserID, cutoffDate, false)
+			return err
+		},
+		func() (err error) {
+			completedCount, err = store.CountTasksCreatedSince(req.UserID, cutoffDate, true)
+			return err
+		},
+	}
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = query()
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, utils.ErrInternal("failed to fetch tasks").WithError(err)
+	}
+
+	// Streaks and velocity are context for the analysis, not essential to it
+	stats, err := completionStats(supabaseClient.WithContext(ctx), req.UserID)
+	if err != nil {
+		utils.LoggerFromContext(ctx).Error("Failed to load completion stats", err, map[string]interface{}{"user_id": req.UserID})
+	}
+
+	// Prepare data for Claude
+	progress.report(1, 3, fmt.Sprintf("Analyzing %d tasks from the last %d days", createdCount, req.Days))
+	tasksJSON, _ := json.Marshal(recentTasks)
+	var insights []string
+	var recommendations []string
+
+	progress.report(2, 3, "Waiting for Claude's insights")
+	var analysis struct {
+		Insights        []string `json:"insights"`
+		Recommendations []string `json:"recommendations"`
+	}
+	err = s.completeJSON(ctx, prompts.AnalyzeProductivity, prompts.AnalyzeProductivityData{
+		StatsLines: statsPromptLines(stats),
+		Days:       req.Days,
+		Created:    createdCount,
+		Listed:     len(recentTasks),
+		Tasks:      string(tasksJSON),
+	}, analyzeProductivitySchema, &analysis)
+	if err == nil {
+		insights, recommendations = analysis.Insights, analysis.Recommendations
+	}
+
+	// Fallback if Claude fails
+	degraded := false
+	if len(insights) == 0 {
+		degraded = true
+		insights = []string{
+			"Analyzed productivity data",
+			"Found patterns in task completion",
+		}
+	}
+	if len(recommendations) == 0 {
+		degraded = true
+		recommendations = []string{
+			"Continue tracking your tasks",
+			"Focus on completing high-priority items",
+		}
+	}
+	if degraded {
+		aiFallbacks.record(ctx, prompts.AnalyzeProductivity, err)
+	}
+
+	progress.report(3, 3, "Analysis complete")
+
+	completionRate := 0.0
+	if totalCount > 0 {
+		completionRate = float64(completedCount) / float64(totalCount)
+	}
+
+	response := models.AnalyzeProductivityResponse{
+		CompletedTasks:  completedCount,
+		TotalTasks:      totalCount,
+		CompletionRate:  completionRate,
+		Insights:        insights,
+		Recommendations: recommendations,
+		Stats:           stats,
+		Degraded:        degraded,
+	}
+
+	return &response, nil
+}
+
+// maxRefineTasks bounds how many tasks Claude reviews when refining the matrix
+const maxRefineTasks = 50
+
+// reviewMatrixSchema is the answer the review_matrix prompt asks for
+var reviewMatrixSchema = &validation.Schema{
+	Type:     "object",
+	Required: []string{"moves"},
+	Properties: map[string]*validation.Schema{
+		"moves": {
+			Type: "array",
+			Items: &validation.Schema{
+				Type:     "object",
+				Required: []string{"id", "quadrant"},
+				Properties: map[string]*validation.Schema{
+					"id":       {Type: "string"},
+					"quadrant": {Type: "string", Enum: []string{QuadrantDo, QuadrantSchedule, QuadrantDelegate, QuadrantEliminate}},
+				},
+			},
+		},
+	},
+}
+
+// EisenhowerMatrix classifies the user's open tasks into urgent/important
+// quadrants by priority and due date in their time zone. With Refine, Claude
+// reviews the placement and may move tasks whose description changes the
+// picture; if it cannot, the rule-based matrix is returned as is.
+func (s *AIService) EisenhowerMatrix(ctx context.Context, req models.EisenhowerMatrixRequest) (*models.EisenhowerMatrix, error) {
+	supabaseClient, err := db.NewSupabaseClient(s.supabaseURL, s.supabaseKey)
+	if err != nil {
+		return nil, utils.ErrInternal("failed to connect to Supabase").WithError(err)
+	}
+	client := supabaseClient.WithContext(ctx)
+
+	now, _, err := userNow(client, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := client.GetUserTasks(req.UserID)
+	if err != nil {
+		return nil, utils.ErrInternal("failed to fetch tasks").WithError(err)
+	}
+
+	matrix := buildMatrix(tasks, now)
+	open := 0
+	for _, q := range matrix.Quadrants {
+		open += len(q.Tasks)
+	}
+	matrix.Explanation = fmt.Sprintf("Classified %d open tasks: priority %d or higher is important, due by the end of tomorrow is urgent",
+		open, importantPriority)
+	if !req.Refine || open == 0 {
+		return matrix, nil
+	}
+
+	type placement struct {
+		ID          string     `json:"id"`
+		Title       string     `json:"title"`
+		Description string     `json:"description,omitempty"`
+		Priority    int        `json:"priority"`
+		DueDate     *time.Time `json:"due_date,omitempty"`
+		Quadrant    string     `json:"quadrant"`
+	}
+	descriptions := make(map[string]string, len(tasks))
+	for _, task := range tasks {
+		descriptions[rowString(task, "id")] = rowString(task, "description")
+	}
+	var placements []placement
+	for _, q := range matrix.Quadrants {
+		for _, item := range q.Tasks {
+			if len(placements) == maxRefineTasks {
+				break
+			}
+			placements = append(placements, placement{item.ID, item.Title, descriptions[item.ID], item.Priority, item.DueDate, q.ID})
+		}
+	}
+	placementsJSON, _ := json.Marshal(placements)
+	var review struct {
+		Moves []struct {
+			ID       string `json:"id"`
+			Quadrant string `json:"quadrant"`
+			Reason   string `json:"reason"`
+		} `json:"moves"`
+	}
+	err = s.completeJSON(ctx, prompts.ReviewMatrix, prompts.ReviewMatrixData{
+		ImportantPriority: importantPriority,
+		Now:               now.Format(time.RFC3339),
+		Tasks:             string(placementsJSON),
+	}, reviewMatrixSchema, &review)
+	var outputErr *claude.OutputError
+	if errors.As(err, &outputErr) {
+		matrix.Explanation += fmt.Sprintf(" (Claude review unavailable: JSON decode error: %v)", err)
+		return matrix, nil
+	}
+	if err != nil {
+		matrix.Explanation += fmt.Sprintf(" (Claude review unavailable: %v)", err)
+		return matrix, nil
+	}
+
+	moved := 0
+	for _, move := range review.Moves {
+		if moveMatrixTask(matrix, move.ID, move.Quadrant, move.Reason) {
+			moved++
+		}
+	}
+	matrix.Refined = true
+	matrix.Explanation += fmt.Sprintf("; Claude reviewed %d and moved %d", len(placements), moved)
+	return matrix, nil
+}
+
+// statsPromptLines describes the user's streak and velocity for the analysis prompt
+func statsPromptLines(stats *models.CompletionStats) string {
+	if stats == nil {
+		return ""
+	}
+	return fmt.Sprintf("\nCompletion streak: %d days (longest %d), %.1f tasks completed per week on average, %d completed in total.\n",
+		stats.CurrentStreak, stats.LongestStreak, stats.AveragePerWeek, stats.TotalCompleted)
+}
+
+// languagePromptLine asks Claude to answer in the input's language, so bilingual
+// users get subtasks and titles back in the language they wrote in
+func languagePromptLine(code string) string {
+	if instruction := language.Instruction(code); instruction != "" {
+		return "\n" + instruction + "\n"
+	}
+	return ""
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// APIKeyPrefix starts every issued API key so leaked keys are easy to recognise
+const APIKeyPrefix = "pmcp_"
+
+// apiKeyTouchInterval limits how often last_used_at is written for a busy key
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyHandler issues and revokes API keys and authenticates X-API-Key headers
+type APIKeyHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(supabaseURL, supabaseKey string) *APIKeyHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &APIKeyHandler{
+		supabaseClient: client,
+	}
+}
+
+// CreateAPIKeyRequest represents a request to issue an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"` // defaults to ["read"]
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// hashAPIKey returns the hex SHA-256 digest stored in place of the key
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAPIKey returns a new random key and the prefix shown in listings
+func generateAPIKey() (key, prefix string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:len(APIKeyPrefix)+6], nil
+}
+
+// CreateAPIKey issues a new API key to the user signed in with a bearer
+// token; a named user_id is ignored. The key is only ever returned here.
+// POST /api/apikeys
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	if !rejectAPIKeyAuth(c) {
+		return
+	}
+	userID := c.GetString("user_id")
+	if c.GetString("auth_method") != "bearer" || userID == "" {
+		c.Error(utils.ErrUnauthorized("sign in to issue an API key"))
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{middleware.ScopeRead}
+	}
+
+	var v validation.Validator
+	v.Required("name", req.Name)
+	v.MaxLength("name", req.Name, 100)
+	validScopes := true
+	for _, scope := range req.Scopes {
+		validScopes = validScopes && isAPIKeyScope(scope)
+	}
+	v.Check(validScopes, "scopes", validation.CodeInvalidValue,
+		"scopes must be any of "+strings.Join(middleware.APIKeyScopes, ", "))
+	if req.ExpiresAt != nil {
+		v.Check(req.ExpiresAt.After(time.Now()), "expires_at", validation.CodeOutOfRange, "expires_at must be in the future")
+	}
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	keyData := map[string]interface{}{
+		"name":       strings.TrimSpace(req.Name),
+		"prefix":     prefix,
+		"key_hash":   hashAPIKey(key),
+		"scopes":     req.Scopes,
+		"created_at": time.Now().UTC().Format(time.RFC3339),
+	}
+	if req.ExpiresAt != nil {
+		keyData["expires_at"] = req.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	row, err := h.supabaseClient.CreateAPIKey(userID, keyData)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	delete(row, "key_hash")
+
+	recordAudit(c, AuditEntityAPIKey, rowString(row, "id"), AuditActionCreate, nil, row)
+
+	resp := gin.H{"key": key}
+	for k, v := range row {
+		resp[k] = v
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListAPIKeys lists the user's API keys without the secret part
+// GET /api/apikeys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	keys, err := h.supabaseClient.ListAPIKeys(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys, "count": len(keys)})
+}
+
+// RevokeAPIKey revokes one of the user's API keys
+// DELETE /api/apikeys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	if !rejectAPIKeyAuth(c) {
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	id := c.Param("id")
+	row, err := h.supabaseClient.RevokeAPIKey(userID, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if row == nil {
+		c.Error(utils.ErrNotFound("API key"))
+		return
+	}
+
+	recordAudit(c, AuditEntityAPIKey, id, AuditActionDelete, row, nil)
+	c.JSON(http.StatusOK, gin.H{"id": id, "revoked": true})
+}
+
+// LookupAPIKey implements middleware.APIKeyStore. Unknown, revoked and expired
+// keys resolve to nil. Use is recorded in the background at most once a minute.
+func (h *APIKeyHandler) LookupAPIKey(key string) (*middleware.APIKey, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, nil
+	}
+
+	row, err := h.supabaseClient.GetAPIKeyByHash(hashAPIKey(key))
+	if err != nil || row == nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if expiresAt, ok := rowTime(row, "expires_at"); ok && !now.Before(expiresAt) {
+		return nil, nil
+	}
+
+	id := rowString(row, "id")
+	if lastUsed, ok := rowTime(row, "last_used_at"); !ok || now.Sub(lastUsed) >= apiKeyTouchInterval {
+		go func() {
+			if err := h.supabaseClient.TouchAPIKey(id, now); err != nil {
+				utils.DefaultLogger().Error("Failed to record API key use", err, map[string]interface{}{"api_key_id": id})
+			}
+		}()
+	}
+
+	return &middleware.APIKey{
+		ID:     id,
+		UserID: rowString(row, "user_id"),
+		Scopes: rowStrings(row, "scopes"),
+	}, nil
+}
+
+// rejectAPIKeyAuth stops API keys from managing keys, which would let a key
+// mint others with wider scopes. It reports whether the request may continue.
+func rejectAPIKeyAuth(c *gin.Context) bool {
+	if c.GetString("auth_method") == "api_key" {
+		c.Error(utils.ErrForbidden("API keys cannot manage API keys"))
+		return false
+	}
+	return true
+}
+
+func isAPIKeyScope(scope string) bool {
+	for _, s := range middleware.APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestCreateAPIKeyRequiresBearer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &APIKeyHandler{}
+	for name, set := range map[string]func(c *gin.Context){
+		"anonymous": func(c *gin.Context) {},
+		"api key": func(c *gin.Context) {
+			c.Set("user_id", "u1")
+			c.Set("auth_method", "api_key")
+		},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/apikeys?user_id=victim", strings.NewReader(`{"name":"cron"}`))
+		c.Request.Header.Set("X-User-ID", "victim")
+		set(c)
+		h.CreateAPIKey(c)
+		if len(c.Errors) == 0 {
+			t.Errorf("%s: issued a key", name)
+			continue
+		}
+		if status := c.Errors.Last().Err.(*utils.AppError).HTTPStatus; status != http.StatusUnauthorized && status != http.StatusForbidden {
+			t.Errorf("%s: got %d, want 401 or 403", name, status)
+		}
+	}
+}
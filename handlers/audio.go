@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/transcription"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// MaxAudioFormBytes bounds a parse-audio upload, leaving room for the form
+// fields around the recording
+const MaxAudioFormBytes = transcription.MaxAudioBytes + 1<<20
+
+// languageCodePattern matches the ISO 639-1 codes transcription takes as a hint
+var languageCodePattern = regexp.MustCompile(`^[a-z]{2}$`)
+
+// audioTranscriber transcribes voice memos for parse-audio; nil turns it off
+var audioTranscriber transcription.Transcriber
+
+// SetTranscriber selects the speech to text model used by parse-audio
+func SetTranscriber(t transcription.Transcriber) {
+	audioTranscriber = t
+}
+
+// ParseAudio transcribes a recording and parses the transcript as ParseTask
+// does. language is a hint for the transcription; empty means detect it.
+func (s *AIService) ParseAudio(ctx context.Context, req models.ParseTaskRequest, fileName string, audio io.Reader, language string) (*models.ParseAudioResponse, error) {
+	if audioTranscriber == nil {
+		return nil, utils.NewAppError(utils.ErrCodeExternal, "audio transcription is not configured", http.StatusServiceUnavailable)
+	}
+	transcript, err := audioTranscriber.Transcribe(ctx, fileName, audio, language)
+	if err != nil {
+		return nil, utils.ErrExternal("transcription", "failed to transcribe audio").WithError(err)
+	}
+	if transcript.Text == "" {
+		return nil, utils.ErrBadRequest("no speech found in the recording")
+	}
+
+	req.Input = transcript.Text
+	return &models.ParseAudioResponse{
+		Transcript: transcript.Text,
+		Language:   transcript.Language,
+		Duration:   transcript.Duration,
+		Model:      audioTranscriber.Model(),
+		Parsed:     s.ParseTask(ctx, req),
+	}, nil
+}
+
+// ParseAudio turns a voice memo into a task: a multipart upload with the
+// recording in "audio" and user_id, and optionally timezone and language, as
+// fields
+// POST /api/mcp/parse-audio
+func (h *ClaudeHandler) ParseAudio(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxAudioFormBytes)
+	file, err := c.FormFile("audio")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.Error(utils.NewAppError(utils.ErrCodeTooLarge,
+			fmt.Sprintf("audio must be at most %d MB", transcription.MaxAudioBytes>>20), http.StatusRequestEntityTooLarge))
+		return
+	}
+	if err != nil {
+		c.Error(utils.ErrBadRequest(`audio is required as the multipart field "audio"`).WithError(err))
+		return
+	}
+	req := models.ParseTaskRequest{UserID: c.PostForm("user_id"), Timezone: c.PostForm("timezone")}
+	language := strings.ToLower(c.PostForm("language"))
+
+	var v validation.Validator
+	v.Required("user_id", req.UserID)
+	validateTimezone(&v, req.Timezone)
+	v.Check(language == "" || languageCodePattern.MatchString(language), "language", validation.CodeInvalidFormat,
+		"language must be an ISO 639-1 code, e.g. en")
+	v.Check(file.Size <= transcription.MaxAudioBytes, "audio", validation.CodeTooLong,
+		fmt.Sprintf("audio must be at most %d MB", transcription.MaxAudioBytes>>20))
+	v.Check(transcription.SupportedFormat(file.Filename), "audio", validation.CodeInvalidFormat,
+		"audio must be one of "+strings.Join(transcription.Formats, ", "))
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	if err := checkAttachmentLimit(c, getUserID(c), int(file.Size)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	audio, err := file.Open()
+	if err != nil {
+		c.Error(utils.ErrBadRequest("failed to read the recording").WithError(err))
+		return
+	}
+	defer audio.Close()
+	response, err := h.service.ParseAudio(c.Request.Context(), req, file.Filename, audio, language)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	h.setRateLimitHeaders(c)
+	c.JSON(http.StatusOK, response)
+}
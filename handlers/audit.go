@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// Audit actions
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionRevoke  = "revoke"
+)
+
+// Audited entity types
+const (
+	AuditEntityTask        = "task"
+	AuditEntityGoal        = "goal"
+	AuditEntityMilestone   = "milestone"
+	AuditEntityOAuthClient = "oauth_client"
+	AuditEntityAPIKey      = "api_key"
+	AuditEntityWorkspace   = "workspace"
+	AuditEntitySession     = "session"
+	AuditEntityOAuthGrant  = "oauth_grant"
+)
+
+// auditIgnoredFields change on every write and would only add noise to diffs
+var auditIgnoredFields = map[string]bool{"updated_at": true}
+
+// AuditLog records mutations to the audit_log table and serves it back
+type AuditLog struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewAuditLog creates a new audit log
+func NewAuditLog(supabaseURL, supabaseKey string) *AuditLog {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &AuditLog{
+		supabaseClient: client,
+	}
+}
+
+// auditLog is the recorder used by handlers; nil disables auditing
+var auditLog *AuditLog
+
+// SetAuditLog installs the audit log used by all handlers
+func SetAuditLog(a *AuditLog) {
+	auditLog = a
+}
+
+// recordAudit records a mutation using the installed audit log and publishes
+// the matching domain event
+func recordAudit(c *gin.Context, entityType, entityID, action string, before, after map[string]interface{}) {
+	auditLog.Record(c, entityType, entityID, action, before, after)
+	publishEntityEvent(c, entityType, entityID, action, before, after)
+}
+
+// Record writes an audit entry. Failures are logged, never returned, so that
+// auditing can't break the mutation it describes.
+func (a *AuditLog) Record(c *gin.Context, entityType, entityID, action string, before, after map[string]interface{}) {
+	if a == nil {
+		return
+	}
+
+	actor := getUserID(c)
+	if actor == "" {
+		actor = "anonymous"
+	}
+
+	owner := rowString(after, "user_id")
+	if owner == "" {
+		owner = rowString(before, "user_id")
+	}
+	if owner == "" && entityType != AuditEntityOAuthClient {
+		owner = actor
+	}
+
+	entry := map[string]interface{}{
+		"actor":       actor,
+		"entity_type": entityType,
+		"entity_id":   entityID,
+		"action":      action,
+		"request_id":  c.GetString("request_id"),
+		// Sub-second precision keeps quick successive actions in order for undo
+		"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	if owner != "" {
+		entry["user_id"] = owner
+	}
+	if before != nil {
+		entry["before"] = before
+	}
+	if after != nil {
+		entry["after"] = after
+	}
+	if diff := auditDiff(before, after); len(diff) > 0 {
+		entry["diff"] = diff
+	}
+
+	if err := a.supabaseClient.InsertAuditEntry(entry); err != nil {
+		utils.LoggerFromContext(c.Request.Context()).Error("Failed to record audit entry", err, map[string]interface{}{
+			"entity_type": entityType,
+			"entity_id":   entityID,
+		})
+	}
+}
+
+// auditDiff returns {field: {"from": old, "to": new}} for every field that changed
+func auditDiff(before, after map[string]interface{}) map[string]interface{} {
+	diff := make(map[string]interface{})
+	for k, newVal := range after {
+		if auditIgnoredFields[k] {
+			continue
+		}
+		oldVal, existed := before[k]
+		if !existed || !reflect.DeepEqual(oldVal, newVal) {
+			diff[k] = gin.H{"from": oldVal, "to": newVal}
+		}
+	}
+	for k, oldVal := range before {
+		if auditIgnoredFields[k] {
+			continue
+		}
+		if _, exists := after[k]; !exists && after != nil {
+			diff[k] = gin.H{"from": oldVal, "to": nil}
+		}
+	}
+	return diff
+}
+
+// ListAudit lists audit entries for the requesting user
+// GET /api/audit?entity_type=task&entity_id=xxx&limit=50
+func (a *AuditLog) ListAudit(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	a.list(c, map[string]string{
+		"user_id":     userID,
+		"entity_type": c.Query("entity_type"),
+		"entity_id":   c.Query("entity_id"),
+	})
+}
+
+// ListAllAudit lists audit entries across all users (admin only)
+// GET /admin/audit?user_id=xxx&actor=xxx&entity_type=task&limit=100
+func (a *AuditLog) ListAllAudit(c *gin.Context) {
+	a.list(c, map[string]string{
+		"user_id":     c.Query("user_id"),
+		"actor":       c.Query("actor"),
+		"entity_type": c.Query("entity_type"),
+		"entity_id":   c.Query("entity_id"),
+	})
+}
+
+func (a *AuditLog) list(c *gin.Context, filters map[string]string) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 500 {
+			c.Error(utils.ErrBadRequest("limit must be between 1 and 500"))
+			return
+		}
+		limit = n
+	}
+
+	entries, err := a.supabaseClient.GetAuditLog(filters, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
+}
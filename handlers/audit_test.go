@@ -0,0 +1,23 @@
+package handlers
+
+import "testing"
+
+func TestAuditDiff(t *testing.T) {
+	before := map[string]interface{}{"title": "Old", "priority": float64(2), "updated_at": "a", "category": "work"}
+	after := map[string]interface{}{"title": "New", "priority": float64(2), "updated_at": "b", "category": "work"}
+
+	diff := auditDiff(before, after)
+	if len(diff) != 1 {
+		t.Fatalf("expected only title to differ, got %v", diff)
+	}
+	if _, ok := diff["title"]; !ok {
+		t.Fatalf("expected title in diff, got %v", diff)
+	}
+
+	if created := auditDiff(nil, after); len(created) != 3 {
+		t.Fatalf("expected every non-ignored field on create, got %v", created)
+	}
+	if deleted := auditDiff(before, nil); len(deleted) != 0 {
+		t.Fatalf("expected no diff on delete, got %v", deleted)
+	}
+}
@@ -3,45 +3,19 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/productivity/mcp-server/signing"
 )
 
-// #region agent log
-func debugLog(location, message string, data map[string]interface{}) {
-	logEntry := map[string]interface{}{
-		"sessionId": "debug-session",
-		"runId":     "run1",
-		"location":  location,
-		"message":   message,
-		"data":      data,
-		"timestamp": time.Now().UnixMilli(),
-	}
-	if logData, err := json.Marshal(logEntry); err == nil {
-		// Use relative path or environment variable for log file
-		logPath := os.Getenv("DEBUG_LOG_PATH")
-		if logPath == "" {
-			// Default to relative path (works in both local and Railway)
-			logPath = ".cursor/debug.log"
-		}
-		if f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
-			fmt.Fprintln(f, string(logData))
-			f.Close()
-		}
-	}
-}
-
-// #endregion
-
-var jwtSecret = getJWTSecret()
+// signingKeys sign and verify OAuth tokens; installed at startup by SetSigningKeys
+var signingKeys = signing.NewKeySet("")
 
 const (
 	// Token expiration constants
@@ -50,22 +24,20 @@ const (
 	AuthCodeExpiration     = 600     // 10 minutes in seconds
 )
 
-func getJWTSecret() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// In production, this should be a fatal error
-		if os.Getenv("GIN_MODE") == "release" {
-			log.Fatal("JWT_SECRET environment variable is required in production mode")
-		}
-		// Generate a random secret for development only
-		bytes := make([]byte, 32)
-		if _, err := rand.Read(bytes); err != nil {
-			log.Fatal("Failed to generate development JWT secret: ", err)
-		}
-		secret = base64.URLEncoding.EncodeToString(bytes)
-		log.Println("⚠️  WARNING: Using auto-generated JWT secret for development. Set JWT_SECRET in production!")
-	}
-	return []byte(secret)
+// SetJWTSecret signs and verifies OAuth tokens with an HS256 secret only
+func SetJWTSecret(secret string) {
+	signingKeys = signing.NewKeySet(secret)
+}
+
+// sameResource reports whether a resource indicator names the server's
+// canonical resource; a trailing slash is not significant
+func sameResource(requested, resource string) bool {
+	return strings.TrimSuffix(requested, "/") == resource
+}
+
+// SetSigningKeys installs the keys used to sign and verify OAuth tokens
+func SetSigningKeys(keys *signing.KeySet) {
+	signingKeys = keys
 }
 
 // OAuthTokenRequest represents an OAuth token request (OAuth 2.1 with PKCE)
@@ -77,6 +49,8 @@ type OAuthTokenRequest struct {
 	ClientSecret string `json:"client_secret,omitempty"`
 	CodeVerifier string `json:"code_verifier,omitempty"` // PKCE: code_verifier for token exchange
 	RedirectURI  string `json:"redirect_uri,omitempty"`  // Must match the one used in authorization
+	Resource     string `json:"resource,omitempty"`      // RFC 8707: must match the one used in authorization
+	Scope        string `json:"scope,omitempty"`         // client_credentials: the scopes wanted, all allowed ones if empty
 }
 
 // OAuthTokenResponse represents an OAuth token response
@@ -92,13 +66,10 @@ type OAuthTokenResponse struct {
 // GET /oauth/authorize?client_id=xxx&redirect_uri=xxx&response_type=code&scope=xxx&state=xxx&code_challenge=xxx&code_challenge_method=S256
 // Also handles GET /authorize (common OAuth pattern)
 func OAuthAuthorize(c *gin.Context) {
-	// #region agent log
-	debugLog("auth.go:67", "OAuthAuthorize entry", map[string]interface{}{
-		"path":         c.Request.URL.Path,
-		"method":       c.Request.Method,
-		"hypothesisId": "H2",
+	oauthTrace(c, "OAuthAuthorize entry", map[string]interface{}{
+		"path":   c.Request.URL.Path,
+		"method": c.Request.Method,
 	})
-	// #endregion
 
 	clientID := c.Query("client_id")
 	redirectURI := c.Query("redirect_uri")
@@ -108,28 +79,22 @@ func OAuthAuthorize(c *gin.Context) {
 	codeChallenge := c.Query("code_challenge")
 	codeChallengeMethod := c.Query("code_challenge_method")
 
-	// #region agent log
-	debugLog("auth.go:79", "OAuthAuthorize params extracted", map[string]interface{}{
+	oauthTrace(c, "OAuthAuthorize params extracted", map[string]interface{}{
 		"clientID":            clientID,
 		"redirectURI":         redirectURI,
 		"responseType":        responseType,
 		"hasState":            state != "",
 		"hasCodeChallenge":    codeChallenge != "",
 		"codeChallengeMethod": codeChallengeMethod,
-		"hypothesisId":        "H2,H4",
 	})
-	// #endregion
 
 	// Validate required parameters
 	// OAuth 2.1: Errors should redirect to redirect_uri with error parameters (if redirect_uri is provided)
 	// If redirect_uri is missing, we can't redirect, so return JSON error
 	if clientID == "" {
-		// #region agent log
-		debugLog("auth.go:117", "OAuthAuthorize error: missing client_id", map[string]interface{}{
+		oauthTrace(c, "OAuthAuthorize error: missing client_id", map[string]interface{}{
 			"hasRedirectURI": redirectURI != "",
-			"hypothesisId":   "H1",
 		})
-		// #endregion
 		if redirectURI != "" {
 			// Redirect with error (OAuth 2.1 spec)
 			redirectURL, _ := url.Parse(redirectURI)
@@ -163,8 +128,7 @@ func OAuthAuthorize(c *gin.Context) {
 	// Validate redirect_uri format
 	parsedURI, err := url.Parse(redirectURI)
 	if err != nil || !parsedURI.IsAbs() {
-		// #region agent log
-		debugLog("auth.go:145", "OAuthAuthorize error: invalid redirect_uri format", map[string]interface{}{
+		oauthTrace(c, "OAuthAuthorize error: invalid redirect_uri format", map[string]interface{}{
 			"redirectURI": redirectURI,
 			"error": func() string {
 				if err != nil {
@@ -173,9 +137,7 @@ func OAuthAuthorize(c *gin.Context) {
 					return "not absolute"
 				}
 			}(),
-			"hypothesisId": "H1",
 		})
-		// #endregion
 		// Can't redirect to invalid URI per OAuth 2.1 spec (security)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":             "invalid_request",
@@ -195,13 +157,10 @@ func OAuthAuthorize(c *gin.Context) {
 		}
 	}
 	if !schemeAllowed {
-		// #region agent log
-		debugLog("auth.go:164", "OAuthAuthorize error: invalid scheme", map[string]interface{}{
-			"scheme":       parsedURI.Scheme,
-			"redirectURI":  redirectURI,
-			"hypothesisId": "H1",
+		oauthTrace(c, "OAuthAuthorize error: invalid scheme", map[string]interface{}{
+			"scheme":      parsedURI.Scheme,
+			"redirectURI": redirectURI,
 		})
-		// #endregion
 		// Can't redirect to invalid URI per OAuth 2.1 spec (security)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":             "invalid_request",
@@ -211,12 +170,9 @@ func OAuthAuthorize(c *gin.Context) {
 	}
 
 	if responseType != "code" {
-		// #region agent log
-		debugLog("auth.go:172", "OAuthAuthorize error: unsupported response_type", map[string]interface{}{
+		oauthTrace(c, "OAuthAuthorize error: unsupported response_type", map[string]interface{}{
 			"responseType": responseType,
-			"hypothesisId": "H1",
 		})
-		// #endregion
 		// Redirect with error (OAuth 2.1 spec)
 		redirectURL, _ := url.Parse(redirectURI)
 		if redirectURL != nil {
@@ -239,11 +195,7 @@ func OAuthAuthorize(c *gin.Context) {
 
 	// Validate state parameter (CSRF protection)
 	if state == "" {
-		// #region agent log
-		debugLog("auth.go:190", "OAuthAuthorize error: missing state", map[string]interface{}{
-			"hypothesisId": "H1",
-		})
-		// #endregion
+		oauthTrace(c, "OAuthAuthorize error: missing state", map[string]interface{}{})
 		// Redirect with error (OAuth 2.1 spec)
 		redirectURL, _ := url.Parse(redirectURI)
 		if redirectURL != nil {
@@ -262,21 +214,15 @@ func OAuthAuthorize(c *gin.Context) {
 	}
 
 	// Validate client_id (check default clients or database)
-	clientValid := validateClient(clientID, "")
-	// #region agent log
-	debugLog("auth.go:139", "Client validation result", map[string]interface{}{
-		"clientID":     clientID,
-		"valid":        clientValid,
-		"hypothesisId": "H6",
+	clientValid := lookupClient(clientID) != nil
+	oauthTrace(c, "Client validation result", map[string]interface{}{
+		"clientID": clientID,
+		"valid":    clientValid,
 	})
-	// #endregion
 	if !clientValid {
-		// #region agent log
-		debugLog("auth.go:210", "OAuthAuthorize error: invalid client", map[string]interface{}{
-			"clientID":     clientID,
-			"hypothesisId": "H1,H6",
+		oauthTrace(c, "OAuthAuthorize error: invalid client", map[string]interface{}{
+			"clientID": clientID,
 		})
-		// #endregion
 		// Redirect with error (OAuth 2.1 spec)
 		redirectURL, _ := url.Parse(redirectURI)
 		if redirectURL != nil {
@@ -299,22 +245,16 @@ func OAuthAuthorize(c *gin.Context) {
 
 	// Validate redirect_uri
 	redirectValid := validateRedirectURI(clientID, redirectURI)
-	// #region agent log
-	debugLog("auth.go:148", "Redirect URI validation result", map[string]interface{}{
-		"clientID":     clientID,
-		"redirectURI":  redirectURI,
-		"valid":        redirectValid,
-		"hypothesisId": "H6",
+	oauthTrace(c, "Redirect URI validation result", map[string]interface{}{
+		"clientID":    clientID,
+		"redirectURI": redirectURI,
+		"valid":       redirectValid,
 	})
-	// #endregion
 	if !redirectValid {
-		// #region agent log
-		debugLog("auth.go:235", "OAuthAuthorize error: invalid redirect_uri", map[string]interface{}{
-			"clientID":     clientID,
-			"redirectURI":  redirectURI,
-			"hypothesisId": "H1,H6",
+		oauthTrace(c, "OAuthAuthorize error: invalid redirect_uri", map[string]interface{}{
+			"clientID":    clientID,
+			"redirectURI": redirectURI,
 		})
-		// #endregion
 		// Can't redirect to invalid URI per OAuth 2.1 spec (security - prevents open redirect)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":             "invalid_request",
@@ -329,12 +269,9 @@ func OAuthAuthorize(c *gin.Context) {
 			codeChallengeMethod = "S256" // Default to S256 per OAuth 2.1
 		}
 		if codeChallengeMethod != "S256" && codeChallengeMethod != "plain" {
-			// #region agent log
-			debugLog("auth.go:252", "OAuthAuthorize error: invalid code_challenge_method", map[string]interface{}{
-				"method":       codeChallengeMethod,
-				"hypothesisId": "H1",
+			oauthTrace(c, "OAuthAuthorize error: invalid code_challenge_method", map[string]interface{}{
+				"method": codeChallengeMethod,
 			})
-			// #endregion
 			// Redirect with error (OAuth 2.1 spec)
 			redirectURL, _ := url.Parse(redirectURI)
 			if redirectURL != nil {
@@ -356,13 +293,10 @@ func OAuthAuthorize(c *gin.Context) {
 		}
 		// Validate code_challenge format (base64url encoded, 43-128 chars for S256)
 		if codeChallengeMethod == "S256" && (len(codeChallenge) < 43 || len(codeChallenge) > 128) {
-			// #region agent log
-			debugLog("auth.go:270", "OAuthAuthorize error: invalid code_challenge length", map[string]interface{}{
-				"length":       len(codeChallenge),
-				"method":       codeChallengeMethod,
-				"hypothesisId": "H1",
+			oauthTrace(c, "OAuthAuthorize error: invalid code_challenge length", map[string]interface{}{
+				"length": len(codeChallenge),
+				"method": codeChallengeMethod,
 			})
-			// #endregion
 			// Redirect with error (OAuth 2.1 spec)
 			redirectURL, _ := url.Parse(redirectURI)
 			if redirectURL != nil {
@@ -384,12 +318,79 @@ func OAuthAuthorize(c *gin.Context) {
 		}
 	}
 
-	// TODO: Check if user is authenticated (if not, redirect to login)
+	// Public clients must use PKCE, and plain only where explicitly allowed
+	if problem := pkceProblem(lookupClient(clientID), codeChallenge, codeChallengeMethod); problem != "" {
+		oauthTrace(c, "OAuthAuthorize error: PKCE required", map[string]interface{}{
+			"clientID": clientID,
+			"method":   codeChallengeMethod,
+		})
+		redirectURL, _ := url.Parse(redirectURI)
+		if redirectURL != nil {
+			q := redirectURL.Query()
+			q.Set("error", "invalid_request")
+			q.Set("error_description", problem)
+			q.Set("state", state)
+			redirectURL.RawQuery = q.Encode()
+			c.Redirect(http.StatusFound, redirectURL.String())
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": problem,
+		})
+		return
+	}
+
+	// RFC 8707: tokens are only issued for this server. Without a configured
+	// resource the indicator is not checked and tokens carry no audience.
+	resource := signingKeys.Audience()
+	if requested := c.Query("resource"); requested != "" && resource != "" && !sameResource(requested, resource) {
+		oauthTrace(c, "OAuthAuthorize error: invalid resource", map[string]interface{}{
+			"resource": requested,
+		})
+		redirectURL, _ := url.Parse(redirectURI)
+		if redirectURL != nil {
+			q := redirectURL.Query()
+			q.Set("error", "invalid_target")
+			q.Set("error_description", "resource must be "+resource)
+			q.Set("state", state)
+			redirectURL.RawQuery = q.Encode()
+			c.Redirect(http.StatusFound, redirectURL.String())
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_target",
+			"error_description": "resource must be " + resource,
+		})
+		return
+	}
+
+	pending := &AuthCodeData{
+		ClientID:            clientID,
+		RedirectURI:         redirectURI,
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
+		Scope:               scope,
+		Resource:            resource,
+		State:               state,
+		UserID:              authorizingUser(c),
+	}
+
+	// Ask the user unless they already granted the client every requested
+	// scope; prompt=consent asks again anyway
+	if c.Query("prompt") == "consent" || !grantCovers(c, pending.UserID, clientID, scope) {
+		showConsent(c, pending)
+		return
+	}
+	issueAuthCode(c, pending)
+}
 
+// issueAuthCode stores a code for the approved authorization request and
+// redirects back to the client with it
+func issueAuthCode(c *gin.Context, authCodeData *AuthCodeData) {
 	// Generate an authorization code
-	authCode, err := generateAuthCode(clientID, redirectURI)
-	// #region agent log
-	debugLog("auth.go:181", "Auth code generation result", map[string]interface{}{
+	authCode, err := generateAuthCode(authCodeData.ClientID, authCodeData.RedirectURI)
+	oauthTrace(c, "Auth code generation result", map[string]interface{}{
 		"hasCode":  authCode != "",
 		"hasError": err != nil,
 		"error": func() string {
@@ -399,16 +400,11 @@ func OAuthAuthorize(c *gin.Context) {
 				return ""
 			}
 		}(),
-		"hypothesisId": "H4",
 	})
-	// #endregion
 	if err != nil {
-		// #region agent log
-		debugLog("auth.go:187", "OAuthAuthorize error: failed to generate code", map[string]interface{}{
-			"error":        err.Error(),
-			"hypothesisId": "H1,H4",
+		oauthTrace(c, "OAuthAuthorize error: failed to generate code", map[string]interface{}{
+			"error": err.Error(),
 		})
-		// #endregion
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":             "server_error",
 			"error_description": "Failed to generate authorization code",
@@ -417,29 +413,18 @@ func OAuthAuthorize(c *gin.Context) {
 	}
 
 	// Store auth code with PKCE data (always store, even without PKCE for consistency)
-	authCodeData := &AuthCodeData{
-		Code:                authCode,
-		ClientID:            clientID,
-		RedirectURI:         redirectURI,
-		CodeChallenge:       codeChallenge,
-		CodeChallengeMethod: codeChallengeMethod,
-		Scope:               scope,
-		State:               state,
-		ExpiresAt:           time.Now().Add(time.Duration(AuthCodeExpiration) * time.Second).Unix(),
-		Used:                false,
-	}
+	authCodeData.Code = authCode
+	authCodeData.ExpiresAt = time.Now().Add(time.Duration(AuthCodeExpiration) * time.Second).Unix()
+	authCodeData.Used = false
 	StoreAuthCode(authCode, authCodeData)
-	// #region agent log
-	debugLog("auth.go:202", "Auth code stored", map[string]interface{}{
-		"code":             authCode,
-		"hasCodeChallenge": codeChallenge != "",
+	oauthTrace(c, "Auth code stored", map[string]interface{}{
+		"auth_code":        authCode,
+		"hasCodeChallenge": authCodeData.CodeChallenge != "",
 		"expiresAt":        authCodeData.ExpiresAt,
-		"hypothesisId":     "H4",
 	})
-	// #endregion
 
 	// Build redirect URL with proper encoding
-	redirectURL, err := url.Parse(redirectURI)
+	redirectURL, err := url.Parse(authCodeData.RedirectURI)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":             "invalid_request",
@@ -450,19 +435,16 @@ func OAuthAuthorize(c *gin.Context) {
 
 	q := redirectURL.Query()
 	q.Set("code", authCode)
-	q.Set("state", state)
-	if scope != "" {
-		q.Set("scope", scope)
+	q.Set("state", authCodeData.State)
+	if authCodeData.Scope != "" {
+		q.Set("scope", authCodeData.Scope)
 	}
 	redirectURL.RawQuery = q.Encode()
 
-	// #region agent log
-	debugLog("auth.go:222", "OAuthAuthorize redirect", map[string]interface{}{
-		"redirectURL":  redirectURL.String(),
-		"statusCode":   302,
-		"hypothesisId": "H2",
+	oauthTrace(c, "OAuthAuthorize redirect", map[string]interface{}{
+		"redirectURL": redirectURL.String(),
+		"statusCode":  302,
 	})
-	// #endregion
 
 	c.Redirect(http.StatusFound, redirectURL.String())
 }
@@ -490,23 +472,24 @@ func OAuthToken(c *gin.Context) {
 			return
 		}
 
-		// Validate client_id and client_secret if provided
-		if req.ClientID != "" {
-			if !validateClient(req.ClientID, req.ClientSecret) {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error":             "invalid_client",
-					"error_description": "Invalid client_id or client_secret",
-				})
-				return
-			}
+		// The client must say who it is, and prove it unless it is public;
+		// a code intercepted without PKCE is useless without the secret
+		if req.ClientID == "" && req.ClientSecret == "" {
+			req.ClientID, req.ClientSecret, _ = c.Request.BasicAuth()
+		}
+		if req.ClientID == "" || !validateClient(req.ClientID, req.ClientSecret) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_client",
+				"error_description": "Invalid client_id or client_secret",
+			})
+			return
 		}
 
 		// Get stored auth code data
 		authCodeData, err := GetAuthCode(req.Code)
-		// #region agent log
-		debugLog("auth.go:260", "GetAuthCode result", map[string]interface{}{
-			"code":  req.Code,
-			"found": err == nil,
+		oauthTrace(c, "GetAuthCode result", map[string]interface{}{
+			"auth_code": req.Code,
+			"found":     err == nil,
 			"error": func() string {
 				if err != nil {
 					return err.Error()
@@ -514,17 +497,12 @@ func OAuthToken(c *gin.Context) {
 					return ""
 				}
 			}(),
-			"hypothesisId": "H4",
 		})
-		// #endregion
 		if err != nil {
-			// #region agent log
-			debugLog("auth.go:266", "OAuthToken error: invalid code", map[string]interface{}{
-				"code":         req.Code,
-				"error":        err.Error(),
-				"hypothesisId": "H4",
+			oauthTrace(c, "OAuthToken error: invalid code", map[string]interface{}{
+				"auth_code": req.Code,
+				"error":     err.Error(),
 			})
-			// #endregion
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":             "invalid_grant",
 				"error_description": fmt.Sprintf("Invalid or expired authorization code: %v", err),
@@ -541,6 +519,15 @@ func OAuthToken(c *gin.Context) {
 			return
 		}
 
+		// A code is only good for the client it was issued to
+		if req.ClientID != authCodeData.ClientID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_grant",
+				"error_description": "authorization code was issued to another client",
+			})
+			return
+		}
+
 		// Validate redirect_uri matches (REQUIRED per Cloudflare CVE-2025-4143)
 		// Must validate redirect_uri in token exchange to prevent open redirect attacks
 		if req.RedirectURI == "" {
@@ -550,17 +537,14 @@ func OAuthToken(c *gin.Context) {
 			})
 			return
 		}
-		
+
 		// Exact match required (per Cloudflare security requirements)
 		if req.RedirectURI != authCodeData.RedirectURI {
-			// #region agent log
-			debugLog("auth.go:551", "OAuthToken error: redirect_uri mismatch", map[string]interface{}{
-				"requested":  req.RedirectURI,
-				"stored":     authCodeData.RedirectURI,
-				"match":      false,
-				"hypothesisId": "H6",
+			oauthTrace(c, "OAuthToken error: redirect_uri mismatch", map[string]interface{}{
+				"requested": req.RedirectURI,
+				"stored":    authCodeData.RedirectURI,
+				"match":     false,
 			})
-			// #endregion
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":             "invalid_grant",
 				"error_description": "redirect_uri does not match the one used in authorization",
@@ -580,8 +564,7 @@ func OAuthToken(c *gin.Context) {
 
 			// Validate code_verifier against stored code_challenge
 			pkceErr := ValidatePKCE(authCodeData.CodeChallenge, authCodeData.CodeChallengeMethod, req.CodeVerifier)
-			// #region agent log
-			debugLog("auth.go:298", "PKCE validation result", map[string]interface{}{
+			oauthTrace(c, "PKCE validation result", map[string]interface{}{
 				"codeChallenge":   authCodeData.CodeChallenge,
 				"method":          authCodeData.CodeChallengeMethod,
 				"hasCodeVerifier": req.CodeVerifier != "",
@@ -593,16 +576,11 @@ func OAuthToken(c *gin.Context) {
 						return ""
 					}
 				}(),
-				"hypothesisId": "H5",
 			})
-			// #endregion
 			if pkceErr != nil {
-				// #region agent log
-				debugLog("auth.go:303", "OAuthToken error: PKCE validation failed", map[string]interface{}{
-					"error":        pkceErr.Error(),
-					"hypothesisId": "H5",
+				oauthTrace(c, "OAuthToken error: PKCE validation failed", map[string]interface{}{
+					"error": pkceErr.Error(),
 				})
-				// #endregion
 				c.JSON(http.StatusBadRequest, gin.H{
 					"error":             "invalid_grant",
 					"error_description": fmt.Sprintf("PKCE validation failed: %v", pkceErr),
@@ -611,6 +589,16 @@ func OAuthToken(c *gin.Context) {
 			}
 		}
 
+		// RFC 8707: a resource named again at the token endpoint must be the
+		// one the code was issued for
+		if req.Resource != "" && authCodeData.Resource != "" && !sameResource(req.Resource, authCodeData.Resource) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_target",
+				"error_description": "resource does not match the one used in authorization",
+			})
+			return
+		}
+
 		// Generate access token using data from auth code
 		accessToken, err := generateAccessTokenFromAuthCode(authCodeData)
 		if err != nil {
@@ -632,7 +620,7 @@ func OAuthToken(c *gin.Context) {
 
 		scope := authCodeData.Scope
 		if scope == "" {
-			scope = "read write"
+			scope = defaultScope
 		}
 
 		c.JSON(http.StatusOK, OAuthTokenResponse{
@@ -673,6 +661,9 @@ func OAuthToken(c *gin.Context) {
 			ExpiresIn:   AccessTokenExpiration,
 		})
 
+	case "client_credentials":
+		clientCredentialsToken(c, req)
+
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":             "unsupported_grant_type",
@@ -704,7 +695,7 @@ func OAuthIntrospect(c *gin.Context) {
 
 	// Validate token
 	claims, err := validateJWT(token)
-	if err != nil {
+	if err != nil || isTokenRevoked(claims) {
 		c.JSON(http.StatusOK, gin.H{
 			"active": false,
 		})
@@ -715,6 +706,7 @@ func OAuthIntrospect(c *gin.Context) {
 		"active":    true,
 		"client_id": claims["client_id"],
 		"scope":     claims["scope"],
+		"aud":       claims["aud"],
 		"exp":       claims["exp"],
 		"iat":       claims["iat"],
 	})
@@ -748,27 +740,47 @@ func generateAuthCode(clientID, redirectURI string) (string, error) {
 
 // generateAccessTokenFromAuthCode generates an access token from stored auth code data
 func generateAccessTokenFromAuthCode(authCodeData *AuthCodeData) (string, error) {
-	// TODO: Get actual user_id from database based on authenticated session
-	// For now, use placeholder
-	userID := "user_id_from_session" // TODO: Get from authCodeData or session
+	userID := authCodeData.UserID
+	if userID == "" {
+		userID = placeholderUserID
+	}
+
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
 
 	claims := jwt.MapClaims{
+		"jti":       jti,
 		"sub":       userID,
 		"client_id": authCodeData.ClientID,
 		"scope":     authCodeData.Scope,
 		"iat":       time.Now().Unix(),
 		"exp":       time.Now().Add(time.Duration(AccessTokenExpiration) * time.Second).Unix(),
 	}
+	if authCodeData.Resource != "" {
+		claims["aud"] = authCodeData.Resource
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token, err := signingKeys.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+	recordSession(claims)
+	return token, nil
 }
 
 // generateAccessToken is kept for backward compatibility
 func generateAccessToken(authCode string) (string, error) {
 	// This is deprecated - use generateAccessTokenFromAuthCode instead
 	// But kept for refresh token flow
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{
+		"jti":       jti,
 		"sub":       "user_id_from_authcode", // TODO: Get from authCode lookup
 		"client_id": "mcp_client",            // TODO: Get from authCode lookup
 		"scope":     "read write",            // TODO: Get from authCode lookup
@@ -776,8 +788,12 @@ func generateAccessToken(authCode string) (string, error) {
 		"exp":       time.Now().Add(time.Duration(AccessTokenExpiration) * time.Second).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token, err := signingKeys.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+	recordSession(claims)
+	return token, nil
 }
 
 func generateRefreshToken() (string, error) {
@@ -829,20 +845,5 @@ func refreshAccessToken(refreshToken string) (string, error) {
 }
 
 func validateJWT(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return jwtSecret, nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
-	}
-
-	return nil, jwt.ErrSignatureInvalid
+	return signingKeys.Verify(tokenString)
 }
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// capacitySuggestDays is how many days past an overbooked one a capacity
+// warning looks for room
+const capacitySuggestDays = 14
+
+// dayLoad is the open work due on one day
+type dayLoad struct {
+	tasks   int
+	minutes int
+}
+
+// workingHours returns the user's working day as clock times, or the
+// defaults if theirs does not parse or ends before it starts
+func workingHours(prefs models.Preferences) (start, end time.Time) {
+	start, err := time.Parse("15:04", prefs.WorkStart)
+	if err == nil {
+		end, err = time.Parse("15:04", prefs.WorkEnd)
+	}
+	if err != nil || !end.After(start) {
+		start, _ = time.Parse("15:04", defaultPreferences.WorkStart)
+		end, _ = time.Parse("15:04", defaultPreferences.WorkEnd)
+	}
+	return start, end
+}
+
+// workingMinutes is how many minutes the user works on day; days outside
+// Monday to Friday have none
+func workingMinutes(prefs models.Preferences, day time.Time) int {
+	if !isWorkingDay(day) {
+		return 0
+	}
+	start, end := workingHours(prefs)
+	return int(end.Sub(start).Minutes())
+}
+
+// bookedLoad totals open tasks and their estimated minutes by the start of
+// their due day in loc, leaving out the task with id exclude
+func bookedLoad(tasks []map[string]interface{}, loc *time.Location, exclude string) map[time.Time]dayLoad {
+	load := map[time.Time]dayLoad{}
+	for _, task := range tasks {
+		due, ok := rowTime(task, "due_date")
+		if !ok || rowBool(task, "completed") || rowString(task, "id") == exclude {
+			continue
+		}
+		day := startOfDay(due.In(loc))
+		l := load[day]
+		l.tasks++
+		l.minutes += rowInt(task, "estimated_duration")
+		load[day] = l
+	}
+	return load
+}
+
+// capacityWarning warns when adding minutes of work due at due overbooks
+// that day, suggesting the first later day with room. It returns nil if the
+// day has room.
+func capacityWarning(due time.Time, minutes int, prefs models.Preferences, load map[time.Time]dayLoad) *models.CapacityWarning {
+	day := startOfDay(due)
+	capacity := workingMinutes(prefs, day)
+	booked := load[day].minutes + minutes
+	if minutes <= 0 || booked <= capacity {
+		return nil
+	}
+
+	warning := &models.CapacityWarning{
+		Date:            day.Format(dayLayout),
+		BookedMinutes:   booked,
+		CapacityMinutes: capacity,
+		Message: fmt.Sprintf("%s is overbooked: %d minutes of tasks against %d working minutes",
+			day.Format("Mon Jan 2"), booked, capacity),
+	}
+	if capacity == 0 {
+		warning.Message = fmt.Sprintf("%s is not a working day", day.Format("Mon Jan 2"))
+	}
+	for i := 1; i <= capacitySuggestDays; i++ {
+		next := day.AddDate(0, 0, i)
+		if load[next].minutes+minutes > workingMinutes(prefs, next) {
+			continue
+		}
+		suggested := time.Date(next.Year(), next.Month(), next.Day(), due.Hour(), due.Minute(), 0, 0, due.Location())
+		warning.SuggestedDate = &suggested
+		warning.Message += fmt.Sprintf("; %s has room", next.Format("Mon Jan 2"))
+		break
+	}
+	return warning
+}
+
+// CapacityWarning checks whether task, as stored, overbooks its due day for
+// userID. Open tasks without a due date or estimate never do. Lookup errors
+// are logged rather than failing the change the warning is about.
+func (s *TaskService) CapacityWarning(c *gin.Context, userID string, task map[string]interface{}) *models.CapacityWarning {
+	due, ok := rowTime(task, "due_date")
+	minutes := rowInt(task, "estimated_duration")
+	if !ok || minutes <= 0 || rowBool(task, "completed") {
+		return nil
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	now, prefs, err := userNow(client, userID)
+	if err == nil {
+		var tasks []map[string]interface{}
+		if tasks, err = client.GetOpenDatedTasks(userID); err == nil {
+			load := bookedLoad(tasks, now.Location(), rowString(task, "id"))
+			return capacityWarning(due.In(now.Location()), minutes, prefs, load)
+		}
+	}
+	utils.LoggerFromContext(c.Request.Context()).Error("Failed to check capacity", err, map[string]interface{}{"task_id": rowString(task, "id")})
+	return nil
+}
+
+// Capacity compares userID's booked work with their working hours for each
+// day of the week containing week, a YYYY-MM-DD date; empty means this week
+func (s *TaskService) Capacity(c *gin.Context, userID, week string) (*models.CapacityReport, error) {
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	now, prefs, err := userNow(client, userID)
+	if err != nil {
+		return nil, err
+	}
+	day := startOfDay(now)
+	if week != "" {
+		var v validation.Validator
+		day, err = time.ParseInLocation(dayLayout, week, now.Location())
+		v.Check(err == nil, "week", validation.CodeInvalidFormat, "week must be a date, YYYY-MM-DD")
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+	}
+	tasks, err := client.GetOpenDatedTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+	load := bookedLoad(tasks, now.Location(), "")
+
+	start := weekStartDay(day, prefs)
+	report := &models.CapacityReport{
+		WeekStart: start.Format(dayLayout),
+		Timezone:  now.Location().String(),
+		Days:      make([]models.DayCapacity, 0, 7),
+	}
+	for i := 0; i < 7; i++ {
+		d := start.AddDate(0, 0, i)
+		l := load[d]
+		capacity := workingMinutes(prefs, d)
+		report.Days = append(report.Days, models.DayCapacity{
+			Date:            d.Format(dayLayout),
+			Tasks:           l.tasks,
+			BookedMinutes:   l.minutes,
+			CapacityMinutes: capacity,
+			Overbooked:      l.minutes > capacity,
+		})
+		report.BookedMinutes += l.minutes
+		report.CapacityMinutes += capacity
+	}
+	return report, nil
+}
+
+// Capacity reports booked work against working hours for each day of a week
+// GET /api/capacity?week=2025-06-02
+func (h *TaskHandler) Capacity(c *gin.Context) {
+	report, err := h.service.Capacity(c, getUserID(c), c.Query("week"))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCapacityWarning(t *testing.T) {
+	prefs := defaultPreferences // 09:00 to 17:00, 480 minutes
+	day := func(d int) time.Time { return time.Date(2026, time.October, d, 0, 0, 0, 0, time.UTC) }
+	due := time.Date(2026, time.October, 19, 15, 0, 0, 0, time.UTC) // a Monday
+	load := map[time.Time]dayLoad{
+		day(19): {tasks: 3, minutes: 420},
+		day(20): {tasks: 4, minutes: 470},
+		day(21): {tasks: 1, minutes: 60},
+	}
+
+	cases := []struct {
+		due       time.Time
+		minutes   int
+		warn      bool
+		suggested time.Time
+	}{
+		{due, 60, false, time.Time{}},
+		{due, 0, false, time.Time{}},
+		{due, 90, true, time.Date(2026, time.October, 21, 15, 0, 0, 0, time.UTC)},
+		// Weekends have no working hours
+		{time.Date(2026, time.October, 17, 10, 0, 0, 0, time.UTC), 30, true, time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)},
+		// No day has room for more than a working day
+		{due, 600, true, time.Time{}},
+	}
+	for i, tc := range cases {
+		warning := capacityWarning(tc.due, tc.minutes, prefs, load)
+		if (warning != nil) != tc.warn {
+			t.Errorf("case %d: warning %+v, want %v", i, warning, tc.warn)
+			continue
+		}
+		if warning == nil {
+			continue
+		}
+		if tc.suggested.IsZero() != (warning.SuggestedDate == nil) ||
+			(warning.SuggestedDate != nil && !warning.SuggestedDate.Equal(tc.suggested)) {
+			t.Errorf("case %d: suggested %v, want %v (%s)", i, warning.SuggestedDate, tc.suggested, warning.Message)
+		}
+	}
+}
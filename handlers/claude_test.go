@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/llm"
+	"github.com/productivity/mcp-server/mockllm"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/transcription"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// TestClaudeHandlerAgainstMockLLM keeps the prompts here and the mock's responders in step
+func TestClaudeHandlerAgainstMockLLM(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+
+	h := NewClaudeHandler("", "", config.Claude{
+		APIKey:    "mock",
+		BaseURL:   llm.URL,
+		Model:     "claude-3-5-sonnet-20241022",
+		MaxTokens: 1024,
+		Timeout:   config.Duration{Duration: 5 * time.Second},
+	})
+	call := func(handler gin.HandlerFunc, body string, out interface{}) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		handler(ctx)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body)
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var parsed models.ParseTaskResponse
+	call(h.ParseTask, `{"input":"urgent: send the client report tomorrow","user_id":"u1"}`, &parsed)
+	if parsed.Confidence != 0.9 {
+		t.Fatalf("expected the mock reply to be used, got %q", parsed.Explanation)
+	}
+	if parsed.Task.Priority != 5 || parsed.Task.Category != "work" || parsed.Task.DueDate.IsZero() {
+		t.Fatalf("unexpected task %+v", parsed.Task)
+	}
+
+	var subtasks models.GenerateSubtasksResponse
+	call(h.GenerateSubtasks, `{"task_title":"Plan offsite","user_id":"u1"}`, &subtasks)
+	if len(subtasks.Subtasks) < 3 || !strings.Contains(subtasks.Subtasks[0], "Plan offsite") {
+		t.Fatalf("unexpected subtasks %+v", subtasks)
+	}
+
+	var suggested models.SuggestMilestonesResponse
+	call(h.SuggestMilestones, `{"goal_title":"Run a marathon","start_date":"2030-01-01T00:00:00Z","target_date":"2030-05-01T00:00:00Z"}`, &suggested)
+	if len(suggested.Milestones) < 3 || !strings.Contains(suggested.Milestones[0].Title, "Run a marathon") {
+		t.Fatalf("unexpected milestones %+v", suggested)
+	}
+	last := suggested.Milestones[len(suggested.Milestones)-1]
+	if last.DueDate == nil || !last.DueDate.Equal(time.Date(2030, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the last milestone on the target date, got %+v", last)
+	}
+}
+
+// TestParseTaskCorrectsReply checks that a reply which is not the JSON asked
+// for is sent back once for correction before parsing falls back
+func TestParseTaskCorrectsReply(t *testing.T) {
+	var requests [][]map[string]interface{}
+	replies := []string{}
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []map[string]interface{} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, req.Messages)
+		text := replies[0]
+		replies = replies[1:]
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content":     []map[string]string{{"type": "text", "text": text}},
+			"stop_reason": "end_turn",
+		})
+	}))
+	defer llm.Close()
+	service := NewAIService("", "", config.Claude{
+		APIKey:  "key",
+		BaseURL: llm.URL,
+		Model:   "claude-test",
+		Timeout: config.Duration{Duration: 5 * time.Second},
+	})
+
+	replies = []string{"Sure! The task is to call mom.", "```json\n{\"title\": \"Call mom\", \"priority\": 2,}\n```"}
+	parsed := service.ParseTask(context.Background(), models.ParseTaskRequest{Input: "call mom"})
+	if parsed.Confidence != 0.9 || parsed.Task.Title != "Call mom" || parsed.Task.Priority != 2 || parsed.Degraded {
+		t.Fatalf("corrected reply: %+v (%s)", parsed.Task, parsed.Explanation)
+	}
+	if len(requests) != 2 || len(requests[1]) != 3 || requests[1][1]["role"] != "assistant" ||
+		!strings.Contains(requests[1][2]["content"].(string), "contains no JSON") {
+		t.Fatalf("correction request: %v", requests)
+	}
+
+	requests = nil
+	replies = []string{`{"priority": 9}`, `{"priority": 9}`}
+	parsed = service.ParseTask(context.Background(), models.ParseTaskRequest{Input: "call mom"})
+	if parsed.Confidence != 0.6 || len(requests) != 2 || !strings.Contains(parsed.Explanation, "title is required") || !parsed.Degraded {
+		t.Fatalf("uncorrected reply: %d requests, %+v (%s)", len(requests), parsed.Task, parsed.Explanation)
+	}
+}
+
+// TestAIFallbacksCounted checks that canned answers given while Claude is
+// down are flagged as degraded and counted per operation
+func TestAIFallbacksCounted(t *testing.T) {
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer llm.Close()
+	service := NewAIService("", "", config.Claude{
+		APIKey:  "key",
+		BaseURL: llm.URL,
+		Model:   "claude-test",
+		Timeout: config.Duration{Duration: 5 * time.Second},
+	})
+	before := aiFallbacks.snapshot()
+	ctx := context.Background()
+
+	if parsed := service.ParseTask(ctx, models.ParseTaskRequest{Input: "call mom"}); !parsed.Degraded {
+		t.Error("parse_task fallback not flagged as degraded")
+	}
+	if subtasks := service.GenerateSubtasks(ctx, models.GenerateSubtasksRequest{TaskTitle: "Plan offsite"}); !subtasks.Degraded {
+		t.Error("generate_subtasks fallback not flagged as degraded")
+	}
+	if milestones := service.SuggestMilestones(ctx, models.SuggestMilestonesRequest{GoalTitle: "Run a marathon"}); !milestones.Degraded {
+		t.Error("suggest_milestones fallback not flagged as degraded")
+	}
+	if file := service.ParseFile(ctx, models.ParseFileRequest{FileName: "notes.md", FileContent: "- call mom"}, nil); !file.Degraded {
+		t.Error("parse_file with a failed part not flagged as degraded")
+	}
+
+	after := aiFallbacks.snapshot()
+	for _, operation := range []string{"parse_task", "generate_subtasks", "suggest_milestones", "parse_file"} {
+		if got := after[operation].Count - before[operation].Count; got != 1 {
+			t.Errorf("%s: %d fallbacks counted, want 1", operation, got)
+		}
+		if after[operation].LastError == "" {
+			t.Errorf("%s: no last error recorded", operation)
+		}
+	}
+}
+
+func TestRefineTaskAgainstMockLLM(t *testing.T) {
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+	service := NewAIService("", "", config.Claude{
+		APIKey:  "mock",
+		BaseURL: llm.URL,
+		Model:   "claude-test",
+		Timeout: config.Duration{Duration: 5 * time.Second},
+	})
+	ctx := context.Background()
+
+	first, err := service.RefineTask(ctx, models.RefineTaskRequest{
+		UserID:     "u1",
+		Correction: "actually it's due Friday and it's priority 5",
+		Task:       &models.Task{Title: "Send the report", Priority: 3, Category: "work"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Confidence != 0.9 || first.Turns != 1 || first.ConversationID == "" {
+		t.Fatalf("first correction: %+v", first)
+	}
+	if first.Task.Priority != 5 || first.Task.Title != "Send the report" || first.Task.DueDate.Weekday() != time.Friday {
+		t.Fatalf("first correction: task %+v", first.Task)
+	}
+
+	// The second correction builds on the first without resending the task
+	second, err := service.RefineTask(ctx, models.RefineTaskRequest{
+		UserID:         "u1",
+		Correction:     "make it priority 2",
+		ConversationID: first.ConversationID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ConversationID != first.ConversationID || second.Turns != 2 || second.Task.Priority != 2 ||
+		!second.Task.DueDate.Equal(first.Task.DueDate) || second.Task.Category != "work" {
+		t.Fatalf("second correction: %+v, task %+v", second, second.Task)
+	}
+
+	if _, err := service.RefineTask(ctx, models.RefineTaskRequest{UserID: "u2", Correction: "priority 1", ConversationID: first.ConversationID}); err == nil {
+		t.Error("another user's conversation: expected an error")
+	}
+	if _, err := service.RefineTask(ctx, models.RefineTaskRequest{UserID: "u1", Correction: "priority 1"}); err == nil {
+		t.Error("no task or conversation: expected an error")
+	}
+}
+
+// fakeTranscriber hears text in every recording
+type fakeTranscriber struct {
+	text string
+}
+
+func (f fakeTranscriber) Transcribe(ctx context.Context, fileName string, audio io.Reader, language string) (*transcription.Transcript, error) {
+	return &transcription.Transcript{Text: f.text, Language: "english"}, nil
+}
+
+func (f fakeTranscriber) Model() string {
+	return "fake-whisper"
+}
+
+func TestParseAudio(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+	h := NewClaudeHandler("", "", config.Claude{
+		APIKey:  "mock",
+		BaseURL: llm.URL,
+		Model:   "claude-test",
+		Timeout: config.Duration{Duration: 5 * time.Second},
+	})
+	defer SetTranscriber(nil)
+
+	upload := func(fileName string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("audio", fileName)
+		part.Write([]byte("RIFF"))
+		form.WriteField("user_id", "u1")
+		form.Close()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/", &body)
+		ctx.Request.Header.Set("Content-Type", form.FormDataContentType())
+		h.ParseAudio(ctx)
+		if len(ctx.Errors) > 0 {
+			recorder.Code = ctx.Errors.Last().Err.(*utils.AppError).HTTPStatus
+		}
+		return recorder
+	}
+
+	if recorder := upload("memo.m4a"); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a transcriber: expected 503, got %d", recorder.Code)
+	}
+
+	SetTranscriber(fakeTranscriber{text: "urgent: send the client report tomorrow"})
+	recorder := upload("memo.m4a")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body)
+	}
+	var response models.ParseAudioResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Transcript != "urgent: send the client report tomorrow" || response.Model != "fake-whisper" ||
+		response.Parsed == nil || response.Parsed.Confidence != 0.9 || response.Parsed.Task.Priority != 5 {
+		t.Fatalf("unexpected response %+v", response)
+	}
+
+	if recorder := upload("memo.txt"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("unsupported format: expected 400, got %d", recorder.Code)
+	}
+}
+
+// TestModelRouting checks that routed operations reach their model and that
+// X-LLM-Model overrides them only with an allowed model
+func TestModelRouting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := httptest.NewServer(mockllm.NewHandler())
+	defer mock.Close()
+
+	h := NewClaudeHandler("", "", config.Claude{
+		APIKey:    "mock",
+		BaseURL:   mock.URL,
+		Model:     "claude-3-5-sonnet-20241022",
+		MaxTokens: 1024,
+		Timeout:   config.Duration{Duration: 5 * time.Second},
+	})
+	routes, err := llm.ParseRoutes([]string{"generate_subtasks=ollama:llama3.2"}, RoutableOperations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetRoutes(routes, llm.NewOllama(config.Ollama{URL: mock.URL, Model: "qwen3-coder:480b-cloud"}))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) > 0 {
+			c.JSON(c.Errors.Last().Err.(*utils.AppError).HTTPStatus, c.Errors.Last().Err)
+		}
+	})
+	group := router.Group("/api/mcp", h.ModelOverride())
+	group.POST("/parse-task", h.ParseTask)
+	group.POST("/generate-subtasks", h.GenerateSubtasks)
+	call := func(path, model, body string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if model != "" {
+			req.Header.Set(ModelHeader, model)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		var out struct {
+			ProviderUsed string `json:"provider_used"`
+		}
+		json.Unmarshal(recorder.Body.Bytes(), &out)
+		return recorder.Code, out.ProviderUsed
+	}
+
+	for _, tc := range []struct {
+		path, model string
+		status      int
+		provider    string
+	}{
+		{"/api/mcp/parse-task", "", http.StatusOK, "claude"},
+		{"/api/mcp/generate-subtasks", "", http.StatusOK, "ollama"},
+		{"/api/mcp/parse-task", "ollama:llama3.2", http.StatusOK, "ollama"},
+		{"/api/mcp/generate-subtasks", "claude-3-5-sonnet-20241022", http.StatusOK, "claude"},
+		{"/api/mcp/parse-task", "claude-3-opus-20240229", http.StatusBadRequest, ""},
+	} {
+		body := `{"input":"send the report","task_title":"Plan offsite","user_id":"u1"}`
+		status, provider := call(tc.path, tc.model, body)
+		if status != tc.status || provider != tc.provider {
+			t.Errorf("%s with %q: got %d from %q, want %d from %q", tc.path, tc.model, status, provider, tc.status, tc.provider)
+		}
+	}
+}
+
+// TestLLMParams checks that a request's model, max_tokens and temperature
+// reach Claude within the allow-list and caps
+func TestLLMParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var sent map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = nil
+		json.NewDecoder(r.Body).Decode(&sent)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content":     []map[string]string{{"type": "text", "text": `["Draft the agenda", "Book the venue"]`}},
+			"stop_reason": "end_turn",
+		})
+	}))
+	defer server.Close()
+
+	h := NewClaudeHandler("", "", config.Claude{
+		APIKey:      "key",
+		BaseURL:     server.URL,
+		Model:       "claude-3-5-sonnet-20241022",
+		MaxTokens:   1024,
+		Temperature: 1,
+		Timeout:     config.Duration{Duration: 5 * time.Second},
+	})
+	h.SetRequestLimits([]string{"claude-3-5-haiku-latest"}, 4096)
+
+	call := func(body string) int {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		h.GenerateSubtasks(ctx)
+		if len(ctx.Errors) > 0 {
+			return ctx.Errors.Last().Err.(*utils.AppError).HTTPStatus
+		}
+		return recorder.Code
+	}
+
+	if code := call(`{"task_title":"Plan offsite","user_id":"u1"}`); code != http.StatusOK ||
+		sent["model"] != "claude-3-5-sonnet-20241022" || sent["max_tokens"] != 1024.0 || sent["temperature"] != 1.0 {
+		t.Fatalf("defaults: %d, sent %v", code, sent)
+	}
+	if code := call(`{"task_title":"Plan offsite","user_id":"u1","model":"claude-3-5-haiku-latest","max_tokens":4096,"temperature":0}`); code != http.StatusOK ||
+		sent["model"] != "claude-3-5-haiku-latest" || sent["max_tokens"] != 4096.0 || sent["temperature"] != 0.0 {
+		t.Fatalf("overrides: %d, sent %v", code, sent)
+	}
+
+	for _, body := range []string{
+		`{"task_title":"Plan offsite","user_id":"u1","model":"claude-3-opus-20240229"}`,
+		`{"task_title":"Plan offsite","user_id":"u1","max_tokens":8192}`,
+		`{"task_title":"Plan offsite","user_id":"u1","temperature":1.5}`,
+	} {
+		sent = nil
+		if code := call(body); code != http.StatusBadRequest || sent != nil {
+			t.Errorf("%s: expected 400 without calling Claude, got %d", body, code)
+		}
+	}
+}
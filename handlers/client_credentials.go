@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/productivity/mcp-server/signing"
+)
+
+// clientCredentialScopes are the scopes each client may get with the
+// client_credentials grant; clients not listed cannot use it
+var clientCredentialScopes = map[string][]string{}
+
+// SetClientCredentials allows the client_credentials grant for the clients
+// of entries, each client_id=scopes with the scopes space-separated
+func SetClientCredentials(entries []string) {
+	allowed := make(map[string][]string, len(entries))
+	for _, entry := range entries {
+		clientID, scopes, ok := strings.Cut(entry, "=")
+		if clientID = strings.TrimSpace(clientID); ok && clientID != "" {
+			allowed[clientID] = append(allowed[clientID], strings.Fields(scopes)...)
+		}
+	}
+	clientCredentialScopes = allowed
+}
+
+// clientCredentialsToken issues a confidential client a token of its own,
+// for a backend service that acts as itself rather than as a signed-in user
+// (RFC 6749 section 4.4). The token carries the service role, so a client
+// allowed act_as may act for the users its requests name. No refresh token
+// is issued; the client authenticates again instead.
+func clientCredentialsToken(c *gin.Context, req OAuthTokenRequest) {
+	if req.ClientID == "" || req.ClientSecret == "" || !validateClient(req.ClientID, req.ClientSecret) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "invalid_client",
+			"error_description": "client_credentials requires a valid client_id and client_secret",
+		})
+		return
+	}
+	allowed, ok := clientCredentialScopes[req.ClientID]
+	if client := lookupClient(req.ClientID); !ok || client == nil || client.Public() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "unauthorized_client",
+			"error_description": "this client may not use the client_credentials grant",
+		})
+		return
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !containsString(allowed, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_scope",
+				"error_description": "this client may not be granted " + scope,
+			})
+			return
+		}
+	}
+	if resource := signingKeys.Audience(); req.Resource != "" && resource != "" && !sameResource(req.Resource, resource) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_target",
+			"error_description": "resource must be " + resource,
+		})
+		return
+	}
+
+	jti, err := newTokenID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "Failed to generate access token",
+		})
+		return
+	}
+	scope := strings.Join(scopes, " ")
+	claims := jwt.MapClaims{
+		"jti":       jti,
+		"sub":       req.ClientID,
+		"client_id": req.ClientID,
+		"role":      signing.ServiceRole,
+		"scope":     scope,
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(time.Duration(AccessTokenExpiration) * time.Second).Unix(),
+	}
+	token, err := signingKeys.Sign(claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "Failed to generate access token",
+		})
+		return
+	}
+	recordSession(claims)
+	oauthTrace(c, "OAuthToken client_credentials issued", map[string]interface{}{
+		"clientID": req.ClientID,
+		"scope":    scope,
+	})
+
+	c.JSON(http.StatusOK, OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   AccessTokenExpiration,
+		Scope:       scope,
+	})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/streaks"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// velocityWeeks is how many weeks, the current one included, weekly velocity covers
+const velocityWeeks = 8
+
+// dayLayout formats the calendar days stored for completion stats
+const dayLayout = "2006-01-02"
+
+// recordActivity adds a completion to the user's running stats. A user
+// without stats yet gets them built once from their completion history,
+// which already includes this completion.
+func recordActivity(client *db.SupabaseClient, userID string, completedAt time.Time) error {
+	prefs, err := loadPreferences(client, userID)
+	if err != nil {
+		return err
+	}
+	loc := preferencesLocation(prefs)
+	row, err := client.GetCompletionStats(userID)
+	if err != nil {
+		return err
+	}
+	if row == nil {
+		_, err := seedActivity(client, userID, prefs)
+		return err
+	}
+
+	day := startOfDay(completedAt.In(loc))
+	if err := client.UpsertCompletionStats(userID, activityRow(activityFromRow(row, loc).Record(day))); err != nil {
+		return err
+	}
+
+	week := weekStartDay(day, prefs)
+	completed := 0
+	rows, err := client.GetWeeklyCompletions(userID, week)
+	if err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if rowString(r, "week_start") == week.Format(dayLayout) {
+			completed = rowInt(r, "completed")
+		}
+	}
+	return client.UpsertWeeklyCompletions(userID, week, completed+1)
+}
+
+// seedActivity builds and saves a user's stats from their completion history
+func seedActivity(client *db.SupabaseClient, userID string, prefs models.Preferences) (streaks.Activity, error) {
+	loc := preferencesLocation(prefs)
+	completions, err := client.GetCompletionsSince(userID, time.Now().Add(-streaks.MaxLookback))
+	if err != nil {
+		return streaks.Activity{}, err
+	}
+
+	var activity streaks.Activity
+	weeks := make(map[time.Time]int)
+	for _, completion := range completions {
+		at, ok := rowTime(completion, "completed_at")
+		if !ok {
+			continue
+		}
+		day := startOfDay(at.In(loc))
+		activity = activity.Record(day)
+		weeks[weekStartDay(day, prefs)]++
+	}
+
+	for week, completed := range weeks {
+		if err := client.UpsertWeeklyCompletions(userID, week, completed); err != nil {
+			return activity, err
+		}
+	}
+	return activity, client.UpsertCompletionStats(userID, activityRow(activity))
+}
+
+// completionStats returns the user's streak, badges and weekly velocity from
+// their running stats
+func completionStats(client *db.SupabaseClient, userID string) (*models.CompletionStats, error) {
+	now, prefs, err := userNow(client, userID)
+	if err != nil {
+		return nil, err
+	}
+	row, err := client.GetCompletionStats(userID)
+	if err != nil {
+		return nil, err
+	}
+	var activity streaks.Activity
+	if row == nil {
+		if activity, err = seedActivity(client, userID, prefs); err != nil {
+			return nil, err
+		}
+	} else {
+		activity = activityFromRow(row, now.Location())
+	}
+
+	stats := &models.CompletionStats{
+		CurrentStreak:  activity.CurrentOn(now),
+		LongestStreak:  activity.Longest,
+		TotalCompleted: activity.Total,
+		Badges:         []models.Badge{},
+		Timezone:       now.Location().String(),
+	}
+	if !activity.LastDay.IsZero() {
+		stats.LastActiveDay = activity.LastDay.Format(dayLayout)
+	}
+	for _, b := range streaks.Badges(activity) {
+		stats.Badges = append(stats.Badges, models.Badge{ID: b.ID, Name: b.Name, Description: b.Description})
+	}
+
+	thisWeek := weekStartDay(startOfDay(now), prefs)
+	first := thisWeek.AddDate(0, 0, -7*(velocityWeeks-1))
+	rows, err := client.GetWeeklyCompletions(userID, first)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, r := range rows {
+		counts[rowString(r, "week_start")] = rowInt(r, "completed")
+	}
+	finished := 0
+	for week := first; !week.After(thisWeek); week = week.AddDate(0, 0, 7) {
+		key := week.Format(dayLayout)
+		stats.WeeklyVelocity = append(stats.WeeklyVelocity, models.WeekCompletions{WeekStart: key, Completed: counts[key]})
+		if week.Before(thisWeek) {
+			finished += counts[key]
+		}
+	}
+	stats.AveragePerWeek = float64(finished) / float64(velocityWeeks-1)
+	return stats, nil
+}
+
+// weekStartDay returns the first day of day's week, by the user's week start
+func weekStartDay(day time.Time, prefs models.Preferences) time.Time {
+	first := dateOptions(prefs).WeekStart
+	return day.AddDate(0, 0, -((int(day.Weekday()) - int(first) + 7) % 7))
+}
+
+func activityFromRow(row map[string]interface{}, loc *time.Location) streaks.Activity {
+	activity := streaks.Activity{
+		Current: rowInt(row, "current_streak"),
+		Longest: rowInt(row, "longest_streak"),
+		Total:   rowInt(row, "total_completed"),
+	}
+	if day := rowString(row, "last_active_day"); len(day) >= len(dayLayout) {
+		activity.LastDay, _ = time.ParseInLocation(dayLayout, day[:len(dayLayout)], loc)
+	}
+	return activity
+}
+
+func activityRow(activity streaks.Activity) map[string]interface{} {
+	row := map[string]interface{}{
+		"current_streak":  activity.Current,
+		"longest_streak":  activity.Longest,
+		"total_completed": activity.Total,
+		"last_active_day": nil,
+		"updated_at":      time.Now().UTC().Format(time.RFC3339),
+	}
+	if !activity.LastDay.IsZero() {
+		row["last_active_day"] = activity.LastDay.Format(dayLayout)
+	}
+	return row
+}
+
+// GetCompletionStats returns the user's daily completion streak, badges and
+// weekly velocity
+// GET /api/stats/streaks
+func (h *StreakHandler) GetCompletionStats(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	stats, err := completionStats(h.supabaseClient.WithContext(c.Request.Context()), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,76 @@
+//go:build lite
+
+package handlers
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+)
+
+func TestCompletionStatsAreKeptIncrementally(t *testing.T) {
+	dbURL := db.SQLiteScheme + filepath.Join(t.TempDir(), "stats.db")
+	client, err := db.NewSupabaseClient(dbURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := startOfDay(time.Now().UTC())
+	taskID, err := client.CreateTask("u1", map[string]interface{}{"title": "Stretch", "due_date": today.Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// History from before stats were kept: a three-day run ending three days ago
+	for _, daysAgo := range []int{5, 4, 3, 3} {
+		if err := client.RecordCompletion("u1", taskID, today.AddDate(0, 0, -daysAgo).Add(10*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := completionStats(client, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.CurrentStreak != 0 || stats.LongestStreak != 3 || stats.TotalCompleted != 4 {
+		t.Fatalf("seeded stats: %+v", stats)
+	}
+	if len(stats.WeeklyVelocity) != velocityWeeks {
+		t.Fatalf("expected %d weeks of velocity, got %v", velocityWeeks, stats.WeeklyVelocity)
+	}
+	if !hasBadge(stats.Badges, "streak_3") || hasBadge(stats.Badges, "streak_7") {
+		t.Errorf("unexpected badges %v", stats.Badges)
+	}
+
+	// Completions from now on update the saved stats without the history
+	for _, daysAgo := range []int{1, 0} {
+		if err := recordActivity(client, "u1", today.AddDate(0, 0, -daysAgo).Add(9*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err = completionStats(client, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.CurrentStreak != 2 || stats.LongestStreak != 3 || stats.TotalCompleted != 6 || stats.LastActiveDay != today.Format(dayLayout) {
+		t.Fatalf("updated stats: %+v", stats)
+	}
+	total := 0
+	for _, week := range stats.WeeklyVelocity {
+		total += week.Completed
+	}
+	if total != 6 {
+		t.Errorf("expected the weekly counts to add up to 6, got %v", stats.WeeklyVelocity)
+	}
+}
+
+func hasBadge(badges []models.Badge, id string) bool {
+	for _, b := range badges {
+		if b.ID == id {
+			return true
+		}
+	}
+	return false
+}
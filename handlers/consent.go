@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// defaultScope is what a client gets when it asks for no scope
+const defaultScope = "read write"
+
+// placeholderUserID stands in for the user approving an authorization when
+// the request carries no credential; the authorize flow has no login yet
+const placeholderUserID = "user_id_from_session"
+
+// scopeDescriptions say what each scope lets a client do, as the consent
+// screen shows it
+var scopeDescriptions = map[string]string{
+	"read":     "See your tasks, goals, milestones and focus sessions",
+	"write":    "Create, change and delete your tasks, goals and milestones",
+	"mcp":      "Use your productivity tools from an MCP client",
+	"claudeai": "Connect your productivity data to Claude",
+}
+
+// grantStore persists the scopes users approved for each client; nil keeps
+// them in memoryGrants
+var grantStore *db.SupabaseClient
+
+var (
+	memoryGrantsMu sync.RWMutex
+	memoryGrants   = make(map[string]map[string]interface{})
+)
+
+// SetGrantStore persists approved OAuth grants in Supabase so they survive
+// restarts and are shared between instances
+func SetGrantStore(supabaseURL, supabaseKey string) {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	grantStore = client
+}
+
+func grantKey(userID, clientID string) string {
+	return userID + "\x00" + clientID
+}
+
+// getGrant returns what userID approved for clientID, nil if nothing
+func getGrant(c *gin.Context, userID, clientID string) (map[string]interface{}, error) {
+	if grantStore == nil {
+		memoryGrantsMu.RLock()
+		defer memoryGrantsMu.RUnlock()
+		return memoryGrants[grantKey(userID, clientID)], nil
+	}
+	return grantStore.WithContext(c.Request.Context()).GetOAuthGrant(userID, clientID)
+}
+
+// saveGrant records the scopes userID approved for clientID
+func saveGrant(c *gin.Context, userID, clientID, scope string) (map[string]interface{}, error) {
+	if grantStore == nil {
+		memoryGrantsMu.Lock()
+		defer memoryGrantsMu.Unlock()
+		now := time.Now().UTC().Format(time.RFC3339)
+		key := grantKey(userID, clientID)
+		createdAt := now
+		if existing, ok := memoryGrants[key]; ok {
+			createdAt = rowString(existing, "created_at")
+		}
+		row := map[string]interface{}{
+			"user_id":    userID,
+			"client_id":  clientID,
+			"scope":      scope,
+			"created_at": createdAt,
+			"updated_at": now,
+		}
+		memoryGrants[key] = row
+		return row, nil
+	}
+	return grantStore.WithContext(c.Request.Context()).UpsertOAuthGrant(userID, clientID, scope)
+}
+
+// listGrants returns every grant userID made, most recent first
+func listGrants(c *gin.Context, userID string) ([]map[string]interface{}, error) {
+	if grantStore == nil {
+		memoryGrantsMu.RLock()
+		defer memoryGrantsMu.RUnlock()
+		grants := []map[string]interface{}{}
+		for _, row := range memoryGrants {
+			if rowString(row, "user_id") == userID {
+				grants = append(grants, row)
+			}
+		}
+		sort.Slice(grants, func(i, j int) bool {
+			return rowString(grants[i], "updated_at") > rowString(grants[j], "updated_at")
+		})
+		return grants, nil
+	}
+	return grantStore.WithContext(c.Request.Context()).ListOAuthGrants(userID)
+}
+
+// deleteGrant forgets what userID approved for clientID, reporting whether
+// there was anything to forget
+func deleteGrant(c *gin.Context, userID, clientID string) (bool, error) {
+	if grantStore == nil {
+		memoryGrantsMu.Lock()
+		defer memoryGrantsMu.Unlock()
+		key := grantKey(userID, clientID)
+		_, ok := memoryGrants[key]
+		delete(memoryGrants, key)
+		return ok, nil
+	}
+	n, err := grantStore.WithContext(c.Request.Context()).DeleteOAuthGrant(userID, clientID)
+	return n > 0, err
+}
+
+// requestedScopes splits a scope parameter, giving defaultScope for none
+func requestedScopes(scope string) []string {
+	if strings.TrimSpace(scope) == "" {
+		scope = defaultScope
+	}
+	return strings.Fields(scope)
+}
+
+// mergeScopes adds the requested scopes to the granted ones
+func mergeScopes(granted, requested string) string {
+	scopes := strings.Fields(granted)
+	for _, scope := range requestedScopes(requested) {
+		if !containsString(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return strings.Join(scopes, " ")
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// grantCovers reports whether userID already approved every scope clientID
+// asks for. A failed lookup asks the user again.
+func grantCovers(c *gin.Context, userID, clientID, scope string) bool {
+	grant, err := getGrant(c, userID, clientID)
+	if err != nil {
+		utils.LoggerFromContext(c.Request.Context()).Error("Failed to look up OAuth grant", err, map[string]interface{}{"client_id": clientID})
+		return false
+	}
+	if grant == nil {
+		return false
+	}
+	granted := strings.Fields(rowString(grant, "scope"))
+	for _, s := range requestedScopes(scope) {
+		if !containsString(granted, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// authorizingUser is who approves an authorization request: the signed-in
+// caller, or the placeholder user while the flow has no login
+func authorizingUser(c *gin.Context) string {
+	if userID := c.GetString("user_id"); userID != "" {
+		return userID
+	}
+	return placeholderUserID
+}
+
+// pendingConsent is an authorization request waiting on the user's answer
+type pendingConsent struct {
+	data      *AuthCodeData
+	expiresAt time.Time
+}
+
+var (
+	pendingConsentsMu sync.Mutex
+	pendingConsents   = make(map[string]pendingConsent)
+)
+
+// storePendingConsent keeps data until the user answers, for as long as an
+// auth code would live, and returns the ID the consent form posts back
+func storePendingConsent(data *AuthCodeData) (string, error) {
+	id, err := randomToken(24)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	pendingConsentsMu.Lock()
+	defer pendingConsentsMu.Unlock()
+	for key, pending := range pendingConsents {
+		if now.After(pending.expiresAt) {
+			delete(pendingConsents, key)
+		}
+	}
+	pendingConsents[id] = pendingConsent{data: data, expiresAt: now.Add(time.Duration(AuthCodeExpiration) * time.Second)}
+	return id, nil
+}
+
+// takePendingConsent returns and forgets the request id names, nil if it is
+// unknown or expired. Each ID can be answered once.
+func takePendingConsent(id string) *AuthCodeData {
+	pendingConsentsMu.Lock()
+	defer pendingConsentsMu.Unlock()
+	pending, ok := pendingConsents[id]
+	delete(pendingConsents, id)
+	if !ok || time.Now().After(pending.expiresAt) {
+		return nil
+	}
+	return pending.data
+}
+
+var consentPage = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Authorize {{.ClientName}}</title>
+  <style>
+    body { font-family: system-ui, sans-serif; max-width: 28rem; margin: 4rem auto; padding: 0 1rem; color: #222; }
+    ul { padding-left: 1.2rem; }
+    li { margin: 0.4rem 0; }
+    .host { color: #666; font-size: 0.9rem; }
+    button { font-size: 1rem; padding: 0.5rem 1.2rem; margin-right: 0.5rem; }
+  </style>
+</head>
+<body>
+  <h1>Authorize {{.ClientName}}</h1>
+  <p><strong>{{.ClientName}}</strong> wants to access your productivity account. It will be able to:</p>
+  <ul>
+    {{range .Scopes}}<li>{{.}}</li>
+    {{end}}
+  </ul>
+  <p class="host">You will be sent back to {{.RedirectHost}}.</p>
+  <form method="post" action="{{.Action}}">
+    <input type="hidden" name="consent_id" value="{{.ConsentID}}">
+    <button type="submit" name="decision" value="approve">Allow</button>
+    <button type="submit" name="decision" value="deny">Deny</button>
+  </form>
+</body>
+</html>
+`))
+
+// consentView fills in consentPage
+type consentView struct {
+	ClientName   string
+	Scopes       []string
+	RedirectHost string
+	Action       string
+	ConsentID    string
+}
+
+// showConsent asks the user to approve data's request
+func showConsent(c *gin.Context, data *AuthCodeData) {
+	consentID, err := storePendingConsent(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "Failed to start consent",
+		})
+		return
+	}
+
+	view := consentView{
+		ClientName: data.ClientID,
+		Action:     c.Request.URL.Path + "/consent",
+		ConsentID:  consentID,
+	}
+	if client := lookupClient(data.ClientID); client != nil && client.Name != "" {
+		view.ClientName = client.Name
+	}
+	for _, scope := range requestedScopes(data.Scope) {
+		if description, ok := scopeDescriptions[scope]; ok {
+			view.Scopes = append(view.Scopes, description)
+		} else {
+			view.Scopes = append(view.Scopes, scope)
+		}
+	}
+	if redirectURL, err := url.Parse(data.RedirectURI); err == nil {
+		view.RedirectHost = redirectURL.Host
+		if view.RedirectHost == "" {
+			view.RedirectHost = redirectURL.Scheme + ":"
+		}
+	}
+
+	oauthTrace(c, "OAuthAuthorize consent required", map[string]interface{}{
+		"clientID": data.ClientID,
+		"scope":    data.Scope,
+	})
+	// The page must not be framed, where a click could be tricked out of the
+	// user, nor cached with its consent ID
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "frame-ancestors 'none'")
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := consentPage.Execute(c.Writer, view); err != nil {
+		utils.LoggerFromContext(c.Request.Context()).Error("Failed to render consent page", err)
+	}
+}
+
+// OAuthConsent takes the user's answer to the consent screen. Approving
+// remembers the scopes for the client and redirects back with a code;
+// denying redirects back with access_denied.
+// POST /authorize/consent and /oauth/authorize/consent
+func OAuthConsent(c *gin.Context) {
+	data := takePendingConsent(c.PostForm("consent_id"))
+	if data == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "The authorization request expired or was already answered; start again",
+		})
+		return
+	}
+	if authorizingUser(c) != data.UserID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":             "access_denied",
+			"error_description": "The authorization request was started by another user",
+		})
+		return
+	}
+
+	if c.PostForm("decision") != "approve" {
+		oauthTrace(c, "OAuthConsent denied", map[string]interface{}{"clientID": data.ClientID})
+		redirectURL, err := url.Parse(data.RedirectURI)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "access_denied",
+				"error_description": "The user denied the request",
+			})
+			return
+		}
+		q := redirectURL.Query()
+		q.Set("error", "access_denied")
+		q.Set("error_description", "The user denied the request")
+		if data.State != "" {
+			q.Set("state", data.State)
+		}
+		redirectURL.RawQuery = q.Encode()
+		c.Redirect(http.StatusFound, redirectURL.String())
+		return
+	}
+
+	before, err := getGrant(c, data.UserID, data.ClientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "Failed to record consent",
+		})
+		return
+	}
+	after, err := saveGrant(c, data.UserID, data.ClientID, mergeScopes(rowString(before, "scope"), data.Scope))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "Failed to record consent",
+		})
+		return
+	}
+	action := AuditActionCreate
+	if before != nil {
+		action = AuditActionUpdate
+	}
+	recordAudit(c, AuditEntityOAuthGrant, data.ClientID, action, before, after)
+	issueAuthCode(c, data)
+}
+
+// ListAuthorizations lists the clients the user approved and the scopes
+// they approved for each
+// GET /api/authorizations
+func ListAuthorizations(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	grants, err := listGrants(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	authorizations := make([]gin.H, 0, len(grants))
+	for _, grant := range grants {
+		clientID := rowString(grant, "client_id")
+		name := clientID
+		if client := lookupClient(clientID); client != nil && client.Name != "" {
+			name = client.Name
+		}
+		authorizations = append(authorizations, gin.H{
+			"client_id":   clientID,
+			"client_name": name,
+			"scopes":      strings.Fields(rowString(grant, "scope")),
+			"created_at":  grant["created_at"],
+			"updated_at":  grant["updated_at"],
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"authorizations": authorizations, "count": len(authorizations)})
+}
+
+// RevokeAuthorization forgets what the user approved for a client, so its
+// next authorization asks again, and revokes the client's active tokens
+// DELETE /api/authorizations/:client_id
+func RevokeAuthorization(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	clientID := c.Param("client_id")
+	before, err := getGrant(c, userID, clientID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	deleted, err := deleteGrant(c, userID, clientID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !deleted {
+		c.Error(utils.ErrNotFound("authorization"))
+		return
+	}
+	recordAudit(c, AuditEntityOAuthGrant, clientID, AuditActionRevoke, before, nil)
+
+	revoked := 0
+	if sessionStore != nil && tokenRevocations != nil {
+		sessions, err := sessionStore.WithContext(c.Request.Context()).ListSessions(map[string]string{"user_id": userID, "client_id": clientID}, true, 500)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		for _, session := range sessions {
+			expiresAt, ok := rowTime(session, "expires_at")
+			if !ok {
+				expiresAt = time.Now().Add(time.Duration(AccessTokenExpiration) * time.Second)
+			}
+			if err := tokenRevocations.Revoke(c.Request.Context(), rowString(session, "jti"), userID, "authorization revoked", expiresAt); err != nil {
+				c.Error(err)
+				return
+			}
+			revoked++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"client_id": clientID, "revoked": true, "revoked_tokens": revoked})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// rememberGrant approves scope for clientID as the placeholder user, so
+// authorizing skips the consent screen
+func rememberGrant(t *testing.T, clientID, scope string) {
+	t.Helper()
+	key := grantKey(placeholderUserID, clientID)
+	memoryGrantsMu.Lock()
+	memoryGrants[key] = map[string]interface{}{"user_id": placeholderUserID, "client_id": clientID, "scope": scope}
+	memoryGrantsMu.Unlock()
+	t.Cleanup(func() {
+		memoryGrantsMu.Lock()
+		delete(memoryGrants, key)
+		memoryGrantsMu.Unlock()
+	})
+}
+
+func TestOAuthConsent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() {
+		memoryGrantsMu.Lock()
+		delete(memoryGrants, grantKey(placeholderUserID, "mcp_client"))
+		memoryGrantsMu.Unlock()
+	})
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(utils.NewLogger()))
+	router.GET("/authorize", OAuthAuthorize)
+	router.POST("/authorize/consent", OAuthConsent)
+	router.GET("/api/authorizations", func(c *gin.Context) { c.Set("user_id", placeholderUserID) }, ListAuthorizations)
+	router.DELETE("/api/authorizations/:client_id", func(c *gin.Context) { c.Set("user_id", placeholderUserID) }, RevokeAuthorization)
+
+	consentIDPattern := regexp.MustCompile(`name="consent_id" value="([^"]+)"`)
+	authorize := func(scope, prompt string) *httptest.ResponseRecorder {
+		q := url.Values{"client_id": {"mcp_client"}, "redirect_uri": {"http://localhost"}, "response_type": {"code"}, "state": {"s1"}, "scope": {scope}}
+		if prompt != "" {
+			q.Set("prompt", prompt)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authorize?"+q.Encode(), nil))
+		return w
+	}
+	answer := func(consentID, decision string) *url.URL {
+		form := url.Values{"consent_id": {consentID}, "decision": {decision}}
+		req := httptest.NewRequest(http.MethodPost, "/authorize/consent", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		location, err := url.Parse(w.Header().Get("Location"))
+		if w.Code != http.StatusFound || err != nil {
+			t.Fatalf("consent %s: %d %s", decision, w.Code, w.Body)
+		}
+		return location
+	}
+	consentID := func(w *httptest.ResponseRecorder) string {
+		t.Helper()
+		m := consentIDPattern.FindStringSubmatch(w.Body.String())
+		if w.Code != http.StatusOK || m == nil {
+			t.Fatalf("want the consent screen, got %d %s", w.Code, w.Body)
+		}
+		if w.Header().Get("X-Frame-Options") != "DENY" {
+			t.Error("the consent screen can be framed")
+		}
+		return m[1]
+	}
+
+	// The screen names the client and what each scope allows
+	w := authorize("read", "")
+	id := consentID(w)
+	if body := w.Body.String(); !strings.Contains(body, "MCP Client") || !strings.Contains(body, scopeDescriptions["read"]) {
+		t.Errorf("consent screen does not describe the request: %s", body)
+	}
+	if got := answer(id, "deny").Query(); got.Get("error") != "access_denied" || got.Get("state") != "s1" {
+		t.Errorf("deny redirected with %v", got)
+	}
+
+	// Approving issues a code and remembers the grant, so asking for no more
+	// than it skips the screen
+	if got := answer(consentID(authorize("read", "")), "approve").Query(); got.Get("code") == "" {
+		t.Errorf("approve redirected with %v", got)
+	}
+	if w := authorize("read", ""); w.Code != http.StatusFound {
+		t.Errorf("a remembered grant asked again: %d", w.Code)
+	}
+	// More scopes, or prompt=consent, ask again
+	consentID(authorize("read write", ""))
+	id = consentID(authorize("read", "consent"))
+	answer(id, "approve")
+	form := url.Values{"consent_id": {id}, "decision": {"approve"}}
+	req := httptest.NewRequest(http.MethodPost, "/authorize/consent", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("answering twice: %d, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/authorizations", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"client_id":"mcp_client"`) {
+		t.Fatalf("list: %d %s", w.Code, w.Body)
+	}
+
+	// Revoking forgets the grant
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/authorizations/mcp_client", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body)
+	}
+	consentID(authorize("read", ""))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/authorizations/mcp_client", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("revoking again: %d, want 404", w.Code)
+	}
+}
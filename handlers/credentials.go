@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/secrets"
+)
+
+// CredentialStore keeps the secrets users hand the server, such as
+// integration tokens, in user_credentials sealed under the master keys.
+// Names say what a credential is for, e.g. "integration:notion".
+type CredentialStore struct {
+	supabaseClient *db.SupabaseClient
+	keyring        *secrets.Keyring
+}
+
+// NewCredentialStore creates a credential store sealing with keyring
+func NewCredentialStore(client *db.SupabaseClient, keyring *secrets.Keyring) *CredentialStore {
+	return &CredentialStore{supabaseClient: client, keyring: keyring}
+}
+
+// credentialAAD binds a sealed credential to its owner and name, so a row
+// copied elsewhere does not open
+func credentialAAD(userID, name string) []byte {
+	return []byte(userID + "\x00" + name)
+}
+
+// Put seals and stores a user's credential, replacing any of the same name
+func (s *CredentialStore) Put(ctx context.Context, userID, name string, value []byte) error {
+	env, err := s.keyring.Seal(value, credentialAAD(userID, name))
+	if err != nil {
+		return err
+	}
+	return s.save(ctx, userID, name, env)
+}
+
+// Get returns a user's credential, false if there is none. A credential
+// still sealed under a rotated-out master key is rewrapped under the
+// primary one as it is read.
+func (s *CredentialStore) Get(ctx context.Context, userID, name string) ([]byte, bool, error) {
+	row, err := s.supabaseClient.WithContext(ctx).GetUserCredential(userID, name)
+	if err != nil || row == nil {
+		return nil, false, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(rowString(row, "wrapped_key"))
+	if err != nil {
+		return nil, false, fmt.Errorf("credential %s: %w", name, err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(rowString(row, "ciphertext"))
+	if err != nil {
+		return nil, false, fmt.Errorf("credential %s: %w", name, err)
+	}
+	env := secrets.Envelope{KeyID: rowString(row, "key_id"), WrappedKey: wrapped, Ciphertext: ciphertext}
+	value, err := s.keyring.Open(env, credentialAAD(userID, name))
+	if err != nil {
+		return nil, false, fmt.Errorf("credential %s: %w", name, err)
+	}
+	if env.KeyID != s.keyring.PrimaryKeyID() {
+		if rewrapped, err := s.keyring.Rewrap(env); err == nil {
+			// Failing to save only postpones the rewrap to the next read
+			s.save(ctx, userID, name, rewrapped)
+		}
+	}
+	return value, true, nil
+}
+
+// Delete forgets a user's credential
+func (s *CredentialStore) Delete(ctx context.Context, userID, name string) error {
+	return s.supabaseClient.WithContext(ctx).DeleteUserCredential(userID, name)
+}
+
+func (s *CredentialStore) save(ctx context.Context, userID, name string, env secrets.Envelope) error {
+	return s.supabaseClient.WithContext(ctx).UpsertUserCredential(userID, name, map[string]interface{}{
+		"key_id":      env.KeyID,
+		"wrapped_key": base64.StdEncoding.EncodeToString(env.WrappedKey),
+		"ciphertext":  base64.StdEncoding.EncodeToString(env.Ciphertext),
+		"updated_at":  time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+	// Time zones resolve on hosts without a zoneinfo database, such as the Alpine image
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/dates"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// loadTimezone loads an IANA time zone name such as "Europe/Berlin"; empty means UTC
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// validateTimezone checks an explicitly requested time zone
+func validateTimezone(v *validation.Validator, name string) {
+	_, err := loadTimezone(name)
+	v.Check(err == nil, "timezone", validation.CodeInvalidValue, "timezone must be an IANA time zone name, e.g. Europe/Berlin")
+}
+
+// parseDate resolves the first date phrase in req.Text relative to the
+// current time in req.Timezone, or in userID's own time zone when the request
+// names none
+func parseDate(ctx context.Context, userID string, req models.ParseDateRequest) (*models.ParseDateResponse, error) {
+	var v validation.Validator
+	v.Required("text", req.Text)
+	validateTimezone(&v, req.Timezone)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	prefs, err := userPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Timezone != "" {
+		prefs.Timezone = req.Timezone
+	}
+	loc := preferencesLocation(prefs)
+	response := &models.ParseDateResponse{Timezone: loc.String()}
+	if match, ok := dates.ParseWith(req.Text, time.Now().In(loc), dateOptions(prefs)); ok {
+		response.Found = true
+		response.Date = &match.Time
+		response.HasTime = match.HasTime
+		response.Matched = match.Text
+		response.Remainder = match.Strip(req.Text)
+	}
+	return response, nil
+}
+
+// ParseDate resolves a natural language date such as "next Friday 3pm"
+// without calling Claude
+// POST /api/dates/parse
+func ParseDate(c *gin.Context) {
+	var req models.ParseDateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	response, err := parseDate(c.Request.Context(), getUserID(c), req)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
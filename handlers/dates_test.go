@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/models"
+)
+
+func TestParseDateEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	call := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/dates/parse", strings.NewReader(body))
+		ParseDate(c)
+		return recorder
+	}
+
+	recorder := call(`{"text":"Dentist next Friday at 3:30pm","timezone":"America/New_York"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body)
+	}
+	var parsed models.ParseDateResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Found || parsed.Date == nil || !parsed.HasTime || parsed.Remainder != "Dentist" || parsed.Timezone != "America/New_York" {
+		t.Fatalf("unexpected response %+v", parsed)
+	}
+	ny, _ := loadTimezone("America/New_York")
+	if local := parsed.Date.In(ny); local.Weekday().String() != "Friday" || local.Hour() != 15 || local.Minute() != 30 {
+		t.Errorf("expected Friday 15:30 in New York, got %s", local)
+	}
+
+	if recorder := call(`{"text":"Buy milk"}`); !strings.Contains(recorder.Body.String(), `"found":false`) {
+		t.Errorf("expected no date, got %s", recorder.Body)
+	}
+	if _, err := parseDate(context.Background(), "", models.ParseDateRequest{Text: "tomorrow", Timezone: "Mars/Olympus"}); err == nil {
+		t.Error("expected an unknown time zone to be rejected")
+	}
+}
+
+func TestParseTaskResolvesDatesWithoutClaude(t *testing.T) {
+	// No API key, so ParseTask falls back to local parsing
+	service := NewAIService("", "", config.Claude{})
+	parsed := service.ParseTask(context.Background(), models.ParseTaskRequest{
+		Input:    "Call Sam tomorrow at 5pm",
+		UserID:   "u1",
+		Timezone: "Asia/Tokyo",
+	})
+
+	if parsed.Task.Title != "Call Sam" {
+		t.Errorf("expected the date phrase removed from the title, got %q", parsed.Task.Title)
+	}
+	due := parsed.Task.DueDate
+	if due.Location().String() != "Asia/Tokyo" || due.Hour() != 17 || due.Minute() != 0 {
+		t.Errorf("expected 17:00 Tokyo time, got %s", due)
+	}
+}
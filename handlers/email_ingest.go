@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+const (
+	// MaxInboundEmailBytes bounds a webhook post; providers cap messages at 25-30 MB
+	MaxInboundEmailBytes = 32 << 20
+	// maxAttachmentBytes is the largest attachment read and sent to ParseFile
+	maxAttachmentBytes = 1 << 20
+	// emailIngestLocalPart prefixes the token in an ingest address: tasks+TOKEN@domain
+	emailIngestLocalPart = "tasks"
+	// emailDefaultDueIn is when a task from an email falls due if its subject names no date
+	emailDefaultDueIn = 24 * time.Hour
+)
+
+var (
+	// replyPrefix matches the "Fwd:" and "Re:" prefixes mail clients add to subjects
+	replyPrefix = regexp.MustCompile(`(?i)^\s*(fwd?|re|aw|wg|tr)\s*:\s*`)
+	htmlTag     = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+	blankLines  = regexp.MustCompile(`\n\s*\n\s*\n+`)
+)
+
+// textAttachmentTypes are the attachment extensions ParseFile can read
+var textAttachmentTypes = map[string]bool{".txt": true, ".md": true, ".markdown": true, ".csv": true, ".tsv": true, ".ics": true}
+
+// EmailHandler turns email forwarded to a user's personal ingest address
+// into tasks. The mail provider (SendGrid Inbound Parse or Mailgun Routes)
+// posts each message to Inbound; the subject is parsed as parse-task does
+// and text attachments as parse-file does.
+type EmailHandler struct {
+	supabaseClient *db.SupabaseClient
+	tasks          *TaskService
+	ai             *AIService
+	domain         string
+	secret         string
+}
+
+// NewEmailHandler creates a new email handler; without an ingest domain and
+// webhook secret in cfg its endpoints answer 503
+func NewEmailHandler(supabaseURL, supabaseKey string, claudeHandler *ClaudeHandler, cfg config.Email) *EmailHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &EmailHandler{
+		supabaseClient: client,
+		tasks:          NewTaskService(client),
+		ai:             claudeHandler.service,
+		domain:         cfg.IngestDomain,
+		secret:         cfg.WebhookSecret,
+	}
+}
+
+// errEmailDisabled answers every email endpoint while ingestion is not configured
+func errEmailDisabled() *utils.AppError {
+	return utils.NewAppError(utils.ErrCodeExternal, "email ingestion is not configured", http.StatusServiceUnavailable)
+}
+
+// inboundEmail is the part of a forwarded message that becomes tasks
+type inboundEmail struct {
+	Recipients  []string
+	From        string
+	Subject     string
+	Text        string
+	Attachments []*multipart.FileHeader
+}
+
+// parseInboundEmail reads the fields SendGrid's Inbound Parse (to, envelope,
+// text, html, attachmentN) and Mailgun's routes (recipient, sender,
+// stripped-text, body-plain, body-html, attachment-N) post
+func parseInboundEmail(form *multipart.Form) inboundEmail {
+	value := func(names ...string) string {
+		for _, name := range names {
+			if values := form.Value[name]; len(values) > 0 && strings.TrimSpace(values[0]) != "" {
+				return strings.TrimSpace(values[0])
+			}
+		}
+		return ""
+	}
+
+	var email inboundEmail
+	var envelope struct {
+		To   []string `json:"to"`
+		From string   `json:"from"`
+	}
+	if raw := value("envelope"); raw != "" && json.Unmarshal([]byte(raw), &envelope) == nil {
+		email.Recipients = append(email.Recipients, envelope.To...)
+	}
+	for _, header := range []string{value("recipient"), value("to"), value("To")} {
+		if header == "" {
+			continue
+		}
+		addresses, err := mail.ParseAddressList(header)
+		if err != nil {
+			email.Recipients = append(email.Recipients, strings.Split(header, ",")...)
+			continue
+		}
+		for _, address := range addresses {
+			email.Recipients = append(email.Recipients, address.Address)
+		}
+	}
+
+	email.From = value("from", "From", "sender")
+	if email.From == "" {
+		email.From = envelope.From
+	}
+	email.Subject = value("subject", "Subject")
+	email.Text = value("stripped-text", "text", "body-plain")
+	if email.Text == "" {
+		email.Text = htmlToText(value("html", "body-html"))
+	}
+
+	var names []string
+	for name := range form.File {
+		if strings.HasPrefix(name, "attachment") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		email.Attachments = append(email.Attachments, form.File[name]...)
+	}
+	return email
+}
+
+// htmlToText reduces an HTML body to its text, for messages sent without a plain part
+func htmlToText(body string) string {
+	text := strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n\n", "</div>", "\n").Replace(body)
+	text = html.UnescapeString(htmlTag.ReplaceAllString(text, ""))
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+}
+
+// cleanSubject drops the Fwd: and Re: prefixes mail clients stack on a subject
+func cleanSubject(subject string) string {
+	for {
+		trimmed := replyPrefix.ReplaceAllString(subject, "")
+		if trimmed == subject {
+			return strings.TrimSpace(subject)
+		}
+		subject = trimmed
+	}
+}
+
+// ingestToken returns the token in address if it is an ingest address on
+// domain: tasks+TOKEN@domain, or TOKEN@domain for providers that drop the tag
+func ingestToken(address, domain string) (string, bool) {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		address = parsed.Address
+	}
+	at := strings.LastIndex(address, "@")
+	if at < 0 || !strings.EqualFold(address[at+1:], domain) {
+		return "", false
+	}
+	local := strings.ToLower(strings.TrimSpace(address[:at]))
+	local = strings.TrimPrefix(local, emailIngestLocalPart+"+")
+	if local == "" || local == emailIngestLocalPart {
+		return "", false
+	}
+	return local, true
+}
+
+// address returns the ingest address holding token
+func (h *EmailHandler) address(token string) string {
+	return fmt.Sprintf("%s+%s@%s", emailIngestLocalPart, token, h.domain)
+}
+
+// newIngestToken returns a random token for an ingest address
+func newIngestToken() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// Inbound receives a message from the mail provider's inbound parse webhook,
+// authenticated by the webhook secret in ?key=. Mail to unknown addresses is
+// accepted and dropped, as providers retry anything else.
+// POST /api/email/inbound
+func (h *EmailHandler) Inbound(c *gin.Context) {
+	if h.secret == "" {
+		c.Error(errEmailDisabled())
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("key")), []byte(h.secret)) != 1 {
+		c.Error(utils.ErrUnauthorized("invalid or missing webhook key"))
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxInboundEmailBytes)
+	form, err := c.MultipartForm()
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.Error(utils.NewAppError(utils.ErrCodeTooLarge,
+			fmt.Sprintf("message must be at most %d MB", MaxInboundEmailBytes>>20), http.StatusRequestEntityTooLarge))
+		return
+	case errors.Is(err, http.ErrNotMultipart):
+		// Mailgun posts messages without attachments as a plain form
+		form = &multipart.Form{Value: c.Request.PostForm}
+	case err != nil:
+		c.Error(utils.ErrBadRequest("failed to read the message").WithError(err))
+		return
+	}
+	email := parseInboundEmail(form)
+
+	var userID string
+	for _, recipient := range email.Recipients {
+		token, ok := ingestToken(recipient, h.domain)
+		if !ok {
+			continue
+		}
+		if userID, err = h.supabaseClient.GetEmailIngestUser(token); err != nil {
+			c.Error(utils.ErrInternal("failed to look up ingest address").WithError(err))
+			return
+		}
+		if userID != "" {
+			break
+		}
+	}
+	if userID == "" {
+		c.JSON(http.StatusOK, models.EmailIngestResponse{Status: models.EmailIgnored})
+		return
+	}
+	// Audit entries and events name the user the message was forwarded for
+	c.Set("user_id", userID)
+
+	response := models.EmailIngestResponse{
+		Status:  models.EmailProcessed,
+		Tasks:   []map[string]interface{}{},
+		Skipped: []models.SkippedEmailPart{},
+	}
+	create := func(source string, task models.Task) {
+		created, err := h.tasks.Create(c, userID, emailTaskRequest(task))
+		if err != nil {
+			response.Skipped = append(response.Skipped, models.SkippedEmailPart{Name: source, Reason: clientMessage(err)})
+			return
+		}
+		row := created.Task
+		if row == nil {
+			row = map[string]interface{}{"id": created.ID}
+		}
+		response.Tasks = append(response.Tasks, row)
+	}
+
+	ctx := c.Request.Context()
+	if input := emailTaskInput(email); input != "" {
+		parsed := h.ai.ParseTask(ctx, models.ParseTaskRequest{Input: input, UserID: userID})
+		task := *parsed.Task
+		task.Description = emailDescription(task.Description, email)
+		create("subject", task)
+	}
+	for _, attachment := range email.Attachments {
+		tasks, err := h.parseAttachment(ctx, userID, attachment)
+		if err != nil {
+			response.Skipped = append(response.Skipped, models.SkippedEmailPart{Name: attachment.Filename, Reason: err.Error()})
+			continue
+		}
+		for _, task := range tasks {
+			create(attachment.Filename, task)
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// emailTaskInput is what parse-task reads for a message: its subject, or
+// the first line of its body when it has none
+func emailTaskInput(email inboundEmail) string {
+	if subject := cleanSubject(email.Subject); subject != "" {
+		return subject
+	}
+	for _, line := range strings.Split(email.Text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return truncateRunes(line, validation.MaxTitleLength)
+		}
+	}
+	return ""
+}
+
+// emailDescription keeps the message body, and who sent it, with the task
+func emailDescription(description string, email inboundEmail) string {
+	var b strings.Builder
+	if description != "" {
+		b.WriteString(description + "\n\n")
+	}
+	if email.From != "" {
+		b.WriteString("Forwarded from " + email.From + "\n\n")
+	}
+	b.WriteString(email.Text)
+	return truncateRunes(strings.TrimSpace(b.String()), validation.MaxDescriptionLength)
+}
+
+// emailTaskRequest turns a parsed task into a task to create. Mail has no
+// one to ask about a missing or past due date, so it gets a default.
+func emailTaskRequest(task models.Task) models.CreateTaskRequest {
+	req := models.CreateTaskRequest{
+		Title:       truncateRunes(task.Title, validation.MaxTitleLength),
+		Description: truncateRunes(task.Description, validation.MaxDescriptionLength),
+		Priority:    task.Priority,
+		DueDate:     task.DueDate,
+		Category:    task.Category,
+		Language:    task.Language,
+	}
+	if req.Priority < validation.MinPriority || req.Priority > validation.MaxPriority {
+		req.Priority = 3
+	}
+	if req.DueDate.Before(time.Now()) {
+		req.DueDate = time.Now().Add(emailDefaultDueIn)
+	}
+	return req
+}
+
+// parseAttachment reads a text attachment and parses the tasks in it as
+// parse-file does. Other attachments are skipped with the reason.
+func (h *EmailHandler) parseAttachment(ctx context.Context, userID string, attachment *multipart.FileHeader) ([]models.Task, error) {
+	ext := strings.ToLower(filepath.Ext(attachment.Filename))
+	contentType := attachment.Header.Get("Content-Type")
+	if !textAttachmentTypes[ext] && !strings.HasPrefix(contentType, "text/") {
+		return nil, fmt.Errorf("only text attachments are parsed")
+	}
+	if attachment.Size > maxAttachmentBytes {
+		return nil, fmt.Errorf("attachment is larger than %d KB", maxAttachmentBytes>>10)
+	}
+	file, err := attachment.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, maxAttachmentBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	fileType := strings.TrimPrefix(ext, ".")
+	if fileType == "" {
+		fileType = contentType
+	}
+	parsed := h.ai.ParseFile(ctx, models.ParseFileRequest{
+		FileName:    attachment.Filename,
+		FileContent: string(content),
+		FileType:    fileType,
+		UserID:      userID,
+	}, nil)
+	if len(parsed.Tasks) == 0 {
+		return nil, fmt.Errorf("no tasks found: %s", parsed.Summary)
+	}
+	return parsed.Tasks, nil
+}
+
+// GetAddress returns the user's ingest address, issuing one on first use
+// GET /api/email/address
+func (h *EmailHandler) GetAddress(c *gin.Context) {
+	h.respondAddress(c, false)
+}
+
+// RotateAddress replaces the user's ingest address; mail to the old one is dropped
+// POST /api/email/address/rotate
+func (h *EmailHandler) RotateAddress(c *gin.Context) {
+	h.respondAddress(c, true)
+}
+
+func (h *EmailHandler) respondAddress(c *gin.Context, rotate bool) {
+	if h.domain == "" {
+		c.Error(errEmailDisabled())
+		return
+	}
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required (provide via query param ?user_id=xxx, header X-User-ID, or context)"))
+		return
+	}
+
+	client := h.supabaseClient.WithContext(c.Request.Context())
+	token, err := client.GetEmailIngestToken(userID)
+	if err != nil {
+		c.Error(utils.ErrInternal("failed to get ingest address").WithError(err))
+		return
+	}
+	if token == "" || rotate {
+		if token, err = newIngestToken(); err != nil {
+			c.Error(utils.ErrInternal("failed to issue ingest address").WithError(err))
+			return
+		}
+		if err := client.SetEmailIngestToken(userID, token); err != nil {
+			c.Error(utils.ErrInternal("failed to save ingest address").WithError(err))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, models.EmailAddressResponse{Address: h.address(token)})
+}
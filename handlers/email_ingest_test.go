@@ -0,0 +1,130 @@
+//go:build lite
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/mockllm"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestEmailIngest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+	dbURL := db.SQLiteScheme + filepath.Join(t.TempDir(), "email.db")
+
+	claudeHandler := NewClaudeHandler(dbURL, "", config.Claude{
+		APIKey:    "mock",
+		BaseURL:   llm.URL,
+		Model:     "claude-3-5-sonnet-20241022",
+		MaxTokens: 1024,
+		Timeout:   config.Duration{Duration: 5 * time.Second},
+	})
+	h := NewEmailHandler(dbURL, "", claudeHandler, config.Email{IngestDomain: "in.example.com", WebhookSecret: "s3cret"})
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(utils.NewLogger()))
+	asUser := func(c *gin.Context) { c.Set("user_id", "u1") }
+	router.GET("/api/email/address", asUser, h.GetAddress)
+	router.POST("/api/email/address/rotate", asUser, h.RotateAddress)
+	router.POST("/api/email/inbound", h.Inbound)
+
+	address := func(path, method string) string {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		var resp models.EmailAddressResponse
+		if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &resp) != nil {
+			t.Fatalf("%s: got %d %s", path, recorder.Code, recorder.Body)
+		}
+		return resp.Address
+	}
+	addr := address("/api/email/address", http.MethodGet)
+	if !strings.HasPrefix(addr, "tasks+") || !strings.HasSuffix(addr, "@in.example.com") {
+		t.Fatalf("unexpected ingest address %q", addr)
+	}
+	if again := address("/api/email/address", http.MethodGet); again != addr {
+		t.Fatalf("address changed between reads: %q, %q", addr, again)
+	}
+
+	post := func(key, contentType string, body *bytes.Buffer) (int, models.EmailIngestResponse) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/email/inbound?key="+key, body)
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(recorder, req)
+		var resp models.EmailIngestResponse
+		json.Unmarshal(recorder.Body.Bytes(), &resp)
+		return recorder.Code, resp
+	}
+	// sendGrid posts a message the way SendGrid's Inbound Parse does
+	sendGrid := func(key, to string) (int, models.EmailIngestResponse) {
+		t.Helper()
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("to", "Me <"+to+">")
+		form.WriteField("from", "Boss <boss@example.com>")
+		form.WriteField("subject", "Fwd: urgent: send the client report tomorrow")
+		form.WriteField("text", "Can you get this out first thing?")
+		notes, _ := form.CreateFormFile("attachment1", "notes.txt")
+		notes.Write([]byte("- buy stamps\n- call the bank\n"))
+		photo, _ := form.CreateFormFile("attachment2", "photo.jpg")
+		photo.Write([]byte{0xff, 0xd8, 0xff})
+		form.Close()
+		return post(key, form.FormDataContentType(), &body)
+	}
+
+	if code, _ := sendGrid("wrong", addr); code != http.StatusUnauthorized {
+		t.Fatalf("wrong key: expected 401, got %d", code)
+	}
+
+	code, resp := sendGrid("s3cret", addr)
+	if code != http.StatusOK || resp.Status != models.EmailProcessed || len(resp.Tasks) != 3 {
+		t.Fatalf("expected three tasks, got %d %+v", code, resp)
+	}
+	if title, _ := resp.Tasks[0]["title"].(string); strings.Contains(title, "Fwd") || !strings.Contains(title, "send the client report") {
+		t.Errorf("expected the subject parsed without Fwd:, got %q", title)
+	}
+	if desc, _ := resp.Tasks[0]["description"].(string); !strings.Contains(desc, "Forwarded from Boss <boss@example.com>") ||
+		!strings.Contains(desc, "first thing") {
+		t.Errorf("expected the sender and body in the description, got %q", desc)
+	}
+	if len(resp.Skipped) != 1 || resp.Skipped[0].Name != "photo.jpg" {
+		t.Errorf("expected the photo to be skipped, got %+v", resp.Skipped)
+	}
+
+	// Mailgun posts messages without attachments as a plain form
+	mailgun := url.Values{
+		"recipient":  {addr},
+		"sender":     {"boss@example.com"},
+		"subject":    {"Book the venue"},
+		"body-plain": {"For the offsite."},
+	}
+	code, resp = post("s3cret", "application/x-www-form-urlencoded", bytes.NewBufferString(mailgun.Encode()))
+	if code != http.StatusOK || len(resp.Tasks) != 1 {
+		t.Fatalf("mailgun: expected one task, got %d %+v", code, resp)
+	}
+
+	rotated := address("/api/email/address/rotate", http.MethodPost)
+	if rotated == addr {
+		t.Fatal("rotating kept the old address")
+	}
+	if _, resp := sendGrid("s3cret", addr); resp.Status != models.EmailIgnored {
+		t.Errorf("old address after rotating: expected it to be ignored, got %+v", resp)
+	}
+}
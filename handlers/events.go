@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/events"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// eventBus receives domain events published by handlers; nil disables publishing
+var eventBus *events.Bus
+
+// SetEventBus installs the event bus used by all handlers
+func SetEventBus(b *events.Bus) {
+	eventBus = b
+}
+
+// publishEvent publishes to the installed event bus
+func publishEvent(e events.Event) {
+	eventBus.Publish(e)
+}
+
+// publishEntityEvent publishes a domain event for a task, goal or client mutation.
+// The event carries the entity as it is after the change (before it, for deletes).
+func publishEntityEvent(c *gin.Context, entityType, entityID, action string, before, after map[string]interface{}) {
+	owner := rowString(after, "user_id")
+	if owner == "" {
+		owner = rowString(before, "user_id")
+	}
+	if owner == "" && entityType != AuditEntityOAuthClient {
+		owner = getUserID(c)
+	}
+
+	data := map[string]interface{}{"request_id": c.GetString("request_id")}
+	if after != nil {
+		data["entity"] = after
+	} else if before != nil {
+		data["entity"] = before
+	}
+	if action == AuditActionUpdate {
+		data["changes"] = auditDiff(before, after)
+	}
+
+	publishEvent(events.Event{
+		Type:       events.EntityEventType(entityType, action),
+		UserID:     owner,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Data:       data,
+	})
+}
+
+// ListEvents returns the user's events in publish order, paginated by sequence number.
+// Pass the returned next_cursor as since to continue; truncated means events between
+// the cursor and the oldest retained event were dropped from history.
+// GET /api/events?since=0&limit=100&type=task.created
+func ListEvents(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	var since int64
+	if raw := c.Query("since"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 0 {
+			c.Error(utils.ErrBadRequest("since must be a non-negative sequence number"))
+			return
+		}
+		since = n
+	}
+
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 1000 {
+			c.Error(utils.ErrBadRequest("limit must be between 1 and 1000"))
+			return
+		}
+		limit = n
+	}
+
+	eventType := c.Query("type")
+	page, oldest, more := eventBus.Since(since, limit, func(e events.Event) bool {
+		return e.UserID == userID && (eventType == "" || e.Type == eventType)
+	})
+
+	next := since
+	if len(page) > 0 {
+		next = page[len(page)-1].Seq
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      page,
+		"next_cursor": next,
+		"has_more":    more,
+		"truncated":   oldest > 0 && since < oldest-1,
+	})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/flags"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// FeatureFlags decides which users get each feature flag, by the rules in
+// FEATURE_FLAGS and FEATURE_FLAG_USERS and, once backed by Supabase, the
+// rules saved in the feature_flags table, which are re-read when older than
+// the refresh interval
+type FeatureFlags struct {
+	env     flags.Rules
+	refresh time.Duration
+
+	supabaseClient *db.SupabaseClient // nil: configured rules only
+
+	mu       sync.Mutex
+	rules    flags.Rules
+	loadedAt time.Time
+}
+
+// NewFeatureFlags creates flags from the configured rules
+func NewFeatureFlags(cfg config.Flags) (*FeatureFlags, error) {
+	env, err := flags.Parse(cfg.Rules, cfg.Users)
+	if err != nil {
+		return nil, err
+	}
+	return &FeatureFlags{env: env, refresh: cfg.RefreshInterval.Duration, rules: env}, nil
+}
+
+// UseSupabase lets rules saved in the feature_flags table override the
+// configured ones
+func (f *FeatureFlags) UseSupabase(supabaseURL, supabaseKey string) {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	f.supabaseClient = client
+}
+
+// featureFlags backs flag checks; nil gives every flag its default
+var featureFlags *FeatureFlags
+
+// SetFeatureFlags installs the flags routes and tools are guarded by
+func SetFeatureFlags(f *FeatureFlags) {
+	featureFlags = f
+}
+
+// Rules returns the rules in force, re-reading the saved ones when stale. A
+// failed read keeps the rules read last.
+func (f *FeatureFlags) Rules(ctx context.Context) flags.Rules {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.supabaseClient == nil || time.Since(f.loadedAt) < f.refresh {
+		return f.rules
+	}
+	f.loadedAt = time.Now()
+
+	rows, err := f.supabaseClient.WithContext(ctx).ListFeatureFlags()
+	if err != nil {
+		utils.LoggerFromContext(ctx).Error("Failed to load feature flags", err)
+		return f.rules
+	}
+	rules := f.env
+	for _, row := range rows {
+		rules = rules.With(rowString(row, "name"), flags.Rule{Percent: rowInt(row, "percent"), Users: rowStrings(row, "users")})
+	}
+	f.rules = rules
+	return rules
+}
+
+// invalidate makes the next check re-read the saved rules
+func (f *FeatureFlags) invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loadedAt = time.Time{}
+}
+
+// currentFlagRules returns the rules in force, none when flags are not set up
+func currentFlagRules(c *gin.Context) flags.Rules {
+	if featureFlags == nil {
+		return nil
+	}
+	return featureFlags.Rules(c.Request.Context())
+}
+
+// flagEnabled reports whether userID gets flag
+func flagEnabled(c *gin.Context, flag, userID string) bool {
+	return currentFlagRules(c).Enabled(flag, userID)
+}
+
+// userFlags returns whether userID gets each known flag and each flag with
+// a rule
+func userFlags(c *gin.Context, userID string) map[string]bool {
+	return currentFlagRules(c).Evaluate(userID)
+}
+
+// errFlagDisabled reports a feature the caller does not have
+func errFlagDisabled(flag string) *utils.AppError {
+	return utils.ErrForbidden("this feature is not enabled for your account").WithField("flag", flag)
+}
+
+// RequireFlag refuses requests from callers who do not get flag
+func RequireFlag(flag string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !flagEnabled(c, flag, getUserID(c)) {
+			c.Error(errFlagDisabled(flag))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ListFlags lists the known flags and the rules in force
+// GET /admin/flags
+func (f *FeatureFlags) ListFlags(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"known": flags.Known, "rules": f.Rules(c.Request.Context())})
+}
+
+// SetFlagRequest is a flag's new rule
+type SetFlagRequest struct {
+	Percent *int     `json:"percent"`
+	Users   []string `json:"users"`
+}
+
+// SetFlag saves a flag's rule, replacing the configured one
+// PUT /admin/flags/:name
+func (f *FeatureFlags) SetFlag(c *gin.Context) {
+	if f.supabaseClient == nil {
+		c.Error(utils.ErrBadRequest("feature flags are configured by FEATURE_FLAGS only"))
+		return
+	}
+	var req SetFlagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	name := c.Param("name")
+	var v validation.Validator
+	v.Check(flags.ValidName(name), "name", validation.CodeInvalidFormat, "name must be lowercase letters, digits and underscores")
+	v.Check(req.Percent != nil, "percent", validation.CodeRequired, "percent is required")
+	if req.Percent != nil {
+		v.Range("percent", *req.Percent, 0, 100)
+	}
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	if req.Users == nil {
+		req.Users = []string{}
+	}
+
+	row, err := f.supabaseClient.WithContext(c.Request.Context()).UpsertFeatureFlag(name, map[string]interface{}{
+		"percent":    *req.Percent,
+		"users":      req.Users,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	f.invalidate()
+	c.JSON(http.StatusOK, row)
+}
+
+// DeleteFlag deletes a flag's saved rule, so the configured one applies again
+// DELETE /admin/flags/:name
+func (f *FeatureFlags) DeleteFlag(c *gin.Context) {
+	if f.supabaseClient == nil {
+		c.Error(utils.ErrBadRequest("feature flags are configured by FEATURE_FLAGS only"))
+		return
+	}
+	if err := f.supabaseClient.WithContext(c.Request.Context()).DeleteFeatureFlag(c.Param("name")); err != nil {
+		c.Error(err)
+		return
+	}
+	f.invalidate()
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// DefaultFocusMinutes is the length of a focus session when none is requested
+const DefaultFocusMinutes = 25
+
+// FocusHandler handles focus session requests
+type FocusHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewFocusHandler creates a new focus handler
+func NewFocusHandler(supabaseURL, supabaseKey string) *FocusHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &FocusHandler{
+		supabaseClient: client,
+	}
+}
+
+// FocusContract is a user's focus contract setting. When enabled, tasks created
+// through create_task, the REST API or Shortcuts quick-add during an active
+// focus session go to the Inbox, deferred until the session ends.
+type FocusContract struct {
+	Enabled bool `json:"enabled"`
+}
+
+// StartFocusRequest represents a request to start a focus session
+type StartFocusRequest struct {
+	Minutes int `json:"minutes"`
+}
+
+// startFocusSession ends any running session for the user and starts a new one
+func startFocusSession(client *db.SupabaseClient, userID string, minutes int, source string) (map[string]interface{}, error) {
+	if minutes <= 0 {
+		minutes = DefaultFocusMinutes
+	}
+
+	active, err := client.GetActiveFocusSession(userID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		if id, ok := active["id"].(string); ok {
+			if err := client.EndFocusSession(id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	return client.CreateFocusSession(userID, map[string]interface{}{
+		"started_at": now.Format(time.RFC3339),
+		"ends_at":    now.Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339),
+		"source":     source,
+	})
+}
+
+// applyFocusContract routes a new task into the Inbox, deferred until the end
+// of the running focus session, when the user has the focus contract enabled.
+// It returns a notice for the caller, or "" if the task was left untouched.
+// Lookup failures are logged and never block the create.
+func applyFocusContract(ctx context.Context, client *db.SupabaseClient, userID string, taskData map[string]interface{}) string {
+	contract, err := client.GetFocusContract(userID)
+	if err != nil {
+		utils.LoggerFromContext(ctx).Error("Failed to load focus contract", err, map[string]interface{}{"user_id": userID})
+		return ""
+	}
+	if !rowBool(contract, "enabled") {
+		return ""
+	}
+
+	session, err := client.GetActiveFocusSession(userID)
+	if err != nil {
+		utils.LoggerFromContext(ctx).Error("Failed to load focus session", err, map[string]interface{}{"user_id": userID})
+		return ""
+	}
+	endsAt, ok := rowTime(session, "ends_at")
+	if !ok {
+		return ""
+	}
+
+	taskData["category"] = "inbox"
+	taskData["deferred_until"] = endsAt.UTC().Format(time.RFC3339)
+	return fmt.Sprintf("You're in a focus session until %s UTC, so this went to your Inbox for later. Your plan is unchanged.",
+		endsAt.UTC().Format("15:04"))
+}
+
+// GetContract returns the user's focus contract setting
+// GET /api/focus/contract
+func (h *FocusHandler) GetContract(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	row, err := h.supabaseClient.GetFocusContract(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, FocusContract{Enabled: rowBool(row, "enabled")})
+}
+
+// UpdateContract turns the user's focus contract on or off
+// PUT /api/focus/contract
+func (h *FocusHandler) UpdateContract(c *gin.Context) {
+	var req FocusContract
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	if _, err := h.supabaseClient.UpsertFocusContract(userID, map[string]interface{}{
+		"enabled":    req.Enabled,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// StartFocus starts a focus session, replacing any running one
+func (h *FocusHandler) StartFocus(c *gin.Context) {
+	var req StartFocusRequest
+	if c.Request.ContentLength > 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	if req.Minutes < 0 || req.Minutes > 480 {
+		c.Error(utils.ErrBadRequest("minutes must be between 1 and 480"))
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	session, err := startFocusSession(h.supabaseClient, userID, req.Minutes, "api")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetFocus returns the user's active focus session
+func (h *FocusHandler) GetFocus(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	session, err := h.supabaseClient.GetActiveFocusSession(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"active": session != nil, "session": session})
+}
+
+// StopFocus ends the user's active focus session
+func (h *FocusHandler) StopFocus(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	session, err := h.supabaseClient.GetActiveFocusSession(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if session == nil {
+		c.Error(utils.NewAppError(utils.ErrCodeNotFound, "no active focus session", http.StatusNotFound))
+		return
+	}
+
+	id, _ := session["id"].(string)
+	if err := h.supabaseClient.EndFocusSession(id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id, "ended": true})
+}
@@ -0,0 +1,61 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/db"
+)
+
+// fakeFocusSupabase serves the focus_contracts and focus_sessions tables
+func fakeFocusSupabase(t *testing.T, enabled bool, session map[string]interface{}) *db.SupabaseClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rows []map[string]interface{}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/focus_contracts"):
+			rows = []map[string]interface{}{{"user_id": "u1", "enabled": enabled}}
+		case strings.HasSuffix(r.URL.Path, "/focus_sessions") && session != nil:
+			rows = []map[string]interface{}{session}
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := db.NewSupabaseClient(srv.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestApplyFocusContract(t *testing.T) {
+	endsAt := time.Now().Add(20 * time.Minute).UTC().Truncate(time.Second)
+	session := map[string]interface{}{"id": "s1", "ends_at": endsAt.Format(time.RFC3339)}
+
+	taskData := map[string]interface{}{"title": "Reply to Sam", "category": "work"}
+	notice := applyFocusContract(context.Background(), fakeFocusSupabase(t, true, session), "u1", taskData)
+	if notice == "" {
+		t.Fatal("expected a notice during an active session")
+	}
+	if taskData["category"] != "inbox" || taskData["deferred_until"] != endsAt.Format(time.RFC3339) {
+		t.Fatalf("task not routed to the inbox: %v", taskData)
+	}
+
+	for name, client := range map[string]*db.SupabaseClient{
+		"contract disabled": fakeFocusSupabase(t, false, session),
+		"no active session": fakeFocusSupabase(t, true, nil),
+	} {
+		taskData := map[string]interface{}{"title": "Reply to Sam", "category": "work"}
+		if notice := applyFocusContract(context.Background(), client, "u1", taskData); notice != "" || taskData["category"] != "work" {
+			t.Errorf("%s: expected task untouched, got notice %q and %v", name, notice, taskData)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// GoalService implements goal operations shared by the REST handlers and the
+// MCP tools. Errors are validation.Errors for bad input, otherwise AppErrors.
+type GoalService struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewGoalService creates a goal service on an existing Supabase client
+func NewGoalService(supabaseClient *db.SupabaseClient) *GoalService {
+	return &GoalService{supabaseClient: supabaseClient}
+}
+
+// CreatedGoal is the result of GoalService.Create
+type CreatedGoal struct {
+	ID string
+	// Goal is the stored row, or nil if it was created but could not be read back.
+	// A goal created with milestones lists them under "milestones".
+	Goal map[string]interface{}
+}
+
+// Create validates req and creates the goal for userID, with its milestones;
+// c is used for the audit trail
+func (s *GoalService) Create(c *gin.Context, userID string, req models.CreateGoalRequest) (*CreatedGoal, error) {
+	return s.create(c, userID, "", req)
+}
+
+// CreateWithID is Create for a goal whose ID the client chose, such as one
+// created offline
+func (s *GoalService) CreateWithID(c *gin.Context, userID, goalID string, req models.CreateGoalRequest) (*CreatedGoal, error) {
+	return s.create(c, userID, goalID, req)
+}
+
+func (s *GoalService) create(c *gin.Context, userID, goalID string, req models.CreateGoalRequest) (*CreatedGoal, error) {
+	client, goalData, err := s.planCreate(c, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if goalID != "" {
+		goalData["id"] = goalID
+	}
+
+	goalID, err = client.CreateGoal(userID, goalData)
+	if err != nil {
+		return nil, err
+	}
+	// Deleting the goal deletes any milestones saved under it
+	var undo saga
+	undo.done("goal "+goalID, func() error { return s.supabaseClient.DeleteGoal(goalID) })
+
+	var milestones []map[string]interface{}
+	if len(req.Milestones) > 0 {
+		// The goal's progress was computed from these up front
+		goal := mergeRow(goalData, map[string]interface{}{"id": goalID})
+		milestones = make([]map[string]interface{}, 0, len(req.Milestones))
+		for _, m := range req.Milestones {
+			milestone, err := client.CreateMilestone(milestoneData(goal, m))
+			if err != nil {
+				return nil, undo.abort(c.Request.Context(), utils.ErrInternal("the goal's milestones could not be saved, so it was not created").WithError(err))
+			}
+			milestones = append(milestones, milestone)
+		}
+	}
+
+	// Fetch the created goal
+	goalMap, err := client.GetGoal(goalID)
+	if err != nil {
+		recordAudit(c, AuditEntityGoal, goalID, AuditActionCreate, nil, goalData)
+	} else {
+		recordAudit(c, AuditEntityGoal, goalID, AuditActionCreate, nil, goalMap)
+	}
+	for _, milestone := range milestones {
+		recordAudit(c, AuditEntityMilestone, rowString(milestone, "id"), AuditActionCreate, nil, milestone)
+	}
+	if goalMap != nil && milestones != nil {
+		goalMap["milestones"] = milestones
+	}
+
+	return &CreatedGoal{ID: goalID, Goal: goalMap}, nil
+}
+
+// PreviewCreate validates req and returns the goal Create would store, without storing it
+func (s *GoalService) PreviewCreate(c *gin.Context, userID string, req models.CreateGoalRequest) (*ChangePreview, error) {
+	_, goalData, err := s.planCreate(c, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	after := mergeRow(goalData, map[string]interface{}{"user_id": userID})
+	if len(req.Milestones) > 0 {
+		milestones := make([]map[string]interface{}, len(req.Milestones))
+		for i, m := range req.Milestones {
+			milestones[i] = milestoneData(after, m)
+		}
+		after["milestones"] = milestones
+	}
+	return newChangePreview(AuditEntityGoal, "", AuditActionCreate, nil, after), nil
+}
+
+// planCreate validates req and builds the goal row, returning the client to write it with
+func (s *GoalService) planCreate(c *gin.Context, userID string, req models.CreateGoalRequest) (*db.SupabaseClient, map[string]interface{}, error) {
+	var v validation.Validator
+	validateTitle(&v, req.Title)
+	v.MaxLength("description", req.Description, validation.MaxDescriptionLength)
+	v.Check(!req.TargetDate.Before(req.StartDate), "target_date", validation.CodeOutOfRange, "target_date must be after start_date")
+	v.Range("progress", req.Progress, 0, 100)
+	validateLanguage(&v, req.Language)
+	validateGoalMilestones(&v, req.Milestones)
+	if err := v.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if userID == "" {
+		return nil, nil, utils.ErrBadRequest("user_id required")
+	}
+
+	// Supabase calls stop if the request is abandoned; the audit entry is
+	// written with its own client either way
+	client := s.supabaseClient.WithContext(c.Request.Context())
+
+	if req.WorkspaceID != "" {
+		if err := checkWorkspaceRole(client, req.WorkspaceID, userID, models.RoleEditor, "workspace"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Convert request to map for Supabase
+	goalData := map[string]interface{}{
+		"title":       req.Title,
+		"description": req.Description,
+		"start_date":  req.StartDate.Format(time.RFC3339),
+		"target_date": req.TargetDate.Format(time.RFC3339),
+		"progress":    req.Progress,
+		"archived":    false,
+		"created_at":  time.Now().Format(time.RFC3339),
+		"updated_at":  time.Now().Format(time.RFC3339),
+	}
+	setLanguage(goalData, entityLanguage(req.Language, req.Title, req.Description))
+	if len(req.Milestones) > 0 {
+		completed := 0
+		for _, m := range req.Milestones {
+			if m.Completed {
+				completed++
+			}
+		}
+		goalData["progress"] = milestoneProgress(len(req.Milestones), completed)
+	}
+	if req.WorkspaceID != "" {
+		goalData["workspace_id"] = req.WorkspaceID
+	}
+
+	return client, goalData, nil
+}
+
+// Update validates req and applies it to the goal as userID, who needs the
+// editor role on shared goals. It returns the updated row, or just the id if
+// the row could not be read back.
+func (s *GoalService) Update(c *gin.Context, userID, goalID string, req models.UpdateGoalRequest) (map[string]interface{}, error) {
+	// Validate only the fields being changed
+	var v validation.Validator
+	if req.Title != nil {
+		validateTitle(&v, *req.Title)
+	}
+	if req.Description != nil {
+		v.MaxLength("description", *req.Description, validation.MaxDescriptionLength)
+	}
+	if req.Progress != nil {
+		v.Range("progress", *req.Progress, 0, 100)
+	}
+	if req.Language != nil {
+		validateLanguage(&v, *req.Language)
+	}
+	if req.StartDate != nil && req.TargetDate != nil {
+		v.Check(!req.TargetDate.Before(*req.StartDate), "target_date", validation.CodeOutOfRange, "target_date must be after start_date")
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	// Build update map from non-nil fields
+	updateData := map[string]interface{}{
+		"updated_at": time.Now().Format(time.RFC3339),
+	}
+
+	if req.Title != nil {
+		updateData["title"] = *req.Title
+	}
+	if req.Description != nil {
+		updateData["description"] = *req.Description
+	}
+	if req.StartDate != nil {
+		updateData["start_date"] = req.StartDate.Format(time.RFC3339)
+	}
+	if req.TargetDate != nil {
+		updateData["target_date"] = req.TargetDate.Format(time.RFC3339)
+	}
+	if req.Progress != nil {
+		updateData["progress"] = *req.Progress
+	}
+	if req.Archived != nil {
+		updateData["archived"] = *req.Archived
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	before, err := client.GetGoal(goalID)
+	if err != nil {
+		return nil, lookupError("goal", err)
+	}
+	if err := checkRowAccess(client, userID, before, "goal", models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	if req.Progress != nil {
+		milestones, err := client.ListMilestones(goalID)
+		if err != nil {
+			return nil, err
+		}
+		if len(milestones) > 0 {
+			return nil, validation.Errors{{Field: "progress", Code: validation.CodeInvalidValue,
+				Message: "progress follows the goal's milestones; complete milestones instead"}}
+		}
+	}
+
+	if code, changed := updatedLanguage(req.Language, req.Title, req.Description, before); changed {
+		updateData["language"] = nullIfEmpty(code)
+	}
+
+	if err := client.UpdateGoal(goalID, updateData); err != nil {
+		return nil, err
+	}
+
+	// Fetch updated goal
+	goal, err := client.GetGoal(goalID)
+	if err != nil {
+		recordAudit(c, AuditEntityGoal, goalID, AuditActionUpdate, before, updateData)
+		return map[string]interface{}{"id": goalID, "updated": true}, nil
+	}
+
+	recordAudit(c, AuditEntityGoal, goalID, AuditActionUpdate, before, goal)
+	return goal, nil
+}
+
+// Delete moves the goal to the trash as userID, who needs the editor role on shared goals
+func (s *GoalService) Delete(c *gin.Context, userID, goalID string) error {
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	before, err := client.GetGoal(goalID)
+	if err != nil {
+		return lookupError("goal", err)
+	}
+	if err := checkRowAccess(client, userID, before, "goal", models.RoleEditor); err != nil {
+		return err
+	}
+
+	if err := client.SoftDeleteGoal(goalID); err != nil {
+		return err
+	}
+
+	recordAudit(c, AuditEntityGoal, goalID, AuditActionDelete, before, nil)
+	return nil
+}
@@ -0,0 +1,69 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+)
+
+func TestGoalCreateRollsBackAfterCancel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The client goes away while the milestones are being saved
+	var mu sync.Mutex
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/goals"):
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`[{"id":"g1"}]`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/goal_milestones"):
+			cancel()
+			http.Error(w, `{"message":"boom"}`, http.StatusInternalServerError)
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/goals"):
+			mu.Lock()
+			deleted = append(deleted, r.URL.Query().Get("id"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := db.NewSupabaseClient(srv.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+	c.Set("user_id", "u1")
+
+	_, err = NewGoalService(client).Create(c, "u1", models.CreateGoalRequest{
+		Title:      "Learn Go",
+		StartDate:  time.Now(),
+		TargetDate: time.Now().AddDate(0, 3, 0),
+		Milestones: []models.CreateMilestoneRequest{{Title: "Finish the tour"}},
+	})
+	if err == nil {
+		t.Fatal("create succeeded without its milestones")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(deleted) != 1 || deleted[0] != "eq.g1" {
+		t.Fatalf("goal not rolled back after the request was cancelled: deleted %v", deleted)
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/signing"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// strictAuth takes users only from credentials; see config.Auth.Strict
+var strictAuth bool
+
+// SetStrictAuth turns strict user identity on or off
+func SetStrictAuth(strict bool) {
+	strictAuth = strict
+}
+
+// getUserID returns the user a request acts for: a service account acts for
+// the user the request names, anyone else for themselves. Outside strict
+// mode an anonymous caller may still name a user with ?user_id= or
+// X-User-ID.
+func getUserID(c *gin.Context) string {
+	caller := c.GetString("user_id")
+	named := namedUserID(c)
+	if named != "" && named != caller && isServiceAccount(c) {
+		return named
+	}
+	if caller != "" || strictAuth {
+		return caller
+	}
+	return named
+}
+
+// namedUserID is the user a request names itself, by query param or header
+func namedUserID(c *gin.Context) string {
+	if c.Request == nil {
+		return ""
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		return userID
+	}
+	return c.GetHeader("X-User-ID")
+}
+
+// isServiceAccount reports whether the caller signed in with a service
+// account token granted the act_as scope
+func isServiceAccount(c *gin.Context) bool {
+	claims := bearerClaims(c)
+	if claims == nil || claims["role"] != signing.ServiceRole {
+		return false
+	}
+	scope, _ := claims["scope"].(string)
+	for _, s := range strings.Fields(scope) {
+		if s == signing.ActAsScope {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNamedUser refuses, in strict mode, a request naming a user other than
+// the caller unless the caller is a service account
+func checkNamedUser(c *gin.Context, named string) error {
+	if !strictAuth || named == "" || named == c.GetString("user_id") || isServiceAccount(c) {
+		return nil
+	}
+	return errActAs(named)
+}
+
+// checkToolUser refuses an MCP tool's user_id param naming anyone but the
+// caller unless the caller is a service account. The param becomes the user
+// the tool acts for, so unlike ?user_id= it is refused in every mode once the
+// caller is signed in; only anonymous callers outside strict mode may name
+// any user.
+func checkToolUser(c *gin.Context, named string) error {
+	if c.GetString("user_id") == "" {
+		return checkNamedUser(c, named)
+	}
+	if named == "" || named == c.GetString("user_id") || isServiceAccount(c) {
+		return nil
+	}
+	return errActAs(named)
+}
+
+func errActAs(named string) error {
+	return utils.ErrForbidden("only service accounts may act for another user; drop user_id and sign in as that user").
+		WithField("user_id", named)
+}
+
+// checkOwnUser refuses a request for userID's data from anyone but userID or
+// a service account acting for them
+func checkOwnUser(c *gin.Context, userID, resource string) error {
+	caller := getUserID(c)
+	if caller == "" {
+		return utils.ErrUnauthorized(resource + " require a signed-in user")
+	}
+	if caller != userID {
+		return utils.ErrForbidden("you can only list your own " + resource)
+	}
+	return nil
+}
+
+// RequireOwnUser refuses, in strict mode, requests that name another user
+// with ?user_id= or X-User-ID, rather than quietly serving the caller's own
+// data
+func RequireOwnUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := checkNamedUser(c, namedUserID(c)); err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
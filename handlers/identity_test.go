@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/productivity/mcp-server/signing"
+)
+
+func TestGetUserIDStrict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetJWTSecret("identity-test-secret-0123456789abcdef")
+	t.Cleanup(func() { SetJWTSecret(""); SetStrictAuth(false) })
+
+	sign := func(claims jwt.MapClaims) string {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		token, err := signingKeys.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	service := sign(jwt.MapClaims{"sub": "scheduler", "role": signing.ServiceRole, "scope": signing.ActAsScope})
+	// An OAuth client asking for act_as does not become a service account
+	oauth := sign(jwt.MapClaims{"sub": "u1", "scope": "read write " + signing.ActAsScope})
+
+	tests := []struct {
+		name    string
+		strict  bool
+		caller  string
+		token   string
+		named   string
+		want    string
+		refused bool
+		// toolRefused is whether an MCP tool's user_id param is refused
+		toolRefused bool
+	}{
+		{"anonymous names a user", false, "", "", "victim", "victim", false, false},
+		{"strict anonymous", true, "", "", "victim", "", true, true},
+		{"signed-in user names another", false, "u1", oauth, "victim", "u1", false, true},
+		{"strict signed-in user names another", true, "u1", oauth, "victim", "u1", true, true},
+		{"strict signed-in user names themselves", true, "u1", oauth, "u1", "u1", false, false},
+		{"service account", false, "scheduler", service, "u2", "u2", false, false},
+		{"strict service account", true, "scheduler", service, "u2", "u2", false, false},
+		{"service account without a user", true, "scheduler", service, "", "scheduler", false, false},
+	}
+	for _, tt := range tests {
+		SetStrictAuth(tt.strict)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+		c.Request.Header.Set("X-User-ID", tt.named)
+		if tt.caller != "" {
+			c.Set("user_id", tt.caller)
+			c.Set("auth_token", tt.token)
+		}
+		if got := getUserID(c); got != tt.want {
+			t.Errorf("%s: user %q, want %q", tt.name, got, tt.want)
+		}
+		if err := checkNamedUser(c, tt.named); (err != nil) != tt.refused {
+			t.Errorf("%s: refused = %v, want %v", tt.name, err != nil, tt.refused)
+		}
+		if err := checkToolUser(c, tt.named); (err != nil) != tt.toolRefused {
+			t.Errorf("%s: tool param refused = %v, want %v", tt.name, err != nil, tt.toolRefused)
+		}
+	}
+}
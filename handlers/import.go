@@ -0,0 +1,570 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/importers"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// MaxImportBytes bounds uploaded export files
+const MaxImportBytes = 10 << 20
+
+// importBatchSize is how many new tasks are created per Supabase request
+const importBatchSize = 500
+
+// Import plan actions
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionTrashed   = "skip_trashed"
+)
+
+// ImportHandler imports habits and tasks exported from other apps, and task
+// lists kept in spreadsheets
+type ImportHandler struct {
+	supabaseClient *db.SupabaseClient
+	ai             *AIService
+}
+
+// NewImportHandler creates a new import handler; claudeHandler's model maps
+// the columns of spreadsheets it cannot map by their headers
+func NewImportHandler(supabaseURL, supabaseKey string, claudeHandler *ClaudeHandler) *ImportHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &ImportHandler{
+		supabaseClient: client,
+		ai:             claudeHandler.service,
+	}
+}
+
+// importPlan is what importing an upload will do
+type importPlan struct {
+	userID  string
+	source  string
+	columns importers.ColumnMapping // spreadsheets only
+	items   []importPlanItem
+}
+
+// importPlanItem describes what importing one item will do
+type importPlanItem struct {
+	importers.Item
+	Action         string `json:"action"`
+	TaskID         string `json:"task_id,omitempty"`
+	NewCompletions int    `json:"new_completions"`
+	existing       map[string]interface{}
+	newCompletions []time.Time
+	failed         bool
+}
+
+// Preview parses an export and reports what an import would create or update without writing anything
+// POST /api/import/:source/preview
+func (h *ImportHandler) Preview(c *gin.Context) {
+	plan, ok := h.plan(c)
+	if !ok {
+		return
+	}
+	response := gin.H{
+		"source":  plan.source,
+		"user_id": plan.userID,
+		"summary": summarizeImportPlan(plan.items),
+		"items":   plan.items,
+	}
+	if plan.columns != nil {
+		response["columns"] = plan.columns
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Import applies an export. Items are matched to earlier imports by external ID,
+// so importing the same export twice changes nothing.
+// POST /api/import/:source
+func (h *ImportHandler) Import(c *gin.Context) {
+	plan, ok := h.plan(c)
+	if !ok {
+		return
+	}
+
+	failures := h.run(c, plan)
+	status := http.StatusOK
+	if len(failures) > 0 {
+		status = http.StatusMultiStatus
+	}
+	response := gin.H{
+		"source":  plan.source,
+		"summary": summarizeImportPlan(plan.items),
+		"items":   plan.items,
+		"errors":  failures,
+	}
+	if plan.columns != nil {
+		response["columns"] = plan.columns
+	}
+	c.JSON(status, response)
+}
+
+// run carries out plan and returns the items that failed, with why
+func (h *ImportHandler) run(c *gin.Context, plan *importPlan) []gin.H {
+	now := time.Now()
+	var failures []gin.H
+	fail := func(item *importPlanItem, err error) {
+		item.failed = true
+		failures = append(failures, gin.H{"external_id": item.ExternalID, "title": item.Title, "error": clientMessage(err)})
+	}
+	h.createAll(c, plan, now, fail)
+	for i := range plan.items {
+		item := &plan.items[i]
+		if item.failed {
+			continue
+		}
+		if err := h.apply(c, plan.userID, item, now); err != nil {
+			fail(item, err)
+		}
+	}
+	return failures
+}
+
+// plan reads the uploaded export and matches each item against earlier imports
+func (h *ImportHandler) plan(c *gin.Context) (*importPlan, bool) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return nil, false
+	}
+
+	source := c.Param("source")
+	data, err := readImportUpload(c)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.Error(utils.ErrTooLarge(MaxImportBytes))
+		return nil, false
+	}
+	if err != nil {
+		c.Error(utils.ErrBadRequest(err.Error()))
+		return nil, false
+	}
+	if err := checkAttachmentLimit(c, userID, len(data)); err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	var items []importers.Item
+	var columns importers.ColumnMapping
+	switch {
+	case importers.IsSpreadsheet(source):
+		items, columns, err = h.spreadsheetItems(c, source, data)
+	case source == importers.SourceMarkdown:
+		items, err = h.markdownItems(c, userID, data)
+	default:
+		items, err = importers.Parse(source, data)
+	}
+	var appErr *utils.AppError
+	if errors.As(err, &appErr) {
+		c.Error(err)
+		return nil, false
+	}
+	if err != nil {
+		c.Error(utils.ErrBadRequest(err.Error()))
+		return nil, false
+	}
+
+	plan, err := h.planItems(userID, source, items)
+	if err == nil {
+		err = checkImportLimit(c, plan)
+	}
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+	plan.columns = columns
+	return plan, true
+}
+
+// planItems matches items from source against earlier imports
+func (h *ImportHandler) planItems(userID, source string, items []importers.Item) (*importPlan, error) {
+	existingRows, err := h.supabaseClient.GetTasksByExternalSource(userID, source)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]map[string]interface{}, len(existingRows))
+	for _, row := range existingRows {
+		existing[rowString(row, "external_id")] = row
+	}
+
+	// Completions already recorded for imported habits, by task and day
+	recorded := make(map[string]map[string]bool)
+	if earliest, ok := earliestCompletion(items); ok && len(existing) > 0 {
+		rows, err := h.supabaseClient.GetCompletionsSince(userID, earliest)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if at, ok := rowTime(row, "completed_at"); ok {
+				taskID := rowString(row, "task_id")
+				if recorded[taskID] == nil {
+					recorded[taskID] = make(map[string]bool)
+				}
+				recorded[taskID][at.UTC().Format("2006-01-02")] = true
+			}
+		}
+	}
+
+	plan := make([]importPlanItem, 0, len(items))
+	for _, item := range items {
+		p := importPlanItem{Item: item, Action: ImportActionCreate}
+		if row, ok := existing[item.ExternalID]; ok {
+			p.existing = row
+			p.TaskID = rowString(row, "id")
+			switch {
+			case rowString(row, "deleted_at") != "":
+				p.Action = ImportActionTrashed
+			case len(importUpdates(item, row)) > 0:
+				p.Action = ImportActionUpdate
+			default:
+				p.Action = ImportActionUnchanged
+			}
+		}
+		if p.Action != ImportActionTrashed {
+			for _, at := range item.Completions {
+				if !recorded[p.TaskID][at.UTC().Format("2006-01-02")] {
+					p.newCompletions = append(p.newCompletions, at)
+				}
+			}
+		}
+		p.NewCompletions = len(p.newCompletions)
+		plan = append(plan, p)
+	}
+
+	return &importPlan{userID: userID, source: source, items: plan}, nil
+}
+
+// checkImportLimit refuses a plan that would take the user over their plan's
+// task count
+func checkImportLimit(c *gin.Context, plan *importPlan) error {
+	creates := 0
+	for _, item := range plan.items {
+		if item.Action == ImportActionCreate {
+			creates++
+		}
+	}
+	if creates == 0 {
+		return nil
+	}
+	return checkTaskLimit(c, plan.userID, creates)
+}
+
+// spreadsheetItems reads the rows of a CSV or XLSX upload as tasks. Columns
+// are found by their usual headers; the "columns" parameter, a JSON object
+// of field to header, maps others. With "assist" set, or when no column
+// holds the title, Claude suggests a mapping from the header and the first
+// rows, which the parameter still overrides. Only the mapping involves the
+// model: every row is read the same way.
+func (h *ImportHandler) spreadsheetItems(c *gin.Context, source string, data []byte) ([]importers.Item, importers.ColumnMapping, error) {
+	table, err := importers.ReadTable(source, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var given importers.ColumnMapping
+	if raw := importParam(c, "columns"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &given); err != nil {
+			return nil, nil, fmt.Errorf("columns must be a JSON object of field to column header: %v", err)
+		}
+	}
+
+	columns := importers.GuessColumns(table.Header)
+	assist, _ := strconv.ParseBool(importParam(c, "assist"))
+	if assist || (columns[importers.FieldTitle] == "" && given[importers.FieldTitle] == "") {
+		suggested, err := h.ai.MapColumns(c.Request.Context(), table)
+		if err != nil {
+			utils.LoggerFromContext(c.Request.Context()).Error("Failed to map import columns", err, map[string]interface{}{"source": source})
+		}
+		for field, column := range suggested {
+			columns[field] = column
+		}
+	}
+	for field, column := range given {
+		if column == "" {
+			delete(columns, field)
+		} else {
+			columns[field] = column
+		}
+	}
+
+	if err := columns.Check(table.Header); err != nil {
+		return nil, nil, fmt.Errorf("%v (columns: %s)", err, strings.Join(table.Header, ", "))
+	}
+	items, err := importers.ParseTable(table, columns)
+	return items, columns, err
+}
+
+// importParam reads a query parameter or, for multipart uploads, a form field
+func importParam(c *gin.Context, name string) string {
+	if value := c.Query(name); value != "" {
+		return value
+	}
+	return c.PostForm(name)
+}
+
+// createAll creates the plan's new tasks, importBatchSize per request, so
+// spreadsheets of thousands of rows import in a few round trips. Subtasks
+// are created after the tasks they belong to, to point at them. A new task
+// and its subtasks are imported together or not at all: when one of them
+// cannot be created, those already created are deleted again, so no
+// checklist is left half imported. fail is called for each task that could
+// not be created or was deleted again.
+func (h *ImportHandler) createAll(c *gin.Context, plan *importPlan, now time.Time, fail func(*importPlanItem, error)) {
+	byExternalID := make(map[string]*importPlanItem, len(plan.items))
+	var pending []*importPlanItem
+	for i := range plan.items {
+		item := &plan.items[i]
+		byExternalID[item.ExternalID] = item
+		if item.Action == ImportActionCreate {
+			pending = append(pending, item)
+		}
+	}
+
+	all := pending
+
+	// rootOf is the first new task above item, whose tree it is created with
+	rootOf := func(item *importPlanItem) *importPlanItem {
+		for {
+			parent := byExternalID[item.Parent]
+			if parent == nil || parent.Action != ImportActionCreate {
+				return item
+			}
+			item = parent
+		}
+	}
+	trees := make(map[*importPlanItem]*saga)
+	tree := func(item *importPlanItem) *saga {
+		root := rootOf(item)
+		if trees[root] == nil {
+			trees[root] = &saga{}
+		}
+		return trees[root]
+	}
+	failTree := func(item *importPlanItem, err error) {
+		fail(item, err)
+		root := rootOf(item)
+		undo := trees[root]
+		if undo == nil {
+			return
+		}
+		delete(trees, root)
+		if undoErr := undo.rollback(); undoErr != nil {
+			utils.LoggerFromContext(c.Request.Context()).Error("Failed to undo part of an import", undoErr, map[string]interface{}{"source": plan.source})
+		}
+		undone := fmt.Errorf("not imported because %q, in the same checklist, could not be", item.Title)
+		for _, other := range all {
+			if other.TaskID != "" && !other.failed && rootOf(other) == root {
+				other.TaskID = ""
+				fail(other, undone)
+			}
+		}
+	}
+
+	for len(pending) > 0 {
+		var ready, waiting []*importPlanItem
+		for _, item := range pending {
+			parent := byExternalID[item.Parent]
+			switch {
+			case parent == nil || parent.TaskID != "":
+				ready = append(ready, item)
+			case parent.failed:
+				failTree(item, errors.New("the task it is a subtask of was not imported"))
+			default:
+				waiting = append(waiting, item)
+			}
+		}
+		if len(ready) == 0 {
+			for _, item := range waiting {
+				failTree(item, errors.New("the task it is a subtask of was not imported"))
+			}
+			return
+		}
+		for start := 0; start < len(ready); start += importBatchSize {
+			h.createBatch(c, plan, ready[start:min(start+importBatchSize, len(ready))], byExternalID, now, tree, failTree)
+		}
+		pending = waiting
+	}
+}
+
+// createBatch creates items in one request, recording in the saga of each
+// one's tree how to delete it again
+func (h *ImportHandler) createBatch(c *gin.Context, plan *importPlan, items []*importPlanItem, byExternalID map[string]*importPlanItem, now time.Time, tree func(*importPlanItem) *saga, fail func(*importPlanItem, error)) {
+	rows := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		rows[i] = importTaskData(item.Item, now)
+		rows[i]["external_source"] = plan.source
+		rows[i]["external_id"] = item.ExternalID
+		if parent := byExternalID[item.Parent]; parent != nil {
+			rows[i]["parent_id"] = parent.TaskID
+		}
+	}
+	ids, err := h.supabaseClient.CreateTasks(plan.userID, rows)
+	for i, item := range items {
+		if err != nil {
+			fail(item, err)
+			continue
+		}
+		id, row := ids[i], rows[i]
+		item.TaskID = id
+		row["id"] = id
+		recordAudit(c, AuditEntityTask, id, AuditActionCreate, nil, row)
+		tree(item).done("task "+id, func() error {
+			if err := h.supabaseClient.DeleteTask(id); err != nil {
+				return err
+			}
+			recordAudit(c, AuditEntityTask, id, AuditActionDelete, row, nil)
+			return nil
+		})
+	}
+}
+
+// apply updates an item imported before and records its new completions;
+// new items were created by createAll
+func (h *ImportHandler) apply(c *gin.Context, userID string, item *importPlanItem, now time.Time) error {
+	if item.Action == ImportActionUpdate {
+		updates := importUpdates(item.Item, item.existing)
+		updates["updated_at"] = now.Format(time.RFC3339)
+		if err := h.supabaseClient.UpdateTask(item.TaskID, updates); err != nil {
+			return err
+		}
+		after := make(map[string]interface{}, len(item.existing))
+		for k, v := range item.existing {
+			after[k] = v
+		}
+		for k, v := range updates {
+			after[k] = v
+		}
+		recordAudit(c, AuditEntityTask, item.TaskID, AuditActionUpdate, item.existing, after)
+	}
+
+	for _, at := range item.newCompletions {
+		if err := h.supabaseClient.RecordCompletion(userID, item.TaskID, at); err != nil {
+			return fmt.Errorf("failed to record completion: %w", err)
+		}
+	}
+	return nil
+}
+
+// importTaskData builds the task row for a new imported item
+func importTaskData(item importers.Item, now time.Time) map[string]interface{} {
+	due := now
+	if item.DueDate != nil {
+		due = *item.DueDate
+	}
+	created := now
+	if item.CreatedAt != nil {
+		created = *item.CreatedAt
+	}
+
+	data := map[string]interface{}{
+		"title":       item.Title,
+		"description": item.Notes,
+		"priority":    item.Priority,
+		"due_date":    due.Format(time.RFC3339),
+		"category":    "inbox",
+		"completed":   item.Completed,
+		"created_at":  created.Format(time.RFC3339),
+		"updated_at":  now.Format(time.RFC3339),
+	}
+	if item.Category != "" {
+		data["category"] = item.Category
+	}
+	if item.Kind == importers.KindHabit {
+		data["category"] = "habit"
+		data["recurring_frequency"] = item.Frequency
+		if item.Interval > 0 {
+			data["recurring_interval"] = item.Interval
+		}
+	}
+	if item.CompletedAt != nil {
+		data["completed_at"] = item.CompletedAt.Format(time.RFC3339)
+	}
+	setLanguage(data, entityLanguage("", item.Title, item.Notes))
+	return data
+}
+
+// importUpdates returns the fields of an earlier import that the export changes
+func importUpdates(item importers.Item, row map[string]interface{}) map[string]interface{} {
+	updates := make(map[string]interface{})
+	if item.Title != rowString(row, "title") {
+		updates["title"] = item.Title
+	}
+	if item.Notes != rowString(row, "description") {
+		updates["description"] = item.Notes
+	}
+	if due, ok := rowTime(row, "due_date"); item.Kind == importers.KindTask && item.DueDate != nil && (!ok || !due.Equal(*item.DueDate)) {
+		updates["due_date"] = item.DueDate.Format(time.RFC3339)
+	}
+	if item.Kind == importers.KindTask && item.Completed != rowBool(row, "completed") {
+		updates["completed"] = item.Completed
+		if item.Completed && item.CompletedAt != nil {
+			updates["completed_at"] = item.CompletedAt.Format(time.RFC3339)
+		}
+	}
+	return updates
+}
+
+func earliestCompletion(items []importers.Item) (time.Time, bool) {
+	var earliest time.Time
+	for _, item := range items {
+		if len(item.Completions) > 0 && (earliest.IsZero() || item.Completions[0].Before(earliest)) {
+			earliest = item.Completions[0]
+		}
+	}
+	return earliest.Add(-24 * time.Hour), !earliest.IsZero()
+}
+
+func summarizeImportPlan(plan []importPlanItem) gin.H {
+	summary := gin.H{
+		ImportActionCreate:    0,
+		ImportActionUpdate:    0,
+		ImportActionUnchanged: 0,
+		ImportActionTrashed:   0,
+		"new_completions":     0,
+	}
+	for _, item := range plan {
+		summary[item.Action] = summary[item.Action].(int) + 1
+		summary["new_completions"] = summary["new_completions"].(int) + item.NewCompletions
+	}
+	return summary
+}
+
+// readImportUpload reads the export from a multipart "file" field or the raw request body
+func readImportUpload(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes)
+
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upload: %w", err)
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("export file is required (multipart field \"file\" or request body)")
+	}
+	return data, nil
+}
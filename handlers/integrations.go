@@ -0,0 +1,417 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/integrations"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/secrets"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// maxIntegrationSyncErrors bounds the errors one sync reports
+const maxIntegrationSyncErrors = 20
+
+// IntegrationHandler serves the connect, status, sync and webhook routes of
+// every registered provider and runs their background syncs
+type IntegrationHandler struct {
+	supabaseClient *db.SupabaseClient
+	registry       *integrations.Registry
+	credentials    *CredentialStore // nil without SECRETS_MASTER_KEYS
+
+	mu      sync.Mutex
+	syncing map[string]bool // user_id/provider with a sync running
+}
+
+// NewIntegrationHandler creates a new integration handler; without a
+// keyring to seal credentials with its endpoints answer 503 and nothing is
+// synced
+func NewIntegrationHandler(supabaseURL, supabaseKey string, registry *integrations.Registry, keyring *secrets.Keyring) *IntegrationHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	h := &IntegrationHandler{
+		supabaseClient: client,
+		registry:       registry,
+		syncing:        make(map[string]bool),
+	}
+	if keyring != nil {
+		h.credentials = NewCredentialStore(client, keyring)
+	}
+	return h
+}
+
+// integrationCredential names a provider's credentials in the credential store
+func integrationCredential(provider string) string {
+	return "integration:" + provider
+}
+
+// errIntegrationsDisabled answers every integration endpoint while no master key is configured
+func errIntegrationsDisabled() *utils.AppError {
+	return utils.NewAppError(utils.ErrCodeExternal, "integrations are not configured", http.StatusServiceUnavailable)
+}
+
+// provider resolves the :provider route parameter, answering the request if it cannot
+func (h *IntegrationHandler) provider(c *gin.Context) (integrations.Provider, bool) {
+	if h.credentials == nil {
+		c.Error(errIntegrationsDisabled())
+		return nil, false
+	}
+	p, ok := h.registry.Get(c.Param("provider"))
+	if !ok {
+		c.Error(utils.ErrNotFound("integration"))
+		return nil, false
+	}
+	return p, true
+}
+
+// connection reads a stored connection with its credentials
+func (h *IntegrationHandler) connection(ctx context.Context, row map[string]interface{}) (*integrations.Connection, error) {
+	conn := &integrations.Connection{
+		UserID:   rowString(row, "user_id"),
+		Provider: rowString(row, "provider"),
+	}
+	sealed, ok, err := h.credentials.Get(ctx, conn.UserID, integrationCredential(conn.Provider))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("no credentials are stored")
+	}
+	if err := json.Unmarshal(sealed, &conn.Credentials); err != nil {
+		return nil, err
+	}
+	conn.Settings, _ = row["settings"].(map[string]interface{})
+	if synced, ok := rowTime(row, "last_synced_at"); ok {
+		conn.LastSyncedAt = synced
+	}
+	return conn, nil
+}
+
+// integrationConnection describes a stored connection without its credentials
+func integrationConnection(provider string, row map[string]interface{}) models.IntegrationConnection {
+	conn := models.IntegrationConnection{Provider: provider}
+	if row == nil {
+		return conn
+	}
+	conn.Connected = true
+	conn.Settings, _ = row["settings"].(map[string]interface{})
+	conn.LastError = rowString(row, "last_error")
+	if synced, ok := rowTime(row, "last_synced_at"); ok {
+		conn.LastSyncedAt = &synced
+	}
+	return conn
+}
+
+// ListConnections returns every provider and whether the user has connected it
+// GET /api/integrations
+func (h *IntegrationHandler) ListConnections(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+	if h.credentials == nil {
+		c.Error(errIntegrationsDisabled())
+		return
+	}
+	rows, err := h.supabaseClient.WithContext(c.Request.Context()).ListUserIntegrationConnections(userID)
+	if err != nil {
+		c.Error(utils.ErrInternal("failed to list integrations").WithError(err))
+		return
+	}
+	byProvider := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		byProvider[rowString(row, "provider")] = row
+	}
+	conns := []models.IntegrationConnection{}
+	for _, name := range h.registry.Names() {
+		conns = append(conns, integrationConnection(name, byProvider[name]))
+	}
+	c.JSON(http.StatusOK, gin.H{"integrations": conns})
+}
+
+// GetConnection returns the user's connection to a provider
+// GET /api/integrations/:provider
+func (h *IntegrationHandler) GetConnection(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+	p, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	row, err := h.supabaseClient.WithContext(c.Request.Context()).GetIntegrationConnection(userID, p.Name())
+	if err != nil {
+		c.Error(utils.ErrInternal("failed to get integration").WithError(err))
+		return
+	}
+	c.JSON(http.StatusOK, integrationConnection(p.Name(), row))
+}
+
+// Connect has the provider check the credentials and settings, then stores
+// them with the credentials sealed. The first sync runs on the scheduler's
+// next pass, or at once through POST .../sync.
+// PUT /api/integrations/:provider
+func (h *IntegrationHandler) Connect(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+	p, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	var req models.IntegrationConnectRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	store := h.supabaseClient.WithContext(c.Request.Context())
+	row, err := store.GetIntegrationConnection(userID, p.Name())
+	if err != nil {
+		c.Error(utils.ErrInternal("failed to get integration").WithError(err))
+		return
+	}
+	var previous *integrations.Connection
+	if row != nil {
+		// Credentials under a master key no longer configured do not open;
+		// the provider still sees the previous settings
+		if previous, err = h.connection(c.Request.Context(), row); err != nil {
+			previous = &integrations.Connection{UserID: userID, Provider: p.Name()}
+			previous.Settings, _ = row["settings"].(map[string]interface{})
+		}
+	}
+
+	conn := &integrations.Connection{
+		UserID:      userID,
+		Provider:    p.Name(),
+		Credentials: req.Credentials,
+		Settings:    req.Settings,
+	}
+	if conn.Settings == nil {
+		conn.Settings = map[string]interface{}{}
+	}
+	if err := p.Connect(c.Request.Context(), conn, previous); err != nil {
+		if _, ok := err.(validation.Errors); ok {
+			respondValidationError(c, err)
+			return
+		}
+		var connectErr *integrations.ConnectError
+		if errors.As(err, &connectErr) {
+			c.Error(utils.ErrBadRequest(connectErr.Message).WithError(err))
+			return
+		}
+		c.Error(utils.ErrExternal(p.Name(), err.Error()).WithError(err))
+		return
+	}
+
+	credentials, err := json.Marshal(conn.Credentials)
+	if err == nil {
+		err = h.credentials.Put(c.Request.Context(), userID, integrationCredential(p.Name()), credentials)
+	}
+	if err != nil {
+		c.Error(utils.ErrInternal("failed to store credentials").WithError(err))
+		return
+	}
+	row, err = store.UpsertIntegrationConnection(userID, p.Name(), map[string]interface{}{
+		"settings":   conn.Settings,
+		"last_error": nil,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		c.Error(utils.ErrInternal("failed to save integration").WithError(err))
+		return
+	}
+	c.JSON(http.StatusOK, integrationConnection(p.Name(), row))
+}
+
+// Disconnect forgets the user's connection to a provider; what was already
+// synced to the service is left there
+// DELETE /api/integrations/:provider
+func (h *IntegrationHandler) Disconnect(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+	p, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	if err := h.supabaseClient.WithContext(c.Request.Context()).DeleteIntegrationConnection(userID, p.Name()); err != nil {
+		c.Error(utils.ErrInternal("failed to delete integration").WithError(err))
+		return
+	}
+	if err := h.credentials.Delete(c.Request.Context(), userID, integrationCredential(p.Name())); err != nil {
+		c.Error(utils.ErrInternal("failed to delete credentials").WithError(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SyncNow syncs the user's connection without waiting for the scheduler
+// POST /api/integrations/:provider/sync
+func (h *IntegrationHandler) SyncNow(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+	p, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	row, err := h.supabaseClient.WithContext(c.Request.Context()).GetIntegrationConnection(userID, p.Name())
+	if err != nil {
+		c.Error(utils.ErrInternal("failed to get integration").WithError(err))
+		return
+	}
+	if row == nil {
+		c.Error(utils.ErrNotFound("integration connection"))
+		return
+	}
+	conn, err := h.connection(c.Request.Context(), row)
+	if err != nil {
+		c.Error(utils.ErrBadRequest("the stored credentials cannot be read; connect again").WithError(err))
+		return
+	}
+	result, ok := h.sync(c.Request.Context(), p, conn)
+	if !ok {
+		c.Error(utils.ErrConflict("a sync is already running"))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Webhook hands a delivery from the service to its provider, which
+// authenticates it
+// POST /api/integrations/:provider/webhook
+func (h *IntegrationHandler) Webhook(c *gin.Context) {
+	p, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	err := p.HandleWebhook(c.Request.Context(), c.Request)
+	var connectErr *integrations.ConnectError
+	switch {
+	case err == nil:
+		c.Status(http.StatusNoContent)
+	case errors.Is(err, integrations.ErrNoWebhooks):
+		c.Error(utils.ErrNotFound("webhook"))
+	case errors.As(err, &connectErr):
+		c.Error(utils.ErrBadRequest(connectErr.Message).WithError(err))
+	default:
+		c.Error(utils.ErrInternal("failed to handle webhook").WithError(err))
+	}
+}
+
+// sync runs one provider sync and records how it went. It reports false,
+// doing nothing, if the connection's previous sync has not finished.
+func (h *IntegrationHandler) sync(ctx context.Context, p integrations.Provider, conn *integrations.Connection) (integrations.Result, bool) {
+	key := conn.UserID + "/" + p.Name()
+	h.mu.Lock()
+	if h.syncing[key] {
+		h.mu.Unlock()
+		return integrations.Result{}, false
+	}
+	h.syncing[key] = true
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.syncing, key)
+		h.mu.Unlock()
+	}()
+
+	result, err := p.Sync(ctx, conn)
+	if err != nil {
+		result.Errors = append([]string{err.Error()}, result.Errors...)
+	}
+	if len(result.Errors) > maxIntegrationSyncErrors {
+		result.Errors = result.Errors[:maxIntegrationSyncErrors]
+	}
+	status := map[string]interface{}{
+		"last_synced_at": time.Now().UTC().Format(time.RFC3339),
+		"last_error":     nil,
+	}
+	if len(result.Errors) > 0 {
+		status["last_error"] = result.Errors[0]
+	}
+	if err := h.supabaseClient.WithContext(ctx).UpdateIntegrationConnection(conn.UserID, p.Name(), status); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	return result, true
+}
+
+// RunSync checks every interval for connections whose provider's sync
+// interval has passed since their last sync and syncs them, until ctx is
+// cancelled
+func (h *IntegrationHandler) RunSync(ctx context.Context, logger *utils.Logger, interval time.Duration) {
+	if h.credentials == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, name := range h.registry.Names() {
+			p, _ := h.registry.Get(name)
+			if p.SyncInterval() <= 0 {
+				continue
+			}
+			rows, err := h.supabaseClient.WithContext(ctx).ListIntegrationConnections(name)
+			if err != nil {
+				logger.Error("Integration sync failed", err, map[string]interface{}{"provider": name})
+				continue
+			}
+			for _, row := range rows {
+				if ctx.Err() != nil {
+					return
+				}
+				conn, err := h.connection(ctx, row)
+				if err != nil {
+					logger.Warn("Integration credentials cannot be read", map[string]interface{}{
+						"provider": name, "user_id": rowString(row, "user_id"), "error": err.Error()})
+					continue
+				}
+				if !integrations.Due(p, conn, time.Now()) {
+					continue
+				}
+				if result, ok := h.sync(ctx, p, conn); ok && len(result.Errors) > 0 {
+					logger.Warn("Integration sync had errors", map[string]interface{}{
+						"provider": name, "user_id": conn.UserID, "errors": result.Errors})
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// integrationContext is the request a provider makes its changes to tasks
+// and goals in, so they are audited and published as the user's own
+func integrationContext(ctx context.Context, provider, userID string) *gin.Context {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("/api/integrations/%s/sync", provider), nil)
+	c := &gin.Context{Request: req}
+	c.Set("user_id", userID)
+	return c
+}
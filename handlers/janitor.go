@@ -0,0 +1,169 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/mcpsession"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// What the janitor purges, as reported in its counts
+const (
+	PurgeAuthCodes     = "auth_codes"      // expired or exchanged authorization codes
+	PurgeSessions      = "oauth_sessions"  // sessions SessionRetention past their token's expiry
+	PurgeRevokedTokens = "revoked_tokens"  // denylist entries of tokens that have expired anyway
+	PurgeMCPSessions   = "mcp_sessions"    // idle Mcp-Session-Id sessions
+	PurgeAuditLog      = "audit_log"       // audit entries older than AUDIT_RETENTION
+	PurgeTombstones    = "sync_tombstones" // deletions older than SyncTombstoneRetention
+)
+
+// JanitorRun is what one pass of the janitor purged
+type JanitorRun struct {
+	StartedAt time.Time         `json:"started_at"`
+	Duration  string            `json:"duration"`
+	Purged    map[string]int    `json:"purged"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// Janitor periodically purges what has outlived its use: auth codes,
+// sessions, revoked token entries, MCP sessions, old audit entries and old
+// sync tombstones.
+// Refresh tokens are signed rather than stored, so there are none to purge.
+type Janitor struct {
+	supabaseClient *db.SupabaseClient
+	mcpSessions    *mcpsession.Store
+	auditRetention time.Duration
+
+	mu      sync.Mutex
+	running bool
+	last    *JanitorRun
+	totals  map[string]int // purged since startup
+	runs    int
+}
+
+// NewJanitor creates a janitor; mcpSessions may be nil
+func NewJanitor(supabaseURL, supabaseKey string, mcpSessions *mcpsession.Store, cfg config.Janitor) *Janitor {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &Janitor{
+		supabaseClient: client,
+		mcpSessions:    mcpSessions,
+		auditRetention: cfg.AuditRetention.Duration,
+		totals:         make(map[string]int),
+	}
+}
+
+// Purge runs one pass, each kind of purge independent of the others'
+// failures. It reports false, doing nothing, while another pass is running.
+func (j *Janitor) Purge(ctx context.Context) (JanitorRun, bool) {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return JanitorRun{}, false
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	now := time.Now()
+	run := JanitorRun{StartedAt: now.UTC(), Purged: make(map[string]int), Errors: make(map[string]string)}
+	store := j.supabaseClient.WithContext(ctx)
+	record := func(kind string, n int, err error) {
+		if err != nil {
+			run.Errors[kind] = err.Error()
+			return
+		}
+		run.Purged[kind] = n
+	}
+
+	record(PurgeAuthCodes, CleanExpiredAuthCodes(), nil)
+	n, err := store.PurgeSessions(now.Add(-SessionRetention))
+	record(PurgeSessions, n, err)
+	n, err = store.PurgeRevokedTokens(now)
+	record(PurgeRevokedTokens, n, err)
+	if j.mcpSessions != nil {
+		record(PurgeMCPSessions, j.mcpSessions.Prune(), nil)
+	}
+	if j.auditRetention > 0 {
+		n, err = store.PurgeAuditLog(now.Add(-j.auditRetention))
+		record(PurgeAuditLog, n, err)
+	}
+	n, err = store.PurgeSyncTombstones(now.Add(-SyncTombstoneRetention))
+	record(PurgeTombstones, n, err)
+	if len(run.Errors) == 0 {
+		run.Errors = nil
+	}
+	run.Duration = time.Since(now).Round(time.Millisecond).String()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.running = false
+	j.last = &run
+	j.runs++
+	for kind, n := range run.Purged {
+		j.totals[kind] += n
+	}
+	return run, true
+}
+
+// Run purges once at startup and then on every interval until ctx is cancelled
+func (j *Janitor) Run(ctx context.Context, logger *utils.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if run, ok := j.Purge(ctx); ok {
+			fields := map[string]interface{}{"duration": run.Duration}
+			for kind, n := range run.Purged {
+				fields[kind] = n
+			}
+			logger.Info("Janitor purged", fields)
+			for kind, msg := range run.Errors {
+				logger.Warn("Janitor purge failed", map[string]interface{}{"kind": kind, "error": msg})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stats returns the last pass and what has been purged since startup
+func (j *Janitor) Stats() gin.H {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	totals := make(map[string]int, len(j.totals))
+	for kind, n := range j.totals {
+		totals[kind] = n
+	}
+	return gin.H{"runs": j.runs, "running": j.running, "last_run": j.last, "purged_total": totals}
+}
+
+// GetStats reports the janitor's last pass and its totals
+// GET /admin/janitor
+func (j *Janitor) GetStats(c *gin.Context) {
+	c.JSON(http.StatusOK, j.Stats())
+}
+
+// RunNow purges without waiting for the next pass
+// POST /admin/janitor/run
+func (j *Janitor) RunNow(c *gin.Context) {
+	run, ok := j.Purge(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrConflict("the janitor is already running"))
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,78 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/mcpsession"
+)
+
+func TestJanitorPurge(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+		}
+		table := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		deleted = append(deleted, table)
+		switch table {
+		case "oauth_sessions":
+			w.Header().Set("Content-Range", "*/3")
+		case "revoked_tokens":
+			w.Header().Set("Content-Range", "*/1")
+		case "sync_tombstones":
+			w.Header().Set("Content-Range", "*/5")
+		case "audit_log":
+			http.Error(w, `{"message":"boom"}`, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	now := time.Now().Unix()
+	StoreAuthCode("janitor-expired", &AuthCodeData{ExpiresAt: now - 60})
+	StoreAuthCode("janitor-used", &AuthCodeData{ExpiresAt: now + 600, Used: true})
+	StoreAuthCode("janitor-live", &AuthCodeData{ExpiresAt: now + 600})
+	defer CleanExpiredAuthCodes()
+
+	sessions := mcpsession.NewStore(time.Minute)
+	janitor := NewJanitor(srv.URL, "key", sessions, config.Janitor{AuditRetention: config.Duration{Duration: 24 * time.Hour}})
+	run, ok := janitor.Purge(context.Background())
+	if !ok {
+		t.Fatal("purge refused to run")
+	}
+
+	want := map[string]int{PurgeAuthCodes: 2, PurgeSessions: 3, PurgeRevokedTokens: 1, PurgeMCPSessions: 0, PurgeTombstones: 5}
+	for kind, n := range want {
+		if got, ok := run.Purged[kind]; !ok || got != n {
+			t.Errorf("%s: purged %d (reported %v), want %d", kind, got, ok, n)
+		}
+	}
+	if _, ok := run.Errors[PurgeAuditLog]; !ok {
+		t.Errorf("expected the audit purge failure to be reported, got %+v", run)
+	}
+	if len(deleted) != 4 {
+		t.Errorf("deleted from %v", deleted)
+	}
+	// Exchanging the live code marks it used, so the deferred clean drops it
+	if _, err := GetAuthCode("janitor-live"); err != nil {
+		t.Errorf("live auth code purged: %v", err)
+	}
+
+	stats := janitor.Stats()
+	if stats["runs"] != 1 || stats["purged_total"].(map[string]int)[PurgeSessions] != 3 {
+		t.Errorf("stats %+v", stats)
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/language"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// entityLanguage returns the explicitly requested language, or detects it from text
+func entityLanguage(explicit string, text ...string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return language.Detect(strings.Join(text, "\n"))
+}
+
+// setLanguage stores the language on a row being written, leaving it unset when undetermined
+func setLanguage(data map[string]interface{}, code string) {
+	if code != "" {
+		data["language"] = code
+	}
+}
+
+// validateLanguage checks an explicitly requested language code
+func validateLanguage(v *validation.Validator, code string) {
+	if code != "" {
+		v.Check(language.Supported(code), "language", validation.CodeInvalidValue, "language must be a supported ISO 639-1 code")
+	}
+}
+
+// updatedLanguage returns the language after a partial update of title and
+// description, or "" when neither the language nor the text changes
+func updatedLanguage(explicit, title, description *string, before map[string]interface{}) (string, bool) {
+	if explicit != nil {
+		return *explicit, true
+	}
+	if title == nil && description == nil {
+		return "", false
+	}
+	newTitle, newDescription := rowString(before, "title"), rowString(before, "description")
+	if title != nil {
+		newTitle = *title
+	}
+	if description != nil {
+		newDescription = *description
+	}
+	return language.Detect(newTitle + "\n" + newDescription), true
+}
+
+// filterByLanguage keeps the rows matching ?language=, if given
+func filterByLanguage(c *gin.Context, rows []map[string]interface{}) []map[string]interface{} {
+	code := c.Query("language")
+	if code == "" {
+		return rows
+	}
+	filtered := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		if rowString(row, "language") == code {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered
+}
+
+// nullIfEmpty maps "" to a JSON null so clearing a column stores NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
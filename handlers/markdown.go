@@ -0,0 +1,267 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/importers"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// markdownItems parses a checklist with dates in userID's time zone, due at
+// the end of their working day unless a time is given
+func (h *ImportHandler) markdownItems(c *gin.Context, userID string, data []byte) ([]importers.Item, error) {
+	_, prefs, err := userNow(h.supabaseClient.WithContext(c.Request.Context()), userID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := importers.ParseMarkdown(data, markdownOptions(prefs))
+	if err != nil {
+		return nil, utils.ErrBadRequest(err.Error())
+	}
+	return items, nil
+}
+
+// markdownOptions reads and writes checklist dates as the user's dates
+func markdownOptions(prefs models.Preferences) importers.MarkdownOptions {
+	opts := dateOptions(prefs)
+	return importers.MarkdownOptions{
+		Location:      preferencesLocation(prefs),
+		DefaultHour:   opts.DefaultHour,
+		DefaultMinute: opts.DefaultMinute,
+	}
+}
+
+// ExportMarkdown writes a project (the tasks in a category) or a day plan
+// (the tasks due on a day, today by default) as a Markdown checklist that
+// POST /api/import/markdown reads back, with subtasks nested under their tasks
+// GET /api/export/markdown?category=work or ?date=2025-03-14
+func (h *ImportHandler) ExportMarkdown(c *gin.Context) {
+	text, _, err := h.exportMarkdown(c, getUserID(c), c.Query("category"), c.Query("date"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(text))
+}
+
+// exportMarkdown renders userID's checklist for category or, without one,
+// for date, and returns it with the number of tasks in it
+func (h *ImportHandler) exportMarkdown(c *gin.Context, userID, category, date string) (string, int, error) {
+	if userID == "" {
+		return "", 0, utils.ErrBadRequest("user_id required")
+	}
+	client := h.supabaseClient.WithContext(c.Request.Context())
+	now, prefs, err := userNow(client, userID)
+	if err != nil {
+		return "", 0, err
+	}
+	day := startOfDay(now)
+	if date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, now.Location())
+		if err != nil {
+			var v validation.Validator
+			v.Add("date", validation.CodeInvalidFormat, "date must be YYYY-MM-DD")
+			return "", 0, v.Err()
+		}
+		day = parsed
+	}
+
+	tasks, err := client.GetUserTasks(userID)
+	if err != nil {
+		return "", 0, err
+	}
+	category = strings.ToLower(strings.TrimSpace(category))
+	selected := func(task map[string]interface{}) bool {
+		if category != "" {
+			return strings.ToLower(rowString(task, "category")) == category
+		}
+		due, ok := rowTime(task, "due_date")
+		return ok && !due.Before(day) && due.Before(day.AddDate(0, 0, 1))
+	}
+
+	opts := markdownOptions(prefs)
+	var b strings.Builder
+	if category != "" {
+		b.WriteString("# " + category + "\n\n")
+		roots := checklistRoots(tasks, selected)
+		items := make([]importers.ChecklistItem, len(roots))
+		for i, root := range roots {
+			items[i] = root.ChecklistItem
+		}
+		importers.WriteMarkdown(&b, items, opts)
+		return b.String(), countChecklist(roots), nil
+	}
+
+	b.WriteString("# Plan for " + day.Format("Monday, Jan 2, 2006") + "\n")
+	roots := checklistRoots(tasks, selected)
+	byCategory := map[string][]importers.ChecklistItem{}
+	var categories []string
+	for _, root := range roots {
+		name := strings.ToLower(rowString(root.row, "category"))
+		if name == "" {
+			name = "inbox"
+		}
+		if _, ok := byCategory[name]; !ok {
+			categories = append(categories, name)
+		}
+		byCategory[name] = append(byCategory[name], root.ChecklistItem)
+	}
+	sort.Strings(categories)
+	for _, name := range categories {
+		b.WriteString("\n## " + name + "\n\n")
+		importers.WriteMarkdown(&b, byCategory[name], opts)
+	}
+	if len(roots) == 0 {
+		b.WriteString("\n_Nothing due._\n")
+	}
+	return b.String(), countChecklist(roots), nil
+}
+
+// checklistRoot is a top-level checklist item and the task it was built from
+type checklistRoot struct {
+	importers.ChecklistItem
+	row map[string]interface{}
+}
+
+// checklistRoots builds the checklist of the selected tasks, soonest due
+// first, each with all its subtasks nested under it. A selected subtask of a
+// selected task appears only under its parent.
+func checklistRoots(tasks []map[string]interface{}, selected func(map[string]interface{}) bool) []checklistRoot {
+	children := map[string][]map[string]interface{}{}
+	isSelected := map[string]bool{}
+	for _, task := range tasks {
+		if parent := rowString(task, "parent_id"); parent != "" {
+			children[parent] = append(children[parent], task)
+		}
+		if selected(task) {
+			isSelected[rowString(task, "id")] = true
+		}
+	}
+
+	var build func(task map[string]interface{}, depth int) importers.ChecklistItem
+	build = func(task map[string]interface{}, depth int) importers.ChecklistItem {
+		item := importers.ChecklistItem{Title: rowString(task, "title"), Completed: rowBool(task, "completed")}
+		if due, ok := rowTime(task, "due_date"); ok {
+			item.Due = &due
+		}
+		// Guard against a cycle of parent_ids
+		if depth < maxChecklistDepth {
+			kids := children[rowString(task, "id")]
+			sortByDue(kids)
+			for _, child := range kids {
+				item.Children = append(item.Children, build(child, depth+1))
+			}
+		}
+		return item
+	}
+
+	var roots []map[string]interface{}
+	for _, task := range tasks {
+		if isSelected[rowString(task, "id")] && !isSelected[rowString(task, "parent_id")] {
+			roots = append(roots, task)
+		}
+	}
+	sortByDue(roots)
+	items := make([]checklistRoot, len(roots))
+	for i, task := range roots {
+		items[i] = checklistRoot{ChecklistItem: build(task, 0), row: task}
+	}
+	return items
+}
+
+// maxChecklistDepth is the deepest subtasks are nested in an export
+const maxChecklistDepth = 16
+
+func sortByDue(tasks []map[string]interface{}) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, _ := rowTime(tasks[i], "due_date")
+		b, _ := rowTime(tasks[j], "due_date")
+		return a.Before(b)
+	})
+}
+
+func countChecklist(roots []checklistRoot) int {
+	var count func(items []importers.ChecklistItem) int
+	count = func(items []importers.ChecklistItem) int {
+		n := len(items)
+		for _, item := range items {
+			n += count(item.Children)
+		}
+		return n
+	}
+	n := 0
+	for _, root := range roots {
+		n += 1 + count(root.Children)
+	}
+	return n
+}
+
+// RegisterTools adds import_markdown and export_markdown to the MCP tools
+func (h *ImportHandler) RegisterTools(tools *ToolRegistry) {
+	tools.Register(Tool{
+		Name:        "import_markdown",
+		Description: "Create tasks from a Markdown checklist: \"- [ ] item\" lines, \"- [x]\" for done ones, indented items as subtasks, \"(due 2025-03-14)\" annotations as due dates and headings as categories. Importing an edited checklist again updates the tasks it created.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"markdown": {Type: "string", Description: "The checklist", MinLength: 1},
+			},
+			Required: []string{"markdown"},
+		},
+		Scope:   middleware.ScopeWrite,
+		Handler: h.importMarkdown,
+	})
+	tools.Register(Tool{
+		Name:        "export_markdown",
+		Description: "Write a project (the tasks in a category) or a day plan (the tasks due on a day, today by default) as a Markdown checklist, with subtasks nested and due dates annotated, that import_markdown reads back",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"category": {Type: "string", Description: "Export this category instead of a day"},
+				"date":     {Type: "string", Description: "Day to export, YYYY-MM-DD (default: today)"},
+			},
+		},
+		Handler: h.exportMarkdownTool,
+	})
+}
+
+func (h *ImportHandler) importMarkdown(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	userID := mcpUserID(c, "")
+	text, _ := params["markdown"].(string)
+	items, err := h.markdownItems(c, userID, []byte(text))
+	if err != nil {
+		return nil, "", err
+	}
+	plan, err := h.planItems(userID, importers.SourceMarkdown, items)
+	if err == nil {
+		err = checkImportLimit(c, plan)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	failures := h.run(c, plan)
+	return gin.H{
+		"summary": summarizeImportPlan(plan.items),
+		"items":   plan.items,
+		"errors":  failures,
+	}, "", nil
+}
+
+func (h *ImportHandler) exportMarkdownTool(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	category, _ := params["category"].(string)
+	date, _ := params["date"].(string)
+	text, count, err := h.exportMarkdown(c, mcpUserID(c, ""), category, date)
+	if err != nil {
+		return nil, "", err
+	}
+	return gin.H{"markdown": text, "tasks": count}, "", nil
+}
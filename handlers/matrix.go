@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/productivity/mcp-server/models"
+)
+
+// Eisenhower matrix quadrants
+const (
+	QuadrantDo        = "do"
+	QuadrantSchedule  = "schedule"
+	QuadrantDelegate  = "delegate"
+	QuadrantEliminate = "eliminate"
+)
+
+const (
+	// importantPriority is the lowest priority counted as important
+	importantPriority = 4
+	// urgentDays is how many days, today included, a task may be due within
+	// to count as urgent; overdue tasks always are
+	urgentDays = 2
+)
+
+// matrixQuadrants are the quadrants in the order they are returned
+var matrixQuadrants = []models.MatrixQuadrant{
+	{ID: QuadrantDo, Label: "Do first", Urgent: true, Important: true},
+	{ID: QuadrantSchedule, Label: "Schedule", Important: true},
+	{ID: QuadrantDelegate, Label: "Delegate", Urgent: true},
+	{ID: QuadrantEliminate, Label: "Eliminate"},
+}
+
+// quadrantFor names the quadrant of an urgent and/or important task
+func quadrantFor(urgent, important bool) string {
+	switch {
+	case urgent && important:
+		return QuadrantDo
+	case important:
+		return QuadrantSchedule
+	case urgent:
+		return QuadrantDelegate
+	}
+	return QuadrantEliminate
+}
+
+// classifyTask places an open task by the rules: priority 4 or 5 is
+// important, and being due by the end of tomorrow in now's time zone is urgent
+func classifyTask(task map[string]interface{}, now time.Time) (string, models.MatrixTask) {
+	item := models.MatrixTask{
+		ID:       rowString(task, "id"),
+		Title:    rowString(task, "title"),
+		Priority: rowInt(task, "priority"),
+	}
+	important := item.Priority >= importantPriority
+
+	urgent := false
+	when := "no due date"
+	if due, ok := rowTime(task, "due_date"); ok {
+		due = due.In(now.Location())
+		item.DueDate = &due
+		today := startOfDay(now)
+		days := int(startOfDay(due).Sub(today).Hours() / 24)
+		urgent = due.Before(today.AddDate(0, 0, urgentDays))
+		switch {
+		case due.Before(today):
+			when = "overdue"
+		case days == 0:
+			when = "due today"
+		case days == 1:
+			when = "due tomorrow"
+		default:
+			when = fmt.Sprintf("due in %d days", days)
+		}
+	}
+	item.Reason = fmt.Sprintf("Priority %d, %s", item.Priority, when)
+	return quadrantFor(urgent, important), item
+}
+
+// buildMatrix sorts open tasks into the quadrants by the rules, soonest due
+// first within each
+func buildMatrix(tasks []map[string]interface{}, now time.Time) *models.EisenhowerMatrix {
+	matrix := &models.EisenhowerMatrix{
+		Quadrants: make([]models.MatrixQuadrant, len(matrixQuadrants)),
+		Timezone:  now.Location().String(),
+	}
+	for i, q := range matrixQuadrants {
+		matrix.Quadrants[i] = q
+		matrix.Quadrants[i].Tasks = []models.MatrixTask{}
+	}
+	for _, task := range tasks {
+		if rowBool(task, "completed") {
+			continue
+		}
+		quadrant, item := classifyTask(task, now)
+		q := matrixQuadrant(matrix, quadrant)
+		q.Tasks = append(q.Tasks, item)
+	}
+	for i := range matrix.Quadrants {
+		sortMatrixTasks(matrix.Quadrants[i].Tasks)
+	}
+	return matrix
+}
+
+// moveMatrixTask moves the task with id to another quadrant for reason. It
+// reports false if the task or the quadrant is unknown.
+func moveMatrixTask(matrix *models.EisenhowerMatrix, id, quadrant, reason string) bool {
+	to := matrixQuadrant(matrix, quadrant)
+	if to == nil {
+		return false
+	}
+	for i := range matrix.Quadrants {
+		from := &matrix.Quadrants[i]
+		for j, item := range from.Tasks {
+			if item.ID != id {
+				continue
+			}
+			if from.ID == quadrant {
+				return false
+			}
+			from.Tasks = append(from.Tasks[:j], from.Tasks[j+1:]...)
+			if reason != "" {
+				item.Reason = reason
+			}
+			to.Tasks = append(to.Tasks, item)
+			sortMatrixTasks(to.Tasks)
+			return true
+		}
+	}
+	return false
+}
+
+func matrixQuadrant(matrix *models.EisenhowerMatrix, id string) *models.MatrixQuadrant {
+	for i := range matrix.Quadrants {
+		if matrix.Quadrants[i].ID == id {
+			return &matrix.Quadrants[i]
+		}
+	}
+	return nil
+}
+
+// sortMatrixTasks orders tasks by due date, undated last, then by priority
+func sortMatrixTasks(tasks []models.MatrixTask) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		switch {
+		case a.DueDate == nil || b.DueDate == nil:
+			if (a.DueDate == nil) != (b.DueDate == nil) {
+				return b.DueDate == nil
+			}
+		case !a.DueDate.Equal(*b.DueDate):
+			return a.DueDate.Before(*b.DueDate)
+		}
+		return a.Priority > b.Priority
+	})
+}
@@ -0,0 +1,101 @@
+//go:build lite
+
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/mockllm"
+	"github.com/productivity/mcp-server/models"
+)
+
+func TestEisenhowerMatrix(t *testing.T) {
+	dbURL := db.SQLiteScheme + filepath.Join(t.TempDir(), "matrix.db")
+	client, err := db.NewSupabaseClient(dbURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := startOfDay(time.Now().UTC())
+	add := func(title, description string, priority int, due time.Time, completed bool) {
+		t.Helper()
+		if _, err := client.CreateTask("u1", map[string]interface{}{
+			"title":       title,
+			"description": description,
+			"priority":    priority,
+			"due_date":    due.Format(time.RFC3339),
+			"completed":   completed,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("file taxes", "", 5, today.Add(-time.Hour), false)
+	add("ship release", "", 4, today.AddDate(0, 0, 1).Add(20*time.Hour), false)
+	add("write strategy", "", 4, today.AddDate(0, 0, 2), false)
+	add("reply to survey", "", 2, today.Add(12*time.Hour), false)
+	add("tidy bookmarks", "someday", 1, today.AddDate(0, 0, 30), false)
+	add("book dentist", "asap, tooth hurts", 3, today.AddDate(0, 0, 5), false)
+	add("done already", "", 5, today, true)
+
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+	service := NewAIService(dbURL, "", config.Claude{
+		APIKey:    "mock",
+		BaseURL:   llm.URL,
+		Model:     "claude-3-5-sonnet-20241022",
+		MaxTokens: 1024,
+		Timeout:   config.Duration{Duration: 5 * time.Second},
+	})
+
+	cases := []struct {
+		refine bool
+		want   map[string][]string
+	}{
+		{false, map[string][]string{
+			QuadrantDo:        {"file taxes", "ship release"},
+			QuadrantSchedule:  {"write strategy"},
+			QuadrantDelegate:  {"reply to survey"},
+			QuadrantEliminate: {"book dentist", "tidy bookmarks"},
+		}},
+		{true, map[string][]string{
+			QuadrantDo:        {"file taxes", "ship release", "book dentist"},
+			QuadrantSchedule:  {"write strategy"},
+			QuadrantDelegate:  {"reply to survey"},
+			QuadrantEliminate: {"tidy bookmarks"},
+		}},
+	}
+	for _, tc := range cases {
+		matrix, err := service.EisenhowerMatrix(context.Background(), models.EisenhowerMatrixRequest{UserID: "u1", Refine: tc.refine})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matrix.Refined != tc.refine {
+			t.Errorf("refine %v: refined %v (%s)", tc.refine, matrix.Refined, matrix.Explanation)
+		}
+		if len(matrix.Quadrants) != len(matrixQuadrants) {
+			t.Fatalf("refine %v: %d quadrants", tc.refine, len(matrix.Quadrants))
+		}
+		for _, q := range matrix.Quadrants {
+			var titles []string
+			for _, task := range q.Tasks {
+				titles = append(titles, task.Title)
+			}
+			want := tc.want[q.ID]
+			if len(titles) != len(want) {
+				t.Errorf("refine %v, %s: got %v, want %v", tc.refine, q.ID, titles, want)
+				continue
+			}
+			for i := range titles {
+				if titles[i] != want[i] {
+					t.Errorf("refine %v, %s: got %v, want %v", tc.refine, q.ID, titles, want)
+					break
+				}
+			}
+		}
+	}
+}
@@ -2,39 +2,121 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
-	"net/http/httptest"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/mcpsession"
 	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
 )
 
 // MCPHandler holds handlers for MCP protocol
 type MCPHandler struct {
-	taskHandler   *TaskHandler
-	goalHandler   *GoalHandler
-	claudeHandler *ClaudeHandler
+	tasks *TaskService
+	goals *GoalService
+	ai    *AIService
+	undo  *UndoService
+	tools *ToolRegistry
+
+	// requireDryRun makes mutating tools wait for a confirmed dry run
+	requireDryRun bool
 }
 
 // NewMCPHandler creates a new MCP handler
-func NewMCPHandler(taskHandler *TaskHandler, goalHandler *GoalHandler, claudeHandler *ClaudeHandler) *MCPHandler {
-	return &MCPHandler{
-		taskHandler:   taskHandler,
-		goalHandler:   goalHandler,
-		claudeHandler: claudeHandler,
+func NewMCPHandler(taskHandler *TaskHandler, goalHandler *GoalHandler, claudeHandler *ClaudeHandler, undoHandler *UndoHandler) *MCPHandler {
+	return newMCPHandler(taskHandler.service, goalHandler.service, claudeHandler.service, undoHandler.service)
+}
+
+func newMCPHandler(tasks *TaskService, goals *GoalService, ai *AIService, undo *UndoService) *MCPHandler {
+	m := &MCPHandler{tasks: tasks, goals: goals, ai: ai, undo: undo, tools: NewToolRegistry()}
+	m.registerTools()
+	return m
+}
+
+// Tools returns the registry, so callers can register further tools
+func (m *MCPHandler) Tools() *ToolRegistry {
+	return m.tools
+}
+
+// MCPProtocolVersions are the MCP protocol versions the server speaks, newest first
+var MCPProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// negotiateProtocolVersion picks the protocol version for a client. A version
+// the server speaks is echoed back; a client that asks for none, or for one
+// newer than the server knows, is offered the newest so it can decide whether
+// to continue. Only versions older than every supported one are refused.
+func negotiateProtocolVersion(requested string) (string, bool) {
+	if requested == "" {
+		return MCPProtocolVersions[0], true
+	}
+	for _, version := range MCPProtocolVersions {
+		if requested == version {
+			return version, true
+		}
 	}
+	// Versions are dates, so they order as strings
+	if requested > MCPProtocolVersions[0] {
+		return MCPProtocolVersions[0], true
+	}
+	return "", false
 }
 
-// MCPInitialize handles MCP protocol initialization
+// MCPInitialize handles MCP protocol initialization, negotiating the
+// protocol version. When sessions are enabled it opens one and returns its ID
+// in the Mcp-Session-Id header. While the server shuts down it answers 503.
 func MCPInitialize(c *gin.Context) {
+	// The body is optional; clients that send none still get the defaults
+	var req models.MCPRequest
+	c.ShouldBindJSON(&req)
+	id := req.ID
+	if id == 0 {
+		id = 1
+	}
+	if MCPDraining() {
+		refuseInitialize(c, id)
+		return
+	}
+
+	requested, _ := req.Params["protocolVersion"].(string)
+	version, ok := negotiateProtocolVersion(requested)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"jsonrpc": "2.0",
+			"id":      id,
+			"error": gin.H{
+				"code":    codeInvalidParams,
+				"message": "Unsupported protocol version " + requested + "; supported versions are " + strings.Join(MCPProtocolVersions, ", "),
+				"data": gin.H{
+					"requested": requested,
+					"supported": MCPProtocolVersions,
+				},
+			},
+		})
+		return
+	}
+
+	if mcpSessions != nil {
+		session, err := mcpSessions.Create(c.GetString("user_id"), version, initializeClientInfo(req.Params))
+		if err != nil {
+			c.Error(utils.ErrInternal("failed to open MCP session").WithError(err))
+			return
+		}
+		c.Header(mcpsession.Header, session.ID)
+	}
+
 	response := gin.H{
 		"jsonrpc": "2.0",
-		"id":      1,
+		"id":      id,
 		"result": gin.H{
-			"protocolVersion": "2024-11-05",
+			"protocolVersion": version,
 			"capabilities": gin.H{
 				"logging": gin.H{},
 				"tools":   gin.H{},
@@ -50,368 +132,245 @@ func MCPInitialize(c *gin.Context) {
 }
 
 // MCPListTools returns available tools for Claude
-func MCPListTools(c *gin.Context) {
-	tools := []gin.H{
-		{
-			"name":        "create_task",
-			"description": "Create a new task in the productivity app",
-			"inputSchema": gin.H{
-				"type": "object",
-				"properties": gin.H{
-					"title": gin.H{
-						"type":        "string",
-						"description": "Task title",
-					},
-					"description": gin.H{
-						"type":        "string",
-						"description": "Task description",
-					},
-					"due_date": gin.H{
-						"type":        "string",
-						"description": "Due date in ISO 8601 format",
-					},
-					"priority": gin.H{
-						"type":        "integer",
-						"description": "Priority level (1-5)",
-					},
-				},
-				"required": []string{"title", "due_date"},
-			},
-		},
-		{
-			"name":        "create_goal",
-			"description": "Create a new goal in the productivity app",
-			"inputSchema": gin.H{
-				"type": "object",
-				"properties": gin.H{
-					"title": gin.H{
-						"type":        "string",
-						"description": "Goal title",
-					},
-					"description": gin.H{
-						"type":        "string",
-						"description": "Goal description",
-					},
-					"target_date": gin.H{
-						"type":        "string",
-						"description": "Target date in ISO 8601 format",
-					},
-				},
-				"required": []string{"title", "target_date"},
-			},
-		},
-		{
-			"name":        "parse_task",
-			"description": "Parse natural language input into a structured task",
-			"inputSchema": gin.H{
-				"type": "object",
-				"properties": gin.H{
-					"input": gin.H{
-						"type":        "string",
-						"description": "Natural language task description",
-					},
-				},
-				"required": []string{"input"},
-			},
-		},
-		{
-			"name":        "generate_subtasks",
-			"description": "Generate subtasks for a given task",
-			"inputSchema": gin.H{
-				"type": "object",
-				"properties": gin.H{
-					"task_title": gin.H{
-						"type":        "string",
-						"description": "Main task title",
-					},
-					"task_description": gin.H{
-						"type":        "string",
-						"description": "Task description for context",
-					},
-				},
-				"required": []string{"task_title"},
-			},
-		},
-		{
-			"name":        "analyze_productivity",
-			"description": "Analyze user productivity patterns and provide insights",
-			"inputSchema": gin.H{
-				"type": "object",
-				"properties": gin.H{
-					"days": gin.H{
-						"type":        "integer",
-						"description": "Number of days to analyze (default: 7)",
-					},
-				},
-			},
-		},
-	}
-
+func (m *MCPHandler) MCPListTools(c *gin.Context) {
 	response := gin.H{
 		"jsonrpc": "2.0",
 		"id":      1,
 		"result": gin.H{
-			"tools": tools,
+			"tools": m.toolsFor(c),
 		},
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// MCPCallTool handles tool calls from Claude
-func (m *MCPHandler) MCPCallTool(c *gin.Context) {
-	var req models.MCPRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"jsonrpc": "2.0",
-			"id":      1,
-			"error": gin.H{
-				"code":    -32700,
-				"message": "Parse error",
-			},
-		})
-		return
+// toolsFor lists the tools the caller's feature flags give them
+func (m *MCPHandler) toolsFor(c *gin.Context) []*Tool {
+	all := m.tools.List()
+	if featureFlags == nil {
+		return all
 	}
-
-	// Extract params
-	params := req.Params
-	if params == nil {
-		params = make(map[string]interface{})
-	}
-
-	// Route to appropriate handler based on method
-	var result interface{}
-	var errMsg string
-
-	switch req.Method {
-	case "create_task":
-		title, _ := params["title"].(string)
-		description, _ := params["description"].(string)
-		dueDateStr, _ := params["due_date"].(string)
-		priority, _ := params["priority"].(float64)
-		userID, _ := params["user_id"].(string)
-
-		if title == "" || dueDateStr == "" {
-			errMsg = "title and due_date are required"
-			break
-		}
-
-		dueDate, err := time.Parse(time.RFC3339, dueDateStr)
-		if err != nil {
-			dueDate, err = time.Parse("2006-01-02T15:04:05Z07:00", dueDateStr)
-			if err != nil {
-				errMsg = "invalid due_date format"
-				break
-			}
-		}
-
-		if userID != "" {
-			c.Set("user_id", userID)
-		} else {
-			c.Set("user_id", getUserID(c))
-		}
-
-		// Create request body
-		reqBody := models.CreateTaskRequest{
-			Title:       title,
-			Description: description,
-			DueDate:     dueDate,
-			Priority:    int(priority),
-		}
-		if reqBody.Priority == 0 {
-			reqBody.Priority = 3
-		}
-
-		// Bind JSON to context
-		c.Request.Body = io.NopCloser(bytes.NewBuffer(mustMarshal(reqBody)))
-		statusCode, body := captureHandlerResponse(c, m.taskHandler.CreateTask)
-
-		if statusCode == http.StatusCreated {
-			var taskData map[string]interface{}
-			if err := json.Unmarshal(body, &taskData); err == nil {
-				result = taskData
-			} else {
-				result = gin.H{"status": "created"}
-			}
-		} else {
-			var errData map[string]interface{}
-			json.Unmarshal(body, &errData)
-			errMsg, _ = errData["error"].(string)
-		}
-
-	case "create_goal":
-		title, _ := params["title"].(string)
-		description, _ := params["description"].(string)
-		targetDateStr, _ := params["target_date"].(string)
-		userID, _ := params["user_id"].(string)
-
-		if title == "" || targetDateStr == "" {
-			errMsg = "title and target_date are required"
-			break
+	userID := getUserID(c)
+	tools := make([]*Tool, 0, len(all))
+	for _, tool := range all {
+		if tool.Flag == "" || flagEnabled(c, tool.Flag, userID) {
+			tools = append(tools, tool)
 		}
+	}
+	return tools
+}
 
-		targetDate, err := time.Parse(time.RFC3339, targetDateStr)
-		if err != nil {
-			targetDate, err = time.Parse("2006-01-02T15:04:05Z07:00", targetDateStr)
-			if err != nil {
-				errMsg = "invalid target_date format"
-				break
-			}
-		}
+// MaxBatchSize caps the number of calls in one JSON-RPC batch
+const MaxBatchSize = 20
 
-		if userID != "" {
-			c.Set("user_id", userID)
-		} else {
-			c.Set("user_id", getUserID(c))
-		}
+// batchWorkers bounds how many calls of a batch run at once
+const batchWorkers = 4
 
-		reqBody := models.CreateGoalRequest{
-			Title:       title,
-			Description: description,
-			StartDate:   time.Now(),
-			TargetDate:  targetDate,
-		}
+// MCPCallTool handles tool calls from Claude. The body is a single JSON-RPC
+// request or a batch (an array of them); a batch gets an array of responses
+// in the same order, with the calls run concurrently. A notifications/cancelled
+// message stops the caller's call with that id and gets no response.
+func (m *MCPHandler) MCPCallTool(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, rpcError(1, -32700, "Parse error"))
+		return
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		m.callBatch(c, trimmed)
+		return
+	}
 
-		c.Request.Body = io.NopCloser(bytes.NewBuffer(mustMarshal(reqBody)))
-		statusCode, body := captureHandlerResponse(c, m.goalHandler.CreateGoal)
-
-		if statusCode == http.StatusCreated {
-			var goalData map[string]interface{}
-			if err := json.Unmarshal(body, &goalData); err == nil {
-				result = goalData
-			} else {
-				result = gin.H{"status": "created"}
-			}
-		} else {
-			var errData map[string]interface{}
-			json.Unmarshal(body, &errData)
-			errMsg, _ = errData["error"].(string)
-		}
+	var req models.MCPRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		c.JSON(http.StatusBadRequest, rpcError(1, -32700, "Parse error"))
+		return
+	}
+	if req.Method == MCPMethodCancelled {
+		handleCancelled(c, req.Params)
+		c.Status(http.StatusAccepted)
+		return
+	}
+	if wantsProgressStream(c, req) {
+		m.streamTool(c, req)
+		return
+	}
+	c.JSON(m.callTool(c, req, nil))
+}
 
-	case "parse_task":
-		input, _ := params["input"].(string)
-		userID, _ := params["user_id"].(string)
+// callBatch runs each request of a batch on its own copy of c, at most
+// batchWorkers at a time. The batch answers 200; failed calls carry their own
+// error objects. Notifications are skipped, so a batch of nothing else gets
+// 202 with no body.
+func (m *MCPHandler) callBatch(c *gin.Context, body []byte) {
+	var messages []json.RawMessage
+	if err := json.Unmarshal(body, &messages); err != nil {
+		c.JSON(http.StatusBadRequest, rpcError(1, -32700, "Parse error"))
+		return
+	}
+	if len(messages) == 0 || len(messages) > MaxBatchSize {
+		c.JSON(http.StatusBadRequest, rpcError(1, -32600, fmt.Sprintf("batch must hold 1 to %d requests", MaxBatchSize)))
+		return
+	}
 
-		if input == "" {
-			errMsg = "input is required"
-			break
+	responses := make([]gin.H, len(messages))
+	sem := make(chan struct{}, batchWorkers)
+	var wg sync.WaitGroup
+	for i, msg := range messages {
+		var req models.MCPRequest
+		if err := json.Unmarshal(msg, &req); err != nil {
+			responses[i] = rpcError(req.ID, -32600, "Invalid Request")
+			continue
 		}
-
-		reqBody := models.ParseTaskRequest{
-			Input:  input,
-			UserID: userID,
+		if req.Method == MCPMethodCancelled {
+			handleCancelled(c, req.Params)
+			continue
 		}
-
-		c.Request.Body = io.NopCloser(bytes.NewBuffer(mustMarshal(reqBody)))
-		statusCode, body := captureHandlerResponse(c, m.claudeHandler.ParseTask)
-
-		if statusCode == http.StatusOK {
-			var parseData map[string]interface{}
-			json.Unmarshal(body, &parseData)
-			result = parseData
-		} else {
-			var errData map[string]interface{}
-			json.Unmarshal(body, &errData)
-			errMsg, _ = errData["error"].(string)
+		if isNotification(msg, req.Method) {
+			continue
 		}
 
-	case "generate_subtasks":
-		taskTitle, _ := params["task_title"].(string)
-		taskDesc, _ := params["task_description"].(string)
-		userID, _ := params["user_id"].(string)
-
-		if taskTitle == "" {
-			errMsg = "task_title is required"
-			break
-		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ctx *gin.Context, req models.MCPRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, responses[i] = m.callTool(ctx, req, nil)
+		}(i, c.Copy(), req)
+	}
+	wg.Wait()
 
-		reqBody := models.GenerateSubtasksRequest{
-			TaskTitle:       taskTitle,
-			TaskDescription: taskDesc,
-			UserID:          userID,
+	// Notifications have no response; a batch of only notifications gets none at all
+	answered := make([]gin.H, 0, len(responses))
+	for _, resp := range responses {
+		if resp != nil {
+			answered = append(answered, resp)
 		}
+	}
+	if len(answered) == 0 {
+		c.Status(http.StatusAccepted)
+		return
+	}
+	c.JSON(http.StatusOK, answered)
+}
 
-		c.Request.Body = io.NopCloser(bytes.NewBuffer(mustMarshal(reqBody)))
-		statusCode, body := captureHandlerResponse(c, m.claudeHandler.GenerateSubtasks)
-
-		if statusCode == http.StatusOK {
-			var subtaskData map[string]interface{}
-			json.Unmarshal(body, &subtaskData)
-			result = subtaskData
-		} else {
-			var errData map[string]interface{}
-			json.Unmarshal(body, &errData)
-			errMsg, _ = errData["error"].(string)
-		}
+// isNotification reports whether a batch message expects no response: it
+// carries no id, or is one of the notifications/* methods
+func isNotification(msg json.RawMessage, method string) bool {
+	if strings.HasPrefix(method, "notifications/") {
+		return true
+	}
+	var probe struct {
+		ID json.RawMessage `json:"id"`
+	}
+	return json.Unmarshal(msg, &probe) == nil && probe.ID == nil
+}
 
-	case "analyze_productivity":
-		userID, _ := params["user_id"].(string)
-		days, _ := params["days"].(float64)
+// rpcError builds a JSON-RPC error response
+func rpcError(id, code int, message string) gin.H {
+	return gin.H{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+	}
+}
 
-		if userID == "" {
-			errMsg = "user_id is required"
-			break
-		}
+// callTool runs one tool call, returning the HTTP status and JSON-RPC response.
+// Long-running tools report their steps to progress when it is set. The
+// call's Claude and Supabase requests stop when the client disconnects or
+// cancels it with notifications/cancelled.
+func (m *MCPHandler) callTool(c *gin.Context, req models.MCPRequest, progress Progress) (int, gin.H) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	defer trackCall(c, req.ID, cancel)()
+	defer func(r *http.Request) { c.Request = r }(c.Request)
+	c.Request = c.Request.WithContext(ctx)
+
+	// An unknown tool is a protocol error, and unknown names are not recorded
+	// so callers cannot grow the SLO tracker
+	tool, ok := m.tools.Lookup(req.Method)
+	if !ok {
+		return http.StatusBadRequest, rpcError(req.ID, -32601, "Unknown method: "+req.Method)
+	}
+	if !callerHasScope(c, tool.Scope) {
+		return http.StatusForbidden, rpcError(req.ID, codeUnauthorized, "Forbidden: API key lacks the "+tool.Scope+" scope")
+	}
 
-		reqBody := models.AnalyzeProductivityRequest{
-			UserID: userID,
-			Days:   int(days),
+	params := req.Params
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	// Arguments are checked against the schema list_tools advertises, so
+	// tools can rely on their types
+	if errs := tool.InputSchema.Validate(params); errs != nil {
+		return invalidParamsResponse(req.ID, errs)
+	}
+	named, _ := params["user_id"].(string)
+	if err := checkToolUser(c, named); err != nil {
+		return http.StatusOK, gin.H{"jsonrpc": "2.0", "id": req.ID, "result": toolError(toolErrorMessage(c.Request.Context(), err))}
+	}
+	if tool.Flag != "" && !flagEnabled(c, tool.Flag, getUserID(c)) {
+		return http.StatusOK, gin.H{"jsonrpc": "2.0", "id": req.ID, "result": toolError(toolErrorMessage(c.Request.Context(), errFlagDisabled(tool.Flag)))}
+	}
+	if tool.AI {
+		if err := takeAICall(c, getUserID(c)); err != nil {
+			return http.StatusOK, gin.H{"jsonrpc": "2.0", "id": req.ID, "result": toolError(toolErrorMessage(c.Request.Context(), err))}
 		}
+	}
 
-		c.Request.Body = io.NopCloser(bytes.NewBuffer(mustMarshal(reqBody)))
-		statusCode, body := captureHandlerResponse(c, m.claudeHandler.AnalyzeProductivity)
-
-		if statusCode == http.StatusOK {
-			var analyzeData map[string]interface{}
-			json.Unmarshal(body, &analyzeData)
-			result = analyzeData
-		} else {
-			var errData map[string]interface{}
-			json.Unmarshal(body, &errData)
-			errMsg, _ = errData["error"].(string)
-		}
+	start := time.Now()
+	result, resourceURI, err := m.runTool(c, tool, params, progress)
+	// Cancelled calls say nothing about the tool's health
+	if ctx.Err() == nil {
+		sloTracker.Record("tool:"+tool.Name, time.Since(start), err != nil)
+	}
 
-	default:
-		errMsg = "Unknown method: " + req.Method
+	if ctx.Err() != nil {
+		return cancelledResponse(req.ID)
 	}
 
-	if errMsg != "" {
-		c.JSON(http.StatusBadRequest, gin.H{
+	// A tool that fails reports it in its result so the model can see what
+	// went wrong and retry
+	if err != nil {
+		return http.StatusOK, gin.H{
 			"jsonrpc": "2.0",
 			"id":      req.ID,
-			"error": gin.H{
-				"code":    -32601,
-				"message": errMsg,
-			},
-		})
-		return
+			"result":  toolError(toolErrorMessage(c.Request.Context(), err)),
+		}
 	}
 
-	response := gin.H{
+	return http.StatusOK, gin.H{
 		"jsonrpc": "2.0",
 		"id":      req.ID,
-		"result":  result,
+		"result":  toolResult(result, resourceURI),
 	}
-
-	c.JSON(http.StatusOK, response)
 }
 
-func mustMarshal(v interface{}) []byte {
-	data, _ := json.Marshal(v)
-	return data
+// mcpUserID picks the user a tool acts for: the user_id param if given,
+// otherwise the caller. call_tool has already refused a param naming anyone
+// but a signed-in caller, unless they are a service account (see
+// checkToolUser). The user is also stored on c so audit entries name them.
+func mcpUserID(c *gin.Context, userID string) string {
+	if userID == "" {
+		userID = getUserID(c)
+	}
+	c.Set("user_id", userID)
+	return userID
 }
 
-func captureHandlerResponse(src *gin.Context, handler func(*gin.Context)) (int, []byte) {
-	rec := httptest.NewRecorder()
-	ctx, _ := gin.CreateTestContext(rec)
-	ctx.Request = src.Request
-	if src.Keys != nil {
-		for k, v := range src.Keys {
-			ctx.Set(k, v)
-		}
+// toolErrorMessage turns a service error into a tool error message: field
+// errors are joined, AppErrors give their message, and anything else is
+// logged and reported generically so internal details do not leak
+func toolErrorMessage(ctx context.Context, err error) string {
+	switch e := err.(type) {
+	case validation.Errors:
+		return e.Error()
+	case *utils.AppError:
+		return e.Message
 	}
-
-	handler(ctx)
-	return rec.Code, rec.Body.Bytes()
+	utils.LoggerFromContext(ctx).Error("MCP tool failed", err)
+	return "internal server error"
 }
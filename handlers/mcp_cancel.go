@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/mcpsession"
+)
+
+// MCPMethodCancelled is the notification a client sends to abandon a call it made
+const MCPMethodCancelled = "notifications/cancelled"
+
+// codeRequestCancelled answers a call that was cancelled before it finished
+const codeRequestCancelled = -32800
+
+// inflightCall is a tool call that can still be cancelled
+type inflightCall struct {
+	cancel context.CancelFunc
+}
+
+// inflightCalls holds the tool calls in progress, keyed by caller, MCP session
+// and JSON-RPC id so a client can only cancel its own calls. Request ids are
+// only unique within a session; two sessions of one user may both send id 1.
+var inflightCalls = struct {
+	sync.Mutex
+	calls map[string]*inflightCall
+}{calls: make(map[string]*inflightCall)}
+
+// inflightKey identifies the call with the given id made on c's session
+func inflightKey(c *gin.Context, id int) string {
+	return getUserID(c) + "\x00" + c.GetHeader(mcpsession.Header) + "\x00" + strconv.Itoa(id)
+}
+
+// trackCall registers a call's cancel func until the returned untrack is
+// called, which removes the entry only if it is still this call's
+func trackCall(c *gin.Context, id int, cancel context.CancelFunc) (untrack func()) {
+	key := inflightKey(c, id)
+	call := &inflightCall{cancel: cancel}
+
+	inflightCalls.Lock()
+	inflightCalls.calls[key] = call
+	inflightCalls.Unlock()
+
+	return func() {
+		inflightCalls.Lock()
+		defer inflightCalls.Unlock()
+		// A later call reusing the id may have replaced this one
+		if inflightCalls.calls[key] == call {
+			delete(inflightCalls.calls, key)
+		}
+	}
+}
+
+// cancelCall cancels the call with the given id made on c's session,
+// reporting whether one was running
+func cancelCall(c *gin.Context, id int) bool {
+	inflightCalls.Lock()
+	call := inflightCalls.calls[inflightKey(c, id)]
+	inflightCalls.Unlock()
+
+	if call == nil {
+		return false
+	}
+	call.cancel()
+	return true
+}
+
+// inflightCount returns how many tool calls are running
+func inflightCount() int {
+	inflightCalls.Lock()
+	defer inflightCalls.Unlock()
+	return len(inflightCalls.calls)
+}
+
+// cancelAllCalls cancels every running tool call and returns how many there were
+func cancelAllCalls() int {
+	inflightCalls.Lock()
+	defer inflightCalls.Unlock()
+	for _, call := range inflightCalls.calls {
+		call.cancel()
+	}
+	return len(inflightCalls.calls)
+}
+
+// handleCancelled processes a notifications/cancelled message. The call may
+// already have finished, so an unknown requestId is ignored as the spec asks.
+func handleCancelled(c *gin.Context, params map[string]interface{}) {
+	if id, ok := params["requestId"].(float64); ok {
+		cancelCall(c, int(id))
+	}
+}
+
+// cancelledResponse answers a call whose context was cancelled. The client has
+// stopped waiting, but an HTTP request still needs a body. A call cut short by
+// a shutdown says so, since the client should retry it.
+func cancelledResponse(id int) (int, gin.H) {
+	if MCPDraining() {
+		return http.StatusServiceUnavailable, rpcError(id, codeRequestCancelled, "Request cancelled: the server is restarting, retry it")
+	}
+	return http.StatusBadRequest, rpcError(id, codeRequestCancelled, "Request cancelled")
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResourceScheme is the URI scheme of the tasks and goals embedded in tool results
+const ResourceScheme = "productivity://"
+
+// TaskResourceURI names a task as an MCP resource
+func TaskResourceURI(id string) string {
+	return ResourceScheme + "tasks/" + id
+}
+
+// GoalResourceURI names a goal as an MCP resource
+func GoalResourceURI(id string) string {
+	return ResourceScheme + "goals/" + id
+}
+
+// toolResult wraps a tool's result as MCP content: a text block holding the
+// JSON for clients that only read text, the same value as structuredContent
+// for clients that parse it, and, when resourceURI is set, the created task or
+// goal as an embedded resource
+func toolResult(value interface{}, resourceURI string) gin.H {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return toolError("failed to encode tool result")
+	}
+
+	content := []gin.H{{"type": "text", "text": string(data)}}
+	if resourceURI != "" {
+		content = append(content, gin.H{
+			"type": "resource",
+			"resource": gin.H{
+				"uri":      resourceURI,
+				"mimeType": "application/json",
+				"text":     string(data),
+			},
+		})
+	}
+	return gin.H{
+		"content":           content,
+		"structuredContent": json.RawMessage(data),
+		"isError":           false,
+	}
+}
+
+// toolError reports a failed tool call as an isError result
+func toolError(message string) gin.H {
+	return gin.H{
+		"content": []gin.H{{"type": "text", "text": message}},
+		"isError": true,
+	}
+}
@@ -0,0 +1,238 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/mockllm"
+	"github.com/productivity/mcp-server/mocksupabase"
+	"github.com/productivity/mcp-server/stdio"
+)
+
+var updateContract = flag.Bool("update", false, "rewrite the MCP contract fixtures in testdata/mcp from the server's answers")
+
+// anyValue in a fixture's expected answer matches any value, for the parts
+// that change from run to run such as ids, timestamps and dates
+const anyValue = "<any>"
+
+// contractFixture is a conversation with the server over stdio: each message
+// is sent in turn, and those with an expected answer must get exactly it
+type contractFixture struct {
+	Description string            `json:"description"`
+	Messages    []contractMessage `json:"messages"`
+}
+
+type contractMessage struct {
+	Send    json.RawMessage `json:"send,omitempty"`
+	SendRaw string          `json:"send_raw,omitempty"` // a line that is not JSON
+	Expect  json.RawMessage `json:"expect,omitempty"`   // none for notifications
+}
+
+// TestMCPContract replays the JSON-RPC conversations in testdata/mcp against
+// the MCP routes as Claude Desktop reaches them, through the stdio transport,
+// with Supabase and Claude faked. Run with -update to record new answers;
+// "<any>" in an expected answer is kept.
+func TestMCPContract(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	paths, err := filepath.Glob(filepath.Join("testdata", "mcp", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no fixtures in testdata/mcp: %v", err)
+	}
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fixture contractFixture
+			if err := json.Unmarshal(data, &fixture); err != nil {
+				t.Fatalf("invalid fixture: %v", err)
+			}
+
+			var in bytes.Buffer
+			for _, msg := range fixture.Messages {
+				if msg.SendRaw != "" {
+					in.WriteString(msg.SendRaw + "\n")
+					continue
+				}
+				var compact bytes.Buffer
+				if err := json.Compact(&compact, msg.Send); err != nil {
+					t.Fatalf("invalid message %s: %v", msg.Send, err)
+				}
+				in.Write(append(compact.Bytes(), '\n'))
+			}
+			var out bytes.Buffer
+			if err := stdio.NewServer(newContractRouter(t)).Serve(context.Background(), &in, &out); err != nil {
+				t.Fatal(err)
+			}
+
+			answers := strings.Split(strings.TrimSpace(out.String()), "\n")
+			next := 0
+			for i, msg := range fixture.Messages {
+				if len(msg.Expect) == 0 {
+					continue
+				}
+				if next >= len(answers) {
+					t.Fatalf("message %d got no answer", i)
+				}
+				var got, want interface{}
+				if err := json.Unmarshal([]byte(answers[next]), &got); err != nil {
+					t.Fatalf("message %d: invalid answer %q", i, answers[next])
+				}
+				next++
+				json.Unmarshal(msg.Expect, &want)
+				if *updateContract {
+					fixture.Messages[i].Expect = fixtureJSON(t, keepAny(want, got))
+					continue
+				}
+				if err := matchContract("$", want, got); err != nil {
+					t.Errorf("message %d %s: %v\nanswer: %s", i, msg.Send, err, answers[next-1])
+				}
+			}
+			if next != len(answers) {
+				t.Errorf("%d unexpected answers: %s", len(answers)-next, strings.Join(answers[next:], "\n"))
+			}
+
+			if *updateContract {
+				if err := os.WriteFile(path, fixtureJSON(t, fixture), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// newContractRouter serves the MCP routes as the stdio server does, for the
+// local user, with every tool the server registers
+func newContractRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	supabase := httptest.NewServer(mocksupabase.NewHandler())
+	t.Cleanup(supabase.Close)
+	llm := httptest.NewServer(mockllm.NewHandler())
+	t.Cleanup(llm.Close)
+
+	claudeCfg := config.Defaults().Claude
+	claudeCfg.APIKey = "mock"
+	claudeCfg.BaseURL = llm.URL
+	claudeHandler := NewClaudeHandler(supabase.URL, "contract-key", claudeCfg)
+	m := NewMCPHandler(NewTaskHandler(supabase.URL, "contract-key"), NewGoalHandler(supabase.URL, "contract-key"),
+		claudeHandler, NewUndoHandler(supabase.URL, "contract-key"))
+	NewOllamaHandler(llm.URL, "llama3.2").RegisterTools(m.Tools())
+	NewImportHandler(supabase.URL, "contract-key", claudeHandler).RegisterTools(m.Tools())
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "contract-user") })
+	router.POST("/mcp/initialize", MCPInitialize)
+	router.POST("/mcp/list_tools", m.MCPListTools)
+	router.POST("/mcp/call_tool", m.MCPCallTool)
+	return router
+}
+
+// matchContract reports where got differs from want. Objects must have the
+// same keys, so fields added to or dropped from an answer are caught too.
+func matchContract(path string, want, got interface{}) error {
+	if want == anyValue {
+		return nil
+	}
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %s, want an object", path, compactJSON(got))
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		for key := range g {
+			if _, ok := w[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			wv, inWant := w[key]
+			gv, inGot := g[key]
+			switch {
+			case !inWant:
+				return fmt.Errorf("%s.%s: unexpected field, = %s", path, key, compactJSON(gv))
+			case !inGot:
+				return fmt.Errorf("%s.%s: missing", path, key)
+			}
+			if err := matchContract(path+"."+key, wv, gv); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return fmt.Errorf("%s: got %s, want %d elements", path, compactJSON(got), len(w))
+		}
+		for i := range w {
+			if err := matchContract(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(want, got) {
+		return fmt.Errorf("%s: got %s, want %s", path, compactJSON(got), compactJSON(want))
+	}
+	return nil
+}
+
+// keepAny is got with the "<any>" placeholders of want where got has a value
+func keepAny(want, got interface{}) interface{} {
+	if want == anyValue {
+		return anyValue
+	}
+	switch g := got.(type) {
+	case map[string]interface{}:
+		if w, ok := want.(map[string]interface{}); ok {
+			for key, gv := range g {
+				g[key] = keepAny(w[key], gv)
+			}
+		}
+	case []interface{}:
+		if w, ok := want.([]interface{}); ok && len(w) == len(g) {
+			for i := range g {
+				g[i] = keepAny(w[i], g[i])
+			}
+		}
+	}
+	return got
+}
+
+func compactJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// fixtureJSON is v indented, with "<any>" left readable
+func fixtureJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
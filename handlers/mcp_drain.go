@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/mcpsession"
+)
+
+// codeServerDraining answers an initialize sent while the server shuts down
+const codeServerDraining = -32002
+
+// mcpDrainPoll is how often DrainMCP checks whether the last call has finished
+const mcpDrainPoll = 50 * time.Millisecond
+
+// mcpDrainRetryAfter is the Retry-After sent with a refused initialize; by
+// then the replacement instance is normally taking traffic
+const mcpDrainRetryAfter = "5"
+
+// mcpDrain is closed when the server starts shutting down
+var (
+	mcpDrain     = make(chan struct{})
+	mcpDrainOnce sync.Once
+)
+
+// MCPDraining reports whether DrainMCP has been called
+func MCPDraining() bool {
+	select {
+	case <-mcpDrain:
+		return true
+	default:
+		return false
+	}
+}
+
+// DrainMCP prepares the MCP endpoints for shutdown: initialize stops opening
+// sessions, calls streaming progress tell their client the server is
+// restarting, and DrainMCP waits for the tool calls in flight to finish.
+// Calls still running when ctx is done are cancelled; it returns how many.
+func DrainMCP(ctx context.Context) int {
+	mcpDrainOnce.Do(func() { close(mcpDrain) })
+
+	ticker := time.NewTicker(mcpDrainPoll)
+	defer ticker.Stop()
+	for inflightCount() > 0 {
+		select {
+		case <-ctx.Done():
+			return cancelAllCalls()
+		case <-ticker.C:
+		}
+	}
+	return 0
+}
+
+// drainingNotification is the notifications/message sent on a progress stream
+// when the server starts shutting down. The call itself still finishes if it
+// can; the session survives the restart.
+func drainingNotification() gin.H {
+	return gin.H{
+		"jsonrpc": "2.0",
+		"method":  "notifications/message",
+		"params": gin.H{
+			"level":  "warning",
+			"logger": "server",
+			"data":   "The server is restarting. This call will finish if it can; send later requests with the same " + mcpsession.Header + " to resume.",
+		},
+	}
+}
+
+// refuseInitialize answers an initialize sent while the server is draining, so
+// the client retries against the instance replacing this one
+func refuseInitialize(c *gin.Context, id int) {
+	c.Header("Retry-After", mcpDrainRetryAfter)
+	c.JSON(http.StatusServiceUnavailable, rpcError(id, codeServerDraining, "Server is restarting; retry initialize shortly"))
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+)
+
+func TestDrainMCP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func() {
+		mcpDrain = make(chan struct{})
+		mcpDrainOnce = sync.Once{}
+	}()
+
+	started := make(chan struct{})
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer llm.Close()
+	m := newMCPHandler(nil, nil, NewAIService("", "", config.Claude{APIKey: "mock", BaseURL: llm.URL, MaxTokens: 1024, Timeout: config.Duration{Duration: time.Minute}}), nil)
+
+	type reply struct {
+		status int
+		resp   map[string]interface{}
+	}
+	replies := make(chan reply, 1)
+	go func() {
+		status, resp := callTool(t, m, `{"jsonrpc":"2.0","id":7,"method":"parse_task","params":{"input":"write the report"}}`)
+		replies <- reply{status, resp}
+	}()
+	<-started
+
+	// The call outlives the drain deadline, so it is cancelled with a 503
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if n := DrainMCP(ctx); n != 1 {
+		t.Errorf("DrainMCP cancelled %d calls, want 1", n)
+	}
+	select {
+	case r := <-replies:
+		errObj, _ := r.resp["error"].(map[string]interface{})
+		if r.status != http.StatusServiceUnavailable || errObj == nil || errObj["code"].(float64) != codeRequestCancelled {
+			t.Errorf("drained call: status %d, response %v", r.status, r.resp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call was not cancelled")
+	}
+
+	// With nothing in flight a second drain returns at once
+	if n := DrainMCP(context.Background()); n != 0 || !MCPDraining() {
+		t.Errorf("second DrainMCP = %d, draining %v", n, MCPDraining())
+	}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/mcp/initialize", strings.NewReader(`{"jsonrpc":"2.0","id":3,"method":"initialize"}`))
+	MCPInitialize(c)
+	var resp map[string]interface{}
+	json.Unmarshal(recorder.Body.Bytes(), &resp)
+	errObj, _ := resp["error"].(map[string]interface{})
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Header().Get("Retry-After") == "" || errObj["code"].(float64) != codeServerDraining {
+		t.Errorf("initialize while draining: status %d, headers %v, response %v", recorder.Code, recorder.Header(), resp)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// confirmationTTL is how long a dry run's confirmation token stays valid
+const confirmationTTL = 10 * time.Minute
+
+// PreviewFunc is a mutating tool's dry run: it checks the arguments as the
+// tool would and describes the change without making it
+type PreviewFunc func(c *gin.Context, params map[string]interface{}) (*ChangePreview, error)
+
+// dryRunProperties are added to the input schema of every tool with a Preview
+var dryRunProperties = map[string]*validation.Schema{
+	"dry_run": {
+		Type:        "boolean",
+		Description: "Return the change this call would make, and a confirmation_token, without making it",
+	},
+	"confirmation_token": {
+		Type:        "string",
+		Description: "Token from a dry run with the same arguments; required when the server asks for confirmation",
+	},
+}
+
+// withDryRunProperties returns a copy of schema that also accepts the dry run arguments
+func withDryRunProperties(schema *validation.Schema) *validation.Schema {
+	copied := *schema
+	copied.Properties = make(map[string]*validation.Schema, len(schema.Properties)+len(dryRunProperties))
+	for name, prop := range schema.Properties {
+		copied.Properties[name] = prop
+	}
+	for name, prop := range dryRunProperties {
+		copied.Properties[name] = prop
+	}
+	return &copied
+}
+
+// SetRequireDryRun makes mutating tools refuse to run without the confirmation
+// token from a dry run with the same arguments, so a person can approve each
+// change an agent proposes
+func (m *MCPHandler) SetRequireDryRun(require bool) {
+	m.requireDryRun = require
+}
+
+// runTool runs a tool, or previews it when the call sets dry_run. A preview
+// carries a single-use token that confirms a later call with the same
+// arguments; with SetRequireDryRun, mutating tools run only with one.
+func (m *MCPHandler) runTool(c *gin.Context, tool *Tool, params map[string]interface{}, progress Progress) (interface{}, string, error) {
+	if tool.Preview == nil {
+		return tool.Handler(c, params, progress)
+	}
+
+	key := confirmationKey(getUserID(c), tool.Name, params)
+	if dryRun, _ := params["dry_run"].(bool); dryRun {
+		preview, err := tool.Preview(c, params)
+		if err != nil {
+			return nil, "", err
+		}
+		if preview.ConfirmationToken, err = issueConfirmation(key); err != nil {
+			return nil, "", utils.ErrInternal("failed to issue confirmation token").WithError(err)
+		}
+		return preview, "", nil
+	}
+
+	if m.requireDryRun {
+		token, _ := params["confirmation_token"].(string)
+		if !redeemConfirmation(token, key) {
+			return nil, "", utils.ErrBadRequest(tool.Name + " needs confirmation: call it with dry_run set to true, " +
+				"then again with the same arguments and the confirmation_token it returns")
+		}
+	}
+	return tool.Handler(c, params, progress)
+}
+
+// confirmationKey identifies a call by caller, tool and arguments, leaving
+// out the dry run arguments themselves
+func confirmationKey(userID, tool string, params map[string]interface{}) string {
+	args := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		if _, ok := dryRunProperties[name]; !ok {
+			args[name] = value
+		}
+	}
+	// Map keys marshal in sorted order, so equal arguments hash alike
+	encoded, _ := json.Marshal(args)
+	sum := sha256.Sum256(append([]byte(userID+"\x00"+tool+"\x00"), encoded...))
+	return hex.EncodeToString(sum[:])
+}
+
+// confirmation is an unused token from a dry run
+type confirmation struct {
+	key     string
+	expires time.Time
+}
+
+// confirmations holds the tokens issued by dry runs until they are used or expire
+var confirmations = struct {
+	sync.Mutex
+	tokens map[string]confirmation
+}{tokens: make(map[string]confirmation)}
+
+func issueConfirmation(key string) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	now := time.Now()
+
+	confirmations.Lock()
+	defer confirmations.Unlock()
+	for t, conf := range confirmations.tokens {
+		if now.After(conf.expires) {
+			delete(confirmations.tokens, t)
+		}
+	}
+	confirmations.tokens[token] = confirmation{key: key, expires: now.Add(confirmationTTL)}
+	return token, nil
+}
+
+// redeemConfirmation uses up token, reporting whether it was issued for key and is still valid
+func redeemConfirmation(token, key string) bool {
+	confirmations.Lock()
+	defer confirmations.Unlock()
+
+	conf, ok := confirmations.tokens[token]
+	if !ok || conf.key != key {
+		return false
+	}
+	delete(confirmations.tokens, token)
+	return time.Now().Before(conf.expires)
+}
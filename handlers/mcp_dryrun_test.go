@@ -0,0 +1,74 @@
+//go:build lite
+
+package handlers
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+)
+
+func TestMCPDryRunConfirmation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, err := db.NewSupabaseClient(db.SQLiteScheme+filepath.Join(t.TempDir(), "dryrun.db"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newMCPHandler(NewTaskService(client), nil, nil, nil)
+	m.SetRequireDryRun(true)
+
+	args := `"title":"Ship it","due_date":"2099-01-01T00:00:00Z","user_id":"u1"`
+	call := func(extra string) map[string]interface{} {
+		t.Helper()
+		_, resp := callTool(t, m, `{"jsonrpc":"2.0","id":1,"method":"create_task","params":{`+args+extra+`}}`)
+		result, _ := resp["result"].(map[string]interface{})
+		if result == nil {
+			t.Fatalf("no result in %v", resp)
+		}
+		return result
+	}
+	taskCount := func() int {
+		tasks, err := client.GetUserTasks("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(tasks)
+	}
+
+	if result := call(""); result["isError"] != true {
+		t.Fatalf("unconfirmed call ran: %v", result)
+	}
+
+	preview := call(`,"dry_run":true`)
+	structured, _ := json.Marshal(preview["structuredContent"])
+	var change ChangePreview
+	if err := json.Unmarshal(structured, &change); err != nil {
+		t.Fatal(err)
+	}
+	if !change.DryRun || change.Action != AuditActionCreate || change.After["title"] != "Ship it" || change.ConfirmationToken == "" {
+		t.Fatalf("unexpected preview %+v", change)
+	}
+	if n := taskCount(); n != 0 {
+		t.Fatalf("dry run stored %d tasks", n)
+	}
+
+	// The token only confirms the same arguments, and only once
+	args = strings.Replace(args, "Ship it", "Ship something else", 1)
+	if result := call(`,"confirmation_token":"` + change.ConfirmationToken + `"`); result["isError"] != true {
+		t.Fatalf("token confirmed different arguments: %v", result)
+	}
+	args = strings.Replace(args, "Ship something else", "Ship it", 1)
+	if result := call(`,"confirmation_token":"` + change.ConfirmationToken + `"`); result["isError"] != false {
+		t.Fatalf("confirmed call failed: %v", result)
+	}
+	if result := call(`,"confirmation_token":"` + change.ConfirmationToken + `"`); result["isError"] != true {
+		t.Fatalf("token was accepted twice: %v", result)
+	}
+	if n := taskCount(); n != 1 {
+		t.Fatalf("expected 1 task, got %d", n)
+	}
+}
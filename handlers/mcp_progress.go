@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/models"
+)
+
+// mcpKeepAlive is how often a progress stream with nothing to report sends an
+// SSE comment, so proxies don't close it while a tool waits on Claude
+const mcpKeepAlive = 15 * time.Second
+
+// progressToken returns params._meta.progressToken, or nil if the caller did
+// not ask for progress
+func progressToken(params map[string]interface{}) interface{} {
+	meta, _ := params["_meta"].(map[string]interface{})
+	return meta["progressToken"]
+}
+
+// wantsProgressStream reports whether a call should be answered over SSE: the
+// client accepts text/event-stream and sent a progress token. Everything else
+// keeps getting a plain JSON response.
+func wantsProgressStream(c *gin.Context, req models.MCPRequest) bool {
+	return strings.Contains(c.GetHeader("Accept"), "text/event-stream") && progressToken(req.Params) != nil
+}
+
+// streamTool runs a tool call and answers over SSE: one notifications/progress
+// message per step the tool reports, then the JSON-RPC response as the final
+// message. If the server starts shutting down meanwhile the client is told
+// with a notifications/message warning. The write deadline is lifted so a slow tool can't hit the server's
+// WriteTimeout part way through; the Claude client's own timeout bounds the call.
+func (m *MCPHandler) streamTool(c *gin.Context, req models.MCPRequest) {
+	token := progressToken(req.Params)
+	ctx := c.Request.Context()
+	notifications := make(chan gin.H)
+	done := make(chan gin.H, 1)
+
+	progress := func(progress, total float64, message string) {
+		params := gin.H{"progressToken": token, "progress": progress}
+		if total > 0 {
+			params["total"] = total
+		}
+		if message != "" {
+			params["message"] = message
+		}
+		select {
+		case notifications <- gin.H{"jsonrpc": "2.0", "method": "notifications/progress", "params": params}:
+		case <-ctx.Done():
+		}
+	}
+	go func(tc *gin.Context) {
+		_, resp := m.callTool(tc, req, progress)
+		done <- resp
+	}(c.Copy())
+
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(mcpKeepAlive)
+	defer keepAlive.Stop()
+	draining := mcpDrain
+	for {
+		select {
+		case notification := <-notifications:
+			c.SSEvent("message", notification)
+		case <-draining:
+			c.SSEvent("message", drainingNotification())
+			draining = nil
+		case resp := <-done:
+			c.SSEvent("message", resp)
+			c.Writer.Flush()
+			return
+		case <-keepAlive.C:
+			io.WriteString(c.Writer, ": keep-alive\n\n")
+		case <-ctx.Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
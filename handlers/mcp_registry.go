@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// codeInvalidParams is the JSON-RPC error for arguments that break a tool's input schema
+const codeInvalidParams = -32602
+
+// codeUnauthorized is the JSON-RPC error the auth middleware answers with;
+// tool calls reuse it for a caller lacking a tool's scope
+const codeUnauthorized = -32001
+
+// ToolFunc runs a tool whose arguments already match its input schema. It
+// returns the result and, for a tool that creates something, the resource URI
+// to embed. Errors are reported to the model as in toolErrorMessage.
+type ToolFunc func(c *gin.Context, params map[string]interface{}, progress Progress) (result interface{}, resourceURI string, err error)
+
+// Tool is a tool offered over MCP
+type Tool struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	InputSchema *validation.Schema `json:"inputSchema"`
+	// Scope is the API key scope a caller needs on top of mcp; empty for none.
+	// Bearer tokens are not scoped.
+	Scope   string   `json:"-"`
+	Handler ToolFunc `json:"-"`
+	// Preview marks a tool that changes data and implements its dry_run
+	Preview PreviewFunc `json:"-"`
+	// AI marks a tool that calls a model, which counts against the caller's
+	// plan's daily AI calls
+	AI bool `json:"-"`
+	// Flag is the feature flag a caller needs for the tool; empty for none
+	Flag string `json:"-"`
+}
+
+// ToolRegistry holds the tools list_tools advertises and call_tool runs, so
+// the two cannot drift apart
+type ToolRegistry struct {
+	tools  []*Tool
+	byName map[string]*Tool
+}
+
+// NewToolRegistry creates an empty registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{byName: make(map[string]*Tool)}
+}
+
+// Register adds a tool. Registering a name twice is a programming error and panics.
+func (r *ToolRegistry) Register(tool Tool) {
+	if _, ok := r.byName[tool.Name]; ok {
+		panic("mcp tool registered twice: " + tool.Name)
+	}
+	if tool.InputSchema == nil {
+		tool.InputSchema = &validation.Schema{Type: "object"}
+	}
+	if tool.Preview != nil {
+		tool.InputSchema = withDryRunProperties(tool.InputSchema)
+	}
+	r.tools = append(r.tools, &tool)
+	r.byName[tool.Name] = &tool
+}
+
+// Lookup returns the tool called name
+func (r *ToolRegistry) Lookup(name string) (*Tool, bool) {
+	tool, ok := r.byName[name]
+	return tool, ok
+}
+
+// List returns the tools in registration order
+func (r *ToolRegistry) List() []*Tool {
+	return r.tools
+}
+
+// callerHasScope reports whether the caller may use a tool needing scope. Only
+// API keys carry scopes; other principals may use every tool.
+func callerHasScope(c *gin.Context, scope string) bool {
+	if scope == "" || c.GetString("auth_method") != "api_key" {
+		return true
+	}
+	key := middleware.APIKey{Scopes: c.GetStringSlice("api_key_scopes")}
+	return key.HasScope(scope)
+}
+
+// invalidParamsResponse answers a call whose arguments break the tool's
+// schema, listing each field error in the error's data
+func invalidParamsResponse(id int, errs validation.Errors) (int, gin.H) {
+	resp := rpcError(id, codeInvalidParams, "Invalid params: "+errs.Error())
+	resp["error"].(gin.H)["data"] = gin.H{"errors": errs}
+	return http.StatusBadRequest, resp
+}
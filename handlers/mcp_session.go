@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/mcpsession"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// mcpSessions holds the sessions opened by /mcp/initialize; nil disables sessions
+var mcpSessions *mcpsession.Store
+
+// SetMCPSessions issues an Mcp-Session-Id from /mcp/initialize, tracked in store
+func SetMCPSessions(store *mcpsession.Store) {
+	mcpSessions = store
+}
+
+// initializeClientInfo reads params.clientInfo from an initialize request
+func initializeClientInfo(params map[string]interface{}) mcpsession.ClientInfo {
+	info, _ := params["clientInfo"].(map[string]interface{})
+	name, _ := info["name"].(string)
+	version, _ := info["version"].(string)
+	return mcpsession.ClientInfo{Name: name, Version: version}
+}
+
+// MCPEndSession ends the session named by the Mcp-Session-Id header
+// DELETE /mcp/session
+func MCPEndSession(c *gin.Context) {
+	id := c.GetHeader(mcpsession.Header)
+	if id == "" {
+		c.Error(utils.ErrBadRequest(mcpsession.Header + " header is required"))
+		return
+	}
+	if mcpSessions == nil {
+		c.Error(utils.ErrNotFound("session"))
+		return
+	}
+	session, ok := mcpSessions.Get(id)
+	if !ok || session.UserID != c.GetString("user_id") {
+		c.Error(utils.ErrNotFound("session"))
+		return
+	}
+	mcpSessions.Delete(id)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,96 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"time"
+
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/mcpsession"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// mcpSessionDB holds sessions saved on shutdown; nil keeps them in memory only
+var mcpSessionDB *db.SupabaseClient
+
+// SetMCPSessionPersistence saves the MCP sessions in Supabase on shutdown and
+// lets the store resume sessions a previous instance saved, so a redeploy
+// does not send every connected client back to initialize. Call it after
+// SetMCPSessions.
+func SetMCPSessionPersistence(supabaseURL, supabaseKey string) {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	mcpSessionDB = client
+	if mcpSessions != nil {
+		mcpSessions.SetLoader(loadMCPSession)
+	}
+}
+
+// SaveMCPSessions stores the open MCP sessions for the next instance and
+// returns how many. Saved sessions that have since expired are dropped.
+func SaveMCPSessions() (int, error) {
+	if mcpSessionDB == nil || mcpSessions == nil {
+		return 0, nil
+	}
+	if _, err := mcpSessionDB.PurgeMCPSessions(time.Now().Add(-mcpSessions.Idle())); err != nil {
+		utils.DefaultLogger().Error("Failed to purge saved MCP sessions", err)
+	}
+
+	snapshot := mcpSessions.Snapshot()
+	rows := make([]map[string]interface{}, 0, len(snapshot))
+	for _, session := range snapshot {
+		rows = append(rows, mcpSessionRow(session))
+	}
+	if err := mcpSessionDB.SaveMCPSessions(rows); err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// loadMCPSession takes over a session saved by a previous instance. The row
+// is deleted once loaded; from then on this instance holds the session.
+func loadMCPSession(id string) (mcpsession.Session, bool) {
+	row, err := mcpSessionDB.GetMCPSession(id)
+	if err != nil {
+		utils.DefaultLogger().Error("Failed to load saved MCP session", err)
+		return mcpsession.Session{}, false
+	}
+	if row == nil {
+		return mcpsession.Session{}, false
+	}
+	if err := mcpSessionDB.DeleteMCPSession(id); err != nil {
+		utils.DefaultLogger().Error("Failed to delete saved MCP session", err)
+	}
+	return mcpSessionFromRow(row), true
+}
+
+func mcpSessionRow(session mcpsession.Session) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               session.ID,
+		"user_id":          session.UserID,
+		"protocol_version": session.ProtocolVersion,
+		"client_name":      session.ClientInfo.Name,
+		"client_version":   session.ClientInfo.Version,
+		"created_at":       session.CreatedAt.UTC().Format(time.RFC3339Nano),
+		"last_seen_at":     session.LastSeenAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+func mcpSessionFromRow(row map[string]interface{}) mcpsession.Session {
+	str := func(key string) string {
+		value, _ := row[key].(string)
+		return value
+	}
+	created, _ := time.Parse(time.RFC3339Nano, str("created_at"))
+	lastSeen, _ := time.Parse(time.RFC3339Nano, str("last_seen_at"))
+	return mcpsession.Session{
+		ID:              str("id"),
+		UserID:          str("user_id"),
+		ProtocolVersion: str("protocol_version"),
+		ClientInfo:      mcpsession.ClientInfo{Name: str("client_name"), Version: str("client_version")},
+		CreatedAt:       created,
+		LastSeenAt:      lastSeen,
+	}
+}
@@ -1,28 +1,484 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/mcpsession"
+	"github.com/productivity/mcp-server/mockllm"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
 )
 
-func TestCaptureHandlerResponse(t *testing.T) {
+// callTool posts one JSON-RPC tools call to m and decodes the response
+func callTool(t *testing.T, m *MCPHandler, body string) (int, map[string]interface{}) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/mcp/call_tool", strings.NewReader(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	m.MCPCallTool(ctx)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", recorder.Body, err)
+	}
+	return recorder.Code, resp
+}
+
+func TestMCPCallToolCallsServicesDirectly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+
+	m := newMCPHandler(NewTaskService(nil), nil, NewAIService("", "", config.Claude{
+		APIKey:    "mock",
+		BaseURL:   llm.URL,
+		Model:     "claude-3-5-sonnet-20241022",
+		MaxTokens: 1024,
+		Timeout:   config.Duration{Duration: 5 * time.Second},
+	}), nil)
+
+	status, resp := callTool(t, m, `{"jsonrpc":"2.0","id":7,"method":"parse_task","params":{"input":"urgent: send the client report tomorrow"}}`)
+	if status != http.StatusOK || resp["id"].(float64) != 7 {
+		t.Fatalf("parse_task: status %d, response %v", status, resp)
+	}
+	result := resp["result"].(map[string]interface{})
+	task := result["structuredContent"].(map[string]interface{})["task"].(map[string]interface{})
+	if task["priority"].(float64) != 5 || result["isError"] != false {
+		t.Errorf("parse_task: unexpected result %v", result)
+	}
+	text := result["content"].([]interface{})[0].(map[string]interface{})
+	if text["type"] != "text" || !strings.Contains(text["text"].(string), `"priority":5`) {
+		t.Errorf("parse_task: unexpected text block %v", text)
+	}
+
+	// Field errors from the service come back as one readable isError result
+	status, resp = callTool(t, m, `{"jsonrpc":"2.0","id":8,"method":"create_task","params":{"title":"`+
+		strings.Repeat("x", 300)+`","due_date":"2099-01-01T00:00:00Z","user_id":"u1"}}`)
+	result = resp["result"].(map[string]interface{})
+	text = result["content"].([]interface{})[0].(map[string]interface{})
+	if status != http.StatusOK || result["isError"] != true || !strings.Contains(text["text"].(string), "title") {
+		t.Fatalf("create_task: status %d, response %v", status, resp)
+	}
+
+	status, resp = callTool(t, m, `{"jsonrpc":"2.0","id":9,"method":"delete_everything"}`)
+	errObj, _ := resp["error"].(map[string]interface{})
+	if status != http.StatusBadRequest || errObj["code"].(float64) != -32601 {
+		t.Fatalf("unknown tool: status %d, response %v", status, resp)
+	}
+}
+
+func TestMCPCallToolInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newMCPHandler(NewTaskService(nil), nil, nil, nil)
+
+	status, resp := callTool(t, m, `{"jsonrpc":"2.0","id":4,"method":"create_task","params":{"title":"Ship it","due_date":"next week","priority":"high"}}`)
+	errObj, _ := resp["error"].(map[string]interface{})
+	if status != http.StatusBadRequest || errObj == nil || errObj["code"].(float64) != codeInvalidParams || resp["id"].(float64) != 4 {
+		t.Fatalf("status %d, response %v", status, resp)
+	}
+	fields := errObj["data"].(map[string]interface{})["errors"].([]interface{})
+	if len(fields) != 2 {
+		t.Fatalf("expected errors for due_date and priority, got %v", fields)
+	}
+	for i, want := range []string{"due_date", "priority"} {
+		if got := fields[i].(map[string]interface{})["field"]; got != want {
+			t.Errorf("error %d names %v, want %s", i, got, want)
+		}
+	}
+
+	status, resp = callTool(t, m, `{"jsonrpc":"2.0","id":5,"method":"create_goal","params":{"title":"Run a marathon"}}`)
+	if errObj, _ := resp["error"].(map[string]interface{}); status != http.StatusBadRequest || errObj == nil || !strings.Contains(errObj["message"].(string), "target_date is required") {
+		t.Fatalf("missing target_date: status %d, response %v", status, resp)
+	}
+}
+
+func TestToolRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newMCPHandler(NewTaskService(nil), nil, nil, nil)
+	m.Tools().Register(Tool{
+		Name: "echo",
+		Handler: func(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+			return params, "", nil
+		},
+	})
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	m.MCPListTools(ctx)
+	var listed struct {
+		Result struct {
+			Tools []map[string]interface{} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	tools := listed.Result.Tools
+	last := len(tools) - 1
+	if last < 1 || tools[0]["name"] != "create_task" || tools[last]["name"] != "echo" || tools[last]["inputSchema"] == nil {
+		t.Fatalf("unexpected tools %v", tools)
+	}
+
+	status, resp := callTool(t, m, `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"say":"hi"}}`)
+	result, _ := resp["result"].(map[string]interface{})
+	if status != http.StatusOK || result["structuredContent"].(map[string]interface{})["say"] != "hi" {
+		t.Fatalf("echo: status %d, response %v", status, resp)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a tool twice should panic")
+		}
+	}()
+	m.Tools().Register(Tool{Name: "echo"})
+}
+
+func TestMCPCallToolChecksScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newMCPHandler(NewTaskService(nil), nil, nil, nil)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/mcp/call_tool", strings.NewReader(
+		`{"jsonrpc":"2.0","id":2,"method":"create_task","params":{"title":"Ship it","due_date":"2099-01-01T00:00:00Z"}}`))
+	ctx.Set("auth_method", "api_key")
+	ctx.Set("api_key_scopes", []string{"mcp"})
+	m.MCPCallTool(ctx)
+	if recorder.Code != http.StatusForbidden || !strings.Contains(recorder.Body.String(), "write scope") {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestToolErrorMessage(t *testing.T) {
+	if got := toolErrorMessage(context.Background(), utils.ErrNotFound("workspace")); got != utils.ErrNotFound("workspace").Message {
+		t.Errorf("AppError message = %q", got)
+	}
+	if got := toolErrorMessage(context.Background(), errors.New("dial tcp 10.0.0.1:5432: refused")); got != "internal server error" {
+		t.Errorf("internal error leaked as %q", got)
+	}
+}
+
+func TestMCPCallToolBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+	m := newMCPHandler(NewTaskService(nil), nil, NewAIService("", "", config.Claude{APIKey: "mock", BaseURL: llm.URL, MaxTokens: 1024, Timeout: config.Duration{Duration: 5 * time.Second}}), nil)
+
+	var batch []string
+	for i := 1; i <= 6; i++ {
+		batch = append(batch, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"generate_subtasks","params":{"task_title":"Step %d"}}`, i, i))
+	}
+	batch = append(batch, `{"jsonrpc":"2.0","id":7,"method":"nope"}`, `"not a request"`)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/mcp/call_tool", strings.NewReader("["+strings.Join(batch, ",")+"]"))
+	m.MCPCallTool(ctx)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("batch: status %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var responses []struct {
+		ID     int `json:"id"`
+		Result *struct {
+			StructuredContent struct {
+				Subtasks []string `json:"subtasks"`
+			} `json:"structuredContent"`
+		} `json:"result"`
+		Error *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != len(batch) {
+		t.Fatalf("got %d responses for %d requests", len(responses), len(batch))
+	}
+	for i, resp := range responses[:6] {
+		if resp.ID != i+1 || resp.Result == nil || !strings.Contains(resp.Result.StructuredContent.Subtasks[0], fmt.Sprintf("Step %d", i+1)) {
+			t.Errorf("response %d out of order or failed: %+v", i, resp)
+		}
+	}
+	if responses[6].Error == nil || responses[6].Error.Code != -32601 {
+		t.Errorf("unknown method: %+v", responses[6])
+	}
+	if responses[7].Error == nil || responses[7].Error.Code != -32600 {
+		t.Errorf("invalid request: %+v", responses[7])
+	}
+
+	status, _ := callTool(t, m, "["+strings.Repeat(`{"id":1,"method":"nope"},`, MaxBatchSize)+`{"id":1,"method":"nope"}]`)
+	if status != http.StatusBadRequest {
+		t.Errorf("oversized batch: status %d, want 400", status)
+	}
+}
+
+func TestMCPCallToolBatchSkipsNotifications(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newMCPHandler(nil, nil, nil, nil)
+	post := func(body string) (int, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/mcp/call_tool", strings.NewReader(body))
+		m.MCPCallTool(ctx)
+		return ctx.Writer.Status(), recorder
+	}
+
+	// Only the message with an id is answered
+	status, recorder := post(`[{"jsonrpc":"2.0","method":"notifications/initialized"},` +
+		`{"jsonrpc":"2.0","method":"nope"},` +
+		`{"jsonrpc":"2.0","id":3,"method":"nope"}]`)
+	var responses []struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &responses); err != nil {
+		t.Fatalf("decode %s: %v", recorder.Body, err)
+	}
+	if status != http.StatusOK || len(responses) != 1 || responses[0].ID != 3 {
+		t.Errorf("mixed batch: status %d, responses %+v", status, responses)
+	}
+
+	status, recorder = post(`[{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":4,"method":"notifications/progress"}]`)
+	if status != http.StatusAccepted || recorder.Body.Len() != 0 {
+		t.Errorf("notifications only: status %d, body %q, want 202 and no body", status, recorder.Body)
+	}
+}
+
+func TestMCPCallToolStreamsProgress(t *testing.T) {
 	gin.SetMode(gin.TestMode)
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+	m := newMCPHandler(nil, nil, NewAIService("", "", config.Claude{APIKey: "mock", BaseURL: llm.URL, MaxTokens: 1024, Timeout: config.Duration{Duration: 5 * time.Second}}), nil)
+
+	// Three parts' worth of distinct list items; the overlap between parts
+	// must not count any twice
+	var items strings.Builder
+	for i := 0; i < parseFileChunkSize/10; i++ {
+		fmt.Fprintf(&items, "- call client %04d tomorrow\n", i)
+	}
+	content := items.String()
+	params, _ := json.Marshal(map[string]interface{}{
+		"file_name":    "notes.md",
+		"file_content": content,
+		"_meta":        map[string]interface{}{"progressToken": "tok-1"},
+	})
 	recorder := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(recorder)
-	ctx.Request = httptest.NewRequest(http.MethodGet, "/mcp", nil)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/mcp/call_tool",
+		strings.NewReader(`{"jsonrpc":"2.0","id":3,"method":"parse_file","params":`+string(params)+`}`))
+	ctx.Request.Header.Set("Accept", "application/json, text/event-stream")
+	m.MCPCallTool(ctx)
+
+	if ct := recorder.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var messages []map[string]interface{}
+	for _, line := range strings.Split(recorder.Body.String(), "\n") {
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			var msg map[string]interface{}
+			if err := json.Unmarshal([]byte(data), &msg); err != nil {
+				t.Fatalf("decode %q: %v", data, err)
+			}
+			messages = append(messages, msg)
+		}
+	}
+
+	parts := len(splitFileContent(content, parseFileChunkSize, parseFileChunkOverlap))
+	if len(messages) != parts+2 {
+		t.Fatalf("got %d messages for %d parts: %s", len(messages), parts, recorder.Body)
+	}
+	for i, msg := range messages[:parts+1] {
+		p := msg["params"].(map[string]interface{})
+		if msg["method"] != "notifications/progress" || p["progressToken"] != "tok-1" || p["progress"].(float64) != float64(i) || p["total"].(float64) != float64(parts) {
+			t.Errorf("notification %d = %v", i, msg)
+		}
+	}
+	final := messages[parts+1]
+	result, _ := final["result"].(map[string]interface{})["structuredContent"].(map[string]interface{})
+	if final["id"].(float64) != 3 || len(result["tasks"].([]interface{})) != parseFileChunkSize/10 {
+		t.Errorf("final response = %v", final)
+	}
+	// The part summaries are combined into one
+	if summary, _ := result["summary"].(string); !strings.HasSuffix(summary, fmt.Sprintf(" tasks across %d parts.", parts)) {
+		t.Errorf("summary = %q", summary)
+	}
+}
 
-	statusCode, body := captureHandlerResponse(ctx, func(c *gin.Context) {
-		c.JSON(http.StatusCreated, gin.H{"ok": true})
+func TestSplitFileContent(t *testing.T) {
+	parts := splitFileContent("aaa\nbbb\ncccccccc\n", 6, 0)
+	if got := strings.Join(parts, "|"); got != "aaa\n|bbb\n|cccccc|cc\n" {
+		t.Errorf("parts = %q", got)
+	}
+	if parts := splitFileContent("short", 6, 4); len(parts) != 1 || parts[0] != "short" {
+		t.Errorf("parts = %q", parts)
+	}
+	// Later parts repeat the whole lines that fit from the end of the one before
+	parts = splitFileContent("a1\nb2\nc3\nd4\ne5\n", 9, 6)
+	if got := strings.Join(parts, "|"); got != "a1\nb2\nc3\n|b2\nc3\nd4\ne5\n" {
+		t.Errorf("overlapping parts = %q", got)
+	}
+}
+
+func TestMergeFileTasks(t *testing.T) {
+	due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tasks := mergeFileTasks(nil, []models.Task{
+		{Title: "Call the bank.", Priority: 3},
+		{Title: "Book flights", Priority: 2},
+	})
+	tasks = mergeFileTasks(tasks, []models.Task{
+		{Title: "call the  BANK", Description: "about the loan", Priority: 4, DueDate: due},
+		{Title: "Send minutes", Priority: 3},
 	})
+	if len(tasks) != 3 {
+		t.Fatalf("tasks = %+v", tasks)
+	}
+	if bank := tasks[0]; bank.Title != "Call the bank." || bank.Description != "about the loan" || bank.Priority != 4 || !bank.DueDate.Equal(due) {
+		t.Errorf("merged task = %+v", bank)
+	}
+	if tasks[1].Title != "Book flights" || tasks[2].Title != "Send minutes" {
+		t.Errorf("order = %+v", tasks)
+	}
+}
+
+func TestToolResultEmbedsResource(t *testing.T) {
+	result := toolResult(map[string]interface{}{"id": "t1", "title": "Ship it"}, TaskResourceURI("t1"))
+	data, _ := json.Marshal(result)
+
+	var decoded struct {
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			Resource struct {
+				URI      string `json:"uri"`
+				MimeType string `json:"mimeType"`
+				Text     string `json:"text"`
+			} `json:"resource"`
+		} `json:"content"`
+		StructuredContent map[string]interface{} `json:"structuredContent"`
+		IsError           bool                   `json:"isError"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Content) != 2 || decoded.Content[0].Type != "text" || decoded.Content[1].Type != "resource" {
+		t.Fatalf("content = %+v", decoded.Content)
+	}
+	if r := decoded.Content[1].Resource; r.URI != "productivity://tasks/t1" || r.MimeType != "application/json" || r.Text != decoded.Content[0].Text {
+		t.Errorf("resource = %+v", r)
+	}
+	if decoded.StructuredContent["title"] != "Ship it" || decoded.IsError {
+		t.Errorf("result = %s", data)
+	}
+}
+
+func TestMCPCallToolCancelled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	started := make(chan struct{})
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client hanging up once the body is read
+		io.Copy(io.Discard, r.Body)
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer llm.Close()
+	m := newMCPHandler(nil, nil, NewAIService("", "", config.Claude{APIKey: "mock", BaseURL: llm.URL, MaxTokens: 1024, Timeout: config.Duration{Duration: time.Minute}}), nil)
+
+	type reply struct {
+		status int
+		resp   map[string]interface{}
+	}
+	replies := make(chan reply)
+	go func() {
+		status, resp := callTool(t, m, `{"jsonrpc":"2.0","id":41,"method":"parse_task","params":{"input":"write the report"}}`)
+		replies <- reply{status, resp}
+	}()
+	<-started
+
+	// Another caller, or the same caller on another session, can't cancel it
+	cancelAs := func(userID, sessionID string) int {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/mcp/call_tool",
+			strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":41}}`))
+		if sessionID != "" {
+			ctx.Request.Header.Set(mcpsession.Header, sessionID)
+		}
+		ctx.Set("user_id", userID)
+		m.MCPCallTool(ctx)
+		return ctx.Writer.Status()
+	}
+	for _, other := range [][2]string{{"someone-else", ""}, {"", "another-session"}} {
+		if status := cancelAs(other[0], other[1]); status != http.StatusAccepted {
+			t.Fatalf("cancel: status %d, want 202", status)
+		}
+	}
+	select {
+	case r := <-replies:
+		t.Fatalf("call ended after another caller's cancel: %v", r.resp)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancelAs("", "")
+	select {
+	case r := <-replies:
+		errObj, _ := r.resp["error"].(map[string]interface{})
+		if errObj == nil || errObj["code"].(float64) != codeRequestCancelled {
+			t.Errorf("cancelled call: status %d, response %v", r.status, r.resp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call was not cancelled")
+	}
+}
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	for requested, want := range map[string]string{
+		"":           "2025-06-18",
+		"2025-06-18": "2025-06-18",
+		"2025-03-26": "2025-03-26",
+		"2024-11-05": "2024-11-05",
+		"2026-01-01": "2025-06-18", // newer than we know: offer ours and let the client decide
+		"2024-10-07": "",
+	} {
+		got, ok := negotiateProtocolVersion(requested)
+		if got != want || ok != (want != "") {
+			t.Errorf("negotiateProtocolVersion(%q) = %q, %v; want %q", requested, got, ok, want)
+		}
+	}
+}
+
+func TestMCPInitializeRejectsUnsupportedVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initialize := func(body string) (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/mcp/initialize", strings.NewReader(body))
+		MCPInitialize(ctx)
+		var resp map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &resp)
+		return recorder.Code, resp
+	}
 
-	if statusCode != http.StatusCreated {
-		t.Fatalf("expected status 201, got %d", statusCode)
+	status, resp := initialize(`{"jsonrpc":"2.0","id":5,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`)
+	result, _ := resp["result"].(map[string]interface{})
+	if status != http.StatusOK || result["protocolVersion"] != "2025-03-26" || resp["id"].(float64) != 5 {
+		t.Errorf("supported version: status %d, response %v", status, resp)
 	}
 
-	if len(body) == 0 {
-		t.Fatalf("expected non-empty body")
+	status, resp = initialize(`{"jsonrpc":"2.0","id":6,"method":"initialize","params":{"protocolVersion":"2023-01-01"}}`)
+	errObj, _ := resp["error"].(map[string]interface{})
+	if status != http.StatusBadRequest || errObj["code"].(float64) != -32602 || !strings.Contains(errObj["message"].(string), "2024-11-05") {
+		t.Errorf("unsupported version: status %d, response %v", status, resp)
 	}
 }
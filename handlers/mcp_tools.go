@@ -0,0 +1,883 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/flags"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// registerTools registers the built-in tools, in list_tools order
+func (m *MCPHandler) registerTools() {
+	m.tools.Register(Tool{
+		Name:        "create_task",
+		Description: "Create a new task in the productivity app. During a focus session with the focus contract enabled, the task goes to the Inbox for later and the result includes a notice.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"title":       {Type: "string", Description: "Task title", MinLength: 1},
+				"description": {Type: "string", Description: "Task description"},
+				"due_date":    {Type: "string", Description: "Due date in ISO 8601 format", Format: "date-time"},
+				"context":     {Type: "string", Description: "Where the task can be done, e.g. @home, @office or @errands"},
+				"priority": {
+					Type:        "integer",
+					Description: "Priority level (1-5)",
+					Minimum:     validation.Bound(validation.MinPriority),
+					Maximum:     validation.Bound(validation.MaxPriority),
+				},
+			},
+			Required: []string{"title", "due_date"},
+		},
+		Scope:   middleware.ScopeWrite,
+		Handler: m.createTask,
+		Preview: m.previewCreateTask,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "update_task",
+		Description: "Change fields of an existing task. Only the fields given are changed; undo_last_action reverts the change.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"task_id":     {Type: "string", Description: "ID of the task to change", MinLength: 1},
+				"title":       {Type: "string", Description: "New title", MinLength: 1},
+				"description": {Type: "string", Description: "New description"},
+				"due_date":    {Type: "string", Description: "New due date in ISO 8601 format", Format: "date-time"},
+				"context":     {Type: "string", Description: "New context, e.g. @home; empty clears it"},
+				"priority": {
+					Type:        "integer",
+					Description: "New priority level (1-5)",
+					Minimum:     validation.Bound(validation.MinPriority),
+					Maximum:     validation.Bound(validation.MaxPriority),
+				},
+				"completed": {Type: "boolean", Description: "Whether the task is done"},
+			},
+			Required: []string{"task_id"},
+		},
+		Scope:   middleware.ScopeWrite,
+		Handler: m.updateTask,
+		Preview: m.previewUpdateTask,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "delete_task",
+		Description: "Move a task to the trash. undo_last_action restores it.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"task_id": {Type: "string", Description: "ID of the task to delete", MinLength: 1},
+			},
+			Required: []string{"task_id"},
+		},
+		Scope:   middleware.ScopeWrite,
+		Handler: m.deleteTask,
+		Preview: m.previewDeleteTask,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "snooze_task",
+		Description: "Push a task back to a time such as \"next Monday morning\", or, without one, to the start of the working day on the least busy of the next few working days",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"task_id": {Type: "string", Description: "ID of the task to snooze", MinLength: 1},
+				"until":   {Type: "string", Description: "When the task should be due instead, in natural language; omit for a smart default", MaxLength: maxSnoozePhraseLength},
+			},
+			Required: []string{"task_id"},
+		},
+		Scope:   middleware.ScopeWrite,
+		Handler: m.snoozeTask,
+		Preview: m.previewSnoozeTask,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "get_overdue_tasks",
+		Description: "List open tasks due before today, in the user's time zone, oldest first",
+		InputSchema: &validation.Schema{Type: "object"},
+		Handler:     m.taskView(TaskViewOverdue),
+	})
+
+	m.tools.Register(Tool{
+		Name:        "get_today_tasks",
+		Description: "List open tasks due today, in the user's time zone, soonest first",
+		InputSchema: &validation.Schema{Type: "object"},
+		Handler:     m.taskView(TaskViewToday),
+	})
+
+	m.tools.Register(Tool{
+		Name:        "get_upcoming_tasks",
+		Description: "List open tasks due after today within the next few days, in the user's time zone, soonest first",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"days": {Type: "integer", Description: "Number of days ahead to include (default: 7)", Minimum: validation.Bound(1), Maximum: validation.Bound(MaxUpcomingDays)},
+			},
+		},
+		Handler: m.taskView(TaskViewUpcoming),
+	})
+
+	m.tools.Register(Tool{
+		Name:        "get_tasks_for_context",
+		Description: "List open tasks that can be done in a context such as @errands, @home or @office, soonest due first, e.g. to answer \"what can I do while I'm out\". Also lists the contexts the user's tasks use.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"context": {Type: "string", Description: "The context, with or without the leading @", MinLength: 1},
+			},
+			Required: []string{"context"},
+		},
+		Handler: m.getTasksForContext,
+	})
+
+	limitSchema := &validation.Schema{Type: "integer", Description: "Maximum number of matches (default: 10)", Minimum: validation.Bound(1), Maximum: validation.Bound(MaxSemanticLimit)}
+
+	m.tools.Register(Tool{
+		Name:        "search_tasks",
+		Description: "Find tasks by meaning rather than exact words, e.g. \"anything about the tax return\"; matches are ranked by similarity",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"query": {Type: "string", Description: "What to look for", MinLength: 1},
+				"limit": limitSchema,
+			},
+			Required: []string{"query"},
+		},
+		Flag:    flags.SemanticSearch,
+		Handler: m.searchTasks,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "find_related_tasks",
+		Description: "List the tasks most similar to a task, e.g. to group or batch them; likely duplicates are flagged",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"task_id": {Type: "string", Description: "ID of the task", MinLength: 1},
+				"limit":   limitSchema,
+			},
+			Required: []string{"task_id"},
+		},
+		Flag:    flags.SemanticSearch,
+		Handler: m.findRelatedTasks,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "find_duplicate_tasks",
+		Description: "Check whether a task already exists before creating it; lists existing tasks similar enough to be the same",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"title":       {Type: "string", Description: "Title of the task to check", MinLength: 1},
+				"description": {Type: "string", Description: "Its description"},
+			},
+			Required: []string{"title"},
+		},
+		Flag:    flags.SemanticSearch,
+		Handler: m.findDuplicateTasks,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "create_goal",
+		Description: "Create a new goal in the productivity app",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"title":       {Type: "string", Description: "Goal title", MinLength: 1},
+				"description": {Type: "string", Description: "Goal description"},
+				"target_date": {Type: "string", Description: "Target date in ISO 8601 format", Format: "date-time"},
+				"milestones": {
+					Type:        "array",
+					Description: "Milestones to create with the goal, e.g. from suggest_milestones; the goal's progress then follows them",
+					Items:       milestoneSchema,
+				},
+			},
+			Required: []string{"title", "target_date"},
+		},
+		Scope:   middleware.ScopeWrite,
+		Handler: m.createGoal,
+		Preview: m.previewCreateGoal,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "parse_task",
+		Description: "Parse natural language input into a structured task",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"input":       {Type: "string", Description: "Natural language task description", MinLength: 1},
+				"timezone":    {Type: "string", Description: "IANA time zone relative dates resolve in, e.g. Europe/Berlin (default: the user's preference, else UTC)"},
+				"model":       llmModelProperty,
+				"max_tokens":  llmMaxTokensProperty,
+				"temperature": llmTemperatureProperty,
+			},
+			Required: []string{"input"},
+		},
+		AI:      true,
+		Handler: m.parseTask,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "refine_task",
+		Description: "Correct a parsed task from a follow-up such as \"actually it's due Friday and it's priority 5\". Send the task to start; send the returned conversation_id with further corrections so they build on the earlier ones.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"correction":      {Type: "string", Description: "What to change, in natural language", MinLength: 1},
+				"task":            refineTaskSchema,
+				"conversation_id": {Type: "string", Description: "Conversation from an earlier refine_task call; the task may then be left out"},
+				"timezone":        {Type: "string", Description: "IANA time zone relative dates resolve in, e.g. Europe/Berlin (default: the user's preference, else UTC)"},
+			},
+			Required: []string{"correction"},
+		},
+		AI:      true,
+		Handler: m.refineTask,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "parse_date",
+		Description: "Resolve a natural language date such as \"tomorrow 5pm\", \"next Friday\" or \"in 2 weeks\" to an exact time, without an LLM",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"text":     {Type: "string", Description: "Text containing a date", MinLength: 1},
+				"timezone": {Type: "string", Description: "IANA time zone, e.g. Europe/Berlin (default: the user's preference, else UTC)"},
+			},
+			Required: []string{"text"},
+		},
+		Handler: m.parseDate,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "parse_file",
+		Description: "Extract tasks, dates and priorities from a document. Large files are parsed in parts; send _meta.progressToken with Accept: text/event-stream to receive progress notifications.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"file_name":    {Type: "string", Description: "File name"},
+				"file_type":    {Type: "string", Description: "File type, e.g. markdown or text"},
+				"file_content": {Type: "string", Description: "File content", MinLength: 1},
+				"model":        llmModelProperty,
+				"max_tokens":   llmMaxTokensProperty,
+				"temperature":  llmTemperatureProperty,
+			},
+			Required: []string{"file_content"},
+		},
+		AI:      true,
+		Handler: m.parseFile,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "generate_subtasks",
+		Description: "Generate subtasks for a given task",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"task_title":       {Type: "string", Description: "Main task title", MinLength: 1},
+				"task_description": {Type: "string", Description: "Task description for context"},
+				"model":            llmModelProperty,
+				"max_tokens":       llmMaxTokensProperty,
+				"temperature":      llmTemperatureProperty,
+			},
+			Required: []string{"task_title"},
+		},
+		AI:      true,
+		Handler: m.generateSubtasks,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "suggest_milestones",
+		Description: "Propose milestones for a new goal, dated between its start and target dates. Pass them to create_goal as milestones to use them.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"goal_title":       {Type: "string", Description: "Goal title", MinLength: 1},
+				"goal_description": {Type: "string", Description: "Goal description for context"},
+				"start_date":       {Type: "string", Description: "Start date in ISO 8601 format (default: now)", Format: "date-time"},
+				"target_date":      {Type: "string", Description: "Target date in ISO 8601 format", Format: "date-time"},
+			},
+			Required: []string{"goal_title"},
+		},
+		AI:      true,
+		Handler: m.suggestMilestones,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "analyze_productivity",
+		Description: "Analyze user productivity patterns and provide insights. Send _meta.progressToken with Accept: text/event-stream to receive progress notifications.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"days": {Type: "integer", Description: "Number of days to analyze (default: 7)", Minimum: validation.Bound(1), Maximum: validation.Bound(maxAnalyzeDays)},
+			},
+		},
+		AI:      true,
+		Handler: m.analyzeProductivity,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "eisenhower_matrix",
+		Description: "Group open tasks into Eisenhower matrix quadrants (do, schedule, delegate, eliminate) by priority and due date, optionally refined by Claude",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"refine": {Type: "boolean", Description: "Have Claude review the rule-based placement using task descriptions (default: false)"},
+			},
+		},
+		AI:      true,
+		Handler: m.eisenhowerMatrix,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "memory/get",
+		Description: "Recall what you remembered about the user in earlier sessions: facts, preferences (e.g. default_category) and recurring commitments. Without a key, returns everything.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"key":  {Type: "string", Description: "Key to recall, e.g. default_category"},
+				"kind": {Type: "string", Description: "Only entries of this kind", Enum: []string{MemoryKindFact, MemoryKindPreference, MemoryKindCommitment}},
+			},
+		},
+		Handler: m.getMemory,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "memory/set",
+		Description: "Remember something about the user for later sessions, replacing the key's earlier value. The user's memory also informs parse_task, e.g. default_category fills in a task's category. An empty value forgets the key.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"key":   {Type: "string", Description: "Lowercase key, e.g. default_category or standup", MinLength: 1, MaxLength: MaxMemoryKeyLength},
+				"value": {Type: "string", Description: "What to remember, e.g. \"work\" or \"every weekday at 9:30\"", MaxLength: MaxMemoryValueLength},
+				"kind":  {Type: "string", Description: "fact (default), preference or commitment", Enum: []string{MemoryKindFact, MemoryKindPreference, MemoryKindCommitment}},
+			},
+			Required: []string{"key", "value"},
+		},
+		Scope:   middleware.ScopeWrite,
+		Handler: m.setMemory,
+	})
+
+	m.tools.Register(Tool{
+		Name:        "undo_last_action",
+		Description: "Undo your latest task or goal change: a create, update_task or delete_task. The undo is itself an action, so calling this again redoes the change.",
+		InputSchema: &validation.Schema{Type: "object"},
+		Scope:       middleware.ScopeWrite,
+		Handler:     m.undoLastAction,
+		Preview:     m.previewUndoLastAction,
+	})
+}
+
+// createTaskParams reads create_task's arguments
+func createTaskParams(c *gin.Context, params map[string]interface{}) (string, models.CreateTaskRequest) {
+	title, _ := params["title"].(string)
+	description, _ := params["description"].(string)
+	dueDateStr, _ := params["due_date"].(string)
+	priority, _ := params["priority"].(float64)
+	userID, _ := params["user_id"].(string)
+	dueDate, _ := time.Parse(time.RFC3339, dueDateStr)
+
+	taskContext, _ := params["context"].(string)
+	taskReq := models.CreateTaskRequest{
+		Title:       title,
+		Description: description,
+		DueDate:     dueDate,
+		Priority:    int(priority),
+		Context:     taskContext,
+	}
+	if taskReq.Priority == 0 {
+		taskReq.Priority = 3
+	}
+	return mcpUserID(c, userID), taskReq
+}
+
+func (m *MCPHandler) createTask(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	userID, taskReq := createTaskParams(c, params)
+	created, err := m.tasks.Create(c, userID, taskReq)
+	if err != nil {
+		return nil, "", err
+	}
+	task := created.Task
+	if task == nil {
+		task = map[string]interface{}{"id": created.ID, "message": "Task created but could not fetch details"}
+	}
+	if created.Notice != "" {
+		task["notice"] = created.Notice
+	}
+	if created.Warning != nil {
+		task["capacity_warning"] = created.Warning
+	}
+	return task, TaskResourceURI(created.ID), nil
+}
+
+func (m *MCPHandler) previewCreateTask(c *gin.Context, params map[string]interface{}) (*ChangePreview, error) {
+	userID, taskReq := createTaskParams(c, params)
+	return m.tasks.PreviewCreate(c, userID, taskReq)
+}
+
+// updateTaskParams reads update_task's arguments
+func updateTaskParams(c *gin.Context, params map[string]interface{}) (string, string, models.UpdateTaskRequest) {
+	taskID, _ := params["task_id"].(string)
+	userID, _ := params["user_id"].(string)
+
+	var req models.UpdateTaskRequest
+	if title, ok := params["title"].(string); ok {
+		req.Title = &title
+	}
+	if description, ok := params["description"].(string); ok {
+		req.Description = &description
+	}
+	if dueDateStr, ok := params["due_date"].(string); ok {
+		dueDate, _ := time.Parse(time.RFC3339, dueDateStr)
+		req.DueDate = &dueDate
+	}
+	if priority, ok := params["priority"].(float64); ok {
+		p := int(priority)
+		req.Priority = &p
+	}
+	if completed, ok := params["completed"].(bool); ok {
+		req.Completed = &completed
+	}
+	if taskContext, ok := params["context"].(string); ok {
+		req.Context = &taskContext
+	}
+	return mcpUserID(c, userID), taskID, req
+}
+
+func (m *MCPHandler) updateTask(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	userID, taskID, req := updateTaskParams(c, params)
+	task, err := m.tasks.Update(c, userID, taskID, req)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.DueDate != nil || req.EstimatedDuration != nil {
+		if warning := m.tasks.CapacityWarning(c, userID, task); warning != nil {
+			task["capacity_warning"] = warning
+		}
+	}
+	return task, TaskResourceURI(taskID), nil
+}
+
+func (m *MCPHandler) previewUpdateTask(c *gin.Context, params map[string]interface{}) (*ChangePreview, error) {
+	userID, taskID, req := updateTaskParams(c, params)
+	return m.tasks.PreviewUpdate(c, userID, taskID, req)
+}
+
+func (m *MCPHandler) deleteTask(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	taskID, _ := params["task_id"].(string)
+	userID, _ := params["user_id"].(string)
+
+	if err := m.tasks.Delete(c, mcpUserID(c, userID), taskID); err != nil {
+		return nil, "", err
+	}
+	return gin.H{"id": taskID, "deleted": true, "trashed": true}, "", nil
+}
+
+func (m *MCPHandler) previewDeleteTask(c *gin.Context, params map[string]interface{}) (*ChangePreview, error) {
+	taskID, _ := params["task_id"].(string)
+	userID, _ := params["user_id"].(string)
+
+	return m.tasks.PreviewDelete(c, mcpUserID(c, userID), taskID)
+}
+
+func snoozeTaskParams(c *gin.Context, params map[string]interface{}) (string, string, models.SnoozeTaskRequest) {
+	taskID, _ := params["task_id"].(string)
+	userID, _ := params["user_id"].(string)
+	until, _ := params["until"].(string)
+	return mcpUserID(c, userID), taskID, models.SnoozeTaskRequest{Until: until}
+}
+
+func (m *MCPHandler) snoozeTask(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	userID, taskID, req := snoozeTaskParams(c, params)
+	result, err := m.tasks.Snooze(c, userID, taskID, req)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, TaskResourceURI(taskID), nil
+}
+
+func (m *MCPHandler) previewSnoozeTask(c *gin.Context, params map[string]interface{}) (*ChangePreview, error) {
+	userID, taskID, req := snoozeTaskParams(c, params)
+	return m.tasks.PreviewSnooze(c, userID, taskID, req)
+}
+
+func (m *MCPHandler) undoLastAction(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	userID, _ := params["user_id"].(string)
+
+	undone, err := m.undo.UndoLast(c, mcpUserID(c, userID))
+	if err != nil {
+		return nil, "", err
+	}
+	return undone, "", nil
+}
+
+func (m *MCPHandler) previewUndoLastAction(c *gin.Context, params map[string]interface{}) (*ChangePreview, error) {
+	userID, _ := params["user_id"].(string)
+
+	return m.undo.PreviewLast(c, mcpUserID(c, userID))
+}
+
+// milestoneSchema describes a milestone in create_goal's arguments
+var milestoneSchema = &validation.Schema{
+	Type: "object",
+	Properties: map[string]*validation.Schema{
+		"title":     {Type: "string", Description: "Milestone title", MinLength: 1},
+		"due_date":  {Type: "string", Description: "Due date in ISO 8601 format", Format: "date-time"},
+		"completed": {Type: "boolean", Description: "Whether the milestone is already done"},
+	},
+	Required: []string{"title"},
+}
+
+// createGoalParams reads create_goal's arguments
+func createGoalParams(c *gin.Context, params map[string]interface{}) (string, models.CreateGoalRequest) {
+	title, _ := params["title"].(string)
+	description, _ := params["description"].(string)
+	targetDateStr, _ := params["target_date"].(string)
+	userID, _ := params["user_id"].(string)
+	targetDate, _ := time.Parse(time.RFC3339, targetDateStr)
+
+	var milestones []models.CreateMilestoneRequest
+	items, _ := params["milestones"].([]interface{})
+	for _, item := range items {
+		fields, _ := item.(map[string]interface{})
+		milestone := models.CreateMilestoneRequest{}
+		milestone.Title, _ = fields["title"].(string)
+		milestone.Completed, _ = fields["completed"].(bool)
+		if dueDateStr, ok := fields["due_date"].(string); ok {
+			if dueDate, err := time.Parse(time.RFC3339, dueDateStr); err == nil {
+				milestone.DueDate = &dueDate
+			}
+		}
+		milestones = append(milestones, milestone)
+	}
+
+	return mcpUserID(c, userID), models.CreateGoalRequest{
+		Title:       title,
+		Description: description,
+		StartDate:   time.Now(),
+		TargetDate:  targetDate,
+		Milestones:  milestones,
+	}
+}
+
+// taskView returns the handler of the tool listing one of the task views
+func (m *MCPHandler) taskView(view string) ToolFunc {
+	return func(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+		days := DefaultUpcomingDays
+		if d, ok := params["days"].(float64); ok {
+			days = int(d)
+		}
+		result, err := m.tasks.View(c, getUserID(c), view, days)
+		if err != nil {
+			return nil, "", err
+		}
+		return result, "", nil
+	}
+}
+
+func (m *MCPHandler) getTasksForContext(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	taskContext, _ := params["context"].(string)
+	result, err := m.tasks.ForContext(c, getUserID(c), taskContext)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, "", nil
+}
+
+func (m *MCPHandler) searchTasks(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	query, _ := params["query"].(string)
+	limit, _ := params["limit"].(float64)
+	result, err := m.tasks.SemanticSearch(c, getUserID(c), models.SemanticSearchRequest{Query: query, Limit: int(limit)})
+	if err != nil {
+		return nil, "", err
+	}
+	return result, "", nil
+}
+
+func (m *MCPHandler) findRelatedTasks(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	taskID, _ := params["task_id"].(string)
+	limit, _ := params["limit"].(float64)
+	result, err := m.tasks.Related(c, getUserID(c), taskID, int(limit))
+	if err != nil {
+		return nil, "", err
+	}
+	return result, "", nil
+}
+
+func (m *MCPHandler) findDuplicateTasks(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	title, _ := params["title"].(string)
+	description, _ := params["description"].(string)
+	result, err := m.tasks.Duplicates(c, getUserID(c), models.DuplicateCheckRequest{Title: title, Description: description})
+	if err != nil {
+		return nil, "", err
+	}
+	return result, "", nil
+}
+
+// memoryClient is the memory store for a tool call
+func memoryClient(c *gin.Context) (*db.SupabaseClient, error) {
+	if memoryStore == nil {
+		return nil, utils.ErrInternal("memory is not available on this server")
+	}
+	return memoryStore.WithContext(c.Request.Context()), nil
+}
+
+func (m *MCPHandler) getMemory(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	key, _ := params["key"].(string)
+	kind, _ := params["kind"].(string)
+	userID := getUserID(c)
+	if userID == "" {
+		return nil, "", utils.ErrBadRequest("user_id is required")
+	}
+	client, err := memoryClient(c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries, err := listMemory(client, userID, kind)
+	if err != nil {
+		return nil, "", err
+	}
+	if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+		for _, entry := range entries {
+			if entry.Key == key {
+				return entry, "", nil
+			}
+		}
+		return nil, "", utils.ErrNotFound("memory " + key)
+	}
+	return gin.H{"entries": entries}, "", nil
+}
+
+func (m *MCPHandler) setMemory(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	key, _ := params["key"].(string)
+	value, _ := params["value"].(string)
+	kind, _ := params["kind"].(string)
+	userID := getUserID(c)
+	client, err := memoryClient(c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if strings.TrimSpace(value) == "" {
+		if userID == "" {
+			return nil, "", utils.ErrBadRequest("user_id is required")
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if err := client.DeleteMemory(userID, key); err != nil {
+			return nil, "", err
+		}
+		return gin.H{"key": key, "forgotten": true}, "", nil
+	}
+	entry, err := setMemory(client, userID, key, models.SetMemoryRequest{Kind: kind, Value: value})
+	if err != nil {
+		return nil, "", err
+	}
+	return entry, "", nil
+}
+
+func (m *MCPHandler) createGoal(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	userID, goalReq := createGoalParams(c, params)
+	created, err := m.goals.Create(c, userID, goalReq)
+	if err != nil {
+		return nil, "", err
+	}
+	if created.Goal == nil {
+		return gin.H{"id": created.ID, "message": "Goal created but could not fetch details"}, GoalResourceURI(created.ID), nil
+	}
+	return created.Goal, GoalResourceURI(created.ID), nil
+}
+
+func (m *MCPHandler) previewCreateGoal(c *gin.Context, params map[string]interface{}) (*ChangePreview, error) {
+	userID, goalReq := createGoalParams(c, params)
+	return m.goals.PreviewCreate(c, userID, goalReq)
+}
+
+func (m *MCPHandler) parseTask(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	input, _ := params["input"].(string)
+	userID, _ := params["user_id"].(string)
+	timezone, _ := params["timezone"].(string)
+
+	var v validation.Validator
+	validateTimezone(&v, timezone)
+	ctx := m.ai.withLLMParams(c.Request.Context(), llmParamsArgs(params), &v)
+	if err := v.Err(); err != nil {
+		return nil, "", err
+	}
+
+	return m.ai.ParseTask(ctx, models.ParseTaskRequest{
+		Input:    input,
+		UserID:   userID,
+		Timezone: timezone,
+	}), "", nil
+}
+
+// The arguments parse_task, parse_file and generate_subtasks take to pick
+// the model that answers, within the server's allow-list and caps
+var (
+	llmModelProperty       = &validation.Schema{Type: "string", Description: "Model to answer with: a Claude model, or ollama: and an Ollama model (default: the operation's route, else CLAUDE_MODEL). Only models the server allows are accepted."}
+	llmMaxTokensProperty   = &validation.Schema{Type: "integer", Description: "Most tokens the answer may use (default: CLAUDE_MAX_TOKENS)", Minimum: validation.Bound(1)}
+	llmTemperatureProperty = &validation.Schema{Type: "number", Description: "Sampling temperature (default: CLAUDE_TEMPERATURE)", Minimum: validation.Bound(0), Maximum: validation.Bound(1)}
+)
+
+// llmParamsArgs reads the model arguments of a tool call
+func llmParamsArgs(params map[string]interface{}) models.LLMParams {
+	var p models.LLMParams
+	p.Model, _ = params["model"].(string)
+	if maxTokens, ok := params["max_tokens"].(float64); ok {
+		p.MaxTokens = int(maxTokens)
+	}
+	if temperature, ok := params["temperature"].(float64); ok {
+		p.Temperature = &temperature
+	}
+	return p
+}
+
+// refineTaskSchema describes the task in refine_task's arguments, as parse_task returns it
+var refineTaskSchema = &validation.Schema{
+	Type:        "object",
+	Description: "The task to correct, as parse_task returned it",
+	Properties: map[string]*validation.Schema{
+		"title":       {Type: "string", Description: "Task title"},
+		"description": {Type: "string", Description: "Task description"},
+		"due_date":    {Type: "string", Description: "Due date in ISO 8601 format", Format: "date-time"},
+		"priority":    {Type: "integer", Description: "Priority 1-5"},
+		"category":    {Type: "string", Description: "Task category"},
+	},
+}
+
+func (m *MCPHandler) refineTask(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	userID, _ := params["user_id"].(string)
+	req := models.RefineTaskRequest{UserID: mcpUserID(c, userID)}
+	req.Correction, _ = params["correction"].(string)
+	req.ConversationID, _ = params["conversation_id"].(string)
+	req.Timezone, _ = params["timezone"].(string)
+	if task, ok := params["task"].(map[string]interface{}); ok {
+		req.Task = &models.Task{}
+		applyParsedTask(req.Task, task)
+	}
+
+	var v validation.Validator
+	validateTimezone(&v, req.Timezone)
+	if err := v.Err(); err != nil {
+		return nil, "", err
+	}
+	response, err := m.ai.RefineTask(c.Request.Context(), req)
+	if err != nil {
+		return nil, "", err
+	}
+	return response, "", nil
+}
+
+func (m *MCPHandler) parseDate(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	text, _ := params["text"].(string)
+	timezone, _ := params["timezone"].(string)
+
+	response, err := parseDate(c.Request.Context(), getUserID(c), models.ParseDateRequest{Text: text, Timezone: timezone})
+	if err != nil {
+		return nil, "", err
+	}
+	return response, "", nil
+}
+
+func (m *MCPHandler) parseFile(c *gin.Context, params map[string]interface{}, progress Progress) (interface{}, string, error) {
+	fileName, _ := params["file_name"].(string)
+	fileContent, _ := params["file_content"].(string)
+	fileType, _ := params["file_type"].(string)
+	userID, _ := params["user_id"].(string)
+	if err := checkAttachmentLimit(c, getUserID(c), len(fileContent)); err != nil {
+		return nil, "", err
+	}
+
+	var v validation.Validator
+	ctx := m.ai.withLLMParams(c.Request.Context(), llmParamsArgs(params), &v)
+	if err := v.Err(); err != nil {
+		return nil, "", err
+	}
+	return m.ai.ParseFile(ctx, models.ParseFileRequest{
+		FileName:    fileName,
+		FileContent: fileContent,
+		FileType:    fileType,
+		UserID:      userID,
+	}, progress), "", nil
+}
+
+func (m *MCPHandler) generateSubtasks(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	taskTitle, _ := params["task_title"].(string)
+	taskDesc, _ := params["task_description"].(string)
+	userID, _ := params["user_id"].(string)
+
+	var v validation.Validator
+	ctx := m.ai.withLLMParams(c.Request.Context(), llmParamsArgs(params), &v)
+	if err := v.Err(); err != nil {
+		return nil, "", err
+	}
+	return m.ai.GenerateSubtasks(ctx, models.GenerateSubtasksRequest{
+		TaskTitle:       taskTitle,
+		TaskDescription: taskDesc,
+		UserID:          userID,
+	}), "", nil
+}
+
+func (m *MCPHandler) suggestMilestones(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	goalTitle, _ := params["goal_title"].(string)
+	goalDesc, _ := params["goal_description"].(string)
+	userID, _ := params["user_id"].(string)
+
+	req := models.SuggestMilestonesRequest{
+		GoalTitle:       goalTitle,
+		GoalDescription: goalDesc,
+		UserID:          userID,
+	}
+	if startDateStr, ok := params["start_date"].(string); ok {
+		if startDate, err := time.Parse(time.RFC3339, startDateStr); err == nil {
+			req.StartDate = &startDate
+		}
+	}
+	if targetDateStr, ok := params["target_date"].(string); ok {
+		if targetDate, err := time.Parse(time.RFC3339, targetDateStr); err == nil {
+			req.TargetDate = &targetDate
+		}
+	}
+	return m.ai.SuggestMilestones(c.Request.Context(), req), "", nil
+}
+
+func (m *MCPHandler) analyzeProductivity(c *gin.Context, params map[string]interface{}, progress Progress) (interface{}, string, error) {
+	userID, _ := params["user_id"].(string)
+	days, _ := params["days"].(float64)
+
+	if userID == "" {
+		return nil, "", utils.ErrBadRequest("user_id is required")
+	}
+
+	analysis, err := m.ai.AnalyzeProductivity(c.Request.Context(), models.AnalyzeProductivityRequest{
+		UserID: userID,
+		Days:   int(days),
+	}, progress)
+	if err != nil {
+		return nil, "", err
+	}
+	return analysis, "", nil
+}
+
+func (m *MCPHandler) eisenhowerMatrix(c *gin.Context, params map[string]interface{}, _ Progress) (interface{}, string, error) {
+	userID, _ := params["user_id"].(string)
+	refine, _ := params["refine"].(bool)
+	userID = mcpUserID(c, userID)
+	if userID == "" {
+		return nil, "", utils.ErrBadRequest("user_id is required")
+	}
+
+	matrix, err := m.ai.EisenhowerMatrix(c.Request.Context(), models.EisenhowerMatrixRequest{
+		UserID: userID,
+		Refine: refine,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return matrix, "", nil
+}
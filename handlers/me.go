@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// Me describes the signed-in user as their credential identifies them: who
+// they are, what the credential lets them do, their plan, their preferences
+// and the feature flags they get. Clients use it instead of passing a
+// user_id of their own.
+// GET /api/me
+func Me(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.Error(utils.ErrUnauthorized("sign in to see your account"))
+		return
+	}
+
+	prefs, err := userPreferences(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	resp := gin.H{
+		"user_id":     userID,
+		"auth_method": c.GetString("auth_method"),
+		"scopes":      callerScopes(c),
+		"service":     isServiceAccount(c),
+		"preferences": prefs,
+		"flags":       userFlags(c, userID),
+	}
+	if userPlans != nil {
+		plan, limits, err := userPlans.planOf(c, userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		resp["plan"] = gin.H{"name": plan, "limits": limits, "enforced": userPlans.cfg.Enabled}
+	}
+	if claims := bearerClaims(c); claims != nil {
+		resp["client_id"] = claims["client_id"]
+		if exp, ok := claims["exp"].(float64); ok {
+			resp["expires_at"] = time.Unix(int64(exp), 0).UTC().Format(time.RFC3339)
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// callerScopes lists what the caller's credential allows: an API key's
+// scopes, a bearer token's scope claim, or every scope for a token without
+// one and for the lite build's local user
+func callerScopes(c *gin.Context) []string {
+	if c.GetString("auth_method") == "api_key" {
+		return c.GetStringSlice("api_key_scopes")
+	}
+	if claims := bearerClaims(c); claims != nil {
+		if scope, _ := claims["scope"].(string); scope != "" {
+			return strings.Fields(scope)
+		}
+	}
+	return middleware.APIKeyScopes
+}
+
+// bearerClaims returns the claims of the bearer token the caller signed in
+// with, nil for other credentials
+func bearerClaims(c *gin.Context) map[string]interface{} {
+	token := c.GetString("auth_token")
+	if token == "" {
+		return nil
+	}
+	claims, err := validateJWT(token)
+	if err != nil {
+		return nil
+	}
+	return claims
+}
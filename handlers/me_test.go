@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestMe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	call := func(set func(c *gin.Context)) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/me?user_id=spoofed", nil)
+		c.Request.Header.Set("X-User-ID", "spoofed")
+		set(c)
+		Me(c)
+		if len(c.Errors) > 0 {
+			w.Code = c.Errors.Last().Err.(*utils.AppError).HTTPStatus
+		}
+		return w
+	}
+
+	// The user comes from the credential, never the query or headers
+	if w := call(func(c *gin.Context) {}); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous caller got %d, want 401", w.Code)
+	}
+
+	w := call(func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Set("auth_method", "api_key")
+		c.Set("api_key_scopes", []string{"read"})
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		UserID      string          `json:"user_id"`
+		Scopes      []string        `json:"scopes"`
+		Flags       map[string]bool `json:"flags"`
+		Preferences struct {
+			Timezone string `json:"timezone"`
+		} `json:"preferences"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.UserID != "u1" || len(resp.Scopes) != 1 || resp.Scopes[0] != "read" {
+		t.Errorf("unexpected identity %+v", resp)
+	}
+	if resp.Preferences.Timezone != "UTC" || !resp.Flags["semantic_search"] {
+		t.Errorf("unexpected preferences or flags %+v", resp)
+	}
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// Kinds of remembered entry
+const (
+	MemoryKindFact       = "fact"
+	MemoryKindPreference = "preference"
+	MemoryKindCommitment = "commitment"
+)
+
+const (
+	// MaxMemoryEntries bounds how much is remembered per user
+	MaxMemoryEntries = 200
+	// MaxMemoryKeyLength and MaxMemoryValueLength bound one entry
+	MaxMemoryKeyLength   = 64
+	MaxMemoryValueLength = 1000
+	// DefaultCategoryKey names the remembered category given to parsed tasks
+	// that do not name one
+	DefaultCategoryKey = "default_category"
+	// maxPromptMemory bounds the entries added to a prompt
+	maxPromptMemory = 20
+)
+
+// memoryKeyPattern allows keys such as default_category or gym.schedule
+var memoryKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// memoryKindOrder puts preferences first in prompts, as they most often
+// change how a task is read
+var memoryKindOrder = map[string]int{MemoryKindPreference: 0, MemoryKindCommitment: 1, MemoryKindFact: 2}
+
+// memoryStore is where the memory tools and prompt enrichment read and
+// write; without one nothing is remembered
+var memoryStore *db.SupabaseClient
+
+// SetMemoryStore installs the store for what the assistant remembers about users
+func SetMemoryStore(supabaseURL, supabaseKey string) {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	memoryStore = client
+}
+
+func memoryEntryFromRow(row map[string]interface{}) models.MemoryEntry {
+	entry := models.MemoryEntry{
+		Key:   rowString(row, "key"),
+		Kind:  rowString(row, "kind"),
+		Value: rowString(row, "value"),
+	}
+	entry.UpdatedAt, _ = rowTime(row, "updated_at")
+	return entry
+}
+
+// listMemory returns what is remembered about userID by key, only of kind
+// when it is set
+func listMemory(client *db.SupabaseClient, userID, kind string) ([]models.MemoryEntry, error) {
+	rows, err := client.ListMemory(userID)
+	if err != nil {
+		return nil, err
+	}
+	entries := []models.MemoryEntry{}
+	for _, row := range rows {
+		if entry := memoryEntryFromRow(row); kind == "" || entry.Kind == kind {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func validateMemoryKind(v *validation.Validator, kind string) {
+	v.Check(kind == "" || kind == MemoryKindFact || kind == MemoryKindPreference || kind == MemoryKindCommitment,
+		"kind", validation.CodeInvalidValue, "kind must be fact, preference or commitment")
+}
+
+// setMemory remembers req under key for userID, replacing any earlier value
+func setMemory(client *db.SupabaseClient, userID, key string, req models.SetMemoryRequest) (*models.MemoryEntry, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	var v validation.Validator
+	v.Required("key", key)
+	v.MaxLength("key", key, MaxMemoryKeyLength)
+	v.Check(key == "" || memoryKeyPattern.MatchString(key), "key", validation.CodeInvalidFormat,
+		"key must be lowercase letters, digits, '_', '.' or '-', e.g. default_category")
+	v.Required("value", strings.TrimSpace(req.Value))
+	v.MaxLength("value", req.Value, MaxMemoryValueLength)
+	validateMemoryKind(&v, req.Kind)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+	if req.Kind == "" {
+		req.Kind = MemoryKindFact
+	}
+
+	existing, err := client.GetMemory(userID, key)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		rows, err := client.ListMemory(userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) >= MaxMemoryEntries {
+			return nil, utils.ErrConflict(fmt.Sprintf("at most %d entries are remembered; forget one first", MaxMemoryEntries))
+		}
+	}
+
+	row, err := client.UpsertMemory(userID, key, map[string]interface{}{
+		"kind":       req.Kind,
+		"value":      strings.TrimSpace(req.Value),
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+	entry := memoryEntryFromRow(row)
+	return &entry, nil
+}
+
+// userMemory returns what is remembered about userID from the installed
+// store, or nothing when there is no store or no user
+func userMemory(ctx context.Context, userID string) ([]models.MemoryEntry, error) {
+	if memoryStore == nil || userID == "" {
+		return nil, nil
+	}
+	return listMemory(memoryStore.WithContext(ctx), userID, "")
+}
+
+// memoryValue returns the value remembered under key, if any
+func memoryValue(entries []models.MemoryEntry, key string) string {
+	for _, entry := range entries {
+		if entry.Key == key {
+			return entry.Value
+		}
+	}
+	return ""
+}
+
+// memoryPromptLines tells Claude what is remembered about the user,
+// preferences first and most recently updated first within a kind
+func memoryPromptLines(entries []models.MemoryEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	sorted := append([]models.MemoryEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if ki, kj := memoryKindOrder[sorted[i].Kind], memoryKindOrder[sorted[j].Kind]; ki != kj {
+			return ki < kj
+		}
+		return sorted[i].UpdatedAt.After(sorted[j].UpdatedAt)
+	})
+	if len(sorted) > maxPromptMemory {
+		sorted = sorted[:maxPromptMemory]
+	}
+
+	var b strings.Builder
+	b.WriteString("\nWhat you know about the user (use it to fill in what the input leaves out):\n")
+	for _, entry := range sorted {
+		fmt.Fprintf(&b, "- %s %s: %s\n", entry.Kind, entry.Key, entry.Value)
+	}
+	return b.String()
+}
+
+// MemoryHandler manages what the assistant remembers about each user
+type MemoryHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewMemoryHandler creates a new memory handler
+func NewMemoryHandler(supabaseURL, supabaseKey string) *MemoryHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &MemoryHandler{
+		supabaseClient: client,
+	}
+}
+
+// ListMemory returns what is remembered about the user
+// GET /api/memory?kind=preference
+func (h *MemoryHandler) ListMemory(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+	kind := c.Query("kind")
+	var v validation.Validator
+	validateMemoryKind(&v, kind)
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	entries, err := listMemory(h.supabaseClient.WithContext(c.Request.Context()), userID, kind)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// SetMemory remembers a value under a key
+// PUT /api/memory/:key
+func (h *MemoryHandler) SetMemory(c *gin.Context) {
+	var req models.SetMemoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	entry, err := setMemory(h.supabaseClient.WithContext(c.Request.Context()), getUserID(c), c.Param("key"), req)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteMemory forgets a key
+// DELETE /api/memory/:key
+func (h *MemoryHandler) DeleteMemory(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+	if err := h.supabaseClient.WithContext(c.Request.Context()).DeleteMemory(userID, strings.ToLower(c.Param("key"))); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
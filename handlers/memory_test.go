@@ -0,0 +1,116 @@
+//go:build lite
+
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/mockllm"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/validation"
+)
+
+func TestMemoryEnrichesParseTask(t *testing.T) {
+	dbURL := db.SQLiteScheme + filepath.Join(t.TempDir(), "memory.db")
+	SetMemoryStore(dbURL, "")
+	t.Cleanup(func() { memoryStore = nil })
+	client := memoryStore
+
+	if _, err := setMemory(client, "u1", "Default_Category", models.SetMemoryRequest{Kind: MemoryKindPreference, Value: "errands"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := setMemory(client, "u1", "standup", models.SetMemoryRequest{Kind: MemoryKindCommitment, Value: "every weekday at 9:30"}); err != nil {
+		t.Fatal(err)
+	}
+	// Setting a key again replaces it
+	if _, err := setMemory(client, "u1", "employer", models.SetMemoryRequest{Value: "Initech"}); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := setMemory(client, "u1", "employer", models.SetMemoryRequest{Value: "Acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Kind != MemoryKindFact || entry.Value != "Acme" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	entries, err := listMemory(client, "u1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Key != "default_category" || entries[1].Key != "employer" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+	if only, _ := listMemory(client, "u1", MemoryKindCommitment); len(only) != 1 || only[0].Key != "standup" {
+		t.Errorf("unexpected commitments %+v", only)
+	}
+
+	for _, bad := range []struct {
+		key string
+		req models.SetMemoryRequest
+	}{
+		{"", models.SetMemoryRequest{Value: "x"}},
+		{"has space", models.SetMemoryRequest{Value: "x"}},
+		{"ok", models.SetMemoryRequest{Value: " "}},
+		{"ok", models.SetMemoryRequest{Value: "x", Kind: "secret"}},
+		{"ok", models.SetMemoryRequest{Value: strings.Repeat("x", MaxMemoryValueLength+1)}},
+	} {
+		if _, err := setMemory(client, "u1", bad.key, bad.req); err == nil {
+			t.Errorf("%q %+v: expected an error", bad.key, bad.req)
+		} else if _, ok := err.(validation.Errors); !ok {
+			t.Errorf("%q: expected validation errors, got %v", bad.key, err)
+		}
+	}
+
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+	claude := config.Claude{
+		APIKey:    "mock",
+		BaseURL:   llm.URL,
+		Model:     "claude-3-5-sonnet-20241022",
+		MaxTokens: 1024,
+		Timeout:   config.Duration{Duration: 5 * time.Second},
+	}
+	cases := []struct {
+		name     string
+		claude   config.Claude
+		input    string
+		category string
+	}{
+		{"claude", claude, "pick up dry cleaning", "errands"},
+		{"claude keyword", claude, "prepare the client presentation", "work"},
+		{"fallback", config.Claude{}, "pick up dry cleaning", "errands"},
+	}
+	for _, tc := range cases {
+		parsed := NewAIService(dbURL, "", tc.claude).ParseTask(context.Background(), models.ParseTaskRequest{Input: tc.input, UserID: "u1"})
+		if parsed.Task.Category != tc.category {
+			t.Errorf("%s: category %q, want %q (%s)", tc.name, parsed.Task.Category, tc.category, parsed.Explanation)
+		}
+	}
+	if parsed := NewAIService(dbURL, "", claude).ParseTask(context.Background(), models.ParseTaskRequest{Input: "pick up dry cleaning", UserID: "u2"}); parsed.Task.Category != "personal" {
+		t.Errorf("other user: category %q", parsed.Task.Category)
+	}
+}
+
+func TestMemoryPromptLines(t *testing.T) {
+	now := time.Now()
+	lines := memoryPromptLines([]models.MemoryEntry{
+		{Key: "employer", Kind: MemoryKindFact, Value: "Acme", UpdatedAt: now},
+		{Key: "standup", Kind: MemoryKindCommitment, Value: "weekdays 9:30", UpdatedAt: now},
+		{Key: "default_category", Kind: MemoryKindPreference, Value: "work", UpdatedAt: now.Add(-time.Hour)},
+	})
+	want := "- preference default_category: work\n- commitment standup: weekdays 9:30\n- fact employer: Acme\n"
+	if !strings.HasSuffix(lines, want) {
+		t.Errorf("got %q", lines)
+	}
+	if memoryPromptLines(nil) != "" {
+		t.Error("expected no lines without memory")
+	}
+}
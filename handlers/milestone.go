@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/models"
+)
+
+// ListMilestones lists a goal's milestones by due date
+// GET /api/goals/:id/milestones
+func (h *GoalHandler) ListMilestones(c *gin.Context) {
+	milestones, err := h.milestones.List(c, getUserID(c), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, milestones)
+}
+
+// CreateMilestone adds a milestone to a goal
+// POST /api/goals/:id/milestones
+func (h *GoalHandler) CreateMilestone(c *gin.Context) {
+	var req models.CreateMilestoneRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	milestone, err := h.milestones.Create(c, getUserID(c), c.Param("id"), req)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, milestone)
+}
+
+// UpdateMilestone changes a milestone, e.g. to mark it completed
+// PUT /api/goals/:id/milestones/:milestone_id
+func (h *GoalHandler) UpdateMilestone(c *gin.Context) {
+	var req models.UpdateMilestoneRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	milestone, err := h.milestones.Update(c, getUserID(c), c.Param("id"), c.Param("milestone_id"), req)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, milestone)
+}
+
+// DeleteMilestone removes a milestone from a goal
+// DELETE /api/goals/:id/milestones/:milestone_id
+func (h *GoalHandler) DeleteMilestone(c *gin.Context) {
+	milestoneID := c.Param("milestone_id")
+	if err := h.milestones.Delete(c, getUserID(c), c.Param("id"), milestoneID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": milestoneID, "deleted": true})
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// MilestoneService manages the milestones under a goal. A goal with milestones
+// takes its progress from the share of them completed, kept up to date on
+// every change. Milestones are readable by whoever can read the goal and
+// changed by whoever can edit it.
+type MilestoneService struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewMilestoneService creates a milestone service on an existing Supabase client
+func NewMilestoneService(supabaseClient *db.SupabaseClient) *MilestoneService {
+	return &MilestoneService{supabaseClient: supabaseClient}
+}
+
+// List returns a goal's milestones by due date
+func (s *MilestoneService) List(c *gin.Context, userID, goalID string) ([]map[string]interface{}, error) {
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	if _, err := milestoneGoal(client, userID, goalID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return client.ListMilestones(goalID)
+}
+
+// Create validates req and adds it to the goal's milestones
+func (s *MilestoneService) Create(c *gin.Context, userID, goalID string, req models.CreateMilestoneRequest) (map[string]interface{}, error) {
+	var v validation.Validator
+	validateMilestone(&v, "", req)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	goal, err := milestoneGoal(client, userID, goalID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	milestone, err := client.CreateMilestone(milestoneData(goal, req))
+	if err != nil {
+		return nil, err
+	}
+	recordAudit(c, AuditEntityMilestone, rowString(milestone, "id"), AuditActionCreate, nil, milestone)
+
+	if err := syncGoalProgress(client, goal); err != nil {
+		return nil, err
+	}
+	return milestone, nil
+}
+
+// Update changes the fields of a milestone that req sets
+func (s *MilestoneService) Update(c *gin.Context, userID, goalID, milestoneID string, req models.UpdateMilestoneRequest) (map[string]interface{}, error) {
+	var v validation.Validator
+	if req.Title != nil {
+		validateTitle(&v, *req.Title)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	goal, err := milestoneGoal(client, userID, goalID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	before, err := client.GetMilestone(goalID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		return nil, utils.ErrNotFound("milestone")
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	data := map[string]interface{}{"updated_at": now}
+	if req.Title != nil {
+		data["title"] = *req.Title
+	}
+	if req.DueDate != nil {
+		data["due_date"] = req.DueDate.Format(time.RFC3339)
+	}
+	if req.Completed != nil && *req.Completed != rowBool(before, "completed") {
+		data["completed"] = *req.Completed
+		if *req.Completed {
+			data["completed_at"] = now
+		} else {
+			data["completed_at"] = nil
+		}
+	}
+
+	milestone, err := client.UpdateMilestone(milestoneID, data)
+	if err != nil {
+		return nil, err
+	}
+	if milestone == nil {
+		return nil, utils.ErrNotFound("milestone")
+	}
+	recordAudit(c, AuditEntityMilestone, milestoneID, AuditActionUpdate, before, milestone)
+
+	if err := syncGoalProgress(client, goal); err != nil {
+		return nil, err
+	}
+	return milestone, nil
+}
+
+// Delete removes a milestone. The goal keeps its last progress when its final
+// milestone goes.
+func (s *MilestoneService) Delete(c *gin.Context, userID, goalID, milestoneID string) error {
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	goal, err := milestoneGoal(client, userID, goalID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+	before, err := client.GetMilestone(goalID, milestoneID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return utils.ErrNotFound("milestone")
+	}
+
+	if err := client.DeleteMilestone(milestoneID); err != nil {
+		return err
+	}
+	recordAudit(c, AuditEntityMilestone, milestoneID, AuditActionDelete, before, nil)
+
+	return syncGoalProgress(client, goal)
+}
+
+// milestoneGoal returns the goal whose milestones userID wants, checking that
+// they have role on it
+func milestoneGoal(client *db.SupabaseClient, userID, goalID, role string) (map[string]interface{}, error) {
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+	goal, err := client.GetGoal(goalID)
+	if err != nil {
+		return nil, lookupError("goal", err)
+	}
+	if err := checkRowAccess(client, userID, goal, "goal", role); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+// validateMilestone checks a new milestone; prefix names it within a larger
+// request, as in "milestones[0]."
+func validateMilestone(v *validation.Validator, prefix string, req models.CreateMilestoneRequest) {
+	v.Required(prefix+"title", req.Title)
+	v.MaxLength(prefix+"title", req.Title, validation.MaxTitleLength)
+}
+
+// validateGoalMilestones checks the milestones a new goal is created with
+func validateGoalMilestones(v *validation.Validator, milestones []models.CreateMilestoneRequest) {
+	for i, m := range milestones {
+		validateMilestone(v, fmt.Sprintf("milestones[%d].", i), m)
+	}
+}
+
+// milestoneData builds the row for a new milestone under goal. Milestones
+// belong to the goal's owner, whoever adds them.
+func milestoneData(goal map[string]interface{}, req models.CreateMilestoneRequest) map[string]interface{} {
+	now := time.Now().Format(time.RFC3339)
+	data := map[string]interface{}{
+		"goal_id":    rowString(goal, "id"),
+		"user_id":    rowString(goal, "user_id"),
+		"title":      req.Title,
+		"completed":  req.Completed,
+		"created_at": now,
+		"updated_at": now,
+	}
+	if req.DueDate != nil {
+		data["due_date"] = req.DueDate.Format(time.RFC3339)
+	}
+	if req.Completed {
+		data["completed_at"] = now
+	}
+	return data
+}
+
+// milestoneProgress is the percentage of milestones completed, rounded down so
+// a goal only reaches 100 when every milestone is done
+func milestoneProgress(total, completed int) int {
+	if total == 0 {
+		return 0
+	}
+	return completed * 100 / total
+}
+
+// syncGoalProgress sets a goal's progress from its milestones. Goals without
+// milestones keep the progress they were given. The milestone's own audit
+// entry records the change, so the progress update is not audited again.
+// The database does this in one transaction where it has the
+// sync_goal_progress function; elsewhere, as in the lite build, the
+// milestones are read and the goal updated here.
+func syncGoalProgress(client *db.SupabaseClient, goal map[string]interface{}) error {
+	goalID := rowString(goal, "id")
+	if _, err := client.SyncGoalProgress(goalID); !errors.Is(err, db.ErrFunctionNotFound) {
+		return err
+	}
+	milestones, err := client.ListMilestones(goalID)
+	if err != nil || len(milestones) == 0 {
+		return err
+	}
+
+	completed := 0
+	for _, m := range milestones {
+		if rowBool(m, "completed") {
+			completed++
+		}
+	}
+	progress := milestoneProgress(len(milestones), completed)
+	if progress == rowInt(goal, "progress") {
+		return nil
+	}
+	return client.UpdateGoal(goalID, map[string]interface{}{
+		"progress":   progress,
+		"updated_at": time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,119 @@
+//go:build lite
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestMilestonesDriveGoalProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbURL := db.SQLiteScheme + filepath.Join(t.TempDir(), "milestones.db")
+
+	client, err := db.NewSupabaseClient(dbURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	goals := NewGoalService(client)
+	milestones := NewMilestoneService(client)
+	newContext := func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+		c.Set("user_id", "u1")
+		return c
+	}
+	progress := func(goalID string) int {
+		t.Helper()
+		goal, err := client.GetGoal(goalID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rowInt(goal, "progress")
+	}
+
+	// A goal created from a template starts with its milestones' progress
+	due := time.Now().AddDate(0, 1, 0)
+	created, err := goals.Create(newContext(), "u1", models.CreateGoalRequest{
+		Title:      "Learn Go",
+		StartDate:  time.Now(),
+		TargetDate: time.Now().AddDate(0, 3, 0),
+		Progress:   80,
+		Milestones: []models.CreateMilestoneRequest{
+			{Title: "Finish the tour", DueDate: &due, Completed: true},
+			{Title: "Build a CLI"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := progress(created.ID); got != 50 {
+		t.Fatalf("progress after create = %d, want 50", got)
+	}
+	if listed, _ := created.Goal["milestones"].([]map[string]interface{}); len(listed) != 2 {
+		t.Fatalf("expected the milestones in the result, got %v", created.Goal["milestones"])
+	}
+
+	added, err := milestones.Create(newContext(), "u1", created.ID, models.CreateMilestoneRequest{Title: "Ship a web service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := progress(created.ID); got != 33 {
+		t.Fatalf("progress after adding a milestone = %d, want 33", got)
+	}
+
+	list, err := milestones.List(newContext(), "u1", created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0]["title"] != "Finish the tour" {
+		t.Fatalf("expected dated milestones first, got %v", list)
+	}
+
+	done := true
+	for _, m := range list {
+		if _, err := milestones.Update(newContext(), "u1", created.ID, rowString(m, "id"), models.UpdateMilestoneRequest{Completed: &done}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := progress(created.ID); got != 100 {
+		t.Fatalf("progress with every milestone done = %d, want 100", got)
+	}
+
+	notDone := false
+	updated, err := milestones.Update(newContext(), "u1", created.ID, rowString(added, "id"), models.UpdateMilestoneRequest{Completed: &notDone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated["completed_at"] != nil || progress(created.ID) != 66 {
+		t.Fatalf("reopening a milestone: %v, progress %d", updated, progress(created.ID))
+	}
+
+	if err := milestones.Delete(newContext(), "u1", created.ID, rowString(added, "id")); err != nil {
+		t.Fatal(err)
+	}
+	if got := progress(created.ID); got != 100 {
+		t.Fatalf("progress after deleting the open milestone = %d, want 100", got)
+	}
+
+	// Another user cannot see or change the goal's milestones
+	if _, err := milestones.List(newContext(), "u2", created.ID); !isNotFound(err) {
+		t.Errorf("expected not found for another user, got %v", err)
+	}
+	if _, err := milestones.Update(newContext(), "u1", created.ID, "missing", models.UpdateMilestoneRequest{Completed: &done}); !isNotFound(err) {
+		t.Errorf("expected not found for an unknown milestone, got %v", err)
+	}
+}
+
+func isNotFound(err error) bool {
+	appErr, ok := err.(*utils.AppError)
+	return ok && appErr.HTTPStatus == http.StatusNotFound
+}
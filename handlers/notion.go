@@ -0,0 +1,442 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/integrations"
+	"github.com/productivity/mcp-server/integrations/notion"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/validation"
+)
+
+const (
+	// notionProvider names the Notion integration in routes and storage
+	notionProvider = "notion"
+	// notionEditSlack widens the window of edits pulled, as Notion rounds
+	// last_edited_time down to the minute
+	notionEditSlack = 2 * time.Minute
+)
+
+// notionKinds are synced in this order, each to its own database
+var notionKinds = []string{notion.KindTask, notion.KindGoal}
+
+// NotionIntegration mirrors users' tasks and goals into Notion databases
+// they share with their own integration, and with pull on applies edits
+// made there. It takes a "token" credential and the settings
+// tasks_database_id, goals_database_id and pull.
+type NotionIntegration struct {
+	supabaseClient *db.SupabaseClient
+	tasks          *TaskService
+	apiURL         string
+	timeout        time.Duration
+	interval       time.Duration
+}
+
+// NewNotionIntegration creates the Notion provider
+func NewNotionIntegration(supabaseURL, supabaseKey string, cfg config.Notion) *NotionIntegration {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &NotionIntegration{
+		supabaseClient: client,
+		tasks:          NewTaskService(client),
+		apiURL:         cfg.APIURL,
+		timeout:        cfg.Timeout.Duration,
+		interval:       cfg.SyncInterval.Duration,
+	}
+}
+
+// Name implements integrations.Provider
+func (n *NotionIntegration) Name() string {
+	return notionProvider
+}
+
+// SyncInterval implements integrations.Provider
+func (n *NotionIntegration) SyncInterval() time.Duration {
+	return n.interval
+}
+
+// HandleWebhook implements integrations.Provider; the sync polls Notion instead
+func (n *NotionIntegration) HandleWebhook(ctx context.Context, r *http.Request) error {
+	return integrations.ErrNoWebhooks
+}
+
+// notionDatabaseSetting is where a kind's database ID is kept
+func notionDatabaseSetting(kind string) string {
+	return kind + "s_database_id"
+}
+
+// Connect checks the token can reach the databases and adds the columns the
+// sync needs to them. Database IDs may be given as links copied from Notion;
+// at least one is required.
+func (n *NotionIntegration) Connect(ctx context.Context, conn *integrations.Connection, previous *integrations.Connection) error {
+	var v validation.Validator
+	v.Required("credentials.token", conn.Credentials["token"])
+	databases := map[string]string{}
+	given := false
+	for _, kind := range notionKinds {
+		setting := notionDatabaseSetting(kind)
+		value := conn.Settings[setting]
+		if value == nil || value == "" {
+			continue
+		}
+		given = true
+		raw, _ := value.(string)
+		id, ok := notion.ParseID(raw)
+		v.Check(ok, "settings."+setting, validation.CodeInvalidFormat,
+			"settings."+setting+" must be a Notion database ID or link")
+		if ok {
+			databases[kind] = id
+		}
+	}
+	v.Check(given, "settings.tasks_database_id", validation.CodeRequired,
+		"settings.tasks_database_id or settings.goals_database_id is required")
+	pull, isBool := conn.Settings["pull"].(bool)
+	v.Check(isBool || conn.Settings["pull"] == nil, "settings.pull", validation.CodeInvalidType, "settings.pull must be true or false")
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	client := notion.NewClient(n.apiURL, conn.Credentials["token"], n.timeout)
+	for _, kind := range notionKinds {
+		if id, ok := databases[kind]; ok {
+			if _, err := client.EnsureProperties(ctx, id, kind); err != nil {
+				return notionConnectError(kind, err)
+			}
+		}
+	}
+
+	conn.Credentials = map[string]string{"token": conn.Credentials["token"]}
+	conn.Settings = map[string]interface{}{"pull": pull}
+	for _, kind := range notionKinds {
+		setting := notionDatabaseSetting(kind)
+		if id, ok := databases[kind]; ok {
+			conn.Settings[setting] = id
+		}
+		// Pages in a database no longer synced would otherwise be updated from the new one
+		if previous != nil && previous.Setting(setting) != databases[kind] {
+			if err := n.supabaseClient.WithContext(ctx).DeleteIntegrationLinks(conn.UserID, notionProvider, kind); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// notionConnectError explains why a database cannot be synced
+func notionConnectError(kind string, err error) error {
+	switch {
+	case notion.IsUnauthorized(err):
+		return &integrations.ConnectError{Message: "Notion rejected the token", Err: err}
+	case notion.IsNotFound(err):
+		return &integrations.ConnectError{Message: fmt.Sprintf("the %s database was not found; share it with your integration", kind), Err: err}
+	}
+	var apiErr *notion.APIError
+	var urlErr *url.Error
+	if errors.As(err, &apiErr) || errors.As(err, &urlErr) {
+		return err
+	}
+	// A column of the wrong type, which the user has to rename
+	return &integrations.ConnectError{Message: err.Error(), Err: err}
+}
+
+// notionLink is the page mirroring a task or goal and the content last synced
+type notionLink struct {
+	pageID string
+	hash   string
+}
+
+// notionSync is one user's sync in progress
+type notionSync struct {
+	n      *NotionIntegration
+	c      *gin.Context
+	userID string
+	client *notion.Client
+	store  *db.SupabaseClient
+	links  map[string]notionLink // by kind:id
+	result integrations.Result
+}
+
+// fail records an error
+func (s *notionSync) fail(err error) {
+	s.result.Errors = append(s.result.Errors, err.Error())
+}
+
+// link records the page mirroring a task or goal
+func (s *notionSync) link(kind, id, pageID, hash string) {
+	if err := s.store.UpsertIntegrationLink(s.userID, notionProvider, kind, id, pageID, hash); err != nil {
+		s.fail(fmt.Errorf("%s %s: %w", kind, id, err))
+		return
+	}
+	s.links[kind+":"+id] = notionLink{pageID: pageID, hash: hash}
+}
+
+// Sync pulls edits from Notion if the user asked for them, then pushes
+// tasks and goals changed since the last sync. Notion's edits win when both
+// sides changed.
+func (n *NotionIntegration) Sync(ctx context.Context, conn *integrations.Connection) (integrations.Result, error) {
+	s := &notionSync{
+		n:      n,
+		c:      integrationContext(ctx, notionProvider, conn.UserID),
+		userID: conn.UserID,
+		client: notion.NewClient(n.apiURL, conn.Credentials["token"], n.timeout),
+		store:  n.supabaseClient.WithContext(ctx),
+		links:  make(map[string]notionLink),
+	}
+	pages, err := s.store.ListIntegrationLinks(conn.UserID, notionProvider)
+	if err != nil {
+		return s.result, err
+	}
+	for _, page := range pages {
+		s.links[rowString(page, "entity_type")+":"+rowString(page, "entity_id")] = notionLink{
+			pageID: rowString(page, "external_id"),
+			hash:   rowString(page, "content_hash"),
+		}
+	}
+
+	var since time.Time
+	if !conn.LastSyncedAt.IsZero() {
+		since = conn.LastSyncedAt.Add(-notionEditSlack)
+	}
+	for _, kind := range notionKinds {
+		databaseID := conn.Setting(notionDatabaseSetting(kind))
+		if databaseID == "" {
+			continue
+		}
+		// Columns deleted in Notion since connecting are put back
+		database, err := s.client.EnsureProperties(ctx, databaseID, kind)
+		if err != nil {
+			s.fail(fmt.Errorf("%s database: %w", kind, err))
+			continue
+		}
+		title := database.TitleProperty()
+		if conn.BoolSetting("pull") {
+			s.pull(kind, databaseID, title, since)
+		}
+		s.push(kind, databaseID, title)
+	}
+	return s.result, nil
+}
+
+// rows returns the user's tasks or goals, trashed ones excluded
+func (s *notionSync) rows(kind string) ([]map[string]interface{}, error) {
+	if kind == notion.KindGoal {
+		return s.store.GetUserGoals(s.userID)
+	}
+	return s.store.GetUserTasks(s.userID)
+}
+
+// notionItem describes a task or goal row as the sync writes it to Notion
+func notionItem(kind string, row map[string]interface{}) notion.Item {
+	item := notion.Item{
+		Kind:        kind,
+		ID:          rowString(row, "id"),
+		Title:       rowString(row, "title"),
+		Description: rowString(row, "description"),
+	}
+	dueColumn := "due_date"
+	if kind == notion.KindGoal {
+		dueColumn = "target_date"
+		item.Done = rowBool(row, "archived")
+		item.Progress = rowInt(row, "progress")
+	} else {
+		item.Done = rowBool(row, "completed")
+		item.Priority = rowInt(row, "priority")
+		item.Category = rowString(row, "category")
+	}
+	if due, ok := rowTime(row, dueColumn); ok {
+		due = due.UTC().Truncate(time.Second)
+		item.Due = &due
+	}
+	return item
+}
+
+// push writes tasks or goals changed since the last sync to the database,
+// and archives the pages of those trashed
+func (s *notionSync) push(kind, databaseID, title string) {
+	ctx := s.c.Request.Context()
+	rows, err := s.rows(kind)
+	if err != nil {
+		s.fail(fmt.Errorf("%s list: %w", kind, err))
+		return
+	}
+
+	present := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		item := notionItem(kind, row)
+		present[item.ID] = true
+		hash := item.Hash()
+		link, linked := s.links[kind+":"+item.ID]
+		if linked && link.hash == hash {
+			continue
+		}
+
+		var page *notion.Page
+		if linked {
+			page, err = s.client.UpdatePage(ctx, link.pageID, item.Properties(title))
+			if notion.IsNotFound(err) || notion.IsArchived(err) {
+				// The page was deleted in Notion; the task or goal still exists
+				linked = false
+			} else if err == nil {
+				s.result.Updated++
+			}
+		}
+		if !linked {
+			page, err = s.client.CreatePage(ctx, databaseID, item.Properties(title))
+			if err == nil {
+				s.result.Created++
+			}
+		}
+		if err != nil {
+			s.fail(fmt.Errorf("%s %s: %w", kind, item.ID, err))
+			continue
+		}
+		s.link(kind, item.ID, page.ID, hash)
+	}
+
+	for key, link := range s.links {
+		id := key[len(kind)+1:]
+		if key[:len(kind)+1] != kind+":" || present[id] {
+			continue
+		}
+		if err := s.client.ArchivePage(ctx, link.pageID); err != nil && !notion.IsNotFound(err) && !notion.IsArchived(err) {
+			s.fail(fmt.Errorf("%s %s: %w", kind, id, err))
+			continue
+		}
+		if err := s.store.DeleteIntegrationLink(notionProvider, kind, id); err != nil {
+			s.fail(fmt.Errorf("%s %s: %w", kind, id, err))
+			continue
+		}
+		delete(s.links, key)
+		s.result.Archived++
+	}
+}
+
+// pull applies edits made in Notion since the last sync to the tasks or
+// goals their pages mirror. Rows added in Notion by hand are left alone.
+func (s *notionSync) pull(kind, databaseID, title string, since time.Time) {
+	pages, err := s.client.QueryDatabase(s.c.Request.Context(), databaseID, since)
+	if err != nil {
+		s.fail(fmt.Errorf("%s database: %w", kind, err))
+		return
+	}
+	for _, page := range pages {
+		edited, err := notion.ItemFromPage(page, kind, title)
+		if err != nil {
+			s.fail(fmt.Errorf("notion page %s: %w", page.ID, err))
+			continue
+		}
+		link, linked := s.links[kind+":"+edited.ID]
+		if !linked || link.pageID != page.ID || page.Archived {
+			continue
+		}
+		hash := edited.Hash()
+		if hash == link.hash {
+			continue
+		}
+		if kind == notion.KindGoal {
+			err = s.applyGoal(edited)
+		} else {
+			err = s.applyTask(edited)
+		}
+		if err != nil {
+			s.fail(fmt.Errorf("%s %s: %w", kind, edited.ID, err))
+			continue
+		}
+		s.result.Pulled++
+		s.link(kind, edited.ID, page.ID, hash)
+	}
+}
+
+// applyTask updates a task with the fields edited in Notion. An emptied
+// title, priority or due date is not applied; the next push restores it.
+func (s *notionSync) applyTask(edited notion.Item) error {
+	row, err := s.store.GetTask(edited.ID)
+	if err != nil {
+		return err
+	}
+	current := notionItem(notion.KindTask, row)
+
+	var req models.UpdateTaskRequest
+	changed := false
+	if edited.Title != "" && edited.Title != current.Title {
+		req.Title, changed = &edited.Title, true
+	}
+	if edited.Description != current.Description {
+		req.Description, changed = &edited.Description, true
+	}
+	if edited.Priority != 0 && edited.Priority != current.Priority {
+		req.Priority, changed = &edited.Priority, true
+	}
+	if edited.Category != current.Category {
+		req.Category, changed = &edited.Category, true
+	}
+	if edited.Due != nil && (current.Due == nil || !edited.Due.Equal(*current.Due)) {
+		req.DueDate, changed = edited.Due, true
+	}
+	if edited.Done != current.Done {
+		req.Completed, changed = &edited.Done, true
+	}
+	if !changed {
+		return nil
+	}
+	_, err = s.n.tasks.Update(s.c, s.userID, edited.ID, req)
+	return err
+}
+
+// applyGoal updates a goal with the fields edited in Notion. Progress is
+// not applied, as milestones decide it for goals that have them.
+func (s *notionSync) applyGoal(edited notion.Item) error {
+	before, err := s.store.GetGoal(edited.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkRowAccess(s.store, s.userID, before, "goal", models.RoleEditor); err != nil {
+		return err
+	}
+	current := notionItem(notion.KindGoal, before)
+
+	data := map[string]interface{}{}
+	var v validation.Validator
+	if edited.Title != "" && edited.Title != current.Title {
+		validateTitle(&v, edited.Title)
+		data["title"] = edited.Title
+	}
+	if edited.Description != current.Description {
+		v.MaxLength("description", edited.Description, validation.MaxDescriptionLength)
+		data["description"] = edited.Description
+	}
+	if edited.Due != nil && (current.Due == nil || !edited.Due.Equal(*current.Due)) {
+		data["target_date"] = edited.Due.Format(time.RFC3339)
+	}
+	if edited.Done != current.Done {
+		data["archived"] = edited.Done
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	data["updated_at"] = time.Now().Format(time.RFC3339)
+	if err := s.store.UpdateGoal(edited.ID, data); err != nil {
+		return err
+	}
+	after, err := s.store.GetGoal(edited.ID)
+	if err != nil {
+		after = data
+	}
+	recordAudit(s.c, AuditEntityGoal, edited.ID, AuditActionUpdate, before, after)
+	return nil
+}
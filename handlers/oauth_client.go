@@ -1,17 +1,54 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+)
+
+// Token endpoint authentication methods (RFC 7591 section 2)
+const (
+	AuthMethodNone              = "none"
+	AuthMethodClientSecretPost  = "client_secret_post"
+	AuthMethodClientSecretBasic = "client_secret_basic"
 )
 
 // OAuthClient represents a registered OAuth client
 type OAuthClient struct {
-	ClientID     string   `json:"client_id"`
-	ClientSecret string   `json:"client_secret,omitempty"`
-	RedirectURIs []string `json:"redirect_uris"`
-	Name         string   `json:"name,omitempty"`
+	ClientID                string   `json:"client_id"`
+	ClientSecret            string   `json:"client_secret,omitempty"`
+	RedirectURIs            []string `json:"redirect_uris"`
+	Name                    string   `json:"name,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+	IssuedAt                int64    `json:"client_id_issued_at,omitempty"`
+
+	// secretHash replaces ClientSecret for registered clients
+	secretHash string
+	// registrationTokenHash authorizes /oauth/register/:client_id; empty for
+	// clients that were not dynamically registered
+	registrationTokenHash string
+}
+
+// Public reports whether the client cannot keep a secret, such as a native
+// or browser app registered with token_endpoint_auth_method "none". Public
+// clients must use PKCE.
+func (c *OAuthClient) Public() bool {
+	return c.TokenEndpointAuthMethod == AuthMethodNone
 }
 
 // Default clients for development/testing
@@ -42,69 +79,409 @@ var defaultClients = map[string]*OAuthClient{
 	},
 }
 
-// OAuthRegister handles OAuth client registration
+// clientStore persists registered clients; nil keeps them in memoryClients
+var clientStore *db.SupabaseClient
+
+var (
+	memoryClientsMu sync.RWMutex
+	memoryClients   = make(map[string]*OAuthClient)
+)
+
+// SetOAuthClientStore persists registered OAuth clients in Supabase so they
+// survive restarts and are shared between instances
+func SetOAuthClientStore(supabaseURL, supabaseKey string) {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	clientStore = client
+}
+
+// StoreOAuthClient saves a client in the installed store, or in memory when
+// there is none. A ClientSecret is replaced by its hash before saving.
+func StoreOAuthClient(client *OAuthClient) error {
+	if client.ClientSecret != "" {
+		client.secretHash = hashClientSecret(client.ClientSecret)
+		client.ClientSecret = ""
+	}
+	if client.IssuedAt == 0 {
+		client.IssuedAt = time.Now().Unix()
+	}
+
+	if clientStore == nil {
+		memoryClientsMu.Lock()
+		defer memoryClientsMu.Unlock()
+		memoryClients[client.ClientID] = client
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"redirect_uris":              client.RedirectURIs,
+		"name":                       client.Name,
+		"client_secret_hash":         nullIfEmpty(client.secretHash),
+		"registration_token_hash":    nullIfEmpty(client.registrationTokenHash),
+		"token_endpoint_auth_method": client.TokenEndpointAuthMethod,
+		"grant_types":                client.GrantTypes,
+		"response_types":             client.ResponseTypes,
+		"scope":                      client.Scope,
+		"client_id_issued_at":        time.Unix(client.IssuedAt, 0).UTC().Format(time.RFC3339),
+	}
+	_, err := clientStore.UpsertOAuthClient(client.ClientID, data)
+	return err
+}
+
+// deleteOAuthClient removes a registered client
+func deleteOAuthClient(clientID string) error {
+	if clientStore == nil {
+		memoryClientsMu.Lock()
+		defer memoryClientsMu.Unlock()
+		delete(memoryClients, clientID)
+		return nil
+	}
+	return clientStore.DeleteOAuthClient(clientID)
+}
+
+// lookupClient finds a client among the built-in clients, then the registered ones
+func lookupClient(clientID string) *OAuthClient {
+	if client, ok := defaultClients[clientID]; ok {
+		return client
+	}
+	if clientStore == nil {
+		memoryClientsMu.RLock()
+		defer memoryClientsMu.RUnlock()
+		return memoryClients[clientID]
+	}
+	row, err := clientStore.GetOAuthClient(clientID)
+	if err != nil || row == nil {
+		return nil
+	}
+
+	client := &OAuthClient{
+		ClientID:      clientID,
+		RedirectURIs:  rowStrings(row, "redirect_uris"),
+		GrantTypes:    rowStrings(row, "grant_types"),
+		ResponseTypes: rowStrings(row, "response_types"),
+	}
+	client.Name, _ = row["name"].(string)
+	client.TokenEndpointAuthMethod, _ = row["token_endpoint_auth_method"].(string)
+	client.Scope, _ = row["scope"].(string)
+	client.secretHash, _ = row["client_secret_hash"].(string)
+	client.registrationTokenHash, _ = row["registration_token_hash"].(string)
+	if issuedAt, ok := rowTime(row, "client_id_issued_at"); ok {
+		client.IssuedAt = issuedAt.Unix()
+	}
+	return client
+}
+
+// hashClientSecret returns the hex SHA-256 digest stored in place of a client
+// secret or registration access token
+func hashClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns n random bytes, base64url encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ClientMetadata is the client metadata accepted by dynamic client
+// registration (RFC 7591 section 2)
+type ClientMetadata struct {
+	ClientID                string   `json:"client_id,omitempty"` // only on PUT, where it must match the URL
+	RedirectURIs            []string `json:"redirect_uris"`
+	ClientName              string   `json:"client_name,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+}
+
+// registrationError writes an RFC 7591 section 3.2.2 error response
+func registrationError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, gin.H{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// applyMetadata validates metadata, fills in the RFC 7591 defaults and copies
+// it onto client. It returns the RFC 7591 error code and description on failure.
+func applyMetadata(client *OAuthClient, meta ClientMetadata) (string, string) {
+	if len(meta.RedirectURIs) == 0 {
+		return "invalid_redirect_uri", "redirect_uris is required"
+	}
+	for _, uri := range meta.RedirectURIs {
+		if err := CheckRedirectURI(uri); err != nil {
+			return "invalid_redirect_uri", err.Error()
+		}
+	}
+
+	if meta.TokenEndpointAuthMethod == "" {
+		meta.TokenEndpointAuthMethod = AuthMethodClientSecretBasic
+	}
+	switch meta.TokenEndpointAuthMethod {
+	case AuthMethodNone, AuthMethodClientSecretPost, AuthMethodClientSecretBasic:
+	default:
+		return "invalid_client_metadata", "unsupported token_endpoint_auth_method " + meta.TokenEndpointAuthMethod
+	}
+
+	if len(meta.GrantTypes) == 0 {
+		meta.GrantTypes = []string{"authorization_code"}
+	}
+	for _, grant := range meta.GrantTypes {
+		if grant != "authorization_code" && grant != "refresh_token" {
+			return "invalid_client_metadata", "unsupported grant_type " + grant
+		}
+	}
+	if len(meta.ResponseTypes) == 0 {
+		meta.ResponseTypes = []string{"code"}
+	}
+	for _, responseType := range meta.ResponseTypes {
+		if responseType != "code" {
+			return "invalid_client_metadata", "unsupported response_type " + responseType
+		}
+	}
+
+	client.RedirectURIs = meta.RedirectURIs
+	client.Name = meta.ClientName
+	client.TokenEndpointAuthMethod = meta.TokenEndpointAuthMethod
+	client.GrantTypes = meta.GrantTypes
+	client.ResponseTypes = meta.ResponseTypes
+	client.Scope = meta.Scope
+	return "", ""
+}
+
+// CheckRedirectURI accepts https URIs, http only on loopback hosts, and
+// private-use schemes for native apps (RFC 8252), never with a fragment
+func CheckRedirectURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return fmt.Errorf("redirect URI %q is not an absolute URI", raw)
+	}
+	if u.Fragment != "" || strings.Contains(raw, "#") {
+		return fmt.Errorf("redirect URI %q must not contain a fragment", raw)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		if u.Host == "" {
+			return fmt.Errorf("redirect URI %q has no host", raw)
+		}
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("redirect URI %q must use https unless it points at localhost", raw)
+		}
+	case "javascript", "data", "file", "vbscript":
+		return fmt.Errorf("redirect URI scheme %q is not allowed", u.Scheme)
+	}
+	return nil
+}
+
+// registrationResponse describes a client as in RFC 7591 section 3.2.1. The
+// secret and registration token are only known when they were just issued.
+func registrationResponse(c *gin.Context, client *OAuthClient, secret, registrationToken string) gin.H {
+	resp := gin.H{
+		"client_id":                  client.ClientID,
+		"client_id_issued_at":        client.IssuedAt,
+		"redirect_uris":              client.RedirectURIs,
+		"client_name":                client.Name,
+		"token_endpoint_auth_method": client.TokenEndpointAuthMethod,
+		"grant_types":                client.GrantTypes,
+		"response_types":             client.ResponseTypes,
+		"registration_client_uri":    getBaseURL(c) + "/oauth/register/" + client.ClientID,
+	}
+	if client.Scope != "" {
+		resp["scope"] = client.Scope
+	}
+	if secret != "" {
+		resp["client_secret"] = secret
+		resp["client_secret_expires_at"] = 0
+	}
+	if registrationToken != "" {
+		resp["registration_access_token"] = registrationToken
+	}
+	return resp
+}
+
+// OAuthRegister handles dynamic client registration (RFC 7591). The server
+// issues the client_id, a client_secret unless token_endpoint_auth_method is
+// "none", and a registration_access_token for the management endpoints.
 // POST /oauth/register
 func OAuthRegister(c *gin.Context) {
-	var req struct {
-		ClientID     string   `json:"client_id"`
-		ClientSecret string   `json:"client_secret,omitempty"`
-		RedirectURIs []string `json:"redirect_uris"`
-		Name         string   `json:"name,omitempty"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":             "invalid_request",
-			"error_description": err.Error(),
-		})
+	var meta ClientMetadata
+	if err := c.ShouldBindJSON(&meta); err != nil {
+		registrationError(c, http.StatusBadRequest, "invalid_client_metadata", err.Error())
+		return
+	}
+	if meta.ClientID != "" {
+		registrationError(c, http.StatusBadRequest, "invalid_client_metadata", "client_id is assigned by the server")
 		return
 	}
 
-	if req.ClientID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":             "invalid_request",
-			"error_description": "client_id is required",
-		})
+	client := &OAuthClient{IssuedAt: time.Now().Unix()}
+	if code, description := applyMetadata(client, meta); code != "" {
+		registrationError(c, http.StatusBadRequest, code, description)
 		return
 	}
 
-	// TODO: Store in database
-	// For now, return success (clients stored in memory)
-	client := &OAuthClient{
-		ClientID:     req.ClientID,
-		ClientSecret: req.ClientSecret,
-		RedirectURIs: req.RedirectURIs,
-		Name:         req.Name,
+	id, err := randomToken(16)
+	if err != nil {
+		registrationError(c, http.StatusInternalServerError, "server_error", "failed to generate client credentials")
+		return
+	}
+	client.ClientID = "mcp_" + id
+
+	secret := ""
+	if client.TokenEndpointAuthMethod != AuthMethodNone {
+		if secret, err = randomToken(32); err != nil {
+			registrationError(c, http.StatusInternalServerError, "server_error", "failed to generate client credentials")
+			return
+		}
+		client.secretHash = hashClientSecret(secret)
 	}
+	registrationToken, err := randomToken(32)
+	if err != nil {
+		registrationError(c, http.StatusInternalServerError, "server_error", "failed to generate client credentials")
+		return
+	}
+	client.registrationTokenHash = hashClientSecret(registrationToken)
 
-	// Store in default clients map (temporary - should use database)
-	if defaultClients == nil {
-		defaultClients = make(map[string]*OAuthClient)
+	if err := StoreOAuthClient(client); err != nil {
+		registrationError(c, http.StatusInternalServerError, "server_error", "failed to store client")
+		return
 	}
-	defaultClients[req.ClientID] = client
+	recordAudit(c, AuditEntityOAuthClient, client.ClientID, AuditActionCreate, nil, auditClientSnapshot(client))
 
-	c.JSON(http.StatusCreated, gin.H{
-		"client_id":     client.ClientID,
-		"client_secret": client.ClientSecret,
-		"redirect_uris": client.RedirectURIs,
-		"name":          client.Name,
-	})
+	c.JSON(http.StatusCreated, registrationResponse(c, client, secret, registrationToken))
 }
 
-// validateClient validates a client_id and client_secret
-func validateClient(clientID, clientSecret string) bool {
-	// Check default clients
-	if client, ok := defaultClients[clientID]; ok {
-		if clientSecret == "" || client.ClientSecret == clientSecret {
-			return true
+// registeredClient loads the client named in the URL and checks the bearer
+// registration access token (RFC 7592 section 2). Unknown clients and bad
+// tokens get the same 401 so client IDs cannot be probed.
+func registeredClient(c *gin.Context) *OAuthClient {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	client := lookupClient(c.Param("client_id"))
+	if token == "" || client == nil || client.registrationTokenHash == "" ||
+		subtle.ConstantTimeCompare([]byte(client.registrationTokenHash), []byte(hashClientSecret(token))) != 1 {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		registrationError(c, http.StatusUnauthorized, "invalid_token", "invalid registration access token")
+		return nil
+	}
+	return client
+}
+
+// OAuthGetClient returns a registered client's metadata (RFC 7592)
+// GET /oauth/register/:client_id
+func OAuthGetClient(c *gin.Context) {
+	client := registeredClient(c)
+	if client == nil {
+		return
+	}
+	c.JSON(http.StatusOK, registrationResponse(c, client, "", ""))
+}
+
+// OAuthUpdateClient replaces a registered client's metadata (RFC 7592). A
+// client switching from "none" to a secret-based auth method is issued a secret.
+// PUT /oauth/register/:client_id
+func OAuthUpdateClient(c *gin.Context) {
+	client := registeredClient(c)
+	if client == nil {
+		return
+	}
+
+	var meta ClientMetadata
+	if err := c.ShouldBindJSON(&meta); err != nil {
+		registrationError(c, http.StatusBadRequest, "invalid_client_metadata", err.Error())
+		return
+	}
+	if meta.ClientID != client.ClientID {
+		registrationError(c, http.StatusBadRequest, "invalid_client_metadata", "client_id must match the client being updated")
+		return
+	}
+
+	before := auditClientSnapshot(client)
+	updated := *client
+	if code, description := applyMetadata(&updated, meta); code != "" {
+		registrationError(c, http.StatusBadRequest, code, description)
+		return
+	}
+
+	secret := ""
+	switch {
+	case updated.TokenEndpointAuthMethod == AuthMethodNone:
+		updated.secretHash = ""
+	case updated.secretHash == "":
+		var err error
+		if secret, err = randomToken(32); err != nil {
+			registrationError(c, http.StatusInternalServerError, "server_error", "failed to generate client credentials")
+			return
 		}
+		updated.secretHash = hashClientSecret(secret)
 	}
-	return false
+
+	if err := StoreOAuthClient(&updated); err != nil {
+		registrationError(c, http.StatusInternalServerError, "server_error", "failed to store client")
+		return
+	}
+	recordAudit(c, AuditEntityOAuthClient, updated.ClientID, AuditActionUpdate, before, auditClientSnapshot(&updated))
+
+	c.JSON(http.StatusOK, registrationResponse(c, &updated, secret, ""))
+}
+
+// OAuthDeleteClient deregisters a client (RFC 7592)
+// DELETE /oauth/register/:client_id
+func OAuthDeleteClient(c *gin.Context) {
+	client := registeredClient(c)
+	if client == nil {
+		return
+	}
+	if err := deleteOAuthClient(client.ClientID); err != nil {
+		registrationError(c, http.StatusInternalServerError, "server_error", "failed to delete client")
+		return
+	}
+	recordAudit(c, AuditEntityOAuthClient, client.ClientID, AuditActionDelete, auditClientSnapshot(client), nil)
+	c.Status(http.StatusNoContent)
+}
+
+// auditClientSnapshot describes a client for the audit log without its secret
+func auditClientSnapshot(client *OAuthClient) map[string]interface{} {
+	return map[string]interface{}{
+		"client_id":                  client.ClientID,
+		"redirect_uris":              client.RedirectURIs,
+		"name":                       client.Name,
+		"token_endpoint_auth_method": client.TokenEndpointAuthMethod,
+		"has_secret":                 client.ClientSecret != "" || client.secretHash != "",
+	}
+}
+
+// validateClient authenticates a client at the token endpoint: a public
+// client has no secret to present, any other client must present its own
+func validateClient(clientID, clientSecret string) bool {
+	client := lookupClient(clientID)
+	if client == nil {
+		return false
+	}
+	if client.Public() {
+		return clientSecret == ""
+	}
+	if clientSecret == "" {
+		return false
+	}
+	if client.secretHash != "" {
+		return subtle.ConstantTimeCompare([]byte(client.secretHash), []byte(hashClientSecret(clientSecret))) == 1
+	}
+	return client.ClientSecret == clientSecret
 }
 
 // validateRedirectURI validates redirect_uri against registered clients
 func validateRedirectURI(clientID, redirectURI string) bool {
-	if client, ok := defaultClients[clientID]; ok {
+	if client := lookupClient(clientID); client != nil {
 		for _, uri := range client.RedirectURIs {
 			if uri == redirectURI {
 				return true
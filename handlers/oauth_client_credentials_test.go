@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/signing"
+)
+
+func TestClientCredentialsGrant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetJWTSecret("client-credentials-test-secret")
+	SetClientCredentials([]string{"cc-worker=read act_as", "cc-public=read"})
+	t.Cleanup(func() { SetJWTSecret(""); SetClientCredentials(nil) })
+	for _, client := range []*OAuthClient{
+		{ClientID: "cc-worker", ClientSecret: "worker-secret", RedirectURIs: []string{"http://localhost"}},
+		{ClientID: "cc-public", RedirectURIs: []string{"http://localhost"}, TokenEndpointAuthMethod: AuthMethodNone},
+	} {
+		if err := StoreOAuthClient(client); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := gin.New()
+	router.POST("/oauth/token", OAuthToken)
+	token := func(clientID, secret, scope string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(OAuthTokenRequest{GrantType: "client_credentials", ClientID: clientID, ClientSecret: secret, Scope: scope})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/oauth/token", bytes.NewReader(body)))
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	for _, tt := range []struct {
+		name, clientID, secret, scope string
+		status                        int
+		wantError                     string
+	}{
+		{"wrong secret", "cc-worker", "nope", "", http.StatusUnauthorized, "invalid_client"},
+		{"no secret", "cc-worker", "", "", http.StatusUnauthorized, "invalid_client"},
+		{"public client", "cc-public", "anything", "", http.StatusUnauthorized, "invalid_client"},
+		{"client not listed", "mcp_client", "mcp_client_secret_dev", "", http.StatusBadRequest, "unauthorized_client"},
+		{"scope not allowed", "cc-worker", "worker-secret", "read write", http.StatusBadRequest, "invalid_scope"},
+	} {
+		if status, resp := token(tt.clientID, tt.secret, tt.scope); status != tt.status || resp["error"] != tt.wantError {
+			t.Errorf("%s: %d %v, want %d %s", tt.name, status, resp["error"], tt.status, tt.wantError)
+		}
+	}
+
+	status, resp := token("cc-worker", "worker-secret", "")
+	if status != http.StatusOK || resp["scope"] != "read act_as" || resp["refresh_token"] != nil {
+		t.Fatalf("token: %d %v", status, resp)
+	}
+	claims, err := validateJWT(resp["access_token"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "cc-worker" || claims["role"] != signing.ServiceRole {
+		t.Errorf("claims = %v", claims)
+	}
+
+	status, resp = token("cc-worker", "worker-secret", "read")
+	if status != http.StatusOK || resp["scope"] != "read" {
+		t.Errorf("narrowed scope: %d %v", status, resp)
+	}
+}
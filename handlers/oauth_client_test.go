@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDynamicClientRegistration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/oauth/register", OAuthRegister)
+	router.GET("/oauth/register/:client_id", OAuthGetClient)
+	router.PUT("/oauth/register/:client_id", OAuthUpdateClient)
+	router.DELETE("/oauth/register/:client_id", OAuthDeleteClient)
+
+	do := func(method, path, token, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(rec, req)
+		var resp map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	for _, body := range []string{
+		`{"client_id":"mine","redirect_uris":["https://app.example.com/cb"]}`,
+		`{"redirect_uris":[]}`,
+		`{"redirect_uris":["http://app.example.com/cb"]}`,
+		`{"redirect_uris":["https://app.example.com/cb#frag"]}`,
+		`{"redirect_uris":["javascript:alert(1)"]}`,
+		`{"redirect_uris":["https://app.example.com/cb"],"grant_types":["password"]}`,
+	} {
+		if rec, _ := do(http.MethodPost, "/oauth/register", "", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("register %s: status %d, want 400", body, rec.Code)
+		}
+	}
+
+	rec, resp := do(http.MethodPost, "/oauth/register", "",
+		`{"client_name":"Test","redirect_uris":["https://app.example.com/cb","http://127.0.0.1:9000/cb","claude://oauth-callback"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status %d (%s)", rec.Code, rec.Body)
+	}
+	clientID, _ := resp["client_id"].(string)
+	secret, _ := resp["client_secret"].(string)
+	token, _ := resp["registration_access_token"].(string)
+	if !strings.HasPrefix(clientID, "mcp_") || secret == "" || token == "" {
+		t.Fatalf("missing issued credentials in %v", resp)
+	}
+	if uri, _ := resp["registration_client_uri"].(string); !strings.HasSuffix(uri, "/oauth/register/"+clientID) {
+		t.Errorf("registration_client_uri = %q", uri)
+	}
+	if !validateClient(clientID, secret) || validateClient(clientID, "wrong") {
+		t.Error("issued secret should be the only one accepted")
+	}
+	if !validateRedirectURI(clientID, "http://127.0.0.1:9000/cb") {
+		t.Error("registered redirect URI rejected")
+	}
+
+	path := "/oauth/register/" + clientID
+	if rec, _ := do(http.MethodGet, path, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("get without token: status %d, want 401", rec.Code)
+	}
+	if rec, _ := do(http.MethodGet, path, secret, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("get with the client secret: status %d, want 401", rec.Code)
+	}
+	if rec, resp := do(http.MethodGet, path, token, ""); rec.Code != http.StatusOK || resp["client_secret"] != nil {
+		t.Errorf("get: status %d, body %v", rec.Code, resp)
+	}
+
+	rec, resp = do(http.MethodPut, path, token,
+		`{"client_id":"`+clientID+`","client_name":"Renamed","redirect_uris":["https://app.example.com/new"],"token_endpoint_auth_method":"none"}`)
+	if rec.Code != http.StatusOK || resp["client_name"] != "Renamed" {
+		t.Fatalf("update: status %d (%s)", rec.Code, rec.Body)
+	}
+	if validateRedirectURI(clientID, "https://app.example.com/cb") || validateClient(clientID, secret) {
+		t.Error("update should replace redirect URIs and drop the secret")
+	}
+
+	if rec, _ := do(http.MethodDelete, path, token, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d, want 204", rec.Code)
+	}
+	if lookupClient(clientID) != nil {
+		t.Error("client still registered after delete")
+	}
+	if rec, _ := do(http.MethodGet, path, token, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("get after delete: status %d, want 401", rec.Code)
+	}
+}
@@ -11,31 +11,66 @@ import (
 // Returns OAuth server metadata per RFC 8414
 func OAuthDiscovery(c *gin.Context) {
 	baseURL := getBaseURL(c)
-	// #region agent log
-	debugLog("oauth_discovery.go:13", "OAuthDiscovery entry", map[string]interface{}{
-		"baseURL":      baseURL,
-		"host":         c.Request.Host,
-		"scheme":       c.Request.URL.Scheme,
-		"hypothesisId": "H3",
+	oauthTrace(c, "OAuthDiscovery entry", map[string]interface{}{
+		"baseURL": baseURL,
+		"host":    c.Request.Host,
+		"scheme":  c.Request.URL.Scheme,
 	})
-	// #endregion
 
 	discovery := map[string]interface{}{
 		"issuer":                                baseURL,
 		"authorization_endpoint":                baseURL + "/authorize", // Claude Desktop calls /authorize
 		"token_endpoint":                        baseURL + "/oauth/token",
+		"registration_endpoint":                 baseURL + "/oauth/register",                                   // RFC 7591 dynamic client registration
 		"token_endpoint_auth_methods_supported": []string{"client_secret_post", "client_secret_basic", "none"}, // OAuth 2.1: PKCE allows no client secret
 		"response_types_supported":              []string{"code"},
-		"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
-		"code_challenge_methods_supported":      []string{"S256", "plain"}, // OAuth 2.1: PKCE support (S256 required, plain optional)
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", "client_credentials"},
+		"code_challenge_methods_supported":      pkceMethods(), // OAuth 2.1: S256, plain only for clients allowed it
 		"scopes_supported":                      []string{"read", "write", "mcp", "claudeai"},
 		"response_modes_supported":              []string{"query"},
 		"revocation_endpoint":                   baseURL + "/oauth/revoke", // OAuth 2.1: Token revocation
+		"jwks_uri":                              baseURL + "/.well-known/jwks.json",
 	}
 
 	c.JSON(http.StatusOK, discovery)
 }
 
+// pkceMethods lists the code_challenge_methods some client may use
+func pkceMethods() []string {
+	if len(plainPKCEClients) > 0 {
+		return []string{"S256", "plain"}
+	}
+	return []string{"S256"}
+}
+
+// OAuthProtectedResource handles the protected resource metadata endpoint
+// GET /.well-known/oauth-protected-resource
+// Returns the resource tokens must be requested for and who issues them, per
+// RFC 9728, so MCP clients can send the right resource indicator
+func OAuthProtectedResource(c *gin.Context) {
+	baseURL := getBaseURL(c)
+	resource := signingKeys.Audience()
+	if resource == "" {
+		resource = baseURL
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"resource":                 resource,
+		"authorization_servers":    []string{baseURL},
+		"scopes_supported":         []string{"read", "write", "mcp", "claudeai"},
+		"bearer_methods_supported": []string{"header"},
+		"jwks_uri":                 baseURL + "/.well-known/jwks.json",
+	})
+}
+
+// JWKS publishes the public keys that verify access tokens
+// GET /.well-known/jwks.json
+// The set is empty while tokens are signed with the shared HS256 secret.
+func JWKS(c *gin.Context) {
+	// Short enough that a rotated-in key is picked up before it starts signing
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, signingKeys.JWKS())
+}
+
 // getBaseURL extracts the base URL from the request
 func getBaseURL(c *gin.Context) string {
 	scheme := "https"
@@ -52,15 +87,12 @@ func getBaseURL(c *gin.Context) string {
 	}
 
 	result := scheme + "://" + host
-	// #region agent log
-	debugLog("oauth_discovery.go:45", "getBaseURL result", map[string]interface{}{
+	oauthTrace(c, "getBaseURL result", map[string]interface{}{
 		"result":         result,
 		"host":           host,
 		"scheme":         scheme,
 		"forwardedProto": forwardedProto,
 		"hasTLS":         c.Request.TLS != nil,
-		"hypothesisId":   "H3",
 	})
-	// #endregion
 	return result
 }
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPublicClientPKCE(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetJWTSecret("pkce-test-secret")
+	t.Cleanup(func() { SetJWTSecret(""); SetPlainPKCEClients(nil) })
+	public := &OAuthClient{ClientID: "pkce-public", RedirectURIs: []string{"http://localhost:9000/cb"}, TokenEndpointAuthMethod: AuthMethodNone}
+	if err := StoreOAuthClient(public); err != nil {
+		t.Fatal(err)
+	}
+
+	rememberGrant(t, "pkce-public", defaultScope)
+	rememberGrant(t, "mcp_client", defaultScope)
+
+	router := gin.New()
+	router.GET("/authorize", OAuthAuthorize)
+	router.POST("/oauth/token", OAuthToken)
+	authorize := func(clientID, redirectURI, challenge, method string) url.Values {
+		q := url.Values{"client_id": {clientID}, "redirect_uri": {redirectURI}, "response_type": {"code"}, "state": {"s1"}}
+		if challenge != "" {
+			q.Set("code_challenge", challenge)
+			q.Set("code_challenge_method", method)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authorize?"+q.Encode(), nil))
+		location, err := url.Parse(w.Header().Get("Location"))
+		if w.Code != http.StatusFound || err != nil {
+			t.Fatalf("authorize: %d %s", w.Code, w.Body)
+		}
+		return location.Query()
+	}
+	verifier := strings.Repeat("v", 43)
+	challenge := "7w_YNF9DSfIdPf_pRjSq646_kPr-2-o9NAl16JGghdM" // S256 of the verifier
+
+	for _, tt := range []struct {
+		name, clientID, redirectURI, challenge, method, wantError string
+	}{
+		{"public without PKCE", "pkce-public", "http://localhost:9000/cb", "", "", "invalid_request"},
+		{"public with plain", "pkce-public", "http://localhost:9000/cb", verifier, "plain", "invalid_request"},
+		{"public with S256", "pkce-public", "http://localhost:9000/cb", challenge, "S256", ""},
+		{"confidential without PKCE", "mcp_client", "http://localhost", "", "", ""},
+	} {
+		got := authorize(tt.clientID, tt.redirectURI, tt.challenge, tt.method)
+		if got.Get("error") != tt.wantError || (tt.wantError == "" && got.Get("code") == "") {
+			t.Errorf("%s: redirected with %v, want error %q", tt.name, got, tt.wantError)
+		}
+	}
+
+	SetPlainPKCEClients([]string{"pkce-public"})
+	if got := authorize("pkce-public", "http://localhost:9000/cb", verifier, "plain"); got.Get("code") == "" {
+		t.Errorf("plain for an allowed client: redirected with %v", got)
+	}
+
+	// The code only works for the client it was issued to, with its verifier
+	exchange := func(clientID, verifier string) int {
+		code := authorize("pkce-public", "http://localhost:9000/cb", challenge, "S256").Get("code")
+		body, _ := json.Marshal(OAuthTokenRequest{GrantType: "authorization_code", Code: code, ClientID: clientID,
+			RedirectURI: "http://localhost:9000/cb", CodeVerifier: verifier})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/oauth/token", bytes.NewReader(body)))
+		return w.Code
+	}
+	if status := exchange("mcp_client", verifier); status != http.StatusBadRequest {
+		t.Errorf("code redeemed by another client: %d, want 400", status)
+	}
+	if status := exchange("pkce-public", ""); status != http.StatusBadRequest {
+		t.Errorf("code redeemed without its verifier: %d, want 400", status)
+	}
+	if status := exchange("pkce-public", verifier); status != http.StatusOK {
+		t.Errorf("code redeemed with its verifier: %d, want 200", status)
+	}
+}
+
+func TestConfidentialClientCodeNeedsSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetJWTSecret("pkce-test-secret")
+	t.Cleanup(func() { SetJWTSecret("") })
+	rememberGrant(t, "mcp_client", defaultScope)
+
+	router := gin.New()
+	router.GET("/authorize", OAuthAuthorize)
+	router.POST("/oauth/token", OAuthToken)
+
+	// A confidential client may skip PKCE, so its code alone must not be enough
+	exchange := func(clientID, secret string) (int, string) {
+		q := url.Values{"client_id": {"mcp_client"}, "redirect_uri": {"http://localhost"}, "response_type": {"code"}, "state": {"s1"}}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authorize?"+q.Encode(), nil))
+		location, _ := url.Parse(w.Header().Get("Location"))
+		code := location.Query().Get("code")
+		if code == "" {
+			t.Fatalf("authorize: %d %s", w.Code, w.Header().Get("Location"))
+		}
+
+		body, _ := json.Marshal(OAuthTokenRequest{GrantType: "authorization_code", Code: code, ClientID: clientID,
+			ClientSecret: secret, RedirectURI: "http://localhost"})
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/oauth/token", bytes.NewReader(body)))
+		var resp struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Error
+	}
+
+	for _, tt := range []struct{ name, clientID, secret string }{
+		{"no client_id", "", ""},
+		{"empty secret", "mcp_client", ""},
+		{"wrong secret", "mcp_client", "guess"},
+	} {
+		if status, oauthErr := exchange(tt.clientID, tt.secret); status != http.StatusBadRequest ||
+			(oauthErr != "invalid_client" && oauthErr != "invalid_grant") {
+			t.Errorf("%s: %d %q, want invalid_client or invalid_grant", tt.name, status, oauthErr)
+		}
+	}
+	if status, oauthErr := exchange("mcp_client", "mcp_client_secret_dev"); status != http.StatusOK {
+		t.Errorf("with its secret: %d %q, want 200", status, oauthErr)
+	}
+}
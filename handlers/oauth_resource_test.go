@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/signing"
+)
+
+func TestOAuthResourceIndicator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const resource = "https://tasks.example.com/mcp"
+	SetSigningKeys(signing.NewKeySet("resource-test-secret").WithAudience(resource))
+	t.Cleanup(func() { SetJWTSecret("") })
+
+	rememberGrant(t, "claude-desktop", defaultScope)
+
+	router := gin.New()
+	router.GET("/authorize", OAuthAuthorize)
+	router.POST("/oauth/token", OAuthToken)
+
+	authorize := func(requested string) *url.URL {
+		q := url.Values{
+			"client_id":     {"claude-desktop"},
+			"redirect_uri":  {"http://localhost"},
+			"response_type": {"code"},
+			"state":         {"s1"},
+			"resource":      {requested},
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authorize?"+q.Encode(), nil))
+		location, err := url.Parse(w.Header().Get("Location"))
+		if w.Code != http.StatusFound || err != nil {
+			t.Fatalf("authorize: %d %s", w.Code, w.Body)
+		}
+		return location
+	}
+
+	if got := authorize("https://other.example.com").Query().Get("error"); got != "invalid_target" {
+		t.Errorf("foreign resource: error %q, want invalid_target", got)
+	}
+
+	exchange := func(requested string) *httptest.ResponseRecorder {
+		code := authorize(resource + "/").Query().Get("code")
+		body, _ := json.Marshal(OAuthTokenRequest{GrantType: "authorization_code", Code: code, ClientID: "claude-desktop",
+			ClientSecret: "claude-desktop-secret-dev", RedirectURI: "http://localhost", Resource: requested})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/oauth/token", bytes.NewReader(body)))
+		return w
+	}
+	if w := exchange("https://other.example.com"); w.Code != http.StatusBadRequest {
+		t.Errorf("token for a foreign resource: %d, want 400", w.Code)
+	}
+
+	w := exchange(resource)
+	var resp OAuthTokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.AccessToken == "" {
+		t.Fatalf("token: %d %s", w.Code, w.Body)
+	}
+	claims, err := validateJWT(resp.AccessToken)
+	if err != nil || claims["aud"] != resource {
+		t.Errorf("token claims %v, err %v; want aud %s", claims, err, resource)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// oauthTracing holds the DEBUG_OAUTH settings; tracing is off until
+// SetOAuthTracing turns it on
+var oauthTracing struct {
+	enabled    bool
+	sampleRate float64
+}
+
+// SetOAuthTracing turns the OAuth flow traces on or off. sampleRate is the
+// share of requests traced, from 0 to 1.
+func SetOAuthTracing(enabled bool, sampleRate float64) {
+	oauthTracing.enabled = enabled
+	oauthTracing.sampleRate = sampleRate
+}
+
+// oauthTrace logs a step of the OAuth flow at DEBUG level through the
+// request's logger, when DEBUG_OAUTH is on and the request is sampled
+func oauthTrace(c *gin.Context, message string, data map[string]interface{}) {
+	if !oauthTracing.enabled || !oauthTraceSampled(c.GetString("request_id")) {
+		return
+	}
+	fields := map[string]interface{}{"trace": "oauth"}
+	for k, v := range data {
+		fields[k] = v
+	}
+	utils.LoggerFromContext(c.Request.Context()).Debug(message, fields)
+}
+
+// oauthTraceSampled decides whether a request is traced. The choice is made
+// from the request ID, so a sampled request has every one of its steps traced.
+func oauthTraceSampled(requestID string) bool {
+	rate := oauthTracing.sampleRate
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	if requestID == "" {
+		return rand.Float64() < rate
+	}
+	h := fnv.New32a()
+	h.Write([]byte(requestID))
+	return float64(h.Sum32()%10000) < rate*10000
+}
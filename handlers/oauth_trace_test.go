@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOAuthTraceSampled(t *testing.T) {
+	defer SetOAuthTracing(false, 1)
+
+	SetOAuthTracing(true, 0.25)
+	sampled := 0
+	for i := 0; i < 4000; i++ {
+		id := fmt.Sprintf("req-%d", i)
+		first := oauthTraceSampled(id)
+		if oauthTraceSampled(id) != first {
+			t.Fatalf("request %s sampled inconsistently", id)
+		}
+		if first {
+			sampled++
+		}
+	}
+	if sampled < 800 || sampled > 1200 {
+		t.Errorf("sampled %d of 4000 requests at rate 0.25", sampled)
+	}
+
+	SetOAuthTracing(true, 0)
+	if oauthTraceSampled("req-1") {
+		t.Error("rate 0 sampled a request")
+	}
+	SetOAuthTracing(true, 1)
+	if !oauthTraceSampled("req-1") || !oauthTraceSampled("") {
+		t.Error("rate 1 skipped a request")
+	}
+}
@@ -1,14 +1,27 @@
+//go:build !lite
+
 package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
 )
 
+// ollamaTimeout bounds a generation that is not streamed; streamed ones run
+// until the model finishes or the client goes away
+const ollamaTimeout = 120 * time.Second
+
 // OllamaHandler handles Ollama LLM integration
 type OllamaHandler struct {
 	ollamaURL  string
@@ -16,26 +29,21 @@ type OllamaHandler struct {
 	httpClient *http.Client
 }
 
-// NewOllamaHandler creates a new Ollama handler
+// NewOllamaHandler creates a new Ollama handler for the server at ollamaURL
+// (OLLAMA_URL), generating with modelName (OLLAMA_MODEL) unless a request
+// names another model
 func NewOllamaHandler(ollamaURL, modelName string) *OllamaHandler {
-	if ollamaURL == "" {
-		ollamaURL = "http://100.74.59.83:11434" // Mac Studio Tailscale IP
-	}
-	if modelName == "" {
-		modelName = "qwen3-coder:480b-cloud"
-	}
-
 	return &OllamaHandler{
-		ollamaURL:  ollamaURL,
+		ollamaURL:  strings.TrimRight(ollamaURL, "/"),
 		modelName:  modelName,
-		httpClient: &http.Client{Timeout: 120 * time.Second}, // Longer timeout for large models
+		httpClient: &http.Client{Transport: utils.NewLoggingTransport("ollama", nil)},
 	}
 }
 
 // GenerateRequest represents an Ollama generate request
 type GenerateRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
+	Model  string `json:"model" binding:"omitempty,max=200"`
+	Prompt string `json:"prompt" binding:"required"`
 	Stream bool   `json:"stream"`
 	System string `json:"system,omitempty"`
 }
@@ -55,56 +63,174 @@ type GenerateResponse struct {
 	EvalDuration       int64     `json:"eval_duration,omitempty"`
 }
 
-// Generate sends a prompt to Ollama and returns the response
-func (h *OllamaHandler) Generate(prompt string, systemPrompt string) (string, error) {
-	req := GenerateRequest{
-		Model:  h.modelName,
-		Prompt: prompt,
-		Stream: false,
+// generate sends req to Ollama, on the default model unless it names one,
+// and calls onChunk with every object the server answers: a single one
+// unless req.Stream is set, otherwise one per chunk of text, the last with
+// Done set. An error from onChunk stops the generation.
+func (h *OllamaHandler) generate(ctx context.Context, req GenerateRequest, onChunk func(GenerateResponse) error) error {
+	if h.ollamaURL == "" {
+		return utils.NewAppError(utils.ErrCodeExternal, "Ollama is not configured", http.StatusServiceUnavailable)
+	}
+	if req.Model == "" {
+		req.Model = h.modelName
 	}
-	if systemPrompt != "" {
-		req.System = systemPrompt
+	if !req.Stream {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ollamaTimeout)
+		defer cancel()
 	}
 
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.ollamaURL+"/api/generate", bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := h.httpClient.Post(h.ollamaURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := h.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return utils.ErrTimeout("Ollama generation")
+		}
+		return utils.ErrExternal("Ollama", "server unreachable").WithError(err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return utils.ErrNotFound(fmt.Sprintf("model %q on the Ollama server", req.Model))
+	}
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return utils.ErrExternal("Ollama", fmt.Sprintf("server returned status %d: %s", resp.StatusCode, body))
 	}
 
-	var genResp GenerateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&genResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk GenerateResponse
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			return nil
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return utils.ErrExternal("Ollama", "failed to decode response").WithError(err)
+		}
+		if err := onChunk(chunk); err != nil {
+			return err
+		}
+		if chunk.Done {
+			return nil
+		}
+	}
+}
+
+// Generate sends a prompt to Ollama and returns the response. With stream
+// set the text is sent as server-sent events as it is generated: a message
+// event per chunk carrying its response, then a done event with the model's
+// counts and durations, or an error event if generation fails part way.
+// POST /api/ollama/generate
+func (h *OllamaHandler) Generate(c *gin.Context) {
+	var req GenerateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	var v validation.Validator
+	v.Check(strings.TrimSpace(req.Prompt) != "", "prompt", validation.CodeRequired, "prompt must not be blank")
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
 	}
 
-	if !genResp.Done {
-		return "", fmt.Errorf("generation did not complete")
+	if !req.Stream {
+		var result GenerateResponse
+		err := h.generate(c.Request.Context(), req, func(chunk GenerateResponse) error {
+			result = chunk
+			return nil
+		})
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
 	}
 
-	return genResp.Response, nil
+	// Generation can take longer than the server's WriteTimeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	started := false
+	err := h.generate(c.Request.Context(), req, func(chunk GenerateResponse) error {
+		if !started {
+			started = true
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("X-Accel-Buffering", "no")
+			c.Status(http.StatusOK)
+		}
+		if chunk.Done {
+			c.SSEvent("done", chunk)
+		} else {
+			c.SSEvent("message", gin.H{"response": chunk.Response})
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	switch {
+	case err == nil || c.Request.Context().Err() != nil:
+	case !started:
+		// Nothing was sent yet, so the error can still be a JSON response
+		c.Error(err)
+	default:
+		c.SSEvent("error", gin.H{"error": toolErrorMessage(c.Request.Context(), err)})
+		c.Writer.Flush()
+	}
 }
 
-// ReviewCodebaseRequest represents a codebase review request
-type ReviewCodebaseRequest struct {
-	BasePath     string   `json:"base_path"`
-	FilePatterns []string `json:"file_patterns,omitempty"` // e.g., ["*.go", "*.ts", "*.tsx"]
-	ExcludeDirs  []string `json:"exclude_dirs,omitempty"`  // e.g., ["node_modules", ".git"]
-	FocusAreas   []string `json:"focus_areas,omitempty"`   // e.g., ["security", "performance", "architecture"]
+// RegisterTools adds local_generate to the MCP tools
+func (h *OllamaHandler) RegisterTools(tools *ToolRegistry) {
+	tools.Register(Tool{
+		Name:        "local_generate",
+		Description: "Generate text with a model on the server's Ollama instance instead of Claude, e.g. for private notes or a second opinion. Send _meta.progressToken with Accept: text/event-stream to receive the text in progress notifications as it is generated.",
+		InputSchema: &validation.Schema{
+			Type: "object",
+			Properties: map[string]*validation.Schema{
+				"prompt": {Type: "string", Description: "Prompt to generate from", MinLength: 1},
+				"model":  {Type: "string", Description: "Ollama model, e.g. llama3.2 (default: OLLAMA_MODEL)", MaxLength: 200},
+				"system": {Type: "string", Description: "System prompt"},
+			},
+			Required: []string{"prompt"},
+		},
+		Handler: h.localGenerate,
+	})
 }
 
-// ReviewCodebase analyzes the codebase using Ollama
-func (h *OllamaHandler) ReviewCodebase(req ReviewCodebaseRequest) (string, error) {
-	// This will be implemented by the script that walks the filesystem
-	// For now, return a placeholder
-	return "", fmt.Errorf("use the review_codebase script for full codebase analysis")
+// localGenerate runs local_generate, streaming the text to progress when the
+// caller asked for progress
+func (h *OllamaHandler) localGenerate(c *gin.Context, params map[string]interface{}, progress Progress) (interface{}, string, error) {
+	req := GenerateRequest{Stream: progress != nil}
+	req.Prompt, _ = params["prompt"].(string)
+	req.Model, _ = params["model"].(string)
+	req.System, _ = params["system"].(string)
+
+	var text strings.Builder
+	var final GenerateResponse
+	err := h.generate(c.Request.Context(), req, func(chunk GenerateResponse) error {
+		text.WriteString(chunk.Response)
+		if chunk.Response != "" {
+			progress.report(float64(text.Len()), 0, chunk.Response)
+		}
+		final = chunk
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return gin.H{
+		"response":    text.String(),
+		"model":       final.Model,
+		"eval_count":  final.EvalCount,
+		"duration_ms": final.TotalDuration / int64(time.Millisecond),
+	}, "", nil
 }
@@ -0,0 +1,66 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/mockllm"
+)
+
+func TestOllamaGenerate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	llm := httptest.NewServer(mockllm.NewHandler())
+	defer llm.Close()
+	h := NewOllamaHandler(llm.URL, "qwen3-coder:480b-cloud")
+
+	call := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/api/ollama/generate", strings.NewReader(body))
+		h.Generate(ctx)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", body, recorder.Code, recorder.Body)
+		}
+		return recorder
+	}
+
+	// The request's model wins over the default
+	var result GenerateResponse
+	if err := json.Unmarshal(call(`{"prompt":"say hello","model":"llama3.2"}`).Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Done || result.Response == "" || result.Model != "llama3.2" {
+		t.Fatalf("unexpected response %+v", result)
+	}
+
+	stream := call(`{"prompt":"say hello","stream":true}`)
+	if ct := stream.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("stream Content-Type %q", ct)
+	}
+	body := stream.Body.String()
+	if !strings.Contains(body, "event:message") || !strings.Contains(body, "event:done") || !strings.Contains(body, "qwen3-coder:480b-cloud") {
+		t.Fatalf("unexpected stream:\n%s", body)
+	}
+
+	// local_generate streams the text to progress as it arrives
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/mcp/call_tool", nil)
+	var streamed strings.Builder
+	out, _, err := h.localGenerate(ctx, map[string]interface{}{"prompt": "say hello"}, func(progress, total float64, message string) {
+		streamed.WriteString(message)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := out.(gin.H)["response"].(string)
+	if text == "" || streamed.String() != text {
+		t.Fatalf("streamed %q, answered %q", streamed.String(), text)
+	}
+}
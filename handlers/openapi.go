@@ -0,0 +1,54 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/openapi"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// swaggerUIVersion is the swagger-ui-dist release /docs loads from unpkg
+const swaggerUIVersion = "5.17.14"
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Productivity MCP Server API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// OpenAPISpec serves the OpenAPI description of the API
+// GET /openapi.json
+func OpenAPISpec(c *gin.Context) {
+	spec, err := openapi.JSON()
+	if err != nil {
+		c.Error(utils.ErrInternal("failed to render the OpenAPI spec").WithError(err))
+		return
+	}
+	c.Data(http.StatusOK, "application/json", spec)
+}
+
+// OpenAPISpecYAML serves the OpenAPI description as it is written
+// GET /openapi.yaml
+func OpenAPISpecYAML(c *gin.Context) {
+	c.Data(http.StatusOK, "application/yaml", openapi.YAML())
+}
+
+// SwaggerUI serves a Swagger UI page for browsing and trying the API
+// GET /docs
+func SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
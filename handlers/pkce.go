@@ -4,8 +4,39 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"sync"
+	"time"
 )
 
+// plainPKCEClients may use the plain code_challenge_method
+var plainPKCEClients = map[string]bool{}
+
+// SetPlainPKCEClients allows the plain code_challenge_method for clientIDs;
+// every other client must use S256
+func SetPlainPKCEClients(clientIDs []string) {
+	allowed := make(map[string]bool, len(clientIDs))
+	for _, id := range clientIDs {
+		allowed[id] = true
+	}
+	plainPKCEClients = allowed
+}
+
+// pkceProblem returns why an authorization request's PKCE parameters are
+// unacceptable for client, or "" when they are fine: public clients must
+// send a code_challenge, and plain is only for clients allowed it
+func pkceProblem(client *OAuthClient, codeChallenge, codeChallengeMethod string) string {
+	if codeChallenge == "" {
+		if client.Public() {
+			return "code_challenge is required for public clients (PKCE with S256)"
+		}
+		return ""
+	}
+	if codeChallengeMethod == "plain" && !plainPKCEClients[client.ClientID] {
+		return "code_challenge_method plain is not allowed for this client; use S256"
+	}
+	return ""
+}
+
 // AuthCodeData stores authorization code with PKCE data
 type AuthCodeData struct {
 	Code                string
@@ -14,27 +45,23 @@ type AuthCodeData struct {
 	CodeChallenge       string
 	CodeChallengeMethod string
 	Scope               string
+	Resource            string // audience of the tokens the code is exchanged for
+	UserID              string // who approved the request
 	State               string
 	ExpiresAt           int64
 	Used                bool
 }
 
 // In-memory storage for auth codes (TODO: Move to database)
-var authCodeStore = make(map[string]*AuthCodeData)
+var (
+	authCodeMu    sync.Mutex
+	authCodeStore = make(map[string]*AuthCodeData)
+)
 
 // ValidatePKCE validates the code_verifier against the stored code_challenge
 // Per OAuth 2.1 RFC 7636, S256 method requires:
 // - code_challenge = base64url(sha256(ASCII(code_verifier)))
 func ValidatePKCE(codeChallenge, codeChallengeMethod, codeVerifier string) error {
-	// #region agent log
-	debugLog("pkce.go:28", "ValidatePKCE entry", map[string]interface{}{
-		"hasCodeChallenge": codeChallenge != "",
-		"method":           codeChallengeMethod,
-		"hasCodeVerifier":  codeVerifier != "",
-		"hypothesisId":     "H5",
-	})
-	// #endregion
-
 	if codeChallengeMethod == "" {
 		// PKCE is optional per spec, but recommended
 		return nil
@@ -56,57 +83,32 @@ func ValidatePKCE(codeChallenge, codeChallengeMethod, codeVerifier string) error
 		hash := sha256.Sum256([]byte(codeVerifier))
 		// Base64URL encode
 		computedChallenge = base64.RawURLEncoding.EncodeToString(hash[:])
-		// #region agent log
-		debugLog("pkce.go:50", "PKCE S256 computation", map[string]interface{}{
-			"codeVerifier":      codeVerifier,
-			"computedChallenge": computedChallenge,
-			"storedChallenge":   codeChallenge,
-			"match":             computedChallenge == codeChallenge,
-			"hypothesisId":      "H5",
-		})
-		// #endregion
 	case "plain":
 		// Plain method: code_challenge == code_verifier
 		computedChallenge = codeVerifier
-		// #region agent log
-		debugLog("pkce.go:58", "PKCE plain comparison", map[string]interface{}{
-			"computedChallenge": computedChallenge,
-			"storedChallenge":   codeChallenge,
-			"match":             computedChallenge == codeChallenge,
-			"hypothesisId":      "H5",
-		})
-		// #endregion
 	default:
 		return fmt.Errorf("unsupported code_challenge_method: %s", codeChallengeMethod)
 	}
 
 	// Compare computed challenge with provided challenge
 	if computedChallenge != codeChallenge {
-		// #region agent log
-		debugLog("pkce.go:70", "PKCE validation failed", map[string]interface{}{
-			"computedChallenge": computedChallenge,
-			"storedChallenge":   codeChallenge,
-			"hypothesisId":      "H5",
-		})
-		// #endregion
 		return fmt.Errorf("code_verifier does not match code_challenge")
 	}
 
-	// #region agent log
-	debugLog("pkce.go:78", "PKCE validation success", map[string]interface{}{
-		"hypothesisId": "H5",
-	})
-	// #endregion
 	return nil
 }
 
 // StoreAuthCode stores an authorization code with PKCE data
 func StoreAuthCode(code string, data *AuthCodeData) {
+	authCodeMu.Lock()
+	defer authCodeMu.Unlock()
 	authCodeStore[code] = data
 }
 
 // GetAuthCode retrieves an authorization code and marks it as used
 func GetAuthCode(code string) (*AuthCodeData, error) {
+	authCodeMu.Lock()
+	defer authCodeMu.Unlock()
 	data, exists := authCodeStore[code]
 	if !exists {
 		return nil, fmt.Errorf("authorization code not found")
@@ -122,8 +124,20 @@ func GetAuthCode(code string) (*AuthCodeData, error) {
 	return data, nil
 }
 
-// CleanExpiredAuthCodes removes expired auth codes (should be called periodically)
-func CleanExpiredAuthCodes() {
-	// TODO: Implement cleanup logic
-	// For now, in-memory store will grow, but in production this should be in database with TTL
+// CleanExpiredAuthCodes removes auth codes that have expired or been
+// exchanged and returns how many. A used code that is replayed afterwards
+// is still refused, as not found.
+func CleanExpiredAuthCodes() int {
+	authCodeMu.Lock()
+	defer authCodeMu.Unlock()
+
+	now := time.Now().Unix()
+	removed := 0
+	for code, data := range authCodeStore {
+		if data.Used || data.ExpiresAt < now {
+			delete(authCodeStore, code)
+			removed++
+		}
+	}
+	return removed
 }
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/plans"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// PlanHandler reports each user's plan and usage, and enforces the plan's
+// limits on task count, daily AI calls and attachment size
+type PlanHandler struct {
+	supabaseClient *db.SupabaseClient
+	cfg            config.Plans
+	aiCalls        *plans.DailyCounter
+}
+
+// NewPlanHandler creates a new plan handler
+func NewPlanHandler(supabaseURL, supabaseKey string, cfg config.Plans) *PlanHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &PlanHandler{
+		supabaseClient: client,
+		cfg:            cfg,
+		aiCalls:        plans.NewDailyCounter(),
+	}
+}
+
+// userPlans enforces plan limits; nil, as in the lite build, enforces none
+var userPlans *PlanHandler
+
+// SetPlans installs the plan limits the task, AI and import handlers enforce
+func SetPlans(p *PlanHandler) {
+	userPlans = p
+}
+
+// Limits returns the limits of plan
+func (p *PlanHandler) Limits(plan string) plans.Limits {
+	if plan == plans.Pro {
+		return plans.Limits{Tasks: p.cfg.ProTasks, AICallsPerDay: p.cfg.ProAICallsPerDay, AttachmentBytes: p.cfg.ProAttachmentBytes}
+	}
+	return plans.Limits{Tasks: p.cfg.FreeTasks, AICallsPerDay: p.cfg.FreeAICallsPerDay, AttachmentBytes: p.cfg.FreeAttachmentBytes}
+}
+
+// planOf returns the plan userID is on and its limits. Anonymous callers are
+// on the default plan.
+func (p *PlanHandler) planOf(c *gin.Context, userID string) (string, plans.Limits, error) {
+	plan := ""
+	if userID != "" {
+		var err error
+		if plan, err = p.supabaseClient.WithContext(c.Request.Context()).GetUserPlan(userID); err != nil {
+			return "", plans.Limits{}, err
+		}
+	}
+	if !plans.Valid(plan) {
+		plan = p.cfg.Default
+	}
+	return plan, p.Limits(plan), nil
+}
+
+// checkTaskLimit refuses adding tasks that would take userID over their
+// plan's task count
+func checkTaskLimit(c *gin.Context, userID string, adding int) error {
+	if userPlans == nil || !userPlans.cfg.Enabled || userID == "" {
+		return nil
+	}
+	plan, limits, err := userPlans.planOf(c, userID)
+	if err != nil || limits.Tasks == 0 {
+		return err
+	}
+	count, err := userPlans.supabaseClient.WithContext(c.Request.Context()).CountTasks(userID)
+	if err != nil {
+		return err
+	}
+	if count+adding > limits.Tasks {
+		return utils.ErrPlanLimit(fmt.Sprintf("the %s plan allows %d tasks and you have %d; complete and delete some, or upgrade", plan, limits.Tasks, count)).
+			WithFields(map[string]interface{}{"plan": plan, "limit": "tasks", "max": limits.Tasks, "used": count})
+	}
+	return nil
+}
+
+// checkAttachmentLimit refuses a file of size bytes over userID's plan's
+// attachment size
+func checkAttachmentLimit(c *gin.Context, userID string, size int) error {
+	if userPlans == nil || !userPlans.cfg.Enabled {
+		return nil
+	}
+	plan, limits, err := userPlans.planOf(c, userID)
+	if err != nil || limits.AttachmentBytes == 0 || size <= limits.AttachmentBytes {
+		return err
+	}
+	return utils.ErrPlanLimit(fmt.Sprintf("the %s plan allows files of up to %s", plan, utils.FormatBytes(int64(limits.AttachmentBytes)))).
+		WithFields(map[string]interface{}{"plan": plan, "limit": "attachment_bytes", "max": limits.AttachmentBytes, "used": size})
+}
+
+// takeAICall counts an AI request by userID, or by the client's address
+// when anonymous, refusing it once their plan's calls for the day are used
+func takeAICall(c *gin.Context, userID string) error {
+	if userPlans == nil || !userPlans.cfg.Enabled {
+		return nil
+	}
+	plan, limits, err := userPlans.planOf(c, userID)
+	if err != nil {
+		return err
+	}
+	caller := "user:" + userID
+	if userID == "" {
+		caller = "ip:" + c.ClientIP()
+	}
+	now := time.Now()
+	if used, ok := userPlans.aiCalls.Take(caller, now, limits.AICallsPerDay); !ok {
+		resetAt := plans.NextReset(now)
+		retryAfter := int(time.Until(resetAt).Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		return utils.NewAppError(utils.ErrCodeRateLimit, fmt.Sprintf("the %s plan allows %d AI requests a day", plan, limits.AICallsPerDay), http.StatusTooManyRequests).
+			WithFields(map[string]interface{}{
+				"plan":        plan,
+				"limit":       "ai_calls_per_day",
+				"max":         limits.AICallsPerDay,
+				"used":        used,
+				"reset_at":    resetAt.Format(time.RFC3339),
+				"retry_after": retryAfter,
+			})
+	}
+	return nil
+}
+
+// AICalls counts the AI requests of the routes it guards against the
+// caller's plan
+func (p *PlanHandler) AICalls() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := takeAICall(c, getUserID(c)); err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetPlan reports the user's plan, its limits and how much of each is used
+// GET /api/plan
+func (p *PlanHandler) GetPlan(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+	plan, limits, err := p.planOf(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	tasks, err := p.supabaseClient.WithContext(c.Request.Context()).CountTasks(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	now := time.Now()
+	c.JSON(http.StatusOK, gin.H{
+		"plan":     plan,
+		"enforced": p.cfg.Enabled,
+		"limits":   limits,
+		"usage": gin.H{
+			"tasks":             tasks,
+			"ai_calls_today":    p.aiCalls.Used("user:"+userID, now),
+			"ai_calls_reset_at": plans.NextReset(now).Format(time.RFC3339),
+		},
+	})
+}
+
+// SetUserPlanRequest assigns a user a plan
+type SetUserPlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+// SetUserPlan moves a user to another plan
+// PUT /admin/users/:user_id/plan
+func (p *PlanHandler) SetUserPlan(c *gin.Context) {
+	var req SetUserPlanRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	var v validation.Validator
+	v.Check(plans.Valid(req.Plan), "plan", validation.CodeInvalidValue, "plan must be free or pro")
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	row, err := p.supabaseClient.WithContext(c.Request.Context()).SetUserPlan(c.Param("user_id"), req.Plan)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, row)
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/dates"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// defaultPreferences apply to users who have not saved their own
+var defaultPreferences = models.Preferences{
+	Timezone:  "UTC",
+	Locale:    "en-US",
+	WeekStart: "monday",
+	WorkStart: "09:00",
+	WorkEnd:   "17:00",
+}
+
+var (
+	localePattern    = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+	clockTimePattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// preferencesStore is where userPreferences reads preferences from; without
+// one every user gets the defaults
+var preferencesStore *db.SupabaseClient
+
+// SetPreferencesStore installs the store that date parsing reads user preferences from
+func SetPreferencesStore(supabaseURL, supabaseKey string) {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	preferencesStore = client
+}
+
+// userPreferences returns userID's preferences from the installed store, or
+// the defaults when there is no store or no user
+func userPreferences(ctx context.Context, userID string) (models.Preferences, error) {
+	if preferencesStore == nil || userID == "" {
+		return defaultPreferences, nil
+	}
+	return loadPreferences(preferencesStore.WithContext(ctx), userID)
+}
+
+// loadPreferences returns userID's saved preferences, filling in defaults for
+// anything they have not set
+func loadPreferences(client *db.SupabaseClient, userID string) (models.Preferences, error) {
+	prefs := defaultPreferences
+	row, err := client.GetPreferences(userID)
+	if err != nil || row == nil {
+		return prefs, err
+	}
+	for field, value := range map[string]*string{
+		"timezone":   &prefs.Timezone,
+		"locale":     &prefs.Locale,
+		"week_start": &prefs.WeekStart,
+		"work_start": &prefs.WorkStart,
+		"work_end":   &prefs.WorkEnd,
+	} {
+		if s := rowString(row, field); s != "" {
+			*value = s
+		}
+	}
+	return prefs, nil
+}
+
+// userNow returns the current time in the user's time zone along with their
+// preferences, so "today" and "overdue" follow the user's calendar rather
+// than the server's
+func userNow(client *db.SupabaseClient, userID string) (time.Time, models.Preferences, error) {
+	prefs, err := loadPreferences(client, userID)
+	if err != nil {
+		return time.Time{}, prefs, err
+	}
+	return time.Now().In(preferencesLocation(prefs)), prefs, nil
+}
+
+// preferencesLocation is the user's time zone, UTC if it no longer loads
+func preferencesLocation(prefs models.Preferences) *time.Location {
+	loc, err := loadTimezone(prefs.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// dateOptions adapts date parsing to the user's week and working day: a date
+// named without a time is due when their working day ends
+func dateOptions(prefs models.Preferences) dates.Options {
+	opts := dates.DefaultOptions
+	if day, ok := weekdayNames[prefs.WeekStart]; ok {
+		opts.WeekStart = day
+	}
+	if end, err := time.Parse("15:04", prefs.WorkEnd); err == nil {
+		opts.DefaultHour, opts.DefaultMinute = end.Hour(), end.Minute()
+	}
+	return opts
+}
+
+// validatePreferences checks the fields an update sets against the
+// preferences they will be merged into
+func validatePreferences(v *validation.Validator, prefs models.Preferences, req models.UpdatePreferencesRequest) {
+	if req.Timezone != nil {
+		v.Required("timezone", *req.Timezone)
+		validateTimezone(v, *req.Timezone)
+	}
+	if req.Locale != nil {
+		v.Check(localePattern.MatchString(*req.Locale), "locale", validation.CodeInvalidFormat, "locale must be a BCP 47 language tag, e.g. en-GB")
+	}
+	if req.WeekStart != nil {
+		_, ok := weekdayNames[strings.ToLower(*req.WeekStart)]
+		v.Check(ok, "week_start", validation.CodeInvalidValue, "week_start must be a day of the week, e.g. monday")
+	}
+	if req.WorkStart != nil {
+		v.Check(clockTimePattern.MatchString(*req.WorkStart), "work_start", validation.CodeInvalidFormat, "work_start must be a 24-hour HH:MM time")
+	}
+	if req.WorkEnd != nil {
+		v.Check(clockTimePattern.MatchString(*req.WorkEnd), "work_end", validation.CodeInvalidFormat, "work_end must be a 24-hour HH:MM time")
+	}
+	// HH:MM strings order the same way as the times they name
+	if clockTimePattern.MatchString(prefs.WorkStart) && clockTimePattern.MatchString(prefs.WorkEnd) {
+		v.Check(prefs.WorkStart < prefs.WorkEnd, "work_end", validation.CodeOutOfRange, "work_end must be after work_start")
+	}
+}
+
+// PreferencesHandler stores each user's time zone, locale, first day of the
+// week and working hours
+type PreferencesHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewPreferencesHandler creates a new preferences handler
+func NewPreferencesHandler(supabaseURL, supabaseKey string) *PreferencesHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &PreferencesHandler{
+		supabaseClient: client,
+	}
+}
+
+// GetPreferences returns the user's preferences, with defaults for any not set
+// GET /api/preferences
+func (h *PreferencesHandler) GetPreferences(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	prefs, err := loadPreferences(h.supabaseClient.WithContext(c.Request.Context()), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences changes the preferences the request sets
+// PUT /api/preferences
+func (h *PreferencesHandler) UpdatePreferences(c *gin.Context) {
+	var req models.UpdatePreferencesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	client := h.supabaseClient.WithContext(c.Request.Context())
+	prefs, err := loadPreferences(client, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if req.Timezone != nil {
+		prefs.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		prefs.Locale = *req.Locale
+	}
+	if req.WeekStart != nil {
+		prefs.WeekStart = strings.ToLower(*req.WeekStart)
+	}
+	if req.WorkStart != nil {
+		prefs.WorkStart = *req.WorkStart
+	}
+	if req.WorkEnd != nil {
+		prefs.WorkEnd = *req.WorkEnd
+	}
+
+	var v validation.Validator
+	validatePreferences(&v, prefs, req)
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	if _, err := client.UpsertPreferences(userID, map[string]interface{}{
+		"timezone":   prefs.Timezone,
+		"locale":     prefs.Locale,
+		"week_start": prefs.WeekStart,
+		"work_start": prefs.WorkStart,
+		"work_end":   prefs.WorkEnd,
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// DeletePreferences forgets the user's preferences and returns the defaults
+// they fall back to
+// DELETE /api/preferences
+func (h *PreferencesHandler) DeletePreferences(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	if err := h.supabaseClient.WithContext(c.Request.Context()).DeletePreferences(userID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, defaultPreferences)
+}
@@ -0,0 +1,96 @@
+//go:build lite
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestPreferencesDriveDateParsing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbURL := db.SQLiteScheme + filepath.Join(t.TempDir(), "preferences.db")
+	SetPreferencesStore(dbURL, "")
+	t.Cleanup(func() { preferencesStore = nil })
+
+	h := NewPreferencesHandler(dbURL, "")
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		c.Next()
+	}, middleware.ErrorHandler(utils.NewLogger()))
+	router.GET("/api/preferences", h.GetPreferences)
+	router.PUT("/api/preferences", h.UpdatePreferences)
+	router.DELETE("/api/preferences", h.DeletePreferences)
+
+	call := func(method, body string) (int, models.Preferences) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, "/api/preferences", strings.NewReader(body)))
+		var prefs models.Preferences
+		if recorder.Code == http.StatusOK {
+			if err := json.Unmarshal(recorder.Body.Bytes(), &prefs); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return recorder.Code, prefs
+	}
+
+	if code, prefs := call(http.MethodGet, ""); code != http.StatusOK || prefs != defaultPreferences {
+		t.Fatalf("expected the defaults before any update, got %d %+v", code, prefs)
+	}
+
+	code, prefs := call(http.MethodPut, `{"timezone":"Asia/Tokyo","week_start":"Sunday","work_end":"18:30"}`)
+	want := models.Preferences{Timezone: "Asia/Tokyo", Locale: "en-US", WeekStart: "sunday", WorkStart: "09:00", WorkEnd: "18:30"}
+	if code != http.StatusOK || prefs != want {
+		t.Fatalf("update: got %d %+v, want %+v", code, prefs, want)
+	}
+	if _, prefs := call(http.MethodGet, ""); prefs != want {
+		t.Fatalf("expected the update saved, got %+v", prefs)
+	}
+
+	for _, body := range []string{
+		`{"timezone":"Mars/Olympus"}`,
+		`{"week_start":"someday"}`,
+		`{"work_start":"9am"}`,
+		`{"work_start":"19:00"}`,
+		`{"locale":"english please"}`,
+	} {
+		if code, _ := call(http.MethodPut, body); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, code)
+		}
+	}
+
+	// Without a time zone in the request, dates resolve in the user's own,
+	// in weeks starting on their week_start and due when their day ends
+	parsed, err := parseDate(context.Background(), "u1", models.ParseDateRequest{Text: "next week"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	due := parsed.Date.In(preferencesLocation(want))
+	if parsed.Timezone != "Asia/Tokyo" || due.Weekday() != time.Sunday || due.Hour() != 18 || due.Minute() != 30 {
+		t.Errorf("expected Sunday 18:30 Tokyo time, got %s in %s", due, parsed.Timezone)
+	}
+	if parsed, _ := parseDate(context.Background(), "u1", models.ParseDateRequest{Text: "today", Timezone: "UTC"}); parsed.Timezone != "UTC" {
+		t.Errorf("expected the request's time zone to win, got %s", parsed.Timezone)
+	}
+
+	if code, prefs := call(http.MethodDelete, ""); code != http.StatusOK || prefs != defaultPreferences {
+		t.Fatalf("delete: got %d %+v", code, prefs)
+	}
+	if _, prefs := call(http.MethodGet, ""); prefs != defaultPreferences {
+		t.Fatalf("expected the defaults after delete, got %+v", prefs)
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+// ChangePreview is what a dry run returns instead of making a change: the
+// item before and after, shaped like the audit entry the change would record
+type ChangePreview struct {
+	DryRun     bool                   `json:"dry_run"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id,omitempty"`
+	Action     string                 `json:"action"`
+	Before     map[string]interface{} `json:"before,omitempty"`
+	After      map[string]interface{} `json:"after,omitempty"`
+	Diff       map[string]interface{} `json:"diff,omitempty"`
+	// Notice is the notice the change would come with, such as a focus contract deferral
+	Notice string `json:"notice,omitempty"`
+	// ConfirmationToken confirms an MCP call with the same arguments
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+}
+
+func newChangePreview(entityType, entityID, action string, before, after map[string]interface{}) *ChangePreview {
+	return &ChangePreview{
+		DryRun:     true,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Before:     before,
+		After:      after,
+		Diff:       auditDiff(before, after),
+	}
+}
+
+// mergeRow returns a copy of row with changes applied
+func mergeRow(row, changes map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(row)+len(changes))
+	for k, v := range row {
+		merged[k] = v
+	}
+	for k, v := range changes {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/prompts"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// promptTemplates are the prompts AIService sends to Claude
+var promptTemplates = prompts.Builtin()
+
+// SetPrompts installs the prompt templates, such as the built-ins with a
+// deployment's overrides
+func SetPrompts(r *prompts.Registry) {
+	promptTemplates = r
+}
+
+// renderPrompt fills in one of the installed prompt templates
+func renderPrompt(name string, data interface{}) (string, error) {
+	return promptTemplates.Render(name, data)
+}
+
+// ListPrompts lists the prompt templates in use with their versions and sources
+// GET /admin/prompts
+func ListPrompts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"prompts": promptTemplates.Templates()})
+}
+
+// ReloadPrompts reads the prompt templates again, picking up edits to the
+// override files; if any fails to load, the ones in use are kept
+// POST /admin/prompts/reload
+func ReloadPrompts(c *gin.Context) {
+	templates, err := promptTemplates.Reload()
+	if err != nil {
+		c.Error(utils.ErrBadRequest("prompt templates not reloaded: " + err.Error()).WithError(err))
+		return
+	}
+	utils.LoggerFromContext(c.Request.Context()).Info("Prompt templates reloaded", map[string]interface{}{"by": getUserID(c)})
+	c.JSON(http.StatusOK, gin.H{"prompts": templates, "reloaded": true})
+}
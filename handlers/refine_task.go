@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/claude"
+	"github.com/productivity/mcp-server/dates"
+	"github.com/productivity/mcp-server/language"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/prompts"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+const (
+	// refineConversationTTL is how long a refinement conversation is kept after its latest correction
+	refineConversationTTL = 30 * time.Minute
+	// maxRefineTurns is how many earlier corrections are resent to Claude with each new one
+	maxRefineTurns = 10
+	// maxCorrectionLength bounds a correction, which is kept in the conversation
+	maxCorrectionLength = 1000
+)
+
+// refineConversation is the state of one task's refinement: the task as
+// corrected so far and the exchanges with Claude that got it there
+type refineConversation struct {
+	userID   string
+	task     models.Task
+	messages []map[string]interface{}
+	turns    int
+	expires  time.Time
+}
+
+// refineConversations holds conversations until they expire
+var refineConversations = struct {
+	sync.Mutex
+	byID map[string]*refineConversation
+}{byID: make(map[string]*refineConversation)}
+
+// loadConversation returns a copy of userID's unexpired conversation id
+func loadConversation(id, userID string, now time.Time) (refineConversation, bool) {
+	refineConversations.Lock()
+	defer refineConversations.Unlock()
+	conv, ok := refineConversations.byID[id]
+	if !ok || conv.userID != userID || now.After(conv.expires) {
+		return refineConversation{}, false
+	}
+	copied := *conv
+	copied.messages = append([]map[string]interface{}(nil), conv.messages...)
+	return copied, true
+}
+
+// saveConversation stores conv under id, issuing an ID if id is empty, and
+// drops expired conversations
+func saveConversation(id string, conv refineConversation, now time.Time) (string, error) {
+	if id == "" {
+		raw := make([]byte, 16)
+		if _, err := rand.Read(raw); err != nil {
+			return "", err
+		}
+		id = hex.EncodeToString(raw)
+	}
+
+	refineConversations.Lock()
+	defer refineConversations.Unlock()
+	for other, c := range refineConversations.byID {
+		if now.After(c.expires) {
+			delete(refineConversations.byID, other)
+		}
+	}
+	refineConversations.byID[id] = &conv
+	return id, nil
+}
+
+// refinePromptTask describes task to Claude with only the fields it may
+// correct, its due date in loc
+func refinePromptTask(task models.Task, loc *time.Location) string {
+	fields := map[string]interface{}{
+		"title":       task.Title,
+		"description": task.Description,
+		"priority":    task.Priority,
+		"category":    task.Category,
+		"due_date":    nil,
+	}
+	if !task.DueDate.IsZero() {
+		fields["due_date"] = task.DueDate.In(loc).Format(time.RFC3339)
+	}
+	encoded, _ := json.MarshalIndent(fields, "", "  ")
+	return string(encoded)
+}
+
+// applyParsedTask copies the fields in Claude's answer onto task. A null
+// due_date clears it; fields left out are kept.
+func applyParsedTask(task *models.Task, parsed map[string]interface{}) {
+	if title, ok := parsed["title"].(string); ok && title != "" {
+		task.Title = title
+	}
+	if desc, ok := parsed["description"].(string); ok {
+		task.Description = desc
+	}
+	if priority, ok := parsed["priority"].(float64); ok {
+		task.Priority = int(priority)
+	}
+	if category, ok := parsed["category"].(string); ok {
+		task.Category = category
+	}
+	if due, present := parsed["due_date"]; present {
+		switch due := due.(type) {
+		case nil:
+			task.DueDate = time.Time{}
+		case string:
+			if dueDate, err := time.Parse(time.RFC3339, due); err == nil {
+				task.DueDate = dueDate
+			}
+		}
+	}
+}
+
+// RefineTask applies a correction to a parsed task, keeping the earlier
+// corrections in a conversation so later ones can build on them. As in
+// ParseTask, a date the dates package finds in the correction beats
+// Claude's reading of it, and the correction still applies to the due date
+// when Claude is unavailable.
+func (s *AIService) RefineTask(ctx context.Context, req models.RefineTaskRequest) (*models.RefineTaskResponse, error) {
+	var v validation.Validator
+	v.MaxLength("correction", req.Correction, maxCorrectionLength)
+	v.Check(req.ConversationID != "" || req.Task != nil, "task", validation.CodeRequired,
+		"task is required to start a conversation")
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	var conv refineConversation
+	if req.ConversationID != "" {
+		var ok bool
+		if conv, ok = loadConversation(req.ConversationID, req.UserID, time.Now()); !ok {
+			return nil, utils.ErrNotFound("conversation")
+		}
+	}
+	conv.userID = req.UserID
+	if req.Task != nil {
+		conv.task = *req.Task
+	}
+	task := conv.task
+	task.UserID = req.UserID
+	if task.Language == "" {
+		task.Language = language.Detect(task.Title + "\n" + task.Description)
+	}
+
+	// Refining never fails for want of preferences; dates follow the defaults
+	prefs, _ := userPreferences(ctx, req.UserID)
+	if req.Timezone != "" {
+		prefs.Timezone = req.Timezone
+	}
+	loc := preferencesLocation(prefs)
+	now := time.Now().In(loc)
+	match, hasDate := dates.ParseWith(req.Correction, now, dateOptions(prefs))
+
+	prompt, err := renderPrompt(prompts.RefineTask, prompts.RefineTaskData{
+		LanguageLine: languagePromptLine(task.Language),
+		Now:          now.Format(time.RFC3339),
+		Timezone:     loc.String(),
+		Locale:       prefs.Locale,
+		Task:         refinePromptTask(task, loc),
+		Correction:   req.Correction,
+	})
+	if err != nil {
+		return nil, utils.ErrInternal("failed to render prompt").WithError(err)
+	}
+	messages := append(conv.messages, map[string]interface{}{"role": "user", "content": prompt})
+
+	var parsed map[string]interface{}
+	reply, err := s.converseJSON(ctx, prompts.RefineTask, messages, parseTaskSchema, &parsed)
+	confidence, explanation := 0.9, "Refined task using Claude AI"
+	var outputErr *claude.OutputError
+	switch {
+	case errors.As(err, &outputErr):
+		confidence, explanation = 0.6, fmt.Sprintf("Refined with Claude but JSON decode failed: %v", err)
+	case err != nil:
+		confidence, explanation = 0.5, fmt.Sprintf("Fallback refinement (Claude API error: %v)", err)
+	default:
+		applyParsedTask(&task, parsed)
+		// Only exchanges that produced the task are resent with later corrections
+		conv.messages = append(messages, map[string]interface{}{"role": "assistant", "content": reply})
+		if max := 2 * maxRefineTurns; len(conv.messages) > max {
+			conv.messages = conv.messages[len(conv.messages)-max:]
+		}
+	}
+	if hasDate {
+		task.DueDate = match.Time
+	}
+
+	conv.task = task
+	conv.turns++
+	conv.expires = time.Now().Add(refineConversationTTL)
+	id, err := saveConversation(req.ConversationID, conv, time.Now())
+	if err != nil {
+		return nil, utils.ErrInternal("failed to start conversation").WithError(err)
+	}
+	return &models.RefineTaskResponse{
+		ConversationID: id,
+		Task:           &task,
+		Turns:          conv.turns,
+		Confidence:     confidence,
+		Explanation:    explanation,
+		ExpiresAt:      conv.expires,
+	}, nil
+}
+
+// RefineTask corrects a parsed task from a follow-up such as "actually it's
+// due Friday and it's priority 5"
+// POST /api/mcp/refine-task
+func (h *ClaudeHandler) RefineTask(c *gin.Context) {
+	var req models.RefineTaskRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	var v validation.Validator
+	validateTimezone(&v, req.Timezone)
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	response, err := h.service.RefineTask(c.Request.Context(), req)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	h.setRateLimitHeaders(c)
+	c.JSON(http.StatusOK, response)
+}
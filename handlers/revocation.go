@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// revocationCacheTTL is how long a token confirmed as not revoked is trusted
+// without another lookup. Revocations made on another instance take up to
+// this long to apply here; revocations made here apply immediately.
+const revocationCacheTTL = 30 * time.Second
+
+// TokenRevocations is the jti denylist for access tokens, stored in Supabase
+// and cached in process
+type TokenRevocations struct {
+	supabaseClient *db.SupabaseClient
+
+	mu      sync.Mutex
+	revoked map[string]time.Time // jti -> token expiry
+	active  map[string]time.Time // jti -> when it was last confirmed not revoked
+}
+
+// NewTokenRevocations creates a new token denylist
+func NewTokenRevocations(supabaseURL, supabaseKey string) *TokenRevocations {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &TokenRevocations{
+		supabaseClient: client,
+		revoked:        make(map[string]time.Time),
+		active:         make(map[string]time.Time),
+	}
+}
+
+// tokenRevocations backs /oauth/logout and introspection; nil disables revocation
+var tokenRevocations *TokenRevocations
+
+// SetTokenRevocations installs the denylist used by the OAuth handlers
+func SetTokenRevocations(r *TokenRevocations) {
+	tokenRevocations = r
+}
+
+// Revoke denylists a token until expiresAt, when it would have stopped working anyway
+func (r *TokenRevocations) Revoke(ctx context.Context, jti, userID, reason string, expiresAt time.Time) error {
+	_, err := r.supabaseClient.RevokeToken(jti, map[string]interface{}{
+		"user_id":    userID,
+		"reason":     reason,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+		"revoked_at": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	// The denylist is what blocks the token; the session row is informational
+	if err := r.supabaseClient.MarkSessionRevoked(jti, time.Now()); err != nil {
+		utils.LoggerFromContext(ctx).Error("Failed to mark session revoked", err, map[string]interface{}{"jti": jti})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revoked[jti] = expiresAt
+	delete(r.active, jti)
+	return nil
+}
+
+// IsRevoked implements middleware.TokenDenylist
+func (r *TokenRevocations) IsRevoked(jti string) (bool, error) {
+	now := time.Now()
+
+	r.mu.Lock()
+	if _, ok := r.revoked[jti]; ok {
+		r.mu.Unlock()
+		return true, nil
+	}
+	if checked, ok := r.active[jti]; ok && now.Sub(checked) < revocationCacheTTL {
+		r.mu.Unlock()
+		return false, nil
+	}
+	r.mu.Unlock()
+
+	row, err := r.supabaseClient.GetRevokedToken(jti)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if row != nil {
+		expiresAt, _ := rowTime(row, "expires_at")
+		r.revoked[jti] = expiresAt
+		return true, nil
+	}
+	r.active[jti] = now
+	return false, nil
+}
+
+// RunPurge drops cached checks of tokens that have expired or gone stale,
+// on every interval until ctx is cancelled. The janitor purges the
+// denylist itself.
+func (r *TokenRevocations) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		r.mu.Lock()
+		for jti, expiresAt := range r.revoked {
+			if expiresAt.Before(now) {
+				delete(r.revoked, jti)
+			}
+		}
+		for jti, checked := range r.active {
+			if now.Sub(checked) >= revocationCacheTTL {
+				delete(r.active, jti)
+			}
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// OAuthLogout revokes an access token so it stops working before it expires.
+// The token is taken from the token form/JSON field, or else the bearer
+// Authorization header. As in RFC 7009, invalid or expired tokens are not an
+// error since they already cannot be used.
+// POST /oauth/logout
+func OAuthLogout(c *gin.Context) {
+	token := c.PostForm("token")
+	if token == "" {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := c.ShouldBindJSON(&req); err == nil {
+			token = req.Token
+		}
+	}
+	if token == "" {
+		token, _ = strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "token is required (token field or Authorization: Bearer header)",
+		})
+		return
+	}
+
+	if tokenRevocations == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":             "temporarily_unavailable",
+			"error_description": "token revocation is not configured",
+		})
+		return
+	}
+
+	claims, err := validateJWT(token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"revoked": false})
+		return
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "token has no jti and cannot be revoked; it stops working when it expires",
+		})
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Duration(AccessTokenExpiration) * time.Second)
+	if exp, ok := claims["exp"].(float64); ok {
+		expiresAt = time.Unix(int64(exp), 0)
+	}
+	userID, _ := claims["sub"].(string)
+
+	if err := tokenRevocations.Revoke(c.Request.Context(), jti, userID, "logout", expiresAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "failed to revoke token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": true, "jti": jti})
+}
+
+// isTokenRevoked reports whether a validated token's jti is denylisted.
+// Lookup failures count as revoked so a broken denylist fails closed.
+func isTokenRevoked(claims map[string]interface{}) bool {
+	jti, _ := claims["jti"].(string)
+	if jti == "" || tokenRevocations == nil {
+		return false
+	}
+	revoked, err := tokenRevocations.IsRevoked(jti)
+	return revoked || err != nil
+}
+
+// newTokenID returns a random jti for an access token
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/productivity/mcp-server/utils"
+)
+
+// saga undoes the writes of an operation made of several when a later one
+// fails. Each Supabase request commits on its own, so an operation such as
+// creating a goal and then its milestones has no transaction to roll back;
+// instead every write that succeeds records how to undo it.
+type saga struct {
+	steps []sagaStep
+}
+
+type sagaStep struct {
+	name string
+	undo func() error
+}
+
+// done records a write that succeeded, named for the log, and how to undo it
+func (s *saga) done(name string, undo func() error) {
+	s.steps = append(s.steps, sagaStep{name: name, undo: undo})
+}
+
+// rollback undoes the recorded writes, the latest first. A step that fails
+// does not stop the others; their errors are returned together.
+func (s *saga) rollback() error {
+	var errs []error
+	for i := len(s.steps) - 1; i >= 0; i-- {
+		if err := s.steps[i].undo(); err != nil {
+			errs = append(errs, fmt.Errorf("undo %s: %w", s.steps[i].name, err))
+		}
+	}
+	s.steps = nil
+	return errors.Join(errs...)
+}
+
+// abort rolls back after err and returns err. A write that could not be
+// undone is logged, as the caller can do nothing more about it.
+func (s *saga) abort(ctx context.Context, err error) error {
+	if undoErr := s.rollback(); undoErr != nil {
+		utils.LoggerFromContext(ctx).Error("Failed to roll back", undoErr, map[string]interface{}{"cause": err.Error()})
+	}
+	return err
+}
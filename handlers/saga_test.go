@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSagaRollback(t *testing.T) {
+	var undone []string
+	var s saga
+	for _, name := range []string{"goal", "milestone 1", "milestone 2"} {
+		s.done(name, func() error {
+			undone = append(undone, name)
+			if name == "milestone 1" {
+				return errors.New("connection reset")
+			}
+			return nil
+		})
+	}
+
+	err := s.rollback()
+	if want := []string{"milestone 2", "milestone 1", "goal"}; !reflect.DeepEqual(undone, want) {
+		t.Errorf("undone %v, want %v", undone, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "undo milestone 1: connection reset") {
+		t.Errorf("rollback error = %v", err)
+	}
+
+	// Steps run once: a second rollback has nothing left to undo
+	undone = nil
+	if err := s.rollback(); err != nil || len(undone) != 0 {
+		t.Errorf("second rollback undid %v (%v)", undone, err)
+	}
+
+	cause := errors.New("insert failed")
+	s.done("task", func() error { undone = append(undone, "task"); return nil })
+	if got := s.abort(context.Background(), cause); got != cause || len(undone) != 1 {
+		t.Errorf("abort returned %v after undoing %v", got, undone)
+	}
+}
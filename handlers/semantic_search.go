@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/embeddings"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+const (
+	// DefaultSemanticLimit is how many matches searches return by default
+	DefaultSemanticLimit = 10
+	// MaxSemanticLimit bounds the matches a search may ask for
+	MaxSemanticLimit = 50
+	// DuplicateSimilarity is the similarity from which two tasks are
+	// reported as likely duplicates
+	DuplicateSimilarity = 0.9
+	// embedBatchSize bounds the texts sent in one embeddings request
+	embedBatchSize = 64
+)
+
+// taskEmbedder embeds task text; the local embedder needs no configuration
+var taskEmbedder embeddings.Embedder = embeddings.NewLocal()
+
+// SetEmbedder selects the embeddings model used for semantic search
+func SetEmbedder(e embeddings.Embedder) {
+	taskEmbedder = e
+}
+
+// taskEmbeddingText is the text a task is embedded from
+func taskEmbeddingText(title, description string) string {
+	return strings.TrimSpace(strings.TrimSpace(title) + "\n" + strings.TrimSpace(description))
+}
+
+func embeddingHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// indexTaskEmbeddings embeds the tasks that have no embedding from the
+// current model yet or whose text changed since, so indexing happens as
+// searches need it rather than on every write
+func indexTaskEmbeddings(ctx context.Context, client *db.SupabaseClient, userID string, tasks []map[string]interface{}) error {
+	model := taskEmbedder.Model()
+	rows, err := client.GetTaskEmbeddingHashes(userID, model)
+	if err != nil {
+		return err
+	}
+	stored := make(map[string]string, len(rows))
+	for _, row := range rows {
+		stored[rowString(row, "task_id")] = rowString(row, "content_hash")
+	}
+
+	var ids, texts []string
+	for _, task := range tasks {
+		id := rowString(task, "id")
+		text := taskEmbeddingText(rowString(task, "title"), rowString(task, "description"))
+		if stored[id] != embeddingHash(text) {
+			ids = append(ids, id)
+			texts = append(texts, text)
+		}
+	}
+
+	for start := 0; start < len(texts); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(texts))
+		vecs, err := taskEmbedder.Embed(ctx, texts[start:end])
+		if err != nil {
+			return err
+		}
+		for i, vec := range vecs {
+			if err := client.UpsertTaskEmbedding(ids[start+i], userID, model, embeddingHash(texts[start+i]), vec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchTasks embeds text and ranks userID's tasks against it, leaving out skipID
+func (s *TaskService) matchTasks(c *gin.Context, userID, text, skipID string, limit int) (*models.TaskMatches, error) {
+	ctx := c.Request.Context()
+	client := s.supabaseClient.WithContext(ctx)
+	tasks, err := client.GetUserTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := indexTaskEmbeddings(ctx, client, userID, tasks); err != nil {
+		return nil, utils.ErrInternal("failed to index tasks for search").WithError(err)
+	}
+
+	vecs, err := taskEmbedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, utils.ErrInternal("failed to embed search text").WithError(err)
+	}
+	found, err := client.MatchTaskEmbeddings(userID, taskEmbedder.Model(), vecs[0], limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]map[string]interface{}, len(tasks))
+	for _, task := range tasks {
+		byID[rowString(task, "id")] = task
+	}
+	result := &models.TaskMatches{Model: taskEmbedder.Model(), Matches: []models.TaskMatch{}}
+	for _, m := range found {
+		// Tasks moved to the trash since they were embedded are not listed
+		task, ok := byID[m.TaskID]
+		if !ok || m.TaskID == skipID || m.Similarity <= 0 || len(result.Matches) == limit {
+			continue
+		}
+		result.Matches = append(result.Matches, models.TaskMatch{
+			Task:       task,
+			Similarity: m.Similarity,
+			Duplicate:  m.Similarity >= DuplicateSimilarity,
+		})
+	}
+	return result, nil
+}
+
+// validateSemanticLimit checks limit, defaulting it when unset
+func validateSemanticLimit(v *validation.Validator, limit *int) {
+	if *limit == 0 {
+		*limit = DefaultSemanticLimit
+	}
+	v.Range("limit", *limit, 1, MaxSemanticLimit)
+}
+
+// SemanticSearch ranks userID's tasks by how close their title and
+// description are in meaning to query
+func (s *TaskService) SemanticSearch(c *gin.Context, userID string, req models.SemanticSearchRequest) (*models.TaskMatches, error) {
+	var v validation.Validator
+	v.Required("query", strings.TrimSpace(req.Query))
+	v.MaxLength("query", req.Query, validation.MaxDescriptionLength)
+	validateSemanticLimit(&v, &req.Limit)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+
+	result, err := s.matchTasks(c, userID, req.Query, "", req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	result.Query = req.Query
+	return result, nil
+}
+
+// Related lists userID's tasks most similar to the task taskID
+func (s *TaskService) Related(c *gin.Context, userID, taskID string, limit int) (*models.TaskMatches, error) {
+	var v validation.Validator
+	validateSemanticLimit(&v, &limit)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	task, err := client.GetTask(taskID)
+	if err != nil {
+		return nil, lookupError("task", err)
+	}
+	if err := checkRowAccess(client, userID, task, "task", models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	result, err := s.matchTasks(c, userID, taskEmbeddingText(rowString(task, "title"), rowString(task, "description")), taskID, limit)
+	if err != nil {
+		return nil, err
+	}
+	result.TaskID = taskID
+	return result, nil
+}
+
+// Duplicates lists userID's tasks similar enough to a prospective task to be
+// the same one, so a client can warn before creating it
+func (s *TaskService) Duplicates(c *gin.Context, userID string, req models.DuplicateCheckRequest) (*models.TaskMatches, error) {
+	var v validation.Validator
+	validateTitle(&v, req.Title)
+	v.MaxLength("description", req.Description, validation.MaxDescriptionLength)
+	validateSemanticLimit(&v, &req.Limit)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+
+	result, err := s.matchTasks(c, userID, taskEmbeddingText(req.Title, req.Description), "", req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	duplicates := []models.TaskMatch{}
+	for _, m := range result.Matches {
+		if m.Duplicate {
+			duplicates = append(duplicates, m)
+		}
+	}
+	result.Matches = duplicates
+	return result, nil
+}
+
+// SearchTasks ranks the user's tasks by meaning
+// POST /api/tasks/search
+func (h *TaskHandler) SearchTasks(c *gin.Context) {
+	var req models.SemanticSearchRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	result, err := h.service.SemanticSearch(c, getUserID(c), req)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RelatedTasks lists the user's tasks most similar to a task
+// GET /api/tasks/:id/related?limit=10
+func (h *TaskHandler) RelatedTasks(c *gin.Context) {
+	limit := DefaultSemanticLimit
+	if raw := c.Query("limit"); raw != "" {
+		// A non-number fails the service's range check
+		limit, _ = strconv.Atoi(raw)
+		if limit == 0 {
+			limit = -1
+		}
+	}
+	result, err := h.service.Related(c, getUserID(c), c.Param("id"), limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// DuplicateTasks lists existing tasks that look like the one described
+// POST /api/tasks/duplicates
+func (h *TaskHandler) DuplicateTasks(c *gin.Context) {
+	var req models.DuplicateCheckRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	result, err := h.service.Duplicates(c, getUserID(c), req)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,108 @@
+//go:build lite
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/validation"
+)
+
+func TestSemanticSearchRelatedAndDuplicates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbURL := db.SQLiteScheme + filepath.Join(t.TempDir(), "semantic.db")
+	client, err := db.NewSupabaseClient(dbURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]string{}
+	add := func(userID, title, description string) {
+		t.Helper()
+		id, err := client.CreateTask(userID, map[string]interface{}{
+			"title":       title,
+			"description": description,
+			"due_date":    time.Now().UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if userID == "u1" {
+			ids[title] = id
+		}
+	}
+	add("u1", "Email the client about the invoice", "")
+	add("u1", "Send invoice reminder email", "The client has not paid yet")
+	add("u1", "Go for a run", "Five kilometres in the park")
+	add("u1", "Water the plants", "")
+	add("u2", "Email the client about the invoice", "")
+
+	service := NewTaskService(client)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	titles := func(result *models.TaskMatches) []string {
+		var out []string
+		for _, m := range result.Matches {
+			out = append(out, rowString(m.Task, "title"))
+		}
+		return out
+	}
+
+	found, err := service.SemanticSearch(c, "u1", models.SemanticSearchRequest{Query: "invoice email", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(found); len(got) != 2 || got[1] == "Go for a run" || got[0] == "Go for a run" {
+		t.Errorf("search: got %v", got)
+	}
+	if found.Model != taskEmbedder.Model() || found.Query != "invoice email" {
+		t.Errorf("search: model %q, query %q", found.Model, found.Query)
+	}
+
+	related, err := service.Related(c, "u1", ids["Email the client about the invoice"], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(related); len(got) == 0 || got[0] != "Send invoice reminder email" {
+		t.Errorf("related: got %v", got)
+	}
+	for _, m := range related.Matches {
+		if rowString(m.Task, "user_id") != "u1" || rowString(m.Task, "id") == related.TaskID {
+			t.Errorf("related: unexpected match %v", m.Task)
+		}
+	}
+
+	duplicates, err := service.Duplicates(c, "u1", models.DuplicateCheckRequest{Title: "email the client about the invoice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(duplicates); len(got) != 1 || got[0] != "Email the client about the invoice" || !duplicates.Matches[0].Duplicate {
+		t.Errorf("duplicates: got %v", got)
+	}
+
+	// Edited text is embedded again before the next search
+	desc := "Ask about the quarterly invoice"
+	title := "Call the accountant"
+	if _, err := service.Update(c, "u1", ids["Water the plants"], models.UpdateTaskRequest{Title: &title, Description: &desc}); err != nil {
+		t.Fatal(err)
+	}
+	duplicates, err = service.Duplicates(c, "u1", models.DuplicateCheckRequest{Title: "Call the accountant", Description: desc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(duplicates); len(got) != 1 || got[0] != "Call the accountant" {
+		t.Errorf("duplicates after edit: got %v", got)
+	}
+
+	if _, err := service.SemanticSearch(c, "u1", models.SemanticSearchRequest{Query: "x", Limit: MaxSemanticLimit + 1}); err == nil {
+		t.Error("expected a validation error for the limit")
+	} else if _, ok := err.(validation.Errors); !ok {
+		t.Errorf("expected validation errors, got %v", err)
+	}
+}
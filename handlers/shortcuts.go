@@ -0,0 +1,330 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// minFuzzyScore is the lowest similarity accepted when completing a task by title
+const minFuzzyScore = 0.6
+
+// ShortcutsHandler serves compact endpoints for Apple Shortcuts.
+// Requests accept plain form fields and responses are flat JSON objects.
+type ShortcutsHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewShortcutsHandler creates a new Shortcuts handler
+func NewShortcutsHandler(supabaseURL, supabaseKey string) *ShortcutsHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &ShortcutsHandler{
+		supabaseClient: client,
+	}
+}
+
+// QuickAddRequest represents a Shortcuts quick-add
+type QuickAddRequest struct {
+	Title    string `form:"title" json:"title"`
+	Due      string `form:"due" json:"due"` // "today", "tomorrow", YYYY-MM-DD or RFC3339
+	Priority string `form:"priority" json:"priority"`
+	Category string `form:"category" json:"category"`
+	Notes    string `form:"notes" json:"notes"`
+}
+
+// CompleteByTitleRequest represents a Shortcuts complete-by-title request
+type CompleteByTitleRequest struct {
+	Title string `form:"title" json:"title"`
+}
+
+// compactTask flattens a task row into the minimal shape returned to
+// Shortcuts, with the due time in the user's time zone loc
+func compactTask(task map[string]interface{}, loc *time.Location) gin.H {
+	due := ""
+	if t, ok := rowTime(task, "due_date"); ok {
+		due = t.In(loc).Format("2006-01-02 15:04")
+	}
+	return gin.H{
+		"id":       rowString(task, "id"),
+		"title":    rowString(task, "title"),
+		"due":      due,
+		"priority": rowInt(task, "priority"),
+		"done":     rowBool(task, "completed"),
+	}
+}
+
+// parseShortcutDue converts the loose due values Shortcuts sends into a timestamp
+func parseShortcutDue(due string, now time.Time) (time.Time, bool) {
+	endOfDay := func(t time.Time) time.Time {
+		return startOfDay(t).Add(23*time.Hour + 59*time.Minute)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(due)) {
+	case "", "today":
+		return endOfDay(now), true
+	case "tomorrow":
+		return endOfDay(now.AddDate(0, 0, 1)), true
+	}
+
+	if t, err := time.Parse(time.RFC3339, due); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", due, now.Location()); err == nil {
+		return endOfDay(t), true
+	}
+	return time.Time{}, false
+}
+
+// QuickAdd creates a task from simple form fields
+// POST /api/shortcuts/add
+func (h *ShortcutsHandler) QuickAdd(c *gin.Context) {
+	var req QuickAddRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(utils.ErrValidation(err.Error()))
+		return
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		c.Error(utils.ErrBadRequest("title is required"))
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	// "today" and "tomorrow" are days in the user's time zone
+	now, _, err := userNow(h.supabaseClient.WithContext(c.Request.Context()), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	dueDate, ok := parseShortcutDue(req.Due, now)
+	if !ok {
+		c.Error(utils.ErrBadRequest("due must be today, tomorrow, YYYY-MM-DD or an ISO 8601 timestamp"))
+		return
+	}
+
+	priority := 3
+	if req.Priority != "" {
+		p, err := strconv.Atoi(req.Priority)
+		if err != nil || p < 1 || p > 5 {
+			c.Error(utils.ErrBadRequest("priority must be between 1 and 5"))
+			return
+		}
+		priority = p
+	}
+
+	category := req.Category
+	if category == "" {
+		category = "inbox"
+	}
+
+	taskData := map[string]interface{}{
+		"title":       req.Title,
+		"description": req.Notes,
+		"priority":    priority,
+		"due_date":    dueDate.Format(time.RFC3339),
+		"category":    category,
+		"completed":   false,
+		"created_at":  now.Format(time.RFC3339),
+		"updated_at":  now.Format(time.RFC3339),
+	}
+	setLanguage(taskData, entityLanguage("", req.Title, req.Notes))
+	notice := applyFocusContract(c.Request.Context(), h.supabaseClient, userID, taskData)
+
+	if err := checkTaskLimit(c, userID, 1); err != nil {
+		c.Error(err)
+		return
+	}
+	taskID, err := h.supabaseClient.CreateTask(userID, taskData)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	taskData["id"] = taskID
+	recordAudit(c, AuditEntityTask, taskID, AuditActionCreate, nil, taskData)
+	resp := compactTask(taskData, now.Location())
+	if notice != "" {
+		resp["notice"] = notice
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// Today lists open tasks due today or overdue, soonest first
+// GET /api/shortcuts/today
+func (h *ShortcutsHandler) Today(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	tasks, err := h.supabaseClient.GetUserTasks(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	now, _, err := userNow(h.supabaseClient.WithContext(c.Request.Context()), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	endOfToday := startOfDay(now).AddDate(0, 0, 1)
+	var due []map[string]interface{}
+	for _, task := range tasks {
+		if rowBool(task, "completed") {
+			continue
+		}
+		if t, ok := rowTime(task, "due_date"); ok && t.Before(endOfToday) {
+			due = append(due, task)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		ti, _ := rowTime(due[i], "due_date")
+		tj, _ := rowTime(due[j], "due_date")
+		return ti.Before(tj)
+	})
+
+	items := make([]gin.H, 0, len(due))
+	lines := make([]string, 0, len(due))
+	for _, task := range due {
+		items = append(items, compactTask(task, now.Location()))
+		lines = append(lines, "• "+rowString(task, "title"))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(items),
+		"tasks": items,
+		"text":  strings.Join(lines, "\n"),
+	})
+}
+
+// CompleteByTitle completes the open task whose title best matches the given text
+// POST /api/shortcuts/complete
+func (h *ShortcutsHandler) CompleteByTitle(c *gin.Context) {
+	var req CompleteByTitleRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(utils.ErrValidation(err.Error()))
+		return
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		c.Error(utils.ErrBadRequest("title is required"))
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	tasks, err := h.supabaseClient.GetUserTasks(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var best map[string]interface{}
+	bestScore := 0.0
+	for _, task := range tasks {
+		if rowBool(task, "completed") {
+			continue
+		}
+		if score := fuzzyScore(req.Title, rowString(task, "title")); score > bestScore {
+			best, bestScore = task, score
+		}
+	}
+	if best == nil || bestScore < minFuzzyScore {
+		c.Error(utils.NewAppError(utils.ErrCodeNotFound, "no open task matches that title", http.StatusNotFound))
+		return
+	}
+
+	userTime, _, err := userNow(h.supabaseClient.WithContext(c.Request.Context()), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	now := userTime.Format(time.RFC3339)
+	taskID := rowString(best, "id")
+	if err := h.supabaseClient.UpdateTask(taskID, map[string]interface{}{
+		"completed":    true,
+		"completed_at": now,
+		"updated_at":   now,
+	}); err != nil {
+		c.Error(err)
+		return
+	}
+
+	before := make(map[string]interface{}, len(best))
+	for k, v := range best {
+		before[k] = v
+	}
+	best["completed"] = true
+	best["completed_at"] = now
+	recordAudit(c, AuditEntityTask, taskID, AuditActionUpdate, before, best)
+	recordTaskCompletion(c.Request.Context(), h.supabaseClient, before, best)
+	result := compactTask(best, userTime.Location())
+	result["match_score"] = bestScore
+	c.JSON(http.StatusOK, result)
+}
+
+// fuzzyScore rates how well query matches title on a 0-1 scale.
+// Exact matches score 1, substring matches of at least three characters
+// 0.9, otherwise the normalized edit-distance similarity of the two strings.
+func fuzzyScore(query, title string) float64 {
+	q := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	t := strings.Join(strings.Fields(strings.ToLower(title)), " ")
+	if q == "" || t == "" {
+		return 0
+	}
+	if q == t {
+		return 1
+	}
+	if len(q) >= 3 && len(t) >= 3 && (strings.Contains(t, q) || strings.Contains(q, t)) {
+		return 0.9
+	}
+
+	longest := len([]rune(q))
+	if n := len([]rune(t)); n > longest {
+		longest = n
+	}
+	return 1 - float64(levenshtein(q, t))/float64(longest)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,46 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	cases := []struct {
+		query, title string
+		wantMatch    bool
+	}{
+		{"Buy milk", "buy milk", true},
+		{"milk", "Buy milk and bread", true},
+		{"call mom", "Call Mum", true},
+		{"report", "Plan vacation", false},
+		{"a", "Buy milk", false},
+	}
+
+	for _, tc := range cases {
+		score := fuzzyScore(tc.query, tc.title)
+		if got := score >= minFuzzyScore; got != tc.wantMatch {
+			t.Errorf("fuzzyScore(%q, %q) = %.2f, want match=%v", tc.query, tc.title, score, tc.wantMatch)
+		}
+	}
+}
+
+func TestParseShortcutDue(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 30, 0, 0, time.UTC)
+
+	due, ok := parseShortcutDue("tomorrow", now)
+	if !ok || due.Day() != 11 || due.Hour() != 23 {
+		t.Fatalf("expected end of 2025-03-11, got %v (ok=%v)", due, ok)
+	}
+
+	due, ok = parseShortcutDue("2025-04-01", now)
+	if !ok || due.Month() != time.April || due.Day() != 1 {
+		t.Fatalf("expected 2025-04-01, got %v (ok=%v)", due, ok)
+	}
+
+	if _, ok := parseShortcutDue("someday", now); ok {
+		t.Fatalf("expected unparseable due value to be rejected")
+	}
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/dates"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// Where a snoozed task's new due date came from
+const (
+	RescheduleSourcePhrase = "phrase"
+	RescheduleSourceSmart  = "smart"
+)
+
+const (
+	// snoozeLookaheadDays is how many working days the smart default weighs
+	snoozeLookaheadDays = 5
+	// maxSnoozePhraseLength bounds the until phrase, which is kept in the history
+	maxSnoozePhraseLength = 200
+)
+
+// taskSnooze is a validated, authorized snooze, ready to apply
+type taskSnooze struct {
+	client   *db.SupabaseClient
+	previous *time.Time
+	due      time.Time
+	source   string
+	reason   string
+}
+
+// isWorkingDay reports whether t falls Monday to Friday
+func isWorkingDay(t time.Time) bool {
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+// smartSnoozeTime picks a new due time when the user names none: the start
+// of their working day, on whichever of the next snoozeLookaheadDays working
+// days after after has the fewest open tasks due, the earliest on a tie.
+// load counts open tasks by the start of their due day.
+func smartSnoozeTime(after time.Time, prefs models.Preferences, load map[time.Time]int) (time.Time, string) {
+	start, _ := workingHours(prefs)
+
+	var best time.Time
+	bestLoad := -1
+	day := startOfDay(after)
+	for n := 0; n < snoozeLookaheadDays; {
+		day = day.AddDate(0, 0, 1)
+		if !isWorkingDay(day) {
+			continue
+		}
+		n++
+		if count := load[day]; bestLoad < 0 || count < bestLoad {
+			best, bestLoad = day, count
+		}
+	}
+
+	due := time.Date(best.Year(), best.Month(), best.Day(), start.Hour(), start.Minute(), 0, 0, best.Location())
+	tasks := "tasks"
+	if bestLoad == 1 {
+		tasks = "task"
+	}
+	reason := fmt.Sprintf("Moved to %s, the lightest of the next %d working days (%d other %s due)",
+		due.Format("Mon Jan 2 15:04"), snoozeLookaheadDays, bestLoad, tasks)
+	return due, reason
+}
+
+// Snooze pushes the task's due date back as userID, who needs the editor
+// role on shared tasks, and records the move in its reschedule history
+func (s *TaskService) Snooze(c *gin.Context, userID, taskID string, req models.SnoozeTaskRequest) (*models.SnoozeResult, error) {
+	plan, err := s.planSnooze(c, userID, taskID, req)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.Update(c, userID, taskID, models.UpdateTaskRequest{DueDate: &plan.due})
+	if err != nil {
+		return nil, err
+	}
+
+	entry := map[string]interface{}{
+		"new_due_date": plan.due.UTC().Format(time.RFC3339),
+		"source":       plan.source,
+	}
+	if plan.previous != nil {
+		entry["previous_due_date"] = plan.previous.UTC().Format(time.RFC3339)
+	}
+	if req.Until != "" {
+		entry["phrase"] = req.Until
+	}
+	if err := plan.client.RecordReschedule(userID, taskID, entry); err != nil {
+		utils.LoggerFromContext(c.Request.Context()).Error("Failed to record reschedule", err, map[string]interface{}{"task_id": taskID})
+	}
+
+	return &models.SnoozeResult{
+		Task:            task,
+		PreviousDueDate: plan.previous,
+		DueDate:         plan.due,
+		Timezone:        plan.due.Location().String(),
+		Source:          plan.source,
+		Reason:          plan.reason,
+		CapacityWarning: s.CapacityWarning(c, userID, task),
+	}, nil
+}
+
+// PreviewSnooze returns the change Snooze would make, without making it
+func (s *TaskService) PreviewSnooze(c *gin.Context, userID, taskID string, req models.SnoozeTaskRequest) (*ChangePreview, error) {
+	plan, err := s.planSnooze(c, userID, taskID, req)
+	if err != nil {
+		return nil, err
+	}
+	preview, err := s.PreviewUpdate(c, userID, taskID, models.UpdateTaskRequest{DueDate: &plan.due})
+	if err != nil {
+		return nil, err
+	}
+	preview.Notice = plan.reason
+	return preview, nil
+}
+
+func (s *TaskService) planSnooze(c *gin.Context, userID, taskID string, req models.SnoozeTaskRequest) (*taskSnooze, error) {
+	var v validation.Validator
+	v.MaxLength("until", req.Until, maxSnoozePhraseLength)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	task, err := client.GetTask(taskID)
+	if err != nil {
+		return nil, lookupError("task", err)
+	}
+	if err := checkRowAccess(client, userID, task, "task", models.RoleEditor); err != nil {
+		return nil, err
+	}
+	if rowBool(task, "completed") {
+		return nil, utils.ErrConflict("task is already completed")
+	}
+
+	now, prefs, err := userNow(client, userID)
+	if err != nil {
+		return nil, err
+	}
+	plan := &taskSnooze{client: client}
+	if due, ok := rowTime(task, "due_date"); ok {
+		due = due.In(now.Location())
+		plan.previous = &due
+	}
+
+	if req.Until != "" {
+		match, ok := dates.ParseWith(req.Until, now, dateOptions(prefs))
+		v.Check(ok, "until", validation.CodeInvalidFormat, `until must name a time, e.g. "next Monday morning"`)
+		v.Check(!ok || match.Time.After(now), "until", validation.CodeOutOfRange, "until must be in the future")
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+		plan.due, plan.source = match.Time, RescheduleSourcePhrase
+		plan.reason = fmt.Sprintf("Moved to %s (%q)", match.Time.Format("Mon Jan 2 15:04"), match.Text)
+		return plan, nil
+	}
+
+	// A snooze moves the task later than it is now, even if it is not yet due
+	after := now
+	if plan.previous != nil && plan.previous.After(now) {
+		after = *plan.previous
+	}
+	tasks, err := client.GetOpenDatedTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+	load := map[time.Time]int{}
+	for _, other := range tasks {
+		if due, ok := rowTime(other, "due_date"); ok && rowString(other, "id") != taskID {
+			load[startOfDay(due.In(now.Location()))]++
+		}
+	}
+	plan.due, plan.reason = smartSnoozeTime(after, prefs, load)
+	plan.source = RescheduleSourceSmart
+	return plan, nil
+}
+
+// Reschedules returns the task's reschedule history as userID, who needs
+// read access to it
+func (s *TaskService) Reschedules(c *gin.Context, userID, taskID string) ([]models.Reschedule, error) {
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	task, err := client.GetTask(taskID)
+	if err != nil {
+		return nil, lookupError("task", err)
+	}
+	if err := checkRowAccess(client, userID, task, "task", models.RoleViewer); err != nil {
+		return nil, err
+	}
+	rows, err := client.GetTaskReschedules(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]models.Reschedule, 0, len(rows))
+	for _, row := range rows {
+		entry := models.Reschedule{
+			ID:     rowString(row, "id"),
+			TaskID: rowString(row, "task_id"),
+			Source: rowString(row, "source"),
+			Phrase: rowString(row, "phrase"),
+		}
+		if previous, ok := rowTime(row, "previous_due_date"); ok {
+			entry.PreviousDueDate = &previous
+		}
+		entry.NewDueDate, _ = rowTime(row, "new_due_date")
+		entry.CreatedAt, _ = rowTime(row, "created_at")
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// SnoozeTask pushes a task back to a named time, or to the lightest of the
+// next few working days
+// POST /api/tasks/:id/snooze
+func (h *TaskHandler) SnoozeTask(c *gin.Context) {
+	var req models.SnoozeTaskRequest
+	// The body is optional: no until means a smart default
+	if c.Request.ContentLength > 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+	result, err := h.service.Snooze(c, getUserID(c), c.Param("id"), req)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// TaskReschedules lists the times a task has been snoozed, oldest first
+// GET /api/tasks/:id/reschedules
+func (h *TaskHandler) TaskReschedules(c *gin.Context) {
+	history, err := h.service.Reschedules(c, getUserID(c), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"task_id": c.Param("id"), "reschedules": history, "count": len(history)})
+}
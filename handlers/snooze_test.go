@@ -0,0 +1,115 @@
+//go:build lite
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/validation"
+)
+
+func TestSmartSnoozeTime(t *testing.T) {
+	prefs := defaultPreferences
+	prefs.WorkStart = "08:30"
+	friday := time.Date(2026, time.October, 16, 14, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, time.October, d, 0, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		load map[time.Time]int
+		want time.Time
+	}{
+		{nil, time.Date(2026, time.October, 19, 8, 30, 0, 0, time.UTC)},
+		{map[time.Time]int{day(19): 3, day(20): 1, day(21): 1, day(22): 2, day(23): 4},
+			time.Date(2026, time.October, 20, 8, 30, 0, 0, time.UTC)},
+		// The weekend and days past the lookahead are never picked
+		{map[time.Time]int{day(19): 1, day(20): 1, day(21): 1, day(22): 1, day(23): 1},
+			time.Date(2026, time.October, 19, 8, 30, 0, 0, time.UTC)},
+	}
+	for i, tc := range cases {
+		got, reason := smartSnoozeTime(friday, prefs, tc.load)
+		if !got.Equal(tc.want) {
+			t.Errorf("case %d: got %v (%s), want %v", i, got, reason, tc.want)
+		}
+	}
+}
+
+func TestSnoozeTask(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, err := db.NewSupabaseClient(db.SQLiteScheme+filepath.Join(t.TempDir(), "snooze.db"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UpsertPreferences("u1", map[string]interface{}{"work_start": "08:00"}); err != nil {
+		t.Fatal(err)
+	}
+	add := func(title string, completed bool) string {
+		t.Helper()
+		id, err := client.CreateTask("u1", map[string]interface{}{
+			"title":     title,
+			"due_date":  time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			"completed": completed,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	taskID := add("write report", false)
+	doneID := add("file expenses", true)
+
+	service := NewTaskService(client)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+	named, err := service.Snooze(c, "u1", taskID, models.SnoozeTaskRequest{Until: "next Monday morning"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if named.Source != RescheduleSourcePhrase || named.DueDate.Weekday() != time.Monday || named.DueDate.Hour() != 9 {
+		t.Errorf("named snooze: %s to %v", named.Source, named.DueDate)
+	}
+	if named.PreviousDueDate == nil || !named.PreviousDueDate.Before(time.Now()) {
+		t.Errorf("named snooze: previous due date %v", named.PreviousDueDate)
+	}
+
+	// Without a time the task moves past its new due date to a working day
+	smart, err := service.Snooze(c, "u1", taskID, models.SnoozeTaskRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if smart.Source != RescheduleSourceSmart || !smart.DueDate.After(named.DueDate) ||
+		!isWorkingDay(smart.DueDate) || smart.DueDate.Hour() != 8 {
+		t.Errorf("smart snooze: %s to %v (%s)", smart.Source, smart.DueDate, smart.Reason)
+	}
+	if due, _ := rowTime(smart.Task, "due_date"); !due.Equal(smart.DueDate) {
+		t.Errorf("smart snooze: task due %v, want %v", due, smart.DueDate)
+	}
+
+	history, err := service.Reschedules(c, "u1", taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Source != RescheduleSourcePhrase || history[0].Phrase != "next Monday morning" ||
+		history[1].Source != RescheduleSourceSmart || !history[1].NewDueDate.Equal(smart.DueDate) {
+		t.Errorf("history: %+v", history)
+	}
+
+	if _, err := service.Snooze(c, "u1", taskID, models.SnoozeTaskRequest{Until: "whenever"}); err == nil {
+		t.Error("unparseable until: expected an error")
+	} else if _, ok := err.(validation.Errors); !ok {
+		t.Errorf("unparseable until: expected validation errors, got %v", err)
+	}
+	if _, err := service.Snooze(c, "u1", doneID, models.SnoozeTaskRequest{}); err == nil {
+		t.Error("completed task: expected an error")
+	}
+	if _, err := service.Snooze(c, "u2", taskID, models.SnoozeTaskRequest{}); err == nil {
+		t.Error("another user's task: expected an error")
+	}
+}
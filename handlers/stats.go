@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/llm"
+	"github.com/productivity/mcp-server/slo"
+)
+
+// startedAt is reported as the server's uptime origin
+var startedAt = time.Now()
+
+// sloTracker records MCP tool latencies; nil disables tracking
+var sloTracker *slo.Tracker
+
+// SetSLOTracker installs the tracker used for tool latency objectives and
+// reported by AdminStats
+func SetSLOTracker(t *slo.Tracker) {
+	sloTracker = t
+}
+
+// llmFailover routes model requests between providers; nil when failover is off
+var llmFailover *llm.Failover
+
+// AdminStats reports server uptime, SLO status for every tracked route and
+// tool, how often each AI operation fell back to a canned answer, and the
+// health of the model providers when failover is on
+// GET /admin/stats
+func AdminStats(c *gin.Context) {
+	status := sloTracker.Status()
+	alerting := 0
+	for _, s := range status {
+		if len(s.Alerting) > 0 {
+			alerting++
+		}
+	}
+
+	stats := gin.H{
+		"started_at":     startedAt.UTC().Format(time.RFC3339),
+		"uptime_seconds": int64(time.Since(startedAt).Seconds()),
+		"slo": gin.H{
+			"targets":  status,
+			"count":    len(status),
+			"alerting": alerting,
+		},
+		"ai_fallbacks": aiFallbacks.snapshot(),
+	}
+	if llmFailover != nil {
+		stats["llm_providers"] = llmFailover.Status()
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/events"
+	"github.com/productivity/mcp-server/streaks"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// StreakHandler computes habit streaks with grace rules
+type StreakHandler struct {
+	supabaseClient *db.SupabaseClient
+	defaultRules   streaks.GraceRules
+}
+
+// NewStreakHandler creates a new streak handler. defaultRules apply to users
+// who have not saved their own grace rules.
+func NewStreakHandler(supabaseURL, supabaseKey string, defaultRules streaks.GraceRules) *StreakHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return &StreakHandler{
+		supabaseClient: client,
+		defaultRules:   defaultRules,
+	}
+}
+
+// recordTaskCompletion appends to the completion history, and updates the
+// running completion stats, when a task goes from open to completed
+func recordTaskCompletion(ctx context.Context, client *db.SupabaseClient, before, after map[string]interface{}) {
+	if rowBool(before, "completed") || !rowBool(after, "completed") {
+		return
+	}
+	completedAt, ok := rowTime(after, "completed_at")
+	if !ok {
+		completedAt = time.Now()
+	}
+	if err := client.RecordCompletion(rowString(after, "user_id"), rowString(after, "id"), completedAt); err != nil {
+		utils.LoggerFromContext(ctx).Error("Failed to record completion", err, map[string]interface{}{"task_id": rowString(after, "id")})
+		return
+	}
+	if err := recordActivity(client, rowString(after, "user_id"), completedAt); err != nil {
+		utils.LoggerFromContext(ctx).Error("Failed to update completion stats", err, map[string]interface{}{"task_id": rowString(after, "id")})
+	}
+}
+
+// rulesFor returns the user's grace rules, falling back to the server defaults
+func (h *StreakHandler) rulesFor(userID string) (streaks.GraceRules, error) {
+	row, err := h.supabaseClient.GetGraceRules(userID)
+	if err != nil {
+		return h.defaultRules, err
+	}
+	if row == nil {
+		return h.defaultRules, nil
+	}
+	return streaks.GraceRules{
+		FreezesPerWeek: rowInt(row, "freezes_per_week"),
+		WeekendExempt:  rowBool(row, "weekend_exempt"),
+	}, nil
+}
+
+// GetStreaks computes the streak of every habit (recurring task) for the user.
+// Freezes consumed by this computation are persisted and announced with a
+// streak.freeze_consumed event.
+// GET /api/streaks
+func (h *StreakHandler) GetStreaks(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	rules, err := h.rulesFor(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	tasks, err := h.supabaseClient.GetUserTasks(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	now := time.Now()
+	completionRows, err := h.supabaseClient.GetCompletionsSince(userID, now.Add(-streaks.MaxLookback))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	freezeRows, err := h.supabaseClient.GetStreakFreezes(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	completions := groupTimesByTask(completionRows, "completed_at")
+	freezes := make(map[string][]time.Time)
+	for _, row := range freezeRows {
+		if t, err := time.ParseInLocation("2006-01-02", rowString(row, "frozen_on"), now.Location()); err == nil {
+			freezes[rowString(row, "task_id")] = append(freezes[rowString(row, "task_id")], t)
+		}
+	}
+
+	habits := make([]gin.H, 0)
+	for _, task := range tasks {
+		frequency := rowString(task, "recurring_frequency")
+		if frequency == "" {
+			continue
+		}
+		taskID := rowString(task, "id")
+		start, ok := rowTime(task, "created_at")
+		if !ok {
+			start = now
+		}
+
+		result := streaks.Compute(streaks.ParsePeriod(frequency), start, now, completions[taskID], freezes[taskID], rules)
+		for _, day := range result.NewFreezes {
+			if err := h.supabaseClient.RecordStreakFreeze(userID, taskID, day); err != nil {
+				utils.LoggerFromContext(c.Request.Context()).Error("Failed to record streak freeze", err, map[string]interface{}{"task_id": taskID})
+				continue
+			}
+			publishEvent(events.Event{
+				Type:       events.TypeStreakFreezeConsumed,
+				UserID:     userID,
+				EntityType: AuditEntityTask,
+				EntityID:   taskID,
+				Data: map[string]interface{}{
+					"title":     rowString(task, "title"),
+					"frozen_on": day.Format("2006-01-02"),
+				},
+			})
+		}
+
+		habits = append(habits, gin.H{
+			"task_id":      taskID,
+			"title":        rowString(task, "title"),
+			"frequency":    frequency,
+			"current":      result.Current,
+			"longest":      result.Longest,
+			"misses":       formatDays(result.Misses),
+			"freezes_used": formatDays(result.FreezesUsed),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "habits": habits})
+}
+
+// GetRules returns the user's grace rules
+// GET /api/streaks/rules
+func (h *StreakHandler) GetRules(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	rules, err := h.rulesFor(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateRules replaces the user's grace rules
+// PUT /api/streaks/rules
+func (h *StreakHandler) UpdateRules(c *gin.Context) {
+	var req streaks.GraceRules
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.FreezesPerWeek < 0 || req.FreezesPerWeek > 7 {
+		c.Error(utils.ErrBadRequest("freezes_per_week must be between 0 and 7"))
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	if _, err := h.supabaseClient.UpsertGraceRules(userID, map[string]interface{}{
+		"freezes_per_week": req.FreezesPerWeek,
+		"weekend_exempt":   req.WeekendExempt,
+		"updated_at":       time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+func groupTimesByTask(rows []map[string]interface{}, field string) map[string][]time.Time {
+	grouped := make(map[string][]time.Time)
+	for _, row := range rows {
+		if t, ok := rowTime(row, field); ok {
+			grouped[rowString(row, "task_id")] = append(grouped[rowString(row, "task_id")], t)
+		}
+	}
+	return grouped
+}
+
+func formatDays(days []time.Time) []string {
+	out := make([]string, len(days))
+	for i, d := range days {
+		out[i] = d.Format("2006-01-02")
+	}
+	return out
+}
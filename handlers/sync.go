@@ -0,0 +1,194 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// SyncLag holds back changes this recent from a sync, so a write whose
+// transaction began earlier but commits later is not stamped behind a
+// cursor already handed out
+const SyncLag = 5 * time.Second
+
+// SyncTombstoneRetention is how long hard deletions are remembered for sync.
+// A cursor older than this may have missed some and gets 410, telling the
+// client to sync from scratch.
+const SyncTombstoneRetention = 90 * 24 * time.Hour
+
+const (
+	defaultSyncLimit = 200
+	maxSyncLimit     = 1000
+)
+
+// SyncHandler serves incremental sync of tasks and goals
+type SyncHandler struct {
+	supabaseClient *db.SupabaseClient
+	tasks          *TaskService
+	goals          *GoalService
+}
+
+// NewSyncHandler creates a new sync handler
+func NewSyncHandler(supabaseURL, supabaseKey string) *SyncHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &SyncHandler{
+		supabaseClient: client,
+		tasks:          NewTaskService(client),
+		goals:          NewGoalService(client),
+	}
+}
+
+// syncCursor is where a client's last sync stopped in each stream, opaque to
+// the client
+type syncCursor struct {
+	Tasks    db.SyncPosition `json:"tasks"`
+	Goals    db.SyncPosition `json:"goals"`
+	Deleted  db.SyncPosition `json:"deleted"`
+	IssuedAt int64           `json:"iat"`
+}
+
+func (cur syncCursor) encode() string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSyncCursor(s string) (syncCursor, bool) {
+	var cur syncCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &cur) != nil || cur.IssuedAt == 0 {
+		return syncCursor{}, false
+	}
+	return cur, true
+}
+
+// SyncChanges is what changed in one kind of item since the cursor: items
+// created or updated, with every field, and the IDs of those deleted or
+// moved to the trash
+type SyncChanges struct {
+	Created []map[string]interface{} `json:"created"`
+	Updated []map[string]interface{} `json:"updated"`
+	Deleted []string                 `json:"deleted"`
+}
+
+func newSyncChanges() *SyncChanges {
+	return &SyncChanges{Created: []map[string]interface{}{}, Updated: []map[string]interface{}{}, Deleted: []string{}}
+}
+
+// Sync returns the caller's tasks and goals created, updated and deleted
+// since a cursor from an earlier sync, and the cursor to pass next time.
+// Without one it returns everything the caller has. has_more means a page
+// filled up and the client should call again straight away.
+// GET /api/sync?since=<cursor>&limit=N
+func (h *SyncHandler) Sync(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrUnauthorized("sign in to sync"))
+		return
+	}
+	limit := defaultSyncLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSyncLimit {
+			c.Error(utils.ErrBadRequest("limit must be between 1 and " + strconv.Itoa(maxSyncLimit)))
+			return
+		}
+		limit = n
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-SyncLag)
+	var since syncCursor
+	initial := c.Query("since") == ""
+	if initial {
+		// A first sync has nothing to delete, so deletions start from here
+		since.Deleted = db.SyncPosition{At: cutoff.UTC().Format(time.RFC3339Nano), ID: "0"}
+	} else {
+		cur, ok := decodeSyncCursor(c.Query("since"))
+		if !ok {
+			c.Error(utils.ErrBadRequest("since is not a cursor from this API"))
+			return
+		}
+		if now.Sub(time.Unix(cur.IssuedAt, 0)) > SyncTombstoneRetention {
+			c.Error(utils.ErrGone("cursor has expired; sync again without since"))
+			return
+		}
+		since = cur
+	}
+
+	store := h.supabaseClient.WithContext(c.Request.Context())
+	next := since
+	next.IssuedAt = now.Unix()
+	hasMore := false
+
+	tasks, goals := newSyncChanges(), newSyncChanges()
+	streams := []struct {
+		fetch   func(string, db.SyncPosition, time.Time, int) ([]map[string]interface{}, error)
+		pos     *db.SyncPosition
+		changes *SyncChanges
+	}{
+		{store.ChangedTasks, &next.Tasks, tasks},
+		{store.ChangedGoals, &next.Goals, goals},
+	}
+	for _, stream := range streams {
+		from := *stream.pos
+		rows, err := stream.fetch(userID, from, cutoff, limit+1)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if len(rows) > limit {
+			rows, hasMore = rows[:limit], true
+		}
+		sinceAt, err := time.Parse(time.RFC3339, from.At)
+		sinceOK := err == nil
+		for _, row := range rows {
+			*stream.pos = db.SyncPosition{At: rowString(row, "updated_at"), ID: rowString(row, "id")}
+			switch created, ok := rowTime(row, "created_at"); {
+			case row["deleted_at"] != nil:
+				if !initial {
+					stream.changes.Deleted = append(stream.changes.Deleted, rowString(row, "id"))
+				}
+			case !sinceOK || (ok && created.After(sinceAt)):
+				stream.changes.Created = append(stream.changes.Created, row)
+			default:
+				stream.changes.Updated = append(stream.changes.Updated, row)
+			}
+		}
+	}
+
+	tombstones, err := store.SyncTombstones(userID, since.Deleted, cutoff, limit+1)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if len(tombstones) > limit {
+		tombstones, hasMore = tombstones[:limit], true
+	}
+	for _, row := range tombstones {
+		next.Deleted = db.SyncPosition{At: rowString(row, "deleted_at"), ID: strconv.Itoa(rowInt(row, "id"))}
+		switch rowString(row, "entity_type") {
+		case "task":
+			tasks.Deleted = append(tasks.Deleted, rowString(row, "entity_id"))
+		case "goal":
+			goals.Deleted = append(goals.Deleted, rowString(row, "entity_id"))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":    tasks,
+		"goals":    goals,
+		"cursor":   next.encode(),
+		"has_more": hasMore,
+	})
+}
@@ -0,0 +1,285 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// fakeSyncSupabase serves rows as the sync queries page them: u1's rows
+// after the position in the or filter, ordered and limited as asked
+func fakeSyncSupabase(t *testing.T, tables map[string][]map[string]interface{}) *db.SupabaseClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		table := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		column := "updated_at"
+		if table == "sync_tombstones" {
+			column = "deleted_at"
+		}
+		if q.Get("user_id") != "eq.u1" || !strings.HasPrefix(q.Get(column), "lt.") {
+			t.Errorf("unexpected query %s", r.URL)
+		}
+		if want := column + ".asc,id.asc"; q.Get("order") != want {
+			t.Errorf("order %q, want %q", q.Get("order"), want)
+		}
+		// (column.gt."T",and(column.eq."T",id.gt."I"))
+		var afterAt, afterID string
+		if or := q.Get("or"); or != "" {
+			parts := strings.Split(or, `"`)
+			afterAt, afterID = parts[1], parts[5]
+		}
+		rows := []map[string]interface{}{}
+		for _, row := range tables[table] {
+			at, id := row[column].(string), rowString(row, "id")
+			if table == "sync_tombstones" {
+				id = strconv.Itoa(rowInt(row, "id"))
+			}
+			if afterAt == "" || at > afterAt || (at == afterAt && id > afterID) {
+				rows = append(rows, row)
+			}
+		}
+		if limit, _ := strconv.Atoi(q.Get("limit")); len(rows) > limit {
+			rows = rows[:limit]
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := db.NewSupabaseClient(srv.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+type syncResponse struct {
+	Tasks   SyncChanges `json:"tasks"`
+	Goals   SyncChanges `json:"goals"`
+	Cursor  string      `json:"cursor"`
+	HasMore bool        `json:"has_more"`
+}
+
+func TestSync(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tables := map[string][]map[string]interface{}{
+		"tasks": {
+			{"id": "t1", "user_id": "u1", "created_at": "2026-01-01T00:00:00Z", "updated_at": "2026-01-01T00:00:00.5+00:00"},
+			{"id": "t2", "user_id": "u1", "created_at": "2026-01-01T00:00:00Z", "updated_at": "2026-01-02T00:00:00+00:00", "deleted_at": "2026-01-02T00:00:00Z"},
+		},
+		"goals": {
+			{"id": "g1", "user_id": "u1", "created_at": "2026-01-01T00:00:00Z", "updated_at": "2026-01-01T00:00:00+00:00"},
+		},
+	}
+	h := &SyncHandler{supabaseClient: fakeSyncSupabase(t, tables)}
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(utils.NewLogger()))
+	router.GET("/api/sync", func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set("user_id", user)
+		}
+		h.Sync(c)
+	})
+
+	sync := func(query string) (int, syncResponse) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/sync"+query, nil)
+		req.Header.Set("X-Test-User", "u1")
+		router.ServeHTTP(w, req)
+		var resp syncResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	code, first := sync("")
+	if code != http.StatusOK {
+		t.Fatalf("first sync: %d", code)
+	}
+	if len(first.Tasks.Created) != 1 || first.Tasks.Created[0]["id"] != "t1" || len(first.Tasks.Deleted) != 0 {
+		t.Errorf("first sync tasks %+v, want t1 created and the trashed t2 left out", first.Tasks)
+	}
+	if len(first.Goals.Created) != 1 || first.HasMore {
+		t.Errorf("first sync goals %+v, has_more %v", first.Goals, first.HasMore)
+	}
+
+	// t1 is edited, t3 is created, t2 is purged from the trash and g1 is
+	// handed to someone else
+	tables["tasks"] = []map[string]interface{}{
+		{"id": "t1", "user_id": "u1", "created_at": "2026-01-01T00:00:00Z", "updated_at": "2026-01-03T00:00:00+00:00"},
+		{"id": "t3", "user_id": "u1", "created_at": "2026-01-03T00:00:00Z", "updated_at": "2026-01-03T00:00:00+00:00"},
+	}
+	tables["goals"] = nil
+	deletedAt := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339Nano)
+	tables["sync_tombstones"] = []map[string]interface{}{
+		{"id": 7, "user_id": "u1", "entity_type": "task", "entity_id": "t2", "deleted_at": deletedAt},
+		{"id": 8, "user_id": "u1", "entity_type": "goal", "entity_id": "g1", "deleted_at": deletedAt},
+	}
+	// The first sync started deletions from its own time, so these predate it
+	code, second := sync("?since=" + first.Cursor)
+	if code != http.StatusOK {
+		t.Fatalf("second sync: %d", code)
+	}
+	if len(second.Tasks.Updated) != 1 || second.Tasks.Updated[0]["id"] != "t1" ||
+		len(second.Tasks.Created) != 1 || second.Tasks.Created[0]["id"] != "t3" {
+		t.Errorf("second sync tasks %+v, want t1 updated and t3 created", second.Tasks)
+	}
+	if len(second.Tasks.Deleted) != 0 || len(second.Goals.Deleted) != 0 {
+		t.Errorf("second sync reported deletions from before the first: %+v %+v", second.Tasks, second.Goals)
+	}
+
+	cur, _ := decodeSyncCursor(second.Cursor)
+	cur.Deleted = db.SyncPosition{At: "2000-01-01T00:00:00Z", ID: "0"}
+	code, third := sync("?since=" + cur.encode())
+	if code != http.StatusOK {
+		t.Fatalf("third sync: %d", code)
+	}
+	if len(third.Tasks.Created)+len(third.Tasks.Updated) != 0 {
+		t.Errorf("third sync repeated tasks %+v", third.Tasks)
+	}
+	if len(third.Tasks.Deleted) != 1 || third.Tasks.Deleted[0] != "t2" || len(third.Goals.Deleted) != 1 || third.Goals.Deleted[0] != "g1" {
+		t.Errorf("third sync deletions %+v %+v, want t2 and g1", third.Tasks, third.Goals)
+	}
+	if next, _ := decodeSyncCursor(third.Cursor); next.Deleted.ID != "8" {
+		t.Errorf("cursor stopped at tombstone %q, want 8", next.Deleted.ID)
+	}
+
+	if code, paged := sync("?limit=1"); code != http.StatusOK || !paged.HasMore || len(paged.Tasks.Created) != 1 {
+		t.Errorf("limit=1: %d %+v", code, paged)
+	}
+
+	old := syncCursor{IssuedAt: time.Now().Add(-SyncTombstoneRetention - time.Hour).Unix()}
+	if code, _ := sync("?since=" + old.encode()); code != http.StatusGone {
+		t.Errorf("expired cursor: %d, want 410", code)
+	}
+	if code, _ := sync("?since=garbage"); code != http.StatusBadRequest {
+		t.Errorf("garbage cursor: %d, want 400", code)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/sync", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous sync: %d, want 401", w.Code)
+	}
+}
+
+func TestResolveSyncFields(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	current := map[string]interface{}{
+		"title":       "Server title",
+		"priority":    float64(3),
+		"description": "Call the bank",
+		"completed":   true,
+		"due_date":    "2026-03-05T09:00:00+00:00",
+		"field_updated_at": map[string]interface{}{
+			"title":       "2026-03-01T12:30:00.123+00:00",
+			"priority":    "2026-03-01T13:30:00+00:00",
+			"description": "2026-03-01T12:10:00+00:00",
+			"completed":   "2026-03-01T12:10:00+00:00",
+			"due_date":    "2026-03-01T12:10:00+00:00",
+			"category":    "2026-02-28T00:00:00+00:00",
+		},
+	}
+	fields := map[string]interface{}{
+		"title":       "Client title",           // changed on both; the client's change is newer
+		"priority":    float64(1),               // changed on both; the server's change is newer
+		"description": "Ask about the mortgage", // notes edited on both are merged
+		"completed":   false,                    // a completion on the server stays
+		"due_date":    "2026-03-05T09:00:00Z",   // changed to the same instant on both
+		"category":    "home",                   // changed on the server before base
+		"context":     "@phone",                 // never changed on the server
+	}
+	changedAt := time.Date(2026, 3, 1, 13, 0, 0, 0, time.UTC)
+
+	resolved, conflicts := resolveSyncFields(fields, current, base, changedAt)
+
+	wantResolved := map[string]interface{}{
+		"title":       "Client title",
+		"description": "Call the bank\n\nAsk about the mortgage",
+		"completed":   true,
+		"due_date":    "2026-03-05T09:00:00Z",
+		"category":    "home",
+		"context":     "@phone",
+	}
+	if len(resolved) != len(wantResolved) {
+		t.Errorf("resolved %v, want %v", resolved, wantResolved)
+	}
+	for name, want := range wantResolved {
+		if resolved[name] != want {
+			t.Errorf("%s resolved to %v, want %v", name, resolved[name], want)
+		}
+	}
+
+	wantConflicts := map[string]string{
+		"title":       ResolutionClient,
+		"priority":    ResolutionServer,
+		"description": ResolutionMerged,
+		"completed":   ResolutionMerged,
+	}
+	if len(conflicts) != len(wantConflicts) {
+		t.Errorf("conflicts %+v", conflicts)
+	}
+	for _, conflict := range conflicts {
+		if wantConflicts[conflict.Field] != conflict.Resolution {
+			t.Errorf("%s resolved by %s, want %s", conflict.Field, conflict.Resolution, wantConflicts[conflict.Field])
+		}
+	}
+}
+
+func TestSyncUploadRejects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &SyncHandler{supabaseClient: fakeSyncSupabase(t, nil)}
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(utils.NewLogger()))
+	router.POST("/api/sync", func(c *gin.Context) {
+		c.Set("user_id", "u1")
+		h.Upload(c)
+	})
+
+	body := `{"changes": [
+		{"type": "note", "op": "create", "id": "6f1c2a9e-1d7b-4c55-9a57-2f0a4f0c1e11"},
+		{"type": "task", "op": "create", "id": "temp-1"},
+		{"type": "task", "op": "update", "id": "6f1c2a9e-1d7b-4c55-9a57-2f0a4f0c1e11", "fields": {"title": "x"}},
+		{"type": "task", "op": "update", "id": "6f1c2a9e-1d7b-4c55-9a57-2f0a4f0c1e11", "base_updated_at": "2026-03-01T12:00:00Z", "fields": {"user_id": "u2"}},
+		{"type": "goal", "op": "archive", "id": "6f1c2a9e-1d7b-4c55-9a57-2f0a4f0c1e11"}
+	]}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/sync", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload: %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Results []SyncResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	wantErrors := []string{
+		"type must be task or goal",
+		"id must be a UUID",
+		"base_updated_at is required to update",
+		"user_id cannot be synced",
+		"op must be create, update or delete",
+	}
+	if len(resp.Results) != len(wantErrors) {
+		t.Fatalf("results %+v", resp.Results)
+	}
+	for i, result := range resp.Results {
+		if result.Index != i || result.Status != SyncRejected || result.Error != wantErrors[i] {
+			t.Errorf("result %d: %+v, want rejected with %q", i, result, wantErrors[i])
+		}
+	}
+}
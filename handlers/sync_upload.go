@@ -0,0 +1,385 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// What became of an uploaded change
+const (
+	SyncApplied  = "applied"  // written as sent
+	SyncResolved = "resolved" // in conflict with the server; row is what was kept
+	SyncRejected = "rejected" // not written; error says why
+)
+
+// How a conflicting field was settled
+const (
+	ResolutionClient = "client" // the client's value was newer
+	ResolutionServer = "server" // the server's value was newer, or the client's could not be applied
+	ResolutionMerged = "merged" // the values were combined
+)
+
+// maxSyncChanges bounds how many changes one upload may carry
+const maxSyncChanges = 500
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// SyncChange is a change a client made to a task or goal, possibly offline
+type SyncChange struct {
+	Type string `json:"type"` // task or goal
+	Op   string `json:"op"`   // create, update or delete
+	// ID is chosen by the client when creating, so an upload retried after a
+	// lost response does not create the item twice
+	ID string `json:"id"`
+	// BaseUpdatedAt is the updated_at of the copy the client changed; fields
+	// changed on the server since are in conflict. Required for updates.
+	BaseUpdatedAt *time.Time `json:"base_updated_at"`
+	// ChangedAt is when the change was made on the device, which settles
+	// conflicts by last writer wins. Missing or in the future, it is the
+	// time of the upload.
+	ChangedAt *time.Time             `json:"changed_at"`
+	Fields    map[string]interface{} `json:"fields"`
+}
+
+// SyncUploadRequest is a batch of changes, applied in order
+type SyncUploadRequest struct {
+	Changes []SyncChange `json:"changes" binding:"required"`
+}
+
+// SyncConflict is a field changed both by the client and on the server since
+// the copy the client changed
+type SyncConflict struct {
+	Field      string      `json:"field"`
+	Client     interface{} `json:"client"`
+	Server     interface{} `json:"server"`
+	Resolution string      `json:"resolution"`
+	Value      interface{} `json:"value"` // what the item holds now
+}
+
+// SyncResult is what became of one uploaded change
+type SyncResult struct {
+	Index     int                    `json:"index"`
+	Type      string                 `json:"type"`
+	Op        string                 `json:"op"`
+	ID        string                 `json:"id"`
+	Status    string                 `json:"status"`
+	Conflicts []SyncConflict         `json:"conflicts,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Errors    validation.Errors      `json:"errors,omitempty"` // the fields a rejected change got wrong
+	Row       map[string]interface{} `json:"row,omitempty"`    // the item as stored now, absent once deleted
+}
+
+// syncKind is how uploaded changes are applied to one type of item
+type syncKind struct {
+	fields map[string]bool // the fields an update may set
+	get    func(client *db.SupabaseClient, id string) (map[string]interface{}, error)
+	create func(c *gin.Context, userID, id string, fields []byte) (map[string]interface{}, error)
+	update func(c *gin.Context, userID, id string, fields []byte) (map[string]interface{}, error)
+	delete func(c *gin.Context, userID, id string) error
+}
+
+func (h *SyncHandler) kinds() map[string]syncKind {
+	return map[string]syncKind{
+		"task": {
+			fields: jsonFields(models.UpdateTaskRequest{}),
+			get:    (*db.SupabaseClient).GetTask,
+			create: func(c *gin.Context, userID, id string, fields []byte) (map[string]interface{}, error) {
+				var req models.CreateTaskRequest
+				if err := validation.DecodeJSON(bytes.NewReader(fields), &req); err != nil {
+					return nil, err
+				}
+				created, err := h.tasks.CreateWithID(c, userID, id, req)
+				if err != nil {
+					return nil, err
+				}
+				return created.Task, nil
+			},
+			update: func(c *gin.Context, userID, id string, fields []byte) (map[string]interface{}, error) {
+				var req models.UpdateTaskRequest
+				if err := validation.DecodeJSON(bytes.NewReader(fields), &req); err != nil {
+					return nil, err
+				}
+				return h.tasks.Update(c, userID, id, req)
+			},
+			delete: h.tasks.Delete,
+		},
+		"goal": {
+			fields: jsonFields(models.UpdateGoalRequest{}),
+			get:    (*db.SupabaseClient).GetGoal,
+			create: func(c *gin.Context, userID, id string, fields []byte) (map[string]interface{}, error) {
+				var req models.CreateGoalRequest
+				if err := validation.DecodeJSON(bytes.NewReader(fields), &req); err != nil {
+					return nil, err
+				}
+				created, err := h.goals.CreateWithID(c, userID, id, req)
+				if err != nil {
+					return nil, err
+				}
+				return created.Goal, nil
+			},
+			update: func(c *gin.Context, userID, id string, fields []byte) (map[string]interface{}, error) {
+				var req models.UpdateGoalRequest
+				if err := validation.DecodeJSON(bytes.NewReader(fields), &req); err != nil {
+					return nil, err
+				}
+				return h.goals.Update(c, userID, id, req)
+			},
+			delete: h.goals.Delete,
+		},
+	}
+}
+
+// jsonFields returns the JSON names of a request struct's fields
+func jsonFields(req interface{}) map[string]bool {
+	t := reflect.TypeOf(req)
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// syncMergeRules combine a field changed both by the client and on the
+// server instead of keeping the newer value
+var syncMergeRules = map[string]func(server, client interface{}) interface{}{
+	// Notes edited on both sides keep both edits
+	"description": func(server, client interface{}) interface{} {
+		s, _ := server.(string)
+		c, _ := client.(string)
+		switch {
+		case strings.Contains(c, s):
+			return c
+		case strings.Contains(s, c):
+			return s
+		}
+		return s + "\n\n" + c
+	},
+	// A task checked off on either side stays done
+	"completed": func(server, client interface{}) interface{} {
+		return server == true || client == true
+	},
+}
+
+// Upload applies changes a client made, possibly offline, to the caller's
+// tasks and goals, in order. Each change is applied, resolved or rejected on
+// its own; a field changed on the server since the copy the client changed
+// is settled by syncMergeRules or else by last writer wins, and reported.
+// POST /api/sync
+func (h *SyncHandler) Upload(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrUnauthorized("sign in to sync"))
+		return
+	}
+	var req SyncUploadRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if len(req.Changes) > maxSyncChanges {
+		respondValidationError(c, validation.Errors{{Field: "changes", Code: validation.CodeOutOfRange,
+			Message: "at most 500 changes may be uploaded at once"}})
+		return
+	}
+
+	kinds := h.kinds()
+	results := make([]SyncResult, len(req.Changes))
+	for i, change := range req.Changes {
+		results[i] = h.apply(c, kinds, userID, change)
+		results[i].Index = i
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+func (h *SyncHandler) apply(c *gin.Context, kinds map[string]syncKind, userID string, change SyncChange) SyncResult {
+	result := SyncResult{Type: change.Type, Op: change.Op, ID: change.ID}
+	kind, ok := kinds[change.Type]
+	if !ok {
+		return result.reject("type must be task or goal")
+	}
+	if !uuidPattern.MatchString(change.ID) {
+		return result.reject("id must be a UUID")
+	}
+	changedAt := time.Now()
+	if change.ChangedAt != nil && change.ChangedAt.Before(changedAt) {
+		changedAt = *change.ChangedAt
+	}
+	store := h.supabaseClient.WithContext(c.Request.Context())
+
+	switch change.Op {
+	case "create":
+		// A create seen before is an upload retried after a lost response
+		if row, err := kind.get(store, change.ID); err == nil {
+			if err := checkRowAccess(store, userID, row, change.Type, models.RoleEditor); err != nil {
+				return result.fail(c.Request.Context(), err)
+			}
+			result.Status, result.Row = SyncApplied, row
+			return result
+		}
+		fields := change.Fields
+		// A subtask of a task deleted meanwhile is kept, as a task of its own
+		if parentID, _ := fields["parent_id"].(string); parentID != "" && change.Type == "task" {
+			if _, err := store.GetTask(parentID); err != nil {
+				fields = mergeRow(fields, map[string]interface{}{"parent_id": ""})
+				result.Conflicts = append(result.Conflicts, SyncConflict{Field: "parent_id", Client: parentID, Resolution: ResolutionServer, Value: nil})
+			}
+		}
+		body, _ := json.Marshal(fields)
+		row, err := kind.create(c, userID, change.ID, body)
+		if err != nil {
+			return result.fail(c.Request.Context(), err)
+		}
+		result.Status, result.Row = syncStatus(result.Conflicts), row
+		return result
+
+	case "update":
+		if change.BaseUpdatedAt == nil {
+			return result.reject("base_updated_at is required to update")
+		}
+		for field := range change.Fields {
+			if !kind.fields[field] {
+				return result.reject(field + " cannot be synced")
+			}
+		}
+		current, err := kind.get(store, change.ID)
+		if err != nil {
+			return result.reject(change.Type + " was deleted")
+		}
+		if err := checkRowAccess(store, userID, current, change.Type, models.RoleEditor); err != nil {
+			return result.fail(c.Request.Context(), err)
+		}
+		fields, conflicts := resolveSyncFields(change.Fields, current, *change.BaseUpdatedAt, changedAt)
+		result.Conflicts = conflicts
+		row := current
+		if len(fields) > 0 {
+			body, _ := json.Marshal(fields)
+			if row, err = kind.update(c, userID, change.ID, body); err != nil {
+				return result.fail(c.Request.Context(), err)
+			}
+		}
+		result.Status, result.Row = syncStatus(conflicts), row
+		return result
+
+	case "delete":
+		current, err := kind.get(store, change.ID)
+		if err != nil {
+			// Already deleted, here or by an earlier upload
+			result.Status = SyncApplied
+			return result
+		}
+		// An edit on the server newer than the deletion keeps the item
+		if updatedAt, ok := rowTime(current, "updated_at"); ok && change.BaseUpdatedAt != nil &&
+			updatedAt.After(*change.BaseUpdatedAt) && updatedAt.After(changedAt) {
+			result.Status, result.Row = SyncResolved, current
+			result.Conflicts = []SyncConflict{{Field: "deleted_at", Client: changedAt.UTC().Format(time.RFC3339), Resolution: ResolutionServer}}
+			return result
+		}
+		if err := kind.delete(c, userID, change.ID); err != nil {
+			return result.fail(c.Request.Context(), err)
+		}
+		result.Status = SyncApplied
+		return result
+	}
+	return result.reject("op must be create, update or delete")
+}
+
+// resolveSyncFields returns the fields to write for an update the client
+// based on a copy updated at base, and the conflicts settled on the way. A
+// field is in conflict when the server changed it to a different value
+// since base.
+func resolveSyncFields(fields, current map[string]interface{}, base, changedAt time.Time) (map[string]interface{}, []SyncConflict) {
+	versions, _ := current["field_updated_at"].(map[string]interface{})
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := make(map[string]interface{}, len(fields))
+	var conflicts []SyncConflict
+	for _, name := range names {
+		value := fields[name]
+		serverAt, ok := rowTime(versions, name)
+		if !ok || !serverAt.After(base) || sameSyncValue(current[name], value) {
+			resolved[name] = value
+			continue
+		}
+		conflict := SyncConflict{Field: name, Client: value, Server: current[name]}
+		switch merge, ok := syncMergeRules[name]; {
+		case ok:
+			conflict.Resolution, conflict.Value = ResolutionMerged, merge(current[name], value)
+		case changedAt.After(serverAt):
+			conflict.Resolution, conflict.Value = ResolutionClient, value
+		default:
+			conflict.Resolution, conflict.Value = ResolutionServer, current[name]
+		}
+		if conflict.Resolution != ResolutionServer {
+			resolved[name] = conflict.Value
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return resolved, conflicts
+}
+
+// sameSyncValue compares a stored value with an uploaded one, timestamps by
+// the instant they name
+func sameSyncValue(stored, uploaded interface{}) bool {
+	if s, ok := stored.(string); ok {
+		if u, ok := uploaded.(string); ok {
+			st, err1 := time.Parse(time.RFC3339, s)
+			ut, err2 := time.Parse(time.RFC3339, u)
+			if err1 == nil && err2 == nil {
+				return st.Equal(ut)
+			}
+		}
+	}
+	return reflect.DeepEqual(stored, uploaded)
+}
+
+func syncStatus(conflicts []SyncConflict) string {
+	if len(conflicts) > 0 {
+		return SyncResolved
+	}
+	return SyncApplied
+}
+
+func (r SyncResult) reject(message string) SyncResult {
+	r.Status, r.Error = SyncRejected, message
+	return r
+}
+
+// fail rejects the change for err, which is reported as is only when it is
+// meant for the client
+func (r SyncResult) fail(ctx context.Context, err error) SyncResult {
+	var errs validation.Errors
+	var appErr *utils.AppError
+	switch {
+	case errors.As(err, &errs):
+		r = r.reject("validation failed")
+		r.Errors = errs
+	case errors.As(err, &appErr) && appErr.HTTPStatus < http.StatusInternalServerError:
+		r = r.reject(appErr.Message)
+	default:
+		utils.LoggerFromContext(ctx).Error("Sync upload failed", err, map[string]interface{}{"type": r.Type, "entity_id": r.ID})
+		r = r.reject("could not be saved; retry later")
+	}
+	return r
+}
@@ -2,16 +2,17 @@ package handlers
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/productivity/mcp-server/db"
 	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
 )
 
 // TaskHandler handles task-related requests
 type TaskHandler struct {
 	supabaseClient *db.SupabaseClient
+	service        *TaskService
 }
 
 // NewTaskHandler creates a new task handler
@@ -22,121 +23,65 @@ func NewTaskHandler(supabaseURL, supabaseKey string) *TaskHandler {
 	}
 	return &TaskHandler{
 		supabaseClient: client,
+		service:        NewTaskService(client),
 	}
 }
 
-// getUserID gets user ID from context, query param, or header
-func getUserID(c *gin.Context) string {
-	// Try context first (set by auth middleware if present)
-	if userID := c.GetString("user_id"); userID != "" {
-		return userID
-	}
-	// Try query parameter
-	if userID := c.Query("user_id"); userID != "" {
-		return userID
-	}
-	// Try header
-	return c.GetHeader("X-User-ID")
-}
-
 // CreateTask creates a new task
 func (h *TaskHandler) CreateTask(c *gin.Context) {
 	var req models.CreateTaskRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Validate required fields
-	if req.Title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
-		return
-	}
-
-	// Validate priority range (assuming 1-5 scale)
-	if req.Priority < 1 || req.Priority > 5 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must be between 1 and 5"})
-		return
-	}
-
-	// Validate due date is in the future (optional check)
-	if req.DueDate.Before(time.Now()) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "due_date must be in the future"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	userID := getUserID(c)
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required (provide via query param ?user_id=xxx, header X-User-ID, or context)"})
+	created, err := h.service.Create(c, getUserID(c), req)
+	if err != nil {
+		respondServiceError(c, err)
 		return
 	}
 
-	// Convert request to map for Supabase
-	taskData := map[string]interface{}{
-		"title":              req.Title,
-		"description":        req.Description,
-		"priority":           req.Priority,
-		"due_date":           req.DueDate.Format(time.RFC3339),
-		"estimated_duration": req.EstimatedDuration,
-		"category":           req.Category,
-		"completed":          false,
-		"created_at":         time.Now().Format(time.RFC3339),
-		"updated_at":         time.Now().Format(time.RFC3339),
-	}
-
-	if req.RecurringFrequency != "" {
-		taskData["recurring_frequency"] = req.RecurringFrequency
-		taskData["recurring_interval"] = req.RecurringInterval
-		if req.RecurringEndDate != nil {
-			taskData["recurring_end_date"] = req.RecurringEndDate.Format(time.RFC3339)
+	if created.Task == nil {
+		resp := gin.H{"id": created.ID, "message": "Task created but could not fetch details"}
+		if created.Notice != "" {
+			resp["notice"] = created.Notice
 		}
-	}
-
-	taskID, err := h.supabaseClient.CreateTask(userID, taskData)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusCreated, resp)
 		return
 	}
 
-	// Fetch the created task
-	taskMap, err := h.supabaseClient.GetTask(taskID)
-	if err != nil {
-		c.JSON(http.StatusCreated, gin.H{"id": taskID, "message": "Task created but could not fetch details"})
-		return
+	if created.Notice != "" {
+		created.Task["notice"] = created.Notice
 	}
-
-	c.JSON(http.StatusCreated, taskMap)
+	if created.Warning != nil {
+		created.Task["capacity_warning"] = created.Warning
+	}
+	c.JSON(http.StatusCreated, created.Task)
 }
 
-// ListTasks lists all tasks
+// ListTasks lists the user's tasks and those shared in their workspaces
 func (h *TaskHandler) ListTasks(c *gin.Context) {
-	userID := getUserID(c)
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id required"})
+	tasks, ok := listVisible(c, h.supabaseClient, h.supabaseClient.GetUserTasks, h.supabaseClient.GetWorkspaceTasks)
+	if !ok {
 		return
 	}
 
-	tasks, err := h.supabaseClient.GetUserTasks(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, filterByContext(c, filterByLanguage(c, tasks)))
 }
 
 // GetTask gets a specific task
 func (h *TaskHandler) GetTask(c *gin.Context) {
 	taskID := c.Param("id")
 	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "task id is required"})
+		c.Error(utils.ErrBadRequest("task id is required"))
 		return
 	}
 
 	task, err := h.supabaseClient.GetTask(taskID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(lookupError("task", err))
+		return
+	}
+	if !authorizeRow(c, h.supabaseClient, task, "task", models.RoleViewer) {
 		return
 	}
 
@@ -147,108 +92,61 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	taskID := c.Param("id")
 	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "task id is required"})
+		c.Error(utils.ErrBadRequest("task id is required"))
 		return
 	}
 
 	var req models.UpdateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	// Validate priority range if provided
-	if req.Priority != nil && (*req.Priority < 1 || *req.Priority > 5) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must be between 1 and 5"})
+	task, err := h.service.Update(c, getUserID(c), taskID, req)
+	if err != nil {
+		respondServiceError(c, err)
 		return
 	}
-
-	// Build update map from non-nil fields
-	updateData := map[string]interface{}{
-		"updated_at": time.Now().Format(time.RFC3339),
-	}
-
-	if req.Title != nil {
-		updateData["title"] = *req.Title
-	}
-	if req.Description != nil {
-		updateData["description"] = *req.Description
-	}
-	if req.Priority != nil {
-		updateData["priority"] = *req.Priority
-	}
-	if req.DueDate != nil {
-		updateData["due_date"] = req.DueDate.Format(time.RFC3339)
-	}
-	if req.EstimatedDuration != nil {
-		updateData["estimated_duration"] = *req.EstimatedDuration
-	}
-	if req.Category != nil {
-		updateData["category"] = *req.Category
-	}
-	if req.Completed != nil {
-		updateData["completed"] = *req.Completed
-		if *req.Completed {
-			now := time.Now()
-			updateData["completed_at"] = now.Format(time.RFC3339)
-		} else {
-			updateData["completed_at"] = nil
+	if req.DueDate != nil || req.EstimatedDuration != nil {
+		if warning := h.service.CapacityWarning(c, getUserID(c), task); warning != nil {
+			task["capacity_warning"] = warning
 		}
 	}
-	if req.RecurringFrequency != nil {
-		updateData["recurring_frequency"] = *req.RecurringFrequency
-	}
-	if req.RecurringInterval != nil {
-		updateData["recurring_interval"] = *req.RecurringInterval
-	}
-	if req.RecurringEndDate != nil {
-		updateData["recurring_end_date"] = req.RecurringEndDate.Format(time.RFC3339)
-	}
-
-	if err := h.supabaseClient.UpdateTask(taskID, updateData); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Fetch updated task
-	task, err := h.supabaseClient.GetTask(taskID)
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"id": taskID, "updated": true})
-		return
-	}
-
 	c.JSON(http.StatusOK, task)
 }
 
-// DeleteTask deletes a task
+// DeleteTask moves a task to the trash
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	taskID := c.Param("id")
 	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "task id is required"})
+		c.Error(utils.ErrBadRequest("task id is required"))
 		return
 	}
 
-	if err := h.supabaseClient.DeleteTask(taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Delete(c, getUserID(c), taskID); err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"id": taskID, "deleted": true})
+	c.JSON(http.StatusOK, gin.H{"id": taskID, "deleted": true, "trashed": true})
 }
 
 // GetUserTasks gets all tasks for a user
 func (h *TaskHandler) GetUserTasks(c *gin.Context) {
 	userID := c.Param("userId")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id parameter required"})
+		c.Error(utils.ErrBadRequest("user_id parameter required"))
+		return
+	}
+	if err := checkOwnUser(c, userID, "tasks"); err != nil {
+		c.Error(err)
 		return
 	}
 
 	tasks, err := h.supabaseClient.GetUserTasks(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, filterByContext(c, filterByLanguage(c, tasks)))
 }
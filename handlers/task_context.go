@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// taskContextPattern is a normalized context: @ and then up to 32 lowercase
+// letters, digits, dashes or underscores
+var taskContextPattern = regexp.MustCompile(`^@[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// normalizeContext lowercases a context and adds the leading @ if missing,
+// so "Home", "@home" and " @HOME " are the same context; "" stays ""
+func normalizeContext(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || strings.HasPrefix(s, "@") {
+		return s
+	}
+	return "@" + s
+}
+
+// validateContext checks a normalized context, which may be empty
+func validateContext(v *validation.Validator, taskContext string) {
+	if taskContext != "" {
+		v.Check(taskContextPattern.MatchString(taskContext), "context", validation.CodeInvalidFormat,
+			"context must be a short name such as @home or @errands, using letters, digits, - and _")
+	}
+}
+
+// filterByContext keeps the rows matching ?context=, if given
+func filterByContext(c *gin.Context, rows []map[string]interface{}) []map[string]interface{} {
+	taskContext := normalizeContext(c.Query("context"))
+	if taskContext == "" {
+		return rows
+	}
+	filtered := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		if rowString(row, "context") == taskContext {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered
+}
+
+// ForContext returns userID's open tasks in a context, soonest due first,
+// along with the contexts their other open tasks use
+func (s *TaskService) ForContext(c *gin.Context, userID, taskContext string) (*models.ContextTasks, error) {
+	taskContext = normalizeContext(taskContext)
+	var v validation.Validator
+	v.Required("context", taskContext)
+	validateContext(&v, taskContext)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+
+	tasks, err := s.supabaseClient.WithContext(c.Request.Context()).GetOpenDatedTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.ContextTasks{Context: taskContext, Tasks: []map[string]interface{}{}, Available: []string{}}
+	seen := map[string]bool{}
+	for _, task := range tasks {
+		other := rowString(task, "context")
+		if other == taskContext {
+			result.Tasks = append(result.Tasks, task)
+		}
+		if other != "" && !seen[other] {
+			seen[other] = true
+			result.Available = append(result.Available, other)
+		}
+	}
+	sort.Strings(result.Available)
+	result.Count = len(result.Tasks)
+	return result, nil
+}
+
+// ContextTasks lists the open tasks that can be done in a context
+// GET /api/tasks/context/:context
+func (h *TaskHandler) ContextTasks(c *gin.Context) {
+	result, err := h.service.ForContext(c, getUserID(c), c.Param("context"))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
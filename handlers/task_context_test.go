@@ -0,0 +1,69 @@
+//go:build lite
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/validation"
+)
+
+func TestTasksForContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, err := db.NewSupabaseClient(db.SQLiteScheme+filepath.Join(t.TempDir(), "context.db"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewTaskService(client)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+	due := time.Now().Add(24 * time.Hour)
+	create := func(title, taskContext string) string {
+		t.Helper()
+		created, err := service.Create(c, "u1", models.CreateTaskRequest{Title: title, Priority: 3, DueDate: due, Context: taskContext})
+		if err != nil {
+			t.Fatal(err)
+		}
+		due = due.Add(time.Hour)
+		return created.ID
+	}
+	create("buy stamps", "Errands")
+	create("water plants", "@home")
+	pickUpID := create("pick up dry cleaning", " @ERRANDS ")
+	create("plan sprint", "")
+
+	result, err := service.ForContext(c, "u1", "errands")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Context != "@errands" || result.Count != 2 ||
+		rowString(result.Tasks[0], "title") != "buy stamps" || rowString(result.Tasks[1], "title") != "pick up dry cleaning" {
+		t.Errorf("errands: %+v", result)
+	}
+	if len(result.Available) != 2 || result.Available[0] != "@errands" || result.Available[1] != "@home" {
+		t.Errorf("available contexts: %v", result.Available)
+	}
+
+	// Clearing the context takes the task out of it
+	cleared := ""
+	if _, err := service.Update(c, "u1", pickUpID, models.UpdateTaskRequest{Context: &cleared}); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := service.ForContext(c, "u1", "@errands"); err != nil || result.Count != 1 {
+		t.Errorf("after clearing: %+v, %v", result, err)
+	}
+
+	if _, err := service.Create(c, "u1", models.CreateTaskRequest{Title: "x", Priority: 3, DueDate: due, Context: "at the office"}); err == nil {
+		t.Error("context with spaces: expected an error")
+	} else if _, ok := err.(validation.Errors); !ok {
+		t.Errorf("context with spaces: expected validation errors, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import "time"
+
+// Helpers for reading fields out of the loosely typed rows returned by Supabase
+
+func rowString(row map[string]interface{}, key string) string {
+	s, _ := row[key].(string)
+	return s
+}
+
+func rowInt(row map[string]interface{}, key string) int {
+	switch v := row[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+func rowBool(row map[string]interface{}, key string) bool {
+	b, _ := row[key].(bool)
+	return b
+}
+
+// rowStrings reads a text[] column, skipping non-string elements
+func rowStrings(row map[string]interface{}, key string) []string {
+	items, _ := row[key].([]interface{})
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// rowTime parses an RFC3339 timestamp column, reporting false when missing or malformed
+func rowTime(row map[string]interface{}, key string) (time.Time, bool) {
+	s, ok := row[key].(string)
+	if !ok || s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// startOfDay returns midnight of t's day in t's location
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// TaskService implements task operations shared by the REST handlers and the
+// MCP tools. Errors are validation.Errors for bad input, otherwise AppErrors.
+type TaskService struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewTaskService creates a task service on an existing Supabase client
+func NewTaskService(supabaseClient *db.SupabaseClient) *TaskService {
+	return &TaskService{supabaseClient: supabaseClient}
+}
+
+// CreatedTask is the result of TaskService.Create
+type CreatedTask struct {
+	ID string
+	// Task is the stored row, or nil if it was created but could not be read back
+	Task map[string]interface{}
+	// Notice is set when the focus contract deferred the task to the Inbox
+	Notice string
+	// Warning is set when the task overbooks its due day
+	Warning *models.CapacityWarning
+}
+
+// taskCreate is a validated task creation, ready to write
+type taskCreate struct {
+	client *db.SupabaseClient
+	data   map[string]interface{}
+	notice string
+}
+
+// Create validates req and creates the task for userID; c is used for the audit trail
+func (s *TaskService) Create(c *gin.Context, userID string, req models.CreateTaskRequest) (*CreatedTask, error) {
+	return s.create(c, userID, "", req)
+}
+
+// CreateWithID is Create for a task whose ID the client chose, such as one
+// created offline
+func (s *TaskService) CreateWithID(c *gin.Context, userID, taskID string, req models.CreateTaskRequest) (*CreatedTask, error) {
+	return s.create(c, userID, taskID, req)
+}
+
+func (s *TaskService) create(c *gin.Context, userID, taskID string, req models.CreateTaskRequest) (*CreatedTask, error) {
+	plan, err := s.planCreate(c, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	client, taskData := plan.client, plan.data
+	if taskID != "" {
+		taskData["id"] = taskID
+	}
+
+	taskID, err = client.CreateTask(userID, taskData)
+	if err != nil {
+		return nil, err
+	}
+	created := &CreatedTask{ID: taskID, Notice: plan.notice}
+
+	// Fetch the created task
+	taskMap, err := client.GetTask(taskID)
+	if err != nil {
+		recordAudit(c, AuditEntityTask, taskID, AuditActionCreate, nil, taskData)
+		return created, nil
+	}
+
+	recordAudit(c, AuditEntityTask, taskID, AuditActionCreate, nil, taskMap)
+	created.Task = taskMap
+	created.Warning = s.CapacityWarning(c, userID, taskMap)
+	return created, nil
+}
+
+// PreviewCreate validates req and returns the task Create would store, without storing it
+func (s *TaskService) PreviewCreate(c *gin.Context, userID string, req models.CreateTaskRequest) (*ChangePreview, error) {
+	plan, err := s.planCreate(c, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	after := mergeRow(plan.data, map[string]interface{}{"user_id": userID})
+	preview := newChangePreview(AuditEntityTask, "", AuditActionCreate, nil, after)
+	preview.Notice = plan.notice
+	return preview, nil
+}
+
+func (s *TaskService) planCreate(c *gin.Context, userID string, req models.CreateTaskRequest) (*taskCreate, error) {
+	var v validation.Validator
+	validateTitle(&v, req.Title)
+	v.MaxLength("description", req.Description, validation.MaxDescriptionLength)
+	v.Range("priority", req.Priority, validation.MinPriority, validation.MaxPriority)
+	v.Check(!req.DueDate.Before(time.Now()), "due_date", validation.CodeOutOfRange, "due_date must be in the future")
+	validateLanguage(&v, req.Language)
+	taskContext := normalizeContext(req.Context)
+	validateContext(&v, taskContext)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required (provide via query param ?user_id=xxx, header X-User-ID, or context)")
+	}
+
+	// Supabase calls stop if the request is abandoned; the audit entry is
+	// written with its own client either way
+	client := s.supabaseClient.WithContext(c.Request.Context())
+
+	if req.WorkspaceID != "" {
+		if err := checkWorkspaceRole(client, req.WorkspaceID, userID, models.RoleEditor, "workspace"); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkTaskLimit(c, userID, 1); err != nil {
+		return nil, err
+	}
+
+	// Convert request to map for Supabase
+	taskData := map[string]interface{}{
+		"title":              req.Title,
+		"description":        req.Description,
+		"priority":           req.Priority,
+		"due_date":           req.DueDate.Format(time.RFC3339),
+		"estimated_duration": req.EstimatedDuration,
+		"category":           req.Category,
+		"completed":          false,
+		"created_at":         time.Now().Format(time.RFC3339),
+		"updated_at":         time.Now().Format(time.RFC3339),
+	}
+
+	setLanguage(taskData, entityLanguage(req.Language, req.Title, req.Description))
+	if taskContext != "" {
+		taskData["context"] = taskContext
+	}
+	if req.WorkspaceID != "" {
+		taskData["workspace_id"] = req.WorkspaceID
+	}
+	if req.ParentID != "" {
+		parent, err := client.GetTask(req.ParentID)
+		if err != nil {
+			return nil, lookupError("parent task", err)
+		}
+		if err := checkRowAccess(client, userID, parent, "task", models.RoleEditor); err != nil {
+			return nil, err
+		}
+		taskData["parent_id"] = req.ParentID
+	}
+
+	if req.RecurringFrequency != "" {
+		taskData["recurring_frequency"] = req.RecurringFrequency
+		taskData["recurring_interval"] = req.RecurringInterval
+		if req.RecurringEndDate != nil {
+			taskData["recurring_end_date"] = req.RecurringEndDate.Format(time.RFC3339)
+		}
+	}
+
+	notice := applyFocusContract(c.Request.Context(), client, userID, taskData)
+	return &taskCreate{client: client, data: taskData, notice: notice}, nil
+}
+
+// taskUpdate is a validated, authorized task update, ready to write
+type taskUpdate struct {
+	client *db.SupabaseClient
+	before map[string]interface{}
+	data   map[string]interface{}
+}
+
+// Update validates req and applies it to the task as userID, who needs the
+// editor role on shared tasks. It returns the updated row, or just the id if
+// the row could not be read back.
+func (s *TaskService) Update(c *gin.Context, userID, taskID string, req models.UpdateTaskRequest) (map[string]interface{}, error) {
+	plan, err := s.planUpdate(c, userID, taskID, req)
+	if err != nil {
+		return nil, err
+	}
+	client, before, updateData := plan.client, plan.before, plan.data
+
+	if err := client.UpdateTask(taskID, updateData); err != nil {
+		return nil, err
+	}
+
+	// Fetch updated task
+	task, err := client.GetTask(taskID)
+	if err != nil {
+		recordAudit(c, AuditEntityTask, taskID, AuditActionUpdate, before, updateData)
+		return map[string]interface{}{"id": taskID, "updated": true}, nil
+	}
+
+	recordAudit(c, AuditEntityTask, taskID, AuditActionUpdate, before, task)
+	recordTaskCompletion(c.Request.Context(), s.supabaseClient, before, task)
+	return task, nil
+}
+
+// PreviewUpdate validates req and returns the change Update would make, without making it
+func (s *TaskService) PreviewUpdate(c *gin.Context, userID, taskID string, req models.UpdateTaskRequest) (*ChangePreview, error) {
+	plan, err := s.planUpdate(c, userID, taskID, req)
+	if err != nil {
+		return nil, err
+	}
+	return newChangePreview(AuditEntityTask, taskID, AuditActionUpdate, plan.before, mergeRow(plan.before, plan.data)), nil
+}
+
+func (s *TaskService) planUpdate(c *gin.Context, userID, taskID string, req models.UpdateTaskRequest) (*taskUpdate, error) {
+	// Validate only the fields being changed
+	var v validation.Validator
+	if req.Title != nil {
+		validateTitle(&v, *req.Title)
+	}
+	if req.Description != nil {
+		v.MaxLength("description", *req.Description, validation.MaxDescriptionLength)
+	}
+	if req.Priority != nil {
+		v.Range("priority", *req.Priority, validation.MinPriority, validation.MaxPriority)
+	}
+	if req.Language != nil {
+		validateLanguage(&v, *req.Language)
+	}
+	if req.Context != nil {
+		validateContext(&v, normalizeContext(*req.Context))
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	// Build update map from non-nil fields
+	updateData := map[string]interface{}{
+		"updated_at": time.Now().Format(time.RFC3339),
+	}
+
+	if req.Title != nil {
+		updateData["title"] = *req.Title
+	}
+	if req.Description != nil {
+		updateData["description"] = *req.Description
+	}
+	if req.Priority != nil {
+		updateData["priority"] = *req.Priority
+	}
+	if req.DueDate != nil {
+		updateData["due_date"] = req.DueDate.Format(time.RFC3339)
+	}
+	if req.EstimatedDuration != nil {
+		updateData["estimated_duration"] = *req.EstimatedDuration
+	}
+	if req.Category != nil {
+		updateData["category"] = *req.Category
+	}
+	if req.Context != nil {
+		updateData["context"] = nullIfEmpty(normalizeContext(*req.Context))
+	}
+	if req.Completed != nil {
+		updateData["completed"] = *req.Completed
+		if *req.Completed {
+			now := time.Now()
+			updateData["completed_at"] = now.Format(time.RFC3339)
+		} else {
+			updateData["completed_at"] = nil
+		}
+	}
+	if req.RecurringFrequency != nil {
+		updateData["recurring_frequency"] = *req.RecurringFrequency
+	}
+	if req.RecurringInterval != nil {
+		updateData["recurring_interval"] = *req.RecurringInterval
+	}
+	if req.RecurringEndDate != nil {
+		updateData["recurring_end_date"] = req.RecurringEndDate.Format(time.RFC3339)
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	before, err := client.GetTask(taskID)
+	if err != nil {
+		return nil, lookupError("task", err)
+	}
+	if err := checkRowAccess(client, userID, before, "task", models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	if code, changed := updatedLanguage(req.Language, req.Title, req.Description, before); changed {
+		updateData["language"] = nullIfEmpty(code)
+	}
+	return &taskUpdate{client: client, before: before, data: updateData}, nil
+}
+
+// Delete moves the task to the trash as userID, who needs the editor role on shared tasks
+func (s *TaskService) Delete(c *gin.Context, userID, taskID string) error {
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	before, err := s.planDelete(client, userID, taskID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.SoftDeleteTask(taskID); err != nil {
+		return err
+	}
+
+	recordAudit(c, AuditEntityTask, taskID, AuditActionDelete, before, nil)
+	return nil
+}
+
+// PreviewDelete returns the task Delete would move to the trash, without moving it
+func (s *TaskService) PreviewDelete(c *gin.Context, userID, taskID string) (*ChangePreview, error) {
+	before, err := s.planDelete(s.supabaseClient.WithContext(c.Request.Context()), userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return newChangePreview(AuditEntityTask, taskID, AuditActionDelete, before, nil), nil
+}
+
+// planDelete returns the task to delete once userID is allowed to
+func (s *TaskService) planDelete(client *db.SupabaseClient, userID, taskID string) (map[string]interface{}, error) {
+	before, err := client.GetTask(taskID)
+	if err != nil {
+		return nil, lookupError("task", err)
+	}
+	if err := checkRowAccess(client, userID, before, "task", models.RoleEditor); err != nil {
+		return nil, err
+	}
+	return before, nil
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// Task views. Together they split open dated tasks by day: overdue before
+// today, then today, then upcoming days.
+const (
+	TaskViewOverdue  = "overdue"
+	TaskViewToday    = "today"
+	TaskViewUpcoming = "upcoming"
+)
+
+const (
+	// DefaultUpcomingDays is how far ahead the upcoming view looks by default
+	DefaultUpcomingDays = 7
+	// MaxUpcomingDays bounds the upcoming view's days
+	MaxUpcomingDays = 90
+)
+
+// taskViewWindow returns the due times a view covers as [from, until); from
+// is zero for overdue. Days are calendar days in now's location.
+func taskViewWindow(view string, now time.Time, days int) (from, until time.Time) {
+	today := startOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	switch view {
+	case TaskViewOverdue:
+		return time.Time{}, today
+	case TaskViewToday:
+		return today, tomorrow
+	}
+	return tomorrow, tomorrow.AddDate(0, 0, days)
+}
+
+// View returns userID's open tasks in one of the task views, computed in
+// their time zone; days is only used by the upcoming view
+func (s *TaskService) View(c *gin.Context, userID, view string, days int) (*models.TaskView, error) {
+	var v validation.Validator
+	v.Check(view == TaskViewOverdue || view == TaskViewToday || view == TaskViewUpcoming,
+		"view", validation.CodeInvalidValue, "view must be overdue, today or upcoming")
+	if view == TaskViewUpcoming {
+		v.Range("days", days, 1, MaxUpcomingDays)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	now, _, err := userNow(client, userID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := client.GetOpenDatedTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, until := taskViewWindow(view, now, days)
+	result := &models.TaskView{
+		View:     view,
+		Timezone: now.Location().String(),
+		Until:    until,
+		Tasks:    []map[string]interface{}{},
+	}
+	if !from.IsZero() {
+		result.From = &from
+	}
+	for _, task := range tasks {
+		due, ok := rowTime(task, "due_date")
+		if !ok || due.Before(from) || !due.Before(until) {
+			continue
+		}
+		result.Tasks = append(result.Tasks, task)
+	}
+	result.Count = len(result.Tasks)
+	return result, nil
+}
+
+// respondTaskView answers with one of the task views
+func (h *TaskHandler) respondTaskView(c *gin.Context, view string, days int) {
+	result, err := h.service.View(c, getUserID(c), view, days)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// OverdueTasks lists open tasks due before today
+// GET /api/tasks/overdue
+func (h *TaskHandler) OverdueTasks(c *gin.Context) {
+	h.respondTaskView(c, TaskViewOverdue, 0)
+}
+
+// TodayTasks lists open tasks due today
+// GET /api/tasks/today
+func (h *TaskHandler) TodayTasks(c *gin.Context) {
+	h.respondTaskView(c, TaskViewToday, 0)
+}
+
+// UpcomingTasks lists open tasks due after today, within the next days days
+// GET /api/tasks/upcoming?days=7
+func (h *TaskHandler) UpcomingTasks(c *gin.Context) {
+	days := DefaultUpcomingDays
+	if raw := c.Query("days"); raw != "" {
+		// A non-number fails the service's range check
+		days, _ = strconv.Atoi(raw)
+	}
+	h.respondTaskView(c, TaskViewUpcoming, days)
+}
@@ -0,0 +1,93 @@
+//go:build lite
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/validation"
+)
+
+func TestTaskViewsFollowTheUsersDay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbURL := db.SQLiteScheme + filepath.Join(t.TempDir(), "views.db")
+	client, err := db.NewSupabaseClient(dbURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UpsertPreferences("u1", map[string]interface{}{"timezone": "Pacific/Kiritimati"}); err != nil {
+		t.Fatal(err)
+	}
+	// UTC+14, so the user's today is rarely the server's
+	loc, _ := loadTimezone("Pacific/Kiritimati")
+	today := startOfDay(time.Now().In(loc))
+
+	add := func(title string, due time.Time, completed bool) {
+		t.Helper()
+		if _, err := client.CreateTask("u1", map[string]interface{}{
+			"title":     title,
+			"due_date":  due.UTC().Format(time.RFC3339),
+			"completed": completed,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("late", today.Add(-time.Hour), false)
+	add("done late", today.Add(-time.Hour), true)
+	add("early today", today.Add(time.Minute), false)
+	add("tonight", today.Add(23*time.Hour), false)
+	add("in two days", today.AddDate(0, 0, 2).Add(9*time.Hour), false)
+	add("in ten days", today.AddDate(0, 0, 10), false)
+
+	service := NewTaskService(client)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	cases := []struct {
+		view   string
+		days   int
+		titles []string
+	}{
+		{TaskViewOverdue, 0, []string{"late"}},
+		{TaskViewToday, 0, []string{"early today", "tonight"}},
+		{TaskViewUpcoming, 7, []string{"in two days"}},
+		{TaskViewUpcoming, 14, []string{"in two days", "in ten days"}},
+	}
+	for _, tc := range cases {
+		view, err := service.View(c, "u1", tc.view, tc.days)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.view, err)
+		}
+		var titles []string
+		for _, task := range view.Tasks {
+			titles = append(titles, rowString(task, "title"))
+		}
+		if len(titles) != len(tc.titles) || view.Count != len(tc.titles) {
+			t.Errorf("%s %d: got %v, want %v", tc.view, tc.days, titles, tc.titles)
+			continue
+		}
+		for i := range titles {
+			if titles[i] != tc.titles[i] {
+				t.Errorf("%s %d: got %v, want %v", tc.view, tc.days, titles, tc.titles)
+				break
+			}
+		}
+		if view.Timezone != "Pacific/Kiritimati" {
+			t.Errorf("%s: timezone %q", tc.view, view.Timezone)
+		}
+	}
+
+	for _, days := range []int{0, MaxUpcomingDays + 1} {
+		if _, err := service.View(c, "u1", TaskViewUpcoming, days); err == nil {
+			t.Errorf("days %d: expected a validation error", days)
+		} else if _, ok := err.(validation.Errors); !ok {
+			t.Errorf("days %d: expected validation errors, got %v", days, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// TrashRetention is how long trashed tasks and goals are kept before being purged
+const TrashRetention = 30 * 24 * time.Hour
+
+// TrashHandler handles listing, restoring and purging soft-deleted items
+type TrashHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewTrashHandler creates a new trash handler
+func NewTrashHandler(supabaseURL, supabaseKey string) *TrashHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &TrashHandler{
+		supabaseClient: client,
+	}
+}
+
+// ListTrash lists the user's trashed tasks and goals
+// GET /api/trash
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	tasks, err := h.supabaseClient.GetDeletedTasks(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	goals, err := h.supabaseClient.GetDeletedGoals(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":          tasks,
+		"goals":          goals,
+		"retention_days": int(TrashRetention.Hours() / 24),
+	})
+}
+
+// RestoreTask restores a trashed task
+// POST /api/trash/tasks/:id/restore
+func (h *TrashHandler) RestoreTask(c *gin.Context) {
+	h.restore(c, AuditEntityTask, h.supabaseClient.RestoreTask)
+}
+
+// RestoreGoal restores a trashed goal
+// POST /api/trash/goals/:id/restore
+func (h *TrashHandler) RestoreGoal(c *gin.Context) {
+	h.restore(c, AuditEntityGoal, h.supabaseClient.RestoreGoal)
+}
+
+func (h *TrashHandler) restore(c *gin.Context, kind string, restoreFn func(userID, id string) (map[string]interface{}, error)) {
+	id := c.Param("id")
+	if id == "" {
+		c.Error(utils.ErrBadRequest(kind + " id is required"))
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	restored, err := restoreFn(userID, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if restored == nil {
+		c.Error(utils.NewAppError(utils.ErrCodeNotFound, kind+" not found in trash", http.StatusNotFound))
+		return
+	}
+
+	recordAudit(c, kind, id, AuditActionRestore, nil, restored)
+	c.JSON(http.StatusOK, restored)
+}
+
+// RunPurge permanently deletes items trashed longer than TrashRetention,
+// once at startup and then on every interval until ctx is cancelled
+func (h *TrashHandler) RunPurge(ctx context.Context, logger *utils.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().Add(-TrashRetention)
+		if err := h.supabaseClient.PurgeDeleted(cutoff); err != nil {
+			logger.Error("Trash purge failed", err)
+		} else {
+			logger.Info("Trash purged", map[string]interface{}{"cutoff": cutoff.UTC().Format(time.RFC3339)})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
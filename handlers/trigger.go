@@ -0,0 +1,281 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// Trigger actions
+const (
+	TriggerActionCreateTask = "create_task"
+	TriggerActionStartFocus = "start_focus"
+)
+
+// TaskTemplate describes a task created by a trigger.
+// "{{value}}" in the title or description is replaced with the value sent by the caller.
+type TaskTemplate struct {
+	Title             string `json:"title"`
+	Description       string `json:"description,omitempty"`
+	Priority          int    `json:"priority,omitempty"`
+	Category          string `json:"category,omitempty"`
+	DueInHours        int    `json:"due_in_hours,omitempty"`
+	EstimatedDuration int    `json:"estimated_duration,omitempty"`
+}
+
+// TriggerDefinition maps a trigger name to a predefined action
+type TriggerDefinition struct {
+	Action       string        `json:"action"`
+	Task         *TaskTemplate `json:"task,omitempty"`
+	FocusMinutes int           `json:"focus_minutes,omitempty"`
+}
+
+// defaultTriggers are available when TRIGGERS_FILE is not set
+var defaultTriggers = map[string]*TriggerDefinition{
+	"quick-task": {
+		Action: TriggerActionCreateTask,
+		Task: &TaskTemplate{
+			Title:      "{{value}}",
+			Priority:   3,
+			Category:   "inbox",
+			DueInHours: 24,
+		},
+	},
+	"focus": {
+		Action:       TriggerActionStartFocus,
+		FocusMinutes: DefaultFocusMinutes,
+	},
+}
+
+// TriggerHandler handles simple token-authenticated triggers for automations
+// (Home Assistant, IFTTT, physical buttons) that cannot do the OAuth dance
+type TriggerHandler struct {
+	supabaseClient *db.SupabaseClient
+	triggers       map[string]*TriggerDefinition
+	tokens         map[string]string // token -> user_id
+}
+
+// NewTriggerHandler creates a new trigger handler.
+// tokens is a TRIGGER_TOKENS list ("token:user_id,token2:user_id2") and definitions
+// come from the JSON file at triggersFile, falling back to defaultTriggers.
+func NewTriggerHandler(supabaseURL, supabaseKey, tokens, triggersFile string) *TriggerHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+
+	triggers := defaultTriggers
+	if triggersFile != "" {
+		loaded, err := loadTriggerDefinitions(triggersFile)
+		if err != nil {
+			panic(err)
+		}
+		triggers = loaded
+	}
+
+	return &TriggerHandler{
+		supabaseClient: client,
+		triggers:       triggers,
+		tokens:         parseTriggerTokens(tokens),
+	}
+}
+
+func loadTriggerDefinitions(path string) (map[string]*TriggerDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read triggers file: %w", err)
+	}
+
+	var triggers map[string]*TriggerDefinition
+	if err := json.Unmarshal(data, &triggers); err != nil {
+		return nil, fmt.Errorf("failed to parse triggers file: %w", err)
+	}
+
+	for name, def := range triggers {
+		switch def.Action {
+		case TriggerActionCreateTask:
+			if def.Task == nil || def.Task.Title == "" {
+				return nil, fmt.Errorf("trigger %q: create_task requires a task template with a title", name)
+			}
+		case TriggerActionStartFocus:
+			if def.FocusMinutes == 0 {
+				def.FocusMinutes = DefaultFocusMinutes
+			}
+			if def.FocusMinutes < 1 || def.FocusMinutes > 480 {
+				return nil, fmt.Errorf("trigger %q: focus_minutes must be between 1 and 480", name)
+			}
+		default:
+			return nil, fmt.Errorf("trigger %q: unknown action %q", name, def.Action)
+		}
+	}
+
+	return triggers, nil
+}
+
+func parseTriggerTokens(raw string) map[string]string {
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+			tokens[parts[0]] = parts[1]
+		}
+	}
+	return tokens
+}
+
+// userForToken returns the user bound to a trigger token
+func (h *TriggerHandler) userForToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	for t, userID := range h.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return userID, true
+		}
+	}
+	return "", false
+}
+
+// TriggerRequest is the optional payload sent with a trigger
+type TriggerRequest struct {
+	Value   string `json:"value" form:"value"`
+	Value1  string `json:"value1" form:"value1"` // IFTTT webhook ingredient
+	Minutes int    `json:"minutes" form:"minutes"`
+}
+
+// FireTrigger runs the action mapped to a named trigger
+// POST /api/triggers/:name
+func (h *TriggerHandler) FireTrigger(c *gin.Context) {
+	// Tokens in query strings end up in proxy and access logs, so only the
+	// header is accepted
+	userID, ok := h.userForToken(c.GetHeader("X-Trigger-Token"))
+	if !ok {
+		c.Error(utils.ErrUnauthorized("invalid or missing trigger token"))
+		return
+	}
+
+	name := c.Param("name")
+	def, ok := h.triggers[name]
+	if !ok {
+		c.Error(utils.NewAppError(utils.ErrCodeNotFound, fmt.Sprintf("unknown trigger: %s", name), http.StatusNotFound))
+		return
+	}
+
+	var req TriggerRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBind(&req); err != nil {
+			c.Error(utils.ErrValidation(err.Error()))
+			return
+		}
+	}
+	value := req.Value
+	if value == "" {
+		value = req.Value1
+	}
+	if value == "" {
+		value = c.Query("value")
+	}
+
+	switch def.Action {
+	case TriggerActionCreateTask:
+		c.Set("user_id", userID)
+		if err := checkTaskLimit(c, userID, 1); err != nil {
+			c.Error(err)
+			return
+		}
+		task, err := h.createTaskFromTemplate(userID, def.Task, value)
+		if err != nil {
+			c.Error(utils.ErrBadRequest(err.Error()))
+			return
+		}
+		recordAudit(c, AuditEntityTask, rowString(task, "id"), AuditActionCreate, nil, task)
+		c.JSON(http.StatusCreated, gin.H{"trigger": name, "action": def.Action, "task": task})
+
+	case TriggerActionStartFocus:
+		if req.Minutes < 0 || req.Minutes > 480 {
+			c.Error(utils.ErrBadRequest("minutes must be between 1 and 480"))
+			return
+		}
+		minutes := def.FocusMinutes
+		if req.Minutes > 0 {
+			minutes = req.Minutes
+		}
+		session, err := startFocusSession(h.supabaseClient, userID, minutes, "trigger:"+name)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"trigger": name, "action": def.Action, "focus_session": session})
+	}
+}
+
+func (h *TriggerHandler) createTaskFromTemplate(userID string, tmpl *TaskTemplate, value string) (map[string]interface{}, error) {
+	if strings.Contains(tmpl.Title, "{{value}}") && strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("value is required for this trigger")
+	}
+
+	priority := tmpl.Priority
+	if priority < 1 || priority > 5 {
+		priority = 3
+	}
+	dueInHours := tmpl.DueInHours
+	if dueInHours <= 0 {
+		dueInHours = 24
+	}
+	category := tmpl.Category
+	if category == "" {
+		category = "inbox"
+	}
+
+	now := time.Now()
+	title := strings.ReplaceAll(tmpl.Title, "{{value}}", value)
+	description := strings.ReplaceAll(tmpl.Description, "{{value}}", value)
+	taskData := map[string]interface{}{
+		"title":              title,
+		"description":        description,
+		"priority":           priority,
+		"due_date":           now.Add(time.Duration(dueInHours) * time.Hour).Format(time.RFC3339),
+		"estimated_duration": tmpl.EstimatedDuration,
+		"category":           category,
+		"completed":          false,
+		"created_at":         now.Format(time.RFC3339),
+		"updated_at":         now.Format(time.RFC3339),
+	}
+	setLanguage(taskData, entityLanguage("", title, description))
+
+	taskID, err := h.supabaseClient.CreateTask(userID, taskData)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := h.supabaseClient.GetTask(taskID)
+	if err != nil {
+		return map[string]interface{}{"id": taskID}, nil
+	}
+	return task, nil
+}
+
+// ListTriggers lists the configured trigger names and their actions
+// GET /api/triggers
+func (h *TriggerHandler) ListTriggers(c *gin.Context) {
+	if _, ok := h.userForToken(c.GetHeader("X-Trigger-Token")); !ok {
+		c.Error(utils.ErrUnauthorized("invalid or missing trigger token"))
+		return
+	}
+
+	triggers := make([]gin.H, 0, len(h.triggers))
+	for name, def := range h.triggers {
+		triggers = append(triggers, gin.H{"name": name, "action": def.Action})
+	}
+	c.JSON(http.StatusOK, gin.H{"triggers": triggers})
+}
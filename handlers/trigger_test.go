@@ -0,0 +1,76 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestFireTriggerRejectsBadRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &TriggerHandler{triggers: defaultTriggers, tokens: parseTriggerTokens("secret:u1")}
+
+	tests := []struct {
+		name   string
+		url    string
+		token  string
+		body   string
+		status int
+	}{
+		{"token in the query string", "/api/triggers/focus?token=secret", "", "", http.StatusUnauthorized},
+		{"too many minutes", "/api/triggers/focus", "secret", `{"minutes":100000}`, http.StatusBadRequest},
+		{"negative minutes", "/api/triggers/focus", "secret", `{"minutes":-5}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if tt.token != "" {
+			c.Request.Header.Set("X-Trigger-Token", tt.token)
+		}
+		c.Params = gin.Params{{Key: "name", Value: "focus"}}
+		h.FireTrigger(c)
+		if len(c.Errors) == 0 {
+			t.Errorf("%s: accepted", tt.name)
+			continue
+		}
+		if status := c.Errors.Last().Err.(*utils.AppError).HTTPStatus; status != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.name, status, tt.status)
+		}
+	}
+}
+
+func TestLoadTriggerDefinitionsChecksFocusMinutes(t *testing.T) {
+	load := func(body string) (map[string]*TriggerDefinition, error) {
+		path := filepath.Join(t.TempDir(), "triggers.json")
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return loadTriggerDefinitions(path)
+	}
+
+	for _, minutes := range []string{"-5", "481", "100000"} {
+		if _, err := load(`{"deep-work":{"action":"start_focus","focus_minutes":` + minutes + `}}`); err == nil {
+			t.Errorf("focus_minutes %s: accepted", minutes)
+		}
+	}
+
+	triggers, err := load(`{"deep-work":{"action":"start_focus","focus_minutes":90},"focus":{"action":"start_focus"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := triggers["deep-work"].FocusMinutes; got != 90 {
+		t.Errorf("deep-work: %d minutes, want 90", got)
+	}
+	if got := triggers["focus"].FocusMinutes; got != DefaultFocusMinutes {
+		t.Errorf("focus without focus_minutes: %d minutes, want %d", got, DefaultFocusMinutes)
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// undoLookback is how many of the caller's latest audit entries UndoLast
+// searches for one it can undo
+const undoLookback = 50
+
+// UndoService undoes task and goal actions recorded in the audit log, using
+// the entries' before-images. An undo is itself recorded, so undoing it redoes
+// the original action.
+type UndoService struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewUndoService creates an undo service on an existing Supabase client
+func NewUndoService(supabaseClient *db.SupabaseClient) *UndoService {
+	return &UndoService{supabaseClient: supabaseClient}
+}
+
+// UndoneAction is the result of an undo
+type UndoneAction struct {
+	ActionID   string `json:"action_id"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	// Action is the action that was undone
+	Action string `json:"action"`
+	// State is the item after the undo, or nil if it went to the trash
+	State map[string]interface{} `json:"state,omitempty"`
+}
+
+// undoTarget is how the undo service reads and writes one entity type
+type undoTarget struct {
+	get     func(id string) (map[string]interface{}, error)
+	update  func(id string, data map[string]interface{}) error
+	trash   func(id string) error
+	restore func(userID, id string) (map[string]interface{}, error)
+}
+
+func undoTargetFor(client *db.SupabaseClient, entityType string) (undoTarget, bool) {
+	switch entityType {
+	case AuditEntityTask:
+		return undoTarget{client.GetTask, client.UpdateTask, client.SoftDeleteTask, client.RestoreTask}, true
+	case AuditEntityGoal:
+		return undoTarget{client.GetGoal, client.UpdateGoal, client.SoftDeleteGoal, client.RestoreGoal}, true
+	}
+	return undoTarget{}, false
+}
+
+// undoable reports whether an audit entry describes an action that can be undone
+func undoable(entry map[string]interface{}) bool {
+	switch rowString(entry, "entity_type") {
+	case AuditEntityTask, AuditEntityGoal:
+	default:
+		return false
+	}
+	switch rowString(entry, "action") {
+	case AuditActionCreate, AuditActionUpdate, AuditActionDelete, AuditActionRestore:
+		return true
+	}
+	return false
+}
+
+// Undo undoes the action with the audit entry actionID. Only the user who
+// took an action can undo it.
+func (s *UndoService) Undo(c *gin.Context, userID, actionID string) (*UndoneAction, error) {
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	entry, err := client.GetAuditEntry(actionID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || rowString(entry, "actor") != userID {
+		return nil, utils.ErrNotFound("action")
+	}
+	if !undoable(entry) {
+		return nil, utils.ErrBadRequest("only task and goal changes can be undone")
+	}
+	plan, err := planUndo(client, userID, entry)
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(c, plan)
+}
+
+// UndoLast undoes the latest task or goal action userID took
+func (s *UndoService) UndoLast(c *gin.Context, userID string) (*UndoneAction, error) {
+	plan, err := s.planLast(c, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(c, plan)
+}
+
+// PreviewLast returns the change UndoLast would make, without making it
+func (s *UndoService) PreviewLast(c *gin.Context, userID string) (*ChangePreview, error) {
+	plan, err := s.planLast(c, userID)
+	if err != nil {
+		return nil, err
+	}
+	return plan.preview(), nil
+}
+
+func (s *UndoService) planLast(c *gin.Context, userID string) (*undoPlan, error) {
+	if userID == "" {
+		return nil, utils.ErrBadRequest("user_id required")
+	}
+
+	client := s.supabaseClient.WithContext(c.Request.Context())
+	entries, err := client.GetAuditLog(map[string]string{"actor": userID}, undoLookback)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if undoable(entry) {
+			return planUndo(client, userID, entry)
+		}
+	}
+	return nil, utils.ErrNotFound("action to undo")
+}
+
+// undoPlan is a checked undo, ready to apply
+type undoPlan struct {
+	target undoTarget
+	undone *UndoneAction
+	// op is the action the undo performs and records: update, restore or delete
+	op string
+	// current is the item now, or for a restore its image before it was deleted
+	current map[string]interface{}
+	// data holds the fields an update puts back
+	data map[string]interface{}
+}
+
+// planUndo checks that userID may undo the action in entry and that the item
+// is still in the state the action left it in
+func planUndo(client *db.SupabaseClient, userID string, entry map[string]interface{}) (*undoPlan, error) {
+	kind := rowString(entry, "entity_type")
+	id := rowString(entry, "entity_id")
+	target, _ := undoTargetFor(client, kind)
+	plan := &undoPlan{
+		target: target,
+		undone: &UndoneAction{
+			ActionID:   rowString(entry, "id"),
+			EntityType: kind,
+			EntityID:   id,
+			Action:     rowString(entry, "action"),
+		},
+	}
+
+	switch plan.undone.Action {
+	case AuditActionUpdate:
+		current, err := target.get(id)
+		if err != nil {
+			return nil, utils.ErrNotFound(kind).WithError(err)
+		}
+		if err := checkRowAccess(client, userID, current, kind, models.RoleEditor); err != nil {
+			return nil, err
+		}
+
+		// Put back each changed field, unless it has changed again since
+		diff, _ := entry["diff"].(map[string]interface{})
+		data := map[string]interface{}{}
+		for field, change := range diff {
+			change, _ := change.(map[string]interface{})
+			if !reflect.DeepEqual(current[field], change["to"]) {
+				return nil, utils.ErrConflict(kind + " has changed since this action; " + field + " no longer matches")
+			}
+			data[field] = change["from"]
+		}
+		plan.op, plan.current, plan.data = AuditActionUpdate, current, data
+
+	case AuditActionDelete:
+		before, _ := entry["before"].(map[string]interface{})
+		if err := checkRowAccess(client, userID, before, kind, models.RoleEditor); err != nil {
+			return nil, err
+		}
+		plan.op, plan.current = AuditActionRestore, before
+
+	case AuditActionCreate, AuditActionRestore:
+		current, err := target.get(id)
+		if err != nil {
+			return nil, utils.ErrConflict(kind + " is already in the trash or gone")
+		}
+		if err := checkRowAccess(client, userID, current, kind, models.RoleEditor); err != nil {
+			return nil, err
+		}
+		plan.op, plan.current = AuditActionDelete, current
+	}
+	return plan, nil
+}
+
+// preview describes the change the plan would make
+func (p *undoPlan) preview() *ChangePreview {
+	kind, id := p.undone.EntityType, p.undone.EntityID
+	switch p.op {
+	case AuditActionUpdate:
+		return newChangePreview(kind, id, p.op, p.current, mergeRow(p.current, p.data))
+	case AuditActionRestore:
+		return newChangePreview(kind, id, p.op, nil, p.current)
+	}
+	return newChangePreview(kind, id, p.op, p.current, nil)
+}
+
+func (s *UndoService) apply(c *gin.Context, plan *undoPlan) (*UndoneAction, error) {
+	undone := plan.undone
+	kind, id := undone.EntityType, undone.EntityID
+
+	switch plan.op {
+	case AuditActionUpdate:
+		if len(plan.data) > 0 {
+			data := mergeRow(plan.data, map[string]interface{}{"updated_at": time.Now().Format(time.RFC3339)})
+			if err := plan.target.update(id, data); err != nil {
+				return nil, err
+			}
+		}
+
+		after, err := plan.target.get(id)
+		if err != nil {
+			after = plan.data
+		}
+		recordAudit(c, kind, id, AuditActionUpdate, plan.current, after)
+		undone.State = after
+
+	case AuditActionRestore:
+		restored, err := plan.target.restore(rowString(plan.current, "user_id"), id)
+		if err != nil {
+			return nil, err
+		}
+		if restored == nil {
+			return nil, utils.ErrConflict(kind + " is no longer in the trash")
+		}
+		recordAudit(c, kind, id, AuditActionRestore, nil, restored)
+		undone.State = restored
+
+	case AuditActionDelete:
+		if err := plan.target.trash(id); err != nil {
+			return nil, err
+		}
+		recordAudit(c, kind, id, AuditActionDelete, plan.current, nil)
+	}
+
+	return undone, nil
+}
+
+// UndoHandler serves undo of actions in the audit log
+type UndoHandler struct {
+	service *UndoService
+}
+
+// NewUndoHandler creates a new undo handler
+func NewUndoHandler(supabaseURL, supabaseKey string) *UndoHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &UndoHandler{
+		service: NewUndoService(client),
+	}
+}
+
+// UndoAction undoes a task or goal action; the id is the action's audit entry
+// POST /api/actions/:id/undo
+func (h *UndoHandler) UndoAction(c *gin.Context) {
+	undone, err := h.service.Undo(c, getUserID(c), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, undone)
+}
@@ -0,0 +1,84 @@
+//go:build lite
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+)
+
+func TestUndoService(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dbURL := db.SQLiteScheme + filepath.Join(t.TempDir(), "undo.db")
+	SetAuditLog(NewAuditLog(dbURL, ""))
+	t.Cleanup(func() { SetAuditLog(nil) })
+
+	client, err := db.NewSupabaseClient(dbURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := NewTaskService(client)
+	undo := NewUndoService(client)
+	newContext := func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+		c.Set("user_id", "u1")
+		return c
+	}
+
+	created, err := tasks.Create(newContext(), "u1", models.CreateTaskRequest{
+		Title:    "Write report",
+		Priority: 2,
+		DueDate:  time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	title := "Write the final report"
+	if _, err := tasks.Update(newContext(), "u1", created.ID, models.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Undo reverts the update; undoing the undo redoes it
+	undone, err := undo.UndoLast(newContext(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undone.Action != AuditActionUpdate || undone.State["title"] != "Write report" {
+		t.Fatalf("undo update: %+v", undone)
+	}
+	redone, err := undo.UndoLast(newContext(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redone.State["title"] != title {
+		t.Fatalf("redo update: %+v", redone)
+	}
+
+	// Undoing a delete restores the task from the trash
+	if err := tasks.Delete(newContext(), "u1", created.ID); err != nil {
+		t.Fatal(err)
+	}
+	undone, err = undo.UndoLast(newContext(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if undone.Action != AuditActionDelete || undone.State["title"] != title {
+		t.Fatalf("undo delete: %+v", undone)
+	}
+	if _, err := client.GetTask(created.ID); err != nil {
+		t.Errorf("task not restored: %v", err)
+	}
+
+	// Another user cannot undo u1's actions
+	if _, err := undo.Undo(newContext(), "u2", undone.ActionID); err == nil {
+		t.Error("expected u2's undo of u1's action to fail")
+	}
+}
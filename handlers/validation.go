@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// bindJSON decodes the request body into req, responding 400 with field
+// errors on failure, or 413 when the body is over the route's limit
+func bindJSON(c *gin.Context, req interface{}) bool {
+	err := validation.DecodeJSON(c.Request.Body, req)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.Error(utils.ErrTooLarge(tooLarge.Limit))
+		return false
+	}
+	if err != nil {
+		respondValidationError(c, err)
+		return false
+	}
+	return true
+}
+
+// respondValidationError reports a 400 VALIDATION_ERROR carrying {"errors": [{field, code, message}]}
+func respondValidationError(c *gin.Context, err error) {
+	errs, ok := err.(validation.Errors)
+	if !ok {
+		errs = validation.Errors{{Field: "body", Code: validation.CodeInvalidValue, Message: err.Error()}}
+	}
+	c.Error(utils.ErrValidation("request validation failed").WithField("errors", errs))
+}
+
+// respondServiceError reports an error from a service: field errors as a 400
+// VALIDATION_ERROR, anything else through the error middleware
+func respondServiceError(c *gin.Context, err error) {
+	if errs, ok := err.(validation.Errors); ok {
+		respondValidationError(c, errs)
+		return
+	}
+	c.Error(err)
+}
+
+// lookupError reports err, from looking up resource, as 404 when the
+// resource does not exist, and as it is otherwise, so that the database
+// being unavailable is not mistaken for a missing row
+func lookupError(resource string, err error) error {
+	if errors.Is(err, db.ErrNotFound) {
+		return utils.ErrNotFound(resource).WithError(err)
+	}
+	return err
+}
+
+// clientMessage is err as a client may see it: the message of an AppError,
+// and what a failed database request attempted, without the database's
+// details
+func clientMessage(err error) string {
+	var appErr *utils.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Message
+	}
+	var pgErr *db.PostgRESTError
+	if errors.As(err, &pgErr) {
+		return "failed to " + pgErr.Op
+	}
+	return err.Error()
+}
+
+// validateTitle checks that a task or goal title is present and not too long
+func validateTitle(v *validation.Validator, title string) {
+	v.Required("title", title)
+	v.MaxLength("title", title, validation.MaxTitleLength)
+}
@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/validation"
+)
+
+// WorkspaceInvitePrefix starts every invite token so leaked tokens are easy to recognise
+const WorkspaceInvitePrefix = "pmcp_inv_"
+
+// WorkspaceInviteExpiration is how long an invite can be accepted
+const WorkspaceInviteExpiration = 7 * 24 * time.Hour
+
+// roleRank orders workspace roles so requiring a role also admits the more privileged ones
+var roleRank = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleOwner:  3,
+}
+
+// WorkspaceHandler manages workspaces, their members and invites
+type WorkspaceHandler struct {
+	supabaseClient *db.SupabaseClient
+}
+
+// NewWorkspaceHandler creates a new workspace handler
+func NewWorkspaceHandler(supabaseURL, supabaseKey string) *WorkspaceHandler {
+	client, err := db.NewSupabaseClient(supabaseURL, supabaseKey)
+	if err != nil {
+		panic(err)
+	}
+	return &WorkspaceHandler{
+		supabaseClient: client,
+	}
+}
+
+// checkWorkspaceRole returns the error to report when userID does not hold at
+// least role in workspaceID: not found for non-members, so outsiders cannot
+// probe for workspace IDs, and forbidden for members with a lesser role
+func checkWorkspaceRole(client *db.SupabaseClient, workspaceID, userID, role, resource string) error {
+	if userID == "" {
+		return utils.ErrUnauthorized("shared " + resource + "s require a signed-in user")
+	}
+	member, err := client.GetWorkspaceMember(workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return utils.ErrNotFound(resource)
+	}
+	if roleRank[rowString(member, "role")] < roleRank[role] {
+		return utils.ErrForbidden(fmt.Sprintf("requires the %s role in this workspace", role))
+	}
+	return nil
+}
+
+// authorizeRow checks that the requesting user may act on a task or goal row
+// with at least role. Shared rows need a workspace membership and personal
+// rows their owner; either way the caller must be known.
+func authorizeRow(c *gin.Context, client *db.SupabaseClient, row map[string]interface{}, resource, role string) bool {
+	if err := checkRowAccess(client, getUserID(c), row, resource, role); err != nil {
+		c.Error(err)
+		return false
+	}
+	return true
+}
+
+// checkRowAccess is authorizeRow for services: it returns the failure instead
+// of reporting it. Another user's personal row is not found rather than
+// forbidden, so its ID cannot be probed.
+func checkRowAccess(client *db.SupabaseClient, userID string, row map[string]interface{}, resource, role string) error {
+	workspaceID := rowString(row, "workspace_id")
+	if workspaceID == "" {
+		if userID == "" {
+			return utils.ErrUnauthorized(resource + "s require a signed-in user")
+		}
+		if rowString(row, "user_id") != userID {
+			return utils.ErrNotFound(resource)
+		}
+		return nil
+	}
+	return checkWorkspaceRole(client, workspaceID, userID, role, resource)
+}
+
+// visibleRows lists the user's personal rows and the rows shared in their
+// workspaces, newest first. Rows the user created in a workspace they have
+// since left are dropped.
+func visibleRows(client *db.SupabaseClient, userID string,
+	personal func(string) ([]map[string]interface{}, error),
+	shared func([]string) ([]map[string]interface{}, error)) ([]map[string]interface{}, error) {
+	memberships, err := client.ListUserMemberships(userID)
+	if err != nil {
+		return nil, err
+	}
+	workspaceIDs := make([]string, 0, len(memberships))
+	isMember := map[string]bool{}
+	for _, m := range memberships {
+		id := rowString(m, "workspace_id")
+		workspaceIDs = append(workspaceIDs, id)
+		isMember[id] = true
+	}
+
+	own, err := personal(userID)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]interface{}, 0, len(own))
+	seen := map[string]bool{}
+	for _, row := range own {
+		if ws := rowString(row, "workspace_id"); ws == "" || isMember[ws] {
+			rows = append(rows, row)
+			seen[rowString(row, "id")] = true
+		}
+	}
+	if len(workspaceIDs) == 0 {
+		return rows, nil
+	}
+
+	sharedRows, err := shared(workspaceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range sharedRows {
+		if !seen[rowString(row, "id")] {
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rowString(rows[i], "created_at") > rowString(rows[j], "created_at")
+	})
+	return rows, nil
+}
+
+// listVisible lists what a list endpoint should return for the requesting
+// user: every visible row, or only the rows shared in ?workspace_id=
+func listVisible(c *gin.Context, client *db.SupabaseClient,
+	personal func(string) ([]map[string]interface{}, error),
+	shared func([]string) ([]map[string]interface{}, error)) ([]map[string]interface{}, bool) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return nil, false
+	}
+
+	var rows []map[string]interface{}
+	var err error
+	if workspaceID := c.Query("workspace_id"); workspaceID != "" {
+		if err := checkWorkspaceRole(client, workspaceID, userID, models.RoleViewer, "workspace"); err != nil {
+			c.Error(err)
+			return nil, false
+		}
+		rows, err = shared([]string{workspaceID})
+	} else {
+		rows, err = visibleRows(client, userID, personal, shared)
+	}
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+	return rows, true
+}
+
+// CreateWorkspace creates a workspace owned by the requesting user
+// POST /api/workspaces
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	var req models.CreateWorkspaceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var v validation.Validator
+	v.Required("name", req.Name)
+	v.MaxLength("name", req.Name, 100)
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	workspace, err := h.supabaseClient.CreateWorkspace(userID, map[string]interface{}{
+		"name":       strings.TrimSpace(req.Name),
+		"created_at": now,
+		"updated_at": now,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	recordAudit(c, AuditEntityWorkspace, rowString(workspace, "id"), AuditActionCreate, nil, workspace)
+	workspace["role"] = models.RoleOwner
+	c.JSON(http.StatusCreated, workspace)
+}
+
+// ListWorkspaces lists the workspaces the requesting user belongs to, with their role
+// GET /api/workspaces
+func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	memberships, err := h.supabaseClient.ListUserMemberships(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	roles := map[string]string{}
+	ids := make([]string, 0, len(memberships))
+	for _, m := range memberships {
+		roles[rowString(m, "workspace_id")] = rowString(m, "role")
+		ids = append(ids, rowString(m, "workspace_id"))
+	}
+
+	workspaces, err := h.supabaseClient.GetWorkspaces(ids)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for _, w := range workspaces {
+		w["role"] = roles[rowString(w, "id")]
+	}
+	c.JSON(http.StatusOK, workspaces)
+}
+
+// GetWorkspace returns a workspace and its members
+// GET /api/workspaces/:id
+func (h *WorkspaceHandler) GetWorkspace(c *gin.Context) {
+	workspaceID := c.Param("id")
+	if err := checkWorkspaceRole(h.supabaseClient, workspaceID, getUserID(c), models.RoleViewer, "workspace"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	workspace, err := h.supabaseClient.GetWorkspace(workspaceID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if workspace == nil {
+		c.Error(utils.ErrNotFound("workspace"))
+		return
+	}
+	members, err := h.supabaseClient.ListWorkspaceMembers(workspaceID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	workspace["members"] = members
+	for _, m := range members {
+		if rowString(m, "user_id") == getUserID(c) {
+			workspace["role"] = rowString(m, "role")
+		}
+	}
+	c.JSON(http.StatusOK, workspace)
+}
+
+// DeleteWorkspace deletes a workspace; its tasks and goals go back to their creators
+// DELETE /api/workspaces/:id
+func (h *WorkspaceHandler) DeleteWorkspace(c *gin.Context) {
+	workspaceID := c.Param("id")
+	if err := checkWorkspaceRole(h.supabaseClient, workspaceID, getUserID(c), models.RoleOwner, "workspace"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	before, _ := h.supabaseClient.GetWorkspace(workspaceID)
+	if err := h.supabaseClient.DeleteWorkspace(workspaceID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	recordAudit(c, AuditEntityWorkspace, workspaceID, AuditActionDelete, before, nil)
+	c.JSON(http.StatusOK, gin.H{"id": workspaceID, "deleted": true})
+}
+
+// CreateInvite issues a single-use invite token. The token is only ever returned here.
+// POST /api/workspaces/:id/invites
+func (h *WorkspaceHandler) CreateInvite(c *gin.Context) {
+	workspaceID := c.Param("id")
+	userID := getUserID(c)
+	if err := checkWorkspaceRole(h.supabaseClient, workspaceID, userID, models.RoleOwner, "workspace"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req models.CreateInviteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoleViewer
+	}
+
+	var v validation.Validator
+	v.Check(req.Role == models.RoleEditor || req.Role == models.RoleViewer, "role", validation.CodeInvalidValue,
+		"role must be editor or viewer; promote members to owner after they join")
+	v.MaxLength("email", req.Email, 254)
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	secret, err := randomToken(24)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	token := WorkspaceInvitePrefix + secret
+
+	inviteData := map[string]interface{}{
+		"workspace_id": workspaceID,
+		"role":         req.Role,
+		"email":        nullIfEmpty(strings.TrimSpace(req.Email)),
+		"token_hash":   hashClientSecret(token),
+		"invited_by":   userID,
+		"created_at":   time.Now().UTC().Format(time.RFC3339),
+		"expires_at":   time.Now().Add(WorkspaceInviteExpiration).UTC().Format(time.RFC3339),
+	}
+	invite, err := h.supabaseClient.CreateWorkspaceInvite(inviteData)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	delete(invite, "token_hash")
+	invite["token"] = token
+	c.JSON(http.StatusCreated, invite)
+}
+
+// AcceptInvite adds the requesting user to the invite's workspace
+// POST /api/invites/accept
+func (h *WorkspaceHandler) AcceptInvite(c *gin.Context) {
+	var req models.AcceptInviteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	userID := getUserID(c)
+	if userID == "" {
+		c.Error(utils.ErrBadRequest("user_id required"))
+		return
+	}
+
+	invite, err := h.supabaseClient.AcceptWorkspaceInvite(hashClientSecret(strings.TrimSpace(req.Token)), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if invite == nil {
+		c.Error(utils.ErrNotFound("invite (it may have expired or already been used)"))
+		return
+	}
+
+	workspaceID := rowString(invite, "workspace_id")
+	role := rowString(invite, "role")
+	// Accepting never demotes: an existing member keeps a higher role
+	if existing, err := h.supabaseClient.GetWorkspaceMember(workspaceID, userID); err == nil && existing != nil &&
+		roleRank[rowString(existing, "role")] >= roleRank[role] {
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+	member, err := h.supabaseClient.UpsertWorkspaceMember(workspaceID, userID, role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+// UpdateMember changes a member's role
+// PUT /api/workspaces/:id/members/:user_id
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	workspaceID, memberID := c.Param("id"), c.Param("user_id")
+	if err := checkWorkspaceRole(h.supabaseClient, workspaceID, getUserID(c), models.RoleOwner, "workspace"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req models.UpdateMemberRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	var v validation.Validator
+	v.Check(roleRank[req.Role] > 0, "role", validation.CodeInvalidValue, "role must be owner, editor or viewer")
+	if err := v.Err(); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	member, err := h.supabaseClient.GetWorkspaceMember(workspaceID, memberID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if member == nil {
+		c.Error(utils.ErrNotFound("member"))
+		return
+	}
+	if rowString(member, "role") == models.RoleOwner && req.Role != models.RoleOwner && !h.hasOtherOwner(c, workspaceID, memberID) {
+		return
+	}
+
+	updated, err := h.supabaseClient.UpsertWorkspaceMember(workspaceID, memberID, req.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// RemoveMember removes a member; owners can remove anyone and members can leave
+// DELETE /api/workspaces/:id/members/:user_id
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	workspaceID, memberID := c.Param("id"), c.Param("user_id")
+	role := models.RoleOwner
+	if memberID == getUserID(c) {
+		role = models.RoleViewer
+	}
+	if err := checkWorkspaceRole(h.supabaseClient, workspaceID, getUserID(c), role, "workspace"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	member, err := h.supabaseClient.GetWorkspaceMember(workspaceID, memberID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if member == nil {
+		c.Error(utils.ErrNotFound("member"))
+		return
+	}
+	if rowString(member, "role") == models.RoleOwner && !h.hasOtherOwner(c, workspaceID, memberID) {
+		return
+	}
+
+	if err := h.supabaseClient.DeleteWorkspaceMember(workspaceID, memberID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"workspace_id": workspaceID, "user_id": memberID, "removed": true})
+}
+
+// hasOtherOwner reports whether someone besides userID owns the workspace,
+// reporting a conflict on c when not so a workspace never loses its last owner
+func (h *WorkspaceHandler) hasOtherOwner(c *gin.Context, workspaceID, userID string) bool {
+	members, err := h.supabaseClient.ListWorkspaceMembers(workspaceID)
+	if err != nil {
+		c.Error(err)
+		return false
+	}
+	for _, m := range members {
+		if rowString(m, "role") == models.RoleOwner && rowString(m, "user_id") != userID {
+			return true
+		}
+	}
+	c.Error(utils.ErrConflict("a workspace needs at least one owner; promote another member first"))
+	return false
+}
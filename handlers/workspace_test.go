@@ -0,0 +1,208 @@
+//go:build !lite
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/models"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// fakeWorkspaceSupabase serves workspace_members and tasks: u1 views w1, u2
+// edits w1, and w2 has no members the tests use
+func fakeWorkspaceSupabase(t *testing.T) *db.SupabaseClient {
+	t.Helper()
+	members := []map[string]interface{}{
+		{"workspace_id": "w1", "user_id": "u1", "role": "viewer"},
+		{"workspace_id": "w1", "user_id": "u2", "role": "editor"},
+	}
+	tasks := []map[string]interface{}{
+		{"id": "t1", "user_id": "u1", "created_at": "2026-01-01T00:00:00Z"},
+		{"id": "t2", "user_id": "u2", "workspace_id": "w1", "created_at": "2026-01-03T00:00:00Z"},
+		{"id": "t3", "user_id": "u1", "workspace_id": "w2", "created_at": "2026-01-02T00:00:00Z"},
+		{"id": "t4", "user_id": "u1", "workspace_id": "w1", "created_at": "2026-01-04T00:00:00Z"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var table []map[string]interface{}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/workspace_members"):
+			table = members
+		case strings.HasSuffix(r.URL.Path, "/tasks"):
+			table = tasks
+		}
+		rows := []map[string]interface{}{}
+		for _, row := range table {
+			if matchesFilter(row, "id", q.Get("id")) && matchesFilter(row, "user_id", q.Get("user_id")) && matchesFilter(row, "workspace_id", q.Get("workspace_id")) {
+				rows = append(rows, row)
+			}
+		}
+		json.NewEncoder(w).Encode(rows)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := db.NewSupabaseClient(srv.URL, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// matchesFilter applies the eq. and in.() filters the workspace queries use
+func matchesFilter(row map[string]interface{}, key, filter string) bool {
+	value := rowString(row, key)
+	switch {
+	case filter == "":
+		return true
+	case strings.HasPrefix(filter, "eq."):
+		return value == strings.TrimPrefix(filter, "eq.")
+	case strings.HasPrefix(filter, "in.("):
+		for _, v := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(filter, "in.("), ")"), ",") {
+			if v == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestCheckWorkspaceRole(t *testing.T) {
+	client := fakeWorkspaceSupabase(t)
+	for _, tt := range []struct {
+		workspace, user, role string
+		status                int
+	}{
+		{"w1", "u1", "viewer", 0},
+		{"w1", "u1", "editor", http.StatusForbidden},
+		{"w1", "u2", "editor", 0},
+		{"w1", "u2", "owner", http.StatusForbidden},
+		{"w1", "u3", "viewer", http.StatusNotFound},
+		{"w2", "u1", "viewer", http.StatusNotFound},
+		{"w1", "", "viewer", http.StatusUnauthorized},
+	} {
+		err := checkWorkspaceRole(client, tt.workspace, tt.user, tt.role, "task")
+		status := 0
+		if appErr, ok := err.(*utils.AppError); ok {
+			status = appErr.HTTPStatus
+		} else if err != nil {
+			t.Fatalf("%s as %q: unexpected error %v", tt.role, tt.user, err)
+		}
+		if status != tt.status {
+			t.Errorf("%s in %s as %q: status %d, want %d", tt.role, tt.workspace, tt.user, status, tt.status)
+		}
+	}
+}
+
+func TestVisibleRows(t *testing.T) {
+	client := fakeWorkspaceSupabase(t)
+	rows, err := visibleRows(client, "u1", client.GetUserTasks, client.GetWorkspaceTasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, row := range rows {
+		ids = append(ids, rowString(row, "id"))
+	}
+	// t3 is u1's but shared in a workspace they are not a member of
+	if got := strings.Join(ids, ","); got != "t4,t2,t1" {
+		t.Errorf("visible tasks = %s, want t4,t2,t1", got)
+	}
+}
+
+func TestAuthorizeRow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client := fakeWorkspaceSupabase(t)
+	personal := map[string]interface{}{"id": "t1", "user_id": "u1"}
+	shared := map[string]interface{}{"id": "t2", "user_id": "u2", "workspace_id": "w1"}
+
+	for _, tt := range []struct {
+		user string
+		row  map[string]interface{}
+		role string
+		ok   bool
+	}{
+		{"u1", personal, "editor", true},
+		{"u2", personal, "viewer", false},
+		{"", personal, "viewer", false},
+		{"u1", shared, "viewer", true},
+		{"u1", shared, "editor", false},
+		{"u2", shared, "editor", true},
+		{"u3", shared, "viewer", false},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.user != "" {
+			c.Set("user_id", tt.user)
+		}
+		if ok := authorizeRow(c, client, tt.row, "task", tt.role); ok != tt.ok {
+			t.Errorf("%s on %s as %q: authorized %v, want %v", tt.role, tt.row["id"], tt.user, ok, tt.ok)
+		}
+	}
+}
+
+func TestTaskServiceCrossUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := NewTaskService(fakeWorkspaceSupabase(t))
+	title := "renamed"
+
+	for _, tt := range []struct {
+		user, task string
+		status     int
+	}{
+		{"u2", "t1", http.StatusNotFound},      // u1's personal task
+		{"", "t1", http.StatusUnauthorized},    // no user at all
+		{"u1", "t4", http.StatusForbidden},     // u1 only views w1
+		{"u3", "t2", http.StatusNotFound},      // not a member of w1
+		{"u2", "missing", http.StatusNotFound}, // no such task
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPatch, "/", nil)
+
+		_, err := service.Update(c, tt.user, tt.task, models.UpdateTaskRequest{Title: &title})
+		if appErr, ok := err.(*utils.AppError); !ok || appErr.HTTPStatus != tt.status {
+			t.Errorf("update %s as %q: %v, want %d", tt.task, tt.user, err, tt.status)
+		}
+		err = service.Delete(c, tt.user, tt.task)
+		if appErr, ok := err.(*utils.AppError); !ok || appErr.HTTPStatus != tt.status {
+			t.Errorf("delete %s as %q: %v, want %d", tt.task, tt.user, err, tt.status)
+		}
+	}
+}
+
+func TestGetUserTasksOwnOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client := fakeWorkspaceSupabase(t)
+	h := &TaskHandler{supabaseClient: client, service: NewTaskService(client)}
+
+	for _, tt := range []struct {
+		caller string
+		status int
+	}{
+		{"u1", http.StatusOK},
+		{"u2", http.StatusForbidden},
+		{"", http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/tasks/user/u1", nil)
+		c.Params = gin.Params{{Key: "userId", Value: "u1"}}
+		if tt.caller != "" {
+			c.Set("user_id", tt.caller)
+		}
+		h.GetUserTasks(c)
+		status := w.Code
+		if len(c.Errors) > 0 {
+			status = c.Errors.Last().Err.(*utils.AppError).HTTPStatus
+		}
+		if status != tt.status {
+			t.Errorf("u1's tasks as %q: %d, want %d", tt.caller, status, tt.status)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// habiticaExport is the subset of Habitica's user data export we import
+type habiticaExport struct {
+	Tasks struct {
+		Habits []habiticaTask `json:"habits"`
+		Dailys []habiticaTask `json:"dailys"`
+		Todos  []habiticaTask `json:"todos"`
+	} `json:"tasks"`
+}
+
+type habiticaTask struct {
+	ID            string            `json:"id"`
+	Text          string            `json:"text"`
+	Notes         string            `json:"notes"`
+	Priority      float64           `json:"priority"`
+	Frequency     string            `json:"frequency"`
+	EveryX        int               `json:"everyX"`
+	Completed     bool              `json:"completed"`
+	Date          string            `json:"date"`
+	DateCompleted string            `json:"dateCompleted"`
+	CreatedAt     string            `json:"createdAt"`
+	History       []habiticaHistory `json:"history"`
+}
+
+type habiticaHistory struct {
+	Date      json.RawMessage `json:"date"`
+	Completed *bool           `json:"completed"`
+	ScoredUp  int             `json:"scoredUp"`
+}
+
+// ParseHabitica parses a Habitica user data export (JSON). Habits and dailies
+// become habits with their completion history; todos become tasks.
+func ParseHabitica(data []byte) ([]Item, error) {
+	var export habiticaExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("invalid Habitica export: %w", err)
+	}
+
+	items := make([]Item, 0, len(export.Tasks.Habits)+len(export.Tasks.Dailys)+len(export.Tasks.Todos))
+	for _, t := range export.Tasks.Habits {
+		items = append(items, habiticaHabit(t))
+	}
+	for _, t := range export.Tasks.Dailys {
+		items = append(items, habiticaHabit(t))
+	}
+	for _, t := range export.Tasks.Todos {
+		item := habiticaItem(t, KindTask)
+		item.Completed = t.Completed
+		item.DueDate = parseHabiticaTime(t.Date)
+		if t.Completed {
+			item.CompletedAt = parseHabiticaTime(t.DateCompleted)
+		}
+		items = append(items, item)
+	}
+
+	for _, item := range items {
+		if item.ExternalID == "" {
+			return nil, fmt.Errorf("Habitica task %q has no id", item.Title)
+		}
+	}
+	return items, nil
+}
+
+func habiticaHabit(t habiticaTask) Item {
+	item := habiticaItem(t, KindHabit)
+	item.Frequency = normalizeFrequency(t.Frequency)
+	item.Interval = t.EveryX
+	for _, h := range t.History {
+		// Dailies record completed, habits record how often they were scored up
+		if (h.Completed != nil && *h.Completed) || h.ScoredUp > 0 {
+			if at := parseHabiticaHistoryDate(h.Date); at != nil {
+				item.Completions = append(item.Completions, *at)
+			}
+		}
+	}
+	item.Completions = sortCompletions(item.Completions)
+	return item
+}
+
+func habiticaItem(t habiticaTask, kind string) Item {
+	return Item{
+		ExternalID: t.ID,
+		Kind:       kind,
+		Title:      strings.TrimSpace(t.Text),
+		Notes:      t.Notes,
+		Priority:   habiticaPriority(t.Priority),
+		CreatedAt:  parseHabiticaTime(t.CreatedAt),
+	}
+}
+
+// habiticaPriority maps Habitica difficulty (trivial 0.1, easy 1, medium 1.5, hard 2) onto 1-5
+func habiticaPriority(difficulty float64) int {
+	switch {
+	case difficulty >= 2:
+		return 4
+	case difficulty >= 1.5:
+		return 3
+	case difficulty >= 1:
+		return 2
+	case difficulty > 0:
+		return 1
+	}
+	return 3
+}
+
+func parseHabiticaTime(raw string) *time.Time {
+	if raw == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// parseHabiticaHistoryDate accepts both millisecond timestamps and RFC 3339 strings
+func parseHabiticaHistoryDate(raw json.RawMessage) *time.Time {
+	var ms float64
+	if err := json.Unmarshal(raw, &ms); err == nil {
+		t := time.UnixMilli(int64(ms)).UTC()
+		return &t
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return parseHabiticaTime(s)
+	}
+	return nil
+}
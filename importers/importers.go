@@ -0,0 +1,101 @@
+// Package importers parses exports from other habit and task apps, and task
+// lists kept in spreadsheets, into a common list of items that can be
+// previewed and then imported.
+package importers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/productivity/mcp-server/dates"
+)
+
+// Supported sources
+const (
+	SourceHabitica = "habitica"
+	SourceStreaks  = "streaks"
+	SourceCSV      = "csv"
+	SourceXLSX     = "xlsx"
+	SourceMarkdown = "markdown"
+)
+
+// Item kinds
+const (
+	KindHabit = "habit"
+	KindTask  = "task"
+)
+
+// Item is one habit or task from an export. ExternalID is stable across
+// exports of the same account, which makes re-imports idempotent.
+type Item struct {
+	ExternalID  string      `json:"external_id"`
+	Kind        string      `json:"kind"`
+	Title       string      `json:"title"`
+	Notes       string      `json:"notes,omitempty"`
+	Frequency   string      `json:"frequency,omitempty"` // habits only: daily, weekly or monthly
+	Interval    int         `json:"interval,omitempty"`
+	Priority    int         `json:"priority"`
+	Category    string      `json:"category,omitempty"` // spreadsheets and checklists only
+	Parent      string      `json:"parent,omitempty"`   // external ID of the item this is a subtask of
+	DueDate     *time.Time  `json:"due_date,omitempty"`
+	Completed   bool        `json:"completed"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+	Completions []time.Time `json:"-"`
+	CreatedAt   *time.Time  `json:"created_at,omitempty"`
+}
+
+// Parse parses an export from source; a spreadsheet's columns are found by
+// their headers, as GuessColumns does, and a checklist's dates are in UTC
+func Parse(source string, data []byte) ([]Item, error) {
+	switch source {
+	case SourceHabitica:
+		return ParseHabitica(data)
+	case SourceStreaks:
+		return ParseStreaks(data)
+	case SourceCSV, SourceXLSX:
+		table, err := ReadTable(source, data)
+		if err != nil {
+			return nil, err
+		}
+		columns := GuessColumns(table.Header)
+		if err := columns.Check(table.Header); err != nil {
+			return nil, err
+		}
+		return ParseTable(table, columns)
+	case SourceMarkdown:
+		return ParseMarkdown(data, MarkdownOptions{DefaultHour: dates.DefaultHour})
+	default:
+		return nil, fmt.Errorf("unsupported import source %q (expected %s, %s, %s, %s or %s)", source, SourceHabitica, SourceStreaks, SourceCSV, SourceXLSX, SourceMarkdown)
+	}
+}
+
+// IsSpreadsheet reports whether source is a spreadsheet format, whose
+// columns can be mapped
+func IsSpreadsheet(source string) bool {
+	return source == SourceCSV || source == SourceXLSX
+}
+
+// normalizeFrequency maps a source's recurrence to daily, weekly or monthly
+func normalizeFrequency(frequency string) string {
+	switch frequency {
+	case "weekly", "monthly":
+		return frequency
+	}
+	return "daily"
+}
+
+// sortCompletions orders completions and drops duplicates within the same day
+func sortCompletions(times []time.Time) []time.Time {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	out := make([]time.Time, 0, len(times))
+	seen := make(map[string]bool, len(times))
+	for _, t := range times {
+		day := t.UTC().Format("2006-01-02")
+		if !seen[day] {
+			seen[day] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
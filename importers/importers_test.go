@@ -0,0 +1,218 @@
+package importers
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHabitica(t *testing.T) {
+	export := `{"tasks": {
+		"habits": [{"id": "h1", "text": "Drink water", "priority": 1, "frequency": "daily",
+			"history": [{"date": 1741338000000, "scoredUp": 2}, {"date": 1741424400000, "scoredUp": 0, "scoredDown": 1}]}],
+		"dailys": [{"id": "d1", "text": "Stretch", "priority": 2, "frequency": "weekly", "everyX": 1,
+			"history": [{"date": "2025-03-07T08:00:00Z", "completed": true}, {"date": "2025-03-08T08:00:00Z", "completed": false}]}],
+		"todos": [{"id": "t1", "text": " File taxes ", "priority": 1.5, "date": "2025-04-15T00:00:00Z"}]
+	}}`
+
+	items, err := ParseHabitica([]byte(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+
+	habit, daily, todo := items[0], items[1], items[2]
+	if habit.Kind != KindHabit || len(habit.Completions) != 1 || habit.Priority != 2 {
+		t.Fatalf("unexpected habit: %+v", habit)
+	}
+	if daily.Frequency != "weekly" || len(daily.Completions) != 1 || daily.Priority != 4 {
+		t.Fatalf("unexpected daily: %+v", daily)
+	}
+	if todo.Kind != KindTask || todo.Title != "File taxes" || todo.DueDate == nil || todo.Priority != 3 {
+		t.Fatalf("unexpected todo: %+v", todo)
+	}
+}
+
+func TestParseStreaks(t *testing.T) {
+	export := "task_id,title,entry_type,entry_date\n" +
+		"A1,Read,completed_manually,20250301\n" +
+		"A1,Read,completed_manually,20250302\n" +
+		"A1,Read,missed_manually,20250303\n" +
+		"B2,Walk,completed_auto,2025-03-02\n" +
+		"A1,Read,completed_manually,20250302\n"
+
+	items, err := ParseStreaks([]byte(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 habits, got %d", len(items))
+	}
+	if items[0].ExternalID != "A1" || len(items[0].Completions) != 2 {
+		t.Fatalf("expected 2 distinct completions for A1, got %+v", items[0])
+	}
+	if items[1].Title != "Walk" || len(items[1].Completions) != 1 {
+		t.Fatalf("unexpected second habit: %+v", items[1])
+	}
+}
+
+func TestParseSpreadsheetCSV(t *testing.T) {
+	export := "\ufeffWhat;Deadline;Prio;Notes;Done\n" +
+		"Send invoice;2025-03-14;high;to ACME;\n" +
+		";;;;\n" +
+		"File taxes;4/15/2025;5;;yes\n" +
+		"Send invoice;2025-03-14;low;again;\n"
+
+	table, err := ReadTable(SourceCSV, []byte(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Header) != 5 || len(table.Rows) != 3 {
+		t.Fatalf("unexpected table: %+v", table)
+	}
+
+	// "Prio" and "Done" are recognized, but "What" has to be named
+	columns := GuessColumns(table.Header)
+	if columns[FieldPriority] != "Prio" || columns[FieldCompleted] != "Done" || columns[FieldDueDate] != "Deadline" {
+		t.Fatalf("unexpected guess: %v", columns)
+	}
+	if err := columns.Check(table.Header); err == nil {
+		t.Fatal("expected an error without a title column")
+	}
+	columns[FieldTitle] = "what"
+	if err := columns.Check(table.Header); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := ParseTable(table, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected the repeated row to be dropped, got %+v", items)
+	}
+	invoice, taxes := items[0], items[1]
+	if invoice.Title != "Send invoice" || invoice.Priority != 4 || invoice.Notes != "to ACME" || invoice.Completed ||
+		invoice.DueDate == nil || !invoice.DueDate.Equal(time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected first task: %+v", invoice)
+	}
+	if taxes.Priority != 5 || !taxes.Completed || taxes.DueDate.Month() != time.April || taxes.ExternalID == invoice.ExternalID {
+		t.Fatalf("unexpected second task: %+v", taxes)
+	}
+
+	columns[FieldPriority] = "Deadline"
+	if _, err := ParseTable(table, columns); err == nil {
+		t.Fatal("expected an error for a date read as a priority")
+	}
+}
+
+func TestParseSpreadsheetXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Tasks" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId3" Type="worksheet" Target="worksheets/tasks.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>Title</t></si><si><t>Due</t></si><si><r><t>Book </t></r><r><t>flights</t></r></si></sst>`,
+		"xl/worksheets/tasks.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><v>45731</v></c></row>` +
+			`<row r="3"><c r="A3" t="inlineStr"><is><t>Renew passport</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+	} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := Parse(SourceXLSX, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Title != "Book flights" || items[1].Title != "Renew passport" || items[1].DueDate != nil {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+	if due := items[0].DueDate; due == nil || !due.Equal(time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the date serial to be read as 2025-03-15, got %v", due)
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	nyc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+	opts := MarkdownOptions{Location: nyc, DefaultHour: 17}
+	checklist := "# Launch\n\n" +
+		"Notes that are not items are ignored.\n" +
+		"- [ ] Write announcement (due 2025-03-14)\n" +
+		"  - [x] Draft\n" +
+		"  - [ ] Review 📅 2025-03-13\n" +
+		"\t- [ ] Proofread @due(2025-03-13 09:30)\n" +
+		"- [X] Book venue\n" +
+		"- plain bullets are not tasks\n"
+
+	items, err := ParseMarkdown([]byte(checklist), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("expected 5 items, got %+v", items)
+	}
+	announce, draft, review, proofread, venue := items[0], items[1], items[2], items[3], items[4]
+	if announce.Title != "Write announcement" || announce.Category != "launch" || announce.Parent != "" ||
+		!announce.DueDate.Equal(time.Date(2025, 3, 14, 17, 0, 0, 0, nyc)) {
+		t.Fatalf("unexpected first item: %+v", announce)
+	}
+	if draft.Parent != announce.ExternalID || !draft.Completed || review.Parent != announce.ExternalID || review.Title != "Review" {
+		t.Fatalf("unexpected subtasks: %+v %+v", draft, review)
+	}
+	if proofread.Parent != review.ExternalID || !proofread.DueDate.Equal(time.Date(2025, 3, 13, 9, 30, 0, 0, nyc)) {
+		t.Fatalf("unexpected nested subtask: %+v", proofread)
+	}
+	if venue.Parent != "" || !venue.Completed {
+		t.Fatalf("unexpected last item: %+v", venue)
+	}
+
+	// Writing the items back and parsing that gives the same items
+	tree := []ChecklistItem{
+		{Title: announce.Title, Due: announce.DueDate, Children: []ChecklistItem{
+			{Title: draft.Title, Completed: true},
+			{Title: review.Title, Due: review.DueDate, Children: []ChecklistItem{
+				{Title: proofread.Title, Due: proofread.DueDate},
+			}},
+		}},
+		{Title: venue.Title, Completed: true},
+	}
+	var b strings.Builder
+	b.WriteString("# Launch\n")
+	WriteMarkdown(&b, tree, opts)
+	want := "# Launch\n" +
+		"- [ ] Write announcement (due 2025-03-14)\n" +
+		"  - [x] Draft\n" +
+		"  - [ ] Review (due 2025-03-13)\n" +
+		"    - [ ] Proofread (due 2025-03-13 09:30)\n" +
+		"- [x] Book venue\n"
+	if b.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+	again, err := ParseMarkdown([]byte(b.String()), opts)
+	if err != nil || len(again) != len(items) {
+		t.Fatalf("re-parsed %+v, %v", again, err)
+	}
+	for i := range items {
+		if again[i].ExternalID != items[i].ExternalID || again[i].Parent != items[i].Parent || again[i].Completed != items[i].Completed {
+			t.Errorf("item %d changed: %+v, was %+v", i, again[i], items[i])
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package importers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MarkdownOptions says how to read due dates in a checklist
+type MarkdownOptions struct {
+	// Location is the time zone dates are written in
+	Location *time.Location
+	// DefaultHour and DefaultMinute are the time of a due date written
+	// without one
+	DefaultHour, DefaultMinute int
+}
+
+var (
+	markdownHeading  = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*\s*$`)
+	markdownCheckbox = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s+(.*)$`)
+	// markdownDue matches the due date annotations of common checklist
+	// formats: "(due 2025-03-14 15:00)", Obsidian Tasks' "📅 2025-03-14",
+	// TaskPaper's "@due(2025-03-14)" and "due: 2025-03-14"
+	markdownDue = regexp.MustCompile(`(?i)\s*(?:\(due:?\s*|@due\(|📅\s*|\bdue:\s*)(\d{4}-\d{2}-\d{2})(?:[ T](\d{1,2}:\d{2}))?\)?`)
+)
+
+// ChecklistItem is a task in a Markdown checklist with the items nested
+// under it
+type ChecklistItem struct {
+	Title     string
+	Completed bool
+	Due       *time.Time
+	Children  []ChecklistItem
+}
+
+// ParseMarkdown parses a Markdown checklist: each "- [ ] item" or
+// "- [x] item" line is a task, completed when ticked, and a subtask of the
+// item above it that it is indented under. A due date annotation is taken
+// out of the title, and a heading sets the category of the items below it.
+// Other lines are ignored. Items are identified by their category and the
+// titles of the items they are nested in, so re-importing an edited
+// checklist updates the tasks it created.
+func ParseMarkdown(data []byte, opts MarkdownOptions) ([]Item, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	type open struct {
+		indent int
+		id     string
+		path   string
+	}
+	var (
+		items    []Item
+		stack    []open
+		category string
+		seen     = map[string]int{}
+	)
+	for n, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			category = strings.ToLower(m[1])
+			stack = stack[:0]
+			continue
+		}
+		m := markdownCheckbox.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		title, due, err := markdownTitle(m[3], opts)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		if title == "" {
+			continue
+		}
+		item := Item{
+			Kind:      KindTask,
+			Title:     title,
+			Category:  category,
+			Priority:  3,
+			DueDate:   due,
+			Completed: m[2] != " ",
+		}
+
+		path := category
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			item.Parent = parent.id
+			path = parent.path
+		}
+		path += "\x00" + strings.ToLower(title)
+		// Repeated items under the same parent are told apart by their order
+		seen[path]++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", path, seen[path])))
+		item.ExternalID = "md-" + hex.EncodeToString(sum[:8])
+
+		items = append(items, item)
+		stack = append(stack, open{indent: indent, id: item.ExternalID, path: path})
+	}
+	return items, nil
+}
+
+// markdownTitle takes the due date annotation, if any, out of an item's text
+func markdownTitle(text string, opts MarkdownOptions) (string, *time.Time, error) {
+	m := markdownDue.FindStringSubmatchIndex(text)
+	if m == nil {
+		return strings.TrimSpace(text), nil, nil
+	}
+	date := text[m[2]:m[3]]
+	due, err := time.ParseInLocation("2006-01-02", date, opts.Location)
+	if err != nil {
+		return "", nil, fmt.Errorf("due date %q is not a date", date)
+	}
+	if m[4] >= 0 {
+		clock, err := time.Parse("15:04", text[m[4]:m[5]])
+		if err != nil {
+			return "", nil, fmt.Errorf("due time %q is not a time", text[m[4]:m[5]])
+		}
+		due = due.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute)
+	} else {
+		due = due.Add(time.Duration(opts.DefaultHour)*time.Hour + time.Duration(opts.DefaultMinute)*time.Minute)
+	}
+	return strings.TrimSpace(text[:m[0]] + " " + text[m[1]:]), &due, nil
+}
+
+// WriteMarkdown writes items as a checklist ParseMarkdown reads back, nesting
+// subtasks two spaces deeper and annotating due dates as "(due 2025-03-14)",
+// with the time only when it is not the default one in opts
+func WriteMarkdown(b *strings.Builder, items []ChecklistItem, opts MarkdownOptions) {
+	writeMarkdownItems(b, items, opts, "")
+}
+
+func writeMarkdownItems(b *strings.Builder, items []ChecklistItem, opts MarkdownOptions, indent string) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	for _, item := range items {
+		box := "[ ]"
+		if item.Completed {
+			box = "[x]"
+		}
+		b.WriteString(indent + "- " + box + " " + strings.Join(strings.Fields(item.Title), " "))
+		if item.Due != nil {
+			due := item.Due.In(opts.Location)
+			if due.Hour() == opts.DefaultHour && due.Minute() == opts.DefaultMinute {
+				b.WriteString(" (due " + due.Format("2006-01-02") + ")")
+			} else {
+				b.WriteString(" (due " + due.Format("2006-01-02 15:04") + ")")
+			}
+		}
+		b.WriteString("\n")
+		writeMarkdownItems(b, item.Children, opts, indent+"  ")
+	}
+}
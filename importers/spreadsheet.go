@@ -0,0 +1,319 @@
+package importers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxSpreadsheetRows bounds the data rows read from one CSV or XLSX file
+const MaxSpreadsheetRows = 20000
+
+// Task fields a spreadsheet column can be mapped to
+const (
+	FieldID          = "id"
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldDueDate     = "due_date"
+	FieldPriority    = "priority"
+	FieldCategory    = "category"
+	FieldCompleted   = "completed"
+)
+
+// ColumnField describes a field for a person or model choosing the mapping
+type ColumnField struct {
+	Name        string
+	Description string
+	aliases     []string
+}
+
+// ColumnFields are the fields a spreadsheet column can be mapped to, with
+// the headers recognized for each without a mapping
+var ColumnFields = []ColumnField{
+	{FieldTitle, "what needs doing (required)", []string{"title", "task", "task name", "name", "summary", "subject", "item", "todo", "action", "action item"}},
+	{FieldDescription, "notes or details", []string{"description", "notes", "note", "details", "comments", "comment"}},
+	{FieldDueDate, "when it is due", []string{"due_date", "due date", "due", "deadline", "date", "due by", "end date"}},
+	{FieldPriority, "1-5 with 5 the most urgent, or a word like high or low", []string{"priority", "prio", "importance", "urgency"}},
+	{FieldCategory, "a category or list name", []string{"category", "list", "project", "area", "type", "tag"}},
+	{FieldCompleted, "whether it is done", []string{"completed", "done", "complete", "status", "finished"}},
+	{FieldID, "a stable ID for the row, so re-imports update it", []string{"id", "task_id", "task id", "key", "ref"}},
+}
+
+// ColumnMapping maps field names to the header of the column holding them
+type ColumnMapping map[string]string
+
+// Table is a spreadsheet's header row and the data rows under it
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// ReadTable reads the first sheet of a CSV or XLSX file. Blank rows are
+// dropped; a file with more than MaxSpreadsheetRows rows is an error.
+func ReadTable(format string, data []byte) (*Table, error) {
+	var rows [][]string
+	var err error
+	switch format {
+	case SourceCSV:
+		rows, err = readCSV(data)
+	case SourceXLSX:
+		rows, err = readXLSX(data)
+	default:
+		return nil, fmt.Errorf("unsupported spreadsheet format %q (expected %s or %s)", format, SourceCSV, SourceXLSX)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	table := &Table{}
+	for _, row := range rows {
+		if blankRow(row) {
+			continue
+		}
+		if table.Header == nil {
+			table.Header = make([]string, len(row))
+			for i, h := range row {
+				table.Header[i] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+			}
+			continue
+		}
+		if len(table.Rows) == MaxSpreadsheetRows {
+			return nil, fmt.Errorf("spreadsheet has more than %d rows", MaxSpreadsheetRows)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	if table.Header == nil {
+		return nil, fmt.Errorf("spreadsheet is empty")
+	}
+	return table, nil
+}
+
+// readCSV reads comma, semicolon or tab separated rows, whichever the first
+// line uses most
+func readCSV(data []byte) ([][]string, error) {
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	for _, sep := range []rune{';', '\t'} {
+		if bytes.Count(first, []byte(string(sep))) > bytes.Count(first, []byte(string(reader.Comma))) {
+			reader.Comma = sep
+		}
+	}
+
+	var rows [][]string
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV at line %d: %w", line, err)
+		}
+		rows = append(rows, record)
+	}
+}
+
+func blankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// GuessColumns maps the fields whose usual header names appear in header
+func GuessColumns(header []string) ColumnMapping {
+	mapping := ColumnMapping{}
+	taken := map[string]bool{}
+	for _, field := range ColumnFields {
+		for _, alias := range field.aliases {
+			if h, ok := findHeader(header, alias); ok && !taken[h] {
+				mapping[field.Name] = h
+				taken[h] = true
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+// Check reports the first problem with mapping for a table with header: an
+// unknown field, a header not in the table, or no title column
+func (m ColumnMapping) Check(header []string) error {
+	for field, h := range m {
+		if !knownField(field) {
+			return fmt.Errorf("unknown field %q in column mapping", field)
+		}
+		if _, ok := findHeader(header, h); !ok {
+			return fmt.Errorf("column %q mapped to %s is not in the spreadsheet", h, field)
+		}
+	}
+	if m[FieldTitle] == "" {
+		return fmt.Errorf("no column is mapped to title; name one in the column mapping")
+	}
+	return nil
+}
+
+// Known returns the entries of m for known fields whose column is in
+// header, naming each column as header does
+func (m ColumnMapping) Known(header []string) ColumnMapping {
+	known := ColumnMapping{}
+	for field, h := range m {
+		if name, ok := findHeader(header, h); ok && knownField(field) {
+			known[field] = name
+		}
+	}
+	return known
+}
+
+func knownField(name string) bool {
+	for _, field := range ColumnFields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findHeader finds name in header, ignoring case and surrounding space
+func findHeader(header []string, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", false
+	}
+	for _, h := range header {
+		if strings.EqualFold(h, name) {
+			return h, true
+		}
+	}
+	return "", false
+}
+
+// ParseTable turns each row of table with a title into a task, reading the
+// fields from the columns in mapping, which must pass Check. Rows without an
+// ID column are identified by their title and due date, so re-importing the
+// same file changes nothing; a row repeating an earlier one is dropped.
+func ParseTable(table *Table, mapping ColumnMapping) ([]Item, error) {
+	index := map[string]int{}
+	for field, h := range mapping {
+		for i, name := range table.Header {
+			if strings.EqualFold(name, strings.TrimSpace(h)) {
+				index[field] = i
+				break
+			}
+		}
+	}
+
+	items := make([]Item, 0, len(table.Rows))
+	seen := make(map[string]bool, len(table.Rows))
+	for r, record := range table.Rows {
+		// Row 1 is the header
+		row := r + 2
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		title := field(FieldTitle)
+		if title == "" {
+			continue
+		}
+		item := Item{
+			Kind:     KindTask,
+			Title:    title,
+			Notes:    field(FieldDescription),
+			Category: strings.ToLower(field(FieldCategory)),
+			Priority: 3,
+		}
+		if raw := field(FieldPriority); raw != "" {
+			p, err := parsePriority(raw)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+			item.Priority = p
+		}
+		if raw := field(FieldDueDate); raw != "" {
+			due, err := parseCellDate(raw)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+			item.DueDate = &due
+		}
+		item.Completed = parseCompleted(field(FieldCompleted))
+
+		item.ExternalID = field(FieldID)
+		if item.ExternalID == "" {
+			sum := sha256.Sum256([]byte(strings.ToLower(title) + "\x00" + field(FieldDueDate)))
+			item.ExternalID = "row-" + hex.EncodeToString(sum[:8])
+		}
+		if seen[item.ExternalID] {
+			continue
+		}
+		seen[item.ExternalID] = true
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// priorityWords are the priorities spreadsheets write out
+var priorityWords = map[string]int{
+	"urgent": 5, "critical": 5, "highest": 5,
+	"high": 4, "important": 4,
+	"medium": 3, "normal": 3, "med": 3,
+	"low": 2, "minor": 2,
+	"lowest": 1, "someday": 1,
+}
+
+func parsePriority(raw string) (int, error) {
+	if p, ok := priorityWords[strings.ToLower(raw)]; ok {
+		return p, nil
+	}
+	p, err := strconv.ParseFloat(raw, 64)
+	if err != nil || p < 1 || p > 5 {
+		return 0, fmt.Errorf("priority %q is not 1-5 or a word like high or low", raw)
+	}
+	return int(math.Round(p)), nil
+}
+
+// cellDateLayouts are the due date formats read from cells, month first
+// where the order is ambiguous
+var cellDateLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02",
+	"2006/01/02", "1/2/2006", "1/2/2006 15:04", "1/2/06", "Jan 2, 2006", "January 2, 2006", "2 Jan 2006", "2 January 2006",
+}
+
+// excelEpoch is day 0 of Excel's date serial numbers, which is how XLSX
+// files store dates
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+func parseCellDate(raw string) (time.Time, error) {
+	for _, layout := range cellDateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil && serial > 0 && serial < 2958466 {
+		return excelEpoch.Add(time.Duration(serial * float64(24*time.Hour))).Round(time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("due date %q is not a date such as 2025-03-14", raw)
+}
+
+func parseCompleted(raw string) bool {
+	switch strings.ToLower(raw) {
+	case "true", "yes", "y", "1", "x", "done", "completed", "complete", "finished", "closed", "✓", "✔":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package importers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// streaksDateLayouts are the entry date formats seen in Streaks CSV exports
+var streaksDateLayouts = []string{"20060102", "2006-01-02", time.RFC3339, "2006-01-02 15:04:05"}
+
+// ParseStreaks parses a Streaks app CSV export. Each row is one log entry for a
+// habit; rows whose entry type mentions a miss or skip are ignored. Recognized
+// columns (case-insensitive): task_id/id, title/task_title/name,
+// entry_date/date, entry_type/type.
+func ParseStreaks(data []byte) ([]Item, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid Streaks export: %w", err)
+	}
+	col := func(names ...string) int {
+		for i, h := range header {
+			h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+			for _, name := range names {
+				if h == name {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	idCol, titleCol := col("task_id", "id"), col("title", "task_title", "name")
+	dateCol, typeCol := col("entry_date", "date"), col("entry_type", "type")
+	if titleCol < 0 || dateCol < 0 {
+		return nil, fmt.Errorf("invalid Streaks export: title and entry_date columns are required")
+	}
+
+	byID := make(map[string]*Item)
+	var order []string
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Streaks export at line %d: %w", line, err)
+		}
+
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		title := field(titleCol)
+		if title == "" {
+			continue
+		}
+		id := field(idCol)
+		if id == "" {
+			id = strings.ToLower(title)
+		}
+
+		item, ok := byID[id]
+		if !ok {
+			item = &Item{ExternalID: id, Kind: KindHabit, Title: title, Frequency: "daily", Priority: 3}
+			byID[id] = item
+			order = append(order, id)
+		}
+
+		entryType := strings.ToLower(field(typeCol))
+		if strings.Contains(entryType, "miss") || strings.Contains(entryType, "skip") {
+			continue
+		}
+		at, err := parseStreaksDate(field(dateCol))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Streaks export at line %d: %w", line, err)
+		}
+		item.Completions = append(item.Completions, at)
+	}
+
+	items := make([]Item, 0, len(order))
+	for _, id := range order {
+		item := byID[id]
+		item.Completions = sortCompletions(item.Completions)
+		if len(item.Completions) > 0 {
+			created := item.Completions[0]
+			item.CreatedAt = &created
+		}
+		items = append(items, *item)
+	}
+	return items, nil
+}
+
+func parseStreaksDate(raw string) (time.Time, error) {
+	for _, layout := range streaksDateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized entry date %q", raw)
+}
@@ -0,0 +1,127 @@
+// Package integrations is the common shape of the outside services users
+// connect their accounts to. Each service is a Provider registered once at
+// startup; the server stores each user's credentials encrypted, serves the
+// same connect, status, sync and webhook routes for every provider, and
+// syncs connections in the background on the provider's interval.
+package integrations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ErrNoWebhooks is returned by providers that take no webhooks
+var ErrNoWebhooks = errors.New("provider takes no webhooks")
+
+// ConnectError is a problem with the credentials or settings a user
+// connected with, which they have to fix
+type ConnectError struct {
+	Message string
+	Err     error
+}
+
+func (e *ConnectError) Error() string {
+	return e.Message
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Connection is one user's connection to a provider
+type Connection struct {
+	UserID   string
+	Provider string
+	// Credentials are the secrets the provider authenticates with, such as
+	// a token; they are encrypted before they are stored
+	Credentials map[string]string
+	// Settings are the provider's other options, stored as given
+	Settings     map[string]interface{}
+	LastSyncedAt time.Time // zero before the first sync
+}
+
+// Setting returns a string setting, or "" if it is unset
+func (c *Connection) Setting(key string) string {
+	s, _ := c.Settings[key].(string)
+	return s
+}
+
+// BoolSetting returns a boolean setting, false if unset
+func (c *Connection) BoolSetting(key string) bool {
+	b, _ := c.Settings[key].(bool)
+	return b
+}
+
+// Result counts what one sync changed
+type Result struct {
+	Created  int      `json:"created"`  // items added to the service
+	Updated  int      `json:"updated"`  // items rewritten there
+	Archived int      `json:"archived"` // items of trashed tasks and goals
+	Pulled   int      `json:"pulled"`   // tasks and goals changed from the service
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// Provider is an outside service users can connect
+type Provider interface {
+	// Name identifies the provider in routes and storage, e.g. "notion"
+	Name() string
+	// SyncInterval is how often connections are synced in the background;
+	// zero leaves syncing to the user
+	SyncInterval() time.Duration
+	// Connect checks a new connection's credentials and settings against
+	// the service, normalizing the settings in place. previous is the
+	// connection it replaces, or nil. Malformed credentials or settings
+	// are validation.Errors, other problems the user can fix *ConnectError.
+	Connect(ctx context.Context, conn *Connection, previous *Connection) error
+	// Sync brings the service and the user's data in step. Errors about
+	// single items are collected in the result; the error is for a sync
+	// that could not run at all.
+	Sync(ctx context.Context, conn *Connection) (Result, error)
+	// HandleWebhook handles a delivery from the service, which the
+	// provider authenticates itself; ErrNoWebhooks if it takes none
+	HandleWebhook(ctx context.Context, r *http.Request) error
+}
+
+// Registry holds the providers the server offers
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry creates a registry of providers
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds a provider, replacing any of the same name
+func (r *Registry) Register(p Provider) {
+	r.providers[p.Name()] = p
+}
+
+// Get returns the named provider
+func (r *Registry) Get(name string) (Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Names lists the registered providers in order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Due reports whether a connection should be synced at now
+func Due(p Provider, conn *Connection, now time.Time) bool {
+	interval := p.SyncInterval()
+	return interval > 0 && !now.Before(conn.LastSyncedAt.Add(interval))
+}
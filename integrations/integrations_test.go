@@ -0,0 +1,49 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	name     string
+	interval time.Duration
+}
+
+func (p fakeProvider) Name() string                { return p.name }
+func (p fakeProvider) SyncInterval() time.Duration { return p.interval }
+func (p fakeProvider) Connect(context.Context, *Connection, *Connection) error {
+	return nil
+}
+func (p fakeProvider) Sync(context.Context, *Connection) (Result, error) { return Result{}, nil }
+func (p fakeProvider) HandleWebhook(context.Context, *http.Request) error {
+	return ErrNoWebhooks
+}
+
+func TestRegistryAndDue(t *testing.T) {
+	hourly := fakeProvider{"b", time.Hour}
+	manual := fakeProvider{"a", 0}
+	registry := NewRegistry(hourly, manual)
+	if names := registry.Names(); len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("names %v", names)
+	}
+	if _, ok := registry.Get("c"); ok {
+		t.Error("unregistered provider found")
+	}
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if !Due(hourly, &Connection{}, now) {
+		t.Error("a connection never synced is not due")
+	}
+	if Due(hourly, &Connection{LastSyncedAt: now.Add(-30 * time.Minute)}, now) {
+		t.Error("due before the interval passed")
+	}
+	if !Due(hourly, &Connection{LastSyncedAt: now.Add(-time.Hour)}, now) {
+		t.Error("not due once the interval passed")
+	}
+	if Due(manual, &Connection{}, now) {
+		t.Error("a provider without an interval is synced in the background")
+	}
+}
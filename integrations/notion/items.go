@@ -0,0 +1,274 @@
+package notion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Item kinds, each synced to its own database
+const (
+	KindTask = "task"
+	KindGoal = "goal"
+)
+
+// Columns the sync reads and writes besides the title. EnsureProperties adds
+// those a database lacks.
+const (
+	PropertyID          = "ID" // the task or goal ID, linking the row back
+	PropertyDescription = "Description"
+	PropertyDone        = "Done"     // tasks: completed
+	PropertyArchived    = "Archived" // goals
+	PropertyDue         = "Due"      // tasks: due date; goals: target date
+	PropertyPriority    = "Priority" // tasks, 1-5
+	PropertyCategory    = "Category" // tasks
+	PropertyProgress    = "Progress" // goals, as a percentage; never applied from Notion
+)
+
+// maxTextLength is the most characters Notion takes in one rich text object
+const maxTextLength = 2000
+
+// Item is a task or goal as it appears in a Notion database
+type Item struct {
+	Kind        string
+	ID          string
+	Title       string
+	Description string
+	Done        bool // a task's completed, a goal's archived
+	Due         *time.Time
+	Priority    int
+	Category    string
+	Progress    int
+}
+
+// columns are the properties of each kind with their Notion configuration
+func columns(kind string) map[string]map[string]interface{} {
+	empty := map[string]interface{}{}
+	cols := map[string]map[string]interface{}{
+		PropertyID:          {"rich_text": empty},
+		PropertyDescription: {"rich_text": empty},
+		PropertyDue:         {"date": empty},
+	}
+	if kind == KindGoal {
+		cols[PropertyArchived] = map[string]interface{}{"checkbox": empty}
+		cols[PropertyProgress] = map[string]interface{}{"number": map[string]string{"format": "percent"}}
+	} else {
+		cols[PropertyDone] = map[string]interface{}{"checkbox": empty}
+		cols[PropertyPriority] = map[string]interface{}{"number": map[string]string{"format": "number"}}
+		cols[PropertyCategory] = map[string]interface{}{"select": empty}
+	}
+	return cols
+}
+
+// EnsureProperties adds the columns kind needs to a database and returns its
+// schema. A column of the right name but the wrong type is an error, since
+// overwriting it could destroy the user's data.
+func (c *Client) EnsureProperties(ctx context.Context, databaseID, kind string) (*Database, error) {
+	database, err := c.Database(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	missing := map[string]interface{}{}
+	for name, config := range columns(kind) {
+		existing, ok := database.Properties[name]
+		if !ok {
+			missing[name] = config
+			continue
+		}
+		for typ := range config {
+			if existing.Type != typ {
+				return nil, fmt.Errorf("database property %q is a %s; the %s sync needs a %s", name, existing.Type, kind, typ)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return database, nil
+	}
+	if err := c.AddProperties(ctx, databaseID, missing); err != nil {
+		return nil, err
+	}
+	return c.Database(ctx, databaseID)
+}
+
+// Properties returns the page properties that write item to a row of a
+// database whose title column is titleProperty
+func (i Item) Properties(titleProperty string) map[string]interface{} {
+	props := map[string]interface{}{
+		titleProperty:       map[string]interface{}{"title": richText(i.Title)},
+		PropertyID:          map[string]interface{}{"rich_text": richText(i.ID)},
+		PropertyDescription: map[string]interface{}{"rich_text": richText(i.Description)},
+		PropertyDue:         map[string]interface{}{"date": nil},
+	}
+	if i.Due != nil {
+		props[PropertyDue] = map[string]interface{}{"date": map[string]string{"start": i.Due.UTC().Format(time.RFC3339)}}
+	}
+	if i.Kind == KindGoal {
+		props[PropertyArchived] = map[string]interface{}{"checkbox": i.Done}
+		props[PropertyProgress] = map[string]interface{}{"number": float64(i.Progress) / 100}
+		return props
+	}
+	props[PropertyDone] = map[string]interface{}{"checkbox": i.Done}
+	props[PropertyPriority] = map[string]interface{}{"number": i.Priority}
+	props[PropertyCategory] = map[string]interface{}{"select": nil}
+	if i.Category != "" {
+		// Notion rejects commas in select options
+		props[PropertyCategory] = map[string]interface{}{"select": map[string]string{"name": strings.ReplaceAll(i.Category, ",", " ")}}
+	}
+	return props
+}
+
+// richText splits s into rich text objects of at most maxTextLength characters
+func richText(s string) []map[string]interface{} {
+	texts := []map[string]interface{}{}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		n := len(runes)
+		if n > maxTextLength {
+			n = maxTextLength
+		}
+		texts = append(texts, map[string]interface{}{"type": "text", "text": map[string]string{"content": string(runes[:n])}})
+		runes = runes[n:]
+	}
+	return texts
+}
+
+// propertyValue is the part of a page property value the sync reads
+type propertyValue struct {
+	Type     string   `json:"type"`
+	Title    []text   `json:"title"`
+	RichText []text   `json:"rich_text"`
+	Checkbox bool     `json:"checkbox"`
+	Number   *float64 `json:"number"`
+	Select   *struct {
+		Name string `json:"name"`
+	} `json:"select"`
+	Date *struct {
+		Start string `json:"start"`
+	} `json:"date"`
+}
+
+type text struct {
+	PlainText string `json:"plain_text"`
+}
+
+func plainText(texts []text) string {
+	var b strings.Builder
+	for _, t := range texts {
+		b.WriteString(t.PlainText)
+	}
+	return b.String()
+}
+
+// ItemFromPage reads a row of a kind database whose title column is
+// titleProperty
+func ItemFromPage(page Page, kind, titleProperty string) (Item, error) {
+	item := Item{Kind: kind}
+	value := func(name string) (propertyValue, error) {
+		var v propertyValue
+		raw, ok := page.Properties[name]
+		if !ok {
+			return v, nil
+		}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return v, fmt.Errorf("property %q: %w", name, err)
+		}
+		return v, nil
+	}
+
+	title, err := value(titleProperty)
+	if err != nil {
+		return item, err
+	}
+	item.Title = strings.TrimSpace(plainText(title.Title))
+	id, err := value(PropertyID)
+	if err != nil {
+		return item, err
+	}
+	item.ID = strings.TrimSpace(plainText(id.RichText))
+	description, err := value(PropertyDescription)
+	if err != nil {
+		return item, err
+	}
+	item.Description = plainText(description.RichText)
+
+	due, err := value(PropertyDue)
+	if err != nil {
+		return item, err
+	}
+	if due.Date != nil && due.Date.Start != "" {
+		parsed, err := parseDate(due.Date.Start)
+		if err != nil {
+			return item, fmt.Errorf("property %q: %w", PropertyDue, err)
+		}
+		item.Due = &parsed
+	}
+
+	doneProperty := PropertyDone
+	if kind == KindGoal {
+		doneProperty = PropertyArchived
+	}
+	done, err := value(doneProperty)
+	if err != nil {
+		return item, err
+	}
+	item.Done = done.Checkbox
+	if kind == KindGoal {
+		progress, err := value(PropertyProgress)
+		if err != nil {
+			return item, err
+		}
+		if progress.Number != nil {
+			item.Progress = int(math.Round(*progress.Number * 100))
+		}
+		return item, nil
+	}
+
+	priority, err := value(PropertyPriority)
+	if err != nil {
+		return item, err
+	}
+	if priority.Number != nil {
+		item.Priority = int(*priority.Number)
+	}
+	category, err := value(PropertyCategory)
+	if err != nil {
+		return item, err
+	}
+	if category.Select != nil {
+		item.Category = category.Select.Name
+	}
+	return item, nil
+}
+
+// parseDate reads a Notion date, which is a day ("2026-01-02") or a time
+// with an offset ("2026-01-02T09:00:00.000+00:00")
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// Hash fingerprints the fields both sides edit, so the sync can tell whether
+// a row changed since it was last written without trusting Notion's
+// last_edited_time, which is rounded to the minute
+func (i Item) Hash() string {
+	due := ""
+	if i.Due != nil {
+		due = i.Due.UTC().Format(time.RFC3339)
+	}
+	fields := []string{i.Kind, i.ID, i.Title, i.Description, strconv.FormatBool(i.Done), due}
+	if i.Kind == KindGoal {
+		fields = append(fields, strconv.Itoa(i.Progress))
+	} else {
+		fields = append(fields, strconv.Itoa(i.Priority), strings.ReplaceAll(i.Category, ",", " "))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
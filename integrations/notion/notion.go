@@ -0,0 +1,267 @@
+// Package notion talks to Notion's REST API on behalf of a user who has
+// shared databases with their own internal integration, so tasks and goals
+// can be mirrored into those databases and edits made there read back.
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultURL is Notion's API
+	DefaultURL = "https://api.notion.com"
+	// Version is the Notion-Version the requests and property shapes follow
+	Version = "2022-06-28"
+	// maxRateLimitWait bounds how long a rate-limited request waits before one retry
+	maxRateLimitWait = 10 * time.Second
+	// pageSize is the most results Notion returns per query page
+	pageSize = 100
+)
+
+// APIError is an error response from Notion
+type APIError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("notion returned %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// IsNotFound reports whether err is Notion saying the object does not exist
+// or is not shared with the integration
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// IsUnauthorized reports whether err is Notion rejecting the token
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized
+}
+
+// IsArchived reports whether err is Notion refusing to edit a page that was
+// deleted, which it keeps archived
+func IsArchived(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest && strings.Contains(apiErr.Message, "archived")
+}
+
+// idPattern matches the 32 hex digits of a Notion ID, with or without dashes
+var idPattern = regexp.MustCompile(`([0-9a-fA-F]{8})-?([0-9a-fA-F]{4})-?([0-9a-fA-F]{4})-?([0-9a-fA-F]{4})-?([0-9a-fA-F]{12})(?:[^0-9a-fA-F]|$)`)
+
+// ParseID returns the dashed ID in s, which may be the ID itself or a link
+// copied from Notion such as https://www.notion.so/team/Tasks-0123...cdef?v=...
+func ParseID(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, "?#"); i >= 0 {
+		s = s[:i]
+	}
+	matches := idPattern.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	m := matches[len(matches)-1]
+	return strings.ToLower(strings.Join(m[1:6], "-")), true
+}
+
+// Client calls the Notion API with one integration token
+type Client struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the API at url (DefaultURL if empty)
+func NewClient(url, token string, timeout time.Duration) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{
+		url:        strings.TrimSuffix(url, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Database is a Notion database's schema
+type Database struct {
+	ID         string                      `json:"id"`
+	Properties map[string]DatabaseProperty `json:"properties"`
+}
+
+// DatabaseProperty is a column of a database
+type DatabaseProperty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TitleProperty returns the name of the database's title column; every
+// database has exactly one, called "Name" unless renamed
+func (d *Database) TitleProperty() string {
+	for name, property := range d.Properties {
+		if property.Type == "title" {
+			return name
+		}
+	}
+	return "Name"
+}
+
+// Page is a row of a database
+type Page struct {
+	ID             string                     `json:"id"`
+	Archived       bool                       `json:"archived"`
+	LastEditedTime time.Time                  `json:"last_edited_time"`
+	Properties     map[string]json.RawMessage `json:"properties"`
+}
+
+// Database fetches a database's schema
+func (c *Client) Database(ctx context.Context, databaseID string) (*Database, error) {
+	var database Database
+	if err := c.do(ctx, http.MethodGet, "/v1/databases/"+databaseID, nil, &database); err != nil {
+		return nil, err
+	}
+	return &database, nil
+}
+
+// AddProperties adds columns to a database; properties maps each name to its
+// configuration, e.g. {"checkbox": {}}
+func (c *Client) AddProperties(ctx context.Context, databaseID string, properties map[string]interface{}) error {
+	return c.do(ctx, http.MethodPatch, "/v1/databases/"+databaseID, map[string]interface{}{"properties": properties}, nil)
+}
+
+// CreatePage adds a row to a database
+func (c *Client) CreatePage(ctx context.Context, databaseID string, properties map[string]interface{}) (*Page, error) {
+	var page Page
+	body := map[string]interface{}{
+		"parent":     map[string]string{"database_id": databaseID},
+		"properties": properties,
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/pages", body, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdatePage sets properties of a row
+func (c *Client) UpdatePage(ctx context.Context, pageID string, properties map[string]interface{}) (*Page, error) {
+	var page Page
+	if err := c.do(ctx, http.MethodPatch, "/v1/pages/"+pageID, map[string]interface{}{"properties": properties}, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ArchivePage deletes a row, which Notion keeps in its trash
+func (c *Client) ArchivePage(ctx context.Context, pageID string) error {
+	return c.do(ctx, http.MethodPatch, "/v1/pages/"+pageID, map[string]interface{}{"archived": true}, nil)
+}
+
+// QueryDatabase returns a database's rows edited at or after since, or
+// every row if since is zero, reading all pages of results
+func (c *Client) QueryDatabase(ctx context.Context, databaseID string, since time.Time) ([]Page, error) {
+	var pages []Page
+	cursor := ""
+	for {
+		body := map[string]interface{}{"page_size": pageSize}
+		if !since.IsZero() {
+			body["filter"] = map[string]interface{}{
+				"timestamp":        "last_edited_time",
+				"last_edited_time": map[string]string{"on_or_after": since.UTC().Format(time.RFC3339)},
+			}
+		}
+		if cursor != "" {
+			body["start_cursor"] = cursor
+		}
+		var result struct {
+			Results    []Page `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := c.do(ctx, http.MethodPost, "/v1/databases/"+databaseID+"/query", body, &result); err != nil {
+			return nil, err
+		}
+		pages = append(pages, result.Results...)
+		if !result.HasMore || result.NextCursor == "" {
+			return pages, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// do sends a request, waiting out one rate limit response, and decodes the
+// answer into out if it is not nil
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.url+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Notion-Version", Version)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("notion request failed: %w", err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read notion response: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			wait := time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(seconds) * time.Second
+			}
+			if wait > maxRateLimitWait {
+				wait = maxRateLimitWait
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			apiErr := &APIError{Status: resp.StatusCode}
+			if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+				apiErr.Message = strings.TrimSpace(string(data))
+			}
+			apiErr.Status = resp.StatusCode
+			return apiErr
+		}
+		if out == nil {
+			return nil
+		}
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode notion response: %w", err)
+		}
+		return nil
+	}
+}
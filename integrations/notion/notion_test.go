@@ -0,0 +1,215 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseID(t *testing.T) {
+	const want = "0123abcd-4567-89ab-cdef-0123456789ab"
+	for _, s := range []string{
+		"0123abcd456789abcdef0123456789ab",
+		"0123ABCD-4567-89ab-cdef-0123456789AB",
+		"https://www.notion.so/team/Tasks-0123abcd456789abcdef0123456789ab?v=ffffffffffffffffffffffffffffffff",
+		" https://www.notion.so/0123abcd456789abcdef0123456789ab#section ",
+	} {
+		if got, ok := ParseID(s); !ok || got != want {
+			t.Errorf("ParseID(%q) = %q, %v", s, got, ok)
+		}
+	}
+	for _, s := range []string{"", "tasks", "https://www.notion.so/team/Tasks-0123abcd"} {
+		if got, ok := ParseID(s); ok {
+			t.Errorf("ParseID(%q) accepted as %q", s, got)
+		}
+	}
+}
+
+// asPage turns the properties a write sends into the page Notion returns,
+// which carries each text's content as plain_text
+func asPage(t *testing.T, props map[string]interface{}) Page {
+	t.Helper()
+	data, err := json.Marshal(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		t.Fatal(err)
+	}
+	page := Page{ID: "page-1", Properties: map[string]json.RawMessage{}}
+	for name, value := range values {
+		for _, key := range []string{"title", "rich_text"} {
+			texts, _ := value[key].([]interface{})
+			for _, text := range texts {
+				text := text.(map[string]interface{})
+				text["plain_text"] = text["text"].(map[string]interface{})["content"]
+			}
+		}
+		raw, _ := json.Marshal(value)
+		page.Properties[name] = raw
+	}
+	return page
+}
+
+func TestItemRoundTrip(t *testing.T) {
+	due := time.Date(2026, 3, 4, 17, 30, 0, 0, time.UTC)
+	items := []Item{
+		{
+			Kind: KindTask, ID: "t1", Title: "Send the report", Description: strings.Repeat("long ", 500),
+			Done: true, Due: &due, Priority: 4, Category: "work",
+		},
+		{Kind: KindTask, ID: "t2", Title: "No dates", Priority: 3},
+		{Kind: KindGoal, ID: "g1", Title: "Run a marathon", Due: &due, Progress: 37},
+	}
+	for _, item := range items {
+		got, err := ItemFromPage(asPage(t, item.Properties("Task")), item.Kind, "Task")
+		if err != nil {
+			t.Fatalf("%s: %v", item.ID, err)
+		}
+		if got.Hash() != item.Hash() {
+			t.Errorf("%s: read back as %+v", item.ID, got)
+		}
+	}
+
+	edited := items[0]
+	edited.Category = "home"
+	if edited.Hash() == items[0].Hash() {
+		t.Error("changing the category kept the hash")
+	}
+	goal := items[2]
+	goal.Priority = 5
+	if goal.Hash() != items[2].Hash() {
+		t.Error("a goal's hash depends on priority, which goals lack")
+	}
+}
+
+func TestItemFromPageReadsDays(t *testing.T) {
+	page := Page{Properties: map[string]json.RawMessage{
+		PropertyDue: json.RawMessage(`{"type":"date","date":{"start":"2026-05-01"}}`),
+	}}
+	item, err := ItemFromPage(page, KindTask, "Name")
+	if err != nil || item.Due == nil || !item.Due.Equal(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("got %+v, %v", item.Due, err)
+	}
+}
+
+// fakeNotion serves one database and records the columns added to it
+type fakeNotion struct {
+	mu         sync.Mutex
+	properties map[string]DatabaseProperty
+	added      []string
+	queries    int
+	limited    bool
+}
+
+func (f *fakeNotion) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"status":401,"code":"unauthorized","message":"API token is invalid."}`))
+		return
+	}
+	if r.Header.Get("Notion-Version") != Version {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch {
+	case r.URL.Path == "/v1/databases/missing":
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":404,"code":"object_not_found","message":"Could not find database."}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/databases/db":
+		json.NewEncoder(w).Encode(Database{ID: "db", Properties: f.properties})
+	case r.Method == http.MethodPatch && r.URL.Path == "/v1/databases/db":
+		var body struct {
+			Properties map[string]map[string]interface{} `json:"properties"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for name, config := range body.Properties {
+			for typ := range config {
+				f.properties[name] = DatabaseProperty{Name: name, Type: typ}
+			}
+			f.added = append(f.added, name)
+		}
+		json.NewEncoder(w).Encode(Database{ID: "db", Properties: f.properties})
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/databases/db/query":
+		if !f.limited {
+			f.limited = true
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		f.queries++
+		var body struct {
+			StartCursor string `json:"start_cursor"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.StartCursor == "" {
+			w.Write([]byte(`{"results":[{"id":"p1"},{"id":"p2"}],"has_more":true,"next_cursor":"c2"}`))
+		} else {
+			w.Write([]byte(`{"results":[{"id":"p3","archived":true}],"has_more":false,"next_cursor":null}`))
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestEnsureProperties(t *testing.T) {
+	fake := &fakeNotion{properties: map[string]DatabaseProperty{
+		"Task":     {Name: "Task", Type: "title"},
+		"Priority": {Name: "Priority", Type: "number"},
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	ctx := context.Background()
+
+	client := NewClient(srv.URL, "secret", 5*time.Second)
+	database, err := client.EnsureProperties(ctx, "db", KindTask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if database.TitleProperty() != "Task" {
+		t.Errorf("title property %q", database.TitleProperty())
+	}
+	if len(fake.added) != len(columns(KindTask))-1 {
+		t.Errorf("added %v", fake.added)
+	}
+	if _, err := client.EnsureProperties(ctx, "db", KindTask); err != nil || len(fake.added) != len(columns(KindTask))-1 {
+		t.Errorf("second call: %v, added %v", err, fake.added)
+	}
+
+	fake.properties[PropertyCategory] = DatabaseProperty{Name: PropertyCategory, Type: "multi_select"}
+	if _, err := client.EnsureProperties(ctx, "db", KindTask); err == nil || !strings.Contains(err.Error(), PropertyCategory) {
+		t.Errorf("expected the wrongly typed column to be refused, got %v", err)
+	}
+
+	if _, err := client.EnsureProperties(ctx, "missing", KindTask); !IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := NewClient(srv.URL, "wrong", time.Second).Database(ctx, "db"); !IsUnauthorized(err) {
+		t.Errorf("expected unauthorized, got %v", err)
+	}
+}
+
+func TestQueryDatabase(t *testing.T) {
+	fake := &fakeNotion{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	pages, err := NewClient(srv.URL, "secret", 5*time.Second).QueryDatabase(context.Background(), "db", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 || pages[2].ID != "p3" || !pages[2].Archived {
+		t.Errorf("got %+v", pages)
+	}
+	if !fake.limited || fake.queries != 2 {
+		t.Errorf("expected one rate limited request and two pages, got %d", fake.queries)
+	}
+}
@@ -0,0 +1,169 @@
+// Package language detects the language of short task and goal text. It is a
+// lightweight heuristic: writing system first, then common words and task
+// verbs for Latin-script languages. Text that gives no clear signal is left
+// undetermined rather than guessed.
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// names maps supported ISO 639-1 codes to English names
+var names = map[string]string{
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"de": "German",
+	"it": "Italian",
+	"pt": "Portuguese",
+	"nl": "Dutch",
+	"ru": "Russian",
+	"el": "Greek",
+	"ar": "Arabic",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"th": "Thai",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"zh": "Chinese",
+}
+
+// latinWords are frequent function words and task verbs per Latin-script language
+var latinWords = map[string][]string{
+	"en": {"the", "and", "to", "for", "with", "of", "my", "on", "at", "is", "buy", "call", "email", "meeting", "review", "write", "fix", "pay", "send", "book", "clean", "finish", "prepare", "update", "check", "read", "plan", "weekly", "report", "tomorrow", "today"},
+	"es": {"el", "la", "los", "las", "de", "del", "y", "para", "con", "en", "mi", "un", "una", "que", "comprar", "llamar", "pagar", "enviar", "revisar", "reunión", "escribir", "terminar", "preparar", "limpiar", "leer", "mañana", "hoy", "semana", "informe"},
+	"fr": {"le", "la", "les", "des", "du", "et", "pour", "avec", "mon", "ma", "mes", "un", "une", "au", "aux", "acheter", "appeler", "payer", "envoyer", "réunion", "écrire", "finir", "préparer", "nettoyer", "lire", "demain", "aujourd'hui", "rapport"},
+	"de": {"der", "die", "das", "und", "für", "mit", "mein", "meine", "ein", "eine", "zu", "von", "im", "am", "kaufen", "anrufen", "bezahlen", "senden", "schicken", "besprechung", "schreiben", "fertig", "vorbereiten", "putzen", "lesen", "morgen", "heute", "bericht"},
+	"it": {"il", "lo", "gli", "della", "di", "e", "per", "con", "mio", "mia", "un", "una", "che", "comprare", "chiamare", "pagare", "inviare", "riunione", "scrivere", "finire", "preparare", "pulire", "leggere", "domani", "oggi"},
+	"pt": {"o", "os", "as", "do", "da", "dos", "das", "e", "para", "com", "meu", "minha", "um", "uma", "comprar", "ligar", "pagar", "enviar", "reunião", "escrever", "terminar", "preparar", "limpar", "ler", "amanhã", "hoje", "relatório"},
+	"nl": {"de", "het", "een", "en", "voor", "met", "mijn", "van", "op", "te", "kopen", "bellen", "betalen", "sturen", "vergadering", "schrijven", "afmaken", "voorbereiden", "schoonmaken", "lezen", "morgen", "vandaag", "verslag"},
+}
+
+// latinLetters are letters that strongly suggest a language
+var latinLetters = map[rune]string{
+	'ñ': "es", '¿': "es", '¡': "es",
+	'ß': "de", 'ä': "de", 'ö': "de", 'ü': "de",
+	'ã': "pt", 'õ': "pt",
+	'ç': "fr", 'è': "fr", 'ê': "fr", 'œ': "fr",
+	'ì': "it", 'ò': "it",
+}
+
+var wordIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, words := range latinWords {
+		for _, w := range words {
+			index[w] = append(index[w], lang)
+		}
+	}
+	return index
+}()
+
+// Supported reports whether code is a language this package can detect
+func Supported(code string) bool {
+	_, ok := names[code]
+	return ok
+}
+
+// Name returns the English name of a language code, or "" if unsupported
+func Name(code string) string {
+	return names[code]
+}
+
+// Detect returns the ISO 639-1 code of text's language, or "" when undetermined
+func Detect(text string) string {
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+	return detectLatin(text)
+}
+
+// detectScript identifies languages by writing system
+func detectScript(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			counts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			counts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["ru"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		}
+	}
+
+	// Japanese mixes kanji with kana; any kana decides it
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+
+	best, bestCount := "", 0
+	for lang, n := range counts {
+		if n > bestCount {
+			best, bestCount = lang, n
+		}
+	}
+	if bestCount*2 < letters {
+		return ""
+	}
+	return best
+}
+
+// detectLatin scores Latin-script text by known words and distinctive letters
+func detectLatin(text string) string {
+	scores := make(map[string]int)
+	lower := strings.ToLower(text)
+	for _, r := range lower {
+		if lang, ok := latinLetters[r]; ok {
+			scores[lang] += 2
+		}
+	}
+	for _, word := range strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		for _, lang := range wordIndex[word] {
+			scores[lang]++
+		}
+	}
+
+	best, bestScore, tied := "", 0, false
+	for lang, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = lang, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if bestScore == 0 || tied {
+		return ""
+	}
+	return best
+}
+
+// Instruction returns a prompt sentence asking for output in code's language,
+// or "" when the language is unknown
+func Instruction(code string) string {
+	if name := Name(code); name != "" {
+		return "Write all text in " + name + "."
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	cases := map[string]string{
+		"Buy milk and call the dentist":         "en",
+		"Comprar leche y llamar al dentista":    "es",
+		"Préparer la réunion de demain":         "fr",
+		"Bericht für die Besprechung schreiben": "de",
+		"Preparar o relatório para amanhã":      "pt",
+		"買い物に行く":                                "ja",
+		"准备明天的会议":                               "zh",
+		"Позвонить маме":                        "ru",
+		"회의 준비하기":                               "ko",
+		"Xyzzy":                                 "",
+		"":                                      "",
+	}
+	for text, want := range cases {
+		if got := Detect(text); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
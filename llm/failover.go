@@ -0,0 +1,206 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/productivity/mcp-server/claude"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// Health tracks whether a provider is answering. After threshold
+// consecutive failures it is down: Available turns callers away for
+// cooldown, then lets a single probe through. A success brings it back up;
+// a failed probe restarts the cooldown.
+type Health struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	downSince time.Time
+	retryAt   time.Time
+	probing   bool
+	lastError string
+}
+
+// NewHealth creates a tracker for one provider
+func NewHealth(threshold int, cooldown time.Duration) *Health {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Health{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Available reports whether a request should go to the provider: it is up,
+// or its cooldown is over and no other request is probing it
+func (h *Health) Available() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.downSince.IsZero() {
+		return true
+	}
+	if h.probing || h.now().Before(h.retryAt) {
+		return false
+	}
+	h.probing = true
+	return true
+}
+
+// Success records an answer, bringing the provider back up
+func (h *Health) Success() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures = 0
+	h.downSince = time.Time{}
+	h.probing = false
+	h.lastError = ""
+}
+
+// Failure records a failed request. It reports whether the provider went
+// down because of it.
+func (h *Health) Failure(err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures++
+	if err != nil {
+		h.lastError = err.Error()
+	}
+	if h.probing || !h.downSince.IsZero() {
+		h.probing = false
+		h.retryAt = h.now().Add(h.cooldown)
+		return false
+	}
+	if h.failures < h.threshold {
+		return false
+	}
+	h.downSince = h.now()
+	h.retryAt = h.downSince.Add(h.cooldown)
+	return true
+}
+
+// HealthStatus is a provider's health as reported by /admin/stats
+type HealthStatus struct {
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DownSince           *time.Time `json:"down_since,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// Status reports the provider's health
+func (h *Health) Status() HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := HealthStatus{
+		Healthy:             h.downSince.IsZero(),
+		ConsecutiveFailures: h.failures,
+		LastError:           h.lastError,
+	}
+	if !status.Healthy {
+		downSince, retryAt := h.downSince.UTC(), h.retryAt.UTC()
+		status.DownSince, status.RetryAt = &downSince, &retryAt
+	}
+	return status
+}
+
+// Failover sends completions to a primary provider while it is healthy and
+// to a fallback while it is not. A request the primary fails with a
+// provider error is retried on the fallback at once, so callers see an
+// answer rather than the outage.
+type Failover struct {
+	primary, fallback Provider
+	health            map[string]*Health
+}
+
+// NewFailover creates a router that fails over from primary to fallback
+// after threshold consecutive provider errors, probing primary again every
+// cooldown
+func NewFailover(primary, fallback Provider, threshold int, cooldown time.Duration) *Failover {
+	return &Failover{
+		primary:  primary,
+		fallback: fallback,
+		health: map[string]*Health{
+			primary.Name():  NewHealth(threshold, cooldown),
+			fallback.Name(): NewHealth(threshold, cooldown),
+		},
+	}
+}
+
+// Complete sends req to the provider that should answer it and returns the
+// text of the answer with the name of the provider that gave it. A model
+// req names is the primary's; the fallback answers with its own.
+func (f *Failover) Complete(ctx context.Context, req Request) (string, string, error) {
+	if f.health[f.primary.Name()].Available() {
+		text, err := f.try(ctx, f.primary, req)
+		if err == nil || !ProviderFailure(ctx, err) {
+			return text, f.primary.Name(), err
+		}
+		utils.LoggerFromContext(ctx).Warn("LLM provider failed, failing over", map[string]interface{}{
+			"provider": f.primary.Name(),
+			"fallback": f.fallback.Name(),
+			"error":    err.Error(),
+		})
+	}
+	req.Model = ""
+	text, err := f.try(ctx, f.fallback, req)
+	return text, f.fallback.Name(), err
+}
+
+// try asks p and records the outcome in its health
+func (f *Failover) try(ctx context.Context, p Provider, req Request) (string, error) {
+	health := f.health[p.Name()]
+	text, err := p.Complete(ctx, req)
+	switch {
+	case err == nil:
+		if !health.Status().Healthy {
+			utils.LoggerFromContext(ctx).Info("LLM provider recovered", map[string]interface{}{"provider": p.Name()})
+		}
+		health.Success()
+	case ProviderFailure(ctx, err):
+		if health.Failure(err) {
+			utils.LoggerFromContext(ctx).Warn("LLM provider marked down", map[string]interface{}{
+				"provider": p.Name(),
+				"error":    err.Error(),
+			})
+		}
+	}
+	return text, err
+}
+
+// Status reports the health of both providers by name
+func (f *Failover) Status() map[string]HealthStatus {
+	status := make(map[string]HealthStatus, len(f.health))
+	for name, health := range f.health {
+		status[name] = health.Status()
+	}
+	return status
+}
+
+// ProviderFailure reports whether err means the provider is unwell rather
+// than the request being at fault: a rate limit, a server error or a failed
+// connection. Requests the caller cancelled or that ran out of time are not
+// held against the provider.
+func ProviderFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *claude.APIError
+	if errors.As(err, &apiErr) {
+		return failureStatus(apiErr.StatusCode)
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return failureStatus(statusErr.StatusCode)
+	}
+	var outputErr *claude.OutputError
+	return !errors.As(err, &outputErr)
+}
+
+func failureStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
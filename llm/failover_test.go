@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/claude"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/mockllm"
+)
+
+// fakeClaude fails with status while it is non-zero
+type fakeClaude struct {
+	status int
+	calls  int
+}
+
+func (f *fakeClaude) Name() string { return ProviderClaude }
+
+func (f *fakeClaude) Complete(ctx context.Context, req Request) (string, error) {
+	f.calls++
+	if f.status != 0 {
+		return "", &claude.APIError{StatusCode: f.status, Status: http.StatusText(f.status)}
+	}
+	return "from claude", nil
+}
+
+func TestFailover(t *testing.T) {
+	srv := httptest.NewServer(mockllm.NewHandler())
+	defer srv.Close()
+
+	primary := &fakeClaude{status: http.StatusServiceUnavailable}
+	f := NewFailover(primary, NewOllama(config.Ollama{URL: srv.URL, Model: "llama3"}), 2, time.Minute)
+	now := time.Now()
+	f.health[ProviderClaude].now = func() time.Time { return now }
+	req := Request{Messages: []map[string]interface{}{{"role": "user", "content": "hello"}}, Model: "claude-3-5-haiku-latest"}
+
+	complete := func() (string, string) {
+		t.Helper()
+		text, provider, err := f.Complete(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return text, provider
+	}
+
+	// Each failure fails over at once; the threshold marks Claude down
+	for i := 1; i <= 2; i++ {
+		if text, provider := complete(); provider != ProviderOllama || text == "" {
+			t.Fatalf("call %d: answered by %s with %q", i, provider, text)
+		}
+	}
+	if status := f.Status()[ProviderClaude]; status.Healthy || status.ConsecutiveFailures != 2 {
+		t.Fatalf("claude status after threshold: %+v", status)
+	}
+
+	// While down Claude is skipped
+	complete()
+	if primary.calls != 2 {
+		t.Fatalf("claude called %d times while down", primary.calls)
+	}
+
+	// After the cooldown one request probes Claude and fails back
+	primary.status = 0
+	now = now.Add(2 * time.Minute)
+	if text, provider := complete(); provider != ProviderClaude || text != "from claude" {
+		t.Fatalf("after cooldown: answered by %s with %q", provider, text)
+	}
+	if status := f.Status()[ProviderClaude]; !status.Healthy || status.ConsecutiveFailures != 0 {
+		t.Fatalf("claude status after recovery: %+v", status)
+	}
+
+	// Errors of the request's own making are returned, not failed over
+	primary.status = http.StatusBadRequest
+	if _, provider, err := f.Complete(context.Background(), req); err == nil || provider != ProviderClaude {
+		t.Fatalf("bad request: provider %s, err %v", provider, err)
+	}
+	if status := f.Status()[ProviderClaude]; !status.Healthy || status.ConsecutiveFailures != 0 {
+		t.Fatalf("bad request counted against claude: %+v", status)
+	}
+}
+
+func TestHealthProbesOnce(t *testing.T) {
+	h := NewHealth(1, time.Minute)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	if !h.Failure(nil) || h.Available() {
+		t.Fatal("one failure at threshold 1 should mark the provider down")
+	}
+	now = now.Add(time.Minute)
+	if !h.Available() || h.Available() {
+		t.Fatal("the cooldown should let exactly one probe through")
+	}
+	h.Failure(nil)
+	if h.Available() {
+		t.Fatal("a failed probe should restart the cooldown")
+	}
+}
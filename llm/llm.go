@@ -0,0 +1,76 @@
+// Package llm routes completions between language model providers. Claude
+// answers while it is healthy; once it keeps failing with rate limits (429)
+// or server errors (5xx), requests fail over to a local Ollama model until a
+// probe finds Claude answering again.
+package llm
+
+import (
+	"context"
+
+	"github.com/productivity/mcp-server/claude"
+)
+
+// Provider names reported as provider_used
+const (
+	ProviderClaude = "claude"
+	ProviderOllama = "ollama"
+)
+
+// Request is a conversation for a provider to complete
+type Request struct {
+	// Messages are {"role", "content"} maps
+	Messages []map[string]interface{}
+	// Model overrides the provider's configured model when set
+	Model string
+	Params
+}
+
+// Params tune one request; zero values keep the provider's settings
+type Params struct {
+	MaxTokens   int
+	Temperature *float64
+}
+
+type paramsKey struct{}
+
+// WithParams applies p to every request made with the returned context
+func WithParams(ctx context.Context, p Params) context.Context {
+	return context.WithValue(ctx, paramsKey{}, p)
+}
+
+// ParamsFrom returns the Params WithParams set on ctx, or none
+func ParamsFrom(ctx context.Context) Params {
+	p, _ := ctx.Value(paramsKey{}).(Params)
+	return p
+}
+
+// Provider completes conversations
+type Provider interface {
+	Name() string
+	Complete(ctx context.Context, req Request) (string, error)
+}
+
+// Claude adapts a Claude client to Provider
+func Claude(client *claude.Client) Provider {
+	return claudeProvider{client}
+}
+
+type claudeProvider struct {
+	client *claude.Client
+}
+
+func (p claudeProvider) Name() string {
+	return ProviderClaude
+}
+
+func (p claudeProvider) Complete(ctx context.Context, req Request) (string, error) {
+	resp, err := p.client.CompleteWith(ctx, req.Messages, claude.Params{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
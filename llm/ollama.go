@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// ollamaTimeout bounds one chat request; local models answer slowly
+const ollamaTimeout = 120 * time.Second
+
+// Ollama completes conversations with a model on an Ollama server through
+// its /api/chat endpoint
+type Ollama struct {
+	url        string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOllama creates an Ollama provider from the Ollama configuration
+func NewOllama(cfg config.Ollama) *Ollama {
+	return &Ollama{
+		url:        strings.TrimRight(cfg.URL, "/"),
+		model:      cfg.Model,
+		httpClient: &http.Client{Transport: utils.NewLoggingTransport(ProviderOllama, nil), Timeout: ollamaTimeout},
+	}
+}
+
+// Name is the provider name reported as provider_used
+func (o *Ollama) Name() string {
+	return ProviderOllama
+}
+
+// Model is the model the provider asks for
+func (o *Ollama) Model() string {
+	return o.model
+}
+
+// Complete sends the conversation to the model and returns the text of its
+// answer
+func (o *Ollama) Complete(ctx context.Context, req Request) (string, error) {
+	model := o.model
+	if req.Model != "" {
+		model = req.Model
+	}
+	payload := map[string]interface{}{
+		"model":    model,
+		"messages": req.Messages,
+		"stream":   false,
+	}
+	options := map[string]interface{}{}
+	if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if len(options) > 0 {
+		payload["options"] = options
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", &StatusError{Provider: ProviderOllama, StatusCode: resp.StatusCode, Body: string(raw)}
+	}
+
+	var result struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode Ollama response: %w", err)
+	}
+	return result.Message.Content, nil
+}
+
+// StatusError is a provider answering with an HTTP error status
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s error: %d %s - %s", e.Provider, e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
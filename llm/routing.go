@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Target is the provider and model a request goes to. An empty Model is the
+// provider's configured one.
+type Target struct {
+	Provider string
+	Model    string
+}
+
+// ParseTarget reads a model as routes and overrides name it: a Claude model
+// such as claude-3-5-haiku-latest, or ollama: followed by an Ollama model,
+// such as ollama:qwen3-coder:480b-cloud
+func ParseTarget(spec string) (Target, error) {
+	spec = strings.TrimSpace(spec)
+	if model, ok := strings.CutPrefix(spec, ProviderOllama+":"); ok {
+		if model == "" {
+			return Target{}, fmt.Errorf("%q names no Ollama model", spec)
+		}
+		return Target{Provider: ProviderOllama, Model: model}, nil
+	}
+	if spec == "" || strings.ContainsAny(spec, " \t,=") {
+		return Target{}, fmt.Errorf("%q is not a model name", spec)
+	}
+	return Target{Provider: ProviderClaude, Model: spec}, nil
+}
+
+// String writes t as ParseTarget reads it
+func (t Target) String() string {
+	if t.Provider == ProviderOllama {
+		return ProviderOllama + ":" + t.Model
+	}
+	return t.Model
+}
+
+// Routes maps operations, such as parse_task, to the target their requests go to
+type Routes map[string]Target
+
+// ParseRoutes reads operation=model rules, such as
+// parse_task=claude-3-5-haiku-latest. Operations must be among known.
+func ParseRoutes(rules []string, known []string) (Routes, error) {
+	routes := make(Routes, len(rules))
+	for _, rule := range rules {
+		operation, spec, ok := strings.Cut(rule, "=")
+		operation = strings.TrimSpace(operation)
+		if !ok || operation == "" {
+			return nil, fmt.Errorf("rule %q is not operation=model", rule)
+		}
+		if !slices.Contains(known, operation) {
+			return nil, fmt.Errorf("rule %q: unknown operation %q (expected one of %s)", rule, operation, strings.Join(known, ", "))
+		}
+		if _, dup := routes[operation]; dup {
+			return nil, fmt.Errorf("operation %q is routed twice", operation)
+		}
+		target, err := ParseTarget(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule, err)
+		}
+		routes[operation] = target
+	}
+	return routes, nil
+}
+
+// Models lists the distinct models the routes name, sorted
+func (r Routes) Models() []string {
+	seen := map[string]bool{}
+	var models []string
+	for _, target := range r {
+		if spec := target.String(); !seen[spec] {
+			seen[spec] = true
+			models = append(models, spec)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+type overrideKey struct{}
+
+// WithOverride sends every request made with the returned context to t,
+// whatever the routes say
+func WithOverride(ctx context.Context, t Target) context.Context {
+	return context.WithValue(ctx, overrideKey{}, t)
+}
+
+// OverrideFrom returns the target WithOverride set on ctx
+func OverrideFrom(ctx context.Context) (Target, bool) {
+	t, ok := ctx.Value(overrideKey{}).(Target)
+	return t, ok
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseRoutes(t *testing.T) {
+	known := []string{"parse_task", "parse_file", "analyze_productivity"}
+	routes, err := ParseRoutes([]string{
+		"parse_task=claude-3-5-haiku-latest",
+		" parse_file = ollama:qwen3-coder:480b-cloud",
+	}, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := routes["parse_task"]; got != (Target{Provider: ProviderClaude, Model: "claude-3-5-haiku-latest"}) {
+		t.Errorf("parse_task routed to %+v", got)
+	}
+	if got := routes["parse_file"]; got != (Target{Provider: ProviderOllama, Model: "qwen3-coder:480b-cloud"}) {
+		t.Errorf("parse_file routed to %+v", got)
+	}
+	if models := routes.Models(); strings.Join(models, ",") != "claude-3-5-haiku-latest,ollama:qwen3-coder:480b-cloud" {
+		t.Errorf("models %v", models)
+	}
+
+	for _, rules := range [][]string{
+		{"parse_task"},
+		{"code_review=ollama:qwen3-coder"},
+		{"parse_task=ollama:"},
+		{"parse_task=claude-3-5-haiku-latest", "parse_task=claude-3-5-sonnet-latest"},
+	} {
+		if _, err := ParseRoutes(rules, known); err == nil {
+			t.Errorf("%v: expected an error", rules)
+		}
+	}
+
+	ctx := WithOverride(context.Background(), Target{Provider: ProviderOllama, Model: "llama3.2"})
+	if got, ok := OverrideFrom(ctx); !ok || got.String() != "ollama:llama3.2" {
+		t.Errorf("override %+v, %v", got, ok)
+	}
+}
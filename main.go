@@ -1,221 +1,138 @@
+//go:build !lite
+
 package main
 
 import (
 	"context"
-	"fmt"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/doctor"
 	"github.com/productivity/mcp-server/handlers"
-	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/mockllm"
+	"github.com/productivity/mcp-server/mocksupabase"
+	"github.com/productivity/mcp-server/recording"
+	"github.com/productivity/mcp-server/seed"
+	"github.com/productivity/mcp-server/setup"
+	"github.com/productivity/mcp-server/signing"
 	"github.com/productivity/mcp-server/utils"
 )
 
+// subcommands run instead of the server when named as the first argument
+var subcommands = map[string]func(ctx context.Context, args []string) error{
+	"setup": func(ctx context.Context, args []string) error {
+		return setup.Run(ctx, args, os.Stdin, os.Stdout)
+	},
+	"doctor": func(ctx context.Context, args []string) error {
+		return doctor.Run(ctx, args, os.Stdout)
+	},
+	"mockllm": func(ctx context.Context, args []string) error {
+		return mockllm.Run(ctx, args, os.Stdout)
+	},
+	"mocksupabase": func(ctx context.Context, args []string) error {
+		return mocksupabase.Run(ctx, args, os.Stdout)
+	},
+	"seed": func(ctx context.Context, args []string) error {
+		return seed.Run(ctx, args, os.Stdout)
+	},
+	"keygen": func(ctx context.Context, args []string) error {
+		return signing.Keygen(ctx, args, os.Stdout)
+	},
+	"admintoken": func(ctx context.Context, args []string) error {
+		return signing.AdminToken(ctx, args, os.Stdout)
+	},
+	"servicetoken": func(ctx context.Context, args []string) error {
+		return signing.ServiceToken(ctx, args, os.Stdout)
+	},
+	"replay": func(ctx context.Context, args []string) error {
+		return recording.Replay(ctx, args, os.Stdout)
+	},
+}
+
 func main() {
 	// Load environment variables
 	godotenv.Load()
 
+	// Subcommands run instead of the server
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if err := run(ctx, os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	configFile := flag.String("config", "", "path to a YAML or TOML config file (default $"+config.FileEnv+")")
+	flag.Parse()
+
 	// Initialize logger
 	logger := utils.NewLogger()
 	logger.Info("Starting productivity MCP server")
 
-	// Get configuration
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Load and validate all settings; every problem is reported at once
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		logger.Error("Invalid configuration", err)
+		log.Fatal(err)
 	}
-
-	supabaseURL := os.Getenv("SUPABASE_URL")
-	supabaseKey := os.Getenv("SUPABASE_ANON_KEY")
-	claudeAPIKey := os.Getenv("CLAUDE_API_KEY")
-
-	if supabaseURL == "" || supabaseKey == "" {
-		logger.Error("Missing required environment variables", nil,
-			map[string]interface{}{
-				"supabase_url_set": supabaseURL != "",
-				"supabase_key_set": supabaseKey != "",
-			},
-		)
-		log.Fatal("Missing SUPABASE_URL or SUPABASE_ANON_KEY environment variables")
+	logger.SetLevel(utils.LogLevel(cfg.Log.Level))
+	redactor, err := utils.NewRedactor(cfg.Log.RedactFields, cfg.Log.RedactPatterns)
+	if err != nil {
+		log.Fatalf("Invalid log redaction configuration: %v", err)
 	}
-
-	// Set Gin mode
-	if os.Getenv("GIN_MODE") == "" {
-		gin.SetMode(gin.ReleaseMode)
+	logger.SetRedactor(redactor)
+	utils.SetDefaultLogger(logger)
+	handlers.SetOAuthTracing(cfg.Log.DebugOAuth, cfg.Log.DebugOAuthSampleRate)
+
+	// Ship logs to the configured sinks; each buffers so logging never blocks requests
+	sinks, err := utils.NewSinks(cfg.Log)
+	if err != nil {
+		log.Fatalf("Invalid log sink configuration: %v", err)
 	}
+	logger.SetSinks(sinks...)
+	defer logger.Close()
 
-	// Initialize Gin router
-	router := gin.New()
-	
-	// Enable route debugging in development
-	if os.Getenv("GIN_MODE") != "release" {
-		gin.DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, nuHandlers int) {
-			logger.Info("Route registered",
-				map[string]interface{}{
-					"method":      httpMethod,
-					"path":        absolutePath,
-					"handler":     handlerName,
-					"num_handlers": nuHandlers,
-				},
-			)
-		}
+	if cfg.File != "" {
+		logger.Info("Loaded config file", map[string]interface{}{"file": cfg.File})
 	}
-
-	// Add recovery middleware with logging
-	router.Use(middleware.Recovery(logger))
-
-	// Add request ID middleware
-	router.Use(middleware.RequestID())
-
-	// Add request logging middleware
-	router.Use(middleware.RequestLogger(logger))
-
-	// Add CORS middleware
-	router.Use(middleware.CORSMiddleware())
-
-	// Enhanced health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		health := gin.H{
-			"status":  "ok",
-			"service": "productivity-mcp-server",
-			"timestamp": time.Now().UTC().Format(time.RFC3339),
-		}
-
-		// Check dependencies
-		deps := gin.H{}
-		if supabaseURL != "" {
-			deps["supabase"] = "configured"
-		}
-		if claudeAPIKey != "" {
-			deps["claude"] = "configured"
-		}
-		health["dependencies"] = deps
-
-		c.JSON(http.StatusOK, health)
-	})
-
-	// Readiness check (more detailed)
-	router.GET("/ready", func(c *gin.Context) {
-		ready := true
-		checks := gin.H{}
-
-		// Check Supabase connectivity (basic check)
-		if supabaseURL == "" || supabaseKey == "" {
-			ready = false
-			checks["supabase"] = "not_configured"
-		} else {
-			checks["supabase"] = "configured"
-		}
-
-		status := http.StatusOK
-		if !ready {
-			status = http.StatusServiceUnavailable
-		}
-
-		c.JSON(status, gin.H{
-			"ready":   ready,
-			"checks":   checks,
-			"timestamp": time.Now().UTC().Format(time.RFC3339),
-		})
-	})
-
-	// Initialize handlers with dependencies
-	taskHandler := handlers.NewTaskHandler(supabaseURL, supabaseKey)
-	goalHandler := handlers.NewGoalHandler(supabaseURL, supabaseKey)
-	claudeHandler := handlers.NewClaudeHandler(supabaseURL, supabaseKey, claudeAPIKey)
-
-	// Task routes
-	tasks := router.Group("/api/tasks")
-	{
-		tasks.POST("", taskHandler.CreateTask)
-		tasks.GET("", taskHandler.ListTasks)
-		tasks.GET("/:id", taskHandler.GetTask)
-		tasks.PUT("/:id", taskHandler.UpdateTask)
-		tasks.DELETE("/:id", taskHandler.DeleteTask)
-		tasks.GET("/user/:userId", taskHandler.GetUserTasks)
+	if cfg.Auth.GeneratedSecret && len(cfg.Auth.SigningKeys) == 0 {
+		logger.Warn("Using auto-generated JWT secret for development. Set JWT_SECRET in production!")
 	}
 
-	// Goal routes
-	goals := router.Group("/api/goals")
-	{
-		goals.POST("", goalHandler.CreateGoal)
-		goals.GET("", goalHandler.ListGoals)
-		goals.GET("/:id", goalHandler.GetGoal)
-		goals.PUT("/:id", goalHandler.UpdateGoal)
-		goals.DELETE("/:id", goalHandler.DeleteGoal)
-		goals.GET("/user/:userId", goalHandler.GetUserGoals)
-	}
+	port := cfg.Server.Port
 
-	// Claude/MCP routes
-	mcp := router.Group("/api/mcp")
-	{
-		mcp.POST("/parse-task", claudeHandler.ParseTask)
-		mcp.POST("/parse-file", claudeHandler.ParseFile)
-		mcp.POST("/generate-subtasks", claudeHandler.GenerateSubtasks)
-		mcp.POST("/analyze-productivity", claudeHandler.AnalyzeProductivity)
+	// Set Gin mode
+	if cfg.Server.GinMode == "" {
+		gin.SetMode(gin.ReleaseMode)
+	} else {
+		gin.SetMode(cfg.Server.GinMode)
 	}
 
-	// OAuth 2.1 endpoints for MCP authentication
-	// Register OAuth routes BEFORE MCP routes to ensure they're matched first
-	// #region agent log
-	logger.Info("Registering OAuth routes", map[string]interface{}{
-		"routes": []string{"/.well-known/oauth-authorization-server", "/authorize", "/oauth/authorize", "/oauth/token"},
-	})
-	// #endregion
-	
-	// OAuth 2.1 discovery endpoint (RFC 8414) - must be exact path match
-	router.GET("/.well-known/oauth-authorization-server", handlers.OAuthDiscovery)
-	
-	// OAuth authorization endpoints - support both patterns
-	router.GET("/authorize", handlers.OAuthAuthorize)
-	router.GET("/oauth/authorize", handlers.OAuthAuthorize)
-	
-	// OAuth token and management endpoints
-	router.POST("/oauth/token", handlers.OAuthToken)
-	router.POST("/oauth/introspect", handlers.OAuthIntrospect)
-	router.POST("/oauth/register", handlers.OAuthRegister) // Client registration
-	
-	logger.Info("OAuth routes registered successfully")
-
-	// MCP Protocol routes (protected with authentication)
-	mcpHandler := handlers.NewMCPHandler(taskHandler, goalHandler, claudeHandler)
-	mcpGroup := router.Group("/mcp")
-	mcpGroup.Use(middleware.AuthMiddleware()) // Require authentication for MCP endpoints
-	{
-		mcpGroup.POST("/initialize", handlers.MCPInitialize)
-		mcpGroup.POST("/call_tool", mcpHandler.MCPCallTool)
-		mcpGroup.POST("/list_tools", handlers.MCPListTools)
+	// Background workers stop when the server shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	router, err := newRouter(workerCtx, cfg, logger)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// 404 handler for debugging - log all unmatched routes
-	router.NoRoute(func(c *gin.Context) {
-		logger.Warn("Route not found",
-			map[string]interface{}{
-				"method": c.Request.Method,
-				"path":   c.Request.URL.Path,
-				"query":  c.Request.URL.RawQuery,
-			},
-		)
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "not_found",
-			"message": fmt.Sprintf("Route %s %s not found", c.Request.Method, c.Request.URL.Path),
-			"path":    c.Request.URL.Path,
-		})
-	})
-
 	// Create HTTP server with timeouts
 	srv := &http.Server{
 		Addr:         ":" + port,
 		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout.Duration,
+		WriteTimeout: cfg.Server.WriteTimeout.Duration,
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration,
 	}
 
 	// Start server in goroutine
@@ -242,11 +159,23 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server")
+	stopWorkers()
 
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
 	defer cancel()
 
+	// Let in-flight MCP tool calls finish, then save the sessions for the
+	// instance replacing this one
+	drainCtx, cancelDrain := context.WithTimeout(ctx, cfg.MCP.DrainTimeout.Duration)
+	cancelled := handlers.DrainMCP(drainCtx)
+	cancelDrain()
+	saved, err := handlers.SaveMCPSessions()
+	if err != nil {
+		logger.Error("Failed to save MCP sessions", err)
+	}
+	logger.Info("MCP drained", map[string]interface{}{"cancelled_calls": cancelled, "saved_sessions": saved})
+
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Error("Server forced to shutdown", err)
 		log.Fatal("Server forced to shutdown:", err)
@@ -0,0 +1,102 @@
+//go:build lite
+
+// The lite build is a single-user MCP server for Claude Desktop and other
+// clients that launch it on demand as a subprocess. It speaks MCP on
+// stdin/stdout, stores everything in a local SQLite database and leaves out
+// Ollama, the integrations (triggers, Shortcuts, imports, event publishers)
+// and the HTTP listener:
+//
+//	CGO_ENABLED=1 go build -tags lite -ldflags="-s -w" -o productivity-mcp-lite .
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/embeddings"
+	"github.com/productivity/mcp-server/handlers"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/prompts"
+	"github.com/productivity/mcp-server/stdio"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func main() {
+	// stdout carries the protocol; every log line goes to stderr
+	log.SetOutput(os.Stderr)
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = io.Discard
+	gin.DefaultErrorWriter = os.Stderr
+
+	godotenv.Load()
+
+	configFile := flag.String("config", "", "path to a YAML or TOML config file (default $"+config.FileEnv+")")
+	flag.Parse()
+
+	logger := utils.NewLogger()
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger.SetLevel(utils.LogLevel(cfg.Log.Level))
+	redactor, err := utils.NewRedactor(cfg.Log.RedactFields, cfg.Log.RedactPatterns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger.SetRedactor(redactor)
+	utils.SetDefaultLogger(logger)
+
+	dbURL := db.SQLiteScheme + cfg.Lite.Database
+	handlers.SetAuditLog(handlers.NewAuditLog(dbURL, ""))
+	handlers.SetPreferencesStore(dbURL, "")
+	handlers.SetMemoryStore(dbURL, "")
+	embedder, err := embeddings.New(cfg.Embeddings, cfg.Ollama.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	handlers.SetEmbedder(embedder)
+	promptTemplates, err := prompts.Load(cfg.Claude.PromptsDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	handlers.SetPrompts(promptTemplates)
+
+	taskHandler := handlers.NewTaskHandler(dbURL, "")
+	goalHandler := handlers.NewGoalHandler(dbURL, "")
+	claudeHandler := handlers.NewClaudeHandler(dbURL, "", cfg.Claude)
+	claudeHandler.SetRequestLimits(cfg.Routing.AllowedModels, cfg.Routing.MaxTokensCap)
+	mcpHandler := handlers.NewMCPHandler(taskHandler, goalHandler, claudeHandler, handlers.NewUndoHandler(dbURL, ""))
+	mcpHandler.SetRequireDryRun(cfg.MCP.RequireDryRun)
+	featureFlags, err := handlers.NewFeatureFlags(cfg.Flags)
+	if err != nil {
+		log.Fatal(err)
+	}
+	handlers.SetFeatureFlags(featureFlags)
+
+	// The local user owns everything; there is no authentication on stdio
+	router := gin.New()
+	router.Use(middleware.Recovery(logger), func(c *gin.Context) {
+		c.Set("user_id", cfg.Lite.UserID)
+		c.Next()
+	}, middleware.ErrorHandler(logger))
+	router.POST("/mcp/initialize", handlers.MCPInitialize)
+	router.POST("/mcp/list_tools", mcpHandler.MCPListTools)
+	router.POST("/mcp/call_tool", mcpHandler.MCPCallTool)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Debug("Serving MCP on stdio", map[string]interface{}{"database": cfg.Lite.Database})
+	if err := stdio.NewServer(router).Serve(ctx, os.Stdin, os.Stdout); err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+}
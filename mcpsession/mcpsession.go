@@ -0,0 +1,210 @@
+// Package mcpsession tracks sessions of the MCP streamable HTTP transport.
+// initialize issues an Mcp-Session-Id recording the protocol version, user
+// and client agreed on then; later requests carry the ID back. Sessions live
+// in memory and expire once they have sat idle for the configured timeout.
+// On shutdown the server snapshots them so the next instance can pick them up
+// through a Loader instead of sending clients back to initialize.
+package mcpsession
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Header carries the session ID on requests and on the initialize response
+const Header = "Mcp-Session-Id"
+
+// ClientInfo identifies the MCP client that opened a session
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Session is the state negotiated on initialize
+type Session struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"user_id"`
+	ProtocolVersion string     `json:"protocol_version"`
+	ClientInfo      ClientInfo `json:"client_info"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastSeenAt      time.Time  `json:"last_seen_at"`
+}
+
+// Loader looks up a session this store does not hold, such as one saved by
+// the instance that ran before a restart
+type Loader func(id string) (Session, bool)
+
+// Store holds the open sessions
+type Store struct {
+	idle   time.Duration
+	now    func() time.Time
+	loader Loader
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewStore creates a store whose sessions expire after idle without a request
+func NewStore(idle time.Duration) *Store {
+	return &Store{
+		idle:     idle,
+		now:      time.Now,
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Create opens a session for userID and returns it
+func (s *Store) Create(userID, protocolVersion string, client ClientInfo) (Session, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return Session{}, err
+	}
+	now := s.now()
+	session := &Session{
+		ID:              hex.EncodeToString(raw),
+		UserID:          userID,
+		ProtocolVersion: protocolVersion,
+		ClientInfo:      client,
+		CreatedAt:       now,
+		LastSeenAt:      now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.ID] = session
+	return *session, nil
+}
+
+// SetLoader makes Get consult load for sessions the store does not hold. It
+// must be called before the store is in use.
+func (s *Store) SetLoader(load Loader) {
+	s.loader = load
+}
+
+// Get returns the session with id and marks it as used, or false if it is
+// unknown or has expired
+func (s *Store) Get(id string) (Session, bool) {
+	if session, ok := s.get(id); ok {
+		return session, true
+	}
+	if s.loader == nil {
+		return Session{}, false
+	}
+	session, ok := s.loader(id)
+	if !ok || session.ID != id {
+		return Session{}, false
+	}
+	// Another request may have restored it meanwhile; either way it is held now
+	// unless it has expired
+	s.Restore(session)
+	return s.get(id)
+}
+
+func (s *Store) get(id string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	now := s.now()
+	if s.expired(session, now) {
+		delete(s.sessions, id)
+		return Session{}, false
+	}
+	session.LastSeenAt = now
+	return *session, true
+}
+
+// Delete ends a session, reporting whether it was open
+func (s *Store) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.sessions[id]
+	delete(s.sessions, id)
+	return ok
+}
+
+// Restore adds a session saved elsewhere, reporting false if it has expired
+// or the store already holds one with its ID
+func (s *Store) Restore(session Session) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[session.ID]; ok || s.expired(&session, s.now()) {
+		return false
+	}
+	s.sessions[session.ID] = &session
+	return true
+}
+
+// Snapshot returns a copy of every session that has not expired
+func (s *Store) Snapshot() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	sessions := make([]Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		if !s.expired(session, now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions
+}
+
+// Idle returns how long a session lasts without a request
+func (s *Store) Idle() time.Duration {
+	return s.idle
+}
+
+// Len returns the number of sessions held, including expired ones not yet pruned
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Run prunes expired sessions on every interval until ctx is cancelled
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.prune()
+		}
+	}
+}
+
+// Prune drops expired sessions now and returns how many
+func (s *Store) Prune() int {
+	return s.prune()
+}
+
+func (s *Store) prune() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	pruned := 0
+	for id, session := range s.sessions {
+		if s.expired(session, now) {
+			delete(s.sessions, id)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+func (s *Store) expired(session *Session, now time.Time) bool {
+	return now.Sub(session.LastSeenAt) >= s.idle
+}
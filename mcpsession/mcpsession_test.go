@@ -0,0 +1,90 @@
+package mcpsession
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionsExpireWhenIdle(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore(30 * time.Minute)
+	store.now = func() time.Time { return now }
+
+	active, err := store.Create("u1", "2024-11-05", ClientInfo{Name: "claude-ai", Version: "1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle, _ := store.Create("u2", "2024-11-05", ClientInfo{})
+	if active.ID == idle.ID || len(active.ID) != 32 {
+		t.Fatalf("session IDs %q and %q", active.ID, idle.ID)
+	}
+
+	// Each use pushes the expiry back
+	now = now.Add(20 * time.Minute)
+	if got, ok := store.Get(active.ID); !ok || got.UserID != "u1" || got.ClientInfo.Name != "claude-ai" {
+		t.Fatalf("Get = %+v, %v", got, ok)
+	}
+	now = now.Add(20 * time.Minute)
+	if _, ok := store.Get(active.ID); !ok {
+		t.Error("session used 20 minutes ago expired")
+	}
+
+	if n := store.Prune(); n != 1 {
+		t.Errorf("pruned %d sessions, want 1", n)
+	}
+	if store.Len() != 1 {
+		t.Errorf("%d sessions after prune, want 1", store.Len())
+	}
+	if _, ok := store.Get(idle.ID); ok {
+		t.Error("idle session still open")
+	}
+
+	if !store.Delete(active.ID) || store.Delete(active.ID) {
+		t.Error("Delete should report only the first removal")
+	}
+}
+
+func TestSnapshotAndLoader(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	old := NewStore(30 * time.Minute)
+	old.now = func() time.Time { return now }
+	live, _ := old.Create("u1", "2025-06-18", ClientInfo{Name: "claude-ai"})
+	stale, _ := old.Create("u2", "2025-06-18", ClientInfo{})
+	now = now.Add(20 * time.Minute)
+	old.Get(live.ID)
+	now = now.Add(15 * time.Minute)
+
+	snapshot := old.Snapshot()
+	if len(snapshot) != 1 || snapshot[0].ID != live.ID {
+		t.Fatalf("Snapshot = %+v", snapshot)
+	}
+
+	// A new instance finds the saved session when the client comes back
+	saved := map[string]Session{live.ID: snapshot[0], stale.ID: stale}
+	loads := 0
+	restarted := NewStore(30 * time.Minute)
+	restarted.now = func() time.Time { return now }
+	restarted.SetLoader(func(id string) (Session, bool) {
+		loads++
+		session, ok := saved[id]
+		return session, ok
+	})
+
+	got, ok := restarted.Get(live.ID)
+	if !ok || got.UserID != "u1" || got.ClientInfo.Name != "claude-ai" || !got.LastSeenAt.Equal(now) {
+		t.Fatalf("Get after restart = %+v, %v", got, ok)
+	}
+	restarted.Get(live.ID)
+	if loads != 1 {
+		t.Errorf("loader called %d times, want 1", loads)
+	}
+	if _, ok := restarted.Get(stale.ID); ok {
+		t.Error("expired session was restored")
+	}
+	if _, ok := restarted.Get("unknown"); ok {
+		t.Error("unknown session was found")
+	}
+	if restarted.Restore(got) {
+		t.Error("Restore replaced a session already held")
+	}
+}
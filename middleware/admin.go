@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/signing"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// AdminOnly restricts a route to the given user IDs (ADMIN_USER_IDS) and to
+// bearer JWTs carrying the admin role claim (minted with `admintoken`).
+// It must run after AuthMiddleware, which sets the authenticated user_id.
+// API keys are never accepted on admin routes.
+func AdminOnly(adminUserIDs []string) gin.HandlerFunc {
+	admins := make(map[string]bool)
+	for _, id := range adminUserIDs {
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") == "api_key" {
+			c.Error(utils.ErrForbidden("admin routes do not accept API keys"))
+			c.Abort()
+			return
+		}
+		if !admins[c.GetString("user_id")] && !hasAdminRole(c) {
+			c.Error(utils.ErrForbidden("admin access required"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasAdminRole reports whether the request's bearer JWT has role=admin
+func hasAdminRole(c *gin.Context) bool {
+	claims, err := validateJWT(c.GetString("auth_token"))
+	return err == nil && claims["role"] == signing.AdminRole
+}
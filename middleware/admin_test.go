@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetJWTSecret("test-secret")
+	defer SetJWTSecret("")
+
+	router := gin.New()
+	router.Use(ErrorHandler(nil))
+	router.GET("/admin/users", AuthMiddleware(), AdminOnly([]string{"ops-1"}), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for name, tt := range map[string]struct {
+		claims jwt.MapClaims
+		want   int
+	}{
+		"listed admin":       {jwt.MapClaims{"sub": "ops-1"}, http.StatusOK},
+		"admin role claim":   {jwt.MapClaims{"sub": "operator", "role": "admin"}, http.StatusOK},
+		"other role claim":   {jwt.MapClaims{"sub": "user-1", "role": "authenticated"}, http.StatusForbidden},
+		"ordinary user":      {jwt.MapClaims{"sub": "user-1"}, http.StatusForbidden},
+		"expired admin role": {jwt.MapClaims{"sub": "operator", "role": "admin", "exp": time.Now().Add(-time.Minute).Unix()}, http.StatusUnauthorized},
+	} {
+		if _, ok := tt.claims["exp"]; !ok {
+			tt.claims["exp"] = time.Now().Add(time.Hour).Unix()
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tt.claims).SignedString([]byte("test-secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", name, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
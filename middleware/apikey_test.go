@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+type fakeAPIKeyStore map[string]*APIKey
+
+func (s fakeAPIKeyStore) LookupAPIKey(key string) (*APIKey, error) {
+	return s[key], nil
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetAPIKeyStore(fakeAPIKeyStore{
+		"reader": {ID: "k1", UserID: "user-1", Scopes: []string{ScopeRead}},
+		"agent":  {ID: "k2", UserID: "user-2", Scopes: []string{ScopeMCP}},
+	})
+	defer SetAPIKeyStore(nil)
+
+	router := gin.New()
+	router.Use(ErrorHandler(utils.NewLogger()))
+	api := router.Group("/api", OptionalAuthMiddleware())
+	api.GET("/tasks", func(c *gin.Context) { c.String(http.StatusOK, c.GetString("user_id")) })
+	api.POST("/tasks", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	router.POST("/mcp/call_tool", AuthMiddleware(), func(c *gin.Context) { c.String(http.StatusOK, c.GetString("user_id")) })
+
+	cases := []struct {
+		method, path, key string
+		want              int
+	}{
+		{http.MethodGet, "/api/tasks", "reader", http.StatusOK},
+		{http.MethodPost, "/api/tasks", "reader", http.StatusForbidden},
+		{http.MethodGet, "/api/tasks", "unknown", http.StatusUnauthorized},
+		{http.MethodPost, "/mcp/call_tool", "agent", http.StatusOK},
+		{http.MethodPost, "/mcp/call_tool", "reader", http.StatusForbidden},
+		{http.MethodGet, "/api/tasks", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.key != "" {
+			req.Header.Set(APIKeyHeader, tc.key)
+		}
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s with key %q: status %d, want %d (%s)", tc.method, tc.path, tc.key, rec.Code, tc.want, rec.Body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/tasks?user_id=someone-else", nil)
+	req.Header.Set(APIKeyHeader, "reader")
+	router.ServeHTTP(rec, req)
+	if rec.Body.String() != "user-1" {
+		t.Errorf("expected the key's user, got %q", rec.Body)
+	}
+}
@@ -3,18 +3,35 @@ package middleware
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/productivity/mcp-server/signing"
+	"github.com/productivity/mcp-server/utils"
 )
 
 // AuthMiddleware handles authentication for MCP endpoints
-// Supports both OAuth Bearer tokens and API keys
+// Supports both OAuth Bearer tokens and API keys (X-API-Key)
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key, status, message := authenticateAPIKey(c); key != nil {
+			setAPIKeyPrincipal(c, key)
+			c.Next()
+			return
+		} else if status != 0 {
+			c.JSON(status, gin.H{
+				"jsonrpc": "2.0",
+				"id":      nil,
+				"error": gin.H{
+					"code":    -32001,
+					"message": "Unauthorized: " + message,
+				},
+			})
+			c.Abort()
+			return
+		}
+
 		// Extract token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -79,6 +96,8 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Store user info in context
 		c.Set("user_id", userID)
 		c.Set("auth_token", token)
+		c.Set("auth_method", "bearer")
+		AddLogFields(c, map[string]interface{}{"user_id": userID})
 
 		c.Next()
 	}
@@ -90,6 +109,9 @@ func validateToken(token string) (string, error) {
 	// Try JWT validation first
 	claims, err := validateJWT(token)
 	if err == nil {
+		if err := checkRevoked(claims); err != nil {
+			return "", err
+		}
 		// Extract user ID from JWT claims
 		if userID, ok := claims["sub"].(string); ok {
 			return userID, nil
@@ -109,53 +131,52 @@ func validateToken(token string) (string, error) {
 
 // validateJWT validates a JWT token and returns claims
 func validateJWT(tokenString string) (map[string]interface{}, error) {
-	// Load JWT secret from environment variable
-	secret := getJWTSecret()
-
-	// Parse and validate JWT using github.com/golang-jwt/jwt/v5
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return secret, nil
-	})
-
+	claims, err := signingKeys.Verify(tokenString)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// Check expiration
-		if exp, ok := claims["exp"].(float64); ok {
-			if time.Now().Unix() > int64(exp) {
-				return nil, fmt.Errorf("token expired")
-			}
+	// Check expiration
+	if exp, ok := claims["exp"].(float64); ok {
+		if time.Now().Unix() > int64(exp) {
+			return nil, fmt.Errorf("token expired")
 		}
-		return map[string]interface{}(claims), nil
 	}
+	return map[string]interface{}(claims), nil
+}
 
-	return nil, fmt.Errorf("invalid token")
+// signingKeys verify bearer JWTs; they must match the keys handlers sign with
+var signingKeys = signing.NewKeySet("")
+
+// SetJWTSecret verifies bearer JWTs with an HS256 secret only
+func SetJWTSecret(secret string) {
+	signingKeys = signing.NewKeySet(secret)
 }
 
-func getJWTSecret() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// In production, this should be a fatal error
-		if os.Getenv("GIN_MODE") == "release" {
-			panic("JWT_SECRET environment variable is required in production mode")
-		}
-		// Generate a random secret for development only
-		// Import crypto/rand if needed, but for now use a warning
-		secret = "dev-secret-change-in-production"
-		fmt.Println("⚠️  WARNING: Using default JWT secret for development. Set JWT_SECRET in production!")
-	}
-	return []byte(secret)
+// SetSigningKeys installs the keys used to verify bearer JWTs
+func SetSigningKeys(keys *signing.KeySet) {
+	signingKeys = keys
 }
 
 // OptionalAuthMiddleware allows requests with or without auth
-// Used for endpoints that can work with optional authentication
+// Used for endpoints that can work with optional authentication.
+// A presented X-API-Key must be valid and carry the route's scope.
 func OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key, status, message := authenticateAPIKey(c); key != nil {
+			setAPIKeyPrincipal(c, key)
+			c.Next()
+			return
+		} else if status != 0 {
+			code := utils.ErrCodeUnauthorized
+			if status == http.StatusForbidden {
+				code = utils.ErrCodeForbidden
+			}
+			c.Error(utils.NewAppError(code, message, status))
+			c.Abort()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader != "" {
 			parts := strings.Split(authHeader, " ")
@@ -166,6 +187,8 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 					if err == nil {
 						c.Set("user_id", userID)
 						c.Set("auth_token", token)
+						c.Set("auth_method", "bearer")
+						AddLogFields(c, map[string]interface{}{"user_id": userID})
 					}
 				}
 			}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// BodyLimit caps request bodies at limit bytes, or at routes' own limit for
+// routes listed by method and path pattern, e.g. "POST /api/mcp/parse-file".
+// A declared Content-Length over the cap is refused with 413 before the
+// handler runs; a body that turns out longer fails the handler's read, which
+// handlers report as 413 too. A limit of 0 leaves bodies uncapped.
+func BodyLimit(limit int64, routes map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		max := limit
+		if routeLimit, ok := routes[c.Request.Method+" "+c.FullPath()]; ok {
+			max = routeLimit
+		}
+		if max <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > max {
+			c.Error(utils.ErrTooLarge(max))
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(utils.NewLogger()), BodyLimit(16, map[string]int64{"POST /upload/:name": 64}))
+	read := func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(utils.ErrTooLarge(16))
+			return
+		}
+		c.String(http.StatusOK, "%d", len(data))
+	}
+	router.POST("/small", read)
+	router.POST("/upload/:name", read)
+
+	cases := []struct {
+		path    string
+		size    int
+		chunked bool
+		status  int
+	}{
+		{"/small", 16, false, http.StatusOK},
+		{"/small", 17, false, http.StatusRequestEntityTooLarge},
+		{"/small", 17, true, http.StatusRequestEntityTooLarge}, // no Content-Length to refuse early
+		{"/upload/a", 64, false, http.StatusOK},
+		{"/upload/a", 65, false, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		var body io.Reader = strings.NewReader(strings.Repeat("x", tc.size))
+		if tc.chunked {
+			body = io.MultiReader(body)
+		}
+		req := httptest.NewRequest(http.MethodPost, tc.path, body)
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s with %d bytes (chunked %v): status %d, want %d", tc.path, tc.size, tc.chunked, rec.Code, tc.status)
+			continue
+		}
+		if tc.status == http.StatusRequestEntityTooLarge {
+			var got map[string]interface{}
+			json.Unmarshal(rec.Body.Bytes(), &got)
+			if got["code"] != utils.ErrCodeTooLarge || got["max_bytes"] == nil {
+				t.Errorf("%s: body %s", tc.path, rec.Body)
+			}
+		}
+	}
+}
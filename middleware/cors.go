@@ -1,32 +1,123 @@
 package middleware
 
 import (
+	"net/http"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
 )
 
-// CORSMiddleware adds CORS headers to all responses
-func CORSMiddleware() gin.HandlerFunc {
+// CORSMiddleware adds CORS headers for allowed origins and answers preflight
+// requests. The request's Origin is echoed back (never "*" alongside
+// credentials), and preflight responses are cached for cfg.MaxAge.
+func CORSMiddleware(cfg config.CORS) gin.HandlerFunc {
+	allowAny := false
+	var exact []string
+	var suffixes []originSuffix
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			allowAny = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*")
+			suffixes = append(suffixes, originSuffix{scheme: scheme + "://", host: host})
+		default:
+			exact = append(exact, origin)
+		}
+	}
+
+	allowed := func(origin string) bool {
+		if allowAny {
+			return true
+		}
+		origin = strings.ToLower(origin)
+		for _, o := range exact {
+			if o == origin {
+				return true
+			}
+		}
+		for _, s := range suffixes {
+			if strings.HasPrefix(origin, s.scheme) && strings.HasSuffix(origin, s.host) &&
+				len(origin) > len(s.scheme)+len(s.host) {
+				return true
+			}
+		}
+		return false
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		h := c.Writer.Header()
 
 		// Security headers (per Cloudflare best practices)
-		c.Writer.Header().Set("X-Content-Type-Options", "nosniff")
-		c.Writer.Header().Set("X-Frame-Options", "DENY")
-		c.Writer.Header().Set("X-XSS-Protection", "1; mode=block")
-		
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("X-XSS-Protection", "1; mode=block")
+
 		// HSTS header (if HTTPS)
 		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
-			c.Writer.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+			h.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
 		}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// Responses differ per origin, so caches must key on it
+		h.Add("Vary", "Origin")
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin != "" {
+			if !allowed(origin) {
+				if preflight {
+					c.AbortWithStatus(http.StatusForbidden)
+					return
+				}
+				// Serve the request without CORS headers; the browser blocks the read
+				c.Next()
+				return
+			}
+
+			if allowAny && !cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if exposed != "" && !preflight {
+				h.Set("Access-Control-Expose-Headers", exposed)
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			if preflight {
+				h.Set("Access-Control-Allow-Methods", methods)
+				h.Set("Access-Control-Allow-Headers", headers)
+				if cfg.MaxAge.Duration > 0 {
+					h.Set("Access-Control-Max-Age", maxAge)
+				}
+			}
+			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
 
 		c.Next()
 	}
 }
+
+// originSuffix matches any subdomain origin such as https://*.example.com
+type originSuffix struct {
+	scheme string // "https://"
+	host   string // ".example.com"
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+)
+
+func corsRouter(cfg config.CORS) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORSMiddleware(cfg))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func corsRequest(router *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", "POST")
+	}
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSAllowList(t *testing.T) {
+	router := corsRouter(config.CORS{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           config.Duration{Duration: 10 * time.Minute},
+	})
+
+	rec := corsRequest(router, http.MethodGet, "https://app.example.com")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("allowed origin echoed as %q", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("expected credentials to be allowed")
+	}
+	if rec.Header().Get("Vary") != "Origin" {
+		t.Errorf("Vary = %q", rec.Header().Get("Vary"))
+	}
+
+	rec = corsRequest(router, http.MethodGet, "https://api.example.org")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://api.example.org" {
+		t.Errorf("wildcard subdomain origin echoed as %q", got)
+	}
+
+	rec = corsRequest(router, http.MethodGet, "https://evil.example.net")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("disallowed origin got Allow-Origin %q", got)
+	}
+
+	rec = corsRequest(router, http.MethodOptions, "https://app.example.com")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("preflight status = %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+		t.Errorf("Allow-Methods = %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Max-Age = %q", got)
+	}
+
+	if rec := corsRequest(router, http.MethodOptions, "https://evil.example.net"); rec.Code != http.StatusForbidden {
+		t.Errorf("disallowed preflight status = %d", rec.Code)
+	}
+}
+
+func TestCORSWildcardWithoutCredentials(t *testing.T) {
+	router := corsRouter(config.CORS{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}})
+
+	rec := corsRequest(router, http.MethodGet, "https://anywhere.test")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("credentials must not be sent with *, got %q", got)
+	}
+}
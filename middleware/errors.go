@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// ErrorHandler renders errors that handlers attach with c.Error as
+// {"code", "message", "request_id", ...fields}. Errors that are not a
+// *utils.AppError are reported as a generic 500 so internal details don't leak.
+func ErrorHandler(logger *utils.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		appErr := WriteError(c, c.Errors.Last().Err)
+		if appErr.HTTPStatus >= http.StatusInternalServerError {
+			logger.Error("Request failed", appErr,
+				map[string]interface{}{
+					"code":       appErr.Code,
+					"path":       c.Request.URL.Path,
+					"request_id": c.GetString("request_id"),
+				},
+			)
+		}
+	}
+}
+
+// WriteError writes err as a JSON error response and returns the AppError it was rendered from
+func WriteError(c *gin.Context, err error) *utils.AppError {
+	var appErr *utils.AppError
+	if !errors.As(err, &appErr) {
+		appErr = utils.ErrInternal("internal server error").WithError(err)
+	}
+
+	body := gin.H{}
+	for k, v := range appErr.Fields {
+		body[k] = v
+	}
+	body["code"] = appErr.Code
+	body["message"] = appErr.Message
+	body["request_id"] = c.GetString("request_id")
+
+	c.AbortWithStatusJSON(appErr.HTTPStatus, body)
+	return appErr
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), ErrorHandler(utils.NewLogger()))
+	router.GET("/missing", func(c *gin.Context) {
+		c.Error(utils.ErrNotFound("task").WithField("id", "42"))
+	})
+	router.GET("/broken", func(c *gin.Context) {
+		c.Error(errors.New("connection refused to db.internal"))
+	})
+
+	cases := []struct {
+		path    string
+		status  int
+		code    string
+		message string
+	}{
+		{"/missing", http.StatusNotFound, utils.ErrCodeNotFound, "task not found"},
+		{"/broken", http.StatusInternalServerError, utils.ErrCodeInternal, "internal server error"},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != tc.status || body["code"] != tc.code || body["message"] != tc.message {
+			t.Errorf("%s: got %d %v", tc.path, rec.Code, body)
+		}
+		if body["request_id"] == "" || body["request_id"] == nil {
+			t.Errorf("%s: expected request_id in %v", tc.path, body)
+		}
+	}
+}
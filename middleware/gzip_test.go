@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestGzip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Gzip(), ErrorHandler(utils.NewLogger()))
+	large := strings.Repeat(`{"title":"Write the report"},`, 100)
+	router.GET("/large", func(c *gin.Context) { c.String(http.StatusOK, large) })
+	router.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, large)
+		c.Writer.Flush()
+	})
+	router.GET("/error", func(c *gin.Context) { c.Error(utils.ErrNotFound("task")) })
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/large", "br, gzip;q=0.8")
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Body.Len() >= len(large) {
+		t.Fatalf("large response not compressed: %v, %d bytes", rec.Header(), rec.Body.Len())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != large {
+		t.Error("compressed body does not round-trip")
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Vary = %q", rec.Header().Get("Vary"))
+	}
+
+	for _, tc := range []struct{ path, accept string }{
+		{"/large", ""},
+		{"/large", "gzip;q=0"},
+		{"/small", "gzip"},
+		{"/stream", "gzip"},
+	} {
+		rec := get(tc.path, tc.accept)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Code != http.StatusOK {
+			t.Errorf("%s with Accept-Encoding %q: compressed (%d)", tc.path, tc.accept, rec.Code)
+		}
+	}
+
+	rec = get("/error", "gzip")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "NOT_FOUND") {
+		t.Errorf("error through gzip: %d %s", rec.Code, rec.Body)
+	}
+}
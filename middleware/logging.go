@@ -10,27 +10,33 @@ import (
 	"github.com/productivity/mcp-server/utils"
 )
 
-// RequestLogger logs HTTP requests
+// RequestLogger logs HTTP requests. It also puts a logger carrying the request
+// ID on the request's context, which later middleware extends with the user
+// and MCP session through AddLogFields; handlers and outbound calls log
+// through it with utils.LoggerFromContext.
 func RequestLogger(logger *utils.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 		query := c.Request.URL.RawQuery
 
-		// Process request
-		c.Next()
-
-		// Calculate latency
-		latency := time.Since(start)
-
 		// Get request ID if set
 		requestID := c.GetString("request_id")
 		if requestID == "" {
 			requestID = c.GetHeader("X-Request-ID")
 		}
+		requestLogger := logger.WithFields(map[string]interface{}{"request_id": requestID})
+		c.Request = c.Request.WithContext(utils.ContextWithLogger(c.Request.Context(), requestLogger))
+
+		// Process request
+		c.Next()
 
-		// Log request
-		logger.Info("HTTP request",
+		// Calculate latency
+		latency := time.Since(start)
+
+		// Log request with whatever the request logger learned along the way
+		requestLogger = utils.LoggerFromContext(c.Request.Context())
+		requestLogger.Info("HTTP request",
 			map[string]interface{}{
 				"method":     c.Request.Method,
 				"path":       path,
@@ -48,7 +54,7 @@ func RequestLogger(logger *utils.Logger) gin.HandlerFunc {
 		if c.Writer.Status() >= 400 {
 			err := c.Errors.Last()
 			if err != nil {
-				logger.Error("HTTP request error", err.Err,
+				requestLogger.Error("HTTP request error", err.Err,
 					map[string]interface{}{
 						"method":     c.Request.Method,
 						"path":       path,
@@ -61,6 +67,13 @@ func RequestLogger(logger *utils.Logger) gin.HandlerFunc {
 	}
 }
 
+// AddLogFields adds fields to the request's logger, for middleware that
+// learns who is calling
+func AddLogFields(c *gin.Context, fields map[string]interface{}) {
+	ctx := c.Request.Context()
+	c.Request = c.Request.WithContext(utils.ContextWithLogger(ctx, utils.LoggerFromContext(ctx).With(fields)))
+}
+
 // RequestID adds a request ID to the context
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -97,10 +110,6 @@ func Recovery(logger *utils.Logger) gin.HandlerFunc {
 			},
 		)
 
-		c.JSON(500, gin.H{
-			"error":      "Internal server error",
-			"request_id": requestID,
-		})
-		c.Abort()
+		WriteError(c, utils.ErrInternal("internal server error"))
 	})
 }
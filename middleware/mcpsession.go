@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/mcpsession"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// MCPSessionKey is the context key holding the request's mcpsession.Session
+const MCPSessionKey = "mcp_session"
+
+// MCPSession checks the Mcp-Session-Id header on MCP requests. It must run
+// after AuthMiddleware. A request without the header is served without a
+// session, for clients that predate sessions; an unknown or expired ID, or one
+// issued to another user, gets 404 so the client starts over with initialize.
+func MCPSession(store *mcpsession.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(mcpsession.Header)
+		if id == "" || store == nil {
+			c.Next()
+			return
+		}
+
+		session, ok := store.Get(id)
+		if !ok || session.UserID != c.GetString("user_id") {
+			c.Error(utils.ErrNotFound("session"))
+			c.Abort()
+			return
+		}
+		c.Set(MCPSessionKey, session)
+		AddLogFields(c, map[string]interface{}{"mcp_session_id": session.ID})
+		c.Next()
+	}
+}
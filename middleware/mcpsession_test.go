@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/mcpsession"
+)
+
+func TestMCPSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := mcpsession.NewStore(time.Hour)
+	session, err := store.Create("user-1", "2024-11-05", mcpsession.ClientInfo{Name: "claude-ai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(ErrorHandler(nil))
+	router.POST("/mcp/call_tool", func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+	}, MCPSession(store), func(c *gin.Context) {
+		if s, ok := c.Get(MCPSessionKey); ok {
+			c.String(http.StatusOK, s.(mcpsession.Session).ClientInfo.Name)
+			return
+		}
+		c.String(http.StatusOK, "sessionless")
+	})
+
+	for name, tt := range map[string]struct {
+		user, session string
+		want          int
+		body          string
+	}{
+		"no session header": {"user-1", "", http.StatusOK, "sessionless"},
+		"own session":       {"user-1", session.ID, http.StatusOK, "claude-ai"},
+		"unknown session":   {"user-1", "nope", http.StatusNotFound, ""},
+		"someone else's":    {"user-2", session.ID, http.StatusNotFound, ""},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/mcp/call_tool", nil)
+		req.Header.Set("X-Test-User", tt.user)
+		if tt.session != "" {
+			req.Header.Set(mcpsession.Header, tt.session)
+		}
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.want || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("%s: status %d %q, want %d %q", name, rec.Code, rec.Body, tt.want, tt.body)
+		}
+	}
+}
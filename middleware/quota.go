@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/events"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// QuotaWarningThresholds are the fractions of the quota at which a warning is emitted
+var QuotaWarningThresholds = []float64{0.80, 0.95}
+
+// Quota enforces a per-user request quota over a fixed window. Before the hard
+// limit is reached it annotates responses with X-Quota-* headers and publishes
+// quota.warning events at each threshold, so clients can back off instead of
+// hitting a surprise 429.
+type Quota struct {
+	limit  int
+	window time.Duration
+	bus    *events.Bus
+
+	mu      sync.Mutex
+	windows map[string]*quotaWindow
+}
+
+type quotaWindow struct {
+	start    time.Time
+	used     int
+	warned   int // number of thresholds already announced
+	exceeded bool
+}
+
+// NewQuota creates a quota of limit requests per window. A limit <= 0 disables enforcement.
+func NewQuota(limit int, window time.Duration, bus *events.Bus) *Quota {
+	if window <= 0 {
+		window = time.Hour
+	}
+	return &Quota{
+		limit:   limit,
+		window:  window,
+		bus:     bus,
+		windows: make(map[string]*quotaWindow),
+	}
+}
+
+// quotaUsage is a snapshot of a caller's quota after counting a request
+type quotaUsage struct {
+	used      int
+	remaining int
+	resetAt   time.Time
+	warning   float64 // highest threshold crossed, 0 if none
+	crossed   []float64
+	exceeded  bool
+	firstOver bool
+}
+
+// take counts one request for key at now
+func (q *Quota) take(key string, now time.Time) quotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	w, ok := q.windows[key]
+	if !ok || !now.Before(w.start.Add(q.window)) {
+		w = &quotaWindow{start: now}
+		q.windows[key] = w
+		q.pruneLocked(now)
+	}
+
+	usage := quotaUsage{resetAt: w.start.Add(q.window)}
+	if w.used >= q.limit {
+		usage.used = w.used
+		usage.exceeded = true
+		usage.firstOver = !w.exceeded
+		w.exceeded = true
+		usage.warning = QuotaWarningThresholds[len(QuotaWarningThresholds)-1]
+		return usage
+	}
+
+	w.used++
+	usage.used = w.used
+	usage.remaining = q.limit - w.used
+	for i, threshold := range QuotaWarningThresholds {
+		if float64(w.used) >= threshold*float64(q.limit) {
+			usage.warning = threshold
+			if i >= w.warned {
+				usage.crossed = append(usage.crossed, threshold)
+				w.warned = i + 1
+			}
+		}
+	}
+	return usage
+}
+
+// pruneLocked drops expired windows so idle callers don't accumulate
+func (q *Quota) pruneLocked(now time.Time) {
+	for key, w := range q.windows {
+		if !now.Before(w.start.Add(q.window)) {
+			delete(q.windows, key)
+		}
+	}
+}
+
+// quotaKey identifies the caller: the user a bearer token or API key
+// authenticated, else the client IP. A user_id the request names itself is
+// ignored; it would let a caller spread requests over made-up users or spend
+// someone else's quota.
+func quotaKey(c *gin.Context) string {
+	if userID := c.GetString("user_id"); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware returns the quota middleware. Place it after AuthMiddleware where
+// one is used so quotas are tracked per authenticated user.
+func (q *Quota) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if q.limit <= 0 {
+			c.Next()
+			return
+		}
+
+		key := quotaKey(c)
+		usage := q.take(key, time.Now())
+
+		c.Header("X-Quota-Limit", strconv.Itoa(q.limit))
+		c.Header("X-Quota-Remaining", strconv.Itoa(usage.remaining))
+		c.Header("X-Quota-Reset", strconv.FormatInt(usage.resetAt.Unix(), 10))
+		if usage.warning > 0 {
+			c.Header("X-Quota-Warning", fmt.Sprintf("%d%% of quota used", int(usage.warning*100)))
+		}
+
+		for _, threshold := range usage.crossed {
+			q.publish(events.TypeQuotaWarning, key, usage, threshold)
+		}
+
+		if usage.exceeded {
+			if usage.firstOver {
+				q.publish(events.TypeQuotaExceeded, key, usage, 1)
+			}
+			retryAfter := int(time.Until(usage.resetAt).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.Error(utils.NewAppError(utils.ErrCodeRateLimit, "quota exceeded", http.StatusTooManyRequests).WithFields(map[string]interface{}{
+				"limit":       q.limit,
+				"reset_at":    usage.resetAt.UTC().Format(time.RFC3339),
+				"retry_after": retryAfter,
+			}))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (q *Quota) publish(eventType, key string, usage quotaUsage, threshold float64) {
+	var userID string
+	if strings.HasPrefix(key, "user:") {
+		userID = strings.TrimPrefix(key, "user:")
+	}
+	q.bus.Publish(events.Event{
+		Type:   eventType,
+		UserID: userID,
+		Data: map[string]interface{}{
+			"caller":    key,
+			"threshold": threshold,
+			"used":      usage.used,
+			"limit":     q.limit,
+			"reset_at":  usage.resetAt.UTC().Format(time.RFC3339),
+		},
+	})
+}
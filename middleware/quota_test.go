@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/events"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestQuotaWarnsBeforeEnforcing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bus := events.NewBus(0)
+	var received []events.Event
+	bus.Subscribe(func(e events.Event) { received = append(received, e) })
+
+	router := gin.New()
+	authenticate := func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+	}
+	router.Use(ErrorHandler(utils.NewLogger()), authenticate, NewQuota(20, time.Hour, bus).Middleware())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	var last *httptest.ResponseRecorder
+	for i := 0; i < 21; i++ {
+		last = httptest.NewRecorder()
+		router.ServeHTTP(last, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if i == 15 && last.Header().Get("X-Quota-Warning") == "" {
+			t.Fatalf("expected a warning header at 80%%")
+		}
+		if i == 19 && last.Header().Get("X-Quota-Remaining") != "0" {
+			t.Fatalf("expected 0 remaining, got %q", last.Header().Get("X-Quota-Remaining"))
+		}
+	}
+
+	if last.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after the limit, got %d", last.Code)
+	}
+
+	var types []string
+	for _, e := range received {
+		types = append(types, e.Type)
+	}
+	want := []string{events.TypeQuotaWarning, events.TypeQuotaWarning, events.TypeQuotaExceeded}
+	if len(types) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, types)
+	}
+	for i := range want {
+		if types[i] != want[i] || received[i].UserID != "user-1" {
+			t.Fatalf("expected events %v for user-1, got %v", want, received)
+		}
+	}
+}
+
+func TestQuotaIgnoresNamedUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(utils.NewLogger()), NewQuota(2, time.Hour, nil).Middleware())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Naming a new user each time still counts against the caller's IP
+	var last *httptest.ResponseRecorder
+	for _, userID := range []string{"a", "b", "c"} {
+		last = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/?user_id="+userID, nil)
+		req.Header.Set("X-User-ID", userID)
+		router.ServeHTTP(last, req)
+	}
+	if last.Code != http.StatusTooManyRequests {
+		t.Errorf("third request got %d, want 429", last.Code)
+	}
+}
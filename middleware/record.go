@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/recording"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// Record saves a sanitized fixture for every request whose route ("POST
+// /api/tasks/:id") matches one of routes, for replay with `replay`. Bodies
+// longer than maxBody bytes are left out of the fixture.
+func Record(w *recording.Writer, routes []string, maxBody int, logger *utils.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		if c.FullPath() == "" || !recording.MatchRoute(routes, route) {
+			c.Next()
+			return
+		}
+
+		var reqBody []byte
+		if c.Request.Body != nil {
+			reqBody, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(reqBody))
+		}
+		recorder := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		start := time.Now()
+
+		c.Next()
+
+		fixture := &recording.Fixture{
+			Route:      route,
+			RecordedAt: start.UTC(),
+			Request: recording.Request{
+				Method: c.Request.Method,
+				Path:   c.Request.URL.Path,
+				Query:  recording.SanitizeQuery(c.Request.URL.Query()).Encode(),
+				Header: recording.SanitizeHeader(c.Request.Header),
+				Body:   recording.NewBody(reqBody, c.GetHeader("Content-Type"), maxBody),
+			},
+			Response: recording.Response{
+				Status: recorder.Status(),
+				Header: recording.SanitizeHeader(recorder.Header()),
+				Body:   recording.NewBody(recorder.body.Bytes(), recorder.Header().Get("Content-Type"), maxBody),
+			},
+		}
+		if err := w.Write(fixture); err != nil {
+			logger.Error("Failed to write recording fixture", err, map[string]interface{}{"route": route})
+		}
+	}
+}
+
+// recordingWriter keeps a copy of the response body
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
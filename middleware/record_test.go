@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/recording"
+	"github.com/productivity/mcp-server/utils"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	writer, err := recording.NewWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	title := "Write report"
+	router := gin.New()
+	router.Use(Record(writer, []string{"POST /api/*"}, 1<<10, utils.NewLogger()))
+	router.POST("/api/tasks", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": c.GetHeader("X-Request-ID"), "title": title, "access_token": "secret-value"})
+	})
+	router.GET("/api/tasks", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	for _, method := range []string{http.MethodPost, http.MethodGet} {
+		req, _ := http.NewRequest(method, srv.URL+"/api/tasks", strings.NewReader(`{"title":"Write report","password":"hunter2"}`))
+		req.Header.Set("Authorization", "Bearer real-token")
+		req.Header.Set("X-Request-ID", "req-1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	fixtures, err := recording.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 1 || fixtures[0].Route != "POST /api/tasks" {
+		t.Fatalf("expected only the POST to be recorded, got %d fixtures", len(fixtures))
+	}
+	data, _ := os.ReadFile(dir + "/" + fixtures[0].Name)
+	for _, secret := range []string{"real-token", "hunter2", "secret-value"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("fixture leaks %q:\n%s", secret, data)
+		}
+	}
+
+	replayer := &recording.Replayer{BaseURL: srv.URL, Client: srv.Client(), Ignore: map[string]bool{"id": true}}
+	fixtures[0].Request.Header.Set("X-Request-ID", "req-2")
+	if res := replayer.Replay(context.Background(), fixtures[0]); res.Failed() {
+		t.Fatalf("expected an unchanged replay, got %v %v", res.Err, res.Diffs)
+	}
+
+	title = "Something else"
+	res := replayer.Replay(context.Background(), fixtures[0])
+	if len(res.Diffs) != 1 || !strings.HasPrefix(res.Diffs[0], "$.title") {
+		t.Fatalf("expected a title diff, got %v", res.Diffs)
+	}
+}
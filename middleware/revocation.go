@@ -0,0 +1,34 @@
+package middleware
+
+import "fmt"
+
+// TokenDenylist reports whether an access token was revoked before it
+// expired, keyed by its jti claim
+type TokenDenylist interface {
+	IsRevoked(jti string) (bool, error)
+}
+
+// tokenDenylist is consulted for every bearer JWT; nil disables revocation checks
+var tokenDenylist TokenDenylist
+
+// SetTokenDenylist installs the denylist checked by AuthMiddleware
+func SetTokenDenylist(d TokenDenylist) {
+	tokenDenylist = d
+}
+
+// checkRevoked rejects tokens whose jti is denylisted. A failed lookup is
+// treated as revoked so an unreachable denylist fails closed.
+func checkRevoked(claims map[string]interface{}) error {
+	jti, _ := claims["jti"].(string)
+	if jti == "" || tokenDenylist == nil {
+		return nil
+	}
+	revoked, err := tokenDenylist.IsRevoked(jti)
+	if err != nil {
+		return fmt.Errorf("could not check token revocation")
+	}
+	if revoked {
+		return fmt.Errorf("token has been revoked")
+	}
+	return nil
+}
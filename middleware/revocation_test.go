@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type fakeDenylist map[string]bool
+
+func (d fakeDenylist) IsRevoked(jti string) (bool, error) {
+	return d[jti], nil
+}
+
+func TestAuthMiddlewareRejectsRevokedTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetJWTSecret("test-secret")
+	defer SetJWTSecret("")
+	SetTokenDenylist(fakeDenylist{"revoked-jti": true})
+	defer SetTokenDenylist(nil)
+
+	router := gin.New()
+	router.POST("/mcp/call_tool", AuthMiddleware(), func(c *gin.Context) { c.String(http.StatusOK, c.GetString("user_id")) })
+
+	for jti, want := range map[string]int{
+		"active-jti":  http.StatusOK,
+		"revoked-jti": http.StatusUnauthorized,
+		"":            http.StatusOK,
+	} {
+		claims := jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()}
+		if jti != "" {
+			claims["jti"] = jti
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/mcp/call_tool", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("jti %q: status %d, want %d (%s)", jti, rec.Code, want, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/slo"
+)
+
+// SLO records every routed request against the tracker's route objectives.
+// Requests are keyed by method and route pattern ("GET /api/tasks/:id") and
+// count as failed when they end in a 5xx.
+func SLO(tracker *slo.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return // unmatched routes would create a series per probed path
+		}
+		tracker.Record(c.Request.Method+" "+route, time.Since(start), c.Writer.Status() >= http.StatusInternalServerError)
+	}
+}
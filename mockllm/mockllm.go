@@ -0,0 +1,275 @@
+// Package mockllm implements the `mockllm` subcommand: a local server that
+// speaks the Anthropic Messages API and the Ollama API and answers the
+// server's known prompts with deterministic, well-formed JSON. Pointing
+// CLAUDE_BASE_URL and OLLAMA_URL at it runs every AI feature offline without
+// API keys.
+package mockllm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+)
+
+// Run parses the subcommand's arguments and serves until ctx is cancelled
+func Run(ctx context.Context, args []string, out io.Writer) error {
+	defaults := config.Defaults()
+	fs := flag.NewFlagSet("mockllm", flag.ContinueOnError)
+	fs.SetOutput(out)
+	addr := fs.String("addr", "localhost:8090", "address to listen on")
+	models := fs.String("models", strings.Join([]string{
+		envOr("CLAUDE_MODEL", defaults.Claude.Model),
+		envOr("OLLAMA_MODEL", defaults.Ollama.Model),
+	}, ","), "comma-separated model names to advertise")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	base := "http://" + ln.Addr().String()
+	fmt.Fprintf(out, "Mock LLM listening on %s\n", base)
+	fmt.Fprintln(out, "Start the server against it with:")
+	fmt.Fprintf(out, "  CLAUDE_BASE_URL=%s CLAUDE_API_KEY=mock OLLAMA_URL=%s productivity-mcp\n", base, base)
+
+	srv := &http.Server{Handler: NewHandler(strings.Split(*models, ",")...)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// NewHandler returns the mock API. models are listed by /v1/models and
+// /api/tags; requests naming any other model are answered all the same.
+func NewHandler(models ...string) http.Handler {
+	m := &mock{models: models}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/messages", m.messages)
+	mux.HandleFunc("GET /v1/models", m.anthropicModels)
+	mux.HandleFunc("POST /api/generate", m.generate)
+	mux.HandleFunc("POST /api/chat", m.chat)
+	mux.HandleFunc("GET /api/tags", m.tags)
+	return mux
+}
+
+type mock struct {
+	models []string
+}
+
+// messages answers the Anthropic Messages API with the reply to the last user message
+func (m *mock) messages(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("x-api-key") == "" {
+		anthropicError(w, http.StatusUnauthorized, "authentication_error", "x-api-key header is required")
+		return
+	}
+	var req struct {
+		Model    string `json:"model"`
+		System   string `json:"system"`
+		Stream   bool   `json:"stream"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		anthropicError(w, http.StatusBadRequest, "invalid_request_error", "invalid JSON body: "+err.Error())
+		return
+	}
+	if req.Stream {
+		anthropicError(w, http.StatusBadRequest, "invalid_request_error", "mockllm does not support streaming")
+		return
+	}
+	prompt := ""
+	for _, msg := range req.Messages {
+		if msg.Role == "user" {
+			prompt = contentText(msg.Content)
+		}
+	}
+	if prompt == "" {
+		anthropicError(w, http.StatusBadRequest, "invalid_request_error", "messages must contain a user message")
+		return
+	}
+
+	text := Reply(prompt)
+	writeJSON(w, map[string]interface{}{
+		"id":            "msg_mock_" + digest(req.Model+"\x00"+prompt),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         req.Model,
+		"content":       []map[string]string{{"type": "text", "text": text}},
+		"stop_reason":   "end_turn",
+		"stop_sequence": nil,
+		"usage": map[string]int{
+			"input_tokens":  countTokens(req.System) + countTokens(prompt),
+			"output_tokens": countTokens(text),
+		},
+	})
+}
+
+func (m *mock) anthropicModels(w http.ResponseWriter, r *http.Request) {
+	data := []map[string]string{}
+	for _, name := range m.models {
+		data = append(data, map[string]string{
+			"type":         "model",
+			"id":           name,
+			"display_name": name + " (mock)",
+			"created_at":   "2024-10-22T00:00:00Z",
+		})
+	}
+	writeJSON(w, map[string]interface{}{"data": data, "has_more": false})
+}
+
+// generate answers Ollama's /api/generate, as a single object or as NDJSON when streaming
+func (m *mock) generate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model  string `json:"model"`
+		Prompt string `json:"prompt"`
+		Stream *bool  `json:"stream"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+	text := Reply(req.Prompt)
+	m.ollamaReply(w, req.Model, req.Stream == nil || *req.Stream, func(chunk string) map[string]interface{} {
+		return map[string]interface{}{"response": chunk}
+	}, text)
+}
+
+// chat answers Ollama's /api/chat with the reply to the last user message
+func (m *mock) chat(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model    string `json:"model"`
+		Stream   *bool  `json:"stream"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid JSON body"}`, http.StatusBadRequest)
+		return
+	}
+	prompt := ""
+	for _, msg := range req.Messages {
+		if msg.Role == "user" {
+			prompt = msg.Content
+		}
+	}
+	text := Reply(prompt)
+	m.ollamaReply(w, req.Model, req.Stream == nil || *req.Stream, func(chunk string) map[string]interface{} {
+		return map[string]interface{}{"message": map[string]string{"role": "assistant", "content": chunk}}
+	}, text)
+}
+
+// ollamaReply writes text in Ollama's framing: one object when not streaming,
+// otherwise the text followed by an empty final chunk, one JSON object per line.
+// Ollama streams by default, so a missing "stream" field means true.
+func (m *mock) ollamaReply(w http.ResponseWriter, model string, stream bool, frame func(chunk string) map[string]interface{}, text string) {
+	object := func(chunk string, done bool) map[string]interface{} {
+		o := frame(chunk)
+		o["model"] = model
+		o["created_at"] = time.Now().UTC().Format(time.RFC3339Nano)
+		o["done"] = done
+		if done {
+			o["done_reason"] = "stop"
+			o["eval_count"] = countTokens(text)
+		}
+		return o
+	}
+
+	if !stream {
+		writeJSON(w, object(text, true))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	enc.Encode(object(text, false))
+	enc.Encode(object("", true))
+}
+
+func (m *mock) tags(w http.ResponseWriter, r *http.Request) {
+	models := []map[string]interface{}{}
+	for _, name := range m.models {
+		models = append(models, map[string]interface{}{
+			"name":        name,
+			"model":       name,
+			"modified_at": "2024-10-22T00:00:00Z",
+			"size":        0,
+			"digest":      digest(name),
+		})
+	}
+	writeJSON(w, map[string]interface{}{"models": models})
+}
+
+// contentText flattens a message's content, which is either a string or a list of blocks
+func contentText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	json.Unmarshal(raw, &blocks)
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func anthropicError(w http.ResponseWriter, status int, kind, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type":  "error",
+		"error": map[string]string{"type": kind, "message": message},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// digest is a short stable identifier for s
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:12])
+}
+
+// countTokens approximates a token count closely enough for usage fields
+func countTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
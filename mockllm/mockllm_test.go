@@ -0,0 +1,108 @@
+package mockllm
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplyIsDeterministic(t *testing.T) {
+	now = func() time.Time { return time.Date(2025, 3, 10, 9, 30, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	prompt := "Parse the following natural language input into a structured task. Return a JSON object with:\n\nInput: \"buy groceries tomorrow\"\n\nReturn ONLY valid JSON, no other text."
+	if Reply(prompt) != Reply(prompt) {
+		t.Fatal("expected identical replies for identical prompts")
+	}
+	var task map[string]interface{}
+	if err := json.Unmarshal([]byte(Reply(prompt)), &task); err != nil {
+		t.Fatal(err)
+	}
+	if task["title"] != "Buy groceries" || task["category"] != "shopping" || task["due_date"] != "2025-03-11T17:00:00Z" {
+		t.Fatalf("unexpected task %v", task)
+	}
+
+	file := "Parse the following file content and extract tasks, dates, and priorities.\nFile Content:\n# Notes\n- call the dentist\n* ship release asap\nrandom line\n\nReturn ONLY valid JSON, no other text."
+	var parsed struct {
+		Tasks []map[string]interface{} `json:"tasks"`
+	}
+	json.Unmarshal([]byte(Reply(file)), &parsed)
+	if len(parsed.Tasks) != 2 || parsed.Tasks[1]["priority"] != float64(5) {
+		t.Fatalf("unexpected file tasks %v", parsed.Tasks)
+	}
+
+	if got := Reply("hello there"); got != "Mock response to: hello there" {
+		t.Fatalf("unexpected fallback %q", got)
+	}
+}
+
+func TestAPIs(t *testing.T) {
+	srv := httptest.NewServer(NewHandler("claude-3-5-sonnet-20241022", "llama3"))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/messages", strings.NewReader(
+		`{"model":"claude-3-5-sonnet-20241022","max_tokens":10,"messages":[{"role":"user","content":[{"type":"text","text":"Generate 3-7 actionable subtasks for the following task.\n\nTask Title: \"Plan offsite\""}]}]}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without an API key, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, srv.URL+"/v1/messages", strings.NewReader(
+		`{"model":"claude-3-5-sonnet-20241022","max_tokens":10,"messages":[{"role":"user","content":[{"type":"text","text":"Generate 3-7 actionable subtasks for the following task.\n\nTask Title: \"Plan offsite\""}]}]}`))
+	req.Header.Set("x-api-key", "mock")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg struct {
+		Type    string `json:"type"`
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	json.NewDecoder(resp.Body).Decode(&msg)
+	resp.Body.Close()
+	var subtasks []string
+	if msg.Type != "message" || len(msg.Content) != 1 || json.Unmarshal([]byte(msg.Content[0].Text), &subtasks) != nil || len(subtasks) < 3 {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	resp, err = http.Post(srv.URL+"/api/generate", "application/json", strings.NewReader(`{"model":"llama3","prompt":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []map[string]interface{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var chunk map[string]interface{}
+		json.Unmarshal(scanner.Bytes(), &chunk)
+		chunks = append(chunks, chunk)
+	}
+	resp.Body.Close()
+	if len(chunks) != 2 || chunks[0]["response"] != "Mock response to: hi" || chunks[1]["done"] != true {
+		t.Fatalf("unexpected stream %v", chunks)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	json.NewDecoder(resp.Body).Decode(&tags)
+	resp.Body.Close()
+	if len(tags.Models) != 2 || tags.Models[1].Name != "llama3" {
+		t.Fatalf("unexpected tags %+v", tags)
+	}
+}
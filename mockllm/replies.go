@@ -0,0 +1,378 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// now is replaced in tests so relative due dates are stable
+var now = time.Now
+
+// responder answers prompts that start with prefix
+type responder struct {
+	prefix string
+	reply  func(prompt string) interface{}
+}
+
+// responders cover the templates in prompts/templates; keep the prefixes in step with them
+var responders = []responder{
+	{"Parse the following natural language input into a structured task", parseTask},
+	{"Parse the following file content and extract tasks", parseFile},
+	{"Combine the following summaries of the parts of one file", summarizeFile},
+	{"Map the columns of the following spreadsheet", mapColumns},
+	{"Apply the user's correction to the following structured task", refineTask},
+	{"Generate 3-7 actionable subtasks", generateSubtasks},
+	{"Propose 3-6 milestones", suggestMilestones},
+	{"Analyze the following productivity data", analyzeProductivity},
+	{"Review the Eisenhower matrix placement", reviewMatrix},
+}
+
+// Reply returns the mock model's answer to prompt: JSON shaped the way the
+// server expects for its known prompts, and an acknowledgement otherwise.
+// The same prompt always gets the same answer on a given day.
+func Reply(prompt string) string {
+	trimmed := strings.TrimSpace(prompt)
+	for _, r := range responders {
+		if strings.HasPrefix(trimmed, r.prefix) {
+			data, _ := json.Marshal(r.reply(trimmed))
+			return string(data)
+		}
+	}
+	first, _, _ := strings.Cut(trimmed, "\n")
+	return fmt.Sprintf("Mock response to: %s", first)
+}
+
+var (
+	quotedInput     = regexp.MustCompile(`(?m)^Input: "(.*)"$`)
+	quotedTitle     = regexp.MustCompile(`(?m)^Task Title: "(.*)"$`)
+	goalTitle       = regexp.MustCompile(`(?m)^Goal Title: "(.*)"$`)
+	goalStart       = regexp.MustCompile(`(?m)^Start Date: (.*)$`)
+	goalTarget      = regexp.MustCompile(`(?m)^Target Date: (.*)$`)
+	fileContent     = regexp.MustCompile(`(?s)File Content:\n(.*)\n\nReturn ONLY`)
+	partSummaries   = regexp.MustCompile(`(?s)Part Summaries:\n(.*)\n\nReturn ONLY`)
+	columnHeaders   = regexp.MustCompile(`(?m)^Columns: (.*)$`)
+	foundTasks      = regexp.MustCompile(`Found (\d+) tasks`)
+	tasksData       = regexp.MustCompile(`(?s)Tasks data \(last (\d+) days\):\n(.*)\n\nReturn ONLY`)
+	defaultCategory = regexp.MustCompile(`(?m)^- \w+ default_category: (.*)$`)
+	matrixTasks     = regexp.MustCompile(`(?s)Tasks:\n(.*)\n\nReturn a JSON`)
+	refineTaskJSON  = regexp.MustCompile(`(?s)Task:\n(.*)\n\nCorrection:`)
+	correction      = regexp.MustCompile(`(?m)^Correction: "(.*)"$`)
+	priorityNumber  = regexp.MustCompile(`(?i)\bpriority (?:to |of |is |= )?([1-5])\b`)
+	listItemPrefix  = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)]|- \[ \]|\[ \]|TODO:?)\s+`)
+)
+
+func parseTask(prompt string) interface{} {
+	input := firstMatch(quotedInput, prompt)
+	task := map[string]interface{}{
+		"title":       taskTitle(input),
+		"description": "Parsed from: " + input,
+		"priority":    priority(input),
+		"category":    category(input),
+	}
+	// A remembered default category beats the mock's own default
+	if remembered := firstMatch(defaultCategory, prompt); remembered != "" && task["category"] == fallbackCategory {
+		task["category"] = remembered
+	}
+	if due, ok := dueDate(input); ok {
+		task["due_date"] = due
+	}
+	return task
+}
+
+// refineTask applies the corrections the mock understands: a numbered or
+// keyword priority, a category keyword and a relative due date
+func refineTask(prompt string) interface{} {
+	task := map[string]interface{}{}
+	json.Unmarshal([]byte(firstMatch(refineTaskJSON, prompt)), &task)
+	if title, _ := task["title"].(string); title == "" {
+		task["title"] = "Untitled task"
+	}
+	fix := firstMatch(correction, prompt)
+	if m := priorityNumber.FindStringSubmatch(fix); m != nil {
+		task["priority"] = int(m[1][0] - '0')
+	} else if p := priority(fix); p != 3 {
+		task["priority"] = p
+	}
+	if c := category(fix); c != fallbackCategory {
+		task["category"] = c
+	}
+	if due, ok := dueDate(fix); ok {
+		task["due_date"] = due
+	}
+	return task
+}
+
+func parseFile(prompt string) interface{} {
+	tasks := []map[string]interface{}{}
+	lines := 0
+	for _, line := range strings.Split(firstMatch(fileContent, prompt), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines++
+		loc := listItemPrefix.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		item := strings.TrimSpace(line[loc[1]:])
+		task := map[string]interface{}{
+			"title":       taskTitle(item),
+			"description": "",
+			"priority":    priority(item),
+			"category":    category(item),
+		}
+		if due, ok := dueDate(item); ok {
+			task["due_date"] = due
+		}
+		tasks = append(tasks, task)
+	}
+	return map[string]interface{}{
+		"tasks":          tasks,
+		"extracted_data": map[string]interface{}{"line_count": lines},
+		"summary":        fmt.Sprintf("Found %d tasks in %d non-empty lines.", len(tasks), lines),
+	}
+}
+
+func summarizeFile(prompt string) interface{} {
+	parts, found := 0, 0
+	for _, line := range strings.Split(firstMatch(partSummaries, prompt), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts++
+		if n, err := strconv.Atoi(firstMatch(foundTasks, line)); err == nil {
+			found += n
+		}
+	}
+	return map[string]interface{}{
+		"summary": fmt.Sprintf("Found %d tasks across %d parts.", found, parts),
+	}
+}
+
+// columnWords are the words in a header that give away the field it holds
+var columnWords = []struct{ field, words string }{
+	{"title", "title task name summary subject item action"},
+	{"description", "description notes note details comment"},
+	{"due_date", "due deadline date when"},
+	{"priority", "priority prio importance urgency"},
+	{"category", "category project list area type tag"},
+	{"completed", "completed done status finished"},
+	{"id", "id key ref"},
+}
+
+func mapColumns(prompt string) interface{} {
+	var headers []string
+	json.Unmarshal([]byte(firstMatch(columnHeaders, prompt)), &headers)
+	columns := map[string]interface{}{}
+	taken := map[string]bool{}
+	for _, c := range columnWords {
+		for _, h := range headers {
+			if taken[h] {
+				continue
+			}
+			if containsAny(strings.ToLower(h), strings.Fields(c.words)...) {
+				columns[c.field] = h
+				taken[h] = true
+				break
+			}
+		}
+	}
+	return map[string]interface{}{"columns": columns}
+}
+
+func generateSubtasks(prompt string) interface{} {
+	title := firstMatch(quotedTitle, prompt)
+	if title == "" {
+		title = "the task"
+	}
+	return []string{
+		fmt.Sprintf("Define what done looks like for %q", title),
+		fmt.Sprintf("Gather what is needed for %q", title),
+		fmt.Sprintf("Do the main work on %q", title),
+		fmt.Sprintf("Review and wrap up %q", title),
+	}
+}
+
+func suggestMilestones(prompt string) interface{} {
+	title := firstMatch(goalTitle, prompt)
+	if title == "" {
+		title = "the goal"
+	}
+	titles := []string{
+		fmt.Sprintf("Plan how to reach %q", title),
+		fmt.Sprintf("Get a quarter of the way to %q", title),
+		fmt.Sprintf("Reach the halfway point of %q", title),
+		fmt.Sprintf("Achieve %q", title),
+	}
+
+	start, startErr := time.Parse(time.RFC3339, firstMatch(goalStart, prompt))
+	target, targetErr := time.Parse(time.RFC3339, firstMatch(goalTarget, prompt))
+	milestones := make([]map[string]interface{}, len(titles))
+	for i, t := range titles {
+		milestones[i] = map[string]interface{}{"title": t}
+		if startErr == nil && targetErr == nil && target.After(start) {
+			due := start.Add(target.Sub(start) * time.Duration(i+1) / time.Duration(len(titles)))
+			milestones[i]["due_date"] = due.UTC().Format(time.RFC3339)
+		}
+	}
+	return milestones
+}
+
+func analyzeProductivity(prompt string) interface{} {
+	m := tasksData.FindStringSubmatch(prompt)
+	days := "?"
+	var tasks []map[string]interface{}
+	if m != nil {
+		days = m[1]
+		json.Unmarshal([]byte(m[2]), &tasks)
+	}
+
+	completed, highPriority := 0, 0
+	categories := map[string]int{}
+	for _, t := range tasks {
+		if t["status"] == "completed" {
+			completed++
+		}
+		if p, ok := t["priority"].(float64); ok && p >= 4 {
+			highPriority++
+		}
+		if c, ok := t["category"].(string); ok && c != "" {
+			categories[c]++
+		}
+	}
+	top, topCount := "", 0
+	for c, n := range categories {
+		if n > topCount || (n == topCount && c < top) {
+			top, topCount = c, n
+		}
+	}
+
+	insights := []string{
+		fmt.Sprintf("You created %d tasks in the last %s days.", len(tasks), days),
+		fmt.Sprintf("You completed %d of them.", completed),
+		fmt.Sprintf("%d tasks were high priority (4 or 5).", highPriority),
+	}
+	if top != "" {
+		insights = append(insights, fmt.Sprintf("Most of your tasks are in the %q category.", top))
+	}
+	return map[string]interface{}{
+		"insights": insights,
+		"recommendations": []string{
+			"Pick your three most important tasks each morning.",
+			"Break tasks you keep postponing into subtasks.",
+			"Review open high-priority tasks at the end of the week.",
+		},
+	}
+}
+
+// reviewMatrix moves tasks whose text calls them urgent into "do" and those
+// marked for someday into "eliminate"
+func reviewMatrix(prompt string) interface{} {
+	var tasks []map[string]interface{}
+	json.Unmarshal([]byte(firstMatch(matrixTasks, prompt)), &tasks)
+
+	moves := []map[string]interface{}{}
+	for _, t := range tasks {
+		title, _ := t["title"].(string)
+		description, _ := t["description"].(string)
+		quadrant, _ := t["quadrant"].(string)
+		to, reason := "", ""
+		switch priority(title + " " + description) {
+		case 5:
+			to, reason = "do", "The task says it is urgent"
+		case 1:
+			to, reason = "eliminate", "The task says it can wait"
+		}
+		if to != "" && to != quadrant {
+			moves = append(moves, map[string]interface{}{"id": t["id"], "quadrant": to, "reason": reason})
+		}
+	}
+	return map[string]interface{}{"moves": moves}
+}
+
+func firstMatch(re *regexp.Regexp, s string) string {
+	if m := re.FindStringSubmatch(s); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return ""
+}
+
+// timePhrases are removed from titles because they become the due date
+var timePhrases = regexp.MustCompile(`(?i)\s*\b(?:by |on |due )?(?:today|tonight|tomorrow|next week)\b`)
+
+func taskTitle(input string) string {
+	title := strings.TrimSpace(timePhrases.ReplaceAllString(input, ""))
+	if title == "" {
+		title = strings.TrimSpace(input)
+	}
+	if title == "" {
+		return "Untitled task"
+	}
+	return strings.ToUpper(title[:1]) + title[1:]
+}
+
+func priority(input string) int {
+	lower := strings.ToLower(input)
+	switch {
+	case containsAny(lower, "urgent", "asap", "critical"):
+		return 5
+	case containsAny(lower, "important", "high priority"):
+		return 4
+	case containsAny(lower, "someday", "low priority", "whenever"):
+		return 1
+	}
+	return 3
+}
+
+// fallbackCategory is given to input that matches no category keywords
+const fallbackCategory = "personal"
+
+var categoryKeywords = []struct {
+	category string
+	words    []string
+}{
+	{"work", []string{"meeting", "report", "email", "client", "deploy", "review", "presentation"}},
+	{"health", []string{"gym", "run", "doctor", "dentist", "workout", "yoga"}},
+	{"shopping", []string{"buy", "groceries", "order", "shop"}},
+}
+
+func category(input string) string {
+	lower := strings.ToLower(input)
+	for _, k := range categoryKeywords {
+		if containsAny(lower, k.words...) {
+			return k.category
+		}
+	}
+	return fallbackCategory
+}
+
+// dueDate resolves the relative dates the mock understands to 17:00 UTC on that day
+func dueDate(input string) (string, bool) {
+	lower := strings.ToLower(input)
+	today := now().UTC().Truncate(24 * time.Hour)
+	var day time.Time
+	switch {
+	case strings.Contains(lower, "tomorrow"):
+		day = today.AddDate(0, 0, 1)
+	case strings.Contains(lower, "next week"):
+		day = today.AddDate(0, 0, 7)
+	case containsAny(lower, "today", "tonight"):
+		day = today
+	default:
+		return "", false
+	}
+	return day.Add(17 * time.Hour).Format(time.RFC3339), true
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
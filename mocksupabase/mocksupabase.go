@@ -0,0 +1,738 @@
+// Package mocksupabase is an in-memory stand-in for Supabase's REST API
+// (PostgREST), for tests that run the server end to end without a database.
+// It answers the requests db.SupabaseClient makes: filtered, ordered and
+// paged selects, counts, inserts, upserts, updates and deletes, with errors
+// coded as PostgREST codes them.
+//
+// Tables need not be declared; any table name is accepted and starts empty.
+// Rows get an id, created_at and updated_at if they have none, and the column
+// defaults of the core tables, but no other triggers run and no functions
+// exist, so RPC calls answer 404 as they do before a migration is applied.
+//
+// It is also the `mocksupabase` subcommand, which serves it until stopped so
+// the server can be load tested without a Supabase project.
+package mocksupabase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PostgREST and Postgres error codes the mock answers with
+const (
+	codeUniqueViolation = "23505"
+	codeBadRequest      = "PGRST100"
+	codeNoFunction      = "PGRST202"
+)
+
+// primaryKeys are the tables whose rows are not keyed by id
+var primaryKeys = map[string][]string{
+	"completion_stats":        {"user_id"},
+	"email_ingest_addresses":  {"user_id"},
+	"feature_flags":           {"name"},
+	"focus_contracts":         {"user_id"},
+	"grace_rules":             {"user_id"},
+	"integration_connections": {"user_id", "provider"},
+	"integration_links":       {"provider", "entity_type", "entity_id"},
+	"notion_connections":      {"user_id"},
+	"notion_pages":            {"entity_type", "entity_id"},
+	"oauth_clients":           {"client_id"},
+	"oauth_grants":            {"user_id", "client_id"},
+	"oauth_sessions":          {"jti"},
+	"revoked_tokens":          {"jti"},
+	"task_embeddings":         {"task_id"},
+	"user_credentials":        {"user_id", "name"},
+	"user_memory":             {"user_id", "key"},
+	"user_plans":              {"user_id"},
+	"user_preferences":        {"user_id"},
+	"weekly_completions":      {"user_id", "week_start"},
+	"workspace_members":       {"workspace_id", "user_id"},
+}
+
+// columnDefaults are the defaults of the core tables' columns, as the
+// migrations declare them
+var columnDefaults = map[string]map[string]interface{}{
+	"tasks": {
+		"description": "", "priority": 2.0, "estimated_duration": 0.0,
+		"category": "work", "completed": false,
+	},
+	"goals": {
+		"description": "", "progress": 0.0, "archived": false,
+	},
+	"goal_milestones": {
+		"completed": false,
+	},
+}
+
+// cascades are the foreign keys declared ON DELETE CASCADE: deleting a row
+// of the table deletes the rows of each child table whose column holds its id
+var cascades = map[string][]struct{ table, column string }{
+	"goals": {{"goal_milestones", "goal_id"}},
+	"tasks": {{"task_completions", "task_id"}, {"task_reschedules", "task_id"}, {"task_embeddings", "task_id"}},
+}
+
+// Run parses the subcommand's arguments and serves until ctx is cancelled
+func Run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("mocksupabase", flag.ContinueOnError)
+	fs.SetOutput(out)
+	addr := fs.String("addr", "localhost:8091", "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	base := "http://" + ln.Addr().String()
+	fmt.Fprintf(out, "Mock Supabase listening on %s (data is kept in memory)\n", base)
+	fmt.Fprintln(out, "Start the server against it with:")
+	fmt.Fprintf(out, "  SUPABASE_URL=%s SUPABASE_ANON_KEY=mock productivity-mcp\n", base)
+
+	srv := &http.Server{Handler: NewHandler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Row is a table row, as PostgREST sends it
+type Row = map[string]interface{}
+
+// Handler serves the REST API under /rest/v1/. Point SUPABASE_URL at a
+// server running it.
+type Handler struct {
+	mu     sync.Mutex
+	tables map[string][]Row
+}
+
+// NewHandler returns a mock with every table empty
+func NewHandler() *Handler {
+	return &Handler{tables: make(map[string][]Row)}
+}
+
+// Insert stores rows in table as a POST would, for tests to seed data
+func (h *Handler) Insert(table string, rows ...Row) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, row := range rows {
+		h.tables[table] = append(h.tables[table], h.newRow(table, row))
+	}
+}
+
+// Rows returns copies of table's rows, in insertion order
+func (h *Handler) Rows(table string) []Row {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rows := make([]Row, len(h.tables[table]))
+	for i, row := range h.tables[table] {
+		rows[i] = copyRow(row)
+	}
+	return rows
+}
+
+// restError is an error answer with PostgREST's body
+type restError struct {
+	status  int
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+}
+
+func (e *restError) Error() string { return e.Message }
+
+func badRequest(format string, args ...interface{}) *restError {
+	return &restError{status: http.StatusBadRequest, Code: codeBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutPrefix(r.URL.Path, "/rest/v1/")
+	if !ok || path == "" {
+		writeError(w, &restError{status: http.StatusNotFound, Code: "PGRST125", Message: "invalid path " + r.URL.Path})
+		return
+	}
+	if name, ok := strings.CutPrefix(path, "rpc/"); ok {
+		writeError(w, &restError{status: http.StatusNotFound, Code: codeNoFunction,
+			Message: fmt.Sprintf("Could not find the function public.%s in the schema cache", name)})
+		return
+	}
+
+	prefer := r.Header.Get("Prefer")
+	status, rows, err := h.handle(r, path)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if strings.Contains(prefer, "count=exact") || r.Method == http.MethodHead {
+		w.Header().Set("Content-Range", fmt.Sprintf("*/%d", len(rows)))
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+	if r.Method != http.MethodGet && !strings.Contains(prefer, "return=representation") {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if rows == nil {
+		rows = []Row{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(rows)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	rerr, ok := err.(*restError)
+	if !ok {
+		rerr = &restError{status: http.StatusInternalServerError, Message: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(rerr.status)
+	json.NewEncoder(w).Encode(rerr)
+}
+
+// handle answers the request to table with the status and the rows read or
+// written
+func (h *Handler) handle(r *http.Request, table string) (int, []Row, error) {
+	query := r.URL.Query()
+	match, err := parseFilters(query)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		rows, err := h.selectRows(table, match, query)
+		return http.StatusOK, rows, err
+
+	case http.MethodPost:
+		var body interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return 0, nil, badRequest("invalid JSON body: %v", err)
+		}
+		var records []Row
+		switch b := body.(type) {
+		case map[string]interface{}:
+			records = []Row{b}
+		case []interface{}:
+			for _, item := range b {
+				record, ok := item.(map[string]interface{})
+				if !ok {
+					return 0, nil, badRequest("expected an array of objects")
+				}
+				records = append(records, record)
+			}
+		default:
+			return 0, nil, badRequest("expected an object or an array of objects")
+		}
+		prefer := r.Header.Get("Prefer")
+		rows, err := h.insert(table, records, query.Get("on_conflict"),
+			strings.Contains(prefer, "resolution=merge-duplicates"),
+			strings.Contains(prefer, "resolution=ignore-duplicates"))
+		return http.StatusCreated, project(rows, query.Get("select")), err
+
+	case http.MethodPatch:
+		var data Row
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			return 0, nil, badRequest("invalid JSON body: %v", err)
+		}
+		var updated []Row
+		for _, row := range h.tables[table] {
+			if match(row) {
+				for k, v := range data {
+					row[k] = v
+				}
+				updated = append(updated, copyRow(row))
+			}
+		}
+		return http.StatusOK, project(updated, query.Get("select")), nil
+
+	case http.MethodDelete:
+		return http.StatusOK, h.delete(table, match), nil
+	}
+	return 0, nil, &restError{status: http.StatusMethodNotAllowed, Message: "unsupported method " + r.Method}
+}
+
+// selectRows returns the rows of table matched, ordered, paged and with the
+// selected columns as query asks
+func (h *Handler) selectRows(table string, match func(Row) bool, query map[string][]string) ([]Row, error) {
+	var rows []Row
+	for _, row := range h.tables[table] {
+		if match(row) {
+			rows = append(rows, copyRow(row))
+		}
+	}
+
+	if order := first(query["order"]); order != "" {
+		var terms []orderTerm
+		for _, term := range strings.Split(order, ",") {
+			parts := strings.Split(term, ".")
+			t := orderTerm{column: parts[0]}
+			for _, p := range parts[1:] {
+				switch p {
+				case "desc":
+					t.desc = true
+				case "asc":
+					t.desc = false
+				case "nullsfirst":
+					t.nulls = -1
+				case "nullslast":
+					t.nulls = 1
+				default:
+					return nil, badRequest("invalid order term %q", term)
+				}
+			}
+			terms = append(terms, t)
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return less(terms, rows[i], rows[j]) })
+	}
+
+	if offset := first(query["offset"]); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return nil, badRequest("invalid offset %q", offset)
+		}
+		rows = rows[min(n, len(rows)):]
+	}
+	if limit := first(query["limit"]); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, badRequest("invalid limit %q", limit)
+		}
+		rows = rows[:min(n, len(rows))]
+	}
+	return project(rows, first(query["select"])), nil
+}
+
+type orderTerm struct {
+	column string
+	desc   bool
+	nulls  int // -1 first, 1 last, 0 as Postgres orders them: last ascending, first descending
+}
+
+func less(terms []orderTerm, a, b Row) bool {
+	for _, t := range terms {
+		av, bv := a[t.column], b[t.column]
+		if av == nil || bv == nil {
+			if av == nil && bv == nil {
+				continue
+			}
+			nullsFirst := t.nulls == -1 || (t.nulls == 0 && t.desc)
+			return (av == nil) == nullsFirst
+		}
+		c, ok := compare(av, fmt.Sprint(bv))
+		if !ok || c == 0 {
+			continue
+		}
+		return (c < 0) != t.desc
+	}
+	return false
+}
+
+// insert stores records, or with merge updates the rows sharing their key,
+// where the key is conflict's columns or else the table's primary key
+func (h *Handler) insert(table string, records []Row, conflict string, merge, ignore bool) ([]Row, error) {
+	key := primaryKey(table)
+	if conflict != "" {
+		key = strings.Split(conflict, ",")
+	}
+
+	var written []Row
+	for _, record := range records {
+		if existing := h.find(table, key, record); existing != nil {
+			switch {
+			case merge:
+				for k, v := range record {
+					existing[k] = v
+				}
+				written = append(written, copyRow(existing))
+				continue
+			case ignore:
+				continue
+			}
+			return nil, &restError{status: http.StatusConflict, Code: codeUniqueViolation,
+				Message: fmt.Sprintf("duplicate key value violates unique constraint %q", table+"_pkey"),
+				Details: fmt.Sprintf("Key (%s) already exists.", strings.Join(key, ", "))}
+		}
+		row := h.newRow(table, record)
+		h.tables[table] = append(h.tables[table], row)
+		written = append(written, copyRow(row))
+	}
+	return written, nil
+}
+
+// find returns the row of table with record's values in every key column
+func (h *Handler) find(table string, key []string, record Row) Row {
+	for _, row := range h.tables[table] {
+		same := true
+		for _, col := range key {
+			if record[col] == nil || fmt.Sprint(row[col]) != fmt.Sprint(record[col]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return row
+		}
+	}
+	return nil
+}
+
+// newRow is record with the columns the database would fill in
+func (h *Handler) newRow(table string, record Row) Row {
+	row := copyRow(record)
+	for k, v := range columnDefaults[table] {
+		if _, ok := row[k]; !ok {
+			row[k] = v
+		}
+	}
+	if key := primaryKey(table); len(key) == 1 && key[0] == "id" && row["id"] == nil {
+		row["id"] = newUUID()
+	}
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, col := range []string{"created_at", "updated_at"} {
+		if row[col] == nil {
+			row[col] = now
+		}
+	}
+	return row
+}
+
+// delete removes the rows of table matched, and the rows cascading from them
+func (h *Handler) delete(table string, match func(Row) bool) []Row {
+	var kept, deleted []Row
+	for _, row := range h.tables[table] {
+		if match(row) {
+			deleted = append(deleted, row)
+		} else {
+			kept = append(kept, row)
+		}
+	}
+	h.tables[table] = kept
+	for _, child := range cascades[table] {
+		for _, row := range deleted {
+			id := fmt.Sprint(row["id"])
+			h.delete(child.table, func(r Row) bool { return fmt.Sprint(r[child.column]) == id })
+		}
+	}
+	return deleted
+}
+
+func primaryKey(table string) []string {
+	if key, ok := primaryKeys[table]; ok {
+		return key
+	}
+	return []string{"id"}
+}
+
+// project keeps the columns named in sel, a comma-separated list or "*"
+func project(rows []Row, sel string) []Row {
+	if sel == "" || sel == "*" {
+		return rows
+	}
+	columns := strings.Split(sel, ",")
+	projected := make([]Row, len(rows))
+	for i, row := range rows {
+		projected[i] = make(Row, len(columns))
+		for _, col := range columns {
+			projected[i][col] = row[col]
+		}
+	}
+	return projected
+}
+
+// parseFilters turns the query's horizontal filters, including or=(...) and
+// and=(...), into a predicate on rows
+func parseFilters(query map[string][]string) (func(Row) bool, error) {
+	var conds []func(Row) bool
+	for key, values := range query {
+		switch key {
+		case "select", "order", "limit", "offset", "on_conflict", "columns":
+			continue
+		}
+		for _, value := range values {
+			var cond func(Row) bool
+			var err error
+			switch key {
+			case "or", "and", "not.or", "not.and":
+				cond, err = parseLogical(key + value)
+			default:
+				cond, err = parseFilter(key, value)
+			}
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, cond)
+		}
+	}
+	return all(conds), nil
+}
+
+// parseLogical parses a tree such as or(a.eq.1,and(b.gt.2,c.is.null))
+func parseLogical(expr string) (func(Row) bool, error) {
+	negate := false
+	if rest, ok := strings.CutPrefix(expr, "not."); ok {
+		negate, expr = true, rest
+	}
+	op, inner, ok := strings.Cut(expr, "(")
+	if !ok || !strings.HasSuffix(inner, ")") || (op != "or" && op != "and") {
+		return nil, badRequest("invalid logical filter %q", expr)
+	}
+
+	var conds []func(Row) bool
+	for _, term := range splitTerms(strings.TrimSuffix(inner, ")")) {
+		var cond func(Row) bool
+		var err error
+		if strings.HasPrefix(term, "or(") || strings.HasPrefix(term, "and(") || strings.HasPrefix(term, "not.or(") || strings.HasPrefix(term, "not.and(") {
+			cond, err = parseLogical(term)
+		} else {
+			column, filter, ok := strings.Cut(term, ".")
+			if !ok {
+				return nil, badRequest("invalid filter %q", term)
+			}
+			cond, err = parseFilter(column, filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+	}
+
+	cond := all(conds)
+	if op == "or" {
+		cond = func(row Row) bool {
+			for _, c := range conds {
+				if c(row) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	if negate {
+		return not(cond), nil
+	}
+	return cond, nil
+}
+
+// splitTerms splits a logical filter's terms at the commas outside
+// parentheses and double quotes
+func splitTerms(s string) []string {
+	var terms []string
+	depth, quoted, start := 0, false, 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			terms = append(terms, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(terms, s[start:])
+}
+
+// parseFilter parses a filter on column such as eq.5, not.is.null or in.(a,b)
+func parseFilter(column, filter string) (func(Row) bool, error) {
+	negate := false
+	if rest, ok := strings.CutPrefix(filter, "not."); ok {
+		negate, filter = true, rest
+	}
+	op, value, ok := strings.Cut(filter, ".")
+	if !ok {
+		return nil, badRequest("invalid filter %s=%s", column, filter)
+	}
+	value = unquote(value)
+
+	var cond func(Row) bool
+	switch op {
+	case "eq", "neq", "gt", "gte", "lt", "lte":
+		cond = func(row Row) bool {
+			c, ok := compare(row[column], value)
+			if !ok {
+				return false
+			}
+			switch op {
+			case "eq":
+				return c == 0
+			case "neq":
+				return c != 0
+			case "gt":
+				return c > 0
+			case "gte":
+				return c >= 0
+			case "lt":
+				return c < 0
+			}
+			return c <= 0
+		}
+	case "like", "ilike":
+		pattern := regexp.QuoteMeta(value)
+		pattern = strings.NewReplacer(`\*`, ".*", "%", ".*", "_", ".").Replace(pattern)
+		if op == "ilike" {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile("^" + pattern + "$")
+		if err != nil {
+			return nil, badRequest("invalid pattern %q", value)
+		}
+		cond = func(row Row) bool {
+			s, ok := row[column].(string)
+			return ok && re.MatchString(s)
+		}
+	case "is":
+		switch value {
+		case "null":
+			cond = func(row Row) bool { return row[column] == nil }
+		case "true", "false":
+			want := value == "true"
+			cond = func(row Row) bool { b, ok := row[column].(bool); return ok && b == want }
+		default:
+			return nil, badRequest("invalid is filter %q", value)
+		}
+	case "in":
+		if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
+			return nil, badRequest("invalid in filter %q", value)
+		}
+		var values []string
+		for _, v := range splitTerms(value[1 : len(value)-1]) {
+			values = append(values, unquote(v))
+		}
+		cond = func(row Row) bool {
+			for _, v := range values {
+				if c, ok := compare(row[column], v); ok && c == 0 {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return nil, badRequest("unsupported operator %q", op)
+	}
+
+	if negate {
+		return not(cond), nil
+	}
+	return cond, nil
+}
+
+// compare orders a stored value against a filter's text, as Postgres would
+// compare the value with the text cast to the column's type. Nulls and
+// values of other types compare as not ok.
+func compare(stored interface{}, text string) (int, bool) {
+	switch v := stored.(type) {
+	case string:
+		if a, err := parseTime(v); err == nil {
+			if b, err := parseTime(text); err == nil {
+				return a.Compare(b), true
+			}
+		}
+		return strings.Compare(v, text), true
+	case float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case v < f:
+			return -1, true
+		case v > f:
+			return 1, true
+		}
+		return 0, true
+	case bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case v == b:
+			return 0, true
+		case b:
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+// parseTime reads timestamps and dates as Postgres writes and accepts them
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func all(conds []func(Row) bool) func(Row) bool {
+	return func(row Row) bool {
+		for _, c := range conds {
+			if !c(row) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func not(cond func(Row) bool) func(Row) bool {
+	return func(row Row) bool { return !cond(row) }
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func copyRow(row Row) Row {
+	c := make(Row, len(row))
+	for k, v := range row {
+		c[k] = v
+	}
+	return c
+}
+
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
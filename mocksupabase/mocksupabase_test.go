@@ -0,0 +1,111 @@
+package mocksupabase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPostgRESTSemantics(t *testing.T) {
+	h := NewHandler()
+	h.Insert("tasks",
+		Row{"id": "a", "user_id": "u1", "title": "Alpha", "priority": 3.0, "due_date": "2026-03-01T09:00:00Z"},
+		Row{"id": "b", "user_id": "u1", "title": "beta", "priority": 5.0, "due_date": "2026-02-01T09:00:00Z", "deleted_at": "2026-02-02T00:00:00Z"},
+		Row{"id": "c", "user_id": "u2", "title": "Gamma", "due_date": "2026-01-01T09:00:00Z"},
+	)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	request := func(method, path, body, prefer string) (*http.Response, []Row) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+"/rest/v1/"+path, strings.NewReader(body))
+		if prefer != "" {
+			req.Header.Set("Prefer", prefer)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var rows []Row
+		if resp.StatusCode < 300 && method != http.MethodHead && resp.StatusCode != http.StatusNoContent {
+			if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+				t.Fatalf("%s %s: %v", method, path, err)
+			}
+		}
+		return resp, rows
+	}
+	ids := func(rows []Row) string {
+		var ids []string
+		for _, row := range rows {
+			ids = append(ids, row["id"].(string))
+		}
+		return strings.Join(ids, ",")
+	}
+
+	for _, tt := range []struct{ query, want string }{
+		{"user_id=eq.u1&order=due_date.asc", "b,a"},
+		{"deleted_at=is.null&order=title.desc", "c,a"},
+		{"priority=gte.3&priority=lt.5", "a"},
+		{"priority=eq.2", "c"}, // the column default
+		{"id=in.(a,c)&order=id.desc&limit=1", "c"},
+		{"title=ilike.*ETA", "b"},
+		{"due_date=lt." + url.QueryEscape("2026-02-15T00:00:00+00:00") + "&order=id", "b,c"},
+		{"or=" + url.QueryEscape(`(due_date.gt."2026-02-01T09:00:00Z",and(due_date.eq."2026-02-01T09:00:00Z",id.gt."a"))`) + "&order=id", "a,b"},
+		{"deleted_at=not.is.null", "b"},
+		{"order=id&offset=1", "b,c"},
+	} {
+		if _, rows := request(http.MethodGet, "tasks?"+tt.query, "", ""); ids(rows) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.query, ids(rows), tt.want)
+		}
+	}
+
+	if resp, _ := request(http.MethodHead, "tasks?user_id=eq.u1", "", "count=exact"); resp.Header.Get("Content-Range") != "*/2" {
+		t.Errorf("count: Content-Range %q", resp.Header.Get("Content-Range"))
+	}
+
+	// Inserts fill in ids and timestamps; a second row with the same key
+	// conflicts unless merged
+	_, rows := request(http.MethodPost, "tasks", `{"user_id":"u3","title":"Delta"}`, "return=representation")
+	if len(rows) != 1 || rows[0]["id"] == nil || rows[0]["created_at"] == nil || rows[0]["completed"] != false {
+		t.Errorf("insert returned %v", rows)
+	}
+	if resp, _ := request(http.MethodPost, "tasks", `{"id":"a","title":"Again"}`, "return=representation"); resp.StatusCode != http.StatusConflict {
+		t.Errorf("duplicate insert: %d", resp.StatusCode)
+	}
+	request(http.MethodPost, "user_preferences?on_conflict=user_id", `{"user_id":"u1","timezone":"UTC"}`, "resolution=merge-duplicates")
+	_, rows = request(http.MethodPost, "user_preferences?on_conflict=user_id", `{"user_id":"u1","timezone":"Europe/Paris"}`,
+		"return=representation,resolution=merge-duplicates")
+	if len(h.Rows("user_preferences")) != 1 || rows[0]["timezone"] != "Europe/Paris" || rows[0]["id"] != nil {
+		t.Errorf("upsert: %v", h.Rows("user_preferences"))
+	}
+
+	// Updates and deletes touch only the rows matched
+	if _, rows := request(http.MethodPatch, "tasks?user_id=eq.u1", `{"completed":true}`, "return=representation"); ids(rows) != "a,b" {
+		t.Errorf("update returned %s", ids(rows))
+	}
+	h.Insert("task_completions", Row{"task_id": "c"})
+	if resp, _ := request(http.MethodDelete, "tasks?id=eq.c", "", "return=minimal,count=exact"); resp.StatusCode != http.StatusNoContent || resp.Header.Get("Content-Range") != "*/1" {
+		t.Errorf("delete: %d %q", resp.StatusCode, resp.Header.Get("Content-Range"))
+	}
+	if len(h.Rows("task_completions")) != 0 {
+		t.Error("delete did not cascade")
+	}
+
+	for path, status := range map[string]int{
+		"rpc/sync_goal_progress":  http.StatusNotFound,
+		"tasks?priority=approx.3": http.StatusBadRequest,
+		"tasks?or=" + "(broken":   http.StatusBadRequest,
+	} {
+		method := http.MethodGet
+		if strings.HasPrefix(path, "rpc/") {
+			method = http.MethodPost
+		}
+		if resp, _ := request(method, path, "{}", ""); resp.StatusCode != status {
+			t.Errorf("%s: %d, want %d", path, resp.StatusCode, status)
+		}
+	}
+}
@@ -17,6 +17,11 @@ type Task struct {
 	RecurringFrequency string     `json:"recurring_frequency"`
 	RecurringInterval  int        `json:"recurring_interval"`
 	RecurringEndDate   *time.Time `json:"recurring_end_date"`
+	Language           string     `json:"language,omitempty"`
+	Context            string     `json:"context,omitempty"`        // e.g. @home, @office, @errands
+	DeferredUntil      *time.Time `json:"deferred_until,omitempty"` // set when created during a focus contract
+	WorkspaceID        *string    `json:"workspace_id,omitempty"`   // nil for personal tasks
+	ParentID           *string    `json:"parent_id,omitempty"`      // set on subtasks
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
 }
@@ -32,6 +37,10 @@ type CreateTaskRequest struct {
 	RecurringFrequency string     `json:"recurring_frequency"`
 	RecurringInterval  int        `json:"recurring_interval"`
 	RecurringEndDate   *time.Time `json:"recurring_end_date"`
+	Language           string     `json:"language"`     // detected from the text when empty
+	Context            string     `json:"context"`      // e.g. @home; the @ is optional
+	WorkspaceID        string     `json:"workspace_id"` // shares the task; requires the editor role
+	ParentID           string     `json:"parent_id"`    // makes it a subtask; requires the editor role on the parent
 }
 
 // UpdateTaskRequest represents a request to update a task
@@ -46,6 +55,114 @@ type UpdateTaskRequest struct {
 	RecurringFrequency *string    `json:"recurring_frequency"`
 	RecurringInterval  *int       `json:"recurring_interval"`
 	RecurringEndDate   *time.Time `json:"recurring_end_date"`
+	Language           *string    `json:"language"`
+	Context            *string    `json:"context"` // "" clears it
+}
+
+// TaskView is the open tasks due in a window of the user's calendar, such as
+// today or the next 7 days. Times are in the user's time zone.
+type TaskView struct {
+	View     string                   `json:"view"` // overdue, today or upcoming
+	Timezone string                   `json:"timezone"`
+	From     *time.Time               `json:"from,omitempty"` // unset for overdue, which has no start
+	Until    time.Time                `json:"until"`          // exclusive
+	Count    int                      `json:"count"`
+	Tasks    []map[string]interface{} `json:"tasks"`
+}
+
+// ContextTasks is the open tasks that can be done in one context
+type ContextTasks struct {
+	Context string                   `json:"context"`
+	Count   int                      `json:"count"`
+	Tasks   []map[string]interface{} `json:"tasks"`
+	// Available lists every context the user's open tasks use
+	Available []string `json:"available_contexts"`
+}
+
+// SnoozeTaskRequest pushes a task's due date back. Until is a natural
+// language time such as "next Monday morning"; without one the server picks
+// a working-day slot.
+type SnoozeTaskRequest struct {
+	Until string `json:"until,omitempty"`
+}
+
+// SnoozeResult is a snoozed task and why it landed where it did
+type SnoozeResult struct {
+	Task            map[string]interface{} `json:"task"`
+	PreviousDueDate *time.Time             `json:"previous_due_date,omitempty"`
+	DueDate         time.Time              `json:"due_date"`
+	Timezone        string                 `json:"timezone"`
+	Source          string                 `json:"source"` // phrase or smart
+	Reason          string                 `json:"reason"`
+	CapacityWarning *CapacityWarning       `json:"capacity_warning,omitempty"`
+}
+
+// CapacityWarning says a task lands on a day whose open tasks' estimates
+// exceed the user's working hours
+type CapacityWarning struct {
+	Date            string     `json:"date"` // YYYY-MM-DD in the user's time zone
+	BookedMinutes   int        `json:"booked_minutes"`
+	CapacityMinutes int        `json:"capacity_minutes"`
+	Message         string     `json:"message"`
+	SuggestedDate   *time.Time `json:"suggested_date,omitempty"` // the next day with room, at the same time
+}
+
+// DayCapacity is how much of one day's working hours open tasks have booked
+type DayCapacity struct {
+	Date            string `json:"date"` // YYYY-MM-DD
+	Tasks           int    `json:"tasks"`
+	BookedMinutes   int    `json:"booked_minutes"`
+	CapacityMinutes int    `json:"capacity_minutes"`
+	Overbooked      bool   `json:"overbooked"`
+}
+
+// CapacityReport is a week of DayCapacity
+type CapacityReport struct {
+	WeekStart       string        `json:"week_start"` // YYYY-MM-DD
+	Timezone        string        `json:"timezone"`
+	BookedMinutes   int           `json:"booked_minutes"`
+	CapacityMinutes int           `json:"capacity_minutes"`
+	Days            []DayCapacity `json:"days"`
+}
+
+// Reschedule is one entry in a task's reschedule history
+type Reschedule struct {
+	ID              string     `json:"id"`
+	TaskID          string     `json:"task_id"`
+	PreviousDueDate *time.Time `json:"previous_due_date,omitempty"`
+	NewDueDate      time.Time  `json:"new_due_date"`
+	Source          string     `json:"source"`
+	Phrase          string     `json:"phrase,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// SemanticSearchRequest finds tasks by meaning rather than exact words
+type SemanticSearchRequest struct {
+	Query string `json:"query" binding:"required"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// DuplicateCheckRequest describes a task that may already exist
+type DuplicateCheckRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description,omitempty"`
+	Limit       int    `json:"limit,omitempty"`
+}
+
+// TaskMatch is a task ranked by similarity, from -1 to 1, to a query or
+// another task
+type TaskMatch struct {
+	Task       map[string]interface{} `json:"task"`
+	Similarity float64                `json:"similarity"`
+	Duplicate  bool                   `json:"duplicate"` // similar enough to be the same task
+}
+
+// TaskMatches is the result of a semantic search, related-task or duplicate lookup
+type TaskMatches struct {
+	Model   string      `json:"model"`
+	Query   string      `json:"query,omitempty"`
+	TaskID  string      `json:"task_id,omitempty"`
+	Matches []TaskMatch `json:"matches"`
 }
 
 // Goal represents a long-term productivity goal
@@ -58,6 +175,8 @@ type Goal struct {
 	TargetDate  time.Time `json:"target_date"`
 	Progress    int       `json:"progress"`
 	Archived    bool      `json:"archived"`
+	Language    string    `json:"language,omitempty"`
+	WorkspaceID *string   `json:"workspace_id,omitempty"` // nil for personal goals
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -69,6 +188,11 @@ type CreateGoalRequest struct {
 	StartDate   time.Time `json:"start_date" binding:"required"`
 	TargetDate  time.Time `json:"target_date" binding:"required"`
 	Progress    int       `json:"progress"`
+	Language    string    `json:"language"`     // detected from the text when empty
+	WorkspaceID string    `json:"workspace_id"` // shares the goal; requires the editor role
+	// Milestones are created with the goal, e.g. those proposed by
+	// suggest-milestones; the goal's progress then follows them
+	Milestones []CreateMilestoneRequest `json:"milestones"`
 }
 
 // UpdateGoalRequest represents a request to update a goal
@@ -79,12 +203,99 @@ type UpdateGoalRequest struct {
 	TargetDate  *time.Time `json:"target_date"`
 	Progress    *int       `json:"progress"`
 	Archived    *bool      `json:"archived"`
+	Language    *string    `json:"language"`
+}
+
+// Milestone is a dated step towards a goal. A goal with milestones takes its
+// progress from the share of them completed.
+type Milestone struct {
+	ID          string     `json:"id"`
+	GoalID      string     `json:"goal_id"`
+	UserID      string     `json:"user_id"`
+	Title       string     `json:"title"`
+	DueDate     *time.Time `json:"due_date"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CreateMilestoneRequest represents a request to add a milestone to a goal
+type CreateMilestoneRequest struct {
+	Title     string     `json:"title" binding:"required"`
+	DueDate   *time.Time `json:"due_date"`
+	Completed bool       `json:"completed"`
+}
+
+// UpdateMilestoneRequest represents a request to update a milestone
+type UpdateMilestoneRequest struct {
+	Title     *string    `json:"title"`
+	DueDate   *time.Time `json:"due_date"`
+	Completed *bool      `json:"completed"`
+}
+
+// Workspace roles, from least to most privileged
+const (
+	RoleViewer = "viewer" // reads shared tasks and goals
+	RoleEditor = "editor" // also creates, changes and deletes them
+	RoleOwner  = "owner"  // also manages members, invites and the workspace
+)
+
+// Workspace is a set of users sharing tasks and goals
+type Workspace struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	Role      string    `json:"role,omitempty"` // the requesting user's role
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Membership gives a user a role in a workspace
+type Membership struct {
+	WorkspaceID string    `json:"workspace_id"`
+	UserID      string    `json:"user_id"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateWorkspaceRequest represents a request to create a workspace
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// CreateInviteRequest represents a request to invite someone to a workspace
+type CreateInviteRequest struct {
+	Role  string `json:"role"`  // editor or viewer; defaults to viewer
+	Email string `json:"email"` // optional, for the inviter's records
+}
+
+// AcceptInviteRequest represents a request to join a workspace with an invite token
+type AcceptInviteRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UpdateMemberRequest represents a request to change a member's role
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
 }
 
 // ParseTaskRequest represents a request to parse natural language into a task
 type ParseTaskRequest struct {
-	Input  string `json:"input" binding:"required"`
-	UserID string `json:"user_id" binding:"required"`
+	Input    string `json:"input" binding:"required"`
+	UserID   string `json:"user_id" binding:"required"`
+	Timezone string `json:"timezone"` // IANA name relative dates resolve in; defaults to the user's preference
+	LLMParams
+}
+
+// LLMParams let a request pick the model that answers it and tune its answer,
+// within the server's allow-list and caps. Zero values keep the configured
+// settings.
+type LLMParams struct {
+	// Model is a Claude model, or ollama: followed by an Ollama model
+	Model       string   `json:"model,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
 }
 
 // ParseTaskResponse represents the response from parsing natural language
@@ -93,6 +304,122 @@ type ParseTaskResponse struct {
 	Subtasks    []string `json:"subtasks"`
 	Confidence  float64  `json:"confidence"`
 	Explanation string   `json:"explanation"`
+	// ProviderUsed is the model provider that answered, claude or ollama;
+	// empty when the task was parsed without one
+	ProviderUsed string `json:"provider_used,omitempty"`
+	// Degraded is set when the answer, or part of it, was made up without a
+	// model, such as while Claude is unavailable
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// ParseAudioResponse is what a voice memo said and the task parsed from it
+type ParseAudioResponse struct {
+	Transcript string             `json:"transcript"`
+	Language   string             `json:"language,omitempty"` // as the transcription model names it
+	Duration   float64            `json:"duration,omitempty"` // seconds
+	Model      string             `json:"model"`              // transcription model
+	Parsed     *ParseTaskResponse `json:"parsed"`
+}
+
+// Email ingestion statuses
+const (
+	EmailProcessed = "processed"
+	EmailIgnored   = "ignored" // not sent to a known ingest address
+)
+
+// EmailIngestResponse reports the tasks created from a forwarded email
+type EmailIngestResponse struct {
+	Status  string                   `json:"status"`
+	Tasks   []map[string]interface{} `json:"tasks,omitempty"`
+	Skipped []SkippedEmailPart       `json:"skipped,omitempty"`
+}
+
+// SkippedEmailPart is a subject or attachment that produced no task, and why
+type SkippedEmailPart struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// EmailAddressResponse is the address a user forwards email to for it to become tasks
+type EmailAddressResponse struct {
+	Address string `json:"address"`
+}
+
+// IntegrationConnectRequest connects a user's account on an outside service.
+// Credentials are sealed before they are stored and never returned; which
+// credentials and settings a provider takes is up to it.
+type IntegrationConnectRequest struct {
+	Credentials map[string]string      `json:"credentials" binding:"required"`
+	Settings    map[string]interface{} `json:"settings"`
+}
+
+// IntegrationConnection is a user's connection to a provider and how its
+// last sync went; the credentials are never returned
+type IntegrationConnection struct {
+	Provider     string                 `json:"provider"`
+	Connected    bool                   `json:"connected"`
+	Settings     map[string]interface{} `json:"settings,omitempty"`
+	LastSyncedAt *time.Time             `json:"last_synced_at,omitempty"`
+	LastError    string                 `json:"last_error,omitempty"`
+}
+
+// RefineTaskRequest asks for a parsed task to be corrected. The first
+// correction sends the task; later ones send the conversation_id returned
+// with it, and may send the task again if the client has changed it.
+type RefineTaskRequest struct {
+	UserID         string `json:"user_id" binding:"required"`
+	Correction     string `json:"correction" binding:"required"` // e.g. "actually it's due Friday and it's priority 5"
+	Task           *Task  `json:"task"`
+	ConversationID string `json:"conversation_id"`
+	Timezone       string `json:"timezone"` // IANA name relative dates resolve in; defaults to the user's preference
+}
+
+// RefineTaskResponse is the corrected task and the conversation to send further corrections in
+type RefineTaskResponse struct {
+	ConversationID string    `json:"conversation_id"`
+	Task           *Task     `json:"task"`
+	Turns          int       `json:"turns"` // corrections made in the conversation so far
+	Confidence     float64   `json:"confidence"`
+	Explanation    string    `json:"explanation"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// ParseDateRequest represents a request to resolve a natural language date
+type ParseDateRequest struct {
+	Text     string `json:"text" binding:"required"`
+	Timezone string `json:"timezone"` // IANA name, e.g. Europe/Berlin; defaults to the user's preference
+}
+
+// ParseDateResponse represents the date found in a ParseDateRequest's text
+type ParseDateResponse struct {
+	Found bool       `json:"found"`
+	Date  *time.Time `json:"date"`
+	// HasTime is false when the text named only a day; Date is then at the
+	// end of the user's working day
+	HasTime   bool   `json:"has_time"`
+	Matched   string `json:"matched,omitempty"`   // the date phrase, as written
+	Remainder string `json:"remainder,omitempty"` // the text without the date phrase
+	Timezone  string `json:"timezone"`
+}
+
+// Preferences are a user's calendar settings, used to resolve relative
+// dates and to decide what is due today or overdue
+type Preferences struct {
+	Timezone  string `json:"timezone"`   // IANA name, e.g. Europe/Berlin
+	Locale    string `json:"locale"`     // BCP 47 tag, e.g. en-GB
+	WeekStart string `json:"week_start"` // lowercase weekday name
+	WorkStart string `json:"work_start"` // HH:MM, 24-hour
+	WorkEnd   string `json:"work_end"`   // HH:MM, 24-hour
+}
+
+// UpdatePreferencesRequest represents a request to change some of a user's
+// preferences; omitted fields keep their current value
+type UpdatePreferencesRequest struct {
+	Timezone  *string `json:"timezone"`
+	Locale    *string `json:"locale"`
+	WeekStart *string `json:"week_start"`
+	WorkStart *string `json:"work_start"`
+	WorkEnd   *string `json:"work_end"`
 }
 
 // GenerateSubtasksRequest represents a request to generate subtasks
@@ -100,12 +427,36 @@ type GenerateSubtasksRequest struct {
 	TaskTitle       string `json:"task_title" binding:"required"`
 	TaskDescription string `json:"task_description"`
 	UserID          string `json:"user_id" binding:"required"`
+	LLMParams
 }
 
 // GenerateSubtasksResponse represents the response from generating subtasks
 type GenerateSubtasksResponse struct {
-	Subtasks    []string `json:"subtasks"`
-	Explanation string   `json:"explanation"`
+	Subtasks     []string `json:"subtasks"`
+	Explanation  string   `json:"explanation"`
+	ProviderUsed string   `json:"provider_used,omitempty"`
+	// Degraded is set when the answer, or part of it, was made up without a
+	// model, such as while Claude is unavailable
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// SuggestMilestonesRequest represents a request to propose milestones for a goal
+type SuggestMilestonesRequest struct {
+	GoalTitle       string     `json:"goal_title" binding:"required"`
+	GoalDescription string     `json:"goal_description"`
+	StartDate       *time.Time `json:"start_date"`  // defaults to now
+	TargetDate      *time.Time `json:"target_date"` // milestones are left undated without one
+	UserID          string     `json:"user_id"`
+}
+
+// SuggestMilestonesResponse represents the milestones proposed for a goal, ready
+// to pass as a new goal's milestones
+type SuggestMilestonesResponse struct {
+	Milestones  []CreateMilestoneRequest `json:"milestones"`
+	Explanation string                   `json:"explanation"`
+	// Degraded is set when the answer, or part of it, was made up without a
+	// model, such as while Claude is unavailable
+	Degraded bool `json:"degraded,omitempty"`
 }
 
 // ParseFileRequest represents a request to parse a file
@@ -114,6 +465,7 @@ type ParseFileRequest struct {
 	FileContent string `json:"file_content" binding:"required"`
 	FileType    string `json:"file_type" binding:"required"`
 	UserID      string `json:"user_id" binding:"required"`
+	LLMParams
 }
 
 // ParseFileResponse represents the response from parsing a file
@@ -121,6 +473,11 @@ type ParseFileResponse struct {
 	Tasks         []Task                 `json:"tasks"`
 	ExtractedData map[string]interface{} `json:"extracted_data"`
 	Summary       string                 `json:"summary"`
+	// ProviderUsed lists the model providers that answered, comma-separated
+	// when parts of a long file failed over
+	ProviderUsed string `json:"provider_used,omitempty"`
+	// Degraded is set when a part of the file could not be parsed
+	Degraded bool `json:"degraded,omitempty"`
 }
 
 // AnalyzeProductivityRequest represents a request to analyze productivity
@@ -136,6 +493,90 @@ type AnalyzeProductivityResponse struct {
 	CompletionRate  float64  `json:"completion_rate"`
 	Insights        []string `json:"insights"`
 	Recommendations []string `json:"recommendations"`
+	// Stats are the user's streaks, badges and weekly velocity, when available
+	Stats *CompletionStats `json:"stats,omitempty"`
+	// Degraded is set when the insights or recommendations are generic ones
+	// given in place of the model's
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// CompletionStats summarizes a user's completed tasks: their daily streak,
+// the badges they have earned and their weekly velocity. Days and weeks
+// follow the user's time zone and first day of the week.
+type CompletionStats struct {
+	// CurrentStreak counts consecutive days with a completion, ending today or yesterday
+	CurrentStreak  int     `json:"current_streak"`
+	LongestStreak  int     `json:"longest_streak"`
+	TotalCompleted int     `json:"total_completed"`
+	LastActiveDay  string  `json:"last_active_day,omitempty"` // YYYY-MM-DD
+	Badges         []Badge `json:"badges"`
+	// WeeklyVelocity is the completions of recent weeks, oldest first and the
+	// current week last
+	WeeklyVelocity []WeekCompletions `json:"weekly_velocity"`
+	AveragePerWeek float64           `json:"average_per_week"` // over the finished weeks
+	Timezone       string            `json:"timezone"`
+}
+
+// Badge is an achievement earned through completions
+type Badge struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// WeekCompletions is the number of tasks completed in a week
+type WeekCompletions struct {
+	WeekStart string `json:"week_start"` // YYYY-MM-DD
+	Completed int    `json:"completed"`
+}
+
+// EisenhowerMatrixRequest represents a request to sort open tasks into the
+// urgent/important quadrants
+type EisenhowerMatrixRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	// Refine asks Claude to review the rule-based placement
+	Refine bool `json:"refine"`
+}
+
+// EisenhowerMatrix is a user's open tasks grouped by urgency and importance
+type EisenhowerMatrix struct {
+	// Quadrants are always do, schedule, delegate and eliminate, in that order
+	Quadrants   []MatrixQuadrant `json:"quadrants"`
+	Refined     bool             `json:"refined"` // Claude reviewed the placement
+	Explanation string           `json:"explanation"`
+	Timezone    string           `json:"timezone"`
+}
+
+// MatrixQuadrant is one quadrant of an EisenhowerMatrix
+type MatrixQuadrant struct {
+	ID        string       `json:"id"`
+	Label     string       `json:"label"`
+	Urgent    bool         `json:"urgent"`
+	Important bool         `json:"important"`
+	Tasks     []MatrixTask `json:"tasks"`
+}
+
+// MatrixTask is a task placed in a quadrant, with why it is there
+type MatrixTask struct {
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Priority int        `json:"priority"`
+	DueDate  *time.Time `json:"due_date,omitempty"`
+	Reason   string     `json:"reason"`
+}
+
+// MemoryEntry is something the assistant remembers about a user across sessions
+type MemoryEntry struct {
+	Key       string    `json:"key"`
+	Kind      string    `json:"kind"` // fact, preference or commitment
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetMemoryRequest remembers value under key, replacing what was there
+type SetMemoryRequest struct {
+	Kind  string `json:"kind,omitempty"` // default: fact
+	Value string `json:"value" binding:"required"`
 }
 
 // MCPRequest represents a generic MCP request
@@ -0,0 +1,44 @@
+// Package openapi holds the server's OpenAPI 3 description. It is written by
+// hand in openapi.yaml rather than generated, so a route added or changed in
+// main.go must be documented there too.
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+var (
+	jsonOnce sync.Once
+	jsonSpec []byte
+	jsonErr  error
+)
+
+// YAML returns the spec as written
+func YAML() []byte {
+	return spec
+}
+
+// JSON returns the spec converted to JSON, the form SDK generators and
+// Swagger UI load
+func JSON() ([]byte, error) {
+	jsonOnce.Do(func() {
+		converted, err := yaml.YAMLToJSON(spec)
+		if err != nil {
+			jsonErr = err
+			return
+		}
+		var compact bytes.Buffer
+		if jsonErr = json.Compact(&compact, converted); jsonErr == nil {
+			jsonSpec = compact.Bytes()
+		}
+	})
+	return jsonSpec, jsonErr
+}
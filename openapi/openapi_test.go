@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var refPattern = regexp.MustCompile(`"\$ref":"#/([^"]+)"`)
+
+func TestSpec(t *testing.T) {
+	raw, err := JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["openapi"] != "3.0.3" {
+		t.Errorf("openapi = %v", doc["openapi"])
+	}
+
+	// Every reference points at something
+	for _, m := range refPattern.FindAllStringSubmatch(string(raw), -1) {
+		var node interface{} = doc
+		for _, part := range strings.Split(m[1], "/") {
+			obj, _ := node.(map[string]interface{})
+			node = obj[part]
+		}
+		if node == nil {
+			t.Errorf("dangling $ref #/%s", m[1])
+		}
+	}
+
+	paths := doc["paths"].(map[string]interface{})
+	operationIDs := map[string]string{}
+	for path, item := range paths {
+		for method, op := range item.(map[string]interface{}) {
+			if method == "parameters" {
+				continue
+			}
+			op := op.(map[string]interface{})
+			where := strings.ToUpper(method) + " " + path
+			id, _ := op["operationId"].(string)
+			if id == "" {
+				t.Errorf("%s has no operationId", where)
+			} else if other, ok := operationIDs[id]; ok {
+				t.Errorf("%s and %s share operationId %s", where, other, id)
+			}
+			operationIDs[id] = where
+			if responses, _ := op["responses"].(map[string]interface{}); len(responses) == 0 {
+				t.Errorf("%s has no responses", where)
+			}
+		}
+	}
+
+	// The routes client SDKs are generated for
+	for _, path := range []string{
+		"/api/tasks", "/api/tasks/{id}", "/api/tasks/{id}/snooze",
+		"/api/goals", "/api/goals/{id}", "/api/goals/{id}/milestones/{milestone_id}",
+		"/api/mcp/parse-task", "/api/mcp/eisenhower-matrix",
+		"/mcp/initialize", "/mcp/list_tools", "/mcp/call_tool", "/mcp/session",
+		"/authorize", "/oauth/token", "/oauth/introspect", "/oauth/logout", "/oauth/register",
+	} {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("%s is not documented", path)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package plans defines the plan tiers users are on and the soft limits each
+// one sets on tasks, AI calls and attachment sizes.
+package plans
+
+import (
+	"sync"
+	"time"
+)
+
+// Plan names
+const (
+	Free = "free"
+	Pro  = "pro"
+)
+
+// Names lists the plans, cheapest first
+var Names = []string{Free, Pro}
+
+// Valid reports whether name is a plan
+func Valid(name string) bool {
+	for _, plan := range Names {
+		if plan == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Limits are a plan's limits; zero is unlimited
+type Limits struct {
+	// Tasks is how many tasks, trash excluded, a user may have
+	Tasks int `json:"tasks"`
+	// AICallsPerDay is how many AI requests a user may make per UTC day
+	AICallsPerDay int `json:"ai_calls_per_day"`
+	// AttachmentBytes is the largest file a user may upload to be parsed or
+	// imported
+	AttachmentBytes int `json:"attachment_bytes"`
+}
+
+// DailyCounter counts each user's calls per UTC day. Counts are kept in
+// process, so each instance counts its own share and a restart starts over.
+type DailyCounter struct {
+	mu     sync.Mutex
+	day    time.Time
+	counts map[string]int
+}
+
+// NewDailyCounter creates a counter with no calls counted
+func NewDailyCounter() *DailyCounter {
+	return &DailyCounter{counts: make(map[string]int)}
+}
+
+// Take counts a call by user at now, unless limit calls have already been
+// counted today. It returns the calls counted today and whether this one
+// was. A limit <= 0 is unlimited.
+func (d *DailyCounter) Take(user string, now time.Time, limit int) (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rollLocked(now)
+
+	used := d.counts[user]
+	if limit > 0 && used >= limit {
+		return used, false
+	}
+	d.counts[user] = used + 1
+	return used + 1, true
+}
+
+// Used returns the calls counted for user today
+func (d *DailyCounter) Used(user string, now time.Time) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rollLocked(now)
+	return d.counts[user]
+}
+
+// rollLocked drops yesterday's counts once the day changes
+func (d *DailyCounter) rollLocked(now time.Time) {
+	if day := Today(now); !day.Equal(d.day) {
+		d.day = day
+		d.counts = make(map[string]int)
+	}
+}
+
+// Today returns the start of now's UTC day, when daily limits reset
+func Today(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
+
+// NextReset returns when daily limits next reset after now
+func NextReset(now time.Time) time.Time {
+	return Today(now).Add(24 * time.Hour)
+}
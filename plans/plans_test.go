@@ -0,0 +1,37 @@
+package plans
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyCounter(t *testing.T) {
+	counter := NewDailyCounter()
+	morning := time.Date(2026, 3, 14, 8, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 2; i++ {
+		if used, ok := counter.Take("u1", morning, 2); !ok || used != i {
+			t.Fatalf("call %d: got (%d, %v), want (%d, true)", i, used, ok, i)
+		}
+	}
+	if used, ok := counter.Take("u1", morning.Add(time.Hour), 2); ok || used != 2 {
+		t.Fatalf("call over the limit: got (%d, %v), want (2, false)", used, ok)
+	}
+	if _, ok := counter.Take("u2", morning, 2); !ok {
+		t.Fatal("another user's call was refused")
+	}
+	if used, ok := counter.Take("u1", morning, 0); !ok || used != 3 {
+		t.Fatalf("unlimited call: got (%d, %v), want (3, true)", used, ok)
+	}
+
+	tomorrow := NextReset(morning)
+	if !tomorrow.Equal(time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("NextReset = %v", tomorrow)
+	}
+	if used := counter.Used("u1", tomorrow); used != 0 {
+		t.Fatalf("calls the next day = %d, want 0", used)
+	}
+	if _, ok := counter.Take("u1", tomorrow, 2); !ok {
+		t.Fatal("call the next day was refused")
+	}
+}
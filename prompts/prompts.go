@@ -0,0 +1,280 @@
+// Package prompts holds the prompt templates sent to Claude. Each is a
+// text/template filled in from one of the *Data types below. The built-in
+// templates are embedded in the binary; a deployment can override any of
+// them with a <name>.tmpl file in its prompts directory and reload the
+// directory without restarting.
+//
+// A template may start with a {{/* version: N */ -}} comment naming its
+// version; an override without one is versioned by a hash of its text, so
+// logs and the admin API always say which prompt was in use.
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Names of the templates the server renders
+const (
+	ParseTask           = "parse_task"
+	ParseFile           = "parse_file"
+	RefineTask          = "refine_task"
+	GenerateSubtasks    = "generate_subtasks"
+	SuggestMilestones   = "suggest_milestones"
+	AnalyzeProductivity = "analyze_productivity"
+	ReviewMatrix        = "review_matrix"
+	SummarizeFile       = "summarize_file"
+	MapColumns          = "map_columns"
+	CorrectJSON         = "correct_json"
+)
+
+// ParseTaskData fills in parse_task. The *Line fields are empty or whole
+// lines, each starting and ending with a newline.
+type ParseTaskData struct {
+	LanguageLine string
+	Now          string
+	Timezone     string
+	Locale       string
+	MemoryLines  string
+	Input        string
+}
+
+// RefineTaskData fills in refine_task; Task is JSON
+type RefineTaskData struct {
+	LanguageLine string
+	Now          string
+	Timezone     string
+	Locale       string
+	Task         string
+	Correction   string
+}
+
+// ParseFileData fills in parse_file; PartLine is set when the file is sent in
+// parts, and says how much of the previous part the content repeats
+type ParseFileData struct {
+	LanguageLine string
+	FileName     string
+	FileType     string
+	PartLine     string
+	Content      string
+}
+
+// SummarizeFileData fills in summarize_file, sent after a file was parsed in
+// parts; Summaries has one "Part N: ..." line per part
+type SummarizeFileData struct {
+	LanguageLine string
+	FileName     string
+	Summaries    string
+}
+
+// MapColumnsData fills in map_columns, sent to map a spreadsheet's columns
+// to task fields; Fields has a "- name: description" line per field,
+// Columns is a JSON array of the headers and Rows a JSON array per sample row
+type MapColumnsData struct {
+	Fields  string
+	Columns string
+	Rows    string
+}
+
+// GenerateSubtasksData fills in generate_subtasks
+type GenerateSubtasksData struct {
+	Title        string
+	Description  string
+	LanguageLine string
+}
+
+// SuggestMilestonesData fills in suggest_milestones; Target is "none" without a target date
+type SuggestMilestonesData struct {
+	Title        string
+	Description  string
+	Start        string
+	Target       string
+	LanguageLine string
+}
+
+// AnalyzeProductivityData fills in analyze_productivity; Tasks is JSON of
+// the Listed most recent of the Created tasks created in the period
+type AnalyzeProductivityData struct {
+	StatsLines string
+	Days       int
+	Created    int
+	Listed     int
+	Tasks      string
+}
+
+// ReviewMatrixData fills in review_matrix; Tasks is JSON
+type ReviewMatrixData struct {
+	ImportantPriority int
+	Now               string
+	Tasks             string
+}
+
+// CorrectJSONData fills in correct_json, sent when a reply to one of the
+// other prompts is not the JSON it asked for; Error says what was wrong
+type CorrectJSONData struct {
+	Error string
+}
+
+// dataTypes are the data each template is rendered with; overrides are
+// checked against them when loaded
+var dataTypes = map[string]interface{}{
+	ParseTask:           ParseTaskData{},
+	ParseFile:           ParseFileData{},
+	RefineTask:          RefineTaskData{},
+	GenerateSubtasks:    GenerateSubtasksData{},
+	SuggestMilestones:   SuggestMilestonesData{},
+	AnalyzeProductivity: AnalyzeProductivityData{},
+	ReviewMatrix:        ReviewMatrixData{},
+	SummarizeFile:       SummarizeFileData{},
+	MapColumns:          MapColumnsData{},
+	CorrectJSON:         CorrectJSONData{},
+}
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// SourceBuiltin is the Source of a template embedded in the binary
+const SourceBuiltin = "builtin"
+
+var versionComment = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\S+)\s*\*/\s*-?\}\}`)
+
+// Template is one loaded prompt template
+type Template struct {
+	Name     string    `json:"name"`
+	Version  string    `json:"version"`
+	Source   string    `json:"source"` // builtin, or the override file
+	LoadedAt time.Time `json:"loaded_at"`
+
+	tmpl *template.Template
+}
+
+// Registry is the set of templates in use. It is safe for concurrent use.
+type Registry struct {
+	dir string
+
+	mu        sync.RWMutex
+	templates map[string]*Template
+}
+
+// Builtin returns a registry of the built-in templates only
+func Builtin() *Registry {
+	r, err := Load("")
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Load returns a registry of the built-in templates, overridden by the
+// <name>.tmpl files in dir if dir is set
+func Load(dir string) (*Registry, error) {
+	r := &Registry{dir: dir}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the templates again. If any fails to load, the templates in
+// use are kept and the error says which.
+func (r *Registry) Reload() ([]Template, error) {
+	now := time.Now()
+	templates := map[string]*Template{}
+	for name := range dataTypes {
+		src, err := builtin.ReadFile("templates/" + name + ".tmpl")
+		if err != nil {
+			return nil, err
+		}
+		t, err := parse(name, string(src), SourceBuiltin, now)
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = t
+	}
+
+	if r.dir != "" {
+		files, err := filepath.Glob(filepath.Join(r.dir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			name := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+			if _, ok := dataTypes[name]; !ok {
+				return nil, fmt.Errorf("%s: unknown prompt %q", file, name)
+			}
+			src, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			t, err := parse(name, string(src), file, now)
+			if err != nil {
+				return nil, err
+			}
+			templates[name] = t
+		}
+	}
+
+	r.mu.Lock()
+	r.templates = templates
+	r.mu.Unlock()
+	return r.Templates(), nil
+}
+
+// parse compiles a template and checks it renders with its data type
+func parse(name, src, source string, loadedAt time.Time) (*Template, error) {
+	src = strings.TrimRight(src, "\n")
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, dataTypes[name]); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	version := ""
+	if m := versionComment.FindStringSubmatch(src); m != nil {
+		version = m[1]
+	} else {
+		sum := sha256.Sum256([]byte(src))
+		version = hex.EncodeToString(sum[:4])
+	}
+	return &Template{Name: name, Version: version, Source: source, LoadedAt: loadedAt, tmpl: tmpl}, nil
+}
+
+// Templates lists the templates in use, by name
+func (r *Registry) Templates() []Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]Template, 0, len(r.templates))
+	for _, t := range r.templates {
+		list = append(list, *t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Render fills in the named template with data, which must be its *Data type
+func (r *Registry) Render(name string, data interface{}) (string, error) {
+	r.mu.RLock()
+	t, ok := r.templates[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown prompt %q", name)
+	}
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("prompt %s (version %s): %w", name, t.Version, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,101 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinRendersTheInlinePrompts(t *testing.T) {
+	r := Builtin()
+	if got := len(r.Templates()); got != len(dataTypes) {
+		t.Fatalf("%d templates, want %d", got, len(dataTypes))
+	}
+	versions := map[string]string{AnalyzeProductivity: "2"} // the rest are "1"
+	for _, tmpl := range r.Templates() {
+		want := versions[tmpl.Name]
+		if want == "" {
+			want = "1"
+		}
+		if tmpl.Version != want || tmpl.Source != SourceBuiltin {
+			t.Errorf("%s: version %q, source %q", tmpl.Name, tmpl.Version, tmpl.Source)
+		}
+	}
+
+	got, err := r.Render(GenerateSubtasks, GenerateSubtasksData{Title: "Plan trip", Description: "to Rome", LanguageLine: "\nAnswer in Italian.\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Generate 3-7 actionable subtasks for the following task. Return a JSON array of strings, each string being a subtask.
+
+Task Title: "Plan trip"
+Task Description: "to Rome"
+
+Answer in Italian.
+
+Return ONLY a JSON array of strings, no other text. Example: ["Subtask 1", "Subtask 2", "Subtask 3"]`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := r.Render("no_such_prompt", nil); err == nil {
+		t.Error("unknown prompt: expected an error")
+	}
+}
+
+func TestOverridesAndReload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(ParseTask, "ignored: not a .tmpl file")
+	write(ParseTask+".tmpl", "{{/* version: 2-terse */ -}}\nTask from: {{.Input}}\n")
+
+	r, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Render(ParseTask, ParseTaskData{Input: "call mom"})
+	if err != nil || got != "Task from: call mom" {
+		t.Errorf("override: %q, %v", got, err)
+	}
+	for _, tmpl := range r.Templates() {
+		if tmpl.Name == ParseTask && (tmpl.Version != "2-terse" || !strings.HasSuffix(tmpl.Source, "parse_task.tmpl")) {
+			t.Errorf("override: version %q, source %q", tmpl.Version, tmpl.Source)
+		}
+	}
+
+	// Without a version comment the version is a hash of the text
+	write(ParseTask+".tmpl", "Task: {{.Input}}")
+	if _, err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.Render(ParseTask, ParseTaskData{Input: "call mom"}); got != "Task: call mom" {
+		t.Errorf("reloaded: %q", got)
+	}
+	for _, tmpl := range r.Templates() {
+		if tmpl.Name == ParseTask && len(tmpl.Version) != 8 {
+			t.Errorf("hashed version %q", tmpl.Version)
+		}
+	}
+
+	// A broken override fails the reload and the templates in use stay
+	for name, src := range map[string]string{
+		ParseTask + ".tmpl":        "Task: {{.Nope}}",
+		"parse_tasks.tmpl":         "Task: {{.Input}}",
+		GenerateSubtasks + ".tmpl": "{{if}}",
+	} {
+		write(name, src)
+		if _, err := r.Reload(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		os.Remove(filepath.Join(dir, name))
+	}
+	if got, _ := r.Render(ParseTask, ParseTaskData{Input: "call mom"}); got != "Task: call mom" {
+		t.Errorf("after failed reloads: %q", got)
+	}
+}
@@ -0,0 +1,236 @@
+// Package recording captures sanitized request/response pairs as fixtures
+// and replays them against another build to catch behavioural regressions.
+// Recording is a development aid: fixtures hold real request data, so the
+// server refuses to record in release mode.
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Redacted replaces secrets in fixtures. On replay a redacted value in the
+// expected response matches anything, and a redacted credential header is
+// replaced with the one given to the replay runner.
+const Redacted = "[REDACTED]"
+
+// sensitiveHeaders are never written to a fixture
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"X-Api-Key":     true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// sensitiveFields are redacted wherever they appear in a JSON body, form or query
+var sensitiveFields = map[string]bool{
+	"password":                  true,
+	"secret":                    true,
+	"token":                     true,
+	"access_token":              true,
+	"refresh_token":             true,
+	"id_token":                  true,
+	"client_secret":             true,
+	"registration_access_token": true,
+	"api_key":                   true,
+	"key":                       true,
+	"code_verifier":             true,
+}
+
+// sensitiveParams are additionally redacted in forms and queries, where
+// "code" is an OAuth authorization code rather than an error code
+var sensitiveParams = map[string]bool{
+	"code": true,
+}
+
+// Fixture is one recorded exchange
+type Fixture struct {
+	// Route is the matched route pattern, e.g. "POST /api/tasks/:id"
+	Route      string    `json:"route"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Request    Request   `json:"request"`
+	Response   Response  `json:"response"`
+
+	// Name is the fixture's file name, set when it is read back
+	Name string `json:"-"`
+}
+
+// Request is the recorded request
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body"`
+}
+
+// Response is the recorded response
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body"`
+}
+
+// Body holds JSON bodies as JSON so fixtures stay readable and comparable,
+// anything else as text
+type Body struct {
+	JSON      json.RawMessage `json:"json,omitempty"`
+	Text      string          `json:"text,omitempty"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// Bytes returns the body as sent on the wire
+func (b Body) Bytes() []byte {
+	if b.JSON != nil {
+		return b.JSON
+	}
+	return []byte(b.Text)
+}
+
+// NewBody sanitizes data for a fixture, dropping it when longer than max
+func NewBody(data []byte, contentType string, max int) Body {
+	if len(data) > max {
+		return Body{Truncated: true}
+	}
+	trimmed := bytes.TrimSpace(data)
+	var v interface{}
+	if len(trimmed) > 0 && json.Unmarshal(trimmed, &v) == nil {
+		sanitized, _ := json.Marshal(redactJSON(v))
+		return Body{JSON: sanitized}
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(data)); err == nil {
+			return Body{Text: SanitizeQuery(form).Encode()}
+		}
+	}
+	return Body{Text: string(data)}
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if sensitiveFields[strings.ToLower(k)] {
+				v[k] = Redacted
+			} else {
+				v[k] = redactJSON(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+	}
+	return v
+}
+
+// SanitizeHeader copies h, redacting credentials and dropping hop-by-hop noise
+func SanitizeHeader(h http.Header) http.Header {
+	out := http.Header{}
+	for k, values := range h {
+		k = http.CanonicalHeaderKey(k)
+		switch {
+		case sensitiveHeaders[k]:
+			out[k] = []string{Redacted}
+		case k == "Content-Length" || k == "Connection" || k == "Accept-Encoding" || k == "Date":
+		default:
+			out[k] = append([]string(nil), values...)
+		}
+	}
+	return out
+}
+
+// SanitizeQuery redacts sensitive parameters
+func SanitizeQuery(q url.Values) url.Values {
+	out := url.Values{}
+	for k, values := range q {
+		if lower := strings.ToLower(k); sensitiveFields[lower] || sensitiveParams[lower] {
+			out[k] = []string{Redacted}
+			continue
+		}
+		out[k] = values
+	}
+	return out
+}
+
+// Writer stores fixtures as one JSON file each in a directory
+type Writer struct {
+	dir string
+	seq atomic.Uint64
+}
+
+// NewWriter creates dir if needed
+func NewWriter(dir string) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Writer{dir: dir}, nil
+}
+
+// Write saves f under a name that sorts in recording order
+func (w *Writer) Write(f *Fixture) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%04d-%s.json", f.RecordedAt.UTC().Format("20060102T150405"), w.seq.Add(1)%10000, slug(f.Route))
+	return os.WriteFile(filepath.Join(w.dir, name), data, 0600)
+}
+
+// slug turns "POST /api/tasks/:id" into "POST_api_tasks_id"
+func slug(route string) string {
+	var b strings.Builder
+	lastUnderscore := false
+	for _, r := range route {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			b.WriteRune(r)
+			lastUnderscore = false
+		} else if !lastUnderscore {
+			b.WriteByte('_')
+			lastUnderscore = true
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// ReadDir loads every fixture in dir in recording order
+func ReadDir(dir string) ([]*Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []*Fixture
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		f := &Fixture{}
+		if err := json.Unmarshal(data, f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		f.Name = filepath.Base(path)
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+// MatchRoute reports whether route ("POST /api/tasks/:id") is selected by
+// one of patterns: an exact route, or a prefix ending in "*" such as
+// "POST /api/*" or "*"
+func MatchRoute(patterns []string, route string) bool {
+	for _, p := range patterns {
+		if p == route || strings.HasSuffix(p, "*") && strings.HasPrefix(route, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
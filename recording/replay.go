@@ -0,0 +1,252 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultIgnore are response fields whose values change on every run
+var DefaultIgnore = []string{
+	"id", "created_at", "updated_at", "completed_at", "deleted_at", "timestamp",
+	"request_id", "jti", "iat", "exp", "expires_at", "issued_at",
+}
+
+// Replayer re-sends fixtures to a server and compares the responses
+type Replayer struct {
+	BaseURL string
+	Client  *http.Client
+
+	// Credentials replace redacted headers; a redacted header without a
+	// replacement is dropped
+	Authorization string
+	APIKey        string
+
+	// Ignore names JSON fields whose values are not compared
+	Ignore map[string]bool
+}
+
+// Result is the outcome of replaying one fixture
+type Result struct {
+	Fixture *Fixture
+	Status  int
+	Diffs   []string
+	Err     error
+}
+
+// Failed reports whether the response differed from the recording
+func (r Result) Failed() bool {
+	return r.Err != nil || len(r.Diffs) > 0
+}
+
+// Replay sends one fixture's request and compares the response with the recording
+func (r *Replayer) Replay(ctx context.Context, f *Fixture) Result {
+	res := Result{Fixture: f}
+	if f.Request.Body.Truncated {
+		res.Err = fmt.Errorf("request body was not recorded (too large)")
+		return res
+	}
+
+	target := strings.TrimSuffix(r.BaseURL, "/") + f.Request.Path
+	if f.Request.Query != "" {
+		target += "?" + f.Request.Query
+	}
+	req, err := http.NewRequestWithContext(ctx, f.Request.Method, target, bytes.NewReader(f.Request.Body.Bytes()))
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	for k, values := range f.Request.Header {
+		if len(values) == 1 && values[0] == Redacted {
+			switch k {
+			case "Authorization":
+				if r.Authorization != "" {
+					req.Header.Set(k, r.Authorization)
+				}
+			case "X-Api-Key":
+				if r.APIKey != "" {
+					req.Header.Set(k, r.APIKey)
+				}
+			}
+			continue
+		}
+		req.Header[k] = values
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	res.Status = resp.StatusCode
+	if resp.StatusCode != f.Response.Status {
+		res.Diffs = append(res.Diffs, fmt.Sprintf("status: want %d, got %d", f.Response.Status, resp.StatusCode))
+	}
+	want := f.Response.Body
+	if want.Truncated {
+		return res
+	}
+	got := NewBody(data, resp.Header.Get("Content-Type"), len(data))
+	switch {
+	case want.JSON != nil && got.JSON != nil:
+		var w, g interface{}
+		json.Unmarshal(want.JSON, &w)
+		json.Unmarshal(got.JSON, &g)
+		res.Diffs = append(res.Diffs, Compare(w, g, "$", r.Ignore)...)
+	case want.JSON != nil || got.JSON != nil:
+		res.Diffs = append(res.Diffs, "body: JSON and non-JSON responses differ")
+	case want.Text != got.Text:
+		res.Diffs = append(res.Diffs, "body: text differs")
+	}
+	return res
+}
+
+// Compare lists the differences between two decoded JSON values. Fields
+// named in ignore are skipped, and a recorded Redacted value matches any
+// value.
+func Compare(want, got interface{}, path string, ignore map[string]bool) []string {
+	if want == Redacted {
+		return nil
+	}
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: want object, got %s", path, describe(got))}
+		}
+		var diffs []string
+		for _, k := range sortedKeys(w, g) {
+			if ignore[k] {
+				continue
+			}
+			wv, inWant := w[k]
+			gv, inGot := g[k]
+			switch {
+			case !inGot:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: missing", path, k))
+			case !inWant:
+				diffs = append(diffs, fmt.Sprintf("%s.%s: unexpected field", path, k))
+			default:
+				diffs = append(diffs, Compare(wv, gv, path+"."+k, ignore)...)
+			}
+		}
+		return diffs
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: want array, got %s", path, describe(got))}
+		}
+		if len(w) != len(g) {
+			return []string{fmt.Sprintf("%s: want %d items, got %d", path, len(w), len(g))}
+		}
+		var diffs []string
+		for i := range w {
+			diffs = append(diffs, Compare(w[i], g[i], fmt.Sprintf("%s[%d]", path, i), ignore)...)
+		}
+		return diffs
+	}
+	if want != got {
+		return []string{fmt.Sprintf("%s: want %s, got %s", path, describe(want), describe(got))}
+	}
+	return nil
+}
+
+func sortedKeys(a, b map[string]interface{}) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range []map[string]interface{}{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func describe(v interface{}) string {
+	data, _ := json.Marshal(v)
+	if len(data) > 60 {
+		return string(data[:57]) + "..."
+	}
+	return string(data)
+}
+
+// Replay implements the `replay` subcommand: it replays every fixture in a
+// directory against a running build and fails when any response changed
+func Replay(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(out)
+	target := fs.String("target", "http://localhost:8080", "base URL of the build under test")
+	token := fs.String("token", "", "bearer token sent in place of redacted Authorization headers")
+	apiKey := fs.String("api-key", "", "API key sent in place of redacted X-API-Key headers")
+	ignore := fs.String("ignore", strings.Join(DefaultIgnore, ","), "comma-separated JSON fields not compared")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout for each request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: replay [flags] <fixture directory>")
+	}
+
+	fixtures, err := ReadDir(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if len(fixtures) == 0 {
+		return fmt.Errorf("no fixtures in %s", fs.Arg(0))
+	}
+
+	r := &Replayer{
+		BaseURL: *target,
+		Client:  &http.Client{Timeout: *timeout},
+		APIKey:  *apiKey,
+		Ignore:  map[string]bool{},
+	}
+	if *token != "" {
+		r.Authorization = "Bearer " + *token
+	}
+	for _, field := range strings.Split(*ignore, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			r.Ignore[field] = true
+		}
+	}
+
+	failed := 0
+	for _, f := range fixtures {
+		res := r.Replay(ctx, f)
+		if !res.Failed() {
+			fmt.Fprintf(out, "ok    %s  %s\n", f.Name, f.Route)
+			continue
+		}
+		failed++
+		fmt.Fprintf(out, "FAIL  %s  %s\n", f.Name, f.Route)
+		if res.Err != nil {
+			fmt.Fprintf(out, "      %v\n", res.Err)
+		}
+		for _, d := range res.Diffs {
+			fmt.Fprintf(out, "      %s\n", d)
+		}
+	}
+	fmt.Fprintf(out, "%d of %d fixtures replayed unchanged\n", len(fixtures)-failed, len(fixtures))
+	if failed > 0 {
+		return fmt.Errorf("%d fixtures regressed", failed)
+	}
+	return nil
+}
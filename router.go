@@ -0,0 +1,611 @@
+//go:build !lite
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/embeddings"
+	"github.com/productivity/mcp-server/events"
+	"github.com/productivity/mcp-server/flags"
+	"github.com/productivity/mcp-server/handlers"
+	"github.com/productivity/mcp-server/integrations"
+	"github.com/productivity/mcp-server/llm"
+	"github.com/productivity/mcp-server/mcpsession"
+	"github.com/productivity/mcp-server/middleware"
+	"github.com/productivity/mcp-server/prompts"
+	"github.com/productivity/mcp-server/recording"
+	"github.com/productivity/mcp-server/secrets"
+	"github.com/productivity/mcp-server/signing"
+	"github.com/productivity/mcp-server/slo"
+	"github.com/productivity/mcp-server/streaks"
+	"github.com/productivity/mcp-server/transcription"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// newRouter wires the handlers, middleware and routes for cfg. Background
+// workers it starts, such as the trash purge and the janitor, run until ctx
+// is cancelled.
+func newRouter(ctx context.Context, cfg *config.Config, logger *utils.Logger) (*gin.Engine, error) {
+	supabaseURL := cfg.Supabase.URL
+	supabaseKey := cfg.Supabase.AnonKey
+	claudeAPIKey := cfg.Claude.APIKey
+
+	// Every handler's Supabase client shares one tuned keep-alive pool and
+	// one cache of users' task lists
+	db.ConfigureHTTP(cfg.Supabase)
+	db.ConfigureTaskCache(cfg.Supabase.TaskCacheTTL.Duration)
+	// Credential tables are only reachable with the service-role key
+	db.ConfigureServiceRole(cfg.Supabase.ServiceRoleKey)
+	if cfg.Supabase.ServiceRoleKey == "" {
+		logger.Warn("SUPABASE_SERVICE_ROLE_KEY is not set; API keys, OAuth clients and token revocations use the anon key, which cannot read them once the migrations are applied", nil)
+	}
+
+	// Tokens issued by the OAuth handlers must verify in AuthMiddleware, and
+	// with OAUTH_RESOURCE set only tokens issued for this server do
+	signingKeys, err := signing.Load(cfg.Auth.JWTSecret, cfg.Auth.SigningKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT signing key: %w", err)
+	}
+	signingKeys = signingKeys.WithAudience(cfg.Auth.Resource)
+	handlers.SetSigningKeys(signingKeys)
+	middleware.SetSigningKeys(signingKeys)
+	if key := signingKeys.SigningKey(); key != nil {
+		logger.Info("Signing tokens with asymmetric key", map[string]interface{}{"alg": key.Algorithm, "kid": key.ID})
+	}
+	// Initialize Gin router
+	router := gin.New()
+
+	// Enable route debugging in development
+	if !cfg.Server.Release() {
+		gin.DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, nuHandlers int) {
+			logger.Info("Route registered",
+				map[string]interface{}{
+					"method":       httpMethod,
+					"path":         absolutePath,
+					"handler":      handlerName,
+					"num_handlers": nuHandlers,
+				},
+			)
+		}
+	}
+
+	// Add recovery middleware with logging
+	router.Use(middleware.Recovery(logger))
+
+	// Add request ID middleware
+	router.Use(middleware.RequestID())
+
+	// Add request logging middleware
+	router.Use(middleware.RequestLogger(logger))
+
+	// Compress large responses; inside the logger so it sees the bytes sent, outside the recorder
+	if cfg.Server.Gzip {
+		router.Use(middleware.Gzip())
+	}
+
+	// Record fixtures for `replay` (development only); outside ErrorHandler so rendered errors are captured
+	if cfg.Record.Dir != "" {
+		writer, err := recording.NewWriter(cfg.Record.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recording directory: %w", err)
+		}
+		router.Use(middleware.Record(writer, cfg.Record.Routes, cfg.Record.MaxBodyBytes, logger))
+		logger.Warn("Recording requests for replay", map[string]interface{}{"dir": cfg.Record.Dir, "routes": cfg.Record.Routes})
+	}
+
+	// Add CORS middleware
+	router.Use(middleware.CORSMiddleware(cfg.CORS))
+
+	// Render errors attached with c.Error as consistent JSON
+	router.Use(middleware.ErrorHandler(logger))
+
+	// Cap request bodies; parse-file and the upload routes get room for their files
+	router.Use(middleware.BodyLimit(int64(cfg.Server.MaxBodyBytes), map[string]int64{
+		"POST /api/mcp/parse-file":         int64(cfg.Server.ParseFileMaxBodyBytes),
+		"POST /api/mcp/parse-audio":        handlers.MaxAudioFormBytes,
+		"POST /api/email/inbound":          handlers.MaxInboundEmailBytes,
+		"POST /api/import/:source":         handlers.MaxImportBytes,
+		"POST /api/import/:source/preview": handlers.MaxImportBytes,
+	}))
+
+	// Enhanced health check endpoint
+	router.GET("/health", func(c *gin.Context) {
+		health := gin.H{
+			"status":    "ok",
+			"service":   "productivity-mcp-server",
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}
+
+		// Check dependencies
+		deps := gin.H{}
+		if supabaseURL != "" {
+			deps["supabase"] = "configured"
+		}
+		if claudeAPIKey != "" {
+			deps["claude"] = "configured"
+		}
+		health["dependencies"] = deps
+
+		c.JSON(http.StatusOK, health)
+	})
+
+	// Readiness check (more detailed)
+	router.GET("/ready", func(c *gin.Context) {
+		ready := true
+		checks := gin.H{}
+
+		// Check Supabase connectivity (basic check)
+		if supabaseURL == "" || supabaseKey == "" {
+			ready = false
+			checks["supabase"] = "not_configured"
+		} else {
+			checks["supabase"] = "configured"
+		}
+
+		// A shutting down instance should get no new traffic
+		if handlers.MCPDraining() {
+			ready = false
+			checks["shutdown"] = "draining"
+		}
+
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		c.JSON(status, gin.H{
+			"ready":     ready,
+			"checks":    checks,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+	})
+
+	// API description for SDK generators, and Swagger UI to browse it
+	router.GET("/openapi.json", handlers.OpenAPISpec)
+	router.GET("/openapi.yaml", handlers.OpenAPISpecYAML)
+	router.GET("/docs", handlers.SwaggerUI)
+
+	// Domain event bus shared by all handlers
+	eventBus := events.NewBus(events.DefaultHistorySize)
+	handlers.SetEventBus(eventBus)
+
+	// Latency and error objectives per route and MCP tool, alerting on fast burn
+	sloTracker := slo.NewTracker(cfg.SLO, eventBus)
+	sloTracker.SignWebhooks(cfg.Webhooks.SigningSecret)
+	router.Use(middleware.SLO(sloTracker))
+	handlers.SetSLOTracker(sloTracker)
+
+	// Per-user request quota with warning headers and events before the hard limit
+	quota := middleware.NewQuota(cfg.Quota.Requests, cfg.Quota.Window.Duration, eventBus)
+
+	// Audit log records every task, goal and OAuth client mutation
+	auditLog := handlers.NewAuditLog(supabaseURL, supabaseKey)
+	handlers.SetAuditLog(auditLog)
+
+	// Initialize handlers with dependencies
+	taskHandler := handlers.NewTaskHandler(supabaseURL, supabaseKey)
+	goalHandler := handlers.NewGoalHandler(supabaseURL, supabaseKey)
+	claudeHandler := handlers.NewClaudeHandler(supabaseURL, supabaseKey, cfg.Claude)
+	ollamaModel := llm.NewOllama(cfg.Ollama)
+	routes, err := llm.ParseRoutes(cfg.Routing.Routes, handlers.RoutableOperations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM_ROUTES: %w", err)
+	}
+	claudeHandler.SetRoutes(routes, ollamaModel)
+	claudeHandler.SetRequestLimits(cfg.Routing.AllowedModels, cfg.Routing.MaxTokensCap)
+	if cfg.Failover.Enabled {
+		claudeHandler.SetFailover(ollamaModel, cfg.Failover)
+	}
+	focusHandler := handlers.NewFocusHandler(supabaseURL, supabaseKey)
+	triggerHandler := handlers.NewTriggerHandler(supabaseURL, supabaseKey, cfg.Triggers.Tokens, cfg.Triggers.File)
+	shortcutsHandler := handlers.NewShortcutsHandler(supabaseURL, supabaseKey)
+	trashHandler := handlers.NewTrashHandler(supabaseURL, supabaseKey)
+	syncHandler := handlers.NewSyncHandler(supabaseURL, supabaseKey)
+	agendaHandler := handlers.NewAgendaHandler(supabaseURL, supabaseKey)
+	streakHandler := handlers.NewStreakHandler(supabaseURL, supabaseKey, streaks.GraceRules{
+		FreezesPerWeek: cfg.Streaks.FreezesPerWeek,
+		WeekendExempt:  cfg.Streaks.WeekendExempt,
+	})
+	importHandler := handlers.NewImportHandler(supabaseURL, supabaseKey, claudeHandler)
+	accountHandler := handlers.NewAccountHandler(supabaseURL, supabaseKey)
+	planHandler := handlers.NewPlanHandler(supabaseURL, supabaseKey, cfg.Plans)
+	handlers.SetPlans(planHandler)
+	featureFlags, err := handlers.NewFeatureFlags(cfg.Flags)
+	if err != nil {
+		return nil, err
+	}
+	featureFlags.UseSupabase(supabaseURL, supabaseKey)
+	handlers.SetFeatureFlags(featureFlags)
+	handlers.SetStrictAuth(cfg.Auth.Strict)
+	apiKeyHandler := handlers.NewAPIKeyHandler(supabaseURL, supabaseKey)
+	workspaceHandler := handlers.NewWorkspaceHandler(supabaseURL, supabaseKey)
+	adminHandler := handlers.NewAdminHandler(supabaseURL, supabaseKey)
+	undoHandler := handlers.NewUndoHandler(supabaseURL, supabaseKey)
+	preferencesHandler := handlers.NewPreferencesHandler(supabaseURL, supabaseKey)
+	memoryHandler := handlers.NewMemoryHandler(supabaseURL, supabaseKey)
+	emailHandler := handlers.NewEmailHandler(supabaseURL, supabaseKey, claudeHandler, cfg.Email)
+	ollamaHandler := handlers.NewOllamaHandler(cfg.Ollama.URL, cfg.Ollama.Model)
+
+	// Credentials users hand the server are sealed under these master keys; without one
+	// the integration endpoints answer 503
+	var keyring *secrets.Keyring
+	if len(cfg.Secrets.MasterKeys) > 0 {
+		if keyring, err = secrets.NewKeyring(cfg.Secrets.MasterKeys...); err != nil {
+			return nil, fmt.Errorf("invalid secrets master key: %w", err)
+		}
+		logger.Info("Sealing credentials", map[string]interface{}{"master_key_id": keyring.PrimaryKeyID()})
+	}
+	integrationHandler := handlers.NewIntegrationHandler(supabaseURL, supabaseKey, integrations.NewRegistry(
+		handlers.NewNotionIntegration(supabaseURL, supabaseKey, cfg.Notion),
+	), keyring)
+
+	// X-API-Key authentication for scripts and server-to-server clients
+	middleware.SetAPIKeyStore(apiKeyHandler)
+
+	// jti denylist so signed-out or compromised access tokens stop working before expiry
+	tokenRevocations := handlers.NewTokenRevocations(supabaseURL, supabaseKey)
+	handlers.SetTokenRevocations(tokenRevocations)
+	middleware.SetTokenDenylist(tokenRevocations)
+
+	// OAuth clients registered by `setup` or POST /oauth/register survive restarts
+	handlers.SetOAuthClientStore(supabaseURL, supabaseKey)
+	handlers.SetPlainPKCEClients(cfg.Auth.PlainPKCEClients)
+	handlers.SetClientCredentials(cfg.Auth.ClientCredentials)
+	// Scopes approved on the consent screen are remembered per user and client
+	handlers.SetGrantStore(supabaseURL, supabaseKey)
+
+	// Natural language dates resolve in each user's time zone and calendar
+	handlers.SetPreferencesStore(supabaseURL, supabaseKey)
+
+	// What the assistant remembers about users carries across MCP sessions and informs parse-task
+	handlers.SetMemoryStore(supabaseURL, supabaseKey)
+
+	// Semantic search, related tasks and duplicate detection embed task text with this model
+	embedder, err := embeddings.New(cfg.Embeddings, cfg.Ollama.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embeddings configuration: %w", err)
+	}
+	handlers.SetEmbedder(embedder)
+
+	// parse-audio transcribes voice memos with this model; without one it answers 503
+	transcriber, err := transcription.New(cfg.Transcription)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transcription configuration: %w", err)
+	}
+	handlers.SetTranscriber(transcriber)
+
+	// Prompt templates can be overridden per deployment and reloaded from /admin
+	promptTemplates, err := prompts.Load(cfg.Claude.PromptsDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt templates: %w", err)
+	}
+	handlers.SetPrompts(promptTemplates)
+
+	// Issued access tokens are recorded so /admin can list and revoke sessions
+	handlers.SetSessionStore(supabaseURL, supabaseKey)
+
+	// MCP clients get an Mcp-Session-Id from /mcp/initialize, expired after sitting idle
+	mcpSessions := mcpsession.NewStore(cfg.MCP.SessionIdleTimeout.Duration)
+	handlers.SetMCPSessions(mcpSessions)
+	// Sessions are saved on shutdown so clients keep them across a redeploy
+	handlers.SetMCPSessionPersistence(supabaseURL, supabaseKey)
+
+	// Expired auth codes, sessions, revoked tokens and old audit entries are purged periodically
+	janitor := handlers.NewJanitor(supabaseURL, supabaseKey, mcpSessions, cfg.Janitor)
+
+	// Background workers stop when ctx is cancelled
+	go trashHandler.RunPurge(ctx, logger, 24*time.Hour)
+	go sloTracker.Run(ctx, 30*time.Second)
+	go tokenRevocations.RunPurge(ctx, time.Hour)
+	go janitor.Run(ctx, logger, cfg.Janitor.Interval.Duration)
+	go integrationHandler.RunSync(ctx, logger, time.Minute)
+
+	// Optional external message bus (NATS or Kafka) receiving every domain event
+	publisher, err := events.NewPublisher(cfg.Events)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event publisher configuration: %w", err)
+	}
+	if publisher != nil {
+		logger.Info("Forwarding domain events", map[string]interface{}{"publisher": cfg.Events.Publisher})
+		go events.Forward(ctx, eventBus, publisher, logger, 0)
+	}
+
+	// API routes accept optional bearer or API key auth and share the request
+	// quota; in strict mode they refuse requests naming another user
+	api := router.Group("/api")
+	api.Use(middleware.OptionalAuthMiddleware(), handlers.RequireOwnUser(), quota.Middleware())
+
+	// Task routes
+	tasks := api.Group("/tasks")
+	{
+		tasks.POST("", taskHandler.CreateTask)
+		tasks.GET("", taskHandler.ListTasks)
+		tasks.GET("/overdue", taskHandler.OverdueTasks)
+		tasks.GET("/today", taskHandler.TodayTasks)
+		tasks.GET("/upcoming", taskHandler.UpcomingTasks)
+		tasks.POST("/search", handlers.RequireFlag(flags.SemanticSearch), taskHandler.SearchTasks)
+		tasks.POST("/duplicates", handlers.RequireFlag(flags.SemanticSearch), taskHandler.DuplicateTasks)
+		tasks.GET("/context/:context", taskHandler.ContextTasks)
+		tasks.GET("/:id", taskHandler.GetTask)
+		tasks.GET("/:id/related", handlers.RequireFlag(flags.SemanticSearch), taskHandler.RelatedTasks)
+		tasks.POST("/:id/snooze", taskHandler.SnoozeTask)
+		tasks.GET("/:id/reschedules", taskHandler.TaskReschedules)
+		tasks.PUT("/:id", taskHandler.UpdateTask)
+		tasks.DELETE("/:id", taskHandler.DeleteTask)
+		tasks.GET("/user/:userId", taskHandler.GetUserTasks)
+	}
+
+	// Goal routes
+	goals := api.Group("/goals")
+	{
+		goals.POST("", goalHandler.CreateGoal)
+		goals.GET("", goalHandler.ListGoals)
+		goals.GET("/:id", goalHandler.GetGoal)
+		goals.PUT("/:id", goalHandler.UpdateGoal)
+		goals.DELETE("/:id", goalHandler.DeleteGoal)
+		goals.GET("/:id/milestones", goalHandler.ListMilestones)
+		goals.POST("/:id/milestones", goalHandler.CreateMilestone)
+		goals.PUT("/:id/milestones/:milestone_id", goalHandler.UpdateMilestone)
+		goals.DELETE("/:id/milestones/:milestone_id", goalHandler.DeleteMilestone)
+		goals.GET("/user/:userId", goalHandler.GetUserGoals)
+	}
+
+	// Workspace routes (shared tasks and goals; roles are owner, editor and viewer)
+	workspaces := api.Group("/workspaces")
+	{
+		workspaces.POST("", workspaceHandler.CreateWorkspace)
+		workspaces.GET("", workspaceHandler.ListWorkspaces)
+		workspaces.GET("/:id", workspaceHandler.GetWorkspace)
+		workspaces.DELETE("/:id", workspaceHandler.DeleteWorkspace)
+		workspaces.POST("/:id/invites", workspaceHandler.CreateInvite)
+		workspaces.PUT("/:id/members/:user_id", workspaceHandler.UpdateMember)
+		workspaces.DELETE("/:id/members/:user_id", workspaceHandler.RemoveMember)
+	}
+	api.POST("/invites/accept", workspaceHandler.AcceptInvite)
+
+	// Habit streak routes
+	streakRoutes := api.Group("/streaks")
+	{
+		streakRoutes.GET("", streakHandler.GetStreaks)
+		streakRoutes.GET("/rules", streakHandler.GetRules)
+		streakRoutes.PUT("/rules", streakHandler.UpdateRules)
+	}
+	api.GET("/stats/streaks", streakHandler.GetCompletionStats)
+
+	// Import routes (Habitica and Streaks exports, spreadsheets and Markdown checklists)
+	imports := api.Group("/import")
+	{
+		imports.POST("/:source/preview", importHandler.Preview)
+		imports.POST("/:source", importHandler.Import)
+	}
+	api.GET("/export/markdown", importHandler.ExportMarkdown)
+
+	// API key management (keys are shown once, stored hashed)
+	apiKeys := api.Group("/apikeys")
+	{
+		apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+		apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+		apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+	}
+
+	// The signed-in user and the feature flags they get
+	api.GET("/me", handlers.Me)
+
+	// The OAuth clients the user approved on the consent screen
+	api.GET("/authorizations", handlers.ListAuthorizations)
+	api.DELETE("/authorizations/:client_id", handlers.RevokeAuthorization)
+
+	// The caller's plan, its limits and usage
+	api.GET("/plan", planHandler.GetPlan)
+
+	// Account export and deletion (signed-in user only; deletion takes a confirmation token)
+	account := api.Group("/account")
+	{
+		account.GET("/export", accountHandler.ExportAccount)
+		account.DELETE("", accountHandler.DeleteAccount)
+	}
+
+	// Audit log for the requesting user
+	api.GET("/audit", auditLog.ListAudit)
+
+	// Undo an action from the audit log
+	api.POST("/actions/:id/undo", undoHandler.UndoAction)
+
+	// Admin routes (authenticated user must be listed in ADMIN_USER_IDS or hold an `admintoken` token)
+	admin := router.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminOnly(cfg.Auth.AdminUserIDs))
+	{
+		admin.GET("/audit", auditLog.ListAllAudit)
+		admin.GET("/stats", handlers.AdminStats)
+		admin.GET("/metrics", adminHandler.Metrics)
+		admin.GET("/janitor", janitor.GetStats)
+		admin.POST("/janitor/run", janitor.RunNow)
+		admin.GET("/users", adminHandler.ListUsers)
+		admin.POST("/users/:user_id/revoke", adminHandler.RevokeUserSessions)
+		admin.PUT("/users/:user_id/plan", planHandler.SetUserPlan)
+		admin.GET("/flags", featureFlags.ListFlags)
+		admin.PUT("/flags/:name", featureFlags.SetFlag)
+		admin.DELETE("/flags/:name", featureFlags.DeleteFlag)
+		admin.GET("/clients", adminHandler.ListClients)
+		admin.GET("/clients/:client_id", adminHandler.GetClient)
+		admin.GET("/sessions", adminHandler.ListSessions)
+		admin.POST("/sessions/:jti/revoke", adminHandler.RevokeSession)
+		admin.GET("/prompts", handlers.ListPrompts)
+		admin.POST("/prompts/reload", handlers.ReloadPrompts)
+		// Demo data overwrites the demo users' accounts, so it is for development only
+		if !cfg.Server.Release() {
+			admin.POST("/seed", adminHandler.Seed)
+		}
+	}
+
+	// Replayable domain event log for external consumers
+	api.GET("/events", handlers.ListEvents)
+
+	// Agenda rendering for terminals and Markdown viewers
+	api.GET("/agenda/today", handlers.RequireFlag(flags.DailyPlanning), agendaHandler.Today)
+	api.GET("/capacity", handlers.RequireFlag(flags.DailyPlanning), taskHandler.Capacity)
+
+	// Trash routes (soft-deleted tasks and goals)
+	trash := api.Group("/trash")
+	{
+		trash.GET("", trashHandler.ListTrash)
+		trash.POST("/tasks/:id/restore", trashHandler.RestoreTask)
+		trash.POST("/goals/:id/restore", trashHandler.RestoreGoal)
+	}
+
+	// Incremental sync of tasks and goals for offline clients
+	api.GET("/sync", syncHandler.Sync)
+	api.POST("/sync", syncHandler.Upload)
+
+	// Focus session routes
+	focus := api.Group("/focus")
+	{
+		focus.POST("", focusHandler.StartFocus)
+		focus.GET("", focusHandler.GetFocus)
+		focus.DELETE("", focusHandler.StopFocus)
+		focus.GET("/contract", focusHandler.GetContract)
+		focus.PUT("/contract", focusHandler.UpdateContract)
+	}
+
+	// Trigger routes (static token auth for automations and physical buttons)
+	triggers := api.Group("/triggers")
+	{
+		triggers.GET("", triggerHandler.ListTriggers)
+		triggers.POST("/:name", triggerHandler.FireTrigger)
+	}
+
+	// Email ingestion (the inbound webhook authenticates with EMAIL_WEBHOOK_SECRET in ?key=)
+	email := api.Group("/email")
+	{
+		email.POST("/inbound", emailHandler.Inbound)
+		email.GET("/address", emailHandler.GetAddress)
+		email.POST("/address/rotate", emailHandler.RotateAddress)
+	}
+
+	// Integrations with outside services (credentials sealed under SECRETS_MASTER_KEYS;
+	// webhooks are authenticated by their provider)
+	integrationRoutes := api.Group("/integrations")
+	{
+		integrationRoutes.GET("", integrationHandler.ListConnections)
+		integrationRoutes.GET("/:provider", integrationHandler.GetConnection)
+		integrationRoutes.PUT("/:provider", integrationHandler.Connect)
+		integrationRoutes.DELETE("/:provider", integrationHandler.Disconnect)
+		integrationRoutes.POST("/:provider/sync", integrationHandler.SyncNow)
+		integrationRoutes.POST("/:provider/webhook", integrationHandler.Webhook)
+	}
+
+	// Apple Shortcuts compact routes (form fields in, flat JSON out)
+	shortcuts := api.Group("/shortcuts")
+	{
+		shortcuts.POST("/add", shortcutsHandler.QuickAdd)
+		shortcuts.GET("/today", shortcutsHandler.Today)
+		shortcuts.POST("/complete", shortcutsHandler.CompleteByTitle)
+	}
+
+	// Per-user time zone, locale, week start and working hours
+	preferences := api.Group("/preferences")
+	{
+		preferences.GET("", preferencesHandler.GetPreferences)
+		preferences.PUT("", preferencesHandler.UpdatePreferences)
+		preferences.DELETE("", preferencesHandler.DeletePreferences)
+	}
+
+	// What the assistant remembers about the user, also available as MCP tools
+	memory := api.Group("/memory")
+	{
+		memory.GET("", memoryHandler.ListMemory)
+		memory.PUT("/:key", memoryHandler.SetMemory)
+		memory.DELETE("/:key", memoryHandler.DeleteMemory)
+	}
+
+	// Natural language dates, resolved without Claude
+	api.POST("/dates/parse", handlers.ParseDate)
+
+	// Claude/MCP routes
+	mcp := api.Group("/mcp", claudeHandler.ModelOverride(), planHandler.AICalls())
+	{
+		mcp.POST("/parse-task", claudeHandler.ParseTask)
+		mcp.POST("/refine-task", claudeHandler.RefineTask)
+		mcp.POST("/parse-file", claudeHandler.ParseFile)
+		mcp.POST("/parse-audio", claudeHandler.ParseAudio)
+		mcp.POST("/generate-subtasks", claudeHandler.GenerateSubtasks)
+		mcp.POST("/suggest-milestones", claudeHandler.SuggestMilestones)
+		mcp.POST("/analyze-productivity", claudeHandler.AnalyzeProductivity)
+		mcp.POST("/eisenhower-matrix", claudeHandler.EisenhowerMatrix)
+	}
+
+	// Local model routes
+	ollama := api.Group("/ollama")
+	{
+		ollama.POST("/generate", ollamaHandler.Generate)
+	}
+
+	// OAuth 2.1 endpoints for MCP authentication
+	// Register OAuth routes BEFORE MCP routes to ensure they're matched first
+	// #region agent log
+	logger.Info("Registering OAuth routes", map[string]interface{}{
+		"routes": []string{"/.well-known/oauth-authorization-server", "/authorize", "/oauth/authorize", "/oauth/token"},
+	})
+	// #endregion
+
+	// OAuth 2.1 discovery endpoint (RFC 8414) - must be exact path match
+	router.GET("/.well-known/oauth-authorization-server", handlers.OAuthDiscovery)
+	router.GET("/.well-known/jwks.json", handlers.JWKS)
+	router.GET("/.well-known/oauth-protected-resource", handlers.OAuthProtectedResource)
+
+	// OAuth authorization endpoints - support both patterns. A signed-in
+	// caller approves as themselves on the consent screen.
+	router.GET("/authorize", middleware.OptionalAuthMiddleware(), handlers.OAuthAuthorize)
+	router.GET("/oauth/authorize", middleware.OptionalAuthMiddleware(), handlers.OAuthAuthorize)
+	router.POST("/authorize/consent", middleware.OptionalAuthMiddleware(), handlers.OAuthConsent)
+	router.POST("/oauth/authorize/consent", middleware.OptionalAuthMiddleware(), handlers.OAuthConsent)
+
+	// OAuth token and management endpoints
+	router.POST("/oauth/token", handlers.OAuthToken)
+	router.POST("/oauth/introspect", handlers.OAuthIntrospect)
+	router.POST("/oauth/logout", handlers.OAuthLogout)
+	router.POST("/oauth/register", handlers.OAuthRegister) // Dynamic client registration (RFC 7591)
+	router.GET("/oauth/register/:client_id", handlers.OAuthGetClient)
+	router.PUT("/oauth/register/:client_id", handlers.OAuthUpdateClient)
+	router.DELETE("/oauth/register/:client_id", handlers.OAuthDeleteClient)
+
+	logger.Info("OAuth routes registered successfully")
+
+	// MCP Protocol routes (protected with authentication)
+	mcpHandler := handlers.NewMCPHandler(taskHandler, goalHandler, claudeHandler, undoHandler)
+	mcpHandler.SetRequireDryRun(cfg.MCP.RequireDryRun)
+	ollamaHandler.RegisterTools(mcpHandler.Tools())
+	importHandler.RegisterTools(mcpHandler.Tools())
+	mcpGroup := router.Group("/mcp")
+	mcpGroup.Use(middleware.AuthMiddleware(), handlers.RequireOwnUser(), quota.Middleware(), middleware.MCPSession(mcpSessions)) // Require authentication for MCP endpoints
+	{
+		mcpGroup.POST("/initialize", handlers.MCPInitialize)
+		mcpGroup.POST("/call_tool", mcpHandler.MCPCallTool)
+		mcpGroup.POST("/list_tools", mcpHandler.MCPListTools)
+		mcpGroup.DELETE("/session", handlers.MCPEndSession)
+	}
+
+	// 404 handler for debugging - log all unmatched routes
+	router.NoRoute(func(c *gin.Context) {
+		logger.Warn("Route not found",
+			map[string]interface{}{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"query":  c.Request.URL.RawQuery,
+			},
+		)
+		c.Error(utils.NewAppError(utils.ErrCodeNotFound,
+			fmt.Sprintf("Route %s %s not found", c.Request.Method, c.Request.URL.Path),
+			http.StatusNotFound,
+		).WithField("path", c.Request.URL.Path))
+	})
+
+	return router, nil
+}
@@ -0,0 +1,78 @@
+#!/usr/bin/env bash
+# Load tests the hot endpoints (GET /api/tasks, MCP tools/call and parse-task)
+# with k6 against a local server whose Supabase and Claude are the in-memory
+# fakes, so only the server's own latency is measured. Fails when a latency
+# budget in scripts/loadtest/k6.js is missed.
+#
+#   scripts/load-test.sh                      # 50 requests/s per endpoint for 30s
+#   RATE=200 DURATION=2m scripts/load-test.sh
+#
+# Runs k6 from PATH, or the grafana/k6 image when only Docker is available.
+# LOAD_TEST_PORT moves the server (default 18080) and the fakes, which listen
+# on the next two ports.
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+PORT="${LOAD_TEST_PORT:-18080}"
+SUPABASE_ADDR="localhost:$((PORT + 1))"
+LLM_ADDR="localhost:$((PORT + 2))"
+K6_IMAGE="${K6_IMAGE:-grafana/k6:0.54.0}"
+WORK="$(mktemp -d)"
+BIN="$WORK/productivity-mcp"
+
+pids=()
+cleanup() {
+  if [ "${#pids[@]}" -gt 0 ]; then
+    kill "${pids[@]}" 2>/dev/null || true
+    wait "${pids[@]}" 2>/dev/null || true
+  fi
+  rm -rf "$WORK"
+}
+trap cleanup EXIT
+
+k6() {
+  if command -v k6 >/dev/null 2>&1; then
+    command k6 "$@"
+  elif command -v docker >/dev/null 2>&1; then
+    docker run --rm -i --network host -v "$PWD/scripts/loadtest:/scripts:ro" "$K6_IMAGE" "$@"
+  else
+    echo "load-test: needs k6 or docker" >&2
+    exit 1
+  fi
+}
+
+go build -o "$BIN" .
+
+export PORT GIN_MODE=release LOG_LEVEL=error
+export SUPABASE_URL="http://$SUPABASE_ADDR" SUPABASE_ANON_KEY=mock SUPABASE_SERVICE_ROLE_KEY=mock
+export CLAUDE_BASE_URL="http://$LLM_ADDR" CLAUDE_API_KEY=mock OLLAMA_URL="http://$LLM_ADDR"
+export JWT_SECRET="load-test-$(date +%s)"
+
+"$BIN" mocksupabase -addr "$SUPABASE_ADDR" >"$WORK/mocksupabase.log" 2>&1 &
+pids+=($!)
+"$BIN" mockllm -addr "$LLM_ADDR" >"$WORK/mockllm.log" 2>&1 &
+pids+=($!)
+"$BIN" >"$WORK/server.log" 2>&1 &
+pids+=($!)
+
+for _ in $(seq 50); do
+  curl -sf "http://localhost:$PORT/health" >/dev/null && break
+  sleep 0.2
+done
+if ! curl -sf "http://localhost:$PORT/health" >/dev/null; then
+  echo "load-test: server did not start:" >&2
+  cat "$WORK/server.log" >&2
+  exit 1
+fi
+
+TOKEN="$("$BIN" servicetoken -sub loadtest -ttl 1h)"
+
+SCRIPT=scripts/loadtest/k6.js
+if ! command -v k6 >/dev/null 2>&1; then
+  SCRIPT=/scripts/k6.js
+fi
+k6 run \
+  -e BASE_URL="http://localhost:$PORT" -e TOKEN="$TOKEN" -e USER_ID=loadtest \
+  -e RATE="${RATE:-50}" -e DURATION="${DURATION:-30s}" \
+  "$SCRIPT"
@@ -0,0 +1,90 @@
+// Load test of the hot endpoints: listing tasks, an MCP tool call and
+// parse-task. Run it with scripts/load-test.sh (make load-test), which starts
+// the server on fake upstreams and passes BASE_URL and TOKEN. The thresholds
+// are the latency budgets in the README's Performance section; a run that
+// misses one exits non-zero.
+import http from 'k6/http';
+import { check } from 'k6';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:8080';
+const TOKEN = __ENV.TOKEN;
+const USER_ID = __ENV.USER_ID || 'loadtest';
+const RATE = parseInt(__ENV.RATE || '50', 10); // requests per second per endpoint
+const DURATION = __ENV.DURATION || '30s';
+const TASKS = 100; // tasks the user has, as in the Go benchmarks
+
+// Each endpoint gets its own constant arrival rate, so a slow one does not
+// throttle the others
+function scenario(exec) {
+  return {
+    executor: 'constant-arrival-rate',
+    exec,
+    rate: RATE,
+    timeUnit: '1s',
+    duration: DURATION,
+    preAllocatedVUs: 20,
+    maxVUs: 200,
+  };
+}
+
+export const options = {
+  scenarios: {
+    list_tasks: scenario('listTasks'),
+    mcp_call_tool: scenario('mcpCallTool'),
+    parse_task: scenario('parseTask'),
+  },
+  thresholds: {
+    'http_req_failed{scenario:list_tasks}': ['rate<0.01'],
+    'http_req_failed{scenario:mcp_call_tool}': ['rate<0.01'],
+    'http_req_failed{scenario:parse_task}': ['rate<0.01'],
+    'http_req_duration{scenario:list_tasks}': ['p(95)<25', 'p(99)<50'],
+    'http_req_duration{scenario:mcp_call_tool}': ['p(95)<50', 'p(99)<100'],
+    'http_req_duration{scenario:parse_task}': ['p(95)<25', 'p(99)<50'],
+  },
+};
+
+function params(extraHeaders) {
+  return {
+    headers: Object.assign({ Authorization: `Bearer ${TOKEN}`, 'Content-Type': 'application/json' }, extraHeaders),
+  };
+}
+
+export function setup() {
+  if (!TOKEN) {
+    throw new Error('TOKEN is required: a bearer token for USER_ID, e.g. from `servicetoken -sub loadtest`');
+  }
+  const due = Date.now() + 2 * 60 * 60 * 1000;
+  for (let i = 0; i < TASKS; i++) {
+    const res = http.post(`${BASE_URL}/api/tasks`, JSON.stringify({
+      title: `Load test task ${i}`,
+      priority: 1 + (i % 5),
+      due_date: new Date(due + i * 60 * 1000).toISOString(),
+    }), params());
+    if (res.status !== 201) {
+      throw new Error(`seeding tasks: ${res.status} ${res.body}`);
+    }
+  }
+}
+
+export function listTasks() {
+  const res = http.get(`${BASE_URL}/api/tasks`, params());
+  check(res, { 'list tasks 200': (r) => r.status === 200 });
+}
+
+export function mcpCallTool() {
+  const res = http.post(`${BASE_URL}/mcp/call_tool`, JSON.stringify({
+    jsonrpc: '2.0',
+    id: 1,
+    method: 'get_today_tasks',
+    params: {},
+  }), params());
+  check(res, { 'call tool 200': (r) => r.status === 200 && !r.json('error') });
+}
+
+export function parseTask() {
+  const res = http.post(`${BASE_URL}/api/mcp/parse-task`, JSON.stringify({
+    input: 'urgent: send the client report tomorrow at 5pm',
+    user_id: USER_ID,
+  }), params());
+  check(res, { 'parse task 200': (r) => r.status === 200 });
+}
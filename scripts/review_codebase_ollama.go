@@ -18,11 +18,19 @@ import (
 	"time"
 )
 
+// envOr returns the environment variable key, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
 func main() {
 	var (
 		basePath     = flag.String("path", ".", "Base path to review")
-		ollamaURL    = flag.String("ollama-url", "http://100.74.59.83:11434", "Ollama server URL")
-		modelName    = flag.String("model", "qwen3-coder:480b-cloud", "Ollama model to use")
+		ollamaURL    = flag.String("ollama-url", envOr("OLLAMA_URL", "http://localhost:11434"), "Ollama server URL (default: $OLLAMA_URL)")
+		modelName    = flag.String("model", envOr("OLLAMA_MODEL", "qwen3-coder:480b-cloud"), "Ollama model to use (default: $OLLAMA_MODEL)")
 		filePatterns = flag.String("patterns", "*.go,*.ts,*.tsx,*.swift,*.js,*.jsx", "Comma-separated file patterns")
 		excludeDirs  = flag.String("exclude", "node_modules,.git,vendor,build,dist,.next,ios_agentic_app/.build", "Comma-separated directories to exclude")
 		focusAreas   = flag.String("focus", "architecture,security,performance,best-practices", "Comma-separated focus areas")
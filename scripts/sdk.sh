@@ -0,0 +1,91 @@
+#!/usr/bin/env bash
+# Generates typed TypeScript and Swift clients for the task, goal, AI and MCP
+# endpoints from openapi/openapi.yaml, and publishes them.
+#
+#   scripts/sdk.sh generate   # writes sdk/typescript and sdk/swift
+#   scripts/sdk.sh publish    # generates, then publishes both
+#
+# Runs openapi-generator in Docker, or the openapi-generator-cli on PATH when
+# Docker is not available. Publishing needs NPM_TOKEN for the TypeScript
+# package and SWIFT_SDK_REPO, a git URL the Swift package is pushed and
+# tagged to. SDK_VERSION overrides the version, which is otherwise the spec's
+# info.version.
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+SPEC=openapi/openapi.yaml
+OUT=sdk
+TAGS="tasks|goals|ai|mcp"
+GENERATOR_IMAGE="${OPENAPI_GENERATOR_IMAGE:-openapitools/openapi-generator-cli:v7.10.0}"
+NPM_PACKAGE="${SDK_NPM_PACKAGE:-@productivity/mcp-client}"
+SWIFT_PACKAGE="${SDK_SWIFT_PACKAGE:-ProductivityClient}"
+VERSION="${SDK_VERSION:-$(awk '/^  version:/ {print $2; exit}' "$SPEC")}"
+
+generator() {
+  if command -v docker >/dev/null 2>&1; then
+    docker run --rm -u "$(id -u):$(id -g)" -v "$PWD:/local" -w /local "$GENERATOR_IMAGE" "$@"
+  elif command -v openapi-generator-cli >/dev/null 2>&1; then
+    openapi-generator-cli "$@"
+  else
+    echo "sdk: needs docker or openapi-generator-cli" >&2
+    exit 1
+  fi
+}
+
+generate() {
+  rm -rf "$OUT/typescript" "$OUT/swift"
+
+  # Only the tagged operations; the admin and integration routes stay out of the clients
+  generator generate -i "$SPEC" -g typescript-fetch -o "$OUT/typescript" \
+    --openapi-normalizer "FILTER=tag:$TAGS" \
+    --additional-properties "npmName=$NPM_PACKAGE,npmVersion=$VERSION,supportsES6=true,withInterfaces=true"
+  generator generate -i "$SPEC" -g swift5 -o "$OUT/swift" \
+    --openapi-normalizer "FILTER=tag:$TAGS" \
+    --additional-properties "projectName=$SWIFT_PACKAGE,podVersion=$VERSION,responseAs=AsyncAwait,useSPMFileStructure=true,swiftPackagePath=Sources/$SWIFT_PACKAGE"
+
+  (cd "$OUT/typescript" && npm install --no-audit --no-fund && npm run build)
+  echo "sdk: generated $VERSION in $OUT/typescript and $OUT/swift"
+}
+
+publish_npm() {
+  : "${NPM_TOKEN:?NPM_TOKEN is required to publish $NPM_PACKAGE}"
+  local npmrc="$WORK/npmrc"
+  echo "//registry.npmjs.org/:_authToken=$NPM_TOKEN" >"$npmrc"
+  (cd "$OUT/typescript" && NPM_CONFIG_USERCONFIG="$npmrc" npm publish --access public)
+}
+
+publish_swift() {
+  : "${SWIFT_SDK_REPO:?SWIFT_SDK_REPO is required to publish $SWIFT_PACKAGE}"
+  local checkout="$WORK/swift"
+  git clone --quiet "$SWIFT_SDK_REPO" "$checkout"
+  # Swift packages are versioned by git tags; a tag that exists is not moved
+  if git -C "$checkout" rev-parse -q --verify "refs/tags/$VERSION" >/dev/null; then
+    echo "sdk: $SWIFT_SDK_REPO already has $VERSION; bump info.version or set SDK_VERSION" >&2
+    exit 1
+  fi
+  find "$checkout" -mindepth 1 -maxdepth 1 ! -name .git -exec rm -rf {} +
+  cp -R "$OUT/swift/." "$checkout/"
+  git -C "$checkout" add -A
+  git -C "$checkout" commit --quiet -m "$SWIFT_PACKAGE $VERSION"
+  git -C "$checkout" tag "$VERSION"
+  git -C "$checkout" push --quiet origin HEAD "refs/tags/$VERSION"
+}
+
+case "${1:-}" in
+generate)
+  generate
+  ;;
+publish)
+  WORK="$(mktemp -d)"
+  trap 'rm -rf "$WORK"' EXIT
+  generate
+  publish_npm
+  publish_swift
+  echo "sdk: published $NPM_PACKAGE and $SWIFT_PACKAGE $VERSION"
+  ;;
+*)
+  echo "usage: $0 generate|publish" >&2
+  exit 2
+  ;;
+esac
@@ -0,0 +1,168 @@
+// Package secrets encrypts values at rest with envelope encryption, the
+// scheme cloud KMSs use: every value is encrypted with its own random data
+// key, and only that data key is encrypted ("wrapped") with a master key.
+// Master keys come from configuration; rotating one only rewraps the small
+// data keys, never the values themselves.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// keySize is the size of master and data keys: AES-256
+const keySize = 32
+
+// ErrUnknownKey means a value was sealed under a master key the keyring no longer holds
+var ErrUnknownKey = errors.New("sealed under a master key that is not configured")
+
+// Envelope is a sealed value as it is stored
+type Envelope struct {
+	KeyID      string // the master key that wrapped the data key
+	WrappedKey []byte // the data key, encrypted with the master key
+	Ciphertext []byte // the value, encrypted with the data key
+}
+
+// Keyring holds the master keys. The first seals new values; the rest
+// only open values sealed before they were rotated out.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring from base64-encoded 32-byte master keys,
+// the primary first
+func NewKeyring(masterKeys ...string) (*Keyring, error) {
+	if len(masterKeys) == 0 {
+		return nil, errors.New("no master key")
+	}
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for i, encoded := range masterKeys {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != keySize {
+			return nil, fmt.Errorf("master key %d must be %d bytes, base64-encoded", i+1, keySize)
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, err
+		}
+		id := KeyID(raw)
+		if i == 0 {
+			k.primary = id
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// KeyID names a master key without revealing it: the first eight bytes of
+// its SHA-256, in hex
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// PrimaryKeyID names the master key new values are sealed under
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// Seal encrypts plaintext under a new data key. aad binds the value to
+// where it is stored, e.g. its owner, so it does not open anywhere else.
+func (k *Keyring) Seal(plaintext, aad []byte) (Envelope, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return Envelope{}, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+	ciphertext, err := seal(aead, plaintext, aad)
+	if err != nil {
+		return Envelope{}, err
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{KeyID: k.primary, WrappedKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// Open decrypts a sealed value with the aad it was sealed with
+func (k *Keyring) Open(env Envelope, aad []byte) ([]byte, error) {
+	dataKey, err := k.unwrap(env)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, env.Ciphertext, aad)
+	if err != nil {
+		return nil, errors.New("sealed value does not open; it was altered or moved")
+	}
+	return plaintext, nil
+}
+
+// Rewrap wraps an envelope's data key with the primary master key, leaving
+// the value's ciphertext as it is. Envelopes already under it are returned
+// unchanged.
+func (k *Keyring) Rewrap(env Envelope) (Envelope, error) {
+	if env.KeyID == k.primary {
+		return env, nil
+	}
+	dataKey, err := k.unwrap(env)
+	if err != nil {
+		return Envelope{}, err
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{KeyID: k.primary, WrappedKey: wrapped, Ciphertext: env.Ciphertext}, nil
+}
+
+// unwrap decrypts an envelope's data key
+func (k *Keyring) unwrap(env Envelope) ([]byte, error) {
+	master, ok := k.keys[env.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %s: %w", env.KeyID, ErrUnknownKey)
+	}
+	dataKey, err := open(master, env.WrappedKey, []byte(env.KeyID))
+	if err != nil || len(dataKey) != keySize {
+		return nil, errors.New("data key does not unwrap")
+	}
+	return dataKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with a random nonce, which it prepends to the ciphertext
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+}
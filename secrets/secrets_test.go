@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+const (
+	oldKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	newKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func TestSealAndOpen(t *testing.T) {
+	keyring, err := NewKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, aad := []byte("secret_abc"), []byte("u1/integration:notion")
+	env, err := keyring.Seal(secret, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.KeyID != keyring.PrimaryKeyID() || bytes.Contains(env.Ciphertext, secret) {
+		t.Fatalf("envelope %+v", env)
+	}
+	again, _ := keyring.Seal(secret, aad)
+	if bytes.Equal(again.WrappedKey, env.WrappedKey) || bytes.Equal(again.Ciphertext, env.Ciphertext) {
+		t.Error("two seals shared a data key or nonce")
+	}
+
+	if got, err := keyring.Open(env, aad); err != nil || !bytes.Equal(got, secret) {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+	if _, err := keyring.Open(env, []byte("u2/integration:notion")); err == nil {
+		t.Error("opened with another owner's aad")
+	}
+	tampered := env
+	tampered.Ciphertext = append([]byte(nil), env.Ciphertext...)
+	tampered.Ciphertext[len(tampered.Ciphertext)-1] ^= 1
+	if _, err := keyring.Open(tampered, aad); err == nil {
+		t.Error("opened an altered ciphertext")
+	}
+
+	for _, bad := range [][]string{nil, {""}, {"c2hvcnQ="}, {oldKey, "not base64!"}} {
+		if _, err := NewKeyring(bad...); err == nil {
+			t.Errorf("NewKeyring(%q) accepted", bad)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	before, _ := NewKeyring(oldKey)
+	aad := []byte("u1/x")
+	env, err := before.Seal([]byte("value"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotating, _ := NewKeyring(newKey, oldKey)
+	if got, err := rotating.Open(env, aad); err != nil || string(got) != "value" {
+		t.Fatalf("old value under the rotating keyring: %q, %v", got, err)
+	}
+	rewrapped, err := rotating.Rewrap(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewrapped.KeyID != rotating.PrimaryKeyID() || !bytes.Equal(rewrapped.Ciphertext, env.Ciphertext) {
+		t.Fatalf("rewrapped %+v", rewrapped)
+	}
+
+	after, _ := NewKeyring(newKey)
+	if got, err := after.Open(rewrapped, aad); err != nil || string(got) != "value" {
+		t.Fatalf("rewrapped value once the old key is gone: %q, %v", got, err)
+	}
+	if _, err := after.Open(env, aad); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package seed
+
+// profile is a demo user: where they live, their working hours and what
+// their days are filled with
+type profile struct {
+	name       string
+	timezone   string
+	locale     string
+	weekStart  string
+	workStart  int // hour of the day
+	workEnd    int
+	categories []string // drawn from for one-off tasks; repeats weigh a category
+	habits     []habit
+	goals      []goalTemplate
+}
+
+// habit is a recurring task the user mostly keeps up
+type habit struct {
+	title     string
+	category  string
+	frequency string
+	minutes   int
+}
+
+type goalTemplate struct {
+	title       string
+	description string
+	milestones  []string
+}
+
+type taskTemplate struct {
+	title   string
+	minutes int
+	context string
+}
+
+// profiles are the demo users, in the order they are created
+var profiles = []profile{
+	{
+		name: "alex", timezone: "America/New_York", locale: "en-US", weekStart: "sunday", workStart: 9, workEnd: 17,
+		categories: []string{"work", "work", "work", "health", "learning", "personal"},
+		habits: []habit{
+			{"Morning run", "health", "daily", 30},
+			{"Weekly review", "work", "weekly", 45},
+		},
+		goals: []goalTemplate{
+			{"Ship the mobile app beta", "Get the beta into TestFlight with onboarding and sync working",
+				[]string{"Finish onboarding screens", "Offline sync", "Internal dogfood", "TestFlight release"}},
+			{"Run a half marathon", "Build up to 21km without injury",
+				[]string{"Run 10km", "Run 15km", "Run 18km", "Race day"}},
+		},
+	},
+	{
+		name: "sam", timezone: "Europe/London", locale: "en-GB", weekStart: "monday", workStart: 8, workEnd: 16,
+		categories: []string{"work", "work", "personal", "shopping", "health", "finance"},
+		habits: []habit{
+			{"Sketch for 20 minutes", "learning", "daily", 20},
+			{"Plan the week's meals", "personal", "weekly", 30},
+		},
+		goals: []goalTemplate{
+			{"Launch the portfolio site", "A new portfolio with five case studies",
+				[]string{"Pick the case studies", "Write the copy", "Design the pages", "Go live"}},
+			{"Save for a new bike", "Put aside enough for a road bike by summer",
+				[]string{"Set up a savings pot", "Reach a quarter", "Reach half", "Buy the bike"}},
+		},
+	},
+	{
+		name: "priya", timezone: "Asia/Kolkata", locale: "en-IN", weekStart: "monday", workStart: 10, workEnd: 19,
+		categories: []string{"work", "work", "finance", "finance", "personal", "health"},
+		habits: []habit{
+			{"Meditate", "health", "daily", 15},
+			{"Review the cash flow", "finance", "weekly", 60},
+		},
+		goals: []goalTemplate{
+			{"Close the seed round", "Raise the round and sign the term sheet",
+				[]string{"Update the pitch deck", "Meet twenty investors", "Term sheet", "Close"}},
+			{"Hire a founding engineer", "Find and onboard the first engineering hire",
+				[]string{"Write the job description", "Shortlist candidates", "Make an offer"}},
+		},
+	},
+	{
+		name: "kenji", timezone: "Asia/Tokyo", locale: "ja-JP", weekStart: "sunday", workStart: 9, workEnd: 18,
+		categories: []string{"learning", "learning", "learning", "work", "personal", "shopping"},
+		habits: []habit{
+			{"Practice English vocabulary", "learning", "daily", 20},
+			{"Call the family", "personal", "weekly", 30},
+		},
+		goals: []goalTemplate{
+			{"Pass the algorithms course", "Finish the course with a grade of A",
+				[]string{"Problem set 1", "Midterm", "Problem set 2", "Final exam"}},
+			{"Read twelve books this year", "One book a month",
+				[]string{"Three books", "Six books", "Nine books", "Twelve books"}},
+		},
+	},
+	{
+		name: "maria", timezone: "Europe/Madrid", locale: "es-ES", weekStart: "monday", workStart: 9, workEnd: 15,
+		categories: []string{"personal", "personal", "shopping", "work", "health", "finance"},
+		habits: []habit{
+			{"Yoga", "health", "daily", 30},
+			{"Pay the weekly bills", "finance", "weekly", 20},
+		},
+		goals: []goalTemplate{
+			{"Renovate the kitchen", "New cabinets and worktop before the holidays",
+				[]string{"Get three quotes", "Choose the design", "Order the cabinets", "Installation"}},
+			{"Learn to swim with the kids", "Weekly lessons until everyone swims a length",
+				[]string{"Sign up for lessons", "First length", "Family swim day"}},
+		},
+	},
+	{
+		name: "omar", timezone: "Africa/Cairo", locale: "ar-EG", weekStart: "saturday", workStart: 8, workEnd: 17,
+		categories: []string{"work", "work", "learning", "health", "personal", "shopping"},
+		habits: []habit{
+			{"Gym workout", "health", "daily", 60},
+			{"Write the team update", "work", "weekly", 30},
+		},
+		goals: []goalTemplate{
+			{"Get the cloud architect certification", "Study for and pass the exam",
+				[]string{"Finish the video course", "Practice exam", "Book the exam", "Pass"}},
+			{"Migrate the billing service", "Move billing off the legacy monolith",
+				[]string{"Design review", "Shadow traffic", "Cut over", "Decommission the old service"}},
+		},
+	},
+}
+
+// tasks are the one-off tasks of each category
+var tasks = map[string][]taskTemplate{
+	"work": {
+		{"Prepare the quarterly report", 120, "@office"},
+		{"Review open pull requests", 45, "@office"},
+		{"1:1 with manager", 30, "@office"},
+		{"Reply to client emails", 30, ""},
+		{"Update the project roadmap", 60, "@office"},
+		{"Plan the next sprint", 60, "@office"},
+		{"Write the design doc", 90, ""},
+		{"Prepare the client presentation", 90, ""},
+		{"Deploy the release", 30, "@office"},
+		{"Team meeting", 60, "@office"},
+	},
+	"personal": {
+		{"Call mom", 30, "@home"},
+		{"Renew passport", 45, "@errands"},
+		{"Plan the weekend trip", 60, "@home"},
+		{"Book a haircut", 10, ""},
+		{"Clean the garage", 120, "@home"},
+		{"Write thank-you cards", 30, "@home"},
+	},
+	"health": {
+		{"Book the dentist", 10, ""},
+		{"Meal prep for the week", 90, "@home"},
+		{"Schedule the annual checkup", 15, ""},
+		{"Pick up the prescription", 20, "@errands"},
+		{"Yoga class", 60, ""},
+	},
+	"learning": {
+		{"Read a chapter of Designing Data-Intensive Applications", 45, ""},
+		{"Watch the Go concurrency talk", 60, "@home"},
+		{"Finish the online course module", 90, "@home"},
+		{"Practice Spanish", 20, ""},
+		{"Write notes on the last book", 30, "@home"},
+	},
+	"finance": {
+		{"Pay the credit card bill", 10, ""},
+		{"Review the monthly budget", 45, "@home"},
+		{"File the expense report", 30, "@office"},
+		{"Compare insurance quotes", 60, ""},
+		{"Prepare tax documents", 120, "@home"},
+	},
+	"shopping": {
+		{"Buy groceries", 45, "@errands"},
+		{"Order printer ink", 10, ""},
+		{"Buy a birthday present", 30, "@errands"},
+		{"Pick up dry cleaning", 15, "@errands"},
+	},
+}
@@ -0,0 +1,414 @@
+// Package seed generates demo data, so analytics, planning and the apps can
+// be tried without entering data by hand: users with their preferences, tasks
+// across categories from weeks ago to weeks ahead, habits with a completion
+// history, goals with milestones, and focus sessions as time entries. It is
+// the `seed` subcommand and, outside release mode, POST /admin/seed.
+//
+// Demo users are named UserPrefix plus a first name. Seeding replaces what
+// earlier runs left for the same users, so it can be repeated.
+package seed
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/utils"
+)
+
+// UserPrefix starts the ID of every demo user, so demo data is easy to tell
+// apart from real users' and to remove
+const UserPrefix = "demo-"
+
+// Limits and defaults of Options
+const (
+	DefaultUsers = 3
+	DefaultDays  = 30
+	MaxDays      = 365
+)
+
+// MaxUsers is how many different demo users there are
+var MaxUsers = len(profiles)
+
+// batchSize caps the rows sent in one insert
+const batchSize = 500
+
+// Options say how much data to generate
+type Options struct {
+	Users int   `json:"users"` // how many demo users, 1 to MaxUsers
+	Days  int   `json:"days"`  // how far history reaches back; plans reach half as far ahead
+	Seed  int64 `json:"seed"`  // the same seed on the same day generates the same data
+}
+
+// withDefaults fills in the options left zero and checks the rest; options
+// out of range are a bad request
+func (o Options) withDefaults() (Options, error) {
+	if o.Users == 0 {
+		o.Users = DefaultUsers
+	}
+	if o.Days == 0 {
+		o.Days = DefaultDays
+	}
+	if o.Users < 1 || o.Users > MaxUsers {
+		return o, utils.ErrBadRequest(fmt.Sprintf("users must be between 1 and %d", MaxUsers))
+	}
+	if o.Days < 1 || o.Days > MaxDays {
+		return o, utils.ErrBadRequest(fmt.Sprintf("days must be between 1 and %d", MaxDays))
+	}
+	return o, nil
+}
+
+// Table is the rows generated for one table
+type Table struct {
+	Name string
+	Rows []map[string]interface{}
+}
+
+// Dataset is the generated data, with tables in the order they must be
+// inserted so that rows come after those they reference
+type Dataset struct {
+	Users  []string
+	Tables []Table
+}
+
+// Result is what Write stored
+type Result struct {
+	Users   []string       `json:"users"`
+	Rows    map[string]int `json:"rows"`    // rows inserted per table
+	Removed int            `json:"removed"` // rows earlier demo data for the same users had
+}
+
+// Generate builds the demo data for opts, relative to now
+func Generate(opts Options, now time.Time) (*Dataset, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{
+		rng:    rand.New(rand.NewPCG(uint64(opts.Seed), 0x5eed)),
+		now:    now.UTC().Truncate(time.Second),
+		days:   opts.Days,
+		tables: map[string][]map[string]interface{}{},
+	}
+	data := &Dataset{}
+	for _, p := range profiles[:opts.Users] {
+		userID := UserPrefix + p.name
+		data.Users = append(data.Users, userID)
+		g.user(userID, p)
+	}
+	for _, name := range []string{"user_preferences", "tasks", "task_completions", "goals", "goal_milestones", "focus_sessions"} {
+		data.Tables = append(data.Tables, Table{Name: name, Rows: g.tables[name]})
+	}
+	return data, nil
+}
+
+// Write replaces the data of the dataset's users with it: every row they own,
+// in the tables account deletion clears, is deleted first
+func Write(client *db.SupabaseClient, data *Dataset) (*Result, error) {
+	result := &Result{Users: data.Users, Rows: map[string]int{}}
+	for _, userID := range data.Users {
+		for _, table := range db.AccountTables {
+			n, err := client.DeleteAccountRows(table, userID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to remove earlier demo data of %s: %w", userID, err)
+			}
+			result.Removed += n
+		}
+	}
+	for _, table := range data.Tables {
+		for start := 0; start < len(table.Rows); start += batchSize {
+			end := min(start+batchSize, len(table.Rows))
+			if err := client.InsertRows(table.Name, table.Rows[start:end]); err != nil {
+				return nil, err
+			}
+		}
+		result.Rows[table.Name] = len(table.Rows)
+	}
+	return result, nil
+}
+
+// Run implements the `seed` subcommand: it writes demo data to the
+// configured Supabase project, refusing to when GIN_MODE=release
+func Run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file (default $"+config.FileEnv+")")
+	var opts Options
+	fs.IntVar(&opts.Users, "users", DefaultUsers, fmt.Sprintf("how many demo users to create (at most %d)", MaxUsers))
+	fs.IntVar(&opts.Days, "days", DefaultDays, "days of history to generate; plans reach half as far ahead")
+	fs.Int64Var(&opts.Seed, "seed", 0, "random seed; the same seed on the same day generates the same data")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return err
+	}
+	if cfg.Server.Release() {
+		return errors.New("refusing to write demo data when GIN_MODE=release")
+	}
+	data, err := Generate(opts, time.Now())
+	if err != nil {
+		return err
+	}
+	db.ConfigureServiceRole(cfg.Supabase.ServiceRoleKey)
+	client, err := db.NewSupabaseClient(cfg.Supabase.URL, cfg.Supabase.AnonKey)
+	if err != nil {
+		return err
+	}
+	result, err := Write(client.WithContext(ctx), data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Seeded %d demo users: %s\n", len(result.Users), strings.Join(result.Users, ", "))
+	for _, table := range data.Tables {
+		fmt.Fprintf(out, "  %-18s %d rows\n", table.Name, result.Rows[table.Name])
+	}
+	if result.Removed > 0 {
+		fmt.Fprintf(out, "Replaced %d rows of earlier demo data\n", result.Removed)
+	}
+	fmt.Fprintln(out, "Act as a demo user with a service token:")
+	fmt.Fprintf(out, "  TOKEN=$(productivity-mcp servicetoken -sub %s)\n", result.Users[0])
+	return nil
+}
+
+// generator builds the rows of every table, drawing from one random source
+// so a seed always gives the same data
+type generator struct {
+	rng    *rand.Rand
+	now    time.Time
+	days   int
+	tables map[string][]map[string]interface{}
+}
+
+func (g *generator) add(table string, row map[string]interface{}) {
+	g.tables[table] = append(g.tables[table], row)
+}
+
+// id is a random version 4 UUID from the generator's source
+func (g *generator) id() string {
+	hi, lo := g.rng.Uint64(), g.rng.Uint64()
+	hi = hi&^0xf000 | 0x4000
+	lo = lo&^(0xc<<60) | 0x8<<60
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", hi>>32, hi>>16&0xffff, hi&0xffff, lo>>48, lo&0xffffffffffff)
+}
+
+// at is a time during working hours on the day offset days from today, in
+// the user's time zone
+func (g *generator) at(today time.Time, offset int, p profile) time.Time {
+	hour := p.workStart + g.rng.IntN(p.workEnd-p.workStart)
+	return today.AddDate(0, 0, offset).Add(time.Duration(hour)*time.Hour + time.Duration(g.rng.IntN(4)*15)*time.Minute)
+}
+
+func (g *generator) user(userID string, p profile) {
+	loc, err := time.LoadLocation(p.timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := g.now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	g.add("user_preferences", map[string]interface{}{
+		"user_id":    userID,
+		"timezone":   p.timezone,
+		"locale":     p.locale,
+		"week_start": p.weekStart,
+		"work_start": fmt.Sprintf("%02d:00", p.workStart),
+		"work_end":   fmt.Sprintf("%02d:00", p.workEnd),
+		"updated_at": timestamp(g.now),
+	})
+	g.oneOffTasks(userID, p, today)
+	g.habits(userID, p, today)
+	g.goals(userID, p, today)
+	g.focusSessions(userID, p, today)
+}
+
+// oneOffTasks spreads two tasks a day from days ago to half as far ahead.
+// Most past tasks were completed around when they were due; the rest are
+// overdue.
+func (g *generator) oneOffTasks(userID string, p profile, today time.Time) {
+	priorities := []int{1, 2, 2, 3, 3, 3, 4, 4, 5}
+	for i := 0; i < 2*g.days; i++ {
+		category := p.categories[g.rng.IntN(len(p.categories))]
+		templates := tasks[category]
+		tmpl := templates[g.rng.IntN(len(templates))]
+		due := g.at(today, g.rng.IntN(g.days+g.days/2+1)-g.days, p)
+		created := due.Add(-time.Duration(1+g.rng.IntN(7*24)) * time.Hour)
+		if created.After(g.now) {
+			created = g.now.Add(-time.Duration(1+g.rng.IntN(48)) * time.Hour)
+		}
+
+		id := g.id()
+		task := map[string]interface{}{
+			"id":                 id,
+			"user_id":            userID,
+			"title":              tmpl.title,
+			"description":        "",
+			"priority":           priorities[g.rng.IntN(len(priorities))],
+			"due_date":           timestamp(due),
+			"estimated_duration": tmpl.minutes,
+			"category":           category,
+			"completed":          false,
+			"completed_at":       nil,
+			"context":            nil,
+			"created_at":         timestamp(created),
+			"updated_at":         timestamp(created),
+		}
+		if tmpl.context != "" {
+			task["context"] = tmpl.context
+		}
+		if due.Before(g.now) && g.rng.Float64() < 0.8 {
+			completedAt := due.Add(time.Duration(g.rng.IntN(8*60)-3*60) * time.Minute)
+			if completedAt.Before(created) {
+				completedAt = created.Add(time.Hour)
+			}
+			if completedAt.After(g.now) {
+				completedAt = g.now
+			}
+			task["completed"] = true
+			task["completed_at"] = timestamp(completedAt)
+			task["updated_at"] = timestamp(completedAt)
+			g.completion(userID, id, completedAt)
+		}
+		g.add("tasks", task)
+	}
+}
+
+// habits adds each recurring task, due next today, with a history of
+// completions that misses a day now and then
+func (g *generator) habits(userID string, p profile, today time.Time) {
+	for _, h := range p.habits {
+		id := g.id()
+		created := today.AddDate(0, 0, -g.days-1)
+		g.add("tasks", map[string]interface{}{
+			"id":                  id,
+			"user_id":             userID,
+			"title":               h.title,
+			"description":         "",
+			"priority":            3,
+			"due_date":            timestamp(today.Add(time.Duration(p.workEnd) * time.Hour)),
+			"estimated_duration":  h.minutes,
+			"category":            h.category,
+			"completed":           false,
+			"completed_at":        nil,
+			"context":             nil,
+			"recurring_frequency": h.frequency,
+			"recurring_interval":  1,
+			"created_at":          timestamp(created),
+			"updated_at":          timestamp(created),
+		})
+
+		step := 1
+		if h.frequency == "weekly" {
+			step = 7
+		}
+		for offset := -g.days; offset < 0; offset += step {
+			if g.rng.Float64() < 0.85 {
+				g.completion(userID, id, g.at(today, offset, p))
+			}
+		}
+	}
+}
+
+func (g *generator) completion(userID, taskID string, at time.Time) {
+	g.add("task_completions", map[string]interface{}{
+		"id":           g.id(),
+		"user_id":      userID,
+		"task_id":      taskID,
+		"completed_at": timestamp(at),
+	})
+}
+
+// goals adds goals that started before the history and end after the plans,
+// with evenly spaced milestones; most of those already due are completed,
+// and the goal's progress is the share completed
+func (g *generator) goals(userID string, p profile, today time.Time) {
+	for _, tmpl := range p.goals {
+		id := g.id()
+		start := today.AddDate(0, 0, -g.days-g.rng.IntN(14))
+		target := today.AddDate(0, 0, g.days+g.rng.IntN(60))
+		span := target.Sub(start) / time.Duration(len(tmpl.milestones))
+
+		completed := 0
+		for i, title := range tmpl.milestones {
+			due := start.Add(time.Duration(i+1) * span)
+			milestone := map[string]interface{}{
+				"id":           g.id(),
+				"goal_id":      id,
+				"user_id":      userID,
+				"title":        title,
+				"due_date":     timestamp(due),
+				"completed":    false,
+				"completed_at": nil,
+				"created_at":   timestamp(start),
+				"updated_at":   timestamp(start),
+			}
+			if due.Before(g.now) && g.rng.Float64() < 0.85 {
+				completedAt := due.Add(-time.Duration(g.rng.IntN(72)) * time.Hour)
+				milestone["completed"] = true
+				milestone["completed_at"] = timestamp(completedAt)
+				milestone["updated_at"] = timestamp(completedAt)
+				completed++
+			}
+			g.add("goal_milestones", milestone)
+		}
+
+		g.add("goals", map[string]interface{}{
+			"id":          id,
+			"user_id":     userID,
+			"title":       tmpl.title,
+			"description": tmpl.description,
+			"start_date":  timestamp(start),
+			"target_date": timestamp(target),
+			"progress":    completed * 100 / len(tmpl.milestones),
+			"archived":    false,
+			"created_at":  timestamp(start),
+			"updated_at":  timestamp(g.now),
+		})
+	}
+}
+
+// focusSessions adds up to two finished focus sessions on each past weekday,
+// the time entries of the demo
+func (g *generator) focusSessions(userID string, p profile, today time.Time) {
+	lengths := []int{25, 25, 45, 50, 90}
+	for offset := -g.days; offset < 0; offset++ {
+		if weekday := today.AddDate(0, 0, offset).Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+			continue
+		}
+		for n := g.rng.IntN(3); n > 0; n-- {
+			started := g.at(today, offset, p)
+			ends := started.Add(time.Duration(lengths[g.rng.IntN(len(lengths))]) * time.Minute)
+			ended := ends
+			if g.rng.Float64() < 0.2 {
+				ended = ends.Add(-time.Duration(1+g.rng.IntN(10)) * time.Minute)
+			}
+			source := "api"
+			if g.rng.IntN(3) == 0 {
+				source = "mcp"
+			}
+			g.add("focus_sessions", map[string]interface{}{
+				"id":         g.id(),
+				"user_id":    userID,
+				"started_at": timestamp(started),
+				"ends_at":    timestamp(ends),
+				"ended_at":   timestamp(ended),
+				"source":     source,
+				"created_at": timestamp(started),
+			})
+		}
+	}
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
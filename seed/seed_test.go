@@ -0,0 +1,118 @@
+//go:build !lite
+
+package seed
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/mocksupabase"
+)
+
+func TestGenerate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+	data, err := Generate(Options{Users: 2, Days: 14, Seed: 7}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Generate(Options{Users: 2, Days: 14, Seed: 7}, now)
+	if !reflect.DeepEqual(data, again) {
+		t.Error("the same seed generated different data")
+	}
+	if other, _ := Generate(Options{Users: 2, Days: 14, Seed: 8}, now); reflect.DeepEqual(data, other) {
+		t.Error("a different seed generated the same data")
+	}
+	if !reflect.DeepEqual(data.Users, []string{"demo-alex", "demo-sam"}) {
+		t.Errorf("users %v", data.Users)
+	}
+
+	rows := map[string][]map[string]interface{}{}
+	for _, table := range data.Tables {
+		rows[table.Name] = table.Rows
+	}
+	tasks := map[string]map[string]interface{}{}
+	categories := map[interface{}]bool{}
+	for _, task := range rows["tasks"] {
+		tasks[task["id"].(string)] = task
+		categories[task["category"]] = true
+		if task["completed"] == true && task["due_date"].(string) > now.Format(time.RFC3339) {
+			t.Errorf("task %v completed before it was due", task["title"])
+		}
+	}
+	if len(tasks) != len(rows["tasks"]) || len(categories) < 4 {
+		t.Errorf("%d tasks with %d ids in %d categories", len(rows["tasks"]), len(tasks), len(categories))
+	}
+
+	// Every completion is of a task of the same user, in the past
+	for _, completion := range rows["task_completions"] {
+		task := tasks[completion["task_id"].(string)]
+		if task == nil || task["user_id"] != completion["user_id"] || completion["completed_at"].(string) > now.Format(time.RFC3339) {
+			t.Errorf("completion %v does not match a task", completion)
+		}
+	}
+
+	// Goal progress is the share of milestones completed
+	milestones := map[interface{}][2]int{}
+	for _, milestone := range rows["goal_milestones"] {
+		counts := milestones[milestone["goal_id"]]
+		counts[1]++
+		if milestone["completed"] == true {
+			counts[0]++
+		}
+		milestones[milestone["goal_id"]] = counts
+	}
+	for _, goal := range rows["goals"] {
+		counts := milestones[goal["id"]]
+		if counts[1] == 0 || goal["progress"] != counts[0]*100/counts[1] {
+			t.Errorf("goal %v: progress %v with %d of %d milestones", goal["title"], goal["progress"], counts[0], counts[1])
+		}
+	}
+
+	if len(rows["user_preferences"]) != 2 || len(rows["focus_sessions"]) == 0 {
+		t.Errorf("%d preferences, %d focus sessions", len(rows["user_preferences"]), len(rows["focus_sessions"]))
+	}
+
+	for _, opts := range []Options{{Users: MaxUsers + 1}, {Days: MaxDays + 1}, {Users: -1}} {
+		if _, err := Generate(opts, now); err == nil {
+			t.Errorf("%+v: no error", opts)
+		}
+	}
+}
+
+func TestWriteReplacesDemoData(t *testing.T) {
+	supabase := mocksupabase.NewHandler()
+	supabase.Insert("tasks", mocksupabase.Row{"id": "real", "user_id": "someone", "title": "Not a demo task"})
+	server := httptest.NewServer(supabase)
+	defer server.Close()
+	client, err := db.NewSupabaseClient(server.URL, "seed-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := Generate(Options{Users: 1, Days: 7}, time.Now())
+	first, err := Write(client, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Removed != 0 || first.Rows["tasks"] != len(data.Tables[1].Rows) {
+		t.Errorf("first write: %+v", first)
+	}
+
+	second, err := Write(client, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, n := range second.Rows {
+		total += n
+	}
+	if second.Removed != total {
+		t.Errorf("second write removed %d rows, want %d", second.Removed, total)
+	}
+	if got := len(supabase.Rows("tasks")); got != first.Rows["tasks"]+1 {
+		t.Errorf("%d tasks stored, want the demo tasks and the real one", got)
+	}
+}
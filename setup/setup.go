@@ -0,0 +1,291 @@
+//go:build !lite
+
+// Package setup implements the `setup` subcommand: it applies the Supabase
+// migrations, generates a JWT secret, registers a first OAuth client, writes
+// a config file and checks that the server can reach every table it needs.
+// Values not given as flags are prompted for unless -yes is set.
+package setup
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	_ "github.com/lib/pq"
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/db"
+	"github.com/productivity/mcp-server/handlers"
+)
+
+// Options are the values setup needs; empty ones are prompted for
+type Options struct {
+	ConfigFile   string
+	SupabaseURL  string
+	SupabaseKey  string
+	ServiceKey   string // service-role key; the only one that can write OAuth clients
+	DatabaseURL  string // direct Postgres connection used only to run migrations
+	Baseline     string // mark migrations up to this one as applied without running them
+	Port         string
+	ClientID     string
+	ClientName   string
+	RedirectURIs []string
+	Yes          bool // never prompt; fail on missing required values
+	Force        bool // overwrite an existing config file
+}
+
+// Run parses the subcommand's arguments and performs the setup
+func Run(ctx context.Context, args []string, in io.Reader, out io.Writer) error {
+	opts := Options{}
+	redirectURIs := ""
+
+	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.StringVar(&opts.ConfigFile, "config", "productivity-mcp.yaml", "config file to write")
+	fs.StringVar(&opts.SupabaseURL, "supabase-url", os.Getenv("SUPABASE_URL"), "Supabase project URL")
+	fs.StringVar(&opts.SupabaseKey, "supabase-key", os.Getenv("SUPABASE_ANON_KEY"), "Supabase anon key")
+	fs.StringVar(&opts.ServiceKey, "supabase-service-key", os.Getenv("SUPABASE_SERVICE_ROLE_KEY"), "Supabase service-role key")
+	fs.StringVar(&opts.DatabaseURL, "database-url", os.Getenv("SUPABASE_DB_URL"), "Postgres connection string for migrations (skipped if empty)")
+	fs.StringVar(&opts.Baseline, "baseline", "", "mark migrations up to this number as already applied (e.g. 009)")
+	fs.StringVar(&opts.Port, "port", "8000", "port the server listens on")
+	fs.StringVar(&opts.ClientID, "client-id", "", "ID of the first OAuth client")
+	fs.StringVar(&opts.ClientName, "client-name", "", "display name of the first OAuth client")
+	fs.StringVar(&redirectURIs, "redirect-uris", "", "comma-separated redirect URIs of the first OAuth client")
+	fs.BoolVar(&opts.Yes, "yes", false, "do not prompt; use flags, environment and defaults")
+	fs.BoolVar(&opts.Force, "force", false, "overwrite an existing config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	opts.RedirectURIs = splitList(redirectURIs)
+
+	if !opts.Yes {
+		if err := prompt(bufio.NewReader(in), out, &opts); err != nil {
+			return err
+		}
+	}
+	return Setup(ctx, opts, out)
+}
+
+// prompt asks for every value that was not given, showing defaults in brackets
+func prompt(r *bufio.Reader, out io.Writer, opts *Options) error {
+	ask := func(label string, value *string, def string) error {
+		if *value != "" {
+			return nil
+		}
+		if def != "" {
+			fmt.Fprintf(out, "%s [%s]: ", label, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", label)
+		}
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		*value = strings.TrimSpace(line)
+		if *value == "" {
+			*value = def
+		}
+		return nil
+	}
+
+	fmt.Fprintln(out, "Productivity MCP server setup")
+	if err := ask("Supabase project URL", &opts.SupabaseURL, ""); err != nil {
+		return err
+	}
+	if err := ask("Supabase anon key", &opts.SupabaseKey, ""); err != nil {
+		return err
+	}
+	if err := ask("Supabase service-role key", &opts.ServiceKey, ""); err != nil {
+		return err
+	}
+	if err := ask("Postgres connection string for migrations (blank to skip)", &opts.DatabaseURL, ""); err != nil {
+		return err
+	}
+	if err := ask("OAuth client ID", &opts.ClientID, "productivity-mcp"); err != nil {
+		return err
+	}
+	if len(opts.RedirectURIs) == 0 {
+		uris := ""
+		if err := ask("OAuth redirect URIs (comma-separated)", &uris, "https://claude.ai/api/mcp/auth_callback"); err != nil {
+			return err
+		}
+		opts.RedirectURIs = splitList(uris)
+	}
+	return nil
+}
+
+// Setup provisions the schema, registers the client, writes the config file and verifies it
+func Setup(ctx context.Context, opts Options, out io.Writer) error {
+	if opts.SupabaseURL == "" || opts.SupabaseKey == "" || opts.ServiceKey == "" {
+		return fmt.Errorf("the Supabase URL, anon key and service-role key are required (-supabase-url, -supabase-key, -supabase-service-key)")
+	}
+	if opts.ClientID == "" {
+		opts.ClientID = "productivity-mcp"
+	}
+	if opts.ClientName == "" {
+		opts.ClientName = opts.ClientID
+	}
+	if len(opts.RedirectURIs) == 0 {
+		opts.RedirectURIs = []string{"https://claude.ai/api/mcp/auth_callback"}
+	}
+	if _, err := os.Stat(opts.ConfigFile); err == nil && !opts.Force {
+		return fmt.Errorf("%s already exists; pass -force to overwrite it", opts.ConfigFile)
+	}
+
+	// 1. Schema
+	if opts.DatabaseURL != "" {
+		if err := migrate(ctx, opts, out); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(out, "Skipping migrations: no database URL. Apply db/migrations/*.sql in the Supabase SQL editor,")
+		fmt.Fprintln(out, "  or rerun with -database-url (Project Settings > Database > Connection string).")
+	}
+
+	// 2. Secrets
+	jwtSecret, err := randomString(32, hex.EncodeToString)
+	if err != nil {
+		return err
+	}
+	clientSecret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return err
+	}
+
+	// 3. First OAuth client
+	db.ConfigureServiceRole(opts.ServiceKey)
+	handlers.SetOAuthClientStore(opts.SupabaseURL, opts.SupabaseKey)
+	if err := handlers.StoreOAuthClient(&handlers.OAuthClient{
+		ClientID:                opts.ClientID,
+		ClientSecret:            clientSecret,
+		RedirectURIs:            opts.RedirectURIs,
+		Name:                    opts.ClientName,
+		TokenEndpointAuthMethod: handlers.AuthMethodClientSecretPost,
+		GrantTypes:              []string{"authorization_code", "refresh_token"},
+		ResponseTypes:           []string{"code"},
+	}); err != nil {
+		return fmt.Errorf("failed to register OAuth client (are the migrations applied?): %w", err)
+	}
+	fmt.Fprintf(out, "Registered OAuth client %q\n", opts.ClientID)
+
+	// 4. Config file
+	if err := writeConfig(opts, jwtSecret); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Wrote %s\n", opts.ConfigFile)
+
+	// 5. Verification
+	if err := verify(opts, out); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "OAuth client ID:     %s\n", opts.ClientID)
+	fmt.Fprintf(out, "OAuth client secret: %s (shown only once)\n", clientSecret)
+	fmt.Fprintf(out, "Start the server with: productivity-mcp -config %s\n", opts.ConfigFile)
+	return nil
+}
+
+func migrate(ctx context.Context, opts Options, out io.Writer) error {
+	conn, err := sql.Open("postgres", opts.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid database URL: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to connect to the database: %w", err)
+	}
+
+	if opts.Baseline != "" {
+		marked, err := db.Baseline(ctx, conn, opts.Baseline)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Marked %d migrations as already applied\n", len(marked))
+	}
+
+	applied, err := db.Migrate(ctx, conn)
+	for _, name := range applied {
+		fmt.Fprintf(out, "Applied migration %s\n", name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(out, "Schema is up to date")
+	}
+	return nil
+}
+
+// writeConfig writes only the settings setup chose; everything else keeps its default
+func writeConfig(opts Options, jwtSecret string) error {
+	doc := yaml.MapSlice{
+		{Key: "server", Value: yaml.MapSlice{{Key: "port", Value: opts.Port}}},
+		{Key: "supabase", Value: yaml.MapSlice{
+			{Key: "url", Value: opts.SupabaseURL},
+			{Key: "anon_key", Value: opts.SupabaseKey},
+			{Key: "service_role_key", Value: opts.ServiceKey},
+		}},
+		{Key: "auth", Value: yaml.MapSlice{{Key: "jwt_secret", Value: jwtSecret}}},
+	}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	header := "# Written by `productivity-mcp setup`. See config.example.yaml for every setting.\n"
+	// The file holds secrets, so only the owner may read it
+	return os.WriteFile(opts.ConfigFile, append([]byte(header), data...), 0600)
+}
+
+// verify loads the written config the way the server will and checks every table
+func verify(opts Options, out io.Writer) error {
+	cfg, err := config.Load(opts.ConfigFile)
+	if err != nil {
+		return err
+	}
+	client, err := db.NewSupabaseClient(cfg.Supabase.URL, cfg.Supabase.AnonKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Checking tables through the REST API:")
+	var missing []string
+	for _, table := range db.Tables {
+		if err := client.Ping(table.Name); err != nil {
+			missing = append(missing, table.Name)
+			fmt.Fprintf(out, "  %-18s FAILED: %v\n", table.Name, err)
+			continue
+		}
+		fmt.Fprintf(out, "  %-18s ok\n", table.Name)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%d tables are not reachable: %s", len(missing), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,63 @@
+//go:build !lite
+
+package setup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/productivity/mcp-server/config"
+)
+
+func TestSetupWritesConfigAndRegistersClient(t *testing.T) {
+	var stored map[string]interface{}
+	var storedWith string
+	supabase := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/rest/v1/oauth_clients" {
+			storedWith = r.Header.Get("apikey")
+			json.NewDecoder(r.Body).Decode(&stored)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode([]interface{}{stored})
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer supabase.Close()
+
+	path := filepath.Join(t.TempDir(), "productivity-mcp.yaml")
+	var out strings.Builder
+	err := Run(context.Background(), []string{
+		"-config", path, "-supabase-url", supabase.URL, "-supabase-key", "anon", "-supabase-service-key", "service",
+		"-redirect-uris", "http://localhost:8080",
+	}, strings.NewReader("\nmy-client\n"), &out)
+	if err != nil {
+		t.Fatalf("setup failed: %v\n%s", err, out.String())
+	}
+
+	if stored["client_id"] != "my-client" || storedWith != "service" {
+		t.Errorf("registered client %v with key %q, want the prompted ID with the service-role key", stored, storedWith)
+	}
+	if hash, _ := stored["client_secret_hash"].(string); len(hash) != 64 || strings.Contains(out.String(), hash) {
+		t.Errorf("expected only a secret hash to be stored, got %v", stored)
+	}
+	if !strings.Contains(out.String(), "shown only once") {
+		t.Errorf("client secret not printed:\n%s", out.String())
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Supabase.URL != supabase.URL || cfg.Supabase.ServiceRoleKey != "service" || len(cfg.Auth.JWTSecret) != 64 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	if err := Run(context.Background(), []string{"-yes", "-config", path, "-supabase-url", supabase.URL, "-supabase-key", "anon", "-supabase-service-key", "service"}, nil, &out); err == nil {
+		t.Error("expected setup to refuse to overwrite the config without -force")
+	}
+}
@@ -0,0 +1,73 @@
+package signing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/productivity/mcp-server/config"
+)
+
+// AdminRole is the role claim that grants access to /admin routes
+const AdminRole = "admin"
+
+// maxAdminTokenTTL caps how long a minted admin token stays valid
+const maxAdminTokenTTL = 24 * time.Hour
+
+// AdminToken implements the `admintoken` subcommand: it signs a short-lived
+// token carrying the admin role claim with the server's configured keys, so
+// operators can call /admin without being listed in ADMIN_USER_IDS
+func AdminToken(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("admintoken", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file (default $"+config.FileEnv+")")
+	subject := fs.String("sub", "admin", "subject (operator name) recorded as the token's user")
+	ttl := fs.Duration("ttl", time.Hour, "how long the token is valid (at most 24h)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *ttl <= 0 || *ttl > maxAdminTokenTTL {
+		return fmt.Errorf("-ttl must be between 0 and %s", maxAdminTokenTTL)
+	}
+
+	token, err := signWithConfig(*configFile, *ttl, jwt.MapClaims{
+		"sub":  *subject,
+		"role": AdminRole,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only the token goes to out, so TOKEN=$(go run . admintoken) works
+	fmt.Fprintln(out, token)
+	return nil
+}
+
+// signWithConfig signs claims valid for ttl with the keys of the server's
+// config, adding a fresh jti and the issue and expiry times
+func signWithConfig(configFile string, ttl time.Duration, claims jwt.MapClaims) (string, error) {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return "", err
+	}
+	keys, err := Load(cfg.Auth.JWTSecret, cfg.Auth.SigningKeys)
+	if err != nil {
+		return "", err
+	}
+	keys = keys.WithAudience(cfg.Auth.Resource)
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims["jti"] = base64.RawURLEncoding.EncodeToString(b)
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	return keys.Sign(claims)
+}
@@ -0,0 +1,49 @@
+package signing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Keygen implements the `keygen` subcommand: it writes a new private key as
+// PEM and prints its key ID
+func Keygen(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	fs.SetOutput(out)
+	algorithm := fs.String("alg", EdDSA, "key algorithm: "+EdDSA+" or "+RS256)
+	file := fs.String("out", "", "file to write the PEM private key to (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("-out is required")
+	}
+
+	key, err := GenerateKey(*algorithm)
+	if err != nil {
+		return err
+	}
+	data, err := key.MarshalPEM()
+	if err != nil {
+		return err
+	}
+	// O_EXCL so an existing key is never overwritten by accident
+	f, err := os.OpenFile(*file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Wrote %s key %s to %s\n", key.Algorithm, key.ID, *file)
+	fmt.Fprintln(out, "Add it to JWT_SIGNING_KEYS; the first key listed signs new tokens.")
+	return nil
+}
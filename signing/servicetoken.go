@@ -0,0 +1,58 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/productivity/mcp-server/config"
+)
+
+// ServiceRole is the role claim of service account tokens
+const ServiceRole = "service"
+
+// ActAsScope lets a service account act for the user a request names with
+// ?user_id= or X-User-ID. It only counts on tokens that also carry the
+// service role: those minted by `servicetoken`, and client_credentials
+// tokens of clients OAUTH_CLIENT_CREDENTIALS grants it to.
+const ActAsScope = "act_as"
+
+// maxServiceTokenTTL caps how long a minted service account token stays valid
+const maxServiceTokenTTL = 90 * 24 * time.Hour
+
+// ServiceToken implements the `servicetoken` subcommand: it signs a token for
+// a service account, such as a scheduler or an integration, that acts on
+// users' behalf, with the server's configured keys
+func ServiceToken(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("servicetoken", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file (default $"+config.FileEnv+")")
+	subject := fs.String("sub", "", "name of the service account, recorded as the token's user")
+	ttl := fs.Duration("ttl", 24*time.Hour, "how long the token is valid (at most 90 days)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *subject == "" {
+		return errors.New("-sub is required")
+	}
+	if *ttl <= 0 || *ttl > maxServiceTokenTTL {
+		return fmt.Errorf("-ttl must be between 0 and %s", maxServiceTokenTTL)
+	}
+
+	token, err := signWithConfig(*configFile, *ttl, jwt.MapClaims{
+		"sub":   *subject,
+		"role":  ServiceRole,
+		"scope": ActAsScope,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only the token goes to out, so TOKEN=$(go run . servicetoken -sub cron) works
+	fmt.Fprintln(out, token)
+	return nil
+}
@@ -0,0 +1,320 @@
+// Package signing holds the keys the server signs and verifies its JWTs with.
+// Without asymmetric keys tokens are HS256 with the shared JWT secret. With
+// RS256 or EdDSA keys the first key signs, every key verifies, and the public
+// halves are published as a JWK set so resource servers can verify tokens
+// without the secret.
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithms supported for asymmetric keys
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// minRSABits is the smallest RSA modulus accepted for signing
+const minRSABits = 2048
+
+// Key is an asymmetric signing key identified by its RFC 7638 thumbprint
+type Key struct {
+	ID        string
+	Algorithm string
+	private   crypto.Signer
+}
+
+// Public returns the verification half of the key
+func (k *Key) Public() crypto.PublicKey {
+	return k.private.Public()
+}
+
+// NewKey wraps an RSA or Ed25519 private key
+func NewKey(private crypto.Signer) (*Key, error) {
+	k := &Key{private: private}
+	switch p := private.(type) {
+	case *rsa.PrivateKey:
+		if p.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key is %d bits; at least %d are required", p.N.BitLen(), minRSABits)
+		}
+		k.Algorithm = RS256
+	case ed25519.PrivateKey:
+		k.Algorithm = EdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T; use RSA or Ed25519", private)
+	}
+	k.ID = k.JWK().Thumbprint()
+	return k, nil
+}
+
+// GenerateKey creates a new key for algorithm
+func GenerateKey(algorithm string) (*Key, error) {
+	switch algorithm {
+	case RS256:
+		private, err := rsa.GenerateKey(rand.Reader, minRSABits)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey(private)
+	case EdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey(private)
+	}
+	return nil, fmt.Errorf("unsupported algorithm %q; use %s or %s", algorithm, RS256, EdDSA)
+}
+
+// ParseKey reads a PEM-encoded PKCS #8 or PKCS #1 private key
+func ParseKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var private interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+	return NewKey(signer)
+}
+
+// MarshalPEM encodes the private key as PKCS #8 PEM
+func (k *Key) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// KeySet signs tokens with its first asymmetric key, or with the shared
+// secret when it has none, and verifies tokens signed by any of them
+type KeySet struct {
+	secret []byte
+	keys   []*Key
+	// audience, when set, is stamped on tokens without an "aud" claim and
+	// required of every token verified
+	audience string
+}
+
+// NewKeySet builds a key set; keys[0] signs and the rest only verify, so a
+// retired key keeps validating its tokens until it is removed
+func NewKeySet(secret string, keys ...*Key) *KeySet {
+	return &KeySet{secret: []byte(secret), keys: keys}
+}
+
+// Load reads the PEM key files in order and builds the key set
+func Load(secret string, files []string) (*KeySet, error) {
+	var keys []*Key
+	seen := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("signing key: %w", err)
+		}
+		key, err := ParseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", file, err)
+		}
+		if other, ok := seen[key.ID]; ok {
+			return nil, fmt.Errorf("signing key %s: same key as %s", file, other)
+		}
+		seen[key.ID] = file
+		keys = append(keys, key)
+	}
+	return NewKeySet(secret, keys...), nil
+}
+
+// WithAudience returns a copy of the key set that signs tokens for audience
+// and accepts only tokens issued for it (RFC 8707), so a token minted for
+// this server cannot be replayed against another service sharing its keys
+func (s *KeySet) WithAudience(audience string) *KeySet {
+	out := *s
+	out.audience = audience
+	return &out
+}
+
+// Audience is the audience tokens are signed for, empty when unchecked
+func (s *KeySet) Audience() string {
+	return s.audience
+}
+
+// Algorithm is the algorithm new tokens are signed with
+func (s *KeySet) Algorithm() string {
+	if len(s.keys) > 0 {
+		return s.keys[0].Algorithm
+	}
+	return "HS256"
+}
+
+// SigningKey is the key new tokens are signed with, or nil for HS256
+func (s *KeySet) SigningKey() *Key {
+	if len(s.keys) > 0 {
+		return s.keys[0]
+	}
+	return nil
+}
+
+// Sign returns a signed JWT for claims, naming the key in the "kid" header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if m, ok := claims.(jwt.MapClaims); ok && s.audience != "" {
+		if _, set := m["aud"]; !set {
+			m["aud"] = s.audience
+		}
+	}
+	if len(s.keys) == 0 {
+		if len(s.secret) == 0 {
+			return "", errors.New("JWT secret not configured")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	}
+
+	key := s.keys[0]
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Verify checks the token's signature, expiry and audience and returns its claims. The
+// key is chosen by algorithm and "kid", so a public key is never accepted as
+// an HMAC secret.
+func (s *KeySet) Verify(tokenString string) (jwt.MapClaims, error) {
+	if len(s.secret) == 0 && len(s.keys) == 0 {
+		return nil, errors.New("JWT secret not configured")
+	}
+	options := []jwt.ParserOption{jwt.WithValidMethods(s.methods())}
+	if s.audience != "" {
+		options = append(options, jwt.WithAudience(s.audience))
+	}
+	token, err := jwt.Parse(tokenString, s.keyFor, options...)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return claims, nil
+}
+
+func (s *KeySet) methods() []string {
+	var methods []string
+	if len(s.secret) > 0 {
+		methods = append(methods, "HS256")
+	}
+	seen := map[string]bool{}
+	for _, k := range s.keys {
+		if !seen[k.Algorithm] {
+			seen[k.Algorithm] = true
+			methods = append(methods, k.Algorithm)
+		}
+	}
+	return methods
+}
+
+func (s *KeySet) keyFor(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(s.secret) == 0 {
+			return nil, jwt.ErrTokenUnverifiable
+		}
+		return s.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	for _, k := range s.keys {
+		if k.Algorithm == token.Method.Alg() && (kid == "" || kid == k.ID) {
+			return k.Public(), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// JWK is a public key in RFC 7517 form
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWK returns the public half of the key
+func (k *Key) JWK() JWK {
+	jwk := JWK{Use: "sig", Algorithm: k.Algorithm, KeyID: k.ID}
+	switch pub := k.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// Thumbprint is the RFC 7638 SHA-256 thumbprint of the key
+func (j JWK) Thumbprint() string {
+	// The required members in lexicographic order, without whitespace
+	var canonical []byte
+	switch j.KeyType {
+	case "RSA":
+		canonical, _ = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.KeyType, j.N})
+	case "OKP":
+		canonical, _ = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Curve, j.KeyType, j.X})
+	}
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public keys of every asymmetric key; the HMAC secret is never published
+func (s *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range s.keys {
+		set.Keys = append(set.Keys, k.JWK())
+	}
+	return set
+}
@@ -0,0 +1,138 @@
+package signing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, alg := range []string{RS256, EdDSA} {
+		key, err := GenerateKey(alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := NewKeySet("", key)
+		token, err := keys.Sign(claims())
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, _, _ := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		if parsed.Header["alg"] != alg || parsed.Header["kid"] != key.ID {
+			t.Fatalf("%s: unexpected header %v", alg, parsed.Header)
+		}
+		if got, err := keys.Verify(token); err != nil || got["sub"] != "user-1" {
+			t.Fatalf("%s: verify failed: %v", alg, err)
+		}
+
+		jwks := keys.JWKS()
+		if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != key.ID || jwks.Keys[0].Algorithm != alg {
+			t.Fatalf("%s: unexpected JWKS %+v", alg, jwks)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old, _ := GenerateKey(EdDSA)
+	next, _ := GenerateKey(RS256)
+
+	oldToken, _ := NewKeySet("", old).Sign(claims())
+
+	// The new key signs while the old one still verifies its tokens
+	rotated := NewKeySet("", next, old)
+	if _, err := rotated.Verify(oldToken); err != nil {
+		t.Fatalf("expected the retired key to verify: %v", err)
+	}
+	newToken, _ := rotated.Sign(claims())
+	if _, err := NewKeySet("", old).Verify(newToken); err == nil {
+		t.Fatal("expected a key set without the new key to reject its tokens")
+	}
+
+	// Once removed, the old key's tokens are rejected
+	if _, err := NewKeySet("", next).Verify(oldToken); err == nil {
+		t.Fatal("expected the removed key's tokens to be rejected")
+	}
+}
+
+func TestAudience(t *testing.T) {
+	keys := NewKeySet("secret").WithAudience("https://tasks.example.com/mcp")
+
+	token, err := keys.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := keys.Verify(token); err != nil || got["aud"] != "https://tasks.example.com/mcp" {
+		t.Fatalf("own token: claims %v, err %v", got, err)
+	}
+
+	// Tokens for another service, or for none, are refused
+	other := claims()
+	other["aud"] = "https://other.example.com"
+	for name, c := range map[string]jwt.MapClaims{"other audience": other, "no audience": claims()} {
+		token, _ := NewKeySet("secret").Sign(c)
+		if _, err := keys.Verify(token); err == nil {
+			t.Errorf("%s: expected the token to be refused", name)
+		}
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	keys := NewKeySet("secret")
+	token, err := keys.Sign(claims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Verify(token); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys.JWKS().Keys) != 0 {
+		t.Fatal("the HMAC secret must never be published")
+	}
+	if _, err := NewKeySet("").Verify(token); err == nil {
+		t.Fatal("expected an empty key set to reject tokens")
+	}
+}
+
+func TestRejectsPublicKeyAsHMACSecret(t *testing.T) {
+	key, _ := GenerateKey(RS256)
+	pem, _ := key.MarshalPEM()
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString(pem)
+	if _, err := NewKeySet("", key).Verify(forged); err == nil {
+		t.Fatal("expected an HS256 token to be rejected without a secret")
+	}
+}
+
+func TestLoadAndKeygen(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "key.pem")
+	var out strings.Builder
+	if err := Keygen(t.Context(), []string{"-alg", RS256, "-out", file}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := Keygen(t.Context(), []string{"-out", file}, &out); err == nil {
+		t.Fatal("expected keygen to refuse to overwrite a key")
+	}
+	info, _ := os.Stat(file)
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected 0600, got %v", info.Mode().Perm())
+	}
+
+	keys, err := Load("", []string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Algorithm() != RS256 || !strings.Contains(out.String(), keys.SigningKey().ID) {
+		t.Fatalf("loaded %s key, keygen said %q", keys.Algorithm(), out.String())
+	}
+	if _, err := Load("", []string{file, file}); err == nil {
+		t.Fatal("expected a duplicate key to be rejected")
+	}
+}
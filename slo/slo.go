@@ -0,0 +1,334 @@
+// Package slo tracks latency and error objectives for REST routes and MCP
+// tools in process and raises alert events when an objective's error budget
+// burns too fast.
+//
+// Requests are counted in fixed time buckets covering the long window. The
+// burn rate of a window is the observed bad fraction divided by the allowed
+// bad fraction (1 - target); a burn rate of 1 spends the budget exactly over
+// the SLO period. An alert fires when both the short and the long window burn
+// faster than the configured threshold, and clears once the short window
+// drops back below it.
+package slo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/events"
+	"github.com/productivity/mcp-server/utils"
+	"github.com/productivity/mcp-server/webhook"
+)
+
+// Objective kinds
+const (
+	KindErrors  = "errors"
+	KindLatency = "latency"
+)
+
+// bucketsPerShortWindow sets the bucket granularity relative to the short window
+const bucketsPerShortWindow = 5
+
+// Tracker records request outcomes per target and evaluates burn rates
+type Tracker struct {
+	cfg        config.SLO
+	bucketSize time.Duration
+	numBuckets int
+	bus        *events.Bus
+	httpClient *http.Client
+	now        func() time.Time
+	// webhookSecret signs alert webhooks; empty sends them unsigned
+	webhookSecret string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	objective config.SLOObjective
+	buckets   []bucket
+	alerting  map[string]bool
+}
+
+type bucket struct {
+	index  int64
+	total  int
+	errors int
+	slow   int
+}
+
+// NewTracker creates a tracker publishing alerts on bus (and to the configured
+// webhook, if any)
+func NewTracker(cfg config.SLO, bus *events.Bus) *Tracker {
+	bucketSize := cfg.ShortWindow.Duration / bucketsPerShortWindow
+	if bucketSize < time.Second {
+		bucketSize = time.Second
+	}
+	numBuckets := int(cfg.LongWindow.Duration/bucketSize) + 1
+
+	return &Tracker{
+		cfg:        cfg,
+		bucketSize: bucketSize,
+		numBuckets: numBuckets,
+		bus:        bus,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+		series:     make(map[string]*series),
+	}
+}
+
+// SignWebhooks signs alert webhooks with secret
+func (t *Tracker) SignWebhooks(secret string) {
+	t.webhookSecret = secret
+}
+
+// objectiveFor returns the most specific objective matching target
+func (t *Tracker) objectiveFor(target string) (config.SLOObjective, bool) {
+	var best config.SLOObjective
+	bestLen := -1
+	for _, o := range t.cfg.Objectives {
+		switch {
+		case o.Target == target:
+			return o, true
+		case strings.HasSuffix(o.Target, "*"):
+			prefix := strings.TrimSuffix(o.Target, "*")
+			if strings.HasPrefix(target, prefix) && len(prefix) > bestLen {
+				best, bestLen = o, len(prefix)
+			}
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// Record counts one request to target. It is a no-op on a nil tracker or
+// when no objective matches.
+func (t *Tracker) Record(target string, latency time.Duration, failed bool) {
+	if t == nil || target == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.series[target]
+	if !ok {
+		objective, found := t.objectiveFor(target)
+		if !found {
+			return
+		}
+		s = &series{
+			objective: objective,
+			buckets:   make([]bucket, t.numBuckets),
+			alerting:  make(map[string]bool),
+		}
+		t.series[target] = s
+	}
+
+	index := t.now().UnixNano() / int64(t.bucketSize)
+	b := &s.buckets[index%int64(len(s.buckets))]
+	if b.index != index {
+		*b = bucket{index: index}
+	}
+	b.total++
+	if failed {
+		b.errors++
+	}
+	if latency > s.objective.Latency.Duration {
+		b.slow++
+	}
+}
+
+// WindowStats summarises one window of a target
+type WindowStats struct {
+	Requests        int     `json:"requests"`
+	ErrorRate       float64 `json:"error_rate"`
+	SlowRate        float64 `json:"slow_rate"`
+	ErrorBurnRate   float64 `json:"error_burn_rate"`
+	LatencyBurnRate float64 `json:"latency_burn_rate"`
+}
+
+// Status is the SLO status of one target
+type Status struct {
+	Target            string      `json:"target"`
+	Objective         string      `json:"objective"`
+	LatencyThreshold  string      `json:"latency_threshold"`
+	LatencyTarget     float64     `json:"latency_target"`
+	ErrorTarget       float64     `json:"error_target"`
+	LatencyCompliance float64     `json:"latency_compliance"` // over the long window
+	ErrorCompliance   float64     `json:"error_compliance"`   // over the long window
+	Short             WindowStats `json:"short_window"`
+	Long              WindowStats `json:"long_window"`
+	Alerting          []string    `json:"alerting"`
+}
+
+func (t *Tracker) window(s *series, d time.Duration, now time.Time) WindowStats {
+	current := now.UnixNano() / int64(t.bucketSize)
+	oldest := current - int64(d/t.bucketSize)
+	var total, errs, slow int
+	for _, b := range s.buckets {
+		if b.total > 0 && b.index > oldest && b.index <= current {
+			total += b.total
+			errs += b.errors
+			slow += b.slow
+		}
+	}
+
+	w := WindowStats{Requests: total}
+	if total > 0 {
+		w.ErrorRate = float64(errs) / float64(total)
+		w.SlowRate = float64(slow) / float64(total)
+		w.ErrorBurnRate = w.ErrorRate / (1 - s.objective.ErrorTarget)
+		w.LatencyBurnRate = w.SlowRate / (1 - s.objective.LatencyTarget)
+	}
+	return w
+}
+
+func (t *Tracker) status(target string, s *series, now time.Time) Status {
+	short := t.window(s, t.cfg.ShortWindow.Duration, now)
+	long := t.window(s, t.cfg.LongWindow.Duration, now)
+
+	st := Status{
+		Target:            target,
+		Objective:         s.objective.Target,
+		LatencyThreshold:  s.objective.Latency.String(),
+		LatencyTarget:     s.objective.LatencyTarget,
+		ErrorTarget:       s.objective.ErrorTarget,
+		LatencyCompliance: 1 - long.SlowRate,
+		ErrorCompliance:   1 - long.ErrorRate,
+		Short:             short,
+		Long:              long,
+		Alerting:          []string{},
+	}
+	for _, kind := range []string{KindErrors, KindLatency} {
+		if s.alerting[kind] {
+			st.Alerting = append(st.Alerting, kind)
+		}
+	}
+	return st
+}
+
+// Status returns the status of every tracked target, sorted by target
+func (t *Tracker) Status() []Status {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	out := make([]Status, 0, len(t.series))
+	for target, s := range t.series {
+		out = append(out, t.status(target, s, now))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Target < out[j].Target })
+	return out
+}
+
+// Evaluate checks every target's burn rates, publishing an alert when an
+// objective starts burning too fast and a recovery event when it stops
+func (t *Tracker) Evaluate() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	now := t.now()
+	var fired []events.Event
+	for target, s := range t.series {
+		st := t.status(target, s, now)
+		for _, kind := range []string{KindErrors, KindLatency} {
+			shortBurn, longBurn := st.Short.ErrorBurnRate, st.Long.ErrorBurnRate
+			if kind == KindLatency {
+				shortBurn, longBurn = st.Short.LatencyBurnRate, st.Long.LatencyBurnRate
+			}
+
+			threshold := t.cfg.BurnRateThreshold
+			breached := st.Long.Requests >= t.cfg.MinRequests && shortBurn > threshold && longBurn > threshold
+			eventType := ""
+			switch {
+			case breached && !s.alerting[kind]:
+				s.alerting[kind] = true
+				eventType = events.TypeSLOBurnRateAlert
+			case s.alerting[kind] && shortBurn <= threshold:
+				s.alerting[kind] = false
+				eventType = events.TypeSLORecovered
+			}
+			if eventType == "" {
+				continue
+			}
+
+			fired = append(fired, events.Event{
+				Type: eventType,
+				Data: map[string]interface{}{
+					"target":             target,
+					"objective":          s.objective.Target,
+					"kind":               kind,
+					"short_burn_rate":    shortBurn,
+					"long_burn_rate":     longBurn,
+					"threshold":          threshold,
+					"short_window":       t.cfg.ShortWindow.String(),
+					"long_window":        t.cfg.LongWindow.String(),
+					"requests":           st.Long.Requests,
+					"error_compliance":   st.ErrorCompliance,
+					"latency_compliance": st.LatencyCompliance,
+				},
+			})
+		}
+	}
+	t.mu.Unlock()
+
+	for _, e := range fired {
+		e = t.bus.Publish(e)
+		t.notify(e)
+	}
+}
+
+// notify posts an alert event to the configured webhook in the background
+func (t *Tracker) notify(e events.Event) {
+	if t.cfg.AlertWebhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(events.NewEnvelope(e))
+	if err != nil {
+		return
+	}
+	go func() {
+		logger := utils.DefaultLogger().WithFields(map[string]interface{}{"event": e.Type, "seq": e.Seq})
+		req, err := http.NewRequest(http.MethodPost, t.cfg.AlertWebhookURL, bytes.NewReader(body))
+		if err != nil {
+			logger.Error("Failed to send SLO alert webhook", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		webhook.SignRequest(req, t.webhookSecret, body)
+		resp, err := t.httpClient.Do(req)
+		if err != nil {
+			logger.Error("Failed to send SLO alert webhook", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			logger.Warn("SLO alert webhook refused", map[string]interface{}{"status": resp.StatusCode})
+		}
+	}()
+}
+
+// Run evaluates burn rates every interval until ctx is cancelled
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.Evaluate()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+	"github.com/productivity/mcp-server/events"
+)
+
+func testConfig() config.SLO {
+	return config.SLO{
+		Objectives: []config.SLOObjective{
+			{Target: "*", Latency: config.Duration{Duration: time.Second}, LatencyTarget: 0.99, ErrorTarget: 0.99},
+			{Target: "tool:*", Latency: config.Duration{Duration: 10 * time.Second}, LatencyTarget: 0.9, ErrorTarget: 0.9},
+			{Target: "tool:create_task", Latency: config.Duration{Duration: 2 * time.Second}, LatencyTarget: 0.9, ErrorTarget: 0.9},
+		},
+		ShortWindow:       config.Duration{Duration: 5 * time.Minute},
+		LongWindow:        config.Duration{Duration: time.Hour},
+		BurnRateThreshold: 5,
+		MinRequests:       10,
+	}
+}
+
+func TestObjectiveMatching(t *testing.T) {
+	tracker := NewTracker(testConfig(), nil)
+	cases := map[string]string{
+		"GET /api/tasks":   "*",
+		"tool:list_tasks":  "tool:*",
+		"tool:create_task": "tool:create_task",
+	}
+	for target, want := range cases {
+		o, ok := tracker.objectiveFor(target)
+		if !ok || o.Target != want {
+			t.Errorf("objectiveFor(%q) = %q, want %q", target, o.Target, want)
+		}
+	}
+}
+
+func TestBurnRateAlertAndRecovery(t *testing.T) {
+	bus := events.NewBus(0)
+	var fired []events.Event
+	bus.Subscribe(func(e events.Event) { fired = append(fired, e) })
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewTracker(testConfig(), bus)
+	tracker.now = func() time.Time { return now }
+
+	// Half the requests fail: a burn rate of 50 against a 1% error budget
+	for i := 0; i < 20; i++ {
+		tracker.Record("GET /api/tasks", 10*time.Millisecond, i%2 == 0)
+	}
+	tracker.Evaluate()
+	tracker.Evaluate() // no duplicate alert while still burning
+
+	if len(fired) != 1 || fired[0].Type != events.TypeSLOBurnRateAlert || fired[0].Data["kind"] != KindErrors {
+		t.Fatalf("expected one error burn alert, got %+v", fired)
+	}
+	if st := tracker.Status(); len(st) != 1 || len(st[0].Alerting) != 1 {
+		t.Fatalf("expected the target to be alerting, got %+v", st)
+	}
+
+	// Once the failures age out of the short window the alert clears
+	now = now.Add(10 * time.Minute)
+	for i := 0; i < 20; i++ {
+		tracker.Record("GET /api/tasks", 10*time.Millisecond, false)
+	}
+	tracker.Evaluate()
+
+	if len(fired) != 2 || fired[1].Type != events.TypeSLORecovered {
+		t.Fatalf("expected a recovery event, got %+v", fired)
+	}
+}
+
+func TestLatencyBurnNeedsMinRequests(t *testing.T) {
+	bus := events.NewBus(0)
+	var fired []events.Event
+	bus.Subscribe(func(e events.Event) { fired = append(fired, e) })
+
+	tracker := NewTracker(testConfig(), bus)
+	for i := 0; i < 5; i++ {
+		tracker.Record("tool:create_task", 3*time.Second, false)
+	}
+	tracker.Evaluate()
+	if len(fired) != 0 {
+		t.Fatalf("expected no alert below min_requests, got %+v", fired)
+	}
+
+	for i := 0; i < 5; i++ {
+		tracker.Record("tool:create_task", 3*time.Second, false)
+	}
+	tracker.Evaluate()
+	if len(fired) != 1 || fired[0].Data["kind"] != KindLatency {
+		t.Fatalf("expected a latency burn alert, got %+v", fired)
+	}
+}
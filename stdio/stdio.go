@@ -0,0 +1,274 @@
+// Package stdio serves the MCP protocol over newline-delimited JSON-RPC on
+// stdin/stdout, as used by Claude Desktop for local subprocess servers. Each
+// message is answered by the same /mcp routes the HTTP server exposes.
+package stdio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// methodCancelled is the notification a client sends to abandon a request
+const methodCancelled = "notifications/cancelled"
+
+// maxMessageSize bounds a single JSON-RPC message
+const maxMessageSize = 4 << 20
+
+// Server dispatches stdio MCP messages to an HTTP handler serving the
+// /mcp/initialize, /mcp/list_tools and /mcp/call_tool routes
+type Server struct {
+	handler http.Handler
+
+	mu             sync.Mutex
+	inflightID     string
+	inflightCancel context.CancelFunc
+}
+
+// NewServer creates a server dispatching to handler
+func NewServer(handler http.Handler) *Server {
+	return &Server{handler: handler}
+}
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// inboxSize is how many requests can queue behind the one being served while
+// the reader keeps watching for cancellations
+const inboxSize = 64
+
+// inbound is a request read from stdin, or a line that was not valid JSON
+type inbound struct {
+	msg        message
+	parseError bool
+}
+
+// Serve reads messages from in and writes responses to out until in is
+// exhausted or ctx is cancelled. Requests are answered one at a time, in
+// order, while a separate reader handles notifications/cancelled so a call
+// the client abandons stops at once and gets no response.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	inbox := make(chan inbound, inboxSize)
+	readErr := make(chan error, 1)
+	go s.read(ctx, in, inbox, readErr)
+
+	for {
+		var next inbound
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case next, ok = <-inbox:
+		}
+		if !ok {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return <-readErr
+		}
+		if next.parseError {
+			writeResponse(out, response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "Parse error"}})
+			continue
+		}
+
+		callCtx, cancel := context.WithCancel(ctx)
+		s.track(next.msg.ID, cancel)
+		result, rerr := s.dispatch(callCtx, next.msg)
+		s.track(next.msg.ID, nil)
+		cancelled := callCtx.Err() != nil && ctx.Err() == nil
+		cancel()
+
+		if !cancelled {
+			writeResponse(out, response{JSONRPC: "2.0", ID: next.msg.ID, Result: result, Error: rerr})
+		}
+	}
+}
+
+// read scans messages from in into inbox, acting on cancellations itself
+func (s *Server) read(ctx context.Context, in io.Reader, inbox chan<- inbound, readErr chan<- error) {
+	defer close(inbox)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var next inbound
+		if err := json.Unmarshal(line, &next.msg); err != nil {
+			next = inbound{parseError: true}
+		} else if next.msg.Method == methodCancelled {
+			s.cancel(next.msg.Params)
+			continue
+		} else if len(next.msg.ID) == 0 {
+			// Other notifications (no id) never get a response
+			continue
+		}
+
+		select {
+		case inbox <- next:
+		case <-ctx.Done():
+			return
+		}
+	}
+	readErr <- scanner.Err()
+}
+
+// track records the cancel func of the request being served; nil clears it
+func (s *Server) track(id json.RawMessage, cancel context.CancelFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel == nil {
+		s.inflightID, s.inflightCancel = "", nil
+		return
+	}
+	s.inflightID, s.inflightCancel = string(id), cancel
+}
+
+// cancel stops the request named by a notifications/cancelled message if it
+// is the one being served; a request that already finished is ignored
+func (s *Server) cancel(params json.RawMessage) {
+	var p struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(params, &p) != nil || len(p.RequestID) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inflightCancel != nil && s.inflightID == string(p.RequestID) {
+		s.inflightCancel()
+	}
+}
+
+func (s *Server) dispatch(ctx context.Context, msg message) (interface{}, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		// Forward the params so the protocol version is negotiated with the client's
+		return s.forward(ctx, "/mcp/initialize", map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"method":  msg.Method,
+			"params":  msg.Params,
+		})
+
+	case "ping":
+		return struct{}{}, nil
+
+	case "tools/list":
+		return s.forward(ctx, "/mcp/list_tools", nil)
+
+	case "tools/call":
+		var params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "Invalid params: tools/call params must be an object with a name and arguments"}
+		}
+		return s.callTool(ctx, params.Name, params.Arguments)
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "Method not found: " + msg.Method}
+}
+
+// callTool runs a tool and returns its outcome as MCP content, passing through
+// results the route already shaped as content and wrapping any other. Tool
+// failures are reported in the result with isError so the model can see them;
+// arguments that break the tool's schema stay an Invalid params error.
+func (s *Server) callTool(ctx context.Context, name string, args map[string]interface{}) (interface{}, *rpcError) {
+	body := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1, // the stdio message id is restored in the reply
+		"method":  name,
+		"params":  args,
+	}
+
+	result, rerr := s.forward(ctx, "/mcp/call_tool", body)
+	if rerr != nil && rerr.Code == codeInvalidParams {
+		return nil, rerr
+	}
+	if rerr != nil {
+		return toolResult(rerr.Message, true), nil
+	}
+	if shaped, ok := result.(map[string]interface{}); ok && shaped["content"] != nil {
+		return shaped, nil
+	}
+	text, _ := json.Marshal(result)
+	return toolResult(string(text), false), nil
+}
+
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// forward posts body to path and returns the JSON-RPC result it answers with
+func (s *Server) forward(ctx context.Context, path string, body interface{}) (interface{}, *rpcError) {
+	rec := s.serve(ctx, path, body)
+	var resp struct {
+		Result interface{} `json:"result"`
+		Error  *rpcError   `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		return nil, &rpcError{Code: codeInternalError, Message: strings.TrimSpace(rec.Body.String())}
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Result, nil
+}
+
+func (s *Server) serve(ctx context.Context, path string, body interface{}) *httptest.ResponseRecorder {
+	data := []byte("{}")
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func writeResponse(out io.Writer, resp response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(response{JSONRPC: "2.0", ID: resp.ID, Error: &rpcError{Code: codeInternalError, Message: err.Error()}})
+	}
+	out.Write(append(data, '\n'))
+}
@@ -0,0 +1,118 @@
+package stdio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServeTranslatesMCPMessages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/mcp/initialize", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"jsonrpc": "2.0", "id": 1, "result": gin.H{"protocolVersion": "2024-11-05"}})
+	})
+	router.POST("/mcp/call_tool", func(c *gin.Context) {
+		var req struct {
+			Method string                 `json:"method"`
+			Params map[string]interface{} `json:"params"`
+		}
+		c.ShouldBindJSON(&req)
+		if req.Method == "parse_task" {
+			c.JSON(http.StatusOK, gin.H{"jsonrpc": "2.0", "id": 1, "result": gin.H{
+				"content": []gin.H{{"type": "text", "text": "already shaped"}},
+				"isError": false,
+			}})
+			return
+		}
+		if req.Method != "create_task" {
+			c.JSON(http.StatusBadRequest, gin.H{"jsonrpc": "2.0", "id": 1, "error": gin.H{"code": -32601, "message": "Unknown method: " + req.Method}})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"jsonrpc": "2.0", "id": 1, "result": gin.H{"title": req.Params["title"]}})
+	})
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"tools/call","params":{"name":"create_task","arguments":{"title":"Ship it"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"nope"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"parse_task","arguments":{}}}`,
+		`not json`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := NewServer(router).Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var reply map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &reply); err != nil {
+			t.Fatalf("invalid reply %q: %v", line, err)
+		}
+		replies = append(replies, reply)
+	}
+	if len(replies) != 6 {
+		t.Fatalf("expected 6 replies (none for the notification), got %d:\n%s", len(replies), out.String())
+	}
+
+	if replies[1]["id"] != "two" {
+		t.Errorf("reply id = %v, want the request's id", replies[1]["id"])
+	}
+	result := replies[1]["result"].(map[string]interface{})
+	content := result["content"].([]interface{})[0].(map[string]interface{})
+	if result["isError"] != false || !strings.Contains(content["text"].(string), "Ship it") {
+		t.Errorf("unexpected tool result %v", result)
+	}
+
+	if failed := replies[2]["result"].(map[string]interface{}); failed["isError"] != true {
+		t.Errorf("expected a tool error result, got %v", replies[2])
+	}
+	if replies[3]["error"] == nil || replies[5]["error"] == nil {
+		t.Errorf("expected errors for an unknown method and bad JSON, got %v and %v", replies[3], replies[5])
+	}
+
+	shaped := replies[4]["result"].(map[string]interface{})["content"].([]interface{})
+	if len(shaped) != 1 || shaped[0].(map[string]interface{})["text"] != "already shaped" {
+		t.Errorf("expected shaped content to pass through, got %v", replies[4])
+	}
+}
+
+func TestServeCancelsInFlightRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	started := make(chan struct{})
+	router := gin.New()
+	router.POST("/mcp/call_tool", func(c *gin.Context) {
+		close(started)
+		<-c.Request.Context().Done()
+		c.JSON(http.StatusOK, gin.H{"jsonrpc": "2.0", "id": 1, "result": gin.H{"too": "late"}})
+	})
+
+	in, input := io.Pipe()
+	var out bytes.Buffer
+	done := make(chan error)
+	go func() { done <- NewServer(router).Serve(context.Background(), in, &out) }()
+
+	io.WriteString(input, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"parse_file","arguments":{}}}`+"\n")
+	<-started
+	io.WriteString(input, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1,"reason":"user stopped"}}`+"\n")
+	io.WriteString(input, `{"jsonrpc":"2.0","id":2,"method":"ping"}`+"\n")
+	input.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The cancelled call gets no response; the next request is still served
+	if got := strings.TrimSpace(out.String()); got != `{"jsonrpc":"2.0","id":2,"result":{}}` {
+		t.Errorf("output = %s", got)
+	}
+}
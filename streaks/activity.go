@@ -0,0 +1,82 @@
+package streaks
+
+import "time"
+
+// Activity is a user's running record of days with at least one completed
+// task. It is updated one completion at a time, so it never needs the full
+// history.
+type Activity struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+	Total   int `json:"total"`
+	// LastDay is the latest day with a completion, at midnight in the user's
+	// time zone; zero before the first
+	LastDay time.Time `json:"last_day"`
+}
+
+// Record adds a completion on day. A completion on the day after LastDay
+// extends the streak and one after a gap starts a new one; a late
+// completion for an earlier day only counts towards Total.
+func (a Activity) Record(day time.Time) Activity {
+	day = periodStart(Daily, day)
+	a.Total++
+	switch {
+	case a.LastDay.IsZero() || day.After(nextPeriod(Daily, a.LastDay)):
+		a.Current = 1
+	case day.Equal(nextPeriod(Daily, a.LastDay)):
+		a.Current++
+	default:
+		return a
+	}
+	a.LastDay = day
+	if a.Current > a.Longest {
+		a.Longest = a.Current
+	}
+	return a
+}
+
+// CurrentOn is the streak as of today. Today is still in progress, so a
+// streak last extended yesterday is intact; one older than that has lapsed.
+func (a Activity) CurrentOn(today time.Time) int {
+	if a.LastDay.IsZero() {
+		return 0
+	}
+	yesterday := periodStart(Daily, today).AddDate(0, 0, -1)
+	if a.LastDay.Before(yesterday) {
+		return 0
+	}
+	return a.Current
+}
+
+// Badge is an achievement earned through completions
+type Badge struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// badgeRules lists every badge with what earns it. Badges depend only on the
+// totals and the longest streak, which never decrease, so a badge once
+// earned is never lost.
+var badgeRules = []struct {
+	Badge
+	earned func(Activity) bool
+}{
+	{Badge{"first_task", "First Step", "Completed a first task"}, func(a Activity) bool { return a.Total >= 1 }},
+	{Badge{"ten_tasks", "Getting Things Done", "Completed 10 tasks"}, func(a Activity) bool { return a.Total >= 10 }},
+	{Badge{"hundred_tasks", "Centurion", "Completed 100 tasks"}, func(a Activity) bool { return a.Total >= 100 }},
+	{Badge{"streak_3", "On a Roll", "Completed tasks 3 days in a row"}, func(a Activity) bool { return a.Longest >= 3 }},
+	{Badge{"streak_7", "Week Warrior", "Completed tasks 7 days in a row"}, func(a Activity) bool { return a.Longest >= 7 }},
+	{Badge{"streak_30", "Unstoppable", "Completed tasks 30 days in a row"}, func(a Activity) bool { return a.Longest >= 30 }},
+}
+
+// Badges returns the badges a has earned, in the order they are usually reached
+func Badges(a Activity) []Badge {
+	badges := []Badge{}
+	for _, rule := range badgeRules {
+		if rule.earned(a) {
+			badges = append(badges, rule.Badge)
+		}
+	}
+	return badges
+}
@@ -0,0 +1,129 @@
+// Package streaks computes habit streaks and recurrence misses with grace rules
+// (weekly freezes and weekend exemptions) so a single missed day doesn't reset
+// a long streak. It also keeps a user's overall completion streak and badges.
+package streaks
+
+import "time"
+
+// Period is the unit a habit recurs in
+type Period string
+
+const (
+	Daily   Period = "daily"
+	Weekly  Period = "weekly"
+	Monthly Period = "monthly"
+)
+
+// MaxLookback bounds how far back streaks are computed
+const MaxLookback = 366 * 24 * time.Hour
+
+// GraceRules soften how missed days are counted. They only apply to daily habits.
+type GraceRules struct {
+	FreezesPerWeek int  `json:"freezes_per_week"`
+	WeekendExempt  bool `json:"weekend_exempt"`
+}
+
+// Result is the outcome of a streak computation
+type Result struct {
+	Current     int         `json:"current"`
+	Longest     int         `json:"longest"`
+	Misses      []time.Time `json:"misses"`
+	FreezesUsed []time.Time `json:"freezes_used"`
+	// NewFreezes are freezes consumed by this computation that were not in the
+	// previously used set; callers persist them and emit events.
+	NewFreezes []time.Time `json:"-"`
+}
+
+// ParsePeriod maps a recurring_frequency value to a Period, defaulting to Daily
+func ParsePeriod(frequency string) Period {
+	switch Period(frequency) {
+	case Weekly, Monthly:
+		return Period(frequency)
+	}
+	return Daily
+}
+
+// Compute walks every period from start through today and returns the streak.
+// completions and usedFreezes may contain any time within a period; today's
+// period never counts as a miss because it is still in progress.
+func Compute(period Period, start, today time.Time, completions, usedFreezes []time.Time, rules GraceRules) Result {
+	loc := today.Location()
+	if earliest := today.Add(-MaxLookback); start.Before(earliest) {
+		start = earliest
+	}
+
+	done := make(map[time.Time]bool, len(completions))
+	for _, t := range completions {
+		done[periodStart(period, t.In(loc))] = true
+	}
+	frozen := make(map[time.Time]bool, len(usedFreezes))
+	freezesInWeek := make(map[time.Time]int)
+	for _, t := range usedFreezes {
+		day := periodStart(Daily, t.In(loc))
+		if !frozen[day] {
+			frozen[day] = true
+			freezesInWeek[periodStart(Weekly, day)]++
+		}
+	}
+
+	result := Result{Misses: []time.Time{}, FreezesUsed: []time.Time{}}
+	current := periodStart(period, today.In(loc))
+	run := 0
+
+	for p := periodStart(period, start.In(loc)); !p.After(current); p = nextPeriod(period, p) {
+		switch {
+		case done[p]:
+			run++
+			if run > result.Longest {
+				result.Longest = run
+			}
+		case p.Equal(current):
+			// still in progress
+		case period != Daily:
+			result.Misses = append(result.Misses, p)
+			run = 0
+		case rules.WeekendExempt && isWeekend(p):
+			// exempt days neither extend nor break the streak
+		case frozen[p]:
+			result.FreezesUsed = append(result.FreezesUsed, p)
+		case freezesInWeek[periodStart(Weekly, p)] < rules.FreezesPerWeek:
+			freezesInWeek[periodStart(Weekly, p)]++
+			result.FreezesUsed = append(result.FreezesUsed, p)
+			result.NewFreezes = append(result.NewFreezes, p)
+		default:
+			result.Misses = append(result.Misses, p)
+			run = 0
+		}
+	}
+
+	result.Current = run
+	return result
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+// periodStart truncates t to the start of its period (weeks start on Monday)
+func periodStart(period Period, t time.Time) time.Time {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	switch period {
+	case Weekly:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case Monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	}
+	return day
+}
+
+func nextPeriod(period Period, t time.Time) time.Time {
+	switch period {
+	case Weekly:
+		return t.AddDate(0, 0, 7)
+	case Monthly:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
@@ -0,0 +1,107 @@
+package streaks
+
+import (
+	"testing"
+	"time"
+)
+
+func day(d int) time.Time {
+	// March 2025: the 3rd is a Monday
+	return time.Date(2025, 3, d, 9, 0, 0, 0, time.UTC)
+}
+
+func TestComputeWithoutGrace(t *testing.T) {
+	completions := []time.Time{day(3), day(4), day(6), day(7)}
+	res := Compute(Daily, day(3), day(8), completions, nil, GraceRules{})
+
+	if res.Current != 2 || res.Longest != 2 {
+		t.Fatalf("expected current=2 longest=2, got %+v", res)
+	}
+	if len(res.Misses) != 1 || !res.Misses[0].Equal(periodStart(Daily, day(5))) {
+		t.Fatalf("expected a single miss on the 5th, got %v", res.Misses)
+	}
+}
+
+func TestComputeConsumesOneFreezePerWeek(t *testing.T) {
+	// Misses on Wed 5th and Thu 6th: only the first is covered by the weekly freeze
+	completions := []time.Time{day(3), day(4), day(7)}
+	res := Compute(Daily, day(3), day(7), completions, nil, GraceRules{FreezesPerWeek: 1})
+
+	if len(res.NewFreezes) != 1 || !res.NewFreezes[0].Equal(periodStart(Daily, day(5))) {
+		t.Fatalf("expected a new freeze on the 5th, got %v", res.NewFreezes)
+	}
+	if len(res.Misses) != 1 || res.Current != 1 {
+		t.Fatalf("expected the 6th to break the streak, got %+v", res)
+	}
+
+	// Recomputing with the freeze already recorded must not consume it again
+	again := Compute(Daily, day(3), day(7), completions, res.NewFreezes, GraceRules{FreezesPerWeek: 1})
+	if len(again.NewFreezes) != 0 || len(again.FreezesUsed) != 1 {
+		t.Fatalf("expected the recorded freeze to be reused, got %+v", again)
+	}
+}
+
+func TestComputeWeekendExempt(t *testing.T) {
+	// Fri 7th and Mon 10th completed; the weekend in between is exempt
+	completions := []time.Time{day(7), day(10)}
+	res := Compute(Daily, day(7), day(10), completions, nil, GraceRules{WeekendExempt: true})
+
+	if res.Current != 2 || len(res.Misses) != 0 {
+		t.Fatalf("expected weekend to be skipped, got %+v", res)
+	}
+}
+
+func TestComputeWeekly(t *testing.T) {
+	completions := []time.Time{day(4), day(18)}
+	res := Compute(Weekly, day(3), day(19), completions, nil, GraceRules{FreezesPerWeek: 1})
+
+	if res.Current != 1 || len(res.Misses) != 1 {
+		t.Fatalf("expected the week of the 10th to be missed, got %+v", res)
+	}
+}
+
+func TestActivityRecord(t *testing.T) {
+	var a Activity
+	for _, d := range []int{3, 3, 4, 5, 7, 8} {
+		a = a.Record(day(d))
+	}
+	if a.Current != 2 || a.Longest != 3 || a.Total != 6 || !a.LastDay.Equal(periodStart(Daily, day(8))) {
+		t.Fatalf("unexpected activity %+v", a)
+	}
+
+	// A late completion for an earlier day only adds to the total
+	late := a.Record(day(6))
+	if late.Current != 2 || late.Total != 7 || !late.LastDay.Equal(a.LastDay) {
+		t.Fatalf("late completion changed the streak: %+v", late)
+	}
+
+	if got := a.CurrentOn(day(9)); got != 2 {
+		t.Errorf("the day after: current = %d, want 2", got)
+	}
+	if got := a.CurrentOn(day(10)); got != 0 {
+		t.Errorf("after a missed day: current = %d, want 0", got)
+	}
+}
+
+func TestBadges(t *testing.T) {
+	ids := func(a Activity) []string {
+		var out []string
+		for _, b := range Badges(a) {
+			out = append(out, b.ID)
+		}
+		return out
+	}
+	if got := ids(Activity{}); len(got) != 0 {
+		t.Errorf("no completions: got %v", got)
+	}
+	got := ids(Activity{Total: 12, Longest: 7})
+	want := []string{"first_task", "ten_tasks", "streak_3", "streak_7"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package transcription turns recorded speech into text through the
+// /v1/audio/transcriptions API that OpenAI's Whisper introduced and
+// self-hosted Whisper servers (faster-whisper-server, whisper.cpp, LocalAI)
+// also serve.
+package transcription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/productivity/mcp-server/config"
+)
+
+// Provider defaults
+const (
+	DefaultOpenAIURL    = "https://api.openai.com"
+	DefaultOpenAIModel  = "whisper-1"
+	DefaultWhisperModel = "whisper-1"
+)
+
+// MaxAudioBytes is the largest recording accepted, OpenAI's limit
+const MaxAudioBytes = 25 << 20
+
+// Formats are the audio file extensions the API accepts
+var Formats = []string{".flac", ".m4a", ".mp3", ".mp4", ".mpeg", ".mpga", ".oga", ".ogg", ".wav", ".webm"}
+
+// Transcript is the text of a recording
+type Transcript struct {
+	Text string `json:"text"`
+	// Language is the spoken language the model detected, as it names it
+	Language string `json:"language,omitempty"`
+	// Duration is the recording's length in seconds, when the model says
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// Transcriber transcribes recordings
+type Transcriber interface {
+	// Transcribe reads audio, named fileName so its format is known, and
+	// returns what was said. language, an ISO 639-1 code, is a hint; empty
+	// means detect it.
+	Transcribe(ctx context.Context, fileName string, audio io.Reader, language string) (*Transcript, error)
+	Model() string
+}
+
+// New creates the transcriber selected by cfg.Provider, or nil if
+// transcription is off
+func New(cfg config.Transcription) (Transcriber, error) {
+	switch cfg.Provider {
+	case "", "none":
+		return nil, nil
+	case "openai":
+		return newHTTPTranscriber(cfg, DefaultOpenAIURL, DefaultOpenAIModel), nil
+	case "whisper":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("TRANSCRIPTION_URL is required for the whisper provider")
+		}
+		return newHTTPTranscriber(cfg, "", DefaultWhisperModel), nil
+	default:
+		return nil, fmt.Errorf("unknown TRANSCRIPTION_PROVIDER %q (expected none, openai or whisper)", cfg.Provider)
+	}
+}
+
+// SupportedFormat reports whether fileName has an extension the API accepts
+func SupportedFormat(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, format := range Formats {
+		if ext == format {
+			return true
+		}
+	}
+	return false
+}
+
+// httpTranscriber calls /v1/audio/transcriptions
+type httpTranscriber struct {
+	url        string
+	model      string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newHTTPTranscriber(cfg config.Transcription, defaultURL, defaultModel string) *httpTranscriber {
+	t := &httpTranscriber{
+		url:        cfg.URL,
+		model:      cfg.Model,
+		apiKey:     cfg.APIKey,
+		httpClient: &http.Client{Timeout: cfg.Timeout.Duration},
+	}
+	if t.url == "" {
+		t.url = defaultURL
+	}
+	if t.model == "" {
+		t.model = defaultModel
+	}
+	return t
+}
+
+func (t *httpTranscriber) Model() string {
+	return t.model
+}
+
+func (t *httpTranscriber) Transcribe(ctx context.Context, fileName string, audio io.Reader, language string) (*Transcript, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(fileName))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+	fields := map[string]string{"model": t.model, "response_format": "verbose_json"}
+	if language != "" {
+		fields["language"] = language
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url+"/v1/audio/transcriptions", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("transcription request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcription response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transcription API returned %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	// verbose_json adds the language and duration; servers that only know
+	// json send the text alone, which decodes all the same
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return nil, fmt.Errorf("failed to decode transcription response: %w", err)
+	}
+	transcript.Text = strings.TrimSpace(transcript.Text)
+	return &transcript, nil
+}
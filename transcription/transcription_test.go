@@ -0,0 +1,71 @@
+package transcription
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+)
+
+func TestTranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		audio, _ := io.ReadAll(file)
+		if header.Filename != "memo.m4a" || string(audio) != "RIFF" {
+			t.Errorf("file %q: %q", header.Filename, audio)
+		}
+		if r.FormValue("model") != "whisper-large" || r.FormValue("language") != "de" || r.FormValue("response_format") != "verbose_json" {
+			t.Errorf("fields: %v", r.MultipartForm.Value)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"text": " Call the bank tomorrow. ", "language": "german", "duration": 2.5})
+	}))
+	defer server.Close()
+
+	transcriber, err := New(config.Transcription{
+		Provider: "whisper",
+		URL:      server.URL,
+		Model:    "whisper-large",
+		APIKey:   "key",
+		Timeout:  config.Duration{Duration: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := transcriber.Transcribe(context.Background(), "/tmp/memo.m4a", strings.NewReader("RIFF"), "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transcript.Text != "Call the bank tomorrow." || transcript.Language != "german" || transcript.Duration != 2.5 {
+		t.Errorf("transcript: %+v", transcript)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if transcriber, err := New(config.Transcription{Provider: "none"}); err != nil || transcriber != nil {
+		t.Errorf("none: %v, %v", transcriber, err)
+	}
+	if transcriber, err := New(config.Transcription{Provider: "openai", APIKey: "key"}); err != nil || transcriber.Model() != DefaultOpenAIModel {
+		t.Errorf("openai: %v, %v", transcriber, err)
+	}
+	if _, err := New(config.Transcription{Provider: "whisper"}); err == nil {
+		t.Error("whisper without a URL: expected an error")
+	}
+	if _, err := New(config.Transcription{Provider: "siri"}); err == nil {
+		t.Error("unknown provider: expected an error")
+	}
+	if !SupportedFormat("Memo.M4A") || SupportedFormat("memo.txt") {
+		t.Error("SupportedFormat")
+	}
+}
@@ -72,6 +72,10 @@ var (
 	ErrCodeRateLimit    = "RATE_LIMIT_EXCEEDED"
 	ErrCodeTimeout      = "TIMEOUT"
 	ErrCodeBadRequest   = "BAD_REQUEST"
+	ErrCodeConflict     = "CONFLICT"
+	ErrCodeTooLarge     = "PAYLOAD_TOO_LARGE"
+	ErrCodePlanLimit    = "PLAN_LIMIT_EXCEEDED"
+	ErrCodeGone         = "GONE"
 )
 
 // Common error constructors
@@ -79,6 +83,10 @@ func ErrValidation(message string) *AppError {
 	return NewAppError(ErrCodeValidation, message, http.StatusBadRequest)
 }
 
+func ErrBadRequest(message string) *AppError {
+	return NewAppError(ErrCodeBadRequest, message, http.StatusBadRequest)
+}
+
 func ErrNotFound(resource string) *AppError {
 	return NewAppError(ErrCodeNotFound, fmt.Sprintf("%s not found", resource), http.StatusNotFound)
 }
@@ -97,6 +105,38 @@ func ErrForbidden(message string) *AppError {
 	return NewAppError(ErrCodeForbidden, message, http.StatusForbidden)
 }
 
+func ErrConflict(message string) *AppError {
+	return NewAppError(ErrCodeConflict, message, http.StatusConflict)
+}
+
+// ErrGone reports something the client held on to that is no longer served
+func ErrGone(message string) *AppError {
+	return NewAppError(ErrCodeGone, message, http.StatusGone)
+}
+
+// ErrTooLarge reports a request body over limit bytes
+func ErrTooLarge(limit int64) *AppError {
+	return NewAppError(ErrCodeTooLarge, fmt.Sprintf("request body must be at most %s", FormatBytes(limit)),
+		http.StatusRequestEntityTooLarge).WithField("max_bytes", limit)
+}
+
+// ErrPlanLimit reports a request over a limit of the caller's plan that
+// upgrading would lift
+func ErrPlanLimit(message string) *AppError {
+	return NewAppError(ErrCodePlanLimit, message, http.StatusPaymentRequired)
+}
+
+// FormatBytes renders a size in whole MB or KB where it divides evenly
+func FormatBytes(n int64) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
 func ErrInternal(message string) *AppError {
 	return NewAppError(ErrCodeInternal, message, http.StatusInternalServerError)
 }
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"net/http"
+	"time"
+)
+
+// LoggingTransport logs every request made through Base with its duration and
+// status, and the attempt number when the caller retries. Entries go to the
+// logger on the request's context, so they carry the request ID of the call
+// that caused them. Failures, 429s and 5xx answers log at WARN, the rest at
+// DEBUG. Only the URL's path is logged; query strings can hold user data.
+type LoggingTransport struct {
+	// Service names the API called, such as supabase or claude
+	Service string
+	Base    http.RoundTripper
+}
+
+// NewLoggingTransport wraps base, or http.DefaultTransport if it is nil
+func NewLoggingTransport(service string, base http.RoundTripper) *LoggingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &LoggingTransport{Service: service, Base: base}
+}
+
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+
+	fields := map[string]interface{}{
+		"service":     t.Service,
+		"method":      req.Method,
+		"host":        req.URL.Host,
+		"path":        req.URL.Path,
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	if attempt := attemptFromContext(req.Context()); attempt > 0 {
+		fields["attempt"] = attempt
+	}
+	logger := LoggerFromContext(req.Context())
+	switch {
+	case err != nil:
+		fields["error"] = err.Error()
+		logger.Warn("Outbound call failed", fields)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		fields["status"] = resp.StatusCode
+		logger.Warn("Outbound call", fields)
+	default:
+		fields["status"] = resp.StatusCode
+		logger.Debug("Outbound call", fields)
+	}
+	return resp, err
+}
+
+// CloseIdleConnections closes Base's idle connections, so
+// http.Client.CloseIdleConnections still reaches them
+func (t *LoggingTransport) CloseIdleConnections() {
+	if closer, ok := t.Base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// memorySink keeps every entry written to it
+type memorySink struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+func (s *memorySink) Write(_ LogLevel, line []byte) error {
+	var entry LogEntry
+	json.Unmarshal(line, &entry)
+	s.mu.Lock()
+	s.entries = append(s.entries, entry)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func TestLoggingTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/busy" {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	sink := &memorySink{}
+	logger := NewLogger()
+	logger.SetLevel(LogLevelDebug)
+	logger.SetSinks(sink)
+	ctx := ContextWithLogger(context.Background(), logger.WithFields(map[string]interface{}{"request_id": "req-1"}))
+
+	client := &http.Client{Transport: NewLoggingTransport("claude", nil)}
+	for _, call := range []struct {
+		path    string
+		attempt int
+	}{{"/v1/messages?key=secret", 0}, {"/busy", 2}} {
+		req, _ := http.NewRequestWithContext(ContextWithAttempt(ctx, call.attempt), http.MethodPost, server.URL+call.path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(sink.entries) != 2 {
+		t.Fatalf("logged %d entries, want 2", len(sink.entries))
+	}
+	ok, busy := sink.entries[0], sink.entries[1]
+	if ok.Level != "DEBUG" || ok.Fields["service"] != "claude" || ok.Fields["path"] != "/v1/messages" ||
+		ok.Fields["status"].(float64) != 200 || ok.Fields["request_id"] != "req-1" || ok.Fields["attempt"] != nil {
+		t.Errorf("successful call logged as %+v", ok)
+	}
+	if _, ok := ok.Fields["duration_ms"]; !ok {
+		t.Error("duration not logged")
+	}
+	if busy.Level != "WARN" || busy.Fields["status"].(float64) != 429 || busy.Fields["attempt"].(float64) != 2 {
+		t.Errorf("rate limited call logged as %+v", busy)
+	}
+}
+
+func TestLoggerFromContextFallsBack(t *testing.T) {
+	logger := NewLogger()
+	SetDefaultLogger(logger)
+	defer SetDefaultLogger(NewLogger())
+
+	if got := LoggerFromContext(context.Background()); got.logger != logger || len(got.fields) != 0 {
+		t.Errorf("LoggerFromContext without a request logger = %+v", got)
+	}
+	withUser := logger.WithFields(map[string]interface{}{"request_id": "r"}).With(map[string]interface{}{"user_id": "u"})
+	if got := LoggerFromContext(ContextWithLogger(context.Background(), withUser)); got.fields["request_id"] != "r" || got.fields["user_id"] != "u" {
+		t.Errorf("request logger fields = %v", got.fields)
+	}
+}
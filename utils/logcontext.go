@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type loggerContextKey struct{}
+
+type attemptContextKey struct{}
+
+// defaultLogger logs where no request logger is at hand, such as background
+// workers and outbound calls made without the request's context
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+	defaultLogger.Store(NewLogger())
+}
+
+// SetDefaultLogger makes logger the one used where no request logger is at hand
+func SetDefaultLogger(logger *Logger) {
+	defaultLogger.Store(logger)
+}
+
+// DefaultLogger returns the logger set by SetDefaultLogger
+func DefaultLogger() *Logger {
+	return defaultLogger.Load()
+}
+
+// With returns a logger with fields added to fl's
+func (fl *FieldLogger) With(fields map[string]interface{}) *FieldLogger {
+	return &FieldLogger{logger: fl.logger, fields: fl.mergeFields(fields)}
+}
+
+// ContextWithLogger returns a copy of ctx carrying logger
+func ContextWithLogger(ctx context.Context, logger *FieldLogger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext returns the request logger ctx carries, with the request
+// ID, user and MCP session as fields, or the default logger without fields
+func LoggerFromContext(ctx context.Context) *FieldLogger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*FieldLogger); ok {
+		return logger
+	}
+	return DefaultLogger().WithFields(nil)
+}
+
+// ContextWithAttempt records which attempt of a retried call requests made
+// with ctx belong to, so their log entries carry it
+func ContextWithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptContextKey{}, attempt)
+}
+
+func attemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptContextKey{}).(int)
+	return attempt
+}
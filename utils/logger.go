@@ -3,7 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"log"
-	"os"
+	"sync"
 	"time"
 )
 
@@ -17,18 +17,62 @@ const (
 	LogLevelError LogLevel = "ERROR"
 )
 
-// Logger provides structured logging
+// Logger provides structured logging. Without sinks entries go to the
+// standard library logger; SetSinks routes them to configured sinks instead.
+// Secrets are scrubbed from every entry by its Redactor.
 type Logger struct {
 	level LogLevel
+
+	mu       sync.RWMutex
+	sinks    []Sink
+	redactor *Redactor
 }
 
-// NewLogger creates a new logger instance
+// baseRedactor redacts the default fields and patterns
+var baseRedactor = sync.OnceValue(defaultRedactor)
+
+// NewLogger creates a new logger instance logging at INFO and above, with
+// the default redactions
 func NewLogger() *Logger {
-	level := LogLevel(os.Getenv("LOG_LEVEL"))
+	return &Logger{level: LogLevelInfo, redactor: baseRedactor()}
+}
+
+// SetRedactor replaces the redactor scrubbing the logger's entries
+func (l *Logger) SetRedactor(r *Redactor) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.redactor = r
+}
+
+// SetSinks replaces the logger's sinks. Call Close on shutdown to flush them.
+func (l *Logger) SetSinks(sinks ...Sink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sinks = sinks
+}
+
+// Close flushes and closes every sink, reverting to the standard library logger
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	sinks := l.sinks
+	l.sinks = nil
+	l.mu.Unlock()
+
+	var firstErr error
+	for _, s := range sinks {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SetLevel sets the minimum level that is logged
+func (l *Logger) SetLevel(level LogLevel) {
 	if level == "" {
 		level = LogLevelInfo
 	}
-	return &Logger{level: level}
+	l.level = level
 }
 
 // LogEntry represents a structured log entry
@@ -55,6 +99,9 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 		return
 	}
 
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	entry := LogEntry{
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Level:     string(level),
@@ -65,9 +112,20 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 	if err != nil {
 		entry.Error = err.Error()
 	}
+	if l.redactor != nil {
+		entry.Message = l.redactor.String(entry.Message)
+		entry.Fields = l.redactor.Fields(entry.Fields)
+		entry.Error = l.redactor.String(entry.Error)
+	}
 
 	jsonData, _ := json.Marshal(entry)
-	log.Println(string(jsonData))
+	if len(l.sinks) == 0 {
+		log.Println(string(jsonData))
+		return
+	}
+	for _, sink := range l.sinks {
+		sink.Write(level, jsonData)
+	}
 }
 
 // Debug logs a debug message
@@ -0,0 +1,392 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/productivity/mcp-server/config"
+)
+
+// Sink receives formatted log lines. Sinks are wrapped in an AsyncSink by
+// NewSinks, so Write may block or be slow without affecting callers.
+type Sink interface {
+	Write(level LogLevel, line []byte) error
+	Close() error
+}
+
+// DefaultLogBufferSize is the number of entries an AsyncSink queues before dropping
+const DefaultLogBufferSize = 1024
+
+// NewSinks builds the sinks named in cfg.Sinks ("stdout", "file", "syslog",
+// "http"), each behind its own buffered AsyncSink
+func NewSinks(cfg config.Log) ([]Sink, error) {
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = DefaultLogBufferSize
+	}
+
+	var sinks []Sink
+	for _, name := range cfg.Sinks {
+		var (
+			sink Sink
+			err  error
+		)
+		switch name {
+		case "stdout":
+			sink = NewWriterSink(os.Stdout)
+		case "file":
+			sink, err = NewFileSink(cfg.File, int64(cfg.FileMaxSizeMB)<<20, cfg.FileMaxBackups)
+		case "syslog":
+			sink, err = NewSyslogSink(cfg.SyslogNetwork, cfg.SyslogAddr, cfg.SyslogTag)
+		case "http":
+			sink = NewHTTPSink(cfg.HTTPURL, cfg.HTTPFormat, 0, 0)
+		default:
+			err = fmt.Errorf("unknown log sink %q", name)
+		}
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, NewAsyncSink(name, sink, bufferSize))
+	}
+	return sinks, nil
+}
+
+// AsyncSink queues lines for a background writer so logging never blocks the
+// caller. When the queue is full new lines are dropped and counted; the count
+// is reported on stderr with the next successful write and on Close.
+type AsyncSink struct {
+	name    string
+	sink    Sink
+	queue   chan asyncLine
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type asyncLine struct {
+	level LogLevel
+	line  []byte
+}
+
+// NewAsyncSink starts a background writer for sink with a queue of size lines
+func NewAsyncSink(name string, sink Sink, size int) *AsyncSink {
+	a := &AsyncSink{
+		name:  name,
+		sink:  sink,
+		queue: make(chan asyncLine, size),
+		done:  make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Write queues line, dropping it if the queue is full or the sink is closed
+func (a *AsyncSink) Write(level LogLevel, line []byte) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return nil
+	}
+	select {
+	case a.queue <- asyncLine{level: level, line: line}:
+	default:
+		a.dropped.Add(1)
+	}
+	return nil
+}
+
+// Dropped returns the number of lines dropped since the last report
+func (a *AsyncSink) Dropped() int64 {
+	return a.dropped.Load()
+}
+
+func (a *AsyncSink) run() {
+	defer close(a.done)
+	for l := range a.queue {
+		if err := a.sink.Write(l.level, l.line); err != nil {
+			fmt.Fprintf(os.Stderr, "log sink %s: %v\n", a.name, err)
+			continue
+		}
+		a.reportDropped()
+	}
+}
+
+func (a *AsyncSink) reportDropped() {
+	if n := a.dropped.Swap(0); n > 0 {
+		fmt.Fprintf(os.Stderr, "log sink %s: dropped %d entries (queue full)\n", a.name, n)
+	}
+}
+
+// Close drains the queue, then closes the underlying sink
+func (a *AsyncSink) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	<-a.done
+	a.reportDropped()
+	return a.sink.Close()
+}
+
+// WriterSink writes one line per entry to an io.Writer such as os.Stdout
+type WriterSink struct {
+	mu sync.Mutex
+	w  *os.File
+}
+
+// NewWriterSink creates a sink writing to f
+func NewWriterSink(f *os.File) *WriterSink {
+	return &WriterSink{w: f}
+}
+
+func (s *WriterSink) Write(_ LogLevel, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close is a no-op; the process owns stdout
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// FileSink appends to a file, rotating it to path.1 ... path.N once it grows
+// past maxBytes. The oldest backup beyond maxBackups is removed.
+type FileSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewFileSink opens (or creates) the log file at path
+func NewFileSink(path string, maxBytes int64, maxBackups int) (*FileSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("log file path is required for the file sink")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	s := &FileSink{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	s.f, s.size = f, info.Size()
+	return nil
+}
+
+func (s *FileSink) Write(_ LogLevel, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := int64(len(line) + 1)
+	if s.maxBytes > 0 && s.size > 0 && s.size+n > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	written, err := s.f.Write(append(line, '\n'))
+	s.size += int64(written)
+	return err
+}
+
+// rotate shifts path.i to path.i+1, moves the live file to path.1 and reopens it
+func (s *FileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	if s.maxBackups > 0 {
+		os.Remove(s.backup(s.maxBackups))
+		for i := s.maxBackups - 1; i >= 1; i-- {
+			os.Rename(s.backup(i), s.backup(i+1))
+		}
+		if err := os.Rename(s.path, s.backup(1)); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	} else if err := os.Truncate(s.path, 0); err != nil {
+		return fmt.Errorf("failed to truncate log file: %w", err)
+	}
+	return s.open()
+}
+
+func (s *FileSink) backup(i int) string {
+	return s.path + "." + strconv.Itoa(i)
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// HTTPSink ships log lines in batches to an HTTP endpoint: a Loki push API
+// (format "loki") or any endpoint accepting a JSON array of entries ("json").
+// Batches are sent when they reach batchSize lines or every flushInterval.
+type HTTPSink struct {
+	url           string
+	format        string
+	batchSize     int
+	httpClient    *http.Client
+	flushInterval time.Duration
+
+	mu    sync.Mutex
+	batch []asyncLine
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewHTTPSink creates an HTTP shipping sink. Zero batchSize or flushInterval
+// use defaults of 100 lines and 2s.
+func NewHTTPSink(url, format string, batchSize int, flushInterval time.Duration) *HTTPSink {
+	if format == "" {
+		format = "loki"
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if flushInterval <= 0 {
+		flushInterval = 2 * time.Second
+	}
+	s := &HTTPSink{
+		url:           url,
+		format:        format,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s
+}
+
+func (s *HTTPSink) Write(level LogLevel, line []byte) error {
+	s.mu.Lock()
+	s.batch = append(s.batch, asyncLine{level: level, line: line})
+	full := len(s.batch) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *HTTPSink) flushLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "log sink http: %v\n", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// flush sends the pending batch. A failed batch is dropped rather than
+// retried so a slow or unavailable endpoint can't grow memory without bound.
+func (s *HTTPSink) flush() error {
+	s.mu.Lock()
+	batch := s.batch
+	s.batch = nil
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	body, err := s.encode(batch)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to ship %d log entries: %w", len(batch), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to ship %d log entries: %s", len(batch), resp.Status)
+	}
+	return nil
+}
+
+func (s *HTTPSink) encode(batch []asyncLine) ([]byte, error) {
+	if s.format == "json" {
+		entries := make([]json.RawMessage, len(batch))
+		for i, l := range batch {
+			entries[i] = l.line
+		}
+		return json.Marshal(entries)
+	}
+
+	// Loki push API: one stream per level, values are [unix-nanos, line]
+	now := time.Now().UnixNano()
+	streams := map[LogLevel][][2]string{}
+	var order []LogLevel
+	for i, l := range batch {
+		if _, ok := streams[l.level]; !ok {
+			order = append(order, l.level)
+		}
+		streams[l.level] = append(streams[l.level], [2]string{strconv.FormatInt(now+int64(i), 10), string(l.line)})
+	}
+	type lokiStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	payload := struct {
+		Streams []lokiStream `json:"streams"`
+	}{}
+	for _, level := range order {
+		payload.Streams = append(payload.Streams, lokiStream{
+			Stream: map[string]string{"service": "productivity-mcp-server", "level": string(level)},
+			Values: streams[level],
+		})
+	}
+	return json.Marshal(payload)
+}
+
+// Close stops the flush loop and sends any remaining lines
+func (s *HTTPSink) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.flush()
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package utils
+
+import "fmt"
+
+// NewSyslogSink is unavailable on platforms without syslog
+func NewSyslogSink(network, addr, tag string) (Sink, error) {
+	return nil, fmt.Errorf("syslog log sink is not supported on this platform")
+}
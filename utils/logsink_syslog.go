@@ -0,0 +1,39 @@
+//go:build !windows && !plan9
+
+package utils
+
+import (
+	"log/syslog"
+)
+
+// SyslogSink writes log lines to syslog at the priority matching their level
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to syslog. An empty network and addr use the local daemon.
+func NewSyslogSink(network, addr, tag string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+func (s *SyslogSink) Write(level LogLevel, line []byte) error {
+	msg := string(line)
+	switch level {
+	case LogLevelDebug:
+		return s.w.Debug(msg)
+	case LogLevelWarn:
+		return s.w.Warning(msg)
+	case LogLevelError:
+		return s.w.Err(msg)
+	default:
+		return s.w.Info(msg)
+	}
+}
+
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "server.log")
+	sink, err := NewFileSink(path, 64, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := []byte(`{"level":"INFO","message":"0123456789012345678901234"}`) // 55 bytes + newline
+	for i := 0; i < 4; i++ {
+		if err := sink.Write(LogLevelInfo, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Close()
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("expected %s: %v", filepath.Base(name), err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, stat .3: %v", err)
+	}
+}
+
+// blockingSink blocks every write until release is closed
+type blockingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	lines   int
+}
+
+func (s *blockingSink) Write(LogLevel, []byte) error {
+	<-s.release
+	s.mu.Lock()
+	s.lines++
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *blockingSink) Close() error { return nil }
+
+func TestAsyncSinkDropsWhenFull(t *testing.T) {
+	inner := &blockingSink{release: make(chan struct{})}
+	sink := NewAsyncSink("test", inner, 2)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			sink.Write(LogLevelInfo, []byte("x"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Write blocked on a slow sink")
+	}
+
+	if sink.Dropped() == 0 {
+		t.Error("expected entries to be dropped once the queue filled")
+	}
+	close(inner.release)
+	sink.Close()
+	if inner.lines == 0 || inner.lines > 3 {
+		t.Errorf("expected the queued entries to be flushed, got %d", inner.lines)
+	}
+}
+
+func TestHTTPSinkShipsLokiBatches(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		pushes = append(pushes, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPSink(srv.URL, "loki", 2, time.Hour)
+	sink.Write(LogLevelInfo, []byte(`{"message":"a"}`))
+	sink.Write(LogLevelError, []byte(`{"message":"b"}`)) // fills the batch
+	sink.Write(LogLevelInfo, []byte(`{"message":"c"}`))  // sent on Close
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushes) != 2 {
+		t.Fatalf("expected 2 pushes, got %d", len(pushes))
+	}
+	streams, _ := pushes[0]["streams"].([]interface{})
+	if len(streams) != 2 {
+		t.Fatalf("expected one stream per level, got %v", pushes[0])
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces a sensitive value in a log entry
+const Redacted = "[REDACTED]"
+
+// DefaultRedactFields are the field names whose values are never logged.
+// Names match case-insensitively, ignoring _ and -, so code_verifier also
+// covers codeVerifier. An authorization code is logged as auth_code, since a
+// code field usually holds an error code.
+var DefaultRedactFields = []string{
+	"password", "secret", "client_secret", "token", "access_token", "refresh_token",
+	"id_token", "auth_token", "registration_access_token", "auth_code", "code_verifier",
+	"api_key", "x_api_key", "authorization", "cookie", "set_cookie", "webhook_secret",
+}
+
+// DefaultRedactPatterns match secrets wherever they appear in a logged string:
+// bearer credentials, JWTs, this server's API keys, Anthropic keys, and
+// secrets passed as query or form parameters
+var DefaultRedactPatterns = []string{
+	`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{16,}`,
+	`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+	`\bpmcp_[A-Za-z0-9_-]+`,
+	`\bsk-ant-[A-Za-z0-9_-]+`,
+	`(?i)\b(?:code|code_verifier|client_secret|access_token|refresh_token|token|api_key|password)=[^&\s"]+`,
+}
+
+// Redactor scrubs secrets from log entries: values of sensitive fields are
+// replaced whole, and any string matching a redaction pattern has the match
+// replaced
+type Redactor struct {
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor builds a redactor from the defaults plus extra field names and
+// regular expressions
+func NewRedactor(extraFields, extraPatterns []string) (*Redactor, error) {
+	r := &Redactor{fields: make(map[string]bool)}
+	for _, name := range append(append([]string(nil), DefaultRedactFields...), extraFields...) {
+		r.fields[normalizeFieldName(name)] = true
+	}
+	for _, pattern := range append(append([]string(nil), DefaultRedactPatterns...), extraPatterns...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// defaultRedactor is built from the defaults, which always compile
+func defaultRedactor() *Redactor {
+	r, err := NewRedactor(nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func normalizeFieldName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "_", "")
+	return strings.ReplaceAll(name, "-", "")
+}
+
+// String redacts pattern matches in s
+func (r *Redactor) String(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllStringFunc(s, redactMatch)
+	}
+	return s
+}
+
+// redactMatch keeps the parameter name of a key=value match so the log still
+// says what was there
+func redactMatch(match string) string {
+	if name, _, ok := strings.Cut(match, "="); ok && !strings.ContainsAny(name, " \t") {
+		return name + "=" + Redacted
+	}
+	return Redacted
+}
+
+// Fields returns a copy of fields with sensitive values redacted, descending
+// into nested maps and slices
+func (r *Redactor) Fields(fields map[string]interface{}) map[string]interface{} {
+	if fields == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if r.fields[normalizeFieldName(k)] && v != nil && v != "" {
+			redacted[k] = Redacted
+			continue
+		}
+		redacted[k] = r.value(v)
+	}
+	return redacted
+}
+
+func (r *Redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case error:
+		return r.String(v.Error())
+	case map[string]interface{}:
+		return r.Fields(v)
+	case map[string]string:
+		redacted := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			redacted[k] = s
+		}
+		return r.Fields(redacted)
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = r.value(item)
+		}
+		return redacted
+	case []string:
+		redacted := make([]string, len(v))
+		for i, s := range v {
+			redacted[i] = r.String(s)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
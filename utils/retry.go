@@ -3,6 +3,9 @@ package utils
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -13,6 +16,14 @@ type RetryConfig struct {
 	MaxDelay     time.Duration
 	Multiplier   float64
 	ShouldRetry  func(error) bool
+	// RetryAfter, if set, returns how long the failed call asked to be given
+	// before the next attempt, such as a Retry-After header; a positive wait
+	// replaces the backoff delay, and one longer than MaxDelay ends the retries
+	RetryAfter func(error) time.Duration
+	// Jitter, from 0 to 1, shortens each backoff delay by a random share of
+	// up to that fraction, so callers that failed together do not all retry
+	// at the same moment
+	Jitter float64
 }
 
 // DefaultRetryConfig returns a default retry configuration
@@ -56,11 +67,20 @@ func Retry(ctx context.Context, config *RetryConfig, fn func() error) error {
 
 		// Don't sleep after the last attempt
 		if attempt < config.MaxAttempts {
+			wait := config.jitter(delay)
+			if config.RetryAfter != nil {
+				if after := config.RetryAfter(err); after > config.MaxDelay {
+					return err
+				} else if after > 0 {
+					wait = after
+				}
+			}
+
 			// Wait before retrying
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(delay):
+			case <-time.After(wait):
 			}
 
 			// Exponential backoff
@@ -73,3 +93,25 @@ func Retry(ctx context.Context, config *RetryConfig, fn func() error) error {
 
 	return fmt.Errorf("max attempts (%d) reached: %w", config.MaxAttempts, lastErr)
 }
+
+// jitter shortens delay by a random share of up to config.Jitter
+func (config *RetryConfig) jitter(delay time.Duration) time.Duration {
+	if config.Jitter <= 0 {
+		return delay
+	}
+	return delay - time.Duration(rand.Float64()*config.Jitter*float64(delay))
+}
+
+// ParseRetryAfter reads a Retry-After header in seconds or as an HTTP date
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
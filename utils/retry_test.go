@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := ParseRetryAfter("7"); got != 7*time.Second {
+		t.Errorf("seconds: %v", got)
+	}
+	at := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := ParseRetryAfter(at); got < 50*time.Second || got > time.Minute {
+		t.Errorf("date: %v", got)
+	}
+	if got := ParseRetryAfter("soon"); got != 0 {
+		t.Errorf("garbage: %v", got)
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	config := &RetryConfig{Jitter: 0.5}
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		wait := config.jitter(time.Second)
+		if wait < 500*time.Millisecond || wait > time.Second {
+			t.Fatalf("jittered wait %v outside [500ms, 1s]", wait)
+		}
+		seen[wait] = true
+	}
+	if len(seen) < 2 {
+		t.Error("jitter did not vary the wait")
+	}
+	if wait := (&RetryConfig{}).jitter(time.Second); wait != time.Second {
+		t.Errorf("without jitter the wait is %v", wait)
+	}
+}
@@ -0,0 +1,166 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CodeTooShort reports a string below its schema's minLength
+const CodeTooShort = "too_short"
+
+// Schema is the subset of JSON Schema used to declare MCP tool inputs. It
+// marshals to the schema advertised to clients and validates arguments
+// against it, so the two cannot drift apart.
+type Schema struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	MinLength   int                `json:"minLength,omitempty"`
+	MaxLength   int                `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+}
+
+// Bound returns a pointer for a schema's Minimum or Maximum
+func Bound(v float64) *float64 {
+	return &v
+}
+
+// Validate checks value, as decoded by encoding/json, against the schema and
+// returns a field error for every violation, or nil. Properties the schema
+// does not declare are allowed. Array elements are named like "items[0].title".
+func (s *Schema) Validate(value interface{}) Errors {
+	return s.ValidateAs("params", value)
+}
+
+// ValidateAs is Validate with errors about value itself, rather than one of
+// its fields, naming it root
+func (s *Schema) ValidateAs(root string, value interface{}) Errors {
+	var v Validator
+	s.validate(&v, root, "", value)
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (s *Schema) validate(v *Validator, root, field string, value interface{}) {
+	name := field
+	if name == "" {
+		name = root
+	}
+	if !hasType(s.Type, value) {
+		v.Add(name, CodeInvalidType, fmt.Sprintf("%s must be %s", name, article(s.Type)))
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := value[key]; !ok {
+				v.Add(join(field, key), CodeRequired, join(field, key)+" is required")
+			}
+		}
+		keys := make([]string, 0, len(s.Properties))
+		for key := range s.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if prop, ok := value[key]; ok {
+				s.Properties[key].validate(v, root, join(field, key), prop)
+			}
+		}
+
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				s.Items.validate(v, root, fmt.Sprintf("%s[%d]", name, i), item)
+			}
+		}
+
+	case string:
+		length := len([]rune(value))
+		v.Check(s.MinLength == 0 || length >= s.MinLength, name, CodeTooShort, minLengthMessage(name, s.MinLength))
+		v.Check(s.MaxLength == 0 || length <= s.MaxLength, name, CodeTooLong, fmt.Sprintf("%s must be at most %d characters", name, s.MaxLength))
+		if s.Format == "date-time" {
+			_, err := time.Parse(time.RFC3339, value)
+			v.Check(err == nil, name, CodeInvalidFormat, name+" must be an RFC 3339 timestamp")
+		}
+		if len(s.Enum) > 0 {
+			v.Check(contains(s.Enum, value), name, CodeInvalidValue, name+" must be one of "+strings.Join(s.Enum, ", "))
+		}
+
+	case float64:
+		if (s.Minimum != nil && value < *s.Minimum) || (s.Maximum != nil && value > *s.Maximum) {
+			v.Add(name, CodeOutOfRange, rangeMessage(name, s.Minimum, s.Maximum))
+		}
+	}
+}
+
+// hasType reports whether a decoded JSON value is of the JSON Schema type t
+func hasType(t string, value interface{}) bool {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	case string:
+		return t == "string"
+	case bool:
+		return t == "boolean"
+	case float64:
+		return t == "number" || (t == "integer" && value == math.Trunc(value))
+	case nil:
+		return t == "null"
+	}
+	return false
+}
+
+func join(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func article(t string) string {
+	if t == "integer" || t == "object" || t == "array" {
+		return "an " + t
+	}
+	return "a " + t
+}
+
+func minLengthMessage(name string, min int) string {
+	if min == 1 {
+		return name + " must not be empty"
+	}
+	return fmt.Sprintf("%s must be at least %d characters", name, min)
+}
+
+func rangeMessage(name string, min, max *float64) string {
+	switch {
+	case min != nil && max != nil:
+		return fmt.Sprintf("%s must be between %g and %g", name, *min, *max)
+	case min != nil:
+		return fmt.Sprintf("%s must be at least %g", name, *min)
+	default:
+		return fmt.Sprintf("%s must be at most %g", name, *max)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"title":    {Type: "string", MinLength: 1, MaxLength: 10},
+			"due_date": {Type: "string", Format: "date-time"},
+			"priority": {Type: "integer", Minimum: Bound(1), Maximum: Bound(5)},
+			"status":   {Type: "string", Enum: []string{"todo", "done"}},
+			"meta": {
+				Type:       "object",
+				Properties: map[string]*Schema{"source": {Type: "string"}},
+				Required:   []string{"source"},
+			},
+			"steps": {
+				Type: "array",
+				Items: &Schema{
+					Type:       "object",
+					Properties: map[string]*Schema{"title": {Type: "string", MinLength: 1}},
+					Required:   []string{"title"},
+				},
+			},
+		},
+		Required: []string{"title"},
+	}
+
+	cases := []struct {
+		args  string
+		field string
+		code  string
+	}{
+		{`{"title": "a", "due_date": "2030-01-01T00:00:00Z", "priority": 3, "status": "done", "extra": true}`, "", ""},
+		{`{}`, "title", CodeRequired},
+		{`{"title": ""}`, "title", CodeTooShort},
+		{`{"title": "far too long a title"}`, "title", CodeTooLong},
+		{`{"title": 42}`, "title", CodeInvalidType},
+		{`{"title": null}`, "title", CodeInvalidType},
+		{`{"title": "a", "due_date": "tomorrow"}`, "due_date", CodeInvalidFormat},
+		{`{"title": "a", "priority": 2.5}`, "priority", CodeInvalidType},
+		{`{"title": "a", "priority": 9}`, "priority", CodeOutOfRange},
+		{`{"title": "a", "status": "maybe"}`, "status", CodeInvalidValue},
+		{`{"title": "a", "meta": {}}`, "meta.source", CodeRequired},
+		{`{"title": "a", "meta": {"source": 1}}`, "meta.source", CodeInvalidType},
+		{`{"title": "a", "steps": [{"title": "one"}, {}]}`, "steps[1].title", CodeRequired},
+		{`{"title": "a", "steps": {"title": "one"}}`, "steps", CodeInvalidType},
+	}
+	for _, tc := range cases {
+		var args interface{}
+		if err := json.Unmarshal([]byte(tc.args), &args); err != nil {
+			t.Fatal(err)
+		}
+		errs := schema.Validate(args)
+		if tc.field == "" {
+			if errs != nil {
+				t.Errorf("%s: unexpected errors %v", tc.args, errs)
+			}
+			continue
+		}
+		if len(errs) != 1 || errs[0].Field != tc.field || errs[0].Code != tc.code {
+			t.Errorf("%s: got %+v, want %s/%s", tc.args, errs, tc.field, tc.code)
+		}
+	}
+
+	if errs := schema.Validate([]interface{}{}); len(errs) != 1 || errs[0].Field != "params" {
+		t.Errorf("non-object params: got %+v", errs)
+	}
+}
+
+func TestSchemaMarshalsAsJSONSchema(t *testing.T) {
+	data, err := json.Marshal(&Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"days": {Type: "integer", Minimum: Bound(1)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"object","properties":{"days":{"type":"integer","minimum":1}}}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
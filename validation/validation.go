@@ -0,0 +1,239 @@
+// Package validation turns request binding failures and field constraints into
+// machine-readable field errors:
+//
+//	{"errors": [{"field": "due_date", "code": "invalid_format", "message": "..."}]}
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Error codes
+const (
+	CodeRequired      = "required"
+	CodeInvalidFormat = "invalid_format"
+	CodeInvalidType   = "invalid_type"
+	CodeOutOfRange    = "out_of_range"
+	CodeTooLong       = "too_long"
+	CodeInvalidValue  = "invalid_value"
+	CodeMalformedBody = "malformed_body"
+)
+
+// Shared field limits
+const (
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 5000
+	MinPriority          = 1
+	MaxPriority          = 5
+)
+
+// FieldError describes one invalid field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors is a list of field errors; it implements error
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validator accumulates field errors so a response reports every problem at once
+type Validator struct {
+	errs Errors
+}
+
+// Add records an error for field
+func (v *Validator) Add(field, code, message string) {
+	v.errs = append(v.errs, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Check records an error unless ok
+func (v *Validator) Check(ok bool, field, code, message string) {
+	if !ok {
+		v.Add(field, code, message)
+	}
+}
+
+// Required checks that a string field is not blank
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, CodeRequired, field+" is required")
+}
+
+// MaxLength checks that a string field has at most max characters
+func (v *Validator) MaxLength(field, value string, max int) {
+	v.Check(len([]rune(value)) <= max, field, CodeTooLong, fmt.Sprintf("%s must be at most %d characters", field, max))
+}
+
+// Range checks that an integer field is within [min, max]
+func (v *Validator) Range(field string, value, min, max int) {
+	v.Check(value >= min && value <= max, field, CodeOutOfRange, fmt.Sprintf("%s must be between %d and %d", field, min, max))
+}
+
+// Err returns the accumulated errors, or nil if there are none
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// DecodeJSON decodes a JSON body into dst (a pointer to a struct) and returns
+// binding failures as Errors. Struct tags `binding:"required"` are enforced.
+// A body cut off by http.MaxBytesReader is returned as its *http.MaxBytesError.
+func DecodeJSON(body io.Reader, dst interface{}) error {
+	raw, err := io.ReadAll(body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return tooLarge
+	}
+	if err != nil {
+		return Errors{{Field: "body", Code: CodeMalformedBody, Message: "failed to read request body"}}
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return FromDecodeError(err, raw, dst)
+	}
+	if err := validate.Struct(dst); err != nil {
+		return FromValidatorError(err)
+	}
+	return nil
+}
+
+var validate = func() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	v.RegisterTagNameFunc(jsonFieldName)
+	return v
+}()
+
+// FromDecodeError converts a JSON decoding error into field errors. raw and dst
+// are used to locate the offending field when the error doesn't name it.
+func FromDecodeError(err error, raw []byte, dst interface{}) Errors {
+	var typeErr *json.UnmarshalTypeError
+	var parseErr *time.ParseError
+	switch {
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return Errors{{Field: field, Code: CodeInvalidType, Message: fmt.Sprintf("%s must be a %s", field, jsonTypeName(typeErr.Type))}}
+	case errors.As(err, &parseErr):
+		field := invalidTimeField(raw, dst)
+		return Errors{{Field: field, Code: CodeInvalidFormat, Message: field + " must be an RFC 3339 timestamp"}}
+	default:
+		return Errors{{Field: "body", Code: CodeMalformedBody, Message: "request body must be valid JSON"}}
+	}
+}
+
+// FromValidatorError converts struct tag validation failures into field errors
+func FromValidatorError(err error) Errors {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return Errors{{Field: "body", Code: CodeInvalidValue, Message: err.Error()}}
+	}
+	out := make(Errors, 0, len(verrs))
+	for _, fe := range verrs {
+		code, message := CodeInvalidValue, fe.Field()+" is invalid"
+		if fe.Tag() == "required" {
+			code, message = CodeRequired, fe.Field()+" is required"
+		}
+		out = append(out, FieldError{Field: fe.Field(), Code: code, Message: message})
+	}
+	return out
+}
+
+// invalidTimeField finds the first time field in dst whose raw JSON value doesn't parse
+func invalidTimeField(raw []byte, dst interface{}) string {
+	var values map[string]json.RawMessage
+	if json.Unmarshal(raw, &values) != nil {
+		return "body"
+	}
+	t := reflect.TypeOf(dst)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return "body"
+	}
+	timeType := reflect.TypeOf(time.Time{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft != timeType {
+			continue
+		}
+		name := jsonFieldName(f)
+		value, ok := values[name]
+		if !ok || bytes.Equal(value, []byte("null")) {
+			continue
+		}
+		var parsed time.Time
+		if parsed.UnmarshalJSON(value) != nil {
+			return name
+		}
+	}
+	return "body"
+}
+
+func jsonFieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return snakeCase(f.Name)
+	}
+	return name
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
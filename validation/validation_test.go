@@ -0,0 +1,80 @@
+package validation
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sampleRequest struct {
+	Title    string     `json:"title" binding:"required"`
+	Priority int        `json:"priority"`
+	DueDate  time.Time  `json:"due_date" binding:"required"`
+	EndDate  *time.Time `json:"end_date"`
+}
+
+func decode(t *testing.T, body string) Errors {
+	t.Helper()
+	var req sampleRequest
+	err := DecodeJSON(strings.NewReader(body), &req)
+	if err == nil {
+		return nil
+	}
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected Errors, got %T", err)
+	}
+	return errs
+}
+
+func TestDecodeJSONFieldErrors(t *testing.T) {
+	cases := []struct {
+		body  string
+		field string
+		code  string
+	}{
+		{`{"title": "a", "due_date": "tomorrow"}`, "due_date", CodeInvalidFormat},
+		{`{"title": "a", "due_date": "2030-01-01T00:00:00Z", "end_date": "soon"}`, "end_date", CodeInvalidFormat},
+		{`{"title": "a", "priority": "high", "due_date": "2030-01-01T00:00:00Z"}`, "priority", CodeInvalidType},
+		{`{"due_date": "2030-01-01T00:00:00Z"}`, "title", CodeRequired},
+		{`{"title": `, "body", CodeMalformedBody},
+	}
+	for _, tc := range cases {
+		errs := decode(t, tc.body)
+		if len(errs) != 1 || errs[0].Field != tc.field || errs[0].Code != tc.code {
+			t.Errorf("%s: expected %s/%s, got %+v", tc.body, tc.field, tc.code, errs)
+		}
+	}
+
+	if errs := decode(t, `{"title": "a", "due_date": "2030-01-01T00:00:00Z"}`); errs != nil {
+		t.Errorf("expected valid body, got %+v", errs)
+	}
+}
+
+func TestDecodeJSONTooLarge(t *testing.T) {
+	body := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader(`{"title": "too long"}`)), 8)
+	var req sampleRequest
+	var tooLarge *http.MaxBytesError
+	if err := DecodeJSON(body, &req); !errors.As(err, &tooLarge) || tooLarge.Limit != 8 {
+		t.Errorf("expected *http.MaxBytesError, got %v", err)
+	}
+}
+
+func TestValidatorAccumulates(t *testing.T) {
+	var v Validator
+	v.Required("title", "  ")
+	v.Range("priority", 9, MinPriority, MaxPriority)
+	v.MaxLength("description", strings.Repeat("é", 11), 10)
+
+	errs, ok := v.Err().(Errors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", v.Err())
+	}
+	if errs[0].Code != CodeRequired || errs[1].Code != CodeOutOfRange || errs[2].Code != CodeTooLong {
+		t.Fatalf("unexpected codes: %+v", errs)
+	}
+}
@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	sent := time.Unix(1735689600, 0)
+	body := []byte(`{"ok":true}`)
+	header := Sign("whsec", sent, body)
+	// Computed independently: HMAC-SHA256("whsec", `1735689600.{"ok":true}`)
+	if want := "t=1735689600,v1=1b8ed03092dee4ddd23492cc5a3c13fe155ee199ede2d133b15b4ebe6134347e"; header != want {
+		t.Fatalf("Sign = %s, want %s", header, want)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   string
+		now    time.Time
+		want   error
+	}{
+		{"valid", "whsec", header, `{"ok":true}`, sent.Add(time.Minute), nil},
+		{"rotated secret", "whsec", header + ",v1=" + strings.Repeat("0", 64), `{"ok":true}`, sent, nil},
+		{"wrong secret", "other", header, `{"ok":true}`, sent, ErrBadSignature},
+		{"changed body", "whsec", header, `{"ok":false}`, sent, ErrBadSignature},
+		{"replayed later", "whsec", header, `{"ok":true}`, sent.Add(10 * time.Minute), ErrExpired},
+		{"from the future", "whsec", header, `{"ok":true}`, sent.Add(-10 * time.Minute), ErrExpired},
+		{"no timestamp", "whsec", "v1=abc", `{"ok":true}`, sent, ErrNoSignature},
+		{"empty", "whsec", "", `{"ok":true}`, sent, ErrNoSignature},
+	}
+	for _, tt := range tests {
+		if err := Verify(tt.secret, tt.header, []byte(tt.body), 0, tt.now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Verify = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	body := `{"type":"slo.alert"}`
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	SignRequest(req, "", []byte(body))
+	if req.Header.Get(SignatureHeader) != "" {
+		t.Error("an empty secret signed the request")
+	}
+
+	SignRequest(req, "whsec", []byte(body))
+	if err := VerifyRequest(req, "whsec", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// The handler still gets the body
+	if rest, _ := req.Body.Read(make([]byte, 64)); rest != len(body) {
+		t.Errorf("body not restored: read %d bytes", rest)
+	}
+}